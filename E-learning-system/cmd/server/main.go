@@ -1,56 +1,90 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"e-learning-system/internal/api/controller"
 	"e-learning-system/internal/api/gateway"
+	"e-learning-system/internal/api/middleware"
 	"e-learning-system/internal/api/routes"
 	"e-learning-system/internal/config"
 	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/health"
+	"e-learning-system/internal/job"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/metrics"
+	"e-learning-system/internal/password"
+	"e-learning-system/internal/server"
+	"e-learning-system/internal/sso"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/throttle"
+	"e-learning-system/internal/tracing"
 	"fmt"
 
 	// utils "kaabe-app/pkg/config"
 
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // profile time zones are validated against the IANA database
 	// "net/http"
-	// "os"
-	// "time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-
-	"github.com/joho/godotenv"
 )
 
-func init() {
-	err := godotenv.Load(".env")
+func main() {
+	// Load configuration: defaults, config file, .env and env vars, then flags
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Println(".env file not found, relying on system environment variables")
+		log.Fatalf("Invalid configuration: %v", err)
 	}
-}
 
-func main() {
-	// Load configuration
-	config.LoadEnv()
-	appCfg, err := config.LoadAppConfig()
+	// Structured logging; log.Printf calls are routed through it as well
+	appLogger := logger.New(logger.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	slog.SetDefault(appLogger)
+
+	// Tracing; spans are exported to stdout or an OTLP collector when configured
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		ServiceName:  cfg.App.Name,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		Environment:  cfg.App.Env,
+	})
 	if err != nil {
-		log.Fatalf("Failed to load config.yaml: %v", err)
+		log.Fatalf("Failed to set up tracing: %v", err)
 	}
 
-	dbCfg := config.LoadDBConfig()
-	db := config.InitDB(dbCfg)
+	db := config.InitDB(cfg.Database)
 	if db == nil {
 		log.Fatal("Failed to initialize the database")
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			log.Printf("Error closing DB: %v", err)
-		}
-	}()
 
-	fmt.Printf("Server running on port %s in %s mode\n", appCfg.App.Port, appCfg.App.Env)
+	slog.Info("Server starting", "port", cfg.App.Port, "env", cfg.App.Env)
 	var dbConn *sql.DB = db
 
+	if err := metrics.RegisterDB(dbConn, cfg.Database.Name); err != nil {
+		log.Printf("Failed to register database metrics: %v", err)
+	}
+
+	// Dependencies checked by the readiness endpoint
+	healthCheckers := []health.Checker{health.NewPostgresChecker(dbConn)}
+	if cfg.Redis.URL != "" {
+		redisChecker, err := health.NewRedisChecker(cfg.Redis.URL)
+		if err != nil {
+			log.Fatalf("Failed to configure Redis health check: %v", err)
+		}
+		healthCheckers = append(healthCheckers, redisChecker)
+	}
+
 	userRepo := gateway.NewUserRepositry(dbConn)
 	tokenRepo := gateway.NewTokenRepository(dbConn)
 	organizationRepo := gateway.NewOrganizationRepository(dbConn)
@@ -58,42 +92,249 @@ func main() {
 	organizationTutorRepo := gateway.NewOrganizationTutorRepository(dbConn)
 	organizationBrandingRepo := gateway.NewOrganizationBrandingRepository(dbConn)
 	organizationBillingRepo := gateway.NewOrganizationBillingRepository(dbConn)
+	assetRepo := gateway.NewAssetRepository(dbConn)
+	courseRepo := gateway.NewCourseRepository(dbConn)
+	lessonRepo := gateway.NewLessonRepository(dbConn)
+	enrollmentRepo := gateway.NewEnrollmentRepository(dbConn)
+	videoUploadRepo := gateway.NewVideoUploadRepository(dbConn)
+	loginEventRepo := gateway.NewLoginEventRepository(dbConn)
+	mfaRepo := gateway.NewMFARepository(dbConn)
+	ssoRepo := gateway.NewSSORepository(dbConn)
+	apiKeyRepo := gateway.NewAPIKeyRepository(dbConn)
+	auditEventRepo := gateway.NewAuditEventRepository(dbConn)
+	retentionRepo := gateway.NewRetentionRepository(dbConn)
+	privacyRepo := gateway.NewPrivacyRepository(dbConn)
+	userImportRepo := gateway.NewUserImportRepository(dbConn)
+
+	// Object storage for uploaded files
+	fileStorage, err := storage.New(storage.Config{
+		Driver:      cfg.Storage.Driver,
+		LocalDir:    cfg.Storage.Dir,
+		PublicURL:   cfg.Storage.PublicURL,
+		SigningKey:  cfg.Storage.SigningKey,
+		S3Endpoint:  cfg.Storage.S3Endpoint,
+		S3Region:    cfg.Storage.S3Region,
+		S3Bucket:    cfg.Storage.S3Bucket,
+		S3AccessKey: cfg.Storage.S3AccessKey,
+		S3SecretKey: cfg.Storage.S3SecretKey,
+		S3PathStyle: cfg.Storage.S3PathStyle,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize file storage: %v", err)
+	}
+
+	// Transactional email such as confirmation links
+	mailer, err := mail.New(mail.Config{
+		Driver:       cfg.Mail.Driver,
+		From:         cfg.Mail.From,
+		SMTPHost:     cfg.Mail.SMTPHost,
+		SMTPPort:     cfg.Mail.SMTPPort,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+
+	// Failed sign-in counters, shared between instances through Redis
+	var throttleStore throttle.Store
+	var redisStore *throttle.RedisStore
+	if cfg.Redis.URL != "" {
+		redisStore, err = throttle.NewRedisStore(cfg.Redis.URL, cfg.App.Name+":")
+		if err != nil {
+			log.Fatalf("Failed to initialize login throttling: %v", err)
+		}
+		throttleStore = redisStore
+	} else {
+		slog.Warn("Redis is not configured; failed sign-ins are counted per instance")
+		throttleStore = throttle.NewMemoryStore()
+	}
+	loginGuard := throttle.NewLoginGuard(throttleStore, throttle.LoginPolicy{
+		Window:          cfg.Auth.Lockout.Window,
+		AccountLimit:    cfg.Auth.Lockout.AccountLimit,
+		IPLimit:         cfg.Auth.Lockout.IPLimit,
+		LockoutDuration: cfg.Auth.Lockout.Duration,
+		DelayAfter:      cfg.Auth.Lockout.DelayAfter,
+		BaseDelay:       cfg.Auth.Lockout.BaseDelay,
+		MaxDelay:        cfg.Auth.Lockout.MaxDelay,
+	})
+
+	// Identity providers of organizations send users back to the frontend,
+	// which posts the code to /sso/callback
+	ssoRedirectURL := cfg.Auth.SSO.RedirectURL
+	if ssoRedirectURL == "" {
+		ssoRedirectURL = strings.TrimRight(cfg.App.FrontendURL, "/") + "/sso/callback"
+	}
+	ssoClient := sso.NewClient(&http.Client{Timeout: 10 * time.Second}, ssoRedirectURL)
+	samlCallbackURL := cfg.Auth.SSO.SAMLCallbackURL
+	if samlCallbackURL == "" {
+		samlCallbackURL = strings.TrimRight(cfg.App.FrontendURL, "/") + "/sso/saml/callback"
+	}
 
 	// Initialize Services
-	userService := service.NewUserService(userRepo, tokenRepo)
-	organizationService := service.NewOrganizationService(organizationRepo)
-	organizationAdminService := service.NewOrganizationAdminService(organizationAdminRepo)
-	organizationTutorService := service.NewOrganizationTutorService(organizationTutorRepo)
-	organizationBrandingService := service.NewOrganizationBrandingService(organizationBrandingRepo)
-	organizationBillingService := service.NewOrganizationBillingService(organizationBillingRepo)
+	auditService := service.NewAuditService(auditEventRepo)
+	retentionService := service.NewRetentionService(retentionRepo, fileStorage, auditService)
+	userService := service.NewUserService(userRepo, tokenRepo, organizationRepo, loginEventRepo, mfaRepo, ssoRepo, ssoClient, mailer, loginGuard, auditService, service.UserServiceOptions{
+		FrontendURL:          cfg.App.FrontendURL,
+		SigningKey:           []byte(cfg.Auth.VerificationKey),
+		VerificationTTL:      cfg.Auth.VerificationTTL,
+		RequireVerifiedLogin: cfg.Auth.EmailVerification == config.VerificationRequired,
+		EncryptionKey:        []byte(cfg.Auth.EncryptionKey),
+		MFAIssuer:            cfg.Auth.MFA.Issuer,
+		MFAChallengeTTL:      cfg.Auth.MFA.ChallengeTTL,
+		SSOStateTTL:          cfg.Auth.SSO.StateTTL,
+		SSOPublicURL:         cfg.Auth.SSO.PublicURL,
+		SAMLCallbackURL:      samlCallbackURL,
+		PasswordPolicy: password.Policy{
+			MinLength:     cfg.Auth.Password.MinLength,
+			MaxLength:     cfg.Auth.Password.MaxLength,
+			RequireUpper:  cfg.Auth.Password.RequireUpper,
+			RequireLower:  cfg.Auth.Password.RequireLower,
+			RequireDigit:  cfg.Auth.Password.RequireDigit,
+			RequireSymbol: cfg.Auth.Password.RequireSymbol,
+			RejectCommon:  cfg.Auth.Password.RejectCommon,
+			History:       cfg.Auth.Password.History,
+		},
+		PasswordResetTTL:  cfg.Auth.PasswordReset.TTL,
+		ResetEmailLimiter: throttle.NewLimiter(throttleStore, "reset-email", cfg.Auth.PasswordReset.EmailLimit, cfg.Auth.PasswordReset.Window),
+		ResetIPLimiter:    throttle.NewLimiter(throttleStore, "reset-ip", cfg.Auth.PasswordReset.IPLimit, cfg.Auth.PasswordReset.Window),
+	})
+	organizationService := service.NewOrganizationService(organizationRepo, ssoRepo, ssoClient, fileStorage, auditService, service.OrganizationServiceOptions{
+		EncryptionKey:        []byte(cfg.Auth.EncryptionKey),
+		AllowInsecureIssuers: !cfg.App.IsProduction(),
+		PublicURL:            cfg.Auth.SSO.PublicURL,
+	})
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, organizationRepo, auditService)
+	organizationAdminService := service.NewOrganizationAdminService(organizationAdminRepo, auditService)
+	organizationTutorService := service.NewOrganizationTutorService(organizationTutorRepo, auditService)
+	assetService := service.NewAssetService(assetRepo, fileStorage, userRepo, organizationAdminRepo)
+	organizationBrandingService := service.NewOrganizationBrandingService(organizationBrandingRepo, assetService, auditService)
+	organizationBillingService := service.NewOrganizationBillingService(organizationBillingRepo, auditService)
+	courseService := service.NewCourseService(courseRepo, lessonRepo, enrollmentRepo, organizationRepo, userRepo)
+	videoService := service.NewVideoService(courseRepo, lessonRepo, enrollmentRepo, videoUploadRepo,
+		fileStorage, cfg.Video.UploadDir, cfg.Storage.SigningKey)
+	privacyService := service.NewPrivacyService(privacyRepo, userRepo, fileStorage, mailer, auditService, service.PrivacyServiceOptions{
+		ExportTTL:   cfg.Privacy.ExportTTL,
+		FrontendURL: strings.TrimRight(cfg.App.FrontendURL, "/"),
+	})
+	userImportService := service.NewUserImportService(userImportRepo, organizationRepo, userRepo, fileStorage, mailer, auditService, service.UserImportServiceOptions{
+		MaxRows:       cfg.Import.MaxRows,
+		InvitationTTL: cfg.Import.InvitationTTL,
+		FrontendURL:   strings.TrimRight(cfg.App.FrontendURL, "/"),
+	})
+
+	// Background jobs
+	worker := job.NewWorker(time.Hour,
+		job.NewOrganizationPurgeJob(organizationService, cfg.ExportDir),
+		job.NewSoftDeletePurgeJob(retentionService, cfg.Retention.SoftDeletePeriod),
+	)
+	worker.Start()
+
+	// Videos are picked up soon after their upload completes
+	videoWorker := job.NewWorker(30*time.Second,
+		job.NewVideoProcessingJob(videoService),
+	)
+	videoWorker.Start()
+
+	// Data exports and user imports are usually done within a minute of the request
+	exportWorker := job.NewWorker(time.Minute,
+		job.NewUserDataExportJob(privacyService),
+		job.NewUserImportJob(userImportService),
+	)
+	exportWorker.Start()
 
 	// Initialize Controllers
 	userController := controller.NewUserController(userService)
 	organizationController := controller.NewOrganizationController(organizationService)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	auditController := controller.NewAuditController(auditService)
 	organizationAdminController := controller.NewOrganizationAdminController(organizationAdminService)
 	organizationTotorController := controller.NewOrganizationTutorController(organizationTutorService)
 	organizationBrandingController := controller.NewOrganizationBrandingController(organizationBrandingService)
 	organizationBillingController := controller.NewOrganizationBillingController(organizationBillingService)
+	assetController := controller.NewAssetController(assetService, fileStorage)
+	courseController := controller.NewCourseController(courseService)
+	videoController := controller.NewVideoController(videoService)
+	privacyController := controller.NewPrivacyController(privacyService)
+	userImportController := controller.NewUserImportController(userImportService)
+	healthController := controller.NewHealthController(cfg.Metrics.Token, healthCheckers...)
 	// Setup Gin HTTP Server
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.Tracing(), middleware.RequestLogger(appLogger), middleware.Recovery(), middleware.Metrics(), middleware.ErrorHandler())
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
+		AllowOrigins:     cfg.CORS.AllowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "HEAD", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Request-ID", "traceparent", "tracestate", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "traceparent", "Location", "Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"},
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
+	// Integrations call the endpoints listed in routes.APIKeyRoutes with an organization's API key
+	r.Use(middleware.APIKeyAuth(apiKeyService, routes.APIKeyRoutes))
+
+	// Unverified users may sign in but not enroll or create content, unless verification is off
+	requireVerified := middleware.RequireVerifiedEmail(userRepo, cfg.Auth.EmailVerification != config.VerificationOff)
+	// Users whose role or organization requires MFA must enroll before managing anything
+	requireMFA := middleware.RequireMFA(mfaRepo)
 
 	// Register API Routes
-	routes.RegisterUserRoutes(r, userController, tokenRepo)
-	routes.RegisterOrganizationRoutes(r, organizationController, tokenRepo)
-	routes.RegisterOrganizationAdminRoutes(r, organizationAdminController, tokenRepo)
-	routes.RegisterOrganizationTutorRoutes(r, organizationTotorController, tokenRepo)
-	routes.RegisterOrganizationBrandingRoutes(r, organizationBrandingController, tokenRepo)
-	routes.RegisterOrganizationBillingRoutes(r, organizationBillingController, tokenRepo)
-
-	// Start Gin server (blocks here, keeps container alive)
-	if err := r.Run(fmt.Sprintf(":%s", appCfg.App.Port)); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	routes.RegisterHealthRoutes(r, healthController)
+	routes.RegisterUserRoutes(r, userController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterPrivacyRoutes(r, privacyController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterSSORoutes(r, userController)
+	routes.RegisterOrganizationRoutes(r, organizationController, tokenRepo, userRepo, requireVerified, requireMFA)
+	routes.RegisterAPIKeyRoutes(r, apiKeyController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterAuditRoutes(r, auditController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterUserImportRoutes(r, userImportController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterOrganizationAdminRoutes(r, organizationAdminController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterOrganizationTutorRoutes(r, organizationTotorController, tokenRepo, userRepo)
+	routes.RegisterOrganizationBrandingRoutes(r, organizationBrandingController, tokenRepo, userRepo)
+	routes.RegisterOrganizationBillingRoutes(r, organizationBillingController, tokenRepo, userRepo, requireMFA)
+	routes.RegisterAssetRoutes(r, assetController, tokenRepo, userRepo)
+	routes.RegisterCourseRoutes(r, courseController, tokenRepo, userRepo, requireVerified)
+	routes.RegisterVideoRoutes(r, videoController, tokenRepo)
+
+	serverCfg := server.Config{
+		Addr:              fmt.Sprintf(":%s", cfg.App.Port),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ShutdownTimeout:   cfg.Server.ShutdownTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+		TLSCertFile:       cfg.Server.TLSCertFile,
+		TLSKeyFile:        cfg.Server.TLSKeyFile,
+	}
+
+	// Serve until SIGINT/SIGTERM, then drain in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	serveErr := server.Run(ctx, server.New(serverCfg, r), serverCfg)
+	stop()
+	if serveErr != nil {
+		slog.Error("HTTP server stopped with an error", "error", serveErr)
+	}
+
+	// Shut down in dependency order: nothing may use the DB once it is closed
+	userService.Wait()
+	worker.Stop()
+	videoWorker.Stop()
+	exportWorker.Stop()
+
+	if redisStore != nil {
+		if err := redisStore.Close(); err != nil {
+			log.Printf("Error closing Redis: %v", err)
+		}
+	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		log.Printf("Error closing DB: %v", err)
+	}
+
+	slog.Info("Server stopped")
+	if serveErr != nil {
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController manages the API keys of organizations
+type APIKeyController struct {
+	APIKeyService service.APIKeyService
+}
+
+// NewAPIKeyController creates a new APIKeyController instance
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{APIKeyService: apiKeyService}
+}
+
+// CreateAPIKey issues a key for an organization. The key itself is only
+// part of this response.
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	var req struct {
+		Name      string                  `json:"name" binding:"required,max=100"`
+		Scopes    []model.AdminPermission `json:"scopes" binding:"required,min=1"`
+		ExpiresAt *time.Time              `json:"expires_at"`
+	}
+
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	userID, _ := session(ctx)
+	key, err := c.APIKeyService.CreateAPIKey(ctx.Request.Context(), &model.APIKey{
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Scopes:         req.Scopes,
+		ExpiresAt:      req.ExpiresAt,
+		CreatedBy:      userID,
+	})
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys lists the keys of an organization without their secrets
+func (c *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	keys, err := c.APIKeyService.ListAPIKeys(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey disables a key of an organization
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+	keyID, ok := paramUUID(ctx, "keyId", "API key")
+	if !ok {
+		return
+	}
+
+	if err := c.APIKeyService.RevokeAPIKey(ctx.Request.Context(), orgID, keyID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
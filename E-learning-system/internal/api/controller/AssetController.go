@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/storage"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// AssetController handles file uploads and downloads
+type AssetController struct {
+	AssetService service.AssetService
+	Storage      storage.Storage
+}
+
+// NewAssetController creates a new AssetController instance
+func NewAssetController(assetService service.AssetService, store storage.Storage) *AssetController {
+	return &AssetController{AssetService: assetService, Storage: store}
+}
+
+// UploadAsset handles a multipart upload of a single "file" field
+func (c *AssetController) UploadAsset(ctx *gin.Context) {
+	purpose := model.AssetPurpose(ctx.DefaultPostForm("purpose", string(model.AssetPurposeCourseMaterial)))
+
+	var orgID *uuid.UUID
+	if raw := ctx.PostForm("organization_id"); raw != "" {
+		id, err := uuid.FromString(raw)
+		if err != nil {
+			fail(ctx, apperr.Validation("invalid_id", "invalid organization ID",
+				apperr.FieldError{Field: "organization_id", Message: "must be a UUID"}))
+			return
+		}
+		orgID = &id
+	}
+
+	file, fileName, ok := openUpload(ctx, c.AssetService.MaxUploadSize(purpose))
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	userID, _ := ctx.Get("userID")
+	ownerID, _ := userID.(uuid.UUID)
+
+	asset, err := c.AssetService.Upload(ctx.Request.Context(), ownerID, orgID, purpose, fileName, file)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, asset)
+}
+
+// GetAsset returns asset metadata with short-lived download URLs
+func (c *AssetController) GetAsset(ctx *gin.Context) {
+	assetID, ok := paramUUID(ctx, "id", "asset")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	asset, err := c.AssetService.GetAsset(ctx.Request.Context(), userID, assetID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	urls, err := c.AssetService.SignedURLs(asset)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"asset": asset, "urls": urls})
+}
+
+// GetAssetContent redirects to a signed URL of a public asset (logos only).
+// The optional "size" query parameter selects a resized variant.
+func (c *AssetController) GetAssetContent(ctx *gin.Context) {
+	assetID, ok := paramUUID(ctx, "id", "asset")
+	if !ok {
+		return
+	}
+
+	asset, err := c.AssetService.GetLogo(ctx.Request.Context(), assetID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	url, err := c.AssetService.SignedURL(asset, ctx.Query("size"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	// The redirect target expires, so only cache the redirect briefly
+	ctx.Header("Cache-Control", "public, max-age=60")
+	ctx.Redirect(http.StatusFound, url)
+}
+
+// DeleteAsset removes an asset and its stored files
+func (c *AssetController) DeleteAsset(ctx *gin.Context) {
+	assetID, ok := paramUUID(ctx, "id", "asset")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	if err := c.AssetService.DeleteAsset(ctx.Request.Context(), userID, assetID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "asset deleted successfully"})
+}
+
+// RestoreAsset brings back a soft-deleted asset
+func (c *AssetController) RestoreAsset(ctx *gin.Context) {
+	assetID, ok := paramUUID(ctx, "id", "asset")
+	if !ok {
+		return
+	}
+
+	asset, err := c.AssetService.RestoreAsset(ctx.Request.Context(), assetID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, asset)
+}
+
+// ServeLocalFile serves files of the local storage driver behind signed URLs
+func (c *AssetController) ServeLocalFile(ctx *gin.Context) {
+	local, ok := c.Storage.(*storage.LocalStorage)
+	if !ok {
+		fail(ctx, storage.ErrNotFound)
+		return
+	}
+
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+	if !local.Verify(key, ctx.Query("expires"), ctx.Query("signature")) {
+		fail(ctx, apperr.Forbidden("invalid_signature", "invalid or expired signature"))
+		return
+	}
+
+	f, err := local.Get(key)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Security-Policy", "default-src 'none'")
+	ctx.DataFromReader(http.StatusOK, -1, contentType, f, nil)
+}
+
+// openUpload opens the "file" form field, enforcing maxSize on the request body.
+// It records the error and returns ok=false on failure.
+func openUpload(ctx *gin.Context, maxSize int64) (file io.ReadCloser, fileName string, ok bool) {
+	if maxSize <= 0 {
+		fail(ctx, apperr.Validation("invalid_purpose", "invalid upload purpose",
+			apperr.FieldError{Field: "purpose", Message: "is not a known upload purpose"}))
+		return nil, "", false
+	}
+
+	// Leave room for the multipart envelope around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+1<<20)
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(ctx, service.ErrAssetTooLarge)
+			return nil, "", false
+		}
+		fail(ctx, apperr.Validation("file_required", "file is required",
+			apperr.FieldError{Field: "file", Message: "is required"}))
+		return nil, "", false
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		fail(ctx, apperr.Validation("malformed_upload", "failed to read upload").Wrap(err))
+		return nil, "", false
+	}
+
+	return f, fh.Filename, true
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController lets platform admins review administrative changes
+type AuditController struct {
+	AuditService service.AuditService
+}
+
+// NewAuditController creates a new AuditController instance
+func NewAuditController(auditService service.AuditService) *AuditController {
+	return &AuditController{AuditService: auditService}
+}
+
+// ListAuditEvents lists audit events page by page, newest first by default
+func (c *AuditController) ListAuditEvents(ctx *gin.Context) {
+	page, ok := pageRequest(ctx, "-created_at", "created_at")
+	if !ok {
+		return
+	}
+	filter, ok := auditFilter(ctx)
+	if !ok {
+		return
+	}
+
+	events, err := c.AuditService.ListAuditEvents(ctx.Request.Context(), filter, page)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	writePage(ctx, events)
+}
+
+// ExportAuditEvents downloads every audit event matching the filters as CSV
+func (c *AuditController) ExportAuditEvents(ctx *gin.Context) {
+	filter, ok := auditFilter(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s.csv", time.Now().UTC().Format("20060102-150405")))
+	if err := c.AuditService.ExportAuditEvents(ctx.Request.Context(), filter, ctx.Writer); err != nil {
+		// Once rows are written the status is sent; the error can only be logged
+		if !ctx.Writer.Written() {
+			ctx.Writer.Header().Del("Content-Type")
+			ctx.Writer.Header().Del("Content-Disposition")
+			fail(ctx, err)
+			return
+		}
+		_ = ctx.Error(err)
+	}
+}
+
+// auditFilter reads the filters shared by listing and exporting: organization_id,
+// actor_id, action and the created_at range from (inclusive) to (exclusive)
+func auditFilter(ctx *gin.Context) (model.AuditEventFilter, bool) {
+	var filter model.AuditEventFilter
+	var ok bool
+
+	if filter.OrganizationID, ok = queryUUID(ctx, "organization_id"); !ok {
+		return filter, false
+	}
+	if filter.ActorID, ok = queryUUID(ctx, "actor_id"); !ok {
+		return filter, false
+	}
+	if filter.From, ok = queryTime(ctx, "from"); !ok {
+		return filter, false
+	}
+	if filter.To, ok = queryTime(ctx, "to"); !ok {
+		return filter, false
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		fail(ctx, invalidQuery("to", "must be after from"))
+		return filter, false
+	}
+	filter.Action = ctx.Query("action")
+	return filter, true
+}
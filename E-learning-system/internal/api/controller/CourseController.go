@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/metrics"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// CourseController handles courses, lessons and enrollments
+type CourseController struct {
+	CourseService service.CourseService
+}
+
+// NewCourseController creates a new CourseController instance
+func NewCourseController(courseService service.CourseService) *CourseController {
+	return &CourseController{CourseService: courseService}
+}
+
+// CreateCourse handles the creation of a new course by the current user
+func (c *CourseController) CreateCourse(ctx *gin.Context) {
+	var course model.Course
+
+	if !bindJSON(ctx, &course) {
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	course.CreatedBy, _ = userID.(uuid.UUID)
+
+	createdCourse, err := c.CourseService.CreateCourse(ctx.Request.Context(), &course)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdCourse)
+}
+
+// GetCourseByID retrieves a course by its ID
+func (c *CourseController) GetCourseByID(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	course, err := c.CourseService.GetCourseByID(ctx.Request.Context(), courseID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, course)
+}
+
+// DeleteCourse soft-deletes a course and its lessons
+func (c *CourseController) DeleteCourse(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	if err := c.CourseService.DeleteCourse(ctx.Request.Context(), userID, courseID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "course deleted successfully"})
+}
+
+// RestoreCourse brings back a soft-deleted course
+func (c *CourseController) RestoreCourse(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	course, err := c.CourseService.RestoreCourse(ctx.Request.Context(), courseID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, course)
+}
+
+// GetAllCourses lists courses page by page, optionally of one organization
+func (c *CourseController) GetAllCourses(ctx *gin.Context) {
+	page, ok := pageRequest(ctx, "-created_at", "created_at", "title")
+	if !ok {
+		return
+	}
+	orgID, ok := queryUUID(ctx, "organization_id")
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.CourseFilter{OrganizationID: orgID, IncludeDeleted: deleted}
+	courses, err := c.CourseService.ListCourses(ctx.Request.Context(), filter, page)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	writePage(ctx, courses)
+}
+
+// CreateLesson adds a lesson to a course
+func (c *CourseController) CreateLesson(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	var lesson model.Lesson
+	if !bindJSON(ctx, &lesson) {
+		return
+	}
+	lesson.CourseID = courseID
+
+	userID, _ := session(ctx)
+	createdLesson, err := c.CourseService.CreateLesson(ctx.Request.Context(), userID, &lesson)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdLesson)
+}
+
+// GetLessonsByCourse lists the lessons of a course
+func (c *CourseController) GetLessonsByCourse(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	lessons, err := c.CourseService.GetLessonsByCourse(ctx.Request.Context(), courseID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lessons)
+}
+
+// GetLessonByID retrieves a lesson, including its video processing status
+func (c *CourseController) GetLessonByID(ctx *gin.Context) {
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	lesson, err := c.CourseService.GetLessonByID(ctx.Request.Context(), lessonID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lesson)
+}
+
+// DeleteLesson soft-deletes a lesson
+func (c *CourseController) DeleteLesson(ctx *gin.Context) {
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	if err := c.CourseService.DeleteLesson(ctx.Request.Context(), userID, lessonID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "lesson deleted successfully"})
+}
+
+// RestoreLesson brings back a soft-deleted lesson
+func (c *CourseController) RestoreLesson(ctx *gin.Context) {
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	lesson, err := c.CourseService.RestoreLesson(ctx.Request.Context(), lessonID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lesson)
+}
+
+// Enroll enrolls the current user in a course
+func (c *CourseController) Enroll(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	uid, _ := userID.(uuid.UUID)
+
+	enrollment, err := c.CourseService.Enroll(ctx.Request.Context(), uid, courseID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	if enrollment.Status == model.EnrollmentPending {
+		ctx.JSON(http.StatusAccepted, enrollment)
+		return
+	}
+
+	metrics.Enrollments.Inc()
+	ctx.JSON(http.StatusCreated, enrollment)
+}
+
+// GetCourseEnrollments lists the enrollments of a course, pending requests included
+func (c *CourseController) GetCourseEnrollments(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	enrollments, err := c.CourseService.GetEnrollmentsByCourse(ctx.Request.Context(), userID, courseID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	if enrollments == nil {
+		enrollments = []*model.Enrollment{}
+	}
+
+	ctx.JSON(http.StatusOK, enrollments)
+}
+
+// ApproveEnrollment admits a student who asked to join a course
+func (c *CourseController) ApproveEnrollment(ctx *gin.Context) {
+	courseID, ok := paramUUID(ctx, "id", "course")
+	if !ok {
+		return
+	}
+	enrollmentID, ok := paramUUID(ctx, "enrollmentID", "enrollment")
+	if !ok {
+		return
+	}
+
+	userID, _ := session(ctx)
+	if err := c.CourseService.ApproveEnrollment(ctx.Request.Context(), userID, courseID, enrollmentID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	metrics.Enrollments.Inc()
+	ctx.JSON(http.StatusOK, gin.H{"message": "enrollment approved successfully"})
+}
+
+// GetMyEnrollments lists the courses the current user is enrolled in
+func (c *CourseController) GetMyEnrollments(ctx *gin.Context) {
+	userID, _ := ctx.Get("userID")
+	uid, _ := userID.(uuid.UUID)
+
+	enrollments, err := c.CourseService.GetEnrollmentsByUser(ctx.Request.Context(), uid)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollments)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/health"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// readinessTimeout bounds each dependency check
+const readinessTimeout = 2 * time.Second
+
+// HealthController serves liveness, readiness and metrics endpoints
+type HealthController struct {
+	Checkers     []health.Checker
+	MetricsToken string
+}
+
+// NewHealthController creates a new HealthController instance. When
+// metricsToken is set, /metrics requires it as a bearer token.
+func NewHealthController(metricsToken string, checkers ...health.Checker) *HealthController {
+	return &HealthController{Checkers: checkers, MetricsToken: metricsToken}
+}
+
+// Liveness reports that the process is up and serving requests
+func (c *HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness reports whether every dependency is reachable
+func (c *HealthController) Readiness(ctx *gin.Context) {
+	results, healthy := health.Run(ctx.Request.Context(), readinessTimeout, c.Checkers...)
+
+	ctx.Header("Cache-Control", "no-store")
+	if !healthy {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": results})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok", "checks": results})
+}
+
+// Metrics exposes Prometheus metrics
+func (c *HealthController) Metrics() gin.HandlerFunc {
+	handler := promhttp.Handler()
+	return func(ctx *gin.Context) {
+		if c.MetricsToken != "" {
+			want := "Bearer " + c.MetricsToken
+			if subtle.ConstantTimeCompare([]byte(ctx.GetHeader("Authorization")), []byte(want)) != 1 {
+				fail(ctx, apperr.Unauthenticated("metrics_token_required", "metrics token required"))
+				return
+			}
+		}
+		handler.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
@@ -3,11 +3,9 @@ package controller
 import (
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gofrs/uuid"
 )
 
 // OrganizationAdminController defines the controller with its service
@@ -24,14 +22,13 @@ func NewOrganizationAdminController(adminService service.OrganizationAdminServic
 func (c *OrganizationAdminController) CreateOrganizationAdmin(ctx *gin.Context) {
 	var admin model.OrganizationAdmin
 
-	if err := ctx.ShouldBindJSON(&admin); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &admin) {
 		return
 	}
 
-	createdAdmin, err := c.OrganizationAdminService.CreateAdmin(&admin)
+	createdAdmin, err := c.OrganizationAdminService.CreateAdmin(ctx.Request.Context(), &admin)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
@@ -42,22 +39,19 @@ func (c *OrganizationAdminController) CreateOrganizationAdmin(ctx *gin.Context)
 func (c *OrganizationAdminController) UpdateOrganizationAdmin(ctx *gin.Context) {
 	var admin model.OrganizationAdmin
 
-	adminIDParam := ctx.Param("id")
-	adminID, err := uuid.FromString(adminIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin ID"})
+	adminID, ok := paramUUID(ctx, "id", "admin")
+	if !ok {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&admin); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &admin) {
 		return
 	}
 
 	admin.ID = adminID
 
-	if err := c.OrganizationAdminService.UpdateAdmin(&admin); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationAdminService.UpdateAdmin(ctx.Request.Context(), &admin); err != nil {
+		fail(ctx, err)
 		return
 	}
 
@@ -66,51 +60,73 @@ func (c *OrganizationAdminController) UpdateOrganizationAdmin(ctx *gin.Context)
 
 // DeleteAdmin handles the soft delete of an admin
 func (c *OrganizationAdminController) DeleteOrganizationAdmin(ctx *gin.Context) {
-	adminIDParam := ctx.Param("id")
-	adminID, err := uuid.FromString(adminIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin ID"})
+	adminID, ok := paramUUID(ctx, "id", "admin")
+	if !ok {
 		return
 	}
 
-	if err := c.OrganizationAdminService.DeleteAdmin(adminID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationAdminService.DeleteAdmin(ctx.Request.Context(), adminID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "admin deleted successfully"})
 }
 
+// RestoreOrganizationAdmin brings back a soft-deleted admin
+func (c *OrganizationAdminController) RestoreOrganizationAdmin(ctx *gin.Context) {
+	adminID, ok := paramUUID(ctx, "id", "admin")
+	if !ok {
+		return
+	}
+
+	admin, err := c.OrganizationAdminService.RestoreAdmin(ctx.Request.Context(), adminID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, admin)
+}
+
 // GetAdminByID retrieves a single admin by its ID
 func (c *OrganizationAdminController) GetOrganizationAdminByID(ctx *gin.Context) {
-	adminIDParam := ctx.Param("id")
-	adminID, err := uuid.FromString(adminIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin ID format"})
+	adminID, ok := paramUUID(ctx, "id", "admin")
+	if !ok {
 		return
 	}
 
-	admin, err := c.OrganizationAdminService.GetAdminByID(adminID)
+	admin, err := c.OrganizationAdminService.GetAdminByID(ctx.Request.Context(), adminID)
 	if err != nil {
-		if err.Error() == "admin not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			log.Printf("Unexpected error: %v", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		}
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, admin)
 }
 
-// GetAllAdmins retrieves all organization admins
+// GetAllOrganizationAdmins lists organization admins page by page, filtered by organization and role
 func (c *OrganizationAdminController) GetAllOrganizationAdmins(ctx *gin.Context) {
-	admins, err := c.OrganizationAdminService.GetAllAdmins()
+	page, ok := pageRequest(ctx, "-created_at", "created_at")
+	if !ok {
+		return
+	}
+	orgID, ok := queryUUID(ctx, "organization_id")
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.OrganizationAdminFilter{OrganizationID: orgID, Role: ctx.Query("role"), IncludeDeleted: deleted}
+	admins, err := c.OrganizationAdminService.ListAdmins(ctx.Request.Context(), filter, page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, admins)
+	writePage(ctx, admins)
 }
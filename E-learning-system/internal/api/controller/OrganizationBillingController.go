@@ -3,11 +3,9 @@ package controller
 import (
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gofrs/uuid"
 )
 
 // OrganizationBillingController defines the controller with its service
@@ -24,14 +22,13 @@ func NewOrganizationBillingController(billingService service.OrganizationBilling
 func (c *OrganizationBillingController) CreateOrganizationBilling(ctx *gin.Context) {
 	var billing model.OrganizationBilling
 
-	if err := ctx.ShouldBindJSON(&billing); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &billing) {
 		return
 	}
 
-	createdBilling, err := c.OrganizationBillingService.CreateBilling(&billing)
+	createdBilling, err := c.OrganizationBillingService.CreateBilling(ctx.Request.Context(), &billing)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
@@ -42,22 +39,19 @@ func (c *OrganizationBillingController) CreateOrganizationBilling(ctx *gin.Conte
 func (c *OrganizationBillingController) UpdateOrganizationBilling(ctx *gin.Context) {
 	var billing model.OrganizationBilling
 
-	billingIDParam := ctx.Param("id")
-	billingID, err := uuid.FromString(billingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid billing ID"})
+	billingID, ok := paramUUID(ctx, "id", "billing")
+	if !ok {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&billing); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &billing) {
 		return
 	}
 
 	billing.ID = billingID
 
-	if err := c.OrganizationBillingService.UpdateBilling(&billing); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationBillingService.UpdateBilling(ctx.Request.Context(), &billing); err != nil {
+		fail(ctx, err)
 		return
 	}
 
@@ -66,51 +60,73 @@ func (c *OrganizationBillingController) UpdateOrganizationBilling(ctx *gin.Conte
 
 // DeleteBilling handles the soft delete of a billing record
 func (c *OrganizationBillingController) DeleteOrganizationBilling(ctx *gin.Context) {
-	billingIDParam := ctx.Param("id")
-	billingID, err := uuid.FromString(billingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid billing ID"})
+	billingID, ok := paramUUID(ctx, "id", "billing")
+	if !ok {
 		return
 	}
 
-	if err := c.OrganizationBillingService.DeleteBilling(billingID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationBillingService.DeleteBilling(ctx.Request.Context(), billingID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "billing deleted successfully"})
 }
 
+// RestoreOrganizationBilling brings back a soft-deleted billing
+func (c *OrganizationBillingController) RestoreOrganizationBilling(ctx *gin.Context) {
+	billingID, ok := paramUUID(ctx, "id", "billing")
+	if !ok {
+		return
+	}
+
+	billing, err := c.OrganizationBillingService.RestoreBilling(ctx.Request.Context(), billingID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, billing)
+}
+
 // GetBillingByID retrieves a single billing record by its ID
 func (c *OrganizationBillingController) GetOrganizationBillingByID(ctx *gin.Context) {
-	billingIDParam := ctx.Param("id")
-	billingID, err := uuid.FromString(billingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid billing ID format"})
+	billingID, ok := paramUUID(ctx, "id", "billing")
+	if !ok {
 		return
 	}
 
-	billing, err := c.OrganizationBillingService.GetBillingByID(billingID)
+	billing, err := c.OrganizationBillingService.GetBillingByID(ctx.Request.Context(), billingID)
 	if err != nil {
-		if err.Error() == "organization billing not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			log.Printf("Unexpected error: %v", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		}
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, billing)
 }
 
-// GetAllBillings retrieves all organization billing records
+// GetAllOrganizationBillings lists billing records page by page, filtered by organization and plan
 func (c *OrganizationBillingController) GetAllOrganizationBillings(ctx *gin.Context) {
-	billings, err := c.OrganizationBillingService.GetAllBillings()
+	page, ok := pageRequest(ctx, "-created_at", "created_at", "next_billing_at")
+	if !ok {
+		return
+	}
+	orgID, ok := queryUUID(ctx, "organization_id")
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.OrganizationBillingFilter{OrganizationID: orgID, Plan: ctx.Query("plan"), IncludeDeleted: deleted}
+	billings, err := c.OrganizationBillingService.ListBillings(ctx.Request.Context(), filter, page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, billings)
+	writePage(ctx, billings)
 }
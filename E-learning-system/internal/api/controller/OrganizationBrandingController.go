@@ -1,10 +1,13 @@
 package controller
 
 import (
+	"crypto/sha256"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
-	"log"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -24,14 +27,13 @@ func NewOrganizationBrandingController(brandingService service.OrganizationBrand
 func (c *OrganizationBrandingController) CreateOrganizationBranding(ctx *gin.Context) {
 	var branding model.OrganizationBranding
 
-	if err := ctx.ShouldBindJSON(&branding); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &branding) {
 		return
 	}
 
-	createdBranding, err := c.OrganizationBrandingService.CreateBranding(&branding)
+	createdBranding, err := c.OrganizationBrandingService.CreateBranding(ctx.Request.Context(), &branding)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
@@ -42,22 +44,19 @@ func (c *OrganizationBrandingController) CreateOrganizationBranding(ctx *gin.Con
 func (c *OrganizationBrandingController) UpdateOrganizationBranding(ctx *gin.Context) {
 	var branding model.OrganizationBranding
 
-	brandingIDParam := ctx.Param("id")
-	brandingID, err := uuid.FromString(brandingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid branding ID"})
+	brandingID, ok := paramUUID(ctx, "id", "branding")
+	if !ok {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&branding); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &branding) {
 		return
 	}
 
 	branding.ID = brandingID
 
-	if err := c.OrganizationBrandingService.UpdateBranding(&branding); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationBrandingService.UpdateBranding(ctx.Request.Context(), &branding); err != nil {
+		fail(ctx, err)
 		return
 	}
 
@@ -66,51 +65,150 @@ func (c *OrganizationBrandingController) UpdateOrganizationBranding(ctx *gin.Con
 
 // DeleteBranding handles the soft delete of a branding
 func (c *OrganizationBrandingController) DeleteOrganizationBranding(ctx *gin.Context) {
-	brandingIDParam := ctx.Param("id")
-	brandingID, err := uuid.FromString(brandingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid branding ID"})
+	brandingID, ok := paramUUID(ctx, "id", "branding")
+	if !ok {
 		return
 	}
 
-	if err := c.OrganizationBrandingService.DeleteBranding(brandingID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationBrandingService.DeleteBranding(ctx.Request.Context(), brandingID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "branding deleted successfully"})
 }
 
+// RestoreOrganizationBranding brings back a soft-deleted branding
+func (c *OrganizationBrandingController) RestoreOrganizationBranding(ctx *gin.Context) {
+	brandingID, ok := paramUUID(ctx, "id", "branding")
+	if !ok {
+		return
+	}
+
+	branding, err := c.OrganizationBrandingService.RestoreBranding(ctx.Request.Context(), brandingID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, branding)
+}
+
 // GetBrandingByID retrieves a single branding by its ID
 func (c *OrganizationBrandingController) GetOrganizationBrandingByID(ctx *gin.Context) {
-	brandingIDParam := ctx.Param("id")
-	brandingID, err := uuid.FromString(brandingIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid branding ID format"})
+	brandingID, ok := paramUUID(ctx, "id", "branding")
+	if !ok {
 		return
 	}
 
-	branding, err := c.OrganizationBrandingService.GetBrandingByID(brandingID)
+	branding, err := c.OrganizationBrandingService.GetBrandingByID(ctx.Request.Context(), brandingID)
 	if err != nil {
-		if err.Error() == "branding not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			log.Printf("Unexpected error: %v", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		}
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, branding)
 }
 
-// GetAllBrandings retrieves all organization brandings
+// GetAllOrganizationBrandings lists brandings page by page, filtered by organization and theme
 func (c *OrganizationBrandingController) GetAllOrganizationBrandings(ctx *gin.Context) {
-	brandings, err := c.OrganizationBrandingService.GetAllBrandings()
+	page, ok := pageRequest(ctx, "-created_at", "created_at", "updated_at")
+	if !ok {
+		return
+	}
+	orgID, ok := queryUUID(ctx, "organization_id")
+	if !ok {
+		return
+	}
+	theme, ok := queryOneOf(ctx, "theme", model.ThemeLight, model.ThemeDark, model.ThemeCustom)
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.OrganizationBrandingFilter{OrganizationID: orgID, Theme: theme, IncludeDeleted: deleted}
+	brandings, err := c.OrganizationBrandingService.ListBrandings(ctx.Request.Context(), filter, page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, brandings)
+	writePage(ctx, brandings)
+}
+
+// GetBrandingManifest serves the public branding manifest of an organization by domain
+func (c *OrganizationBrandingController) GetBrandingManifest(ctx *gin.Context) {
+	manifest, err := c.OrganizationBrandingService.GetBrandingManifest(ctx.Request.Context(), ctx.Param("domain"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	serveCacheable(ctx, "application/json; charset=utf-8", body)
+}
+
+// GetThemeStylesheet serves the generated CSS variables stylesheet of an organization by domain
+func (c *OrganizationBrandingController) GetThemeStylesheet(ctx *gin.Context) {
+	manifest, err := c.OrganizationBrandingService.GetBrandingManifest(ctx.Request.Context(), ctx.Param("domain"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	css := c.OrganizationBrandingService.RenderThemeCSS(manifest)
+	serveCacheable(ctx, "text/css; charset=utf-8", []byte(css))
+}
+
+// serveCacheable writes a public, ETag-validated response, answering 304 when the client copy is current
+func serveCacheable(ctx *gin.Context, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age=300, must-revalidate")
+
+	for _, candidate := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	ctx.Data(http.StatusOK, contentType, body)
+}
+
+// UploadOrganizationLogo handles a multipart logo upload for a branding
+func (c *OrganizationBrandingController) UploadOrganizationLogo(ctx *gin.Context) {
+	brandingID, ok := paramUUID(ctx, "id", "branding")
+	if !ok {
+		return
+	}
+
+	file, fileName, ok := openUpload(ctx, service.MaxLogoSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	userID, _ := ctx.Get("userID")
+	ownerID, _ := userID.(uuid.UUID)
+
+	branding, err := c.OrganizationBrandingService.UploadLogo(ctx.Request.Context(), brandingID, ownerID, fileName, file)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, branding)
 }
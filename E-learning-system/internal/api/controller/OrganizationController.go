@@ -3,6 +3,7 @@ package controller
 import (
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,14 +24,13 @@ func NewOrganizationController(orgService service.OrganizationService) *Organiza
 func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
 	var org model.Organization
 
-	if err := ctx.ShouldBindJSON(&org); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &org) {
 		return
 	}
 
-	createdOrg, err := c.OrganizationService.CreateOrganization(&org)
+	createdOrg, err := c.OrganizationService.CreateOrganization(ctx.Request.Context(), &org)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
@@ -41,22 +41,19 @@ func (c *OrganizationController) CreateOrganization(ctx *gin.Context) {
 func (c *OrganizationController) UpdateOrganization(ctx *gin.Context) {
 	var org model.Organization
 
-	orgIDParam := ctx.Param("id")
-	orgID, err := uuid.FromString(orgIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&org); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &org) {
 		return
 	}
 
 	org.ID = orgID
 
-	if err := c.OrganizationService.UpdateOrganization(&org); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationService.UpdateOrganization(ctx.Request.Context(), &org); err != nil {
+		fail(ctx, err)
 		return
 	}
 
@@ -65,46 +62,327 @@ func (c *OrganizationController) UpdateOrganization(ctx *gin.Context) {
 
 // DeleteOrganization handles the soft delete of an organization
 func (c *OrganizationController) DeleteOrganization(ctx *gin.Context) {
-	orgIDParam := ctx.Param("id")
-	orgID, err := uuid.FromString(orgIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
 		return
 	}
 
-	if err := c.OrganizationService.DeleteOrganization(orgID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	actorID, _ := session(ctx)
+	if err := c.OrganizationService.DeleteOrganization(ctx.Request.Context(), orgID, actorID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "organization deleted successfully"})
 }
 
+// RestoreOrganization cancels the deletion of an organization
+func (c *OrganizationController) RestoreOrganization(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	actorID, _ := session(ctx)
+	org, err := c.OrganizationService.RestoreOrganization(ctx.Request.Context(), orgID, actorID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, org)
+}
+
 // GetOrganizationByID retrieves a single organization by its ID
 func (c *OrganizationController) GetOrganizationByID(ctx *gin.Context) {
-	orgIDParam := ctx.Param("id")
-	orgID, err := uuid.FromString(orgIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
 		return
 	}
 
-	org, err := c.OrganizationService.GetOrganizationByID(orgID)
+	org, err := c.OrganizationService.GetOrganizationByID(ctx.Request.Context(), orgID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, org)
 }
 
-// GetAllOrganizations retrieves all organizations
+// GetAllOrganizations lists organizations page by page, filtered by status and plan.
+// Organizations pending deletion are only listed with include_deleted=true
+// or asked for by status.
 func (c *OrganizationController) GetAllOrganizations(ctx *gin.Context) {
-	orgs, err := c.OrganizationService.GetAllOrganizations()
+	page, ok := pageRequest(ctx, "-created_at", "created_at", "name")
+	if !ok {
+		return
+	}
+
+	status := model.OrganizationStatus(ctx.Query("status"))
+	if status != "" && !status.IsValid() {
+		fail(ctx, invalidQuery("status", "is not a known organization status"))
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.OrganizationFilter{Status: status, Plan: ctx.Query("plan"), IncludeDeleted: deleted}
+	orgs, err := c.OrganizationService.ListOrganizations(ctx.Request.Context(), filter, page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
+		return
+	}
+
+	writePage(ctx, orgs)
+}
+
+// ChangeOrganizationStatus moves an organization to a new lifecycle state
+func (c *OrganizationController) ChangeOrganizationStatus(ctx *gin.Context) {
+	var req struct {
+		Status model.OrganizationStatus `json:"status" binding:"required"`
+		Reason string                   `json:"reason"`
+	}
+
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	actorID, _ := ctx.Get("userID")
+	actor, _ := actorID.(uuid.UUID)
+
+	org, err := c.OrganizationService.ChangeOrganizationStatus(ctx.Request.Context(), orgID, req.Status, req.Reason, actor)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, org)
+}
+
+// GetOrganizationStatusHistory returns the lifecycle transitions of an organization
+func (c *OrganizationController) GetOrganizationStatusHistory(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	events, err := c.OrganizationService.GetOrganizationStatusHistory(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
+
+// ExportOrganization downloads a JSON export of all data held for an organization
+func (c *OrganizationController) ExportOrganization(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	data, err := c.OrganizationService.ExportOrganization(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=organization-%s.json", orgID))
+	ctx.Data(http.StatusOK, "application/json", data)
+}
+
+// GetOrganizationPolicy returns the member policy of an organization
+func (c *OrganizationController) GetOrganizationPolicy(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	policy, err := c.OrganizationService.GetOrganizationPolicy(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, policy)
+}
+
+// UpdateOrganizationPolicy replaces the member policy of an organization.
+// Leaving out password_max_age_days turns password expiry off.
+func (c *OrganizationController) UpdateOrganizationPolicy(ctx *gin.Context) {
+	var req struct {
+		RequireVerifiedEmail *bool `json:"require_verified_email" binding:"required"`
+		RequireMFA           *bool `json:"require_mfa" binding:"required"`
+		PasswordMaxAgeDays   int   `json:"password_max_age_days" binding:"min=0,max=3650"`
+	}
+
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	policy, err := c.OrganizationService.UpdateOrganizationPolicy(ctx.Request.Context(), &model.OrganizationPolicy{
+		OrganizationID:       orgID,
+		RequireVerifiedEmail: *req.RequireVerifiedEmail,
+		RequireMFA:           *req.RequireMFA,
+		PasswordMaxAgeDays:   req.PasswordMaxAgeDays,
+	})
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, policy)
+}
+
+// GetOrganizationSSO returns the identity provider of an organization
+func (c *OrganizationController) GetOrganizationSSO(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	config, err := c.OrganizationService.GetSSOConfig(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, config)
+}
+
+// UpdateOrganizationSSO creates or replaces the identity provider of an
+// organization. Leaving out client_secret keeps the stored one; an empty
+// string removes it for public clients.
+func (c *OrganizationController) UpdateOrganizationSSO(ctx *gin.Context) {
+	var req struct {
+		Issuer         string   `json:"issuer" binding:"required,url"`
+		ClientID       string   `json:"client_id" binding:"required,max=255"`
+		ClientSecret   *string  `json:"client_secret" binding:"omitempty,max=1024"`
+		AllowedDomains []string `json:"allowed_domains" binding:"required,min=1,dive,fqdn"`
+		DefaultRole    string   `json:"default_role" binding:"required,oneof=student instructor"`
+		Enabled        *bool    `json:"enabled" binding:"required"`
+	}
+
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	config, err := c.OrganizationService.UpdateSSOConfig(ctx.Request.Context(), &model.OrganizationSSO{
+		OrganizationID: orgID,
+		Issuer:         req.Issuer,
+		ClientID:       req.ClientID,
+		AllowedDomains: req.AllowedDomains,
+		DefaultRole:    req.DefaultRole,
+		Enabled:        *req.Enabled,
+	}, req.ClientSecret)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, config)
+}
+
+// GetOrganizationSAML returns the SAML identity provider of an organization
+// and the service provider details to register with it
+func (c *OrganizationController) GetOrganizationSAML(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	config, err := c.OrganizationService.GetSAMLConfig(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, config)
+}
+
+// UpdateOrganizationSAML creates or replaces the SAML identity provider of an
+// organization. Metadata is given inline as idp_metadata or imported from
+// idp_metadata_url; with neither the stored metadata is kept. Attributes
+// left out of attribute_mapping use the standard eduPerson names.
+func (c *OrganizationController) UpdateOrganizationSAML(ctx *gin.Context) {
+	var req struct {
+		IdPMetadata      string                     `json:"idp_metadata" binding:"max=1048576"`
+		IdPMetadataURL   string                     `json:"idp_metadata_url" binding:"omitempty,url"`
+		AttributeMapping model.SAMLAttributeMapping `json:"attribute_mapping"`
+		AllowedDomains   []string                   `json:"allowed_domains" binding:"required,min=1,dive,fqdn"`
+		DefaultRole      string                     `json:"default_role" binding:"required,oneof=student instructor"`
+		Enabled          *bool                      `json:"enabled" binding:"required"`
+	}
+
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	config, err := c.OrganizationService.UpdateSAMLConfig(ctx.Request.Context(), &model.OrganizationSAML{
+		OrganizationID:   orgID,
+		AttributeMapping: req.AttributeMapping,
+		AllowedDomains:   req.AllowedDomains,
+		DefaultRole:      req.DefaultRole,
+		Enabled:          *req.Enabled,
+	}, req.IdPMetadata, req.IdPMetadataURL)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, config)
+}
+
+// DeleteOrganizationSAML removes the SAML identity provider of an organization
+func (c *OrganizationController) DeleteOrganizationSAML(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if err := c.OrganizationService.DeleteSAMLConfig(ctx.Request.Context(), orgID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "SAML sign-on removed successfully"})
+}
+
+// DeleteOrganizationSSO removes the identity provider of an organization
+func (c *OrganizationController) DeleteOrganizationSSO(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	if err := c.OrganizationService.DeleteSSOConfig(ctx.Request.Context(), orgID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, orgs)
+	ctx.JSON(http.StatusOK, gin.H{"message": "single sign-on removed successfully"})
 }
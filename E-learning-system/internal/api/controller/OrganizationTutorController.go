@@ -3,11 +3,9 @@ package controller
 import (
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gofrs/uuid"
 )
 
 // OrganizationTutorController defines the tutor controller with its service
@@ -24,14 +22,13 @@ func NewOrganizationTutorController(tutorService service.OrganizationTutorServic
 func (c *OrganizationTutorController) CreateTutorOrganization(ctx *gin.Context) {
 	var tutor model.OrganizationTutor
 
-	if err := ctx.ShouldBindJSON(&tutor); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &tutor) {
 		return
 	}
 
-	createdTutor, err := c.OrganizationTutorService.CreateTutor(&tutor)
+	createdTutor, err := c.OrganizationTutorService.CreateTutor(ctx.Request.Context(), &tutor)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
@@ -42,22 +39,19 @@ func (c *OrganizationTutorController) CreateTutorOrganization(ctx *gin.Context)
 func (c *OrganizationTutorController) UpdateTutorOrganization(ctx *gin.Context) {
 	var tutor model.OrganizationTutor
 
-	tutorIDParam := ctx.Param("id")
-	tutorID, err := uuid.FromString(tutorIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tutor ID"})
+	tutorID, ok := paramUUID(ctx, "id", "tutor")
+	if !ok {
 		return
 	}
 
-	if err := ctx.ShouldBindJSON(&tutor); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &tutor) {
 		return
 	}
 
 	tutor.ID = tutorID
 
-	if err := c.OrganizationTutorService.UpdateTutor(&tutor); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationTutorService.UpdateTutor(ctx.Request.Context(), &tutor); err != nil {
+		fail(ctx, err)
 		return
 	}
 
@@ -66,51 +60,77 @@ func (c *OrganizationTutorController) UpdateTutorOrganization(ctx *gin.Context)
 
 // DeleteTutor handles the soft delete of a tutor
 func (c *OrganizationTutorController) DeleteTutorOrganization(ctx *gin.Context) {
-	tutorIDParam := ctx.Param("id")
-	tutorID, err := uuid.FromString(tutorIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tutor ID"})
+	tutorID, ok := paramUUID(ctx, "id", "tutor")
+	if !ok {
 		return
 	}
 
-	if err := c.OrganizationTutorService.DeleteTutor(tutorID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.OrganizationTutorService.DeleteTutor(ctx.Request.Context(), tutorID); err != nil {
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "tutor deleted successfully"})
 }
 
+// RestoreTutorOrganization brings back a soft-deleted tutor
+func (c *OrganizationTutorController) RestoreTutorOrganization(ctx *gin.Context) {
+	tutorID, ok := paramUUID(ctx, "id", "tutor")
+	if !ok {
+		return
+	}
+
+	tutor, err := c.OrganizationTutorService.RestoreTutor(ctx.Request.Context(), tutorID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tutor)
+}
+
 // GetTutorByID retrieves a single tutor by its ID
 func (c *OrganizationTutorController) GetTutorOrganizationByID(ctx *gin.Context) {
-	tutorIDParam := ctx.Param("id")
-	tutorID, err := uuid.FromString(tutorIDParam)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tutor ID"})
+	tutorID, ok := paramUUID(ctx, "id", "tutor")
+	if !ok {
 		return
 	}
 
-	tutor, err := c.OrganizationTutorService.GetTutorByID(tutorID)
+	tutor, err := c.OrganizationTutorService.GetTutorByID(ctx.Request.Context(), tutorID)
 	if err != nil {
-		if err.Error() == "tutor not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			log.Printf("Unexpected error: %v", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		}
+		fail(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, tutor)
 }
 
-// GetAllTutors retrieves all organization tutors
+// GetAllTutorsOrganization lists organization tutors page by page, filtered by organization and approval
 func (c *OrganizationTutorController) GetAllTutorsOrganization(ctx *gin.Context) {
-	tutors, err := c.OrganizationTutorService.GetAllTutors()
+	page, ok := pageRequest(ctx, "-created_at", "created_at")
+	if !ok {
+		return
+	}
+	orgID, ok := queryUUID(ctx, "organization_id")
+	if !ok {
+		return
+	}
+	approved, ok := queryBool(ctx, "approved")
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.OrganizationTutorFilter{OrganizationID: orgID, Approved: approved, IncludeDeleted: deleted}
+	tutors, err := c.OrganizationTutorService.ListTutors(ctx.Request.Context(), filter, page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, tutors)
+	writePage(ctx, tutors)
 }
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"e-learning-system/internal/api/dto"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// PrivacyController handles data subject requests: data exports and erasure
+type PrivacyController struct {
+	PrivacyService service.PrivacyService
+}
+
+// NewPrivacyController creates a new PrivacyController instance
+func NewPrivacyController(privacyService service.PrivacyService) *PrivacyController {
+	return &PrivacyController{PrivacyService: privacyService}
+}
+
+// RequestMyExport queues an export of the signed-in user's data
+func (c *PrivacyController) RequestMyExport(ctx *gin.Context) {
+	userID, _ := session(ctx)
+	c.requestExport(ctx, userID)
+}
+
+// ListMyExports lists the signed-in user's data exports, newest first
+func (c *PrivacyController) ListMyExports(ctx *gin.Context) {
+	userID, _ := session(ctx)
+
+	exports, err := c.PrivacyService.ListExports(ctx.Request.Context(), userID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	if exports == nil {
+		exports = []*model.UserDataExport{}
+	}
+
+	ctx.JSON(http.StatusOK, exports)
+}
+
+// GetMyExport returns one of the signed-in user's data exports with its
+// download link once it is ready
+func (c *PrivacyController) GetMyExport(ctx *gin.Context) {
+	userID, _ := session(ctx)
+	c.getExport(ctx, userID)
+}
+
+// EraseMe erases the signed-in user's account after checking their password
+func (c *PrivacyController) EraseMe(ctx *gin.Context) {
+	var req dto.EraseAccountRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	userID, _ := session(ctx)
+	if err := c.PrivacyService.EraseOwnAccount(ctx.Request.Context(), userID, req.CurrentPassword); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Your account has been erased"})
+}
+
+// RequestUserExport queues an export of a user's data on their behalf
+func (c *PrivacyController) RequestUserExport(ctx *gin.Context) {
+	userID, ok := paramUUID(ctx, "id", "user")
+	if !ok {
+		return
+	}
+	c.requestExport(ctx, userID)
+}
+
+// GetUserExport returns one of a user's data exports
+func (c *PrivacyController) GetUserExport(ctx *gin.Context) {
+	userID, ok := paramUUID(ctx, "id", "user")
+	if !ok {
+		return
+	}
+	c.getExport(ctx, userID)
+}
+
+// EraseUser erases a user's account
+func (c *PrivacyController) EraseUser(ctx *gin.Context) {
+	userID, ok := paramUUID(ctx, "id", "user")
+	if !ok {
+		return
+	}
+
+	if err := c.PrivacyService.EraseUser(ctx.Request.Context(), userID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User erased"})
+}
+
+func (c *PrivacyController) requestExport(ctx *gin.Context, userID uuid.UUID) {
+	requestedBy, _ := session(ctx)
+
+	export, err := c.PrivacyService.RequestExport(ctx.Request.Context(), userID, requestedBy)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, export)
+}
+
+func (c *PrivacyController) getExport(ctx *gin.Context, userID uuid.UUID) {
+	exportID, ok := paramUUID(ctx, "exportID", "export")
+	if !ok {
+		return
+	}
+
+	export, err := c.PrivacyService.GetExport(ctx.Request.Context(), userID, exportID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, export)
+}
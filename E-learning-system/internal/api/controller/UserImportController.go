@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/xlsx"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserImportController handles bulk user imports and member exports of organizations
+type UserImportController struct {
+	UserImportService service.UserImportService
+}
+
+// NewUserImportController creates a new UserImportController instance
+func NewUserImportController(userImportService service.UserImportService) *UserImportController {
+	return &UserImportController{UserImportService: userImportService}
+}
+
+// GetImportTemplate downloads a CSV file with the columns an import expects
+func (c *UserImportController) GetImportTemplate(ctx *gin.Context) {
+	ctx.Header("Content-Disposition", "attachment; filename=user-import-template.csv")
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", c.UserImportService.Template())
+}
+
+// StartImport takes a multipart CSV upload in the "file" field and queues
+// it. Setting send_invitations mails new users a link to choose a password.
+func (c *UserImportController) StartImport(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	file, fileName, ok := openUpload(ctx, service.MaxImportFileSize)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	sendInvitations := false
+	if raw := ctx.PostForm("send_invitations"); raw != "" {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			fail(ctx, apperr.Validation("invalid_request", "invalid form field",
+				apperr.FieldError{Field: "send_invitations", Message: "must be true or false"}))
+			return
+		}
+		sendInvitations = b
+	}
+
+	userID, _ := session(ctx)
+	userImport, err := c.UserImportService.StartImport(ctx.Request.Context(), orgID, userID, fileName, file, sendInvitations)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, userImport)
+}
+
+// ListImports lists an organization's imports, newest first
+func (c *UserImportController) ListImports(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+
+	imports, err := c.UserImportService.ListImports(ctx.Request.Context(), orgID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	if imports == nil {
+		imports = []*model.UserImport{}
+	}
+
+	ctx.JSON(http.StatusOK, imports)
+}
+
+// GetImport returns the progress and row counts of an import
+func (c *UserImportController) GetImport(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+	importID, ok := paramUUID(ctx, "importID", "import")
+	if !ok {
+		return
+	}
+
+	userImport, err := c.UserImportService.GetImport(ctx.Request.Context(), orgID, importID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userImport)
+}
+
+// ListImportErrors returns the rows of an import that were not applied,
+// as JSON or, with format=csv, as a file to correct and upload again
+func (c *UserImportController) ListImportErrors(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+	importID, ok := paramUUID(ctx, "importID", "import")
+	if !ok {
+		return
+	}
+	format, ok := queryOneOf(ctx, "format", "json", "csv")
+	if !ok {
+		return
+	}
+
+	if format == "csv" {
+		ctx.Header("Content-Type", "text/csv; charset=utf-8")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user-import-%s-errors.csv", importID))
+		streamDownload(ctx, func(w io.Writer) error {
+			return c.UserImportService.ExportImportErrors(ctx.Request.Context(), orgID, importID, w)
+		})
+		return
+	}
+
+	rowErrs, err := c.UserImportService.ListImportErrors(ctx.Request.Context(), orgID, importID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	if rowErrs == nil {
+		rowErrs = []model.UserImportError{}
+	}
+
+	ctx.JSON(http.StatusOK, rowErrs)
+}
+
+// ExportMembers downloads an organization's members as CSV, or as an Excel
+// workbook with format=xlsx
+func (c *UserImportController) ExportMembers(ctx *gin.Context) {
+	orgID, ok := paramUUID(ctx, "id", "organization")
+	if !ok {
+		return
+	}
+	format, ok := queryOneOf(ctx, "format", service.MemberExportCSV, service.MemberExportXLSX)
+	if !ok {
+		return
+	}
+	if format == "" {
+		format = service.MemberExportCSV
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.MemberExportXLSX {
+		contentType = xlsx.ContentType
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=members-%s-%s.%s", orgID, time.Now().UTC().Format("20060102-150405"), format))
+	streamDownload(ctx, func(w io.Writer) error {
+		return c.UserImportService.ExportMembers(ctx.Request.Context(), orgID, format, w)
+	})
+}
+
+// streamDownload writes a file with write. Download headers are dropped
+// when write fails before writing anything, so the error is sent instead;
+// after that the status is sent and the error can only be logged.
+func streamDownload(ctx *gin.Context, write func(w io.Writer) error) {
+	if err := write(ctx.Writer); err != nil {
+		if !ctx.Writer.Written() {
+			ctx.Writer.Header().Del("Content-Type")
+			ctx.Writer.Header().Del("Content-Disposition")
+			fail(ctx, err)
+			return
+		}
+		_ = ctx.Error(err)
+	}
+}
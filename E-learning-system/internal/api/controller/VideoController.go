@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/service"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// tusVersion is the tus resumable upload protocol version we speak
+const tusVersion = "1.0.0"
+
+// VideoController handles resumable lesson video uploads and playback
+type VideoController struct {
+	VideoService service.VideoService
+}
+
+// NewVideoController creates a new VideoController instance
+func NewVideoController(videoService service.VideoService) *VideoController {
+	return &VideoController{VideoService: videoService}
+}
+
+// UploadOptions advertises the supported tus protocol features
+func (c *VideoController) UploadOptions(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+	ctx.Header("Tus-Version", tusVersion)
+	ctx.Header("Tus-Extension", "creation,termination,expiration")
+	ctx.Header("Tus-Max-Size", strconv.FormatInt(service.MaxVideoSize, 10))
+	ctx.Status(http.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload for a lesson video. The total size
+// comes in Upload-Length and the file name in the "filename" Upload-Metadata.
+func (c *VideoController) CreateUpload(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		fail(ctx, apperr.Validation("invalid_upload_length", "Upload-Length header is required",
+			apperr.FieldError{Field: "Upload-Length", Message: "must be a byte count"}))
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	ownerID, _ := userID.(uuid.UUID)
+
+	fileName := uploadMetadata(ctx.GetHeader("Upload-Metadata"))["filename"]
+	upload, err := c.VideoService.CreateUpload(ctx.Request.Context(), lessonID, ownerID, length, fileName)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Header("Location", "/video-uploads/"+upload.ID.String())
+	ctx.Header("Upload-Offset", "0")
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.JSON(http.StatusCreated, upload)
+}
+
+// GetUploadOffset tells the client where to resume
+func (c *VideoController) GetUploadOffset(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+	ctx.Header("Cache-Control", "no-store")
+
+	uploadID, ownerID, ok := uploadParams(ctx)
+	if !ok {
+		return
+	}
+
+	upload, err := c.VideoService.GetUpload(ctx.Request.Context(), uploadID, ownerID)
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.Status(http.StatusOK)
+}
+
+// UploadChunk appends the request body at Upload-Offset
+func (c *VideoController) UploadChunk(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+
+	if ctx.ContentType() != "application/offset+octet-stream" {
+		fail(ctx, apperr.New(apperr.KindUnsupportedMedia, "unsupported_content_type",
+			"Content-Type must be application/offset+octet-stream"))
+		return
+	}
+
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		fail(ctx, apperr.Validation("invalid_upload_offset", "Upload-Offset header is required",
+			apperr.FieldError{Field: "Upload-Offset", Message: "must be a non-negative byte offset"}))
+		return
+	}
+
+	uploadID, ownerID, ok := uploadParams(ctx)
+	if !ok {
+		return
+	}
+
+	upload, err := c.VideoService.AppendChunk(ctx.Request.Context(), uploadID, ownerID, offset, ctx.Request.Body)
+	if err != nil {
+		if upload != nil {
+			// Partially received; the client resumes from the reported offset
+			ctx.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ctx.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	ctx.Status(http.StatusNoContent)
+}
+
+// DeleteUpload cancels an upload
+func (c *VideoController) DeleteUpload(ctx *gin.Context) {
+	ctx.Header("Tus-Resumable", tusVersion)
+
+	uploadID, ownerID, ok := uploadParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.VideoService.DeleteUpload(ctx.Request.Context(), uploadID, ownerID); err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetPlayback returns a signed, expiring playback URL for the lesson video
+func (c *VideoController) GetPlayback(ctx *gin.Context) {
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	userID, _ := ctx.Get("userID")
+	uid, _ := userID.(uuid.UUID)
+
+	playback, err := c.VideoService.Playback(ctx.Request.Context(), lessonID, uid, "/lessons/"+lessonID.String()+"/stream")
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, playback)
+}
+
+// StreamFile serves HLS playlists and redirects to signed segment URLs.
+// It is public; access is granted by the token query parameter.
+func (c *VideoController) StreamFile(ctx *gin.Context) {
+	lessonID, ok := paramUUID(ctx, "id", "lesson")
+	if !ok {
+		return
+	}
+
+	file := strings.TrimPrefix(ctx.Param("file"), "/")
+	resp, err := c.VideoService.StreamFile(ctx.Request.Context(), lessonID, file, ctx.Query("token"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+
+	// Tokens are per viewer, so nothing may be shared between them
+	ctx.Header("Cache-Control", "private, no-store")
+	if resp.Redirect != "" {
+		ctx.Redirect(http.StatusFound, resp.Redirect)
+		return
+	}
+	ctx.Data(http.StatusOK, resp.ContentType, resp.Body)
+}
+
+// uploadParams reads the upload ID and the current user.
+// It records the error and returns ok=false on failure.
+func uploadParams(ctx *gin.Context) (uploadID, ownerID uuid.UUID, ok bool) {
+	uploadID, ok = paramUUID(ctx, "id", "upload")
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, _ := ctx.Get("userID")
+	ownerID, _ = userID.(uuid.UUID)
+	return uploadID, ownerID, true
+}
+
+// uploadMetadata decodes a tus Upload-Metadata header ("key base64value,...")
+func uploadMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 {
+			continue
+		}
+		value := ""
+		if len(fields) > 1 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				continue
+			}
+			value = string(decoded)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata
+}
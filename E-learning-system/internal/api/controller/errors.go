@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+)
+
+func init() {
+	// Report validation failures with the JSON field names clients send
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(f.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// fail hands err to middleware.ErrorHandler, which renders it as problem+json
+func fail(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
+
+// paramUUID parses the named path parameter as a UUID.
+// It records a validation error and returns ok=false on failure.
+func paramUUID(ctx *gin.Context, name, label string) (uuid.UUID, bool) {
+	id, err := uuid.FromString(ctx.Param(name))
+	if err != nil {
+		fail(ctx, apperr.Validation("invalid_id", "invalid "+label+" ID",
+			apperr.FieldError{Field: name, Message: "must be a UUID"}))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// bindJSON decodes and validates the request body into obj.
+// It records a validation error with field details and returns false on failure.
+func bindJSON(ctx *gin.Context, obj any) bool {
+	if err := ctx.ShouldBindJSON(obj); err != nil {
+		fail(ctx, bindingError(err))
+		return false
+	}
+	return true
+}
+
+// bindingError converts JSON decoding and validator errors into a validation error
+func bindingError(err error) error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]apperr.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, apperr.FieldError{Field: fieldPath(fe), Message: validationMessage(fe)})
+		}
+		return apperr.Validation("validation_failed", "request validation failed", fields...).Wrap(err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperr.Validation("malformed_body", "request body is invalid",
+			apperr.FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}).Wrap(err)
+	}
+
+	return apperr.Validation("malformed_body", "request body is not valid JSON").Wrap(err)
+}
+
+// fieldPath returns the field's JSON path without the top-level struct name
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// validationMessage describes a failed validation rule in plain words
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "url":
+		return "must be a URL"
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testAddress struct {
+	City string `json:"city" binding:"required"`
+}
+
+type testRequest struct {
+	Email   string      `json:"email" binding:"required,email"`
+	Name    string      `json:"name" binding:"omitempty,min=2,max=5"`
+	Role    string      `json:"role" binding:"omitempty,oneof=admin student"`
+	Seats   int         `json:"seats" binding:"max=10"`
+	Address testAddress `json:"address"`
+}
+
+func TestBindJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   string            // empty when the body binds
+		wantFields map[string]string // field path to message
+	}{
+		{"valid", `{"email":"a@example.com","address":{"city":"Oslo"}}`, "", nil},
+		{"missing and invalid fields", `{"email":"nope","name":"x","role":"owner","seats":11}`, "validation_failed", map[string]string{
+			"email":        "must be a valid email address",
+			"name":         "must be at least 2 characters",
+			"role":         "must be one of admin, student",
+			"seats":        "must be at most 10",
+			"address.city": "is required",
+		}},
+		{"wrong type", `{"email":"a@example.com","seats":"many"}`, "malformed_body", map[string]string{
+			"seats": "must be a int",
+		}},
+		{"not JSON", `{"email":`, "malformed_body", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			var req testRequest
+			ok := bindJSON(ctx, &req)
+			if tt.wantCode == "" {
+				if !ok || len(ctx.Errors) != 0 {
+					t.Fatalf("bindJSON failed: %v", ctx.Errors)
+				}
+				return
+			}
+			if ok || !ctx.IsAborted() || len(ctx.Errors) != 1 {
+				t.Fatalf("bindJSON = %v, aborted %v, errors %v", ok, ctx.IsAborted(), ctx.Errors)
+			}
+
+			e, isAppErr := apperr.As(ctx.Errors.Last().Err)
+			if !isAppErr || e.Kind != apperr.KindValidation || e.Code != tt.wantCode {
+				t.Fatalf("error = %v, want validation %s", ctx.Errors.Last().Err, tt.wantCode)
+			}
+			got := map[string]string{}
+			for _, f := range e.Fields {
+				got[f.Field] = f.Message
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Errorf("fields = %v, want %v", got, tt.wantFields)
+			}
+			for field, message := range tt.wantFields {
+				if got[field] != message {
+					t.Errorf("%s: %q, want %q", field, got[field], message)
+				}
+			}
+		})
+	}
+}
+
+func TestParamUUID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		param string
+		ok    bool
+	}{
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+		{"42", false},
+		{"", false},
+	} {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Params = gin.Params{{Key: "id", Value: tt.param}}
+
+		_, ok := paramUUID(ctx, "id", "course")
+		if ok != tt.ok {
+			t.Errorf("%q: ok = %v, want %v", tt.param, ok, tt.ok)
+		}
+		if tt.ok {
+			continue
+		}
+		e, isAppErr := apperr.As(ctx.Errors.Last().Err)
+		if !isAppErr || e.Code != "invalid_id" || e.Message != "invalid course ID" || len(e.Fields) != 1 || e.Fields[0].Field != "id" {
+			t.Errorf("%q: error = %+v", tt.param, e)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// invalidQuery reports a rejected query parameter
+func invalidQuery(name, message string) error {
+	return apperr.Validation("invalid_query", "invalid query parameter",
+		apperr.FieldError{Field: name, Message: message})
+}
+
+// pageRequest reads the pagination parameters shared by list endpoints:
+// limit, cursor and sort, where sort is a field name, prefixed with "-" for
+// descending order. defaultSort applies when sort is absent and sortable
+// lists the accepted fields. It records the error and returns ok=false on failure.
+func pageRequest(ctx *gin.Context, defaultSort string, sortable ...string) (model.PageRequest, bool) {
+	page := model.PageRequest{Limit: model.DefaultPageLimit}
+
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxPageLimit {
+			fail(ctx, invalidQuery("limit", "must be between 1 and "+strconv.Itoa(model.MaxPageLimit)))
+			return page, false
+		}
+		page.Limit = limit
+	}
+
+	sort := ctx.DefaultQuery("sort", defaultSort)
+	page.Desc = strings.HasPrefix(sort, "-")
+	page.Sort = strings.TrimPrefix(sort, "-")
+	if !slices.Contains(sortable, page.Sort) {
+		fail(ctx, invalidQuery("sort", "must be one of "+strings.Join(sortable, ", ")))
+		return page, false
+	}
+
+	if raw := ctx.Query("cursor"); raw != "" {
+		cursor, err := model.DecodeCursor(raw)
+		if err == nil && (cursor.Sort != page.Sort || cursor.Desc != page.Desc) {
+			err = model.ErrInvalidCursor
+		}
+		if err != nil {
+			fail(ctx, err)
+			return page, false
+		}
+		page.After = cursor
+	}
+
+	return page, true
+}
+
+// writePage responds with a page of items. When another page follows, it
+// adds the URL of that page to the body and as a Link header.
+func writePage[T any](ctx *gin.Context, page *model.Page[T]) {
+	if page.NextCursor != "" {
+		query := ctx.Request.URL.Query()
+		query.Set("cursor", page.NextCursor)
+		page.Next = ctx.Request.URL.Path + "?" + query.Encode()
+		ctx.Header("Link", `<`+page.Next+`>; rel="next"`)
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// queryUUID reads an optional UUID query parameter
+func queryUUID(ctx *gin.Context, name string) (*uuid.UUID, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := uuid.FromString(raw)
+	if err != nil {
+		fail(ctx, invalidQuery(name, "must be a UUID"))
+		return nil, false
+	}
+	return &id, true
+}
+
+// queryTime reads an optional RFC 3339 timestamp query parameter
+func queryTime(ctx *gin.Context, name string) (*time.Time, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		fail(ctx, invalidQuery(name, "must be an RFC 3339 timestamp, e.g. 2024-01-31T00:00:00Z"))
+		return nil, false
+	}
+	return &t, true
+}
+
+// queryBool reads an optional boolean query parameter
+func queryBool(ctx *gin.Context, name string) (*bool, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		fail(ctx, invalidQuery(name, "must be true or false"))
+		return nil, false
+	}
+	return &b, true
+}
+
+// includeDeleted reads the include_deleted flag of list endpoints; routes
+// only let platform admins set it
+func includeDeleted(ctx *gin.Context) (bool, bool) {
+	include, ok := queryBool(ctx, "include_deleted")
+	return include != nil && *include, ok
+}
+
+// queryOneOf reads an optional query parameter restricted to allowed values
+func queryOneOf(ctx *gin.Context, name string, allowed ...string) (string, bool) {
+	raw := ctx.Query(name)
+	if raw != "" && !slices.Contains(allowed, raw) {
+		fail(ctx, invalidQuery(name, "must be one of "+strings.Join(allowed, ", ")))
+		return "", false
+	}
+	return raw, true
+}
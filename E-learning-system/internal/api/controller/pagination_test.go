@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+func newQueryContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return ctx, w
+}
+
+// queryErrorField returns the field of the validation error recorded on ctx
+func queryErrorField(t *testing.T, ctx *gin.Context) string {
+	t.Helper()
+	if len(ctx.Errors) != 1 {
+		t.Fatalf("errors = %v, want one", ctx.Errors)
+	}
+	e, ok := apperr.As(ctx.Errors.Last().Err)
+	if !ok || e.Kind != apperr.KindValidation {
+		t.Fatalf("error = %v, want a validation error", ctx.Errors.Last().Err)
+	}
+	if len(e.Fields) == 0 {
+		return e.Code
+	}
+	return e.Fields[0].Field
+}
+
+func TestPageRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	id := uuid.Must(uuid.NewV4())
+	byEmail := model.Cursor{Sort: "email", Value: "a@example.com", ID: id}.Encode()
+	byEmailDesc := model.Cursor{Sort: "email", Desc: true, Value: "a@example.com", ID: id}.Encode()
+
+	tests := []struct {
+		name      string
+		query     string
+		want      model.PageRequest
+		wantField string // field or code of the rejection; empty when accepted
+	}{
+		{"defaults", "", model.PageRequest{Limit: model.DefaultPageLimit, Sort: "created_at", Desc: true}, ""},
+		{"limit and ascending sort", "?limit=5&sort=email", model.PageRequest{Limit: 5, Sort: "email"}, ""},
+		{"descending sort", "?sort=-email", model.PageRequest{Limit: model.DefaultPageLimit, Sort: "email", Desc: true}, ""},
+		{"largest limit", "?limit=100", model.PageRequest{Limit: model.MaxPageLimit, Sort: "created_at", Desc: true}, ""},
+		{"limit too large", "?limit=101", model.PageRequest{}, "limit"},
+		{"limit zero", "?limit=0", model.PageRequest{}, "limit"},
+		{"limit not a number", "?limit=ten", model.PageRequest{}, "limit"},
+		{"unsortable field", "?sort=password_hash", model.PageRequest{}, "sort"},
+		{"cursor", "?sort=email&cursor=" + byEmail, model.PageRequest{Limit: model.DefaultPageLimit, Sort: "email",
+			After: &model.Cursor{Sort: "email", Value: "a@example.com", ID: id}}, ""},
+		{"cursor of another direction", "?sort=email&cursor=" + byEmailDesc, model.PageRequest{}, "invalid_cursor"},
+		{"cursor of another sort", "?cursor=" + byEmail, model.PageRequest{}, "invalid_cursor"},
+		{"garbage cursor", "?cursor=abc", model.PageRequest{}, "invalid_cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := newQueryContext("/users" + tt.query)
+
+			page, ok := pageRequest(ctx, "-created_at", "created_at", "email")
+			if tt.wantField != "" {
+				if ok {
+					t.Fatalf("accepted as %+v", page)
+				}
+				if field := queryErrorField(t, ctx); field != tt.wantField {
+					t.Errorf("rejected %s, want %s", field, tt.wantField)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("rejected: %v", ctx.Errors)
+			}
+			if page.Limit != tt.want.Limit || page.Sort != tt.want.Sort || page.Desc != tt.want.Desc {
+				t.Errorf("page = %+v, want %+v", page, tt.want)
+			}
+			if (page.After == nil) != (tt.want.After == nil) || page.After != nil && *page.After != *tt.want.After {
+				t.Errorf("cursor = %+v, want %+v", page.After, tt.want.After)
+			}
+		})
+	}
+}
+
+func TestWritePage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx, w := newQueryContext("/users?role=student&cursor=old&limit=2")
+	writePage(ctx, &model.Page[string]{Items: []string{"a", "b"}, Total: 5, NextCursor: "next"})
+
+	want := "/users?cursor=next&limit=2&role=student"
+	if link := w.Header().Get("Link"); link != `<`+want+`>; rel="next"` {
+		t.Errorf("Link = %q, want %s", link, want)
+	}
+	var body model.Page[string]
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Items) != 2 || body.Total != 5 || body.NextCursor != "next" || body.Next != want {
+		t.Errorf("body = %s", w.Body)
+	}
+
+	ctx, w = newQueryContext("/users?cursor=old")
+	writePage(ctx, &model.Page[string]{Items: []string{"c"}, Total: 5})
+	if link := w.Header().Get("Link"); link != "" {
+		t.Errorf("Link = %q on the last page", link)
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	id := uuid.Must(uuid.NewV4())
+
+	ctx, _ := newQueryContext("/x?organization_id=" + id.String() + "&approved=false&from=2024-01-31T00:00:00Z&role=admin&include_deleted=1")
+	orgID, ok1 := queryUUID(ctx, "organization_id")
+	approved, ok2 := queryBool(ctx, "approved")
+	from, ok3 := queryTime(ctx, "from")
+	role, ok4 := queryOneOf(ctx, "role", "admin", "member")
+	deleted, ok5 := includeDeleted(ctx)
+	if !(ok1 && ok2 && ok3 && ok4 && ok5) {
+		t.Fatalf("rejected: %v", ctx.Errors)
+	}
+	if *orgID != id || *approved || from.Day() != 31 || role != "admin" || !deleted {
+		t.Errorf("parsed %v %v %v %q %v", *orgID, *approved, from, role, deleted)
+	}
+
+	ctx, _ = newQueryContext("/x")
+	orgID, _ = queryUUID(ctx, "organization_id")
+	approved, _ = queryBool(ctx, "approved")
+	from, _ = queryTime(ctx, "from")
+	role, _ = queryOneOf(ctx, "role", "admin")
+	deleted, _ = includeDeleted(ctx)
+	if orgID != nil || approved != nil || from != nil || role != "" || deleted || len(ctx.Errors) != 0 {
+		t.Errorf("absent parameters parsed as %v %v %v %q %v", orgID, approved, from, role, deleted)
+	}
+
+	rejected := []struct {
+		query string
+		parse func(ctx *gin.Context) bool
+	}{
+		{"organization_id=42", func(ctx *gin.Context) bool { _, ok := queryUUID(ctx, "organization_id"); return ok }},
+		{"approved=maybe", func(ctx *gin.Context) bool { _, ok := queryBool(ctx, "approved"); return ok }},
+		{"from=2024-01-31", func(ctx *gin.Context) bool { _, ok := queryTime(ctx, "from"); return ok }},
+		{"role=owner", func(ctx *gin.Context) bool { _, ok := queryOneOf(ctx, "role", "admin", "member"); return ok }},
+		{"include_deleted=yes", func(ctx *gin.Context) bool { _, ok := includeDeleted(ctx); return ok }},
+	}
+	for _, tt := range rejected {
+		ctx, _ := newQueryContext("/x?" + tt.query)
+		if tt.parse(ctx) {
+			t.Errorf("%s accepted", tt.query)
+			continue
+		}
+		queryErrorField(t, ctx)
+	}
+}
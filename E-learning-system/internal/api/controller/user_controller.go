@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"e-learning-system/internal/api/dto"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/service"
-	"log"
+	"e-learning-system/internal/metrics"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,168 +22,623 @@ func NewUserController(userService service.UserService) *UserController {
 
 // RegisterUser handles user registration
 func (us *UserController) RegisterUser(c *gin.Context) {
-	var user model.User
+	var req dto.RegisterUserRequest
 
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	log.Printf("bound user: %+v", user)
-
-	createdUser, err := us.userService.RegisterUser(
-		user.Email,
-		user.Password,
-		user.FirstName,
-		user.LastName,
-		user.Role,
+	createdUser, err := us.userService.RegisterUser(c.Request.Context(),
+		req.Email,
+		req.Password,
+		req.FirstName,
+		req.LastName,
+		req.RoleOrDefault(),
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdUser)
+	metrics.Registrations.Inc()
+	c.JSON(http.StatusCreated, dto.NewUserResponse(createdUser))
 }
 
 // AuthenticateUser handles login
 func (us *UserController) AuthenticateUser(c *gin.Context) {
-	var user model.User
+	var req dto.LoginRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := us.userService.AuthenticateUser(c.Request.Context(), req.Email, req.Password, clientInfo(c))
+	writeLogin(c, result, err)
+}
+
+// RenewExpiredPassword signs in a user whose password has expired by replacing it
+func (us *UserController) RenewExpiredPassword(c *gin.Context) {
+	var req dto.RenewPasswordRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := us.userService.RenewExpiredPassword(c.Request.Context(), req.Email, req.CurrentPassword, req.NewPassword, clientInfo(c))
+	writeLogin(c, result, err)
+}
+
+// VerifyMFALogin completes a login with a code from the authenticator app or a recovery code
+func (us *UserController) VerifyMFALogin(c *gin.Context) {
+	var req dto.MFALoginRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := us.userService.VerifyMFALogin(c.Request.Context(), req.ChallengeToken, req.Code, clientInfo(c))
+	writeLogin(c, result, err)
+}
+
+// DiscoverSSO lists the organizations offering single sign-on for an email address
+func (us *UserController) DiscoverSSO(c *gin.Context) {
+	var req dto.SSODiscoverRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	orgs, err := us.userService.FindSSOOrganizations(c.Request.Context(), req.Email)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SSODiscoverResponse{Organizations: orgs})
+}
+
+// StartSSO returns the identity provider URL to send the user to
+func (us *UserController) StartSSO(c *gin.Context) {
+	orgID, ok := paramUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	start, err := us.userService.StartSSOLogin(c.Request.Context(), orgID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, start)
+}
+
+// CompleteSSO signs in the user the identity provider redirected back
+func (us *UserController) CompleteSSO(c *gin.Context) {
+	var req dto.SSOCallbackRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := us.userService.CompleteSSOLogin(c.Request.Context(), req.StateToken, req.State, req.Code, clientInfo(c))
+	writeLogin(c, result, err)
+}
+
+// GetSAMLMetadata serves the SAML service provider metadata of an organization
+func (us *UserController) GetSAMLMetadata(c *gin.Context) {
+	orgID, ok := paramUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	metadata, err := us.userService.GetSAMLMetadata(c.Request.Context(), orgID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// StartSAML returns the signed identity provider URL to send the user to
+func (us *UserController) StartSAML(c *gin.Context) {
+	orgID, ok := paramUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	start, err := us.userService.StartSAMLLogin(c.Request.Context(), orgID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, start)
+}
+
+// ConsumeSAMLAssertion receives the identity provider's form post and sends
+// the browser on to the frontend, which completes the sign-in
+func (us *UserController) ConsumeSAMLAssertion(c *gin.Context) {
+	orgID, ok := paramUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	redirect := us.userService.AcceptSAMLResponse(c.Request.Context(), orgID, c.PostForm("SAMLResponse"))
+	c.Redirect(http.StatusSeeOther, redirect)
+}
+
+// CompleteSAML signs in the user of an accepted SAML response
+func (us *UserController) CompleteSAML(c *gin.Context) {
+	var req dto.SAMLCallbackRequest
 
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	authenticatedUser, err := us.userService.AuthenticateUser(user.Email, user.Password)
+	result, err := us.userService.CompleteSAMLLogin(c.Request.Context(), req.StateToken, req.Code, clientInfo(c))
+	writeLogin(c, result, err)
+}
+
+// writeLogin answers a login step with a session or an MFA challenge and counts the result
+func writeLogin(c *gin.Context, result *model.LoginResult, err error) {
+	if errors.Is(err, service.ErrAccountLocked) || errors.Is(err, service.ErrTooManyLoginAttempts) {
+		metrics.Logins.WithLabelValues("locked").Inc()
+		fail(c, err)
+		return
+	}
+	if errors.Is(err, service.ErrOrganizationSuspended) || errors.Is(err, service.ErrEmailNotVerified) || errors.Is(err, service.ErrPasswordExpired) ||
+		errors.Is(err, service.ErrSSODomainNotAllowed) || errors.Is(err, service.ErrSSOEmailNotVerified) || errors.Is(err, service.ErrSSOLinkNotAllowed) {
+		metrics.Logins.WithLabelValues("blocked").Inc()
+		fail(c, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed: " + err.Error()})
+		metrics.Logins.WithLabelValues("failure").Inc()
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, authenticatedUser)
+	if result.Challenge != nil {
+		metrics.Logins.WithLabelValues("mfa_required").Inc()
+		c.JSON(http.StatusOK, dto.NewMFAChallengeResponse(result.Challenge))
+		return
+	}
+
+	metrics.Logins.WithLabelValues("success").Inc()
+	c.JSON(http.StatusOK, dto.NewLoginResponse(result.User, result.Token))
 }
 
 // GetUserByID returns user by UUID
 func (us *UserController) GetUserByID(c *gin.Context) {
-	userParam := c.Param("id")
-	userID, err := uuid.FromString(userParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
 		return
 	}
 
-	user, err := us.userService.GetUserByID(userID)
+	user, err := us.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
 }
 
-// ListUsers returns all users
+// ListUsers lists users page by page, filtered by role and email prefix
 func (us *UserController) ListUsers(c *gin.Context) {
-	users, err := us.userService.ListUsers()
+	page, ok := pageRequest(c, "-created_at", "created_at", "email", "last_name")
+	if !ok {
+		return
+	}
+	role, ok := queryOneOf(c, "role", "admin", "instructor", "student")
+	if !ok {
+		return
+	}
+
+	deleted, ok := includeDeleted(c)
+	if !ok {
+		return
+	}
+
+	filter := model.UserFilter{Role: role, EmailPrefix: c.Query("email_prefix"), IncludeDeleted: deleted}
+	users, err := us.userService.ListUsers(c.Request.Context(), filter, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	writePage(c, dto.NewUserPage(users))
 }
 
-// UpdateUser updates user by ID
+// UpdateUser updates the email and name of a user by ID
 func (us *UserController) UpdateUser(c *gin.Context) {
-	var user model.User
+	var req dto.UpdateUserRequest
+
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	userParam := c.Param("id")
-	userID, err := uuid.FromString(userParam)
+	user, err := us.userService.UpdateUser(c.Request.Context(), userID, req.Changes())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
+}
+
+// ChangeUserRole appoints a user as instructor or admin, or makes them a student again
+func (us *UserController) ChangeUserRole(c *gin.Context) {
+	var req dto.ChangeUserRoleRequest
+
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
 		return
 	}
 
-	user.ID = userID
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	if err := us.userService.UpdateUser(&user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, err := us.userService.ChangeUserRole(c.Request.Context(), userID, req.Role)
+	if err != nil {
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
 }
 
 // DeleteUser removes a user
 func (us *UserController) DeleteUser(c *gin.Context) {
-	userParam := c.Param("id")
-	userID, err := uuid.FromString(userParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
 		return
 	}
 
-	err = us.userService.DeleteUser(userID)
+	err := us.userService.DeleteUser(c.Request.Context(), userID)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		fail(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
 
-// ForgotPassword initiates the reset process
-func (us *UserController) ForgotPassword(c *gin.Context) {
-	var req struct {
-		Email string `json:"email" binding:"required,email"`
+// RestoreUser brings back a soft-deleted user
+func (us *UserController) RestoreUser(c *gin.Context) {
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	user, err := us.userService.RestoreUser(c.Request.Context(), userID)
+	if err != nil {
+		fail(c, err)
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
+}
+
+// ForgotPassword initiates the reset process. The response is the same
+// whether or not the address has an account.
+func (us *UserController) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	err := us.userService.ForgotPassword(req.Email)
+	err := us.userService.ForgotPassword(c.Request.Context(), req.Email, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Reset token sent to email if user exists"})
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link is on its way"})
 }
 
 // ResetPassword completes the password reset process
 func (us *UserController) ResetPassword(c *gin.Context) {
-	var req struct {
-		Token       string `json:"token" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+	var req dto.ResetPasswordRequest
+
+	if !bindJSON(c, &req) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+	err := us.userService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		fail(c, err)
 		return
 	}
 
-	tokenUUID, err := uuid.FromString(req.Token)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// GetMe returns the signed-in user's profile
+func (us *UserController) GetMe(c *gin.Context) {
+	userID, _ := session(c)
+
+	user, err := us.userService.GetProfile(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token format"})
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewProfileResponse(user))
+}
+
+// UpdateMe changes the signed-in user's profile
+func (us *UserController) UpdateMe(c *gin.Context) {
+	var req dto.UpdateProfileRequest
+
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	err = us.userService.ResetPassword(tokenUUID, req.NewPassword)
+	userID, _ := session(c)
+	user, err := us.userService.UpdateProfile(c.Request.Context(), userID, req.Changes())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fail(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	c.JSON(http.StatusOK, dto.NewProfileResponse(user))
+}
+
+// ChangePassword replaces the signed-in user's password and signs out their other sessions
+func (us *UserController) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, tokenID := session(c)
+	err := us.userService.ChangePassword(c.Request.Context(), userID, tokenID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed; other sessions have been signed out"})
+}
+
+// ChangeEmail sends a confirmation link to the requested new address
+func (us *UserController) ChangeEmail(c *gin.Context) {
+	var req dto.ChangeEmailRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, _ := session(c)
+	err := us.userService.RequestEmailChange(c.Request.Context(), userID, req.NewEmail, req.CurrentPassword)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation link sent to the new email address"})
+}
+
+// ConfirmEmailChange applies an email change from its emailed token
+func (us *UserController) ConfirmEmailChange(c *gin.Context) {
+	var req dto.ConfirmEmailChangeRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := us.userService.ConfirmEmailChange(c.Request.Context(), req.Token)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewProfileResponse(user))
+}
+
+// VerifyEmail marks the address in a verification link as verified
+func (us *UserController) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := us.userService.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewUserResponse(user))
+}
+
+// ResendVerification sends a new verification link
+func (us *UserController) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := us.userService.ResendVerification(c.Request.Context(), req.Email)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification link sent if the address belongs to an unverified account"})
+}
+
+// UnlockAccount lifts a sign-in lockout using the link mailed when it started
+func (us *UserController) UnlockAccount(c *gin.Context) {
+	var req dto.UnlockAccountRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := us.userService.UnlockAccount(c.Request.Context(), req.Token)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// GetMFA reports whether the signed-in user has MFA enabled and whether it is required
+func (us *UserController) GetMFA(c *gin.Context) {
+	userID, _ := session(c)
+
+	status, err := us.userService.GetMFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// StartMFA returns a new secret and provisioning URI for an authenticator app
+func (us *UserController) StartMFA(c *gin.Context) {
+	var req dto.StartMFARequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, _ := session(c)
+	setup, err := us.userService.StartMFAEnrollment(c.Request.Context(), userID, req.CurrentPassword)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmMFA enables MFA with a first code and returns the recovery codes
+func (us *UserController) ConfirmMFA(c *gin.Context) {
+	var req dto.ConfirmMFARequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, _ := session(c)
+	codes, err := us.userService.ConfirmMFAEnrollment(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the signed-in user
+func (us *UserController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.RegenerateRecoveryCodesRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, _ := session(c)
+	codes, err := us.userService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.CurrentPassword)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA turns MFA off for the signed-in user
+func (us *UserController) DisableMFA(c *gin.Context) {
+	var req dto.DisableMFARequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, _ := session(c)
+	err := us.userService.DisableMFA(c.Request.Context(), userID, req.CurrentPassword, req.Code)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ResetMFA removes another user's MFA enrollment and signs them out
+func (us *UserController) ResetMFA(c *gin.Context) {
+	userID, ok := paramUUID(c, "id", "user")
+	if !ok {
+		return
+	}
+
+	err := us.userService.ResetMFA(c.Request.Context(), userID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMFARequiredRoles lists the roles whose users must use MFA
+func (us *UserController) GetMFARequiredRoles(c *gin.Context) {
+	roles, err := us.userService.GetMFARequiredRoles(c.Request.Context())
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFARequiredRolesResponse{Roles: roles})
+}
+
+// SetMFARequiredRoles replaces the roles whose users must use MFA
+func (us *UserController) SetMFARequiredRoles(c *gin.Context) {
+	var req dto.SetMFARequiredRolesRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	roles, err := us.userService.SetMFARequiredRoles(c.Request.Context(), req.Roles)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFARequiredRolesResponse{Roles: roles})
+}
+
+// clientInfo describes the client making a login request
+func clientInfo(c *gin.Context) model.ClientInfo {
+	return model.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// session returns the user and session token IDs set by the auth middleware
+func session(c *gin.Context) (userID, tokenID uuid.UUID) {
+	if v, ok := c.Get("userID"); ok {
+		userID, _ = v.(uuid.UUID)
+	}
+	if v, ok := c.Get("tokenID"); ok {
+		tokenID, _ = v.(uuid.UUID)
+	}
+	return userID, tokenID
 }
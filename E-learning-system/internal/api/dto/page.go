@@ -0,0 +1,17 @@
+package dto
+
+import "e-learning-system/internal/domain/model"
+
+// mapPage converts the items of a page, keeping its total and cursors
+func mapPage[T, R any](page *model.Page[T], fn func(T) R) *model.Page[R] {
+	items := make([]R, len(page.Items))
+	for i, item := range page.Items {
+		items[i] = fn(item)
+	}
+	return &model.Page[R]{
+		Items:      items,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		Next:       page.Next,
+	}
+}
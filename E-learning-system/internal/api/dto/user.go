@@ -0,0 +1,302 @@
+// Package dto holds the request and response bodies of the HTTP API and
+// maps them to and from domain models, so internal fields such as password
+// hashes and reset tokens never reach clients.
+package dto
+
+import (
+	"e-learning-system/internal/domain/model"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// defaultRole is given to users who register. Instructors and admins are
+// appointed by an admin through PUT /users/:id/role.
+const defaultRole = "student"
+
+// RegisterUserRequest is the body of POST /users
+type RegisterUserRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"` // checked against the password policy
+	FirstName string `json:"first_name" binding:"max=100"`
+	LastName  string `json:"last_name" binding:"max=100"`
+	Role      string `json:"role" binding:"omitempty,oneof=student"`
+}
+
+// RoleOrDefault returns the requested role, or student when none was given
+func (r RegisterUserRequest) RoleOrDefault() string {
+	if r.Role == "" {
+		return defaultRole
+	}
+	return r.Role
+}
+
+// LoginRequest is the body of POST /users/authenticate
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,max=1024"`
+}
+
+// RenewPasswordRequest is the body of POST /users/authenticate/renew-password,
+// used instead of a login when the password has expired
+type RenewPasswordRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required,max=1024"`
+	NewPassword     string `json:"new_password" binding:"required"` // checked against the password policy
+}
+
+// UpdateUserRequest is the body of PUT /users/:id. Only the fields listed
+// here can be changed; omitted fields keep their value. Role and password
+// have their own flows.
+type UpdateUserRequest struct {
+	Email     *string `json:"email" binding:"omitempty,email"`
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=100"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=100"`
+}
+
+// Changes maps the request to the domain update allowlist
+func (r UpdateUserRequest) Changes() model.UserChanges {
+	return model.UserChanges{
+		Email:     r.Email,
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+	}
+}
+
+// ForgotPasswordRequest is the body of POST /users/forgot-password
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest is the body of POST /users/reset-password
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,max=256"`
+	NewPassword string `json:"new_password" binding:"required"` // checked against the password policy
+}
+
+// UserResponse is the public representation of a user
+type UserResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"email_verified"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // only listed with include_deleted
+}
+
+// NewUserResponse maps a user to its public representation
+func NewUserResponse(u *model.User) UserResponse {
+	return UserResponse{
+		ID:            u.ID,
+		Email:         u.Email,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		DeletedAt:     u.DeletedAt,
+	}
+}
+
+// NewUserPage maps a page of users to their public representation
+func NewUserPage(page *model.Page[*model.User]) *model.Page[UserResponse] {
+	return mapPage(page, NewUserResponse)
+}
+
+// LoginResponse is returned by a successful login. AccessToken goes in the
+// Authorization header as "Bearer <token>".
+type LoginResponse struct {
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        UserResponse `json:"user"`
+}
+
+// NewLoginResponse maps a signed-in user and their session token
+func NewLoginResponse(u *model.User, token *model.Token) LoginResponse {
+	return LoginResponse{
+		AccessToken: token.Token,
+		TokenType:   "Bearer",
+		ExpiresAt:   token.ExpiresAt,
+		User:        NewUserResponse(u),
+	}
+}
+
+// MFAChallengeResponse is returned instead of a session when the password
+// was right and a second factor is needed. The challenge token is sent to
+// POST /users/authenticate/mfa together with a code.
+type MFAChallengeResponse struct {
+	MFARequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// NewMFAChallengeResponse maps a login challenge
+func NewMFAChallengeResponse(challenge *model.MFAChallenge) MFAChallengeResponse {
+	return MFAChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: challenge.Token,
+		ExpiresAt:      challenge.ExpiresAt,
+	}
+}
+
+// MFALoginRequest is the body of POST /users/authenticate/mfa. Code is a
+// six-digit code from the authenticator app or a recovery code.
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
+// SSODiscoverRequest is the body of POST /sso/discover
+type SSODiscoverRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// SSODiscoverResponse lists the organizations an email address can sign in to with SSO
+type SSODiscoverResponse struct {
+	Organizations []model.SSOOrganization `json:"organizations"`
+}
+
+// SSOCallbackRequest is the body of POST /sso/callback. StateToken is the
+// one returned by POST /sso/:id/start; State and Code are the query
+// parameters the identity provider redirected back with.
+type SSOCallbackRequest struct {
+	StateToken string `json:"state_token" binding:"required"`
+	State      string `json:"state" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+}
+
+// SAMLCallbackRequest is the body of POST /saml/callback. StateToken is the
+// one returned by POST /saml/:id/start; Code is from the fragment of the
+// page the assertion consumer redirected to.
+type SAMLCallbackRequest struct {
+	StateToken string `json:"state_token" binding:"required"`
+	Code       string `json:"code" binding:"required,max=64"`
+}
+
+// ProfileResponse is the signed-in user's own record, returned by /users/me
+type ProfileResponse struct {
+	UserResponse
+	AvatarURL string `json:"avatar_url"`
+	Bio       string `json:"bio"`
+	Locale    string `json:"locale"`
+	Timezone  string `json:"timezone"`
+}
+
+// NewProfileResponse maps a user to their own profile
+func NewProfileResponse(u *model.User) ProfileResponse {
+	return ProfileResponse{
+		UserResponse: NewUserResponse(u),
+		AvatarURL:    u.AvatarURL,
+		Bio:          u.Bio,
+		Locale:       u.Locale,
+		Timezone:     u.Timezone,
+	}
+}
+
+// UpdateProfileRequest is the body of PATCH /users/me. Omitted fields keep
+// their value; an empty string clears the optional ones. Email and password
+// have their own endpoints.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=100"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=100"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,url,max=2048"`
+	Bio       *string `json:"bio" binding:"omitempty,max=1000"`
+	Locale    *string `json:"locale" binding:"omitempty,max=35"`
+	Timezone  *string `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// Changes maps the request to the domain profile changes
+func (r UpdateProfileRequest) Changes() model.ProfileChanges {
+	return model.ProfileChanges{
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		AvatarURL: r.AvatarURL,
+		Bio:       r.Bio,
+		Locale:    r.Locale,
+		Timezone:  r.Timezone,
+	}
+}
+
+// ChangePasswordRequest is the body of POST /users/me/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"` // checked against the password policy
+}
+
+// ChangeEmailRequest is the body of POST /users/me/email
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" binding:"required,email,max=255"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// ConfirmEmailChangeRequest is the body of POST /users/email/confirm
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmailRequest is the body of POST /users/verify-email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest is the body of POST /users/verify-email/resend
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// UnlockAccountRequest is the body of POST /users/unlock
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// StartMFARequest is the body of POST /users/me/mfa
+type StartMFARequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// ConfirmMFARequest is the body of POST /users/me/mfa/confirm
+type ConfirmMFARequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// RegenerateRecoveryCodesRequest is the body of POST /users/me/mfa/recovery-codes
+type RegenerateRecoveryCodesRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// DisableMFARequest is the body of POST /users/me/mfa/disable
+type DisableMFARequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code" binding:"required,max=32"`
+}
+
+// RecoveryCodesResponse carries newly issued recovery codes. They are not
+// stored in readable form and cannot be shown again.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ChangeUserRoleRequest is the body of PUT /users/:id/role
+type ChangeUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin instructor student"`
+}
+
+// SetMFARequiredRolesRequest is the body of PUT /users/mfa/required-roles
+type SetMFARequiredRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,dive,oneof=admin instructor student"`
+}
+
+// MFARequiredRolesResponse lists the roles whose users must use MFA
+type MFARequiredRolesResponse struct {
+	Roles []string `json:"roles"`
+}
+
+// EraseAccountRequest is the body of POST /users/me/erase
+type EraseAccountRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
@@ -1,23 +1,26 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
-	"fmt"
+	"e-learning-system/internal/tracing"
 	"log"
+	"log/slog"
 
 	"github.com/gofrs/uuid"
 )
 
 type AdminRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Delete implements repository.AdminRepository.
-func (r *AdminRepositoryImpl) Delete(AdminID uuid.UUID) error {
+func (r *AdminRepositoryImpl) Delete(ctx context.Context, AdminID uuid.UUID) error {
 	//Delete performs a soft delete of an admin
-	_, err := r.db.Exec(`Call Delete Admin($1)`, AdminID)
+	_, err := r.db.ExecContext(ctx, `Call Delete Admin($1)`, AdminID)
 	if err != nil {
 		log.Printf("error calling delete_admin for ID %v: %v", AdminID, err)
 		return err
@@ -27,8 +30,8 @@ func (r *AdminRepositoryImpl) Delete(AdminID uuid.UUID) error {
 }
 
 // GetAll implements repository.AdminRepository.
-func (r *AdminRepositoryImpl) GetAll() ([]*model.Admin, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_admins()`)
+func (r *AdminRepositoryImpl) GetAll(ctx context.Context) ([]*model.Admin, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_all_admins()`)
 	if err != nil {
 		log.Printf("Error querying get_all_admins: %v", err)
 		return nil, err
@@ -67,11 +70,11 @@ func (r *AdminRepositoryImpl) GetAll() ([]*model.Admin, error) {
 }
 
 // GetByID implements repository.AdminRepository.
-func (r *AdminRepositoryImpl) GetByID(AdminID uuid.UUID) (*model.Admin, error) {
+func (r *AdminRepositoryImpl) GetByID(ctx context.Context, AdminID uuid.UUID) (*model.Admin, error) {
 
 	var admin model.Admin
 
-	row := r.db.QueryRow(`SELECT * FROM get_admin_by_id($1)`, AdminID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_admin_by_id($1)`, AdminID)
 	err := row.Scan(
 		&admin.ID,
 		&admin.UserID,
@@ -87,19 +90,19 @@ func (r *AdminRepositoryImpl) GetByID(AdminID uuid.UUID) (*model.Admin, error) {
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Admin not found with ID: %v", AdminID)
-			return nil, fmt.Errorf("admin not found")
+			return nil, apperr.NotFound("admin_not_found", "admin not found")
 		}
 		log.Printf("Error scanning admin by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("Admin retrieved by ID: %+v", admin)
+	slog.Debug("Admin retrieved by ID", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return &admin, nil
 	} 
 	
 // Create inserts a new admin using the stored procedure
-func (r AdminRepositoryImpl) Create(admin *model.Admin) error {
-	_, err := r.db.Exec(`CALL create_admin($1,$2, $3, $4, $5)`,
+func (r AdminRepositoryImpl) Create(ctx context.Context, admin *model.Admin) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_admin($1,$2, $3, $4, $5)`,
 		admin.UserID, admin.OrganizationID, admin.Role, admin.Permission, admin.Status,
 	)
 	if err != nil {
@@ -107,25 +110,25 @@ func (r AdminRepositoryImpl) Create(admin *model.Admin) error {
 		return err
 	}
 
-	log.Printf("Admin created: %+v", admin)
+	slog.Info("Admin created", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return nil
 
 }
 
 // Update modifies an existing admin using the stored procedure
-func (r AdminRepositoryImpl) Update(admin *model.Admin) error {
-	_, err := r.db.Exec(`call update admin($1, $2, $3, $4, $5, $6)`,
+func (r AdminRepositoryImpl) Update(ctx context.Context, admin *model.Admin) error {
+	_, err := r.db.ExecContext(ctx, `call update admin($1, $2, $3, $4, $5, $6)`,
 		admin.ID, admin.OrganizationID, admin.Role, admin.Permission, admin.Status, admin.LastLogin)
 	if err != nil {
 		log.Printf("Error calling update_admin: %v", err)
 		return err
 	}
 
-	log.Printf("Admin updated: %+v", admin)
+	slog.Info("Admin updated", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return nil
 }
 
 // Constructor
 func NewAdminRepository(db *sql.DB) repository.AdminRepository {
-	return &AdminRepositoryImpl{db: db}
+	return &AdminRepositoryImpl{db: tracing.WrapDB(db)}
 }
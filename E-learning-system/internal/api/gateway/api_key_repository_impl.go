@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create stores a new key
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, key *model.APIKey) error {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM create_api_key($1, $2, $3, $4, $5, $6, $7)`,
+		key.OrganizationID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(scopes),
+		key.CreatedBy,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		log.Printf("Error calling create_api_key: %v", err)
+		return writeError(err, "api_key")
+	}
+
+	slog.Info("API key created", "organization_id", key.OrganizationID, "api_key_id", key.ID, "prefix", key.Prefix)
+	return nil
+}
+
+// List returns the keys of an organization, newest first
+func (r *APIKeyRepositoryImpl) List(ctx context.Context, orgID uuid.UUID) ([]*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_api_keys($1)`, orgID)
+	if err != nil {
+		log.Printf("Error calling list_api_keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		var key model.APIKey
+		var scopes []string
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+		if err := rows.Scan(
+			&key.ID,
+			&key.OrganizationID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&scopes),
+			&key.CreatedBy,
+			&key.CreatedAt,
+			&expiresAt,
+			&lastUsedAt,
+			&revokedAt,
+		); err != nil {
+			log.Printf("Error scanning API key: %v", err)
+			return nil, err
+		}
+		setAPIKeyFields(&key, scopes, expiresAt, lastUsedAt, revokedAt)
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// FindByPrefix looks a key up for authentication
+func (r *APIKeyRepositoryImpl) FindByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes []string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM find_api_key_by_prefix($1)`, prefix).Scan(
+		&key.ID,
+		&key.OrganizationID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&key.OrganizationStatus,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("api_key_not_found", "API key not found")
+		}
+		log.Printf("Error calling find_api_key_by_prefix: %v", err)
+		return nil, err
+	}
+	setAPIKeyFields(&key, scopes, expiresAt, lastUsedAt, revokedAt)
+	return &key, nil
+}
+
+// Revoke disables a key of an organization
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, orgID, keyID uuid.UUID) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, `SELECT revoke_api_key($1, $2)`, orgID, keyID).Scan(&revoked)
+	if err != nil {
+		log.Printf("Error calling revoke_api_key: %v", err)
+		return false, err
+	}
+	if revoked {
+		slog.Info("API key revoked", "organization_id", orgID, "api_key_id", keyID)
+	}
+	return revoked, nil
+}
+
+// Touch records that a key was used
+func (r *APIKeyRepositoryImpl) Touch(ctx context.Context, keyID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL touch_api_key($1)`, keyID)
+	if err != nil {
+		log.Printf("Error calling touch_api_key: %v", err)
+		return err
+	}
+	return nil
+}
+
+// setAPIKeyFields copies the nullable and array columns of a key row onto key
+func setAPIKeyFields(key *model.APIKey, scopes []string, expiresAt, lastUsedAt, revokedAt sql.NullTime) {
+	key.Scopes = make([]model.AdminPermission, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = model.AdminPermission(scope)
+	}
+	key.ExpiresAt = nullTimePtr(expiresAt)
+	key.LastUsedAt = nullTimePtr(lastUsedAt)
+	key.RevokedAt = nullTimePtr(revokedAt)
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// NewAPIKeyRepository returns a new APIKeyRepository instance
+func NewAPIKeyRepository(db *sql.DB) repository.APIKeyRepository {
+	return &APIKeyRepositoryImpl{db: tracing.WrapDB(db)}
+}
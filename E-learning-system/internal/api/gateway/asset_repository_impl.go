@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"encoding/json"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type AssetRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create inserts asset metadata using the stored procedure
+func (r *AssetRepositoryImpl) Create(ctx context.Context, asset *model.Asset) error {
+	variants, err := json.Marshal(asset.Variants)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `CALL create_asset($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		asset.ID,
+		asset.OrganizationID,
+		asset.OwnerID,
+		asset.Purpose,
+		asset.FileName,
+		asset.ContentType,
+		asset.Size,
+		asset.StorageKey,
+		variants,
+	)
+	if err != nil {
+		log.Printf("Error calling create_asset: %v", err)
+		return writeError(err, "asset")
+	}
+
+	log.Printf("Asset created: %v (%s, %d bytes)", asset.ID, asset.ContentType, asset.Size)
+	return nil
+}
+
+// GetByID retrieves asset metadata by ID using the stored function
+func (r *AssetRepositoryImpl) GetByID(ctx context.Context, assetID uuid.UUID) (*model.Asset, error) {
+	var a model.Asset
+	var variants []byte
+
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_asset_by_id($1)`, assetID)
+	err := row.Scan(
+		&a.ID,
+		&a.OrganizationID,
+		&a.OwnerID,
+		&a.Purpose,
+		&a.FileName,
+		&a.ContentType,
+		&a.Size,
+		&a.StorageKey,
+		&variants,
+		&a.CreatedAt,
+		&a.DeletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Asset not found with ID: %v", assetID)
+			return nil, apperr.NotFound("asset_not_found", "asset not found")
+		}
+		log.Printf("Error scanning asset by ID: %v", err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal(variants, &a.Variants); err != nil {
+		log.Printf("Error decoding asset variants: %v", err)
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// Delete performs a soft delete of an asset using the stored procedure
+func (r *AssetRepositoryImpl) Delete(ctx context.Context, assetID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_asset($1)`, assetID)
+	if err != nil {
+		log.Printf("Error calling delete_asset for ID %v: %v", assetID, err)
+		return err
+	}
+
+	log.Printf("Asset soft-deleted: %v", assetID)
+	return nil
+}
+
+// Restore undeletes a soft-deleted asset using the stored function
+func (r *AssetRepositoryImpl) Restore(ctx context.Context, assetID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_asset($1)`, assetID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_asset for ID %v: %v", assetID, err)
+		return err
+	}
+	if !restored {
+		return apperr.NotFound("deleted_asset_not_found", "no deleted asset with this ID")
+	}
+
+	log.Printf("Asset restored: %v", assetID)
+	return nil
+}
+
+// Constructor
+func NewAssetRepository(db *sql.DB) repository.AssetRepository {
+	return &AssetRepositoryImpl{db: tracing.WrapDB(db)}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type AuditEventRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create appends an audit event using the stored procedure
+func (r *AuditEventRepositoryImpl) Create(ctx context.Context, event *model.AuditEvent) error {
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `CALL record_audit_event($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+		event.OrganizationID,
+		event.ActorID,
+		event.APIKeyID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		string(changes),
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+	)
+	if err != nil {
+		log.Printf("Error calling record_audit_event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// List retrieves one page of audit events matching the filter
+func (r *AuditEventRepositoryImpl) List(ctx context.Context, filter model.AuditEventFilter, page model.PageRequest) (*model.Page[*model.AuditEvent], error) {
+	action := nullIfEmpty(filter.Action)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_audit_events($1,$2,$3,$4,$5)`,
+		filter.OrganizationID, filter.ActorID, action, filter.From, filter.To).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_audit_events: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, filter.ActorID, action, filter.From, filter.To}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_audit_events($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`, args...)
+	if err != nil {
+		log.Printf("Error querying list_audit_events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		var e model.AuditEvent
+		var orgID, actorID, apiKeyID, targetID uuid.NullUUID
+		var changes []byte
+		err := rows.Scan(
+			&e.ID,
+			&orgID,
+			&actorID,
+			&apiKeyID,
+			&e.Action,
+			&e.TargetType,
+			&targetID,
+			&changes,
+			&e.IP,
+			&e.UserAgent,
+			&e.RequestID,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning audit event row: %v", err)
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode audit changes of %s: %w", e.ID, err)
+		}
+		e.OrganizationID = nullUUIDPtr(orgID)
+		e.ActorID = nullUUIDPtr(actorID)
+		e.APIKeyID = nullUUIDPtr(apiKeyID)
+		e.TargetID = nullUUIDPtr(targetID)
+		events = append(events, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return newPage(events, total, page, func(e *model.AuditEvent) (string, uuid.UUID) {
+		return cursorTime(e.CreatedAt), e.ID
+	}), nil
+}
+
+// nullUUIDPtr converts a nullable UUID column to a pointer
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+// NewAuditEventRepository returns a new AuditEventRepository instance
+func NewAuditEventRepository(db *sql.DB) repository.AuditEventRepository {
+	return &AuditEventRepositoryImpl{db: tracing.WrapDB(db)}
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type CourseRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create inserts a new course using the stored procedure
+func (r *CourseRepositoryImpl) Create(ctx context.Context, course *model.Course) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_course($1,$2,$3,$4,$5)`,
+		course.ID, course.OrganizationID, course.Title, course.Description, course.CreatedBy,
+	)
+	if err != nil {
+		log.Printf("Error calling create_course: %v", err)
+		return writeError(err, "course")
+	}
+
+	log.Printf("Course created: %v", course.ID)
+	return nil
+}
+
+// GetByID retrieves a single course by ID using the stored function
+func (r *CourseRepositoryImpl) GetByID(ctx context.Context, courseID uuid.UUID) (*model.Course, error) {
+	var c model.Course
+
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_course_by_id($1)`, courseID)
+	err := row.Scan(
+		&c.ID,
+		&c.OrganizationID,
+		&c.Title,
+		&c.Description,
+		&c.CreatedBy,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Course not found with ID: %v", courseID)
+			return nil, apperr.NotFound("course_not_found", "course not found")
+		}
+		log.Printf("Error scanning course by ID: %v", err)
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// List retrieves one page of courses matching the filter using the stored functions
+func (r *CourseRepositoryImpl) List(ctx context.Context, filter model.CourseFilter, page model.PageRequest) (*model.Page[*model.Course], error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_courses($1,$2)`, filter.OrganizationID, filter.IncludeDeleted).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_courses: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_courses($1,$2,$3,$4,$5,$6,$7)`, args...)
+	if err != nil {
+		log.Printf("Error querying list_courses: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []*model.Course
+	for rows.Next() {
+		var c model.Course
+		err := rows.Scan(
+			&c.ID,
+			&c.OrganizationID,
+			&c.Title,
+			&c.Description,
+			&c.CreatedBy,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+			&c.DeletedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning course row: %v", err)
+			return nil, err
+		}
+		courses = append(courses, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return newPage(courses, total, page, func(c *model.Course) (string, uuid.UUID) {
+		if page.Sort == "title" {
+			return c.Title, c.ID
+		}
+		return cursorTime(c.CreatedAt), c.ID
+	}), nil
+}
+
+// Delete soft-deletes a course and its lessons using the stored function
+func (r *CourseRepositoryImpl) Delete(ctx context.Context, courseID uuid.UUID) error {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, `SELECT delete_course($1)`, courseID).Scan(&deleted)
+	if err != nil {
+		log.Printf("Error calling delete_course for ID %v: %v", courseID, err)
+		return err
+	}
+	if !deleted {
+		return apperr.NotFound("course_not_found", "course not found")
+	}
+
+	log.Printf("Course soft-deleted: %v", courseID)
+	return nil
+}
+
+// Restore undeletes a soft-deleted course using the stored function
+func (r *CourseRepositoryImpl) Restore(ctx context.Context, courseID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_course($1)`, courseID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_course for ID %v: %v", courseID, err)
+		return writeError(err, "course")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_course_not_found", "no deleted course with this ID")
+	}
+
+	log.Printf("Course restored: %v", courseID)
+	return nil
+}
+
+// Constructor
+func NewCourseRepository(db *sql.DB) repository.CourseRepository {
+	return &CourseRepositoryImpl{db: tracing.WrapDB(db)}
+}
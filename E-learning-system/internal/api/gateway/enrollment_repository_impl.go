@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type EnrollmentRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create enrolls a user in a course using the stored procedure
+func (r *EnrollmentRepositoryImpl) Create(ctx context.Context, enrollment *model.Enrollment) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_enrollment($1,$2,$3,$4)`,
+		enrollment.ID, enrollment.CourseID, enrollment.UserID, enrollment.Status,
+	)
+	if err != nil {
+		log.Printf("Error calling create_enrollment: %v", err)
+		return writeError(err, "enrollment")
+	}
+
+	log.Printf("User %v enrolled in course %v", enrollment.UserID, enrollment.CourseID)
+	return nil
+}
+
+// IsEnrolled reports whether the user is enrolled in the course
+func (r *EnrollmentRepositoryImpl) IsEnrolled(ctx context.Context, userID, courseID uuid.UUID) (bool, error) {
+	var enrolled bool
+	err := r.db.QueryRowContext(ctx, `SELECT is_user_enrolled($1, $2)`, userID, courseID).Scan(&enrolled)
+	if err != nil {
+		log.Printf("Error calling is_user_enrolled: %v", err)
+		return false, err
+	}
+	return enrolled, nil
+}
+
+// GetByUser retrieves every enrollment of a user
+func (r *EnrollmentRepositoryImpl) GetByUser(ctx context.Context, userID uuid.UUID) ([]*model.Enrollment, error) {
+	return r.queryEnrollments(ctx, "get_enrollments_by_user", userID)
+}
+
+// GetByCourse retrieves every enrollment of a course, pending ones included
+func (r *EnrollmentRepositoryImpl) GetByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Enrollment, error) {
+	return r.queryEnrollments(ctx, "get_enrollments_by_course", courseID)
+}
+
+// Approve activates a pending enrollment using the stored function
+func (r *EnrollmentRepositoryImpl) Approve(ctx context.Context, enrollmentID, courseID uuid.UUID) error {
+	var approved bool
+	err := r.db.QueryRowContext(ctx, `SELECT approve_enrollment($1, $2)`, enrollmentID, courseID).Scan(&approved)
+	if err != nil {
+		log.Printf("Error calling approve_enrollment: %v", err)
+		return err
+	}
+	if !approved {
+		return apperr.NotFound("enrollment_not_found", "no pending enrollment with this ID")
+	}
+	return nil
+}
+
+// queryEnrollments runs a stored function returning enrollment rows
+func (r *EnrollmentRepositoryImpl) queryEnrollments(ctx context.Context, fn string, id uuid.UUID) ([]*model.Enrollment, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM `+fn+`($1)`, id)
+	if err != nil {
+		log.Printf("Error querying %s: %v", fn, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enrollments []*model.Enrollment
+	for rows.Next() {
+		var e model.Enrollment
+		if err := rows.Scan(&e.ID, &e.CourseID, &e.UserID, &e.Status, &e.CreatedAt); err != nil {
+			log.Printf("Error scanning enrollment row: %v", err)
+			return nil, err
+		}
+		enrollments = append(enrollments, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+// Constructor
+func NewEnrollmentRepository(db *sql.DB) repository.EnrollmentRepository {
+	return &EnrollmentRepositoryImpl{db: tracing.WrapDB(db)}
+}
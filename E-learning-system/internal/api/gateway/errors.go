@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL error codes we translate into domain errors
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// writeError translates constraint violations of an insert or update into
+// domain errors; entity names the record in snake case, e.g. "organization_admin".
+// Other errors pass through.
+func writeError(err error, entity string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	name := strings.ReplaceAll(entity, "_", " ")
+
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		return apperr.Conflict(entity+"_exists", name+" already exists").Wrap(err)
+	case pgForeignKeyViolation:
+		return apperr.Validation("invalid_reference", name+" refers to a record that does not exist").Wrap(err)
+	case pgCheckViolation:
+		return apperr.Validation("invalid_"+entity, name+" has an invalid value").Wrap(err)
+	}
+	return err
+}
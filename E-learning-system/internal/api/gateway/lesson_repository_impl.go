@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type LessonRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// scanLesson reads one row of the lesson functions
+func scanLesson(row interface{ Scan(...any) error }) (*model.Lesson, error) {
+	var l model.Lesson
+	err := row.Scan(
+		&l.ID,
+		&l.CourseID,
+		&l.Title,
+		&l.Position,
+		&l.VideoStatus,
+		&l.VideoError,
+		&l.VideoSourceKey,
+		&l.VideoPlaybackType,
+		&l.VideoPlaylistKey,
+		&l.VideoThumbnailKey,
+		&l.VideoDuration,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Create inserts a new lesson using the stored procedure
+func (r *LessonRepositoryImpl) Create(ctx context.Context, lesson *model.Lesson) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_lesson($1,$2,$3,$4)`,
+		lesson.ID, lesson.CourseID, lesson.Title, lesson.Position,
+	)
+	if err != nil {
+		log.Printf("Error calling create_lesson: %v", err)
+		return writeError(err, "lesson")
+	}
+
+	log.Printf("Lesson created: %v", lesson.ID)
+	return nil
+}
+
+// GetByID retrieves a single lesson by ID using the stored function
+func (r *LessonRepositoryImpl) GetByID(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error) {
+	lesson, err := scanLesson(r.db.QueryRowContext(ctx, `SELECT * FROM get_lesson_by_id($1)`, lessonID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Lesson not found with ID: %v", lessonID)
+			return nil, apperr.NotFound("lesson_not_found", "lesson not found")
+		}
+		log.Printf("Error scanning lesson by ID: %v", err)
+		return nil, err
+	}
+
+	return lesson, nil
+}
+
+// GetByCourse retrieves the lessons of a course in order
+func (r *LessonRepositoryImpl) GetByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Lesson, error) {
+	return r.query(ctx, `SELECT * FROM get_lessons_by_course($1)`, courseID)
+}
+
+// Delete soft-deletes a lesson using the stored function
+func (r *LessonRepositoryImpl) Delete(ctx context.Context, lessonID uuid.UUID) error {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, `SELECT delete_lesson($1)`, lessonID).Scan(&deleted)
+	if err != nil {
+		log.Printf("Error calling delete_lesson for ID %v: %v", lessonID, err)
+		return err
+	}
+	if !deleted {
+		return apperr.NotFound("lesson_not_found", "lesson not found")
+	}
+
+	log.Printf("Lesson soft-deleted: %v", lessonID)
+	return nil
+}
+
+// Restore undeletes a soft-deleted lesson using the stored function. The
+// function refuses lessons of a deleted course as a foreign key violation.
+func (r *LessonRepositoryImpl) Restore(ctx context.Context, lessonID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_lesson($1)`, lessonID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_lesson for ID %v: %v", lessonID, err)
+		return writeError(err, "lesson")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_lesson_not_found", "no deleted lesson with this ID")
+	}
+
+	log.Printf("Lesson restored: %v", lessonID)
+	return nil
+}
+
+// GetByVideoStatus retrieves lessons whose video is in the given state
+func (r *LessonRepositoryImpl) GetByVideoStatus(ctx context.Context, status model.VideoStatus) ([]*model.Lesson, error) {
+	return r.query(ctx, `SELECT * FROM get_lessons_by_video_status($1)`, status)
+}
+
+func (r *LessonRepositoryImpl) query(ctx context.Context, query string, args ...any) ([]*model.Lesson, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("Error querying lessons: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lessons []*model.Lesson
+	for rows.Next() {
+		lesson, err := scanLesson(rows)
+		if err != nil {
+			log.Printf("Error scanning lesson row: %v", err)
+			return nil, err
+		}
+		lessons = append(lessons, lesson)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return lessons, nil
+}
+
+// UpdateVideo saves the video fields of a lesson using the stored procedure
+func (r *LessonRepositoryImpl) UpdateVideo(ctx context.Context, lesson *model.Lesson) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_lesson_video($1,$2,$3,$4,$5,$6,$7,$8)`,
+		lesson.ID,
+		lesson.VideoStatus,
+		lesson.VideoError,
+		lesson.VideoSourceKey,
+		lesson.VideoPlaybackType,
+		lesson.VideoPlaylistKey,
+		lesson.VideoThumbnailKey,
+		lesson.VideoDuration,
+	)
+	if err != nil {
+		log.Printf("Error calling update_lesson_video: %v", err)
+		return err
+	}
+
+	log.Printf("Lesson %v video status: %s", lesson.ID, lesson.VideoStatus)
+	return nil
+}
+
+// ClaimVideoProcessing moves an uploaded video to processing, returning false if already claimed
+func (r *LessonRepositoryImpl) ClaimVideoProcessing(ctx context.Context, lessonID uuid.UUID) (bool, error) {
+	var claimed bool
+	err := r.db.QueryRowContext(ctx, `SELECT claim_lesson_video_processing($1)`, lessonID).Scan(&claimed)
+	if err != nil {
+		log.Printf("Error calling claim_lesson_video_processing: %v", err)
+		return false, err
+	}
+	return claimed, nil
+}
+
+// ReleaseStaleVideoClaims puts videos claimed before the given time back to uploaded
+func (r *LessonRepositoryImpl) ReleaseStaleVideoClaims(ctx context.Context, claimedBefore time.Time) (int, error) {
+	var released int
+	err := r.db.QueryRowContext(ctx, `SELECT release_stale_lesson_video_claims($1)`, claimedBefore).Scan(&released)
+	if err != nil {
+		log.Printf("Error calling release_stale_lesson_video_claims: %v", err)
+		return 0, err
+	}
+	return released, nil
+}
+
+// Constructor
+func NewLessonRepository(db *sql.DB) repository.LessonRepository {
+	return &LessonRepositoryImpl{db: tracing.WrapDB(db)}
+}
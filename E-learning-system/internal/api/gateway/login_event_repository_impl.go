@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+)
+
+type LoginEventRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create records a sign-in attempt using the stored procedure
+func (r *LoginEventRepositoryImpl) Create(ctx context.Context, event *model.LoginEvent) error {
+	_, err := r.db.ExecContext(ctx, `CALL record_login_event($1,$2,$3,$4,$5,$6)`,
+		event.UserID,
+		event.Email,
+		event.Outcome,
+		event.Reason,
+		event.IP,
+		event.UserAgent,
+	)
+	if err != nil {
+		log.Printf("Error calling record_login_event: %v", err)
+		return err
+	}
+	return nil
+}
+
+// NewLoginEventRepository returns a new LoginEventRepository instance
+func NewLoginEventRepository(db *sql.DB) repository.LoginEventRepository {
+	return &LoginEventRepositoryImpl{db: tracing.WrapDB(db)}
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+	"log/slog"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type MFARepositoryImpl struct {
+	db *tracing.DB
+}
+
+// StartEnrollment stores a pending secret, replacing an earlier pending one
+func (r *MFARepositoryImpl) StartEnrollment(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	var started bool
+	err := r.db.QueryRowContext(ctx, `SELECT start_mfa_enrollment($1, $2)`, userID, secret).Scan(&started)
+	if err != nil {
+		log.Printf("Error calling start_mfa_enrollment: %v", err)
+		return false, err
+	}
+	return started, nil
+}
+
+// Get retrieves the enrollment of a user, pending or enabled
+func (r *MFARepositoryImpl) Get(ctx context.Context, userID uuid.UUID) (*model.MFAEnrollment, error) {
+	var enrollment model.MFAEnrollment
+	var enabledAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM get_user_mfa($1)`, userID).Scan(
+		&enrollment.UserID,
+		&enrollment.Secret,
+		&enrollment.Enabled,
+		&enrollment.LastUsedStep,
+		&enabledAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("mfa_not_enrolled", "two-factor authentication is not set up")
+		}
+		log.Printf("Error calling get_user_mfa: %v", err)
+		return nil, err
+	}
+	if enabledAt.Valid {
+		enrollment.EnabledAt = &enabledAt.Time
+	}
+	return &enrollment, nil
+}
+
+// Enable confirms a pending enrollment and stores the recovery code hashes
+func (r *MFARepositoryImpl) Enable(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRowContext(ctx, `SELECT enable_mfa($1, $2, $3)`, userID, step, pq.Array(codeHashes)).Scan(&enabled)
+	if err != nil {
+		log.Printf("Error calling enable_mfa: %v", err)
+		return false, err
+	}
+	if enabled {
+		slog.Info("MFA enabled", "user_id", userID)
+	}
+	return enabled, nil
+}
+
+// Disable removes the enrollment and recovery codes of a user
+func (r *MFARepositoryImpl) Disable(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var disabled bool
+	err := r.db.QueryRowContext(ctx, `SELECT disable_mfa($1)`, userID).Scan(&disabled)
+	if err != nil {
+		log.Printf("Error calling disable_mfa: %v", err)
+		return false, err
+	}
+	if disabled {
+		slog.Info("MFA disabled", "user_id", userID)
+	}
+	return disabled, nil
+}
+
+// UseStep records the time step of an accepted code, refusing replays
+func (r *MFARepositoryImpl) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	var used bool
+	err := r.db.QueryRowContext(ctx, `SELECT use_mfa_step($1, $2)`, userID, step).Scan(&used)
+	if err != nil {
+		log.Printf("Error calling use_mfa_step: %v", err)
+		return false, err
+	}
+	return used, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+func (r *MFARepositoryImpl) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	var used bool
+	err := r.db.QueryRowContext(ctx, `SELECT use_recovery_code($1, $2)`, userID, codeHash).Scan(&used)
+	if err != nil {
+		log.Printf("Error calling use_recovery_code: %v", err)
+		return false, err
+	}
+	return used, nil
+}
+
+// ReplaceRecoveryCodes swaps all recovery codes of an enabled enrollment
+func (r *MFARepositoryImpl) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) (bool, error) {
+	var replaced bool
+	err := r.db.QueryRowContext(ctx, `SELECT replace_recovery_codes($1, $2)`, userID, pq.Array(codeHashes)).Scan(&replaced)
+	if err != nil {
+		log.Printf("Error calling replace_recovery_codes: %v", err)
+		return false, err
+	}
+	return replaced, nil
+}
+
+// GetStatus reports whether a user has MFA enabled and whether it is required
+func (r *MFARepositoryImpl) GetStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error) {
+	var status model.MFAStatus
+	var enabledAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM get_mfa_status($1)`, userID).Scan(
+		&status.Enabled,
+		&status.Required,
+		&status.RecoveryCodesRemaining,
+		&enabledAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user_not_found", "user not found")
+		}
+		log.Printf("Error calling get_mfa_status: %v", err)
+		return nil, err
+	}
+	if enabledAt.Valid {
+		status.EnabledAt = &enabledAt.Time
+	}
+	return &status, nil
+}
+
+// GetRequiredRoles lists the roles whose users must use MFA
+func (r *MFARepositoryImpl) GetRequiredRoles(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_mfa_required_roles()`)
+	if err != nil {
+		log.Printf("Error calling get_mfa_required_roles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			log.Printf("Error scanning MFA role: %v", err)
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SetRequiredRoles replaces the roles whose users must use MFA
+func (r *MFARepositoryImpl) SetRequiredRoles(ctx context.Context, roles []string) error {
+	_, err := r.db.ExecContext(ctx, `CALL set_mfa_required_roles($1)`, pq.Array(roles))
+	if err != nil {
+		log.Printf("Error calling set_mfa_required_roles: %v", err)
+		return err
+	}
+	slog.Info("MFA required roles updated", "roles", roles)
+	return nil
+}
+
+// NewMFARepository returns a new MFARepository instance
+func NewMFARepository(db *sql.DB) repository.MFARepository {
+	return &MFARepositoryImpl{db: tracing.WrapDB(db)}
+}
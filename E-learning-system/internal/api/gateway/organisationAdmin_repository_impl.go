@@ -1,50 +1,53 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
-	"fmt"
+	"e-learning-system/internal/tracing"
 	"log"
+	"log/slog"
 
 	"github.com/gofrs/uuid"
 )
 
 type OrganizationAdminRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create inserts a new admin using the stored procedure
-func (r *OrganizationAdminRepositoryImpl) Create(admin *model.OrganizationAdmin) error {
-	_, err := r.db.Exec(`CALL create_organization_admin($1, $2, $3)`,
+func (r *OrganizationAdminRepositoryImpl) Create(ctx context.Context, admin *model.OrganizationAdmin) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_organization_admin($1, $2, $3)`,
 		admin.UserID, admin.OrganizationID, admin.Role,
 	)
 	if err != nil {
 		log.Printf("Error calling create_organization_admin: %v", err)
-		return err
+		return writeError(err, "organization_admin")
 	}
 
-	log.Printf("OrganizationAdmin created: %+v", admin)
+	slog.Info("OrganizationAdmin created", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return nil
 }
 
 // Update modifies an existing admin using the stored procedure
-func (r *OrganizationAdminRepositoryImpl) Update(admin *model.OrganizationAdmin) error {
-	_, err := r.db.Exec(`CALL update_organization_admin($1, $2)`,
+func (r *OrganizationAdminRepositoryImpl) Update(ctx context.Context, admin *model.OrganizationAdmin) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_organization_admin($1, $2)`,
 		admin.ID, admin.Role,
 	)
 	if err != nil {
 		log.Printf("Error calling update_organization_admin: %v", err)
-		return err
+		return writeError(err, "organization_admin")
 	}
 
-	log.Printf("OrganizationAdmin updated: %+v", admin)
+	slog.Info("OrganizationAdmin updated", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return nil
 }
 
 // Delete performs a soft delete of an admin using the stored procedure
-func (r *OrganizationAdminRepositoryImpl) Delete(adminID uuid.UUID) error {
-	_, err := r.db.Exec(`CALL delete_organization_admin($1)`, adminID)
+func (r *OrganizationAdminRepositoryImpl) Delete(ctx context.Context, adminID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_organization_admin($1)`, adminID)
 	if err != nil {
 		log.Printf("Error calling delete_organization_admin for ID %v: %v", adminID, err)
 		return err
@@ -54,11 +57,48 @@ func (r *OrganizationAdminRepositoryImpl) Delete(adminID uuid.UUID) error {
 	return nil
 }
 
-// GetAll retrieves all admins using the stored function
-func (r *OrganizationAdminRepositoryImpl) GetAll() ([]*model.OrganizationAdmin, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_organization_admins()`)
+// Restore undeletes a soft-deleted admin using the stored function
+func (r *OrganizationAdminRepositoryImpl) Restore(ctx context.Context, adminID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_organization_admin($1)`, adminID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_organization_admin for ID %v: %v", adminID, err)
+		return writeError(err, "organization_admin")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_organization_admin_not_found", "no deleted organization admin with this ID")
+	}
+
+	log.Printf("OrganizationAdmin restored: %v", adminID)
+	return nil
+}
+
+// IsAdmin reports whether the user administers the organization using the stored function
+func (r *OrganizationAdminRepositoryImpl) IsAdmin(ctx context.Context, orgID, userID uuid.UUID) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRowContext(ctx, `SELECT is_organization_admin($1, $2)`, orgID, userID).Scan(&isAdmin)
+	if err != nil {
+		log.Printf("Error calling is_organization_admin: %v", err)
+		return false, err
+	}
+	return isAdmin, nil
+}
+
+// List retrieves one page of organization admins matching the filter using the stored functions
+func (r *OrganizationAdminRepositoryImpl) List(ctx context.Context, filter model.OrganizationAdminFilter, page model.PageRequest) (*model.Page[*model.OrganizationAdmin], error) {
+	role := nullIfEmpty(filter.Role)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_organization_admins($1,$2,$3)`, filter.OrganizationID, role, filter.IncludeDeleted).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_organization_admins: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, role, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_organization_admins($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
 	if err != nil {
-		log.Printf("Error querying get_all_organization_admins: %v", err)
+		log.Printf("Error querying list_organization_admins: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -72,6 +112,7 @@ func (r *OrganizationAdminRepositoryImpl) GetAll() ([]*model.OrganizationAdmin,
 			&a.OrganizationID,
 			&a.Role,
 			&a.CreatedAt,
+			&a.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning admin row: %v", err)
@@ -86,35 +127,38 @@ func (r *OrganizationAdminRepositoryImpl) GetAll() ([]*model.OrganizationAdmin,
 	}
 
 	log.Printf("OrganizationAdmins retrieved: %d", len(admins))
-	return admins, nil
+	return newPage(admins, total, page, func(a *model.OrganizationAdmin) (string, uuid.UUID) {
+		return cursorTime(a.CreatedAt), a.ID
+	}), nil
 }
 
 // GetByID retrieves a single admin by ID using the stored function
-func (r *OrganizationAdminRepositoryImpl) GetByID(adminID uuid.UUID) (*model.OrganizationAdmin, error) {
+func (r *OrganizationAdminRepositoryImpl) GetByID(ctx context.Context, adminID uuid.UUID) (*model.OrganizationAdmin, error) {
 	var a model.OrganizationAdmin
 
-	row := r.db.QueryRow(`SELECT * FROM get_organization_admin_by_id($1)`, adminID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_admin_by_id($1)`, adminID)
 	err := row.Scan(
 		&a.ID,
 		&a.UserID,
 		&a.OrganizationID,
 		&a.Role,
 		&a.CreatedAt,
+		&a.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("OrganizationAdmin not found with ID: %v", adminID)
-			return nil, fmt.Errorf("organization admin not found")
+			return nil, apperr.NotFound("organization_admin_not_found", "organization admin not found")
 		}
 		log.Printf("Error scanning admin by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("OrganizationAdmin retrieved by ID: %+v", a)
+	slog.Debug("OrganizationAdmin retrieved by ID", "admin_id", a.ID, "organization_id", a.OrganizationID)
 	return &a, nil
 }
 
 // Constructor
 func NewOrganizationAdminRepository(db *sql.DB) repository.OrganizationAdminRepository {
-	return &OrganizationAdminRepositoryImpl{db: db}
+	return &OrganizationAdminRepositoryImpl{db: tracing.WrapDB(db)}
 }
@@ -1,22 +1,25 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
 	"log"
-	"fmt"
+	"log/slog"
 
 	"github.com/gofrs/uuid"
 )
 
 type OrganizationBrandingRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create inserts a new branding record using the stored procedure
-func (r *OrganizationBrandingRepositoryImpl) Create(branding *model.OrganizationBranding) error {
-	_, err := r.db.Exec(`CALL create_organization_branding($1,$2,$3,$4,$5,$6)`,
+func (r *OrganizationBrandingRepositoryImpl) Create(ctx context.Context, branding *model.OrganizationBranding) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_organization_branding($1,$2,$3,$4,$5,$6)`,
 		branding.OrganizationID,
 		branding.LogoURL,
 		branding.PrimaryColor,
@@ -26,17 +29,17 @@ func (r *OrganizationBrandingRepositoryImpl) Create(branding *model.Organization
 	)
 	if err != nil {
 		log.Printf("Error calling create_organization_branding: %v", err)
-		return err
+		return writeError(err, "organization_branding")
 	}
 
-	log.Printf("OrganizationBranding created: %+v", branding)
+	slog.Info("OrganizationBranding created", "branding_id", branding.ID, "organization_id", branding.OrganizationID)
 	return nil
 }
 
 // Update modifies an existing branding record using the stored procedure
-func (r *OrganizationBrandingRepositoryImpl) Update(branding *model.OrganizationBranding) error {
-	_, err := r.db.Exec(`CALL update_organization_branding($1,$2,$3,$4,$5,$6)`,
-		branding.OrganizationID,
+func (r *OrganizationBrandingRepositoryImpl) Update(ctx context.Context, branding *model.OrganizationBranding) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_organization_branding($1,$2,$3,$4,$5,$6)`,
+		branding.ID,
 		branding.LogoURL,
 		branding.PrimaryColor,
 		branding.SecondaryColor,
@@ -45,30 +48,56 @@ func (r *OrganizationBrandingRepositoryImpl) Update(branding *model.Organization
 	)
 	if err != nil {
 		log.Printf("Error calling update_organization_branding: %v", err)
-		return err
+		return writeError(err, "organization_branding")
 	}
 
-	log.Printf("OrganizationBranding updated: %+v", branding)
+	slog.Info("OrganizationBranding updated", "branding_id", branding.ID, "organization_id", branding.OrganizationID)
 	return nil
 }
 
 // Delete performs a soft delete of a branding record using the stored procedure
-func (r *OrganizationBrandingRepositoryImpl) Delete(orgID uuid.UUID) error {
-	_, err := r.db.Exec(`CALL delete_organization_branding($1)`, orgID)
+func (r *OrganizationBrandingRepositoryImpl) Delete(ctx context.Context, brandingID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_organization_branding($1)`, brandingID)
 	if err != nil {
-		log.Printf("Error calling delete_organization_branding for ID %v: %v", orgID, err)
+		log.Printf("Error calling delete_organization_branding for ID %v: %v", brandingID, err)
 		return err
 	}
 
-	log.Printf("OrganizationBranding soft-deleted for organization: %v", orgID)
+	log.Printf("OrganizationBranding soft-deleted: %v", brandingID)
+	return nil
+}
+
+// Restore undeletes a soft-deleted branding record using the stored function
+func (r *OrganizationBrandingRepositoryImpl) Restore(ctx context.Context, brandingID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_organization_branding($1)`, brandingID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_organization_branding for ID %v: %v", brandingID, err)
+		return writeError(err, "organization_branding")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_organization_branding_not_found", "no deleted organization branding with this ID")
+	}
+
+	log.Printf("OrganizationBranding restored: %v", brandingID)
 	return nil
 }
 
-// GetAll retrieves all branding records using the stored function
-func (r *OrganizationBrandingRepositoryImpl) GetAll() ([]*model.OrganizationBranding, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_organization_brandings()`)
+// List retrieves one page of organization brandings matching the filter using the stored functions
+func (r *OrganizationBrandingRepositoryImpl) List(ctx context.Context, filter model.OrganizationBrandingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBranding], error) {
+	theme := nullIfEmpty(filter.Theme)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_organization_brandings($1,$2,$3)`, filter.OrganizationID, theme, filter.IncludeDeleted).Scan(&total)
 	if err != nil {
-		log.Printf("Error querying get_all_organization_brandings: %v", err)
+		log.Printf("Error calling count_organization_brandings: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, theme, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_organization_brandings($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
+	if err != nil {
+		log.Printf("Error querying list_organization_brandings: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -86,6 +115,7 @@ func (r *OrganizationBrandingRepositoryImpl) GetAll() ([]*model.OrganizationBran
 			&b.EmailTemplate,
 			&b.CreatedAt,
 			&b.UpdatedAt,
+			&b.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning branding row: %v", err)
@@ -100,14 +130,19 @@ func (r *OrganizationBrandingRepositoryImpl) GetAll() ([]*model.OrganizationBran
 	}
 
 	log.Printf("OrganizationBrandings retrieved: %d", len(brandings))
-	return brandings, nil
+	return newPage(brandings, total, page, func(b *model.OrganizationBranding) (string, uuid.UUID) {
+		if page.Sort == "updated_at" {
+			return cursorTime(b.UpdatedAt), b.ID
+		}
+		return cursorTime(b.CreatedAt), b.ID
+	}), nil
 }
 
-// GetByID retrieves a single branding record by organization ID using the stored function
-func (r *OrganizationBrandingRepositoryImpl) GetByID(orgID uuid.UUID) (*model.OrganizationBranding, error) {
+// GetByID retrieves a single branding record by ID using the stored function
+func (r *OrganizationBrandingRepositoryImpl) GetByID(ctx context.Context, brandingID uuid.UUID) (*model.OrganizationBranding, error) {
 	var b model.OrganizationBranding
 
-	row := r.db.QueryRow(`SELECT * FROM get_organization_branding_by_id($1)`, orgID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_branding_by_id($1)`, brandingID)
 	err := row.Scan(
 		&b.ID,
 		&b.OrganizationID,
@@ -118,21 +153,49 @@ func (r *OrganizationBrandingRepositoryImpl) GetByID(orgID uuid.UUID) (*model.Or
 		&b.EmailTemplate,
 		&b.CreatedAt,
 		&b.UpdatedAt,
+		&b.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			log.Printf("OrganizationBranding not found for organization ID: %v", orgID)
-			return nil, fmt.Errorf("organization branding not found")
+			log.Printf("OrganizationBranding not found with ID: %v", brandingID)
+			return nil, apperr.NotFound("organization_branding_not_found", "organization branding not found")
 		}
-		log.Printf("Error scanning branding by organization ID: %v", err)
+		log.Printf("Error scanning branding by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("OrganizationBranding retrieved for organization ID: %+v", b)
+	slog.Debug("OrganizationBranding retrieved", "branding_id", b.ID, "organization_id", b.OrganizationID)
 	return &b, nil
 }
 
+// GetManifestByDomain retrieves the public branding of an organization by its domain
+func (r *OrganizationBrandingRepositoryImpl) GetManifestByDomain(ctx context.Context, domain string) (*model.BrandingManifest, error) {
+	var m model.BrandingManifest
+
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_branding_manifest_by_domain($1)`, domain)
+	err := row.Scan(
+		&m.OrganizationID,
+		&m.Name,
+		&m.Domain,
+		&m.LogoURL,
+		&m.PrimaryColor,
+		&m.SecondaryColor,
+		&m.Theme,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("Branding not found for domain: %s", domain)
+			return nil, apperr.NotFound("organization_branding_not_found", "organization branding not found")
+		}
+		log.Printf("Error scanning branding manifest for domain %s: %v", domain, err)
+		return nil, err
+	}
+
+	return &m, nil
+}
+
 // Constructor
 func NewOrganizationBrandingRepository(db *sql.DB) repository.OrganizationBrandingRepository {
-	return &OrganizationBrandingRepositoryImpl{db: db}
+	return &OrganizationBrandingRepositoryImpl{db: tracing.WrapDB(db)}
 }
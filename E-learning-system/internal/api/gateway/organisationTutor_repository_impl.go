@@ -1,50 +1,53 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
-	"fmt"
+	"e-learning-system/internal/tracing"
 	"log"
+	"log/slog"
 
 	"github.com/gofrs/uuid"
 )
 
 type OrganizationTutorRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create inserts a new tutor using the stored procedure
-func (r *OrganizationTutorRepositoryImpl) Create(tutor *model.OrganizationTutor) error {
-	_, err := r.db.Exec(`CALL create_organization_tutor($1, $2, $3)`,
+func (r *OrganizationTutorRepositoryImpl) Create(ctx context.Context, tutor *model.OrganizationTutor) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_organization_tutor($1, $2, $3)`,
 		tutor.UserID, tutor.OrganizationID, tutor.Approved,
 	)
 	if err != nil {
 		log.Printf("Error calling create_organization_tutor: %v", err)
-		return err
+		return writeError(err, "organization_tutor")
 	}
 
-	log.Printf("OrganizationTutor created: %+v", tutor)
+	slog.Info("OrganizationTutor created", "tutor_id", tutor.ID, "organization_id", tutor.OrganizationID)
 	return nil
 }
 
 // Update modifies an existing tutor using the stored procedure
-func (r *OrganizationTutorRepositoryImpl) Update(tutor *model.OrganizationTutor) error {
-	_, err := r.db.Exec(`CALL update_organization_tutor($1, $2)`,
+func (r *OrganizationTutorRepositoryImpl) Update(ctx context.Context, tutor *model.OrganizationTutor) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_organization_tutor($1, $2)`,
 		tutor.ID, tutor.Approved,
 	)
 	if err != nil {
 		log.Printf("Error calling update_organization_tutor: %v", err)
-		return err
+		return writeError(err, "organization_tutor")
 	}
 
-	log.Printf("OrganizationTutor updated: %+v", tutor)
+	slog.Info("OrganizationTutor updated", "tutor_id", tutor.ID, "organization_id", tutor.OrganizationID)
 	return nil
 }
 
 // Delete performs a soft delete of a tutor using the stored procedure
-func (r *OrganizationTutorRepositoryImpl) Delete(tutorID uuid.UUID) error {
-	_, err := r.db.Exec(`CALL delete_organization_tutor($1)`, tutorID)
+func (r *OrganizationTutorRepositoryImpl) Delete(ctx context.Context, tutorID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_organization_tutor($1)`, tutorID)
 	if err != nil {
 		log.Printf("Error calling delete_organization_tutor for ID %v: %v", tutorID, err)
 		return err
@@ -54,11 +57,35 @@ func (r *OrganizationTutorRepositoryImpl) Delete(tutorID uuid.UUID) error {
 	return nil
 }
 
-// GetAll retrieves all tutors using the stored function
-func (r *OrganizationTutorRepositoryImpl) GetAll() ([]*model.OrganizationTutor, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_organization_tutors()`)
+// Restore undeletes a soft-deleted tutor using the stored function
+func (r *OrganizationTutorRepositoryImpl) Restore(ctx context.Context, tutorID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_organization_tutor($1)`, tutorID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_organization_tutor for ID %v: %v", tutorID, err)
+		return writeError(err, "organization_tutor")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_organization_tutor_not_found", "no deleted organization tutor with this ID")
+	}
+
+	log.Printf("OrganizationTutor restored: %v", tutorID)
+	return nil
+}
+
+// List retrieves one page of organization tutors matching the filter using the stored functions
+func (r *OrganizationTutorRepositoryImpl) List(ctx context.Context, filter model.OrganizationTutorFilter, page model.PageRequest) (*model.Page[*model.OrganizationTutor], error) {
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_organization_tutors($1,$2,$3)`, filter.OrganizationID, filter.Approved, filter.IncludeDeleted).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_organization_tutors: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, filter.Approved, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_organization_tutors($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
 	if err != nil {
-		log.Printf("Error querying get_all_organization_tutors: %v", err)
+		log.Printf("Error querying list_organization_tutors: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -72,6 +99,7 @@ func (r *OrganizationTutorRepositoryImpl) GetAll() ([]*model.OrganizationTutor,
 			&t.OrganizationID,
 			&t.Approved,
 			&t.CreatedAt,
+			&t.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning tutor row: %v", err)
@@ -86,35 +114,38 @@ func (r *OrganizationTutorRepositoryImpl) GetAll() ([]*model.OrganizationTutor,
 	}
 
 	log.Printf("OrganizationTutors retrieved: %d", len(tutors))
-	return tutors, nil
+	return newPage(tutors, total, page, func(t *model.OrganizationTutor) (string, uuid.UUID) {
+		return cursorTime(t.CreatedAt), t.ID
+	}), nil
 }
 
 // GetByID retrieves a single tutor by ID using the stored function
-func (r *OrganizationTutorRepositoryImpl) GetByID(tutorID uuid.UUID) (*model.OrganizationTutor, error) {
+func (r *OrganizationTutorRepositoryImpl) GetByID(ctx context.Context, tutorID uuid.UUID) (*model.OrganizationTutor, error) {
 	var t model.OrganizationTutor
 
-	row := r.db.QueryRow(`SELECT * FROM get_organization_tutor_by_id($1)`, tutorID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_tutor_by_id($1)`, tutorID)
 	err := row.Scan(
 		&t.ID,
 		&t.UserID,
 		&t.OrganizationID,
 		&t.Approved,
 		&t.CreatedAt,
+		&t.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("OrganizationTutor not found with ID: %v", tutorID)
-			return nil, fmt.Errorf("organization tutor not found")
+			return nil, apperr.NotFound("organization_tutor_not_found", "organization tutor not found")
 		}
 		log.Printf("Error scanning tutor by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("OrganizationTutor retrieved by ID: %+v", t)
+	slog.Debug("OrganizationTutor retrieved by ID", "tutor_id", t.ID, "organization_id", t.OrganizationID)
 	return &t, nil
 }
 
 // Constructor
 func NewOrganizationTutorRepository(db *sql.DB) repository.OrganizationTutorRepository {
-	return &OrganizationTutorRepositoryImpl{db: db}
+	return &OrganizationTutorRepositoryImpl{db: tracing.WrapDB(db)}
 }
@@ -1,22 +1,25 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
 	"log"
-	"fmt"
+	"log/slog"
 
 	"github.com/gofrs/uuid"
 )
 
 type OrganizationBillingRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create inserts a new billing record using the stored procedure
-func (r *OrganizationBillingRepositoryImpl) Create(billing *model.OrganizationBilling) error {
-	_, err := r.db.Exec(`CALL create_organization_billing($1,$2,$3,$4)`,
+func (r *OrganizationBillingRepositoryImpl) Create(ctx context.Context, billing *model.OrganizationBilling) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_organization_billing($1,$2,$3,$4)`,
 		billing.OrganizationID,
 		billing.Plan,
 		billing.PaymentMethod,
@@ -24,16 +27,16 @@ func (r *OrganizationBillingRepositoryImpl) Create(billing *model.OrganizationBi
 	)
 	if err != nil {
 		log.Printf("Error calling create_organization_billing: %v", err)
-		return err
+		return writeError(err, "organization_billing")
 	}
 
-	log.Printf("OrganizationBilling created: %+v", billing)
+	slog.Info("OrganizationBilling created", "billing_id", billing.ID, "organization_id", billing.OrganizationID)
 	return nil
 }
 
 // Update modifies an existing billing record using the stored procedure
-func (r *OrganizationBillingRepositoryImpl) Update(billing *model.OrganizationBilling) error {
-	_, err := r.db.Exec(`CALL update_organization_billing($1,$2,$3,$4,$5)`,
+func (r *OrganizationBillingRepositoryImpl) Update(ctx context.Context, billing *model.OrganizationBilling) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_organization_billing($1,$2,$3,$4,$5)`,
 		billing.ID,
 		billing.Plan,
 		billing.PaymentMethod,
@@ -42,16 +45,16 @@ func (r *OrganizationBillingRepositoryImpl) Update(billing *model.OrganizationBi
 	)
 	if err != nil {
 		log.Printf("Error calling update_organization_billing: %v", err)
-		return err
+		return writeError(err, "organization_billing")
 	}
 
-	log.Printf("OrganizationBilling updated: %+v", billing)
+	slog.Info("OrganizationBilling updated", "billing_id", billing.ID, "organization_id", billing.OrganizationID)
 	return nil
 }
 
 // Delete performs a soft delete of a billing record using the stored procedure
-func (r *OrganizationBillingRepositoryImpl) Delete(billingID uuid.UUID) error {
-	_, err := r.db.Exec(`CALL delete_organization_billing($1)`, billingID)
+func (r *OrganizationBillingRepositoryImpl) Delete(ctx context.Context, billingID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_organization_billing($1)`, billingID)
 	if err != nil {
 		log.Printf("Error calling delete_organization_billing for ID %v: %v", billingID, err)
 		return err
@@ -61,11 +64,37 @@ func (r *OrganizationBillingRepositoryImpl) Delete(billingID uuid.UUID) error {
 	return nil
 }
 
-// GetAll retrieves all billing records using the stored function
-func (r *OrganizationBillingRepositoryImpl) GetAll() ([]*model.OrganizationBilling, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_organization_billings()`)
+// Restore undeletes a soft-deleted billing record using the stored function
+func (r *OrganizationBillingRepositoryImpl) Restore(ctx context.Context, billingID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_organization_billing($1)`, billingID).Scan(&restored)
 	if err != nil {
-		log.Printf("Error querying get_all_organization_billings: %v", err)
+		log.Printf("Error calling restore_organization_billing for ID %v: %v", billingID, err)
+		return writeError(err, "organization_billing")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_organization_billing_not_found", "no deleted organization billing with this ID")
+	}
+
+	log.Printf("OrganizationBilling restored: %v", billingID)
+	return nil
+}
+
+// List retrieves one page of organization billings matching the filter using the stored functions
+func (r *OrganizationBillingRepositoryImpl) List(ctx context.Context, filter model.OrganizationBillingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBilling], error) {
+	plan := nullIfEmpty(filter.Plan)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_organization_billings($1,$2,$3)`, filter.OrganizationID, plan, filter.IncludeDeleted).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_organization_billings: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{filter.OrganizationID, plan, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_organization_billings($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
+	if err != nil {
+		log.Printf("Error querying list_organization_billings: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -82,6 +111,7 @@ func (r *OrganizationBillingRepositoryImpl) GetAll() ([]*model.OrganizationBilli
 			&b.NextBillingAt,
 			&b.CreatedAt,
 			&b.UpdatedAt,
+			&b.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning billing row: %v", err)
@@ -96,14 +126,19 @@ func (r *OrganizationBillingRepositoryImpl) GetAll() ([]*model.OrganizationBilli
 	}
 
 	log.Printf("OrganizationBillings retrieved: %d", len(billings))
-	return billings, nil
+	return newPage(billings, total, page, func(b *model.OrganizationBilling) (string, uuid.UUID) {
+		if page.Sort == "next_billing_at" {
+			return cursorTime(b.NextBillingAt), b.ID
+		}
+		return cursorTime(b.CreatedAt), b.ID
+	}), nil
 }
 
 // GetByID retrieves a single billing record by ID using the stored function
-func (r *OrganizationBillingRepositoryImpl) GetByID(billingID uuid.UUID) (*model.OrganizationBilling, error) {
+func (r *OrganizationBillingRepositoryImpl) GetByID(ctx context.Context, billingID uuid.UUID) (*model.OrganizationBilling, error) {
 	var b model.OrganizationBilling
 
-	row := r.db.QueryRow(`SELECT * FROM get_organization_billing_by_id($1)`, billingID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_billing_by_id($1)`, billingID)
 	err := row.Scan(
 		&b.ID,
 		&b.OrganizationID,
@@ -113,21 +148,22 @@ func (r *OrganizationBillingRepositoryImpl) GetByID(billingID uuid.UUID) (*model
 		&b.NextBillingAt,
 		&b.CreatedAt,
 		&b.UpdatedAt,
+		&b.DeletedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("OrganizationBilling not found with ID: %v", billingID)
-			return nil, fmt.Errorf("organization billing not found")
+			return nil, apperr.NotFound("organization_billing_not_found", "organization billing not found")
 		}
 		log.Printf("Error scanning billing by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("OrganizationBilling retrieved by ID: %+v", b)
+	slog.Debug("OrganizationBilling retrieved by ID", "billing_id", b.ID, "organization_id", b.OrganizationID)
 	return &b, nil
 }
 
 // Constructor
 func NewOrganizationBillingRepository(db *sql.DB) repository.OrganizationBillingRepository {
-	return &OrganizationBillingRepositoryImpl{db: db}
+	return &OrganizationBillingRepositoryImpl{db: tracing.WrapDB(db)}
 }
@@ -1,68 +1,70 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
-	"fmt"
+	"e-learning-system/internal/tracing"
 	"log"
+	"log/slog"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
 
 type OrganizationRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create inserts a new organization using the stored procedure
-func (r *OrganizationRepositoryImpl) Create(org *model.Organization) error {
-	_, err := r.db.Exec(`CALL create_organization($1,$2,$3,$4,$5,$6,$7,$8)`,
+func (r *OrganizationRepositoryImpl) Create(ctx context.Context, org *model.Organization) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_organization($1,$2,$3,$4,$5,$6,$7,$8)`,
 		org.Name, org.Description, org.LogoURL,
 		org.PrimaryColor, org.SecondaryColor, org.Domain,
 		org.Status, org.Plan,
 	)
 	if err != nil {
 		log.Printf("Error calling create_organization: %v", err)
-		return err
+		return writeError(err, "organization")
 	}
 
-	log.Printf("Organization created: %+v", org)
+	slog.Info("Organization created", "organization_id", org.ID)
 	return nil
 }
 
 // Update modifies an existing organization using the stored procedure
-func (r *OrganizationRepositoryImpl) Update(org *model.Organization) error {
-	_, err := r.db.Exec(`CALL update_organization($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+func (r *OrganizationRepositoryImpl) Update(ctx context.Context, org *model.Organization) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_organization($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
 		org.ID, org.Name, org.Description, org.LogoURL,
 		org.PrimaryColor, org.SecondaryColor, org.Domain,
 		org.Status, org.Plan,
 	)
 	if err != nil {
 		log.Printf("Error calling update_organization: %v", err)
-		return err
+		return writeError(err, "organization")
 	}
 
-	log.Printf("Organization updated: %+v", org)
+	slog.Info("Organization updated", "organization_id", org.ID)
 	return nil
 }
 
-// Delete performs a soft delete of an organization using the stored procedure
-func (r *OrganizationRepositoryImpl) Delete(orgID uuid.UUID) error {
-	_, err := r.db.Exec(`CALL delete_organization($1)`, orgID)
+// List retrieves one page of organizations matching the filter using the stored functions
+func (r *OrganizationRepositoryImpl) List(ctx context.Context, filter model.OrganizationFilter, page model.PageRequest) (*model.Page[*model.Organization], error) {
+	status, plan := nullIfEmpty(string(filter.Status)), nullIfEmpty(filter.Plan)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_organizations($1,$2,$3)`, status, plan, filter.IncludeDeleted).Scan(&total)
 	if err != nil {
-		log.Printf("Error calling delete_organization for ID %v: %v", orgID, err)
-		return err
+		log.Printf("Error calling count_organizations: %v", err)
+		return nil, err
 	}
 
-	log.Printf("Organization soft-deleted: %v", orgID)
-	return nil
-}
-
-// GetAll retrieves all active organizations using the stored function
-func (r *OrganizationRepositoryImpl) GetAll() ([]*model.Organization, error) {
-	rows, err := r.db.Query(`SELECT * FROM get_all_organizations()`)
+	args := append([]any{status, plan, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_organizations($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
 	if err != nil {
-		log.Printf("Error querying get_all_organizations: %v", err)
+		log.Printf("Error querying list_organizations: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -80,6 +82,10 @@ func (r *OrganizationRepositoryImpl) GetAll() ([]*model.Organization, error) {
 			&org.Domain,
 			&org.Status,
 			&org.Plan,
+			&org.StatusReason,
+			&org.StatusChangedBy,
+			&org.StatusChangedAt,
+			&org.DeletionScheduledAt,
 			&org.CreatedAt,
 			&org.UpdatedAt,
 		)
@@ -96,14 +102,19 @@ func (r *OrganizationRepositoryImpl) GetAll() ([]*model.Organization, error) {
 	}
 
 	log.Printf("Organizations retrieved: %d", len(orgs))
-	return orgs, nil
+	return newPage(orgs, total, page, func(o *model.Organization) (string, uuid.UUID) {
+		if page.Sort == "name" {
+			return o.Name, o.ID
+		}
+		return cursorTime(o.CreatedAt), o.ID
+	}), nil
 }
 
 // GetByID retrieves a single organization by ID using the stored function
-func (r *OrganizationRepositoryImpl) GetByID(orgID uuid.UUID) (*model.Organization, error) {
+func (r *OrganizationRepositoryImpl) GetByID(ctx context.Context, orgID uuid.UUID) (*model.Organization, error) {
 	var org model.Organization
 
-	row := r.db.QueryRow(`SELECT * FROM get_organization_by_id($1)`, orgID)
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_by_id($1)`, orgID)
 
 	err := row.Scan(
 		&org.ID,
@@ -115,6 +126,10 @@ func (r *OrganizationRepositoryImpl) GetByID(orgID uuid.UUID) (*model.Organizati
 		&org.Domain,
 		&org.Status,
 		&org.Plan,
+		&org.StatusReason,
+		&org.StatusChangedBy,
+		&org.StatusChangedAt,
+		&org.DeletionScheduledAt,
 		&org.CreatedAt,
 		&org.UpdatedAt,
 	)
@@ -122,17 +137,227 @@ func (r *OrganizationRepositoryImpl) GetByID(orgID uuid.UUID) (*model.Organizati
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Organization not found with ID: %v", orgID)
-			return nil, fmt.Errorf("organization not found")
+			return nil, apperr.NotFound("organization_not_found", "organization not found")
 		}
 		log.Printf("Error scanning organization by ID: %v", err)
 		return nil, err
 	}
 
-	log.Printf("Organization retrieved by ID: %+v", org)
+	slog.Debug("Organization retrieved by ID", "organization_id", org.ID)
 	return &org, nil
 }
 
+// ChangeStatus moves an organization between lifecycle states and records the transition
+func (r *OrganizationRepositoryImpl) ChangeStatus(ctx context.Context, orgID uuid.UUID, from, to model.OrganizationStatus, reason string, actorID *uuid.UUID, deletionScheduledAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `CALL change_organization_status($1,$2,$3,$4,$5,$6)`,
+		orgID, from, to, reason, actorID, deletionScheduledAt,
+	)
+	if err != nil {
+		log.Printf("Error calling change_organization_status for ID %v: %v", orgID, err)
+		return err
+	}
+
+	log.Printf("Organization %v status changed: %s -> %s", orgID, from, to)
+	return nil
+}
+
+// GetStatusEvents retrieves the lifecycle history of an organization
+func (r *OrganizationRepositoryImpl) GetStatusEvents(ctx context.Context, orgID uuid.UUID) ([]*model.OrganizationStatusEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_organization_status_events($1)`, orgID)
+	if err != nil {
+		log.Printf("Error querying get_organization_status_events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.OrganizationStatusEvent
+	for rows.Next() {
+		var e model.OrganizationStatusEvent
+		var from sql.NullString
+		err := rows.Scan(
+			&e.ID,
+			&e.OrganizationID,
+			&from,
+			&e.ToStatus,
+			&e.Reason,
+			&e.ActorID,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning organization status event row: %v", err)
+			return nil, err
+		}
+		e.FromStatus = model.OrganizationStatus(from.String)
+		events = append(events, &e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetDueForDeletion returns the organizations whose scheduled hard delete is before the given time
+func (r *OrganizationRepositoryImpl) GetDueForDeletion(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_organizations_due_for_deletion($1)`, before)
+	if err != nil {
+		log.Printf("Error querying get_organizations_due_for_deletion: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Error scanning organization ID: %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Export returns a JSON document with all data held for an organization
+func (r *OrganizationRepositoryImpl) Export(ctx context.Context, orgID uuid.UUID) ([]byte, error) {
+	var data []byte
+
+	err := r.db.QueryRowContext(ctx, `SELECT export_organization($1)`, orgID).Scan(&data)
+	if err != nil {
+		log.Printf("Error calling export_organization for ID %v: %v", orgID, err)
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// HardDelete permanently removes an organization that is pending deletion
+func (r *OrganizationRepositoryImpl) HardDelete(ctx context.Context, orgID uuid.UUID, reason string) error {
+	_, err := r.db.ExecContext(ctx, `CALL hard_delete_organization($1,$2)`, orgID, reason)
+	if err != nil {
+		log.Printf("Error calling hard_delete_organization for ID %v: %v", orgID, err)
+		return err
+	}
+
+	log.Printf("Organization hard-deleted: %v", orgID)
+	return nil
+}
+
+// GetStorageKeys lists the stored files of an organization's assets, lesson videos and imports
+func (r *OrganizationRepositoryImpl) GetStorageKeys(ctx context.Context, orgID uuid.UUID) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_organization_storage_keys($1)`, orgID)
+	if err != nil {
+		log.Printf("Error calling get_organization_storage_keys for ID %v: %v", orgID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			log.Printf("Error scanning get_organization_storage_keys row: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetMemberStatuses returns the status of every organization the user belongs to
+func (r *OrganizationRepositoryImpl) GetMemberStatuses(ctx context.Context, userID uuid.UUID) ([]model.OrganizationStatus, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_member_organization_statuses($1)`, userID)
+	if err != nil {
+		log.Printf("Error querying get_member_organization_statuses: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []model.OrganizationStatus
+	for rows.Next() {
+		var orgID uuid.UUID
+		var status model.OrganizationStatus
+		if err := rows.Scan(&orgID, &status); err != nil {
+			log.Printf("Error scanning member organization status: %v", err)
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// GetMemberRole returns the user's membership of the organization using the stored function
+func (r *OrganizationRepositoryImpl) GetMemberRole(ctx context.Context, organizationID, userID uuid.UUID) (string, error) {
+	var role sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT get_organization_member_role($1, $2)`, organizationID, userID).Scan(&role)
+	if err != nil {
+		log.Printf("Error calling get_organization_member_role: %v", err)
+		return "", err
+	}
+	return role.String, nil
+}
+
 // Constructor
 func NewOrganizationRepository(db *sql.DB) repository.OrganizationRepository {
-	return &OrganizationRepositoryImpl{db: db}
+	return &OrganizationRepositoryImpl{db: tracing.WrapDB(db)}
+}
+
+// GetPolicy retrieves the member policy of an organization
+func (r *OrganizationRepositoryImpl) GetPolicy(ctx context.Context, orgID uuid.UUID) (*model.OrganizationPolicy, error) {
+	var policy model.OrganizationPolicy
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_policy($1)`, orgID).Scan(
+		&policy.OrganizationID,
+		&policy.RequireVerifiedEmail,
+		&policy.RequireMFA,
+		&policy.PasswordMaxAgeDays,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("organization_not_found", "organization not found")
+		}
+		log.Printf("Error calling get_organization_policy: %v", err)
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy saves the member policy of an organization
+func (r *OrganizationRepositoryImpl) UpdatePolicy(ctx context.Context, policy *model.OrganizationPolicy) error {
+	var updatedAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT update_organization_policy($1, $2, $3, $4)`,
+		policy.OrganizationID, policy.RequireVerifiedEmail, policy.RequireMFA, policy.PasswordMaxAgeDays,
+	).Scan(&updatedAt)
+	if err != nil {
+		log.Printf("Error calling update_organization_policy: %v", err)
+		return err
+	}
+	if !updatedAt.Valid {
+		return apperr.NotFound("organization_not_found", "organization not found")
+	}
+
+	policy.UpdatedAt = updatedAt.Time
+	slog.Info("Organization policy updated", "organization_id", policy.OrganizationID)
+	return nil
 }
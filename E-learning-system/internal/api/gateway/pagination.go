@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"e-learning-system/internal/domain/model"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// The list_* stored functions take the page as their trailing arguments:
+// sort, descending, cursor value, cursor ID and limit. pageArgs returns them,
+// asking for one row more than the page size to learn whether another page follows.
+func pageArgs(page model.PageRequest) []any {
+	var afterValue, afterID any
+	if page.After != nil {
+		afterValue, afterID = page.After.Value, page.After.ID
+	}
+	return []any{page.Sort, page.Desc, afterValue, afterID, page.Limit + 1}
+}
+
+// newPage drops the extra row fetched by pageArgs and, when it was there,
+// points the next cursor at the last item; key returns an item's sort value and ID.
+func newPage[T any](items []T, total int64, page model.PageRequest, key func(T) (string, uuid.UUID)) *model.Page[T] {
+	p := &model.Page[T]{Items: items, Total: total}
+	if len(items) > page.Limit {
+		p.Items = items[:page.Limit]
+		value, id := key(p.Items[len(p.Items)-1])
+		p.NextCursor = model.Cursor{Sort: page.Sort, Desc: page.Desc, Value: value, ID: id}.Encode()
+	}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+	return p
+}
+
+// cursorTime formats a timestamp sort value so PostgreSQL parses it back exactly
+func cursorTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// nullIfEmpty passes an unset string filter to SQL as NULL
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"e-learning-system/internal/domain/model"
+	"strconv"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+type pageItem struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func pageItemKey(item pageItem) (string, uuid.UUID) {
+	return item.Name, item.ID
+}
+
+func pageItems(n int) []pageItem {
+	items := make([]pageItem, n)
+	for i := range items {
+		items[i] = pageItem{ID: uuid.Must(uuid.NewV4()), Name: "item " + strconv.Itoa(i)}
+	}
+	return items
+}
+
+func TestPageArgs(t *testing.T) {
+	first := pageArgs(model.PageRequest{Limit: 20, Sort: "name"})
+	if first[2] != nil || first[3] != nil || first[4] != 21 {
+		t.Errorf("first page args = %v, want no cursor and one extra row", first)
+	}
+
+	id := uuid.Must(uuid.NewV4())
+	next := pageArgs(model.PageRequest{Limit: 20, Sort: "name", Desc: true, After: &model.Cursor{Sort: "name", Desc: true, Value: "bob", ID: id}})
+	if next[0] != "name" || next[1] != true || next[2] != "bob" || next[3] != id {
+		t.Errorf("next page args = %v", next)
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	page := model.PageRequest{Limit: 3, Sort: "name", Desc: true}
+
+	tests := []struct {
+		name      string
+		fetched   int
+		wantItems int
+		wantNext  bool
+	}{
+		{"empty", 0, 0, false},
+		{"short last page", 2, 2, false},
+		{"exactly full last page", 3, 3, false},
+		{"another page follows", 4, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := pageItems(tt.fetched)
+			p := newPage(items, 42, page, pageItemKey)
+
+			if p.Items == nil || len(p.Items) != tt.wantItems || p.Total != 42 {
+				t.Fatalf("items %v, total %d, want %d items of 42", p.Items, p.Total, tt.wantItems)
+			}
+			if (p.NextCursor != "") != tt.wantNext {
+				t.Fatalf("next cursor = %q, want one: %v", p.NextCursor, tt.wantNext)
+			}
+			if !tt.wantNext {
+				return
+			}
+
+			cursor, err := model.DecodeCursor(p.NextCursor)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			last := items[page.Limit-1]
+			if cursor.Sort != "name" || !cursor.Desc || cursor.Value != last.Name || cursor.ID != last.ID {
+				t.Errorf("cursor = %+v, want the last item of the page", cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type PrivacyRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// CreateExport queues a user data export using the stored function
+func (r *PrivacyRepositoryImpl) CreateExport(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID) (uuid.UUID, error) {
+	var exportID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT create_user_data_export($1,$2)`, userID, requestedBy).Scan(&exportID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+			return uuid.Nil, apperr.Conflict("export_in_progress", "an export of this user's data is already being prepared").Wrap(err)
+		}
+		log.Printf("Error calling create_user_data_export: %v", err)
+		return uuid.Nil, writeError(err, "user_data_export")
+	}
+
+	log.Printf("User data export queued: %v", exportID)
+	return exportID, nil
+}
+
+// GetExport retrieves a user data export by ID using the stored function
+func (r *PrivacyRepositoryImpl) GetExport(ctx context.Context, exportID uuid.UUID) (*model.UserDataExport, error) {
+	e, err := scanUserDataExport(r.db.QueryRowContext(ctx, `SELECT * FROM get_user_data_export($1)`, exportID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user_data_export_not_found", "data export not found")
+		}
+		log.Printf("Error scanning user data export %v: %v", exportID, err)
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListExportsByUser retrieves a user's exports, newest first, using the stored function
+func (r *PrivacyRepositoryImpl) ListExportsByUser(ctx context.Context, userID uuid.UUID) ([]*model.UserDataExport, error) {
+	return r.queryExports(ctx, "get_user_data_exports_by_user", userID)
+}
+
+// ClaimExports moves pending exports to processing using the stored function
+func (r *PrivacyRepositoryImpl) ClaimExports(ctx context.Context, limit int) ([]*model.UserDataExport, error) {
+	return r.queryExports(ctx, "claim_user_data_exports", limit)
+}
+
+// CompleteExport records where the finished archive is stored using the stored procedure
+func (r *PrivacyRepositoryImpl) CompleteExport(ctx context.Context, exportID uuid.UUID, storageKey string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `CALL complete_user_data_export($1,$2,$3)`, exportID, storageKey, expiresAt)
+	if err != nil {
+		log.Printf("Error calling complete_user_data_export for ID %v: %v", exportID, err)
+		return err
+	}
+	return nil
+}
+
+// FailExport marks an export as failed using the stored procedure
+func (r *PrivacyRepositoryImpl) FailExport(ctx context.Context, exportID uuid.UUID, reason string) error {
+	_, err := r.db.ExecContext(ctx, `CALL fail_user_data_export($1,$2)`, exportID, reason)
+	if err != nil {
+		log.Printf("Error calling fail_user_data_export for ID %v: %v", exportID, err)
+		return err
+	}
+	return nil
+}
+
+// ListExpiredExports retrieves exports to clean up using the stored function
+func (r *PrivacyRepositoryImpl) ListExpiredExports(ctx context.Context, now time.Time) ([]*model.UserDataExport, error) {
+	return r.queryExports(ctx, "get_expired_user_data_exports", now)
+}
+
+// DeleteExport removes an export record using the stored procedure
+func (r *PrivacyRepositoryImpl) DeleteExport(ctx context.Context, exportID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_user_data_export($1)`, exportID)
+	if err != nil {
+		log.Printf("Error calling delete_user_data_export for ID %v: %v", exportID, err)
+		return err
+	}
+	return nil
+}
+
+// ExportUserData collects a user's data using the stored function
+func (r *PrivacyRepositoryImpl) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	var data []byte
+	err := r.db.QueryRowContext(ctx, `SELECT export_user_data($1)`, userID).Scan(&data)
+	if err != nil {
+		log.Printf("Error calling export_user_data for ID %v: %v", userID, err)
+		return nil, err
+	}
+
+	var files map[string]json.RawMessage
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// EraseUser anonymizes a user using the stored function
+func (r *PrivacyRepositoryImpl) EraseUser(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var erased bool
+	err := r.db.QueryRowContext(ctx, `SELECT erase_user($1)`, userID).Scan(&erased)
+	if err != nil {
+		log.Printf("Error calling erase_user for ID %v: %v", userID, err)
+		return false, err
+	}
+
+	if erased {
+		log.Printf("User erased: %v", userID)
+	}
+	return erased, nil
+}
+
+// queryExports runs a stored function returning user data export rows
+func (r *PrivacyRepositoryImpl) queryExports(ctx context.Context, fn string, arg any) ([]*model.UserDataExport, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM `+fn+`($1)`, arg)
+	if err != nil {
+		log.Printf("Error querying %s: %v", fn, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []*model.UserDataExport
+	for rows.Next() {
+		e, err := scanUserDataExport(rows)
+		if err != nil {
+			log.Printf("Error scanning user data export row: %v", err)
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return exports, nil
+}
+
+func scanUserDataExport(row interface{ Scan(...any) error }) (*model.UserDataExport, error) {
+	var e model.UserDataExport
+	var requestedBy uuid.NullUUID
+	err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&requestedBy,
+		&e.Status,
+		&e.StorageKey,
+		&e.Error,
+		&e.CreatedAt,
+		&e.CompletedAt,
+		&e.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if requestedBy.Valid {
+		e.RequestedBy = &requestedBy.UUID
+	}
+	return &e, nil
+}
+
+// NewPrivacyRepository returns a new PrivacyRepository instance
+func NewPrivacyRepository(db *sql.DB) repository.PrivacyRepository {
+	return &PrivacyRepositoryImpl{db: tracing.WrapDB(db)}
+}
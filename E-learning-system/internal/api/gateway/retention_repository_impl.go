@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+	"time"
+)
+
+type RetentionRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// PurgeSoftDeleted hard deletes soft-deleted records using the stored function
+func (r *RetentionRepositoryImpl) PurgeSoftDeleted(ctx context.Context, before time.Time) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM purge_soft_deleted($1)`, before)
+	if err != nil {
+		log.Printf("Error calling purge_soft_deleted: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	purged := make(map[string]int64)
+	for rows.Next() {
+		var recordType string
+		var n int64
+		if err := rows.Scan(&recordType, &n); err != nil {
+			log.Printf("Error scanning purge_soft_deleted row: %v", err)
+			return nil, err
+		}
+		purged[recordType] = n
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return purged, nil
+}
+
+// GetExpiredStorageKeys lists the stored files of expired records using the stored function
+func (r *RetentionRepositoryImpl) GetExpiredStorageKeys(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_expired_storage_keys($1)`, before)
+	if err != nil {
+		log.Printf("Error calling get_expired_storage_keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			log.Printf("Error scanning get_expired_storage_keys row: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// NewRetentionRepository returns a new RetentionRepository instance
+func NewRetentionRepository(db *sql.DB) repository.RetentionRepository {
+	return &RetentionRepositoryImpl{db: tracing.WrapDB(db)}
+}
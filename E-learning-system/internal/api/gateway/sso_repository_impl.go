@@ -0,0 +1,288 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"encoding/json"
+	"log"
+	"log/slog"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type SSORepositoryImpl struct {
+	db *tracing.DB
+}
+
+// GetConfig retrieves the identity provider of an organization
+func (r *SSORepositoryImpl) GetConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSSO, error) {
+	var config model.OrganizationSSO
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_sso($1)`, orgID).Scan(
+		&config.OrganizationID,
+		&config.Issuer,
+		&config.ClientID,
+		&config.ClientSecret,
+		pq.Array(&config.AllowedDomains),
+		&config.DefaultRole,
+		&config.Enabled,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("sso_not_configured", "single sign-on is not configured for this organization")
+		}
+		log.Printf("Error calling get_organization_sso: %v", err)
+		return nil, err
+	}
+	config.HasClientSecret = config.ClientSecret != ""
+	return &config, nil
+}
+
+// SaveConfig creates or replaces the identity provider of an organization
+func (r *SSORepositoryImpl) SaveConfig(ctx context.Context, config *model.OrganizationSSO, clientSecret *string) error {
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM save_organization_sso($1, $2, $3, $4, $5, $6, $7)`,
+		config.OrganizationID,
+		config.Issuer,
+		config.ClientID,
+		clientSecret,
+		pq.Array(config.AllowedDomains),
+		config.DefaultRole,
+		config.Enabled,
+	).Scan(&config.CreatedAt, &config.UpdatedAt)
+	if err != nil {
+		log.Printf("Error calling save_organization_sso: %v", err)
+		return writeError(err, "organization")
+	}
+
+	slog.Info("Organization SSO saved", "organization_id", config.OrganizationID, "issuer", config.Issuer)
+	return nil
+}
+
+// DeleteConfig removes the identity provider of an organization
+func (r *SSORepositoryImpl) DeleteConfig(ctx context.Context, orgID uuid.UUID) error {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, `SELECT delete_organization_sso($1)`, orgID).Scan(&deleted)
+	if err != nil {
+		log.Printf("Error calling delete_organization_sso: %v", err)
+		return err
+	}
+	if !deleted {
+		return apperr.NotFound("sso_not_configured", "single sign-on is not configured for this organization")
+	}
+
+	slog.Info("Organization SSO deleted", "organization_id", orgID)
+	return nil
+}
+
+// FindByDomain lists the active organizations offering SSO for an email domain, per protocol
+func (r *SSORepositoryImpl) FindByDomain(ctx context.Context, domain string) ([]model.SSOOrganization, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM find_sso_organizations_by_domain($1)`, domain)
+	if err != nil {
+		log.Printf("Error calling find_sso_organizations_by_domain: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []model.SSOOrganization{}
+	for rows.Next() {
+		var org model.SSOOrganization
+		if err := rows.Scan(&org.OrganizationID, &org.Name, &org.Protocol); err != nil {
+			log.Printf("Error scanning SSO organization: %v", err)
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// GetSAMLConfig retrieves the SAML identity provider of an organization
+func (r *SSORepositoryImpl) GetSAMLConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSAML, error) {
+	var config model.OrganizationSAML
+	var mapping []byte
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM get_organization_saml($1)`, orgID).Scan(
+		&config.OrganizationID,
+		&config.IdPEntityID,
+		&config.IdPSSOURL,
+		&config.IdPMetadata,
+		&config.IdPMetadataURL,
+		&config.SPCertificate,
+		&config.SPPrivateKey,
+		&mapping,
+		pq.Array(&config.AllowedDomains),
+		&config.DefaultRole,
+		&config.Enabled,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("saml_not_configured", "SAML sign-on is not configured for this organization")
+		}
+		log.Printf("Error calling get_organization_saml: %v", err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal(mapping, &config.AttributeMapping); err != nil {
+		log.Printf("Error decoding SAML attribute mapping: %v", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SaveSAMLConfig creates or replaces the SAML identity provider of an organization
+func (r *SSORepositoryImpl) SaveSAMLConfig(ctx context.Context, config *model.OrganizationSAML) error {
+	mapping, err := json.Marshal(config.AttributeMapping)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `SELECT * FROM save_organization_saml($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		config.OrganizationID,
+		config.IdPEntityID,
+		config.IdPSSOURL,
+		config.IdPMetadata,
+		config.IdPMetadataURL,
+		config.SPCertificate,
+		config.SPPrivateKey,
+		mapping,
+		pq.Array(config.AllowedDomains),
+		config.DefaultRole,
+		config.Enabled,
+	).Scan(&config.CreatedAt, &config.UpdatedAt)
+	if err != nil {
+		log.Printf("Error calling save_organization_saml: %v", err)
+		return writeError(err, "organization")
+	}
+
+	slog.Info("Organization SAML saved", "organization_id", config.OrganizationID, "idp_entity_id", config.IdPEntityID)
+	return nil
+}
+
+// DeleteSAMLConfig removes the SAML identity provider of an organization
+func (r *SSORepositoryImpl) DeleteSAMLConfig(ctx context.Context, orgID uuid.UUID) error {
+	var deleted bool
+	err := r.db.QueryRowContext(ctx, `SELECT delete_organization_saml($1)`, orgID).Scan(&deleted)
+	if err != nil {
+		log.Printf("Error calling delete_organization_saml: %v", err)
+		return err
+	}
+	if !deleted {
+		return apperr.NotFound("saml_not_configured", "SAML sign-on is not configured for this organization")
+	}
+
+	slog.Info("Organization SAML deleted", "organization_id", orgID)
+	return nil
+}
+
+// CreateSAMLLogin stores a validated assertion until it is claimed
+func (r *SSORepositoryImpl) CreateSAMLLogin(ctx context.Context, login *model.SAMLLogin) error {
+	var created bool
+	err := r.db.QueryRowContext(ctx, `SELECT create_saml_login($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		login.OrganizationID,
+		login.RequestID,
+		login.CodeHash,
+		login.Subject,
+		login.Email,
+		login.FirstName,
+		login.LastName,
+		login.Role,
+		login.ExpiresAt,
+	).Scan(&created)
+	if err != nil {
+		log.Printf("Error calling create_saml_login: %v", err)
+		return err
+	}
+	if !created {
+		return apperr.Conflict("saml_response_replayed", "the SAML request was already answered")
+	}
+	return nil
+}
+
+// ConsumeSAMLLogin claims a stored assertion by the hash of its code
+func (r *SSORepositoryImpl) ConsumeSAMLLogin(ctx context.Context, codeHash string) (*model.SAMLLogin, error) {
+	login := model.SAMLLogin{CodeHash: codeHash}
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM consume_saml_login($1)`, codeHash).Scan(
+		&login.OrganizationID,
+		&login.RequestID,
+		&login.Subject,
+		&login.Email,
+		&login.FirstName,
+		&login.LastName,
+		&login.Role,
+		&login.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("saml_login_not_found", "sign-in code is invalid or expired")
+		}
+		log.Printf("Error calling consume_saml_login: %v", err)
+		return nil, err
+	}
+	return &login, nil
+}
+
+// FindIdentity looks up the link of a provider subject
+func (r *SSORepositoryImpl) FindIdentity(ctx context.Context, orgID uuid.UUID, issuer, subject string) (*model.SSOIdentity, error) {
+	var identity model.SSOIdentity
+
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM find_sso_identity($1, $2, $3)`, orgID, issuer, subject).Scan(
+		&identity.ID,
+		&identity.OrganizationID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("sso_identity_not_found", "identity is not linked")
+		}
+		log.Printf("Error calling find_sso_identity: %v", err)
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// LinkIdentity links a provider subject to a user and adds the membership
+func (r *SSORepositoryImpl) LinkIdentity(ctx context.Context, identity *model.SSOIdentity) error {
+	_, err := r.db.ExecContext(ctx, `CALL link_sso_identity($1, $2, $3, $4, $5)`,
+		identity.OrganizationID,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+	)
+	if err != nil {
+		log.Printf("Error calling link_sso_identity: %v", err)
+		return writeError(err, "sso_identity")
+	}
+
+	slog.Info("SSO identity linked", "organization_id", identity.OrganizationID, "user_id", identity.UserID)
+	return nil
+}
+
+// TouchIdentity records a sign-in through a linked identity
+func (r *SSORepositoryImpl) TouchIdentity(ctx context.Context, identityID uuid.UUID, email string) error {
+	_, err := r.db.ExecContext(ctx, `CALL touch_sso_identity($1, $2)`, identityID, email)
+	if err != nil {
+		log.Printf("Error calling touch_sso_identity: %v", err)
+		return err
+	}
+	return nil
+}
+
+// NewSSORepository returns a new SSORepository instance
+func NewSSORepository(db *sql.DB) repository.SSORepository {
+	return &SSORepositoryImpl{db: tracing.WrapDB(db)}
+}
@@ -1,31 +1,35 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
 	"errors"
 	"log"
 	"time"
+
+	"github.com/gofrs/uuid"
 )
 
 // tokenRepositoryImpl is the PostgreSQL-based implementation of TokenRepository
 type tokenRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // NewTokenRepository returns a new TokenRepository instance
 func NewTokenRepository(db *sql.DB) repository.TokenRepository {
-	return &tokenRepositoryImpl{db: db}
+	return &tokenRepositoryImpl{db: tracing.WrapDB(db)}
 }
 
 // Create inserts a new token into the database using a stored procedure
-func (t *tokenRepositoryImpl) Create(token *model.Token) error {
+func (t *tokenRepositoryImpl) Create(ctx context.Context, token *model.Token) error {
 	now := time.Now().UTC()
 	token.CreatedAt = now
 	token.UpdatedAt = now
 
-	_, err := t.db.Exec(`
+	_, err := t.db.ExecContext(ctx, `
 		CALL create_token($1, $2, $3, $4, $5, $6)
 	`,
 		token.ID,
@@ -40,15 +44,15 @@ func (t *tokenRepositoryImpl) Create(token *model.Token) error {
 		return err
 	}
 
-	log.Printf("Token successfully created: ID=%v", token.ID)
+	log.Printf("Token successfully created for user %v", token.UserID)
 	return nil
 }
 
 // FindByToken retrieves a token by its value using a SQL function
-func (t *tokenRepositoryImpl) FindByToken(tokenStr string) (*model.Token, error) {
+func (t *tokenRepositoryImpl) FindByToken(ctx context.Context, tokenStr string) (*model.Token, error) {
 	query := `SELECT * FROM get_token_by_token($1);`
 
-	row := t.db.QueryRow(query, tokenStr)
+	row := t.db.QueryRowContext(ctx, query, tokenStr)
 
 	var token model.Token
 	err := row.Scan(
@@ -63,7 +67,7 @@ func (t *tokenRepositoryImpl) FindByToken(tokenStr string) (*model.Token, error)
 
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
-		log.Printf("Token not found: %s", tokenStr)
+		log.Println("Token not found")
 		return nil, nil
 	case err != nil:
 		log.Printf("Error scanning token row: %v", err)
@@ -72,3 +76,17 @@ func (t *tokenRepositoryImpl) FindByToken(tokenStr string) (*model.Token, error)
 		return &token, nil
 	}
 }
+
+// RevokeOthers signs the user out of every session except keepID
+func (t *tokenRepositoryImpl) RevokeOthers(ctx context.Context, userID, keepID uuid.UUID) (int, error) {
+	var revoked int
+
+	err := t.db.QueryRowContext(ctx, `SELECT revoke_user_tokens($1, $2)`, userID, keepID).Scan(&revoked)
+	if err != nil {
+		log.Printf("Error calling revoke_user_tokens: %v", err)
+		return 0, err
+	}
+
+	log.Printf("Revoked %d sessions of user %v", revoked, userID)
+	return revoked, nil
+}
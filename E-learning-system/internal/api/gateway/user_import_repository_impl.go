@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type UserImportRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create queues a user import using the stored function
+func (r *UserImportRepositoryImpl) Create(ctx context.Context, userImport *model.UserImport) error {
+	err := r.db.QueryRowContext(ctx, `SELECT create_user_import($1,$2,$3,$4,$5,$6)`,
+		userImport.OrganizationID,
+		userImport.RequestedBy,
+		userImport.FileName,
+		userImport.StorageKey,
+		userImport.SendInvitations,
+		userImport.TotalRows,
+	).Scan(&userImport.ID)
+	if err != nil {
+		log.Printf("Error calling create_user_import: %v", err)
+		return writeError(err, "user_import")
+	}
+
+	log.Printf("User import queued: %v", userImport.ID)
+	return nil
+}
+
+// Get retrieves a user import by ID using the stored function
+func (r *UserImportRepositoryImpl) Get(ctx context.Context, importID uuid.UUID) (*model.UserImport, error) {
+	i, err := scanUserImport(r.db.QueryRowContext(ctx, `SELECT * FROM get_user_import($1)`, importID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user_import_not_found", "user import not found")
+		}
+		log.Printf("Error scanning user import %v: %v", importID, err)
+		return nil, err
+	}
+	return i, nil
+}
+
+// ListByOrganization retrieves an organization's imports, newest first, using the stored function
+func (r *UserImportRepositoryImpl) ListByOrganization(ctx context.Context, orgID uuid.UUID) ([]*model.UserImport, error) {
+	return r.queryImports(ctx, "get_user_imports_by_organization", orgID)
+}
+
+// Claim moves pending imports to processing using the stored function
+func (r *UserImportRepositoryImpl) Claim(ctx context.Context, limit int) ([]*model.UserImport, error) {
+	return r.queryImports(ctx, "claim_user_imports", limit)
+}
+
+// Complete records the outcome of an import using the stored procedure
+func (r *UserImportRepositoryImpl) Complete(ctx context.Context, userImport *model.UserImport) error {
+	_, err := r.db.ExecContext(ctx, `CALL complete_user_import($1,$2,$3,$4,$5,$6)`,
+		userImport.ID,
+		userImport.TotalRows,
+		userImport.CreatedRows,
+		userImport.UpdatedRows,
+		userImport.UnchangedRows,
+		userImport.FailedRows,
+	)
+	if err != nil {
+		log.Printf("Error calling complete_user_import for ID %v: %v", userImport.ID, err)
+		return err
+	}
+	return nil
+}
+
+// Fail marks an import as failed using the stored procedure
+func (r *UserImportRepositoryImpl) Fail(ctx context.Context, importID uuid.UUID, reason string) error {
+	_, err := r.db.ExecContext(ctx, `CALL fail_user_import($1,$2)`, importID, reason)
+	if err != nil {
+		log.Printf("Error calling fail_user_import for ID %v: %v", importID, err)
+		return err
+	}
+	return nil
+}
+
+// AddError records a row that was not applied using the stored procedure
+func (r *UserImportRepositoryImpl) AddError(ctx context.Context, importID uuid.UUID, rowErr model.UserImportError) error {
+	_, err := r.db.ExecContext(ctx, `CALL add_user_import_error($1,$2,$3,$4)`, importID, rowErr.Row, rowErr.Email, rowErr.Message)
+	if err != nil {
+		log.Printf("Error calling add_user_import_error for ID %v: %v", importID, err)
+		return err
+	}
+	return nil
+}
+
+// ListErrors retrieves the rejected rows of an import using the stored function
+func (r *UserImportRepositoryImpl) ListErrors(ctx context.Context, importID uuid.UUID) ([]model.UserImportError, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_user_import_errors($1)`, importID)
+	if err != nil {
+		log.Printf("Error querying get_user_import_errors: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rowErrs []model.UserImportError
+	for rows.Next() {
+		var e model.UserImportError
+		if err := rows.Scan(&e.Row, &e.Email, &e.Message); err != nil {
+			log.Printf("Error scanning user import error row: %v", err)
+			return nil, err
+		}
+		rowErrs = append(rowErrs, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return rowErrs, nil
+}
+
+// ImportUser applies one row of an import using the stored function
+func (r *UserImportRepositoryImpl) ImportUser(ctx context.Context, orgID uuid.UUID, row model.UserImportRow) (uuid.UUID, string, error) {
+	var userID uuid.UUID
+	var outcome string
+	err := r.db.QueryRowContext(ctx, `SELECT * FROM import_organization_user($1,$2,$3,$4,$5)`,
+		orgID, row.Email, row.FirstName, row.LastName, row.Role,
+	).Scan(&userID, &outcome)
+	if err != nil {
+		log.Printf("Error calling import_organization_user: %v", err)
+		return uuid.Nil, "", writeError(err, "user")
+	}
+	return userID, outcome, nil
+}
+
+// ListMembers retrieves an organization's memberships using the stored function
+func (r *UserImportRepositoryImpl) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrganizationMember, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_organization_members($1)`, orgID)
+	if err != nil {
+		log.Printf("Error querying get_organization_members: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*model.OrganizationMember
+	for rows.Next() {
+		var m model.OrganizationMember
+		err := rows.Scan(&m.UserID, &m.Email, &m.FirstName, &m.LastName, &m.Role, &m.Approved, &m.JoinedAt)
+		if err != nil {
+			log.Printf("Error scanning organization member row: %v", err)
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// queryImports runs a stored function returning user import rows
+func (r *UserImportRepositoryImpl) queryImports(ctx context.Context, fn string, arg any) ([]*model.UserImport, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM `+fn+`($1)`, arg)
+	if err != nil {
+		log.Printf("Error querying %s: %v", fn, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []*model.UserImport
+	for rows.Next() {
+		i, err := scanUserImport(rows)
+		if err != nil {
+			log.Printf("Error scanning user import row: %v", err)
+			return nil, err
+		}
+		imports = append(imports, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Row iteration error: %v", err)
+		return nil, err
+	}
+	return imports, nil
+}
+
+func scanUserImport(row interface{ Scan(...any) error }) (*model.UserImport, error) {
+	var i model.UserImport
+	var requestedBy uuid.NullUUID
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&requestedBy,
+		&i.Status,
+		&i.FileName,
+		&i.StorageKey,
+		&i.SendInvitations,
+		&i.TotalRows,
+		&i.CreatedRows,
+		&i.UpdatedRows,
+		&i.UnchangedRows,
+		&i.FailedRows,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if requestedBy.Valid {
+		i.RequestedBy = &requestedBy.UUID
+	}
+	return &i, nil
+}
+
+// NewUserImportRepository returns a new UserImportRepository instance
+func NewUserImportRepository(db *sql.DB) repository.UserImportRepository {
+	return &UserImportRepositoryImpl{db: tracing.WrapDB(db)}
+}
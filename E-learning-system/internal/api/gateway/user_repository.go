@@ -1,9 +1,12 @@
 package gateway
 
 import (
+	"context"
 	"database/sql"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
 	"github.com/gofrs/uuid"
 	"fmt"
 	"log"
@@ -11,15 +14,16 @@ import (
 )
 
 type userRepositoryImpl struct {
-	db *sql.DB
+	db *tracing.DB
 }
 
 // Create implements repository.UserRepository.
-func (r *userRepositoryImpl) Create(user *model.User) error {
+func (r *userRepositoryImpl) Create(ctx context.Context, user *model.User) error {
 
 	query := `SELECT create_user($1, $2, $3, $4, $5 );`
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		user.Email,
 		user.Password,
@@ -30,7 +34,7 @@ func (r *userRepositoryImpl) Create(user *model.User) error {
 
 	if err != nil {
 		log.Printf("Error calling create_user: %v", err)
-		return err
+		return writeError(err, "user")
 	}
 
 	log.Printf("User created with ID: %s", user.ID)
@@ -39,10 +43,10 @@ func (r *userRepositoryImpl) Create(user *model.User) error {
 
 
 // Delete implements repository.UserRepository.
-func (r *userRepositoryImpl) Delete(userID uuid.UUID) error {
+func (r *userRepositoryImpl) Delete(ctx context.Context, userID uuid.UUID) error {
 	var rowsDeleted int
 
-	err := r.db.QueryRow("SELECT delete_user($1)", userID).Scan(&rowsDeleted)
+	err := r.db.QueryRowContext(ctx, "SELECT delete_user($1)", userID).Scan(&rowsDeleted)
 	if err != nil {
 		log.Printf("Error calling delete_user: %v", err)
 		return err
@@ -50,19 +54,35 @@ func (r *userRepositoryImpl) Delete(userID uuid.UUID) error {
 
 	if rowsDeleted == 0 {
 		log.Printf("User not found")
-		return fmt.Errorf("user not found")
+		return apperr.NotFound("user_not_found", "user not found")
 	}
 
 	log.Printf("User deleted")
 	return nil
 }
 
+// Restore undeletes a soft-deleted user using the stored function
+func (r *userRepositoryImpl) Restore(ctx context.Context, userID uuid.UUID) error {
+	var restored bool
+	err := r.db.QueryRowContext(ctx, `SELECT restore_user($1)`, userID).Scan(&restored)
+	if err != nil {
+		log.Printf("Error calling restore_user for ID %v: %v", userID, err)
+		return writeError(err, "user")
+	}
+	if !restored {
+		return apperr.NotFound("deleted_user_not_found", "no deleted user with this ID")
+	}
+
+	log.Printf("User restored: %v", userID)
+	return nil
+}
+
 // Find user by email
-func (r *userRepositoryImpl) FindByEmail(email string) (*model.User, error) {
+func (r *userRepositoryImpl) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
 
 	query := `SELECT * FROM get_user_by_email($1)`
-	err := r.db.QueryRow(query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
@@ -71,9 +91,13 @@ func (r *userRepositoryImpl) FindByEmail(email string) (*model.User, error) {
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerified,
 	)
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("user_not_found", "user not found")
+		}
 		log.Printf("DB error: %v", err)
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
@@ -85,11 +109,11 @@ func (r *userRepositoryImpl) FindByEmail(email string) (*model.User, error) {
 
 
 // GetByID implements repository.UserRepository.
-func (r *userRepositoryImpl) Get(userID uuid.UUID) (*model.User, error) {
+func (r *userRepositoryImpl) Get(ctx context.Context, userID uuid.UUID) (*model.User, error) {
 	var user model.User
 
 	query := `SELECT * FROM get_user_by_id($1)`
-	err := r.db.QueryRow(query, userID).Scan(
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Password,
@@ -98,11 +122,16 @@ func (r *userRepositoryImpl) Get(userID uuid.UUID) (*model.User, error) {
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.AvatarURL,
+		&user.Bio,
+		&user.Locale,
+		&user.Timezone,
+		&user.EmailVerified,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("User not found")
-			return nil, fmt.Errorf("user not found")
+			return nil, apperr.NotFound("user_not_found", "user not found")
 		}
 		log.Printf("DB error: %v", err)
 		return nil, err
@@ -111,12 +140,21 @@ func (r *userRepositoryImpl) Get(userID uuid.UUID) (*model.User, error) {
 	return &user, nil
 }
 
-// List implements repository.UserRepository.
-// List all users
-func (r *userRepositoryImpl) List() ([]*model.User, error) {
-	rows, err := r.db.Query("SELECT * FROM get_all_users()")
+// List retrieves one page of users matching the filter using the stored functions
+func (r *userRepositoryImpl) List(ctx context.Context, filter model.UserFilter, page model.PageRequest) (*model.Page[*model.User], error) {
+	role, emailPrefix := nullIfEmpty(filter.Role), nullIfEmpty(filter.EmailPrefix)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT count_users($1,$2,$3)`, role, emailPrefix, filter.IncludeDeleted).Scan(&total)
+	if err != nil {
+		log.Printf("Error calling count_users: %v", err)
+		return nil, err
+	}
+
+	args := append([]any{role, emailPrefix, filter.IncludeDeleted}, pageArgs(page)...)
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM list_users($1,$2,$3,$4,$5,$6,$7,$8)`, args...)
 	if err != nil {
-		log.Printf("Error getting users: %v", err)
+		log.Printf("Error querying list_users: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -134,6 +172,8 @@ func (r *userRepositoryImpl) List() ([]*model.User, error) {
 			&user.Role,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.EmailVerified,
+			&user.DeletedAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning user: %v", err)
@@ -147,15 +187,24 @@ func (r *userRepositoryImpl) List() ([]*model.User, error) {
 		return nil, err
 	}
 
-	return users, nil
+	return newPage(users, total, page, func(u *model.User) (string, uuid.UUID) {
+		switch page.Sort {
+		case "email":
+			return u.Email, u.ID
+		case "last_name":
+			return u.LastName, u.ID
+		}
+		return cursorTime(u.CreatedAt), u.ID
+	}), nil
 }
 
 // Update implements repository.UserRepository.
-func (r *userRepositoryImpl) Update(user *model.User) error {
+func (r *userRepositoryImpl) Update(ctx context.Context, user *model.User) error {
 	query := `CALL update_user($1, $2, $3, $4, $5, $6, $7)`
 	var updatedAt time.Time
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(
+		ctx,
 		query,
 		user.ID,
 		user.Email,
@@ -168,7 +217,7 @@ func (r *userRepositoryImpl) Update(user *model.User) error {
 
 	if err != nil {
 		log.Printf("Error updating user: %v", err)
-		return err
+		return writeError(err, "user")
 	}
 
 	user.UpdatedAt = updatedAt
@@ -176,51 +225,51 @@ func (r *userRepositoryImpl) Update(user *model.User) error {
 	return nil
 }
 
-// SetResetToken saves reset token and expiry
-func (r *userRepositoryImpl) SetResetToken(email string, token uuid.UUID, expiry string) error {
-	query := `SELECT set_reset_token($1, $2, $3)`
-	_, err := r.db.Exec(query, email, token, expiry)
+// CreatePasswordReset stores a reset token hash, replacing earlier unused ones of the user
+func (r *userRepositoryImpl) CreatePasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, ttl time.Duration, ip string) error {
+	query := `SELECT create_password_reset($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, userID, tokenHash, int(ttl.Seconds()), ip)
 	if err != nil {
-		log.Printf("Error setting reset token: %v", err)
-		return fmt.Errorf("failed to set reset token: %w", err)
+		log.Printf("Error calling create_password_reset: %v", err)
+		return writeError(err, "password_reset")
 	}
 	return nil
 }
 
-// FindByResetToken finds user by reset token
-func (r *userRepositoryImpl) FindByResetToken(token uuid.UUID) (*model.User, error) {
-	var user model.User
-
-	query := `SELECT * FROM get_user_by_reset_token($1)`
-	err := r.db.QueryRow(query, token).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.FirstName,
-		&user.LastName,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// FindPasswordReset returns the user a valid reset token belongs to
+func (r *userRepositoryImpl) FindPasswordReset(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var userID uuid.NullUUID
 
+	err := r.db.QueryRowContext(ctx, `SELECT find_password_reset($1)`, tokenHash).Scan(&userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("Reset token invalid or expired")
-			return nil, fmt.Errorf("reset token invalid or expired")
-		}
-		log.Printf("DB error: %v", err)
-		return nil, fmt.Errorf("failed to find user by reset token: %w", err)
+		log.Printf("Error calling find_password_reset: %v", err)
+		return uuid.Nil, fmt.Errorf("failed to find password reset: %w", err)
 	}
+	if !userID.Valid {
+		return uuid.Nil, nil
+	}
+	return userID.UUID, nil
+}
 
+// CompletePasswordReset spends a reset token and sets the new password
+func (r *userRepositoryImpl) CompletePasswordReset(ctx context.Context, tokenHash, hashedPassword string) (uuid.UUID, error) {
+	var userID uuid.NullUUID
 
-
-	return &user, nil
+	err := r.db.QueryRowContext(ctx, `SELECT complete_password_reset($1, $2)`, tokenHash, hashedPassword).Scan(&userID)
+	if err != nil {
+		log.Printf("Error calling complete_password_reset: %v", err)
+		return uuid.Nil, fmt.Errorf("failed to complete password reset: %w", err)
+	}
+	if !userID.Valid {
+		return uuid.Nil, nil
+	}
+	return userID.UUID, nil
 }
 
 // UpdatePassword updates the user's hashed password
-func (r *userRepositoryImpl) UpdatePassword(userID uuid.UUID, hashedPassword string) error {
+func (r *userRepositoryImpl) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
 	query := `SELECT update_user_password($1, $2)`
-	_, err := r.db.Exec(query, userID, hashedPassword)
+	_, err := r.db.ExecContext(ctx, query, userID, hashedPassword)
 	if err != nil {
 		log.Printf("Error updating password: %v", err)
 		return fmt.Errorf("failed to update password: %w", err)
@@ -228,10 +277,10 @@ func (r *userRepositoryImpl) UpdatePassword(userID uuid.UUID, hashedPassword str
 	return nil
 }
 
-// ClearResetToken clears the reset token fields
-func (r *userRepositoryImpl) ClearResetToken(userID uuid.UUID) error {
+// ClearResetToken invalidates the pending reset tokens of a user
+func (r *userRepositoryImpl) ClearResetToken(ctx context.Context, userID uuid.UUID) error {
 	query := `SELECT clear_reset_token($1)`
-	_, err := r.db.Exec(query, userID)
+	_, err := r.db.ExecContext(ctx, query, userID)
 	if err != nil {
 		log.Printf("Error clearing reset token: %v", err)
 		return fmt.Errorf("failed to clear reset token: %w", err)
@@ -239,6 +288,134 @@ func (r *userRepositoryImpl) ClearResetToken(userID uuid.UUID) error {
 	return nil
 }
 
+// UpdateProfile saves the self-service profile fields of a user
+func (r *userRepositoryImpl) UpdateProfile(ctx context.Context, user *model.User) error {
+	query := `CALL update_user_profile($1, $2, $3, $4, $5, $6, $7, $8)`
+	var updatedAt sql.NullTime
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		user.ID,
+		user.FirstName,
+		user.LastName,
+		user.AvatarURL,
+		user.Bio,
+		user.Locale,
+		user.Timezone,
+		nil,
+	).Scan(&updatedAt)
+
+	if err != nil {
+		log.Printf("Error updating user profile: %v", err)
+		return writeError(err, "user")
+	}
+	if !updatedAt.Valid {
+		return apperr.NotFound("user_not_found", "user not found")
+	}
+
+	user.UpdatedAt = updatedAt.Time
+	return nil
+}
+
+// CreateEmailChange stores a pending email change, replacing earlier ones of the user
+func (r *userRepositoryImpl) CreateEmailChange(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, ttl time.Duration) error {
+	query := `SELECT create_email_change($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, userID, newEmail, tokenHash, int(ttl.Seconds()))
+	if err != nil {
+		log.Printf("Error calling create_email_change: %v", err)
+		return writeError(err, "email_change")
+	}
+	return nil
+}
+
+// ConfirmEmailChange applies the pending email change matching the token hash
+func (r *userRepositoryImpl) ConfirmEmailChange(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var userID uuid.NullUUID
+
+	err := r.db.QueryRowContext(ctx, `SELECT confirm_email_change($1)`, tokenHash).Scan(&userID)
+	if err != nil {
+		log.Printf("Error calling confirm_email_change: %v", err)
+		return uuid.Nil, writeError(err, "email")
+	}
+	if !userID.Valid {
+		return uuid.Nil, nil
+	}
+	return userID.UUID, nil
+}
+
+// MarkVerificationSent records that a verification link goes out, unless one went out recently
+func (r *userRepositoryImpl) MarkVerificationSent(ctx context.Context, userID uuid.UUID, minInterval time.Duration) (bool, error) {
+	var marked bool
+
+	err := r.db.QueryRowContext(ctx, `SELECT mark_verification_sent($1, $2)`, userID, int(minInterval.Seconds())).Scan(&marked)
+	if err != nil {
+		log.Printf("Error calling mark_verification_sent: %v", err)
+		return false, err
+	}
+	return marked, nil
+}
+
+// VerifyEmail marks email as verified if it is still the user's address
+func (r *userRepositoryImpl) VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (bool, error) {
+	var verified bool
+
+	err := r.db.QueryRowContext(ctx, `SELECT verify_user_email($1, $2)`, userID, email).Scan(&verified)
+	if err != nil {
+		log.Printf("Error calling verify_user_email: %v", err)
+		return false, err
+	}
+	return verified, nil
+}
+
+// GetPasswordHistory returns the hashes of the user's previous passwords, newest first
+func (r *userRepositoryImpl) GetPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT * FROM get_password_history($1, $2)`, userID, limit)
+	if err != nil {
+		log.Printf("Error calling get_password_history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			log.Printf("Error scanning password history: %v", err)
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// RehashPassword replaces oldHash with newHash if the password is unchanged
+func (r *userRepositoryImpl) RehashPassword(ctx context.Context, userID uuid.UUID, oldHash, newHash string) (bool, error) {
+	var rehashed bool
+
+	err := r.db.QueryRowContext(ctx, `SELECT rehash_user_password($1, $2, $3)`, userID, oldHash, newHash).Scan(&rehashed)
+	if err != nil {
+		log.Printf("Error calling rehash_user_password: %v", err)
+		return false, err
+	}
+	return rehashed, nil
+}
+
+// GetPasswordExpiry returns when the user's password expires, or nil if it does not
+func (r *userRepositoryImpl) GetPasswordExpiry(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var expiresAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT get_password_expiry($1)`, userID).Scan(&expiresAt)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error calling get_password_expiry: %v", err)
+		return nil, err
+	}
+	if !expiresAt.Valid {
+		return nil, nil
+	}
+	return &expiresAt.Time, nil
+}
+
 func NewUserRepositry(db *sql.DB) repository.UserRepository {
-	return &userRepositoryImpl{db: db}
+	return &userRepositoryImpl{db: tracing.WrapDB(db)}
 }
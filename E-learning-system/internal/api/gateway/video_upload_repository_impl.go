@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type VideoUploadRepositoryImpl struct {
+	db *tracing.DB
+}
+
+// Create inserts a new upload session using the stored procedure
+func (r *VideoUploadRepositoryImpl) Create(ctx context.Context, upload *model.VideoUpload) error {
+	_, err := r.db.ExecContext(ctx, `CALL create_video_upload($1,$2,$3,$4,$5,$6)`,
+		upload.ID, upload.LessonID, upload.OwnerID, upload.FileName, upload.Length, upload.ExpiresAt,
+	)
+	if err != nil {
+		log.Printf("Error calling create_video_upload: %v", err)
+		return writeError(err, "video_upload")
+	}
+
+	log.Printf("Video upload created: %v (%d bytes)", upload.ID, upload.Length)
+	return nil
+}
+
+// GetByID retrieves an upload session by ID using the stored function
+func (r *VideoUploadRepositoryImpl) GetByID(ctx context.Context, uploadID uuid.UUID) (*model.VideoUpload, error) {
+	var u model.VideoUpload
+
+	row := r.db.QueryRowContext(ctx, `SELECT * FROM get_video_upload_by_id($1)`, uploadID)
+	err := row.Scan(
+		&u.ID,
+		&u.LessonID,
+		&u.OwnerID,
+		&u.FileName,
+		&u.Length,
+		&u.Offset,
+		&u.ExpiresAt,
+		&u.CompletedAt,
+		&u.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperr.NotFound("video_upload_not_found", "video upload not found")
+		}
+		log.Printf("Error scanning video upload by ID: %v", err)
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// UpdateOffset records how many bytes have been received
+func (r *VideoUploadRepositoryImpl) UpdateOffset(ctx context.Context, uploadID uuid.UUID, offset int64) error {
+	_, err := r.db.ExecContext(ctx, `CALL update_video_upload_offset($1,$2)`, uploadID, offset)
+	if err != nil {
+		log.Printf("Error calling update_video_upload_offset: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Complete marks an upload session as finished
+func (r *VideoUploadRepositoryImpl) Complete(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL complete_video_upload($1)`, uploadID)
+	if err != nil {
+		log.Printf("Error calling complete_video_upload: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Delete removes an upload session
+func (r *VideoUploadRepositoryImpl) Delete(ctx context.Context, uploadID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `CALL delete_video_upload($1)`, uploadID)
+	if err != nil {
+		log.Printf("Error calling delete_video_upload: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Constructor
+func NewVideoUploadRepository(db *sql.DB) repository.VideoUploadRepository {
+	return &VideoUploadRepositoryImpl{db: tracing.WrapDB(db)}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/tenant"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errAPIKeyNotAccepted = apperr.Forbidden("api_key_not_accepted", "API keys cannot be used for this endpoint")
+
+// APIKeyAuthenticator checks a full API key and returns it when it is active
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+// APIKeyRoute is what a key needs to call one endpoint
+type APIKeyRoute struct {
+	Scope model.AdminPermission
+	// OrgParam names the path parameter holding an organization ID that must
+	// be the key's own organization, if the endpoint has one
+	OrgParam string
+}
+
+// APIKeyAuth accepts "Authorization: Bearer elk_..." API keys on the
+// endpoints listed in routes, keyed by method and route pattern such as
+// "GET /organization-tutors/:id". Other endpoints refuse keys. An accepted
+// key signs the request in as the admin who created it, and AuthMiddleware
+// then lets it through. The request context is confined to the key's
+// organization, so the services refuse records of other organizations.
+// Requests without an API key are left to AuthMiddleware.
+//
+// It must be installed on the engine, before the route groups, so it sees
+// every request.
+func APIKeyAuth(keys APIKeyAuthenticator, routes map[string]APIKeyRoute) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "+model.APIKeyPrefix)
+		if !ok {
+			c.Next()
+			return
+		}
+		rawKey = model.APIKeyPrefix + rawKey
+
+		route, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			WriteProblem(c, errAPIKeyNotAccepted)
+			return
+		}
+
+		key, err := keys.Authenticate(c.Request.Context(), rawKey)
+		if err != nil {
+			WriteProblem(c, err)
+			return
+		}
+
+		if !key.HasScope(route.Scope) {
+			WriteProblem(c, apperr.Forbidden("api_key_scope_missing", "this API key lacks the "+string(route.Scope)+" scope"))
+			return
+		}
+		if route.OrgParam != "" && c.Param(route.OrgParam) != key.OrganizationID.String() {
+			WriteProblem(c, tenant.ErrOtherOrganization)
+			return
+		}
+
+		c.Set("userID", key.CreatedBy)
+		c.Set("apiKeyID", key.ID)
+		setAuditSource(c, key.CreatedBy, &key.ID)
+		c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), key.OrganizationID))
+
+		c.Next()
+	}
+}
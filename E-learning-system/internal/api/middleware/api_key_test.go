@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/tenant"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// fakeAuthenticator accepts exactly one key
+type fakeAuthenticator struct {
+	raw  string
+	key  *model.APIKey
+	seen []string
+}
+
+func (a *fakeAuthenticator) Authenticate(_ context.Context, rawKey string) (*model.APIKey, error) {
+	a.seen = append(a.seen, rawKey)
+	if rawKey != a.raw {
+		return nil, apperr.Unauthenticated("api_key_invalid", "invalid API key")
+	}
+	return a.key, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	orgID := uuid.Must(uuid.NewV4())
+	creatorID := uuid.Must(uuid.NewV4())
+	key := &model.APIKey{
+		ID:             uuid.Must(uuid.NewV4()),
+		OrganizationID: orgID,
+		CreatedBy:      creatorID,
+		Scopes:         []model.AdminPermission{model.ManageCourses},
+	}
+	raw := model.APIKeyPrefix + "abcdef012345_secret_with_underscores"
+	routes := map[string]APIKeyRoute{
+		"GET /courses":                   {Scope: model.ManageCourses},
+		"GET /organizations/:id/members": {Scope: model.ManageCourses, OrgParam: "id"},
+		"DELETE /users/:id":              {Scope: model.ManageUser},
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+		wantCode      string
+		wantAsKey     bool // the handler ran as the key's creator, confined to its organization
+	}{
+		{"valid key", http.MethodGet, "/courses", "Bearer " + raw, http.StatusOK, "", true},
+		{"own organization", http.MethodGet, "/organizations/" + orgID.String() + "/members", "Bearer " + raw, http.StatusOK, "", true},
+		{"other organization", http.MethodGet, "/organizations/" + uuid.Must(uuid.NewV4()).String() + "/members", "Bearer " + raw, http.StatusForbidden, tenant.ErrOtherOrganization.Code, false},
+		{"missing scope", http.MethodDelete, "/users/" + creatorID.String(), "Bearer " + raw, http.StatusForbidden, "api_key_scope_missing", false},
+		{"endpoint not open to keys", http.MethodGet, "/profile", "Bearer " + raw, http.StatusForbidden, "api_key_not_accepted", false},
+		{"wrong key", http.MethodGet, "/courses", "Bearer " + model.APIKeyPrefix + "abcdef012345_wrong", http.StatusUnauthorized, "api_key_invalid", false},
+		{"session token left to the auth middleware", http.MethodGet, "/courses", "Bearer eyJhbGciOi", http.StatusOK, "", false},
+		{"key without Bearer", http.MethodGet, "/courses", raw, http.StatusOK, "", false},
+		{"no authorization", http.MethodGet, "/courses", "", http.StatusOK, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeAuthenticator{raw: raw, key: key}
+			var asKey bool
+			handler := func(c *gin.Context) {
+				userID, _ := c.Get("userID")
+				keyID, _ := c.Get("apiKeyID")
+				reqOrg, confined := tenant.OrganizationFromContext(c.Request.Context())
+				asKey = userID == creatorID && keyID == key.ID && confined && reqOrg == orgID
+				c.Status(http.StatusOK)
+			}
+
+			r := gin.New()
+			r.Use(APIKeyAuth(keys, routes))
+			r.GET("/courses", handler)
+			r.GET("/organizations/:id/members", handler)
+			r.DELETE("/users/:id", handler)
+			r.GET("/profile", handler)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode != "" {
+				var problem Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatal(err)
+				}
+				if problem.Code != tt.wantCode {
+					t.Errorf("code = %q, want %q", problem.Code, tt.wantCode)
+				}
+			}
+			if asKey != tt.wantAsKey {
+				t.Errorf("handler ran as the key = %v, want %v", asKey, tt.wantAsKey)
+			}
+			if tt.wantAsKey && (len(keys.seen) != 1 || keys.seen[0] != raw) {
+				t.Errorf("authenticated %q, want the full key once", keys.seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"e-learning-system/internal/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// setAuditSource records who is signed in on the request context, along
+// with the client and request ID, for the audit events services write
+func setAuditSource(c *gin.Context, userID uuid.UUID, apiKeyID *uuid.UUID) {
+	src := audit.Source{
+		ActorID:   &userID,
+		APIKeyID:  apiKeyID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString("requestID"),
+	}
+	c.Request = c.Request.WithContext(audit.WithSource(c.Request.Context(), src))
+}
@@ -1,24 +1,37 @@
 package middleware
 
 import (
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/repository"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Authentication failures, rendered as 401 problems
+var (
+	errMissingToken   = apperr.Unauthenticated("token_missing", "authorization token required")
+	errMalformedToken = apperr.Unauthenticated("token_malformed", "authorization format must be Bearer <token>")
+	errInvalidToken   = apperr.Unauthenticated("token_invalid", "invalid or expired token")
+	errExpiredToken   = apperr.Unauthenticated("token_expired", "token expired")
+)
+
 // AuthMiddleware verifies the token and protects the routes by checking the token in the database.
+// Requests already signed in with an API key by APIKeyAuth pass.
 func AuthMiddleware(tokenRepo repository.TokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			c.Next()
+			return
+		}
+
 		// Get the token from the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			log.Println("Missing Authorization header")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-			c.Abort()
+			WriteProblem(c, errMissingToken)
 			return
 		}
 
@@ -26,44 +39,40 @@ func AuthMiddleware(tokenRepo repository.TokenRepository) gin.HandlerFunc {
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			log.Println("Invalid Authorization format")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization format must be Bearer <token>"})
-			c.Abort()
+			WriteProblem(c, errMalformedToken)
 			return
 		}
 
 		tokenString := parts[1]
 
 		// Fetch the token from the database
-		token, err := tokenRepo.FindByToken(tokenString)
+		token, err := tokenRepo.FindByToken(c.Request.Context(), tokenString)
 		if err != nil {
 			log.Printf("Token lookup failed: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			WriteProblem(c, errInvalidToken)
 			return
 		}
 
 		// ✅ Added check to prevent nil pointer dereference
 		if token == nil {
 			log.Println("Token not found")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			WriteProblem(c, errInvalidToken)
 			return
 		}
 
 		// Check if the token has expired
 		if token.ExpiresAt.Before(time.Now()) {
 			log.Printf("Token expired at: %v", token.ExpiresAt)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
-			c.Abort()
+			WriteProblem(c, errExpiredToken)
 			return
 		}
 
-		// Token is valid — set user ID into request context
+		// Token is valid — set user and session IDs into request context
 		c.Set("userID", token.UserID)
+		c.Set("tokenID", token.ID)
+		setAuditSource(c, token.UserID, nil)
 
 		// Proceed to the next handler
 		c.Next()
 	}
 }
-
-
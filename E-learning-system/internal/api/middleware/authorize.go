@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+var (
+	errRoleRequired          = apperr.Forbidden("permission_denied", "you do not have permission to perform this action")
+	errVerifiedEmailRequired = apperr.Forbidden("email_not_verified", "verify your email address to perform this action")
+	errMFAEnrollmentRequired = apperr.Forbidden("mfa_enrollment_required", "set up two-factor authentication to perform this action")
+)
+
+// RequireRole only lets users with one of the given roles through. It must
+// run after AuthMiddleware; the role is read from the database so that a
+// demotion takes effect immediately.
+func RequireRole(userRepo repository.UserRepository, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, userRepo)
+		if !ok {
+			return
+		}
+
+		if !slices.Contains(roles, user.Role) {
+			WriteProblem(c, errRoleRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRoleToIncludeDeleted applies RequireRole to list requests that ask
+// for soft-deleted records with include_deleted; other requests pass.
+func RequireRoleToIncludeDeleted(userRepo repository.UserRepository, roles ...string) gin.HandlerFunc {
+	requireRole := RequireRole(userRepo, roles...)
+	return func(c *gin.Context) {
+		if include, err := strconv.ParseBool(c.Query("include_deleted")); err != nil || !include {
+			c.Next()
+			return
+		}
+		requireRole(c)
+	}
+}
+
+// RequireVerifiedEmail only lets users with a verified email through. It
+// must run after AuthMiddleware. When enforce is false it lets everyone
+// through, so routes can be wired the same way whatever the configuration.
+func RequireVerifiedEmail(userRepo repository.UserRepository, enforce bool) gin.HandlerFunc {
+	if !enforce {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		user, ok := currentUser(c, userRepo)
+		if !ok {
+			return
+		}
+
+		if !user.EmailVerified {
+			WriteProblem(c, errVerifiedEmailRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireMFA keeps users whose role or organization requires two-factor
+// authentication out until they have set it up. It must run after
+// AuthMiddleware. Users with MFA enabled could only sign in with it, so
+// their sessions pass.
+func RequireMFA(mfaRepo repository.MFARepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("userID")
+		userID, ok := value.(uuid.UUID)
+		if !ok {
+			WriteProblem(c, errMissingToken)
+			return
+		}
+
+		status, err := mfaRepo.GetStatus(c.Request.Context(), userID)
+		if err != nil {
+			if apperr.KindOf(err) == apperr.KindNotFound {
+				WriteProblem(c, errInvalidToken)
+				return
+			}
+			WriteProblem(c, err)
+			return
+		}
+
+		if status.Required && !status.Enabled {
+			WriteProblem(c, errMFAEnrollmentRequired)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// currentUser loads the signed-in user. It writes the problem and returns
+// ok=false when there is none.
+func currentUser(c *gin.Context, userRepo repository.UserRepository) (*model.User, bool) {
+	value, _ := c.Get("userID")
+	userID, ok := value.(uuid.UUID)
+	if !ok {
+		WriteProblem(c, errMissingToken)
+		return nil, false
+	}
+
+	user, err := userRepo.Get(c.Request.Context(), userID)
+	if err != nil {
+		if apperr.KindOf(err) == apperr.KindNotFound {
+			WriteProblem(c, errInvalidToken)
+			return nil, false
+		}
+		WriteProblem(c, err)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/logger"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code is a stable,
+// machine-readable identifier; Detail is meant for humans.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []apperr.FieldError `json:"errors,omitempty"`
+}
+
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindValidation:       http.StatusBadRequest,
+	apperr.KindUnauthenticated:  http.StatusUnauthorized,
+	apperr.KindForbidden:        http.StatusForbidden,
+	apperr.KindNotFound:         http.StatusNotFound,
+	apperr.KindConflict:         http.StatusConflict,
+	apperr.KindGone:             http.StatusGone,
+	apperr.KindTooLarge:         http.StatusRequestEntityTooLarge,
+	apperr.KindUnsupportedMedia: http.StatusUnsupportedMediaType,
+	apperr.KindRateLimited:      http.StatusTooManyRequests,
+}
+
+// ErrorHandler renders the last error a handler attached with c.Error as
+// problem+json, unless a response has already been written.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		WriteProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// WriteProblem maps err to its status and writes it as problem+json.
+// Untyped errors become a generic 500; their text is logged, not returned.
+func WriteProblem(c *gin.Context, err error) {
+	e, ok := apperr.As(err)
+	if !ok || e.Kind == apperr.KindInternal {
+		logger.FromContext(c.Request.Context()).Error("request failed",
+			"error", err,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		)
+		writeInternalProblem(c)
+		return
+	}
+
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfter.Seconds()))))
+	}
+	writeProblem(c, Problem{
+		Status: kindStatus[e.Kind],
+		Code:   e.Code,
+		Detail: err.Error(),
+		Errors: e.Fields,
+	})
+}
+
+func writeInternalProblem(c *gin.Context) {
+	writeProblem(c, Problem{
+		Status: http.StatusInternalServerError,
+		Code:   "internal_error",
+		Detail: "an unexpected error occurred",
+	})
+}
+
+func writeProblem(c *gin.Context, problem Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = c.Request.URL.Path
+	problem.RequestID = c.GetString("requestID")
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       string
+		wantDetail     string
+		wantFields     int
+		wantRetryAfter string
+	}{
+		{"validation", apperr.Validation("invalid_email", "email is invalid", apperr.FieldError{Field: "email", Message: "must be a valid email address"}),
+			http.StatusBadRequest, "invalid_email", "email is invalid", 1, ""},
+		{"unauthenticated", apperr.Unauthenticated("token_expired", "token has expired"), http.StatusUnauthorized, "token_expired", "token has expired", 0, ""},
+		{"forbidden", apperr.Forbidden("course_forbidden", "not your course"), http.StatusForbidden, "course_forbidden", "not your course", 0, ""},
+		{"not found", apperr.NotFound("course_not_found", "course not found"), http.StatusNotFound, "course_not_found", "course not found", 0, ""},
+		{"conflict", apperr.Conflict("email_taken", "email is taken"), http.StatusConflict, "email_taken", "email is taken", 0, ""},
+		{"gone", apperr.New(apperr.KindGone, "upload_expired", "upload has expired"), http.StatusGone, "upload_expired", "upload has expired", 0, ""},
+		{"too large", apperr.New(apperr.KindTooLarge, "file_too_large", "file is too large"), http.StatusRequestEntityTooLarge, "file_too_large", "file is too large", 0, ""},
+		{"unsupported media", apperr.New(apperr.KindUnsupportedMedia, "video_unsupported", "not a video"), http.StatusUnsupportedMediaType, "video_unsupported", "not a video", 0, ""},
+		{"rate limited rounds up", apperr.RateLimited("too_many_attempts", "try later").WithRetryAfter(1500 * time.Millisecond),
+			http.StatusTooManyRequests, "too_many_attempts", "try later", 0, "2"},
+		{"wrapped keeps the outer text", fmt.Errorf("course not found with ID 42: %w", apperr.NotFound("course_not_found", "course not found")),
+			http.StatusNotFound, "course_not_found", "course not found with ID 42: course not found", 0, ""},
+		{"cause stays hidden", apperr.Conflict("email_taken", "email is taken").Wrap(errors.New("pq: duplicate key users_email_key")),
+			http.StatusConflict, "email_taken", "email is taken", 0, ""},
+		{"untyped", errors.New("pq: connection refused"), http.StatusInternalServerError, "internal_error", "an unexpected error occurred", 0, ""},
+		{"internal kind", apperr.New(apperr.KindInternal, "storage_down", "storage is down"), http.StatusInternalServerError, "internal_error", "an unexpected error occurred", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("requestID", "req-1") }, ErrorHandler())
+			r.GET("/courses/:id", func(c *gin.Context) {
+				_ = c.Error(errors.New("an earlier error"))
+				_ = c.Error(tt.err)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/courses/42", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ProblemContentType) {
+				t.Errorf("content type = %q", ct)
+			}
+			if ra := w.Header().Get("Retry-After"); ra != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", ra, tt.wantRetryAfter)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != tt.wantStatus || problem.Code != tt.wantCode || problem.Detail != tt.wantDetail {
+				t.Errorf("problem = %d %s %q, want %d %s %q", problem.Status, problem.Code, problem.Detail,
+					tt.wantStatus, tt.wantCode, tt.wantDetail)
+			}
+			if problem.Title != http.StatusText(tt.wantStatus) || problem.Instance != "/courses/42" || problem.RequestID != "req-1" {
+				t.Errorf("problem = %+v", problem)
+			}
+			if len(problem.Errors) != tt.wantFields {
+				t.Errorf("field errors = %v, want %d", problem.Errors, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerLeavesWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{"queued": true})
+		_ = c.Error(errors.New("logged but not rendered"))
+	})
+	r.GET("/nothing", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for path, want := range map[string]int{"/ok": http.StatusAccepted, "/nothing": http.StatusNoContent} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want || strings.Contains(w.Body.String(), "internal_error") {
+			t.Errorf("%s: %d %s, want %d untouched", path, w.Code, w.Body, want)
+		}
+	}
+}
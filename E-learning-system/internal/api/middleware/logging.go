@@ -1 +1,103 @@
-package middleware
\ No newline at end of file
+package middleware
+
+import (
+	"e-learning-system/internal/logger"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID accepts IDs from upstream proxies that are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID reuses the caller's X-Request-ID or generates one, exposes it as
+// "requestID" on the gin context and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			id, err := uuid.NewV4()
+			if err == nil {
+				requestID = id.String()
+			}
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// RequestLogger attaches a request scoped logger to the request context and
+// writes one access log line per request. Query strings are left out because
+// they may carry signed tokens.
+func RequestLogger(base *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		reqLogger := base.With("request_id", c.GetString("requestID"))
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			reqLogger = reqLogger.With("trace_id", sc.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), reqLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := c.Get("userID"); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				attrs = append(attrs, slog.String("user_id", id.String()))
+			}
+		}
+		if apiKeyID, ok := c.Get("apiKeyID"); ok {
+			if id, ok := apiKeyID.(uuid.UUID); ok {
+				attrs = append(attrs, slog.String("api_key_id", id.String()))
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		reqLogger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns panics into 500 responses and logs them with the request logger
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logger.FromContext(c.Request.Context()).Error("panic recovered",
+			"error", recovered,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"stack", string(debug.Stack()),
+		)
+		writeInternalProblem(c)
+	})
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"e-learning-system/internal/tracing"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span per request, continuing the trace of an
+// incoming W3C traceparent header, and returns traceparent in the response.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, fmt.Sprintf("%s %s", c.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				attribute.String("request_id", c.GetString("requestID")),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if userID, ok := c.Get("userID"); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				span.SetAttributes(attribute.String("user_id", id.String()))
+			}
+		}
+		if apiKeyID, ok := c.Get("apiKeyID"); ok {
+			if id, ok := apiKeyID.(uuid.UUID); ok {
+				span.SetAttributes(attribute.String("api_key_id", id.String()))
+			}
+		}
+		if len(c.Errors) > 0 {
+			span.SetAttributes(attribute.String("errors", c.Errors.String()))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
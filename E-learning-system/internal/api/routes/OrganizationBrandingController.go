@@ -13,19 +13,32 @@ func RegisterOrganizationBrandingRoutes(
 	routes *gin.Engine,
 	brandingController *controller.OrganizationBrandingController,
 	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
 ) {
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Restoring and listing deleted records are for platform admins
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
+
+	// Public theming, fetched by the frontend before login
+	publicGroup := routes.Group("/branding")
+	{
+		publicGroup.GET("/:domain", brandingController.GetBrandingManifest)          // Branding manifest by domain
+		publicGroup.GET("/:domain/theme.css", brandingController.GetThemeStylesheet) // Generated CSS variables
+	}
 
 	brandingGroup := routes.Group("/organization-brandings")
 	{
 		// All organization branding routes are protected by authentication
 		brandingGroup.Use(authMiddleware)
 		{
-			brandingGroup.POST("", brandingController.CreateOrganizationBranding)       // Create new branding
-			brandingGroup.PUT("/:id", brandingController.UpdateOrganizationBranding)    // Update branding by ID
-			brandingGroup.DELETE("/:id", brandingController.DeleteOrganizationBranding) // Soft delete branding by ID
-			brandingGroup.GET("/:id", brandingController.GetOrganizationBrandingByID)   // Get branding by ID
-			brandingGroup.GET("", brandingController.GetAllOrganizationBrandings)       // Get all brandings
+			brandingGroup.POST("", brandingController.CreateOrganizationBranding)                         // Create new branding
+			brandingGroup.PUT("/:id", brandingController.UpdateOrganizationBranding)                      // Update branding by ID
+			brandingGroup.DELETE("/:id", brandingController.DeleteOrganizationBranding)                   // Soft delete branding by ID
+			brandingGroup.POST("/:id/restore", adminOnly, brandingController.RestoreOrganizationBranding) // Restore soft-deleted branding by ID
+			brandingGroup.GET("/:id", brandingController.GetOrganizationBrandingByID)                     // Get branding by ID
+			brandingGroup.GET("", adminForDeleted, brandingController.GetAllOrganizationBrandings)        // Get all brandings
+			brandingGroup.POST("/:id/logo", brandingController.UploadOrganizationLogo)                    // Upload logo (multipart "file")
 		}
 	}
 }
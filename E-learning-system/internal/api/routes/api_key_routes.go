@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRoutes lists the endpoints integrations may call with an API key
+// and the scope each needs. Every one of them stays inside the key's
+// organization: endpoints that name an organization in their path only
+// accept keys of that organization, and the services behind the others
+// refuse records of other organizations. User accounts are shared between
+// organizations, so /users is left to signed-in admins; integrations read
+// their members from the member export instead. Anything not listed, such
+// as signing in or managing keys, needs a user session.
+var APIKeyRoutes = map[string]middleware.APIKeyRoute{
+	// Members and tutors
+	"GET /organizations/:id/members/export": {Scope: model.ManageUser, OrgParam: "id"},
+	"GET /organization-tutors":              {Scope: model.ManageUser},
+	"POST /organization-tutors":             {Scope: model.ManageUser},
+	"GET /organization-tutors/:id":          {Scope: model.ManageUser},
+	"PUT /organization-tutors/:id":          {Scope: model.ManageUser},
+	"DELETE /organization-tutors/:id":       {Scope: model.ManageUser},
+
+	// Courses and lessons
+	"GET /courses":              {Scope: model.ManageCourses},
+	"POST /courses":             {Scope: model.ManageCourses},
+	"GET /courses/:id":          {Scope: model.ManageCourses},
+	"GET /courses/:id/lessons":  {Scope: model.ManageCourses},
+	"POST /courses/:id/lessons": {Scope: model.ManageCourses},
+	"GET /lessons/:id":          {Scope: model.ManageCourses},
+
+	// The key's own organization
+	"GET /organizations/:id":                {Scope: model.ManageOrganizations, OrgParam: "id"},
+	"PUT /organizations/:id":                {Scope: model.ManageOrganizations, OrgParam: "id"},
+	"GET /organizations/:id/export":         {Scope: model.ManageOrganizations, OrgParam: "id"},
+	"GET /organizations/:id/status-history": {Scope: model.ViewAnalytics, OrgParam: "id"},
+	"GET /organizations/:id/policy":         {Scope: model.ManageSettings, OrgParam: "id"},
+	"PUT /organizations/:id/policy":         {Scope: model.ManageSettings, OrgParam: "id"},
+
+	// Branding and billing
+	"GET /organization-brandings":           {Scope: model.ManageSettings},
+	"POST /organization-brandings":          {Scope: model.ManageSettings},
+	"GET /organization-brandings/:id":       {Scope: model.ManageSettings},
+	"PUT /organization-brandings/:id":       {Scope: model.ManageSettings},
+	"POST /organization-brandings/:id/logo": {Scope: model.ManageSettings},
+	"GET /organization-billings":            {Scope: model.ManagePayments},
+	"POST /organization-billings":           {Scope: model.ManagePayments},
+	"GET /organization-billings/:id":        {Scope: model.ManagePayments},
+	"PUT /organization-billings/:id":        {Scope: model.ManagePayments},
+}
+
+// RegisterAPIKeyRoutes registers the endpoints organizations manage their API keys with
+func RegisterAPIKeyRoutes(routes *gin.Engine, apiKeyController *controller.APIKeyController, tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository, requireMFA gin.HandlerFunc) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Keys act with their creator's rights, so only platform admins issue them
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+
+	keyGroup := routes.Group("/organizations/:id/api-keys")
+	{
+		keyGroup.Use(authMiddleware, requireMFA, adminOnly)
+		{
+			keyGroup.POST("", apiKeyController.CreateAPIKey)          // Issue a key; the secret is shown once
+			keyGroup.GET("", apiKeyController.ListAPIKeys)            // List keys without secrets
+			keyGroup.DELETE("/:keyId", apiKeyController.RevokeAPIKey) // Revoke a key
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAssetRoutes registers file upload and download endpoints
+func RegisterAssetRoutes(
+	routes *gin.Engine,
+	assetController *controller.AssetController,
+	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+
+	// Public: signed local files and logo redirects
+	routes.GET("/files/*key", assetController.ServeLocalFile)
+	routes.GET("/assets/:id/content", assetController.GetAssetContent)
+
+	assetGroup := routes.Group("/assets")
+	{
+		assetGroup.Use(authMiddleware)
+		{
+			assetGroup.POST("", assetController.UploadAsset)                         // Upload a file
+			assetGroup.GET("/:id", assetController.GetAsset)                         // Asset metadata with signed URLs
+			assetGroup.DELETE("/:id", assetController.DeleteAsset)                   // Soft delete asset; files stay until the retention purge
+			assetGroup.POST("/:id/restore", adminOnly, assetController.RestoreAsset) // Restore soft-deleted asset
+		}
+	}
+}
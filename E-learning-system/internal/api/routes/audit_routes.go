@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAuditRoutes registers the audit log endpoints
+func RegisterAuditRoutes(routes *gin.Engine, auditController *controller.AuditController, tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository, requireMFA gin.HandlerFunc) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// The log spans every organization, so only platform admins read it
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+
+	auditGroup := routes.Group("/audit")
+	{
+		auditGroup.Use(authMiddleware, requireMFA, adminOnly)
+		{
+			auditGroup.GET("", auditController.ListAuditEvents)          // List audit events
+			auditGroup.GET("/export", auditController.ExportAuditEvents) // Download audit events as CSV
+		}
+	}
+}
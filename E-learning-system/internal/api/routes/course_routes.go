@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCourseRoutes registers course, lesson and enrollment endpoints
+func RegisterCourseRoutes(
+	routes *gin.Engine,
+	courseController *controller.CourseController,
+	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+	requireVerified gin.HandlerFunc,
+) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Students take courses; instructors and admins write them
+	authorsOnly := middleware.RequireRole(userRepo, "admin", "instructor")
+	// Restoring and listing deleted records are for platform admins
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
+
+	courseGroup := routes.Group("/courses")
+	{
+		courseGroup.Use(authMiddleware)
+		{
+			courseGroup.POST("", requireVerified, authorsOnly, courseController.CreateCourse)              // Create course
+			courseGroup.GET("", adminForDeleted, courseController.GetAllCourses)                           // List courses
+			courseGroup.GET("/:id", courseController.GetCourseByID)                                        // Get course by ID
+			courseGroup.DELETE("/:id", courseController.DeleteCourse)                                      // Soft delete course and its lessons
+			courseGroup.POST("/:id/restore", adminOnly, courseController.RestoreCourse)                    // Restore soft-deleted course
+			courseGroup.POST("/:id/lessons", requireVerified, authorsOnly, courseController.CreateLesson)  // Add lesson
+			courseGroup.GET("/:id/lessons", courseController.GetLessonsByCourse)                           // List lessons
+			courseGroup.POST("/:id/enroll", requireVerified, courseController.Enroll)                      // Enroll current user, or ask to
+			courseGroup.GET("/:id/enrollments", courseController.GetCourseEnrollments)                     // List enrollments and requests
+			courseGroup.POST("/:id/enrollments/:enrollmentID/approve", courseController.ApproveEnrollment) // Admit a student
+		}
+	}
+
+	routes.GET("/lessons/:id", authMiddleware, courseController.GetLessonByID)
+	routes.DELETE("/lessons/:id", authMiddleware, courseController.DeleteLesson)
+	routes.POST("/lessons/:id/restore", authMiddleware, adminOnly, courseController.RestoreLesson)
+	routes.GET("/enrollments", authMiddleware, courseController.GetMyEnrollments)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHealthRoutes registers liveness, readiness and metrics endpoints
+func RegisterHealthRoutes(routes *gin.Engine, healthController *controller.HealthController) {
+	routes.GET("/healthz", healthController.Liveness)  // Process is alive
+	routes.GET("/readyz", healthController.Readiness)  // Postgres and Redis reachable
+	routes.GET("/metrics", healthController.Metrics()) // Prometheus scrape endpoint
+}
@@ -13,19 +13,25 @@ func RegisterOrganizationAdminRoutes(
 	routes *gin.Engine,
 	orgAdminController *controller.OrganizationAdminController,
 	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+	requireMFA gin.HandlerFunc,
 ) {
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Restoring and listing deleted records are for platform admins
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
 
 	adminGroup := routes.Group("/organization-admins")
 	{
 		// All organization admin routes are protected by authentication
-		adminGroup.Use(authMiddleware)
+		adminGroup.Use(authMiddleware, requireMFA)
 		{
-			adminGroup.POST("", orgAdminController.CreateOrganizationAdmin)       // Create new admin
-			adminGroup.PUT("/:id", orgAdminController.UpdateOrganizationAdmin)    // Update admin by ID
-			adminGroup.DELETE("/:id", orgAdminController.DeleteOrganizationAdmin) // Soft delete admin by ID
-			adminGroup.GET("/:id", orgAdminController.GetOrganizationAdminByID)   // Get admin by ID
-			adminGroup.GET("", orgAdminController.GetAllOrganizationAdmins)       // Get all admins
+			adminGroup.POST("", orgAdminController.CreateOrganizationAdmin)                         // Create new admin
+			adminGroup.PUT("/:id", orgAdminController.UpdateOrganizationAdmin)                      // Update admin by ID
+			adminGroup.DELETE("/:id", orgAdminController.DeleteOrganizationAdmin)                   // Soft delete admin by ID
+			adminGroup.POST("/:id/restore", adminOnly, orgAdminController.RestoreOrganizationAdmin) // Restore soft-deleted admin by ID
+			adminGroup.GET("/:id", orgAdminController.GetOrganizationAdminByID)                     // Get admin by ID
+			adminGroup.GET("", adminForDeleted, orgAdminController.GetAllOrganizationAdmins)        // Get all admins
 		}
 	}
 }
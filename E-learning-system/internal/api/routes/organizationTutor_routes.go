@@ -13,19 +13,24 @@ func RegisterOrganizationTutorRoutes(
 	routes *gin.Engine,
 	orgTutorController *controller.OrganizationTutorController,
 	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
 ) {
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Restoring and listing deleted records are for platform admins
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
 
 	tutorGroup := routes.Group("/organization-tutors")
 	{
 		// All organization tutor routes are protected by authentication
 		tutorGroup.Use(authMiddleware)
 		{
-			tutorGroup.POST("", orgTutorController.CreateTutorOrganization)       // Add a tutor to organization
-			tutorGroup.PUT("/:id", orgTutorController.UpdateTutorOrganization)    // Update tutor info (approve, etc.)
-			tutorGroup.DELETE("/:id", orgTutorController.DeleteTutorOrganization) // Remove tutor from organization
-			tutorGroup.GET("/:id", orgTutorController.GetTutorOrganizationByID)   // Get tutor by ID
-			tutorGroup.GET("", orgTutorController.GetAllTutorsOrganization)       // Get all tutors
+			tutorGroup.POST("", orgTutorController.CreateTutorOrganization)                         // Add a tutor to organization
+			tutorGroup.PUT("/:id", orgTutorController.UpdateTutorOrganization)                      // Update tutor info (approve, etc.)
+			tutorGroup.DELETE("/:id", orgTutorController.DeleteTutorOrganization)                   // Remove tutor from organization
+			tutorGroup.POST("/:id/restore", adminOnly, orgTutorController.RestoreTutorOrganization) // Restore soft-deleted tutor by ID
+			tutorGroup.GET("/:id", orgTutorController.GetTutorOrganizationByID)                     // Get tutor by ID
+			tutorGroup.GET("", adminForDeleted, orgTutorController.GetAllTutorsOrganization)        // Get all tutors
 		}
 	}
 }
@@ -13,19 +13,25 @@ func RegisterOrganizationBillingRoutes(
 	routes *gin.Engine,
 	billingController *controller.OrganizationBillingController,
 	tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository,
+	requireMFA gin.HandlerFunc,
 ) {
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Restoring and listing deleted records are for platform admins
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
 
 	billingGroup := routes.Group("/organization-billings")
 	{
 		// All organization billing routes are protected by authentication
-		billingGroup.Use(authMiddleware)
+		billingGroup.Use(authMiddleware, requireMFA)
 		{
-			billingGroup.POST("", billingController.CreateOrganizationBilling)       // Create new billing record
-			billingGroup.PUT("/:id", billingController.UpdateOrganizationBilling)    // Update billing by ID
-			billingGroup.DELETE("/:id", billingController.DeleteOrganizationBilling) // Soft delete billing by ID
-			billingGroup.GET("/:id", billingController.GetOrganizationBillingByID)   // Get billing by ID
-			billingGroup.GET("", billingController.GetAllOrganizationBillings)       // Get all billing records
+			billingGroup.POST("", billingController.CreateOrganizationBilling)                         // Create new billing record
+			billingGroup.PUT("/:id", billingController.UpdateOrganizationBilling)                      // Update billing by ID
+			billingGroup.DELETE("/:id", billingController.DeleteOrganizationBilling)                   // Soft delete billing by ID
+			billingGroup.POST("/:id/restore", adminOnly, billingController.RestoreOrganizationBilling) // Restore soft-deleted billing by ID
+			billingGroup.GET("/:id", billingController.GetOrganizationBillingByID)                     // Get billing by ID
+			billingGroup.GET("", adminForDeleted, billingController.GetAllOrganizationBillings)        // Get all billing records
 		}
 	}
 }
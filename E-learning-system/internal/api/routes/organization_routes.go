@@ -9,19 +9,40 @@ import (
 )
 
 // RegisterOrganizationRoutes registers organization-related endpoints
-func RegisterOrganizationRoutes(routes *gin.Engine, orgController *controller.OrganizationController, tokenRepo repository.TokenRepository,) {
+func RegisterOrganizationRoutes(routes *gin.Engine, orgController *controller.OrganizationController, tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository, requireVerified, requireMFA gin.HandlerFunc) {
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
 
 	orgGroup := routes.Group("/organizations")
 	{
 		// All organization routes are protected by authentication
-		orgGroup.Use(authMiddleware)
+		orgGroup.Use(authMiddleware, requireMFA)
 		{
-			orgGroup.POST("", orgController.CreateOrganization)       // Create new organization
+			orgGroup.POST("", requireVerified, orgController.CreateOrganization) // Create new organization
 			orgGroup.PUT("/:id", orgController.UpdateOrganization)    // Update organization by ID
-			orgGroup.DELETE("/:id", orgController.DeleteOrganization) // Soft delete organization by ID
-			orgGroup.GET("/:id", orgController.GetOrganizationByID)   // Get organization by ID
-			orgGroup.GET("", orgController.GetAllOrganizations)       // Get all organizations
+			orgGroup.DELETE("/:id", adminOnly, orgController.DeleteOrganization)        // Schedule organization for deletion
+			orgGroup.POST("/:id/restore", adminOnly, orgController.RestoreOrganization) // Cancel a scheduled deletion
+			orgGroup.GET("/:id", orgController.GetOrganizationByID)                    // Get organization by ID
+			orgGroup.GET("", adminForDeleted, orgController.GetAllOrganizations)        // Get all organizations
+
+			// Lifecycle
+			orgGroup.POST("/:id/status", adminOnly, orgController.ChangeOrganizationStatus)            // Move organization to a new status
+			orgGroup.GET("/:id/status-history", adminOnly, orgController.GetOrganizationStatusHistory) // List status transitions
+			orgGroup.GET("/:id/export", adminOnly, orgController.ExportOrganization)                   // Download organization data
+
+			// Member policy
+			orgGroup.GET("/:id/policy", adminOnly, orgController.GetOrganizationPolicy)    // Get member policy
+			orgGroup.PUT("/:id/policy", adminOnly, orgController.UpdateOrganizationPolicy) // Replace member policy
+
+			// Single sign-on; the provider vouches for members' emails, so only platform admins configure it
+			orgGroup.GET("/:id/sso", adminOnly, orgController.GetOrganizationSSO)       // Get identity provider
+			orgGroup.PUT("/:id/sso", adminOnly, orgController.UpdateOrganizationSSO)    // Create or replace identity provider
+			orgGroup.DELETE("/:id/sso", adminOnly, orgController.DeleteOrganizationSSO) // Remove identity provider
+			orgGroup.GET("/:id/saml", adminOnly, orgController.GetOrganizationSAML)       // Get SAML identity provider and SP details
+			orgGroup.PUT("/:id/saml", adminOnly, orgController.UpdateOrganizationSAML)    // Import IdP metadata and attribute mapping
+			orgGroup.DELETE("/:id/saml", adminOnly, orgController.DeleteOrganizationSAML) // Remove SAML identity provider
 		}
 	}
 }
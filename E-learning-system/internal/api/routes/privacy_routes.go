@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPrivacyRoutes registers the data export and erasure endpoints under /users
+func RegisterPrivacyRoutes(routes *gin.Engine, privacyController *controller.PrivacyController, tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository, requireMFA gin.HandlerFunc) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+
+	privacyGroup := routes.Group("/users")
+	{
+		privacyGroup.Use(authMiddleware, requireMFA)
+		{
+			// The signed-in user's own data
+			privacyGroup.POST("/me/exports", privacyController.RequestMyExport)      // Request an export of my data
+			privacyGroup.GET("/me/exports", privacyController.ListMyExports)         // List my data exports
+			privacyGroup.GET("/me/exports/:exportID", privacyController.GetMyExport) // Get a data export and its download link
+			privacyGroup.POST("/me/erase", privacyController.EraseMe)                // Erase my account
+
+			// Requests made on a user's behalf are reserved for platform admins
+			privacyGroup.POST("/:id/exports", adminOnly, privacyController.RequestUserExport)
+			privacyGroup.GET("/:id/exports/:exportID", adminOnly, privacyController.GetUserExport)
+			privacyGroup.POST("/:id/erase", adminOnly, privacyController.EraseUser)
+		}
+	}
+}
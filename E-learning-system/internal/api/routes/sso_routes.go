@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSSORoutes registers the OpenID Connect and SAML login endpoints. They are
+// public: the user is not signed in until the callback succeeds.
+func RegisterSSORoutes(router *gin.Engine, userController *controller.UserController) {
+	ssoGroup := router.Group("/sso")
+	{
+		ssoGroup.POST("/discover", userController.DiscoverSSO) // Organizations offering SSO for an email
+		ssoGroup.POST("/:id/start", userController.StartSSO)   // Identity provider URL of an organization
+		ssoGroup.POST("/callback", userController.CompleteSSO) // Exchange the provider's code for a session
+	}
+
+	samlGroup := router.Group("/saml")
+	{
+		samlGroup.GET("/:id/metadata", userController.GetSAMLMetadata)  // Service provider metadata for the IdP
+		samlGroup.POST("/:id/start", userController.StartSAML)          // Signed AuthnRequest URL of an organization
+		samlGroup.POST("/:id/acs", userController.ConsumeSAMLAssertion) // Assertion consumer, posted to by the IdP
+		samlGroup.POST("/callback", userController.CompleteSAML)        // Claim the accepted assertion for a session
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUserImportRoutes registers the bulk user import and member export endpoints
+func RegisterUserImportRoutes(routes *gin.Engine, userImportController *controller.UserImportController, tokenRepo repository.TokenRepository,
+	userRepo repository.UserRepository, requireMFA gin.HandlerFunc) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	// Imports create accounts and can add existing users of any organization,
+	// so only platform admins run them
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+
+	orgGroup := routes.Group("/organizations/:id")
+	{
+		orgGroup.Use(authMiddleware, requireMFA, adminOnly)
+		{
+			orgGroup.GET("/user-imports/template", userImportController.GetImportTemplate)        // Download the CSV template
+			orgGroup.POST("/user-imports", userImportController.StartImport)                      // Upload a CSV of users
+			orgGroup.GET("/user-imports", userImportController.ListImports)                       // List imports
+			orgGroup.GET("/user-imports/:importID", userImportController.GetImport)               // Get import progress
+			orgGroup.GET("/user-imports/:importID/errors", userImportController.ListImportErrors) // Rows that were not applied
+			orgGroup.GET("/members/export", userImportController.ExportMembers)                   // Download members as CSV or XLSX
+		}
+	}
+}
@@ -9,9 +9,12 @@ import (
 )
 
 // RegisterUserRoutes registers user-related routes
-func RegisterUserRoutes(router *gin.Engine, userController *controller.UserController, tokenRepo repository.TokenRepository) {
+func RegisterUserRoutes(router *gin.Engine, userController *controller.UserController, tokenRepo repository.TokenRepository, userRepo repository.UserRepository,
+	requireMFA gin.HandlerFunc) {
 	// Auth middleware
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminOnly := middleware.RequireRole(userRepo, "admin")
+	adminForDeleted := middleware.RequireRoleToIncludeDeleted(userRepo, "admin")
 
 	// Group all /users endpoints
 	userGroup := router.Group("/users")
@@ -19,16 +22,46 @@ func RegisterUserRoutes(router *gin.Engine, userController *controller.UserContr
 		// 🚪 Public Routes
 		userGroup.POST("", userController.RegisterUser)
 		userGroup.POST("/authenticate", userController.AuthenticateUser)
+		userGroup.POST("/authenticate/mfa", userController.VerifyMFALogin)
+		userGroup.POST("/authenticate/renew-password", userController.RenewExpiredPassword)
 		userGroup.POST("/forgot-password", userController.ForgotPassword)
 		userGroup.POST("/reset-password", userController.ResetPassword)
+		// The confirmation link may be opened in a browser that is not signed in
+		userGroup.POST("/email/confirm", userController.ConfirmEmailChange)
+		userGroup.POST("/verify-email", userController.VerifyEmail)
+		userGroup.POST("/verify-email/resend", userController.ResendVerification)
+		userGroup.POST("/unlock", userController.UnlockAccount)
 
 		// 🔒 Protected Routes (Require Auth)
 		userGroup.Use(authMiddleware)
 		{
-			userGroup.GET("", userController.ListUsers)
+			// The signed-in user's own account
+			userGroup.GET("/me", userController.GetMe)
+			userGroup.PATCH("/me", userController.UpdateMe)
+			userGroup.POST("/me/password", userController.ChangePassword)
+			userGroup.POST("/me/email", userController.ChangeEmail)
+
+			// Two-factor setup stays reachable for users who are required to enroll
+			userGroup.GET("/me/mfa", userController.GetMFA)
+			userGroup.POST("/me/mfa", userController.StartMFA)
+			userGroup.POST("/me/mfa/confirm", userController.ConfirmMFA)
+			userGroup.POST("/me/mfa/recovery-codes", userController.RegenerateRecoveryCodes)
+			userGroup.POST("/me/mfa/disable", userController.DisableMFA)
+
+			// Everything below needs MFA from users it is required for
+			userGroup.Use(requireMFA)
+
+			userGroup.GET("", adminForDeleted, userController.ListUsers)
 			userGroup.GET("/:id", userController.GetUserByID)
-			userGroup.PUT("/:id", userController.UpdateUser)
-			userGroup.DELETE("/:id", userController.DeleteUser)
+
+			// Changing other accounts is reserved for admins
+			userGroup.PUT("/:id", adminOnly, userController.UpdateUser)
+			userGroup.PUT("/:id/role", adminOnly, userController.ChangeUserRole)
+			userGroup.DELETE("/:id", adminOnly, userController.DeleteUser)
+			userGroup.POST("/:id/restore", adminOnly, userController.RestoreUser)
+			userGroup.DELETE("/:id/mfa", adminOnly, userController.ResetMFA)
+			userGroup.GET("/mfa/required-roles", adminOnly, userController.GetMFARequiredRoles)
+			userGroup.PUT("/mfa/required-roles", adminOnly, userController.SetMFARequiredRoles)
 		}
 	}
 }
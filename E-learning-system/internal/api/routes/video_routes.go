@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"e-learning-system/internal/api/controller"
+	"e-learning-system/internal/api/middleware"
+	"e-learning-system/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterVideoRoutes registers resumable video upload (tus) and playback endpoints
+func RegisterVideoRoutes(
+	routes *gin.Engine,
+	videoController *controller.VideoController,
+	tokenRepo repository.TokenRepository,
+) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	// Public: access is granted by the signed playback token
+	routes.GET("/lessons/:id/stream/*file", videoController.StreamFile)
+
+	routes.POST("/lessons/:id/video/uploads", authMiddleware, videoController.CreateUpload) // Start upload
+	routes.GET("/lessons/:id/playback", authMiddleware, videoController.GetPlayback)        // Signed playback URL
+
+	uploadGroup := routes.Group("/video-uploads")
+	{
+		uploadGroup.OPTIONS("", videoController.UploadOptions)
+		uploadGroup.Use(authMiddleware)
+		{
+			uploadGroup.HEAD("/:id", videoController.GetUploadOffset) // Resume offset
+			uploadGroup.PATCH("/:id", videoController.UploadChunk)    // Append chunk
+			uploadGroup.DELETE("/:id", videoController.DeleteUpload)  // Cancel upload
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package audit carries who is making a request down to the services that
+// record audit events, and computes the field changes those events hold.
+package audit
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+	"encoding/json"
+	"reflect"
+
+	"github.com/gofrs/uuid"
+)
+
+// Source describes who made a change and from where
+type Source struct {
+	ActorID   *uuid.UUID // signed-in user, or the creator of the API key used
+	APIKeyID  *uuid.UUID
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type contextKey struct{}
+
+// WithSource returns a copy of ctx carrying src
+func WithSource(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, contextKey{}, src)
+}
+
+// SourceFromContext returns the source stored in ctx. Background work such
+// as scheduled jobs has none and gets the zero Source.
+func SourceFromContext(ctx context.Context) Source {
+	src, _ := ctx.Value(contextKey{}).(Source)
+	return src
+}
+
+// bookkeeping fields change on every write and say nothing about what an
+// administrator changed. Models without JSON tags use the Go field names.
+var bookkeeping = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"CreatedAt":  true,
+	"UpdatedAt":  true,
+}
+
+// Diff returns the fields that differ between before and after as they are
+// serialized to JSON, so fields hidden from the API, such as secrets, never
+// appear. before is nil for creations and after for deletions.
+func Diff(before, after any) map[string]model.AuditChange {
+	from, to := fields(before), fields(after)
+
+	changes := make(map[string]model.AuditChange)
+	for name, value := range to {
+		if bookkeeping[name] {
+			continue
+		}
+		if old, ok := from[name]; !ok || !reflect.DeepEqual(old, value) {
+			changes[name] = model.AuditChange{From: old, To: value}
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok && !bookkeeping[name] {
+			changes[name] = model.AuditChange{From: value}
+		}
+	}
+	return changes
+}
+
+// fields decodes the JSON object form of v; nil and non-objects have none
+func fields(v any) map[string]any {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
@@ -1,10 +1,20 @@
 package config
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/mail"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -12,112 +22,869 @@ import (
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
-// App-wide structured config
+// Config is the whole application configuration. It is built in layers:
+// Defaults, then the YAML file, then environment variables, then flags.
+type Config struct {
+	App       AppConfig       `yaml:"app"`
+	Server    ServerConfig    `yaml:"server"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Video     VideoConfig     `yaml:"video"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Billing   BillingConfig   `yaml:"billing"`
+	Mail      MailConfig      `yaml:"mail"`
+	Retention RetentionConfig `yaml:"retention"`
+	Privacy   PrivacyConfig   `yaml:"privacy"`
+	Import    ImportConfig    `yaml:"import"`
+	ExportDir string          `yaml:"export_dir"`
+}
+
+// AppConfig identifies the running service
 type AppConfig struct {
-	App struct {
-		Name string `yaml:"name"`
-		Env  string `yaml:"env"`
-		Port string
-	} `yaml:"app"`
-
-	DatabaseURL string
-	JWTSecret   string
-
-	Redis struct {
-		Address string `yaml:"address"`
-		DB      int    `yaml:"db"`
-	} `yaml:"redis"`
-}
-
-// Env + DB + JWT secrets config
-type DBConfig struct {
-	Port             string
-	DBHost           string
-	DBPort           string
-	DBUser           string
-	DBPassword       string
-	DBName           string
-	DBSSLMode        string
-	DatabaseURL      string
-	JWTSecret        string
-	JWTRefreshSecret string
-	RedisURL         string
-	WaafiMerchantUID string
-	Env              string
-}
-
-// LoadEnv loads from .env file into OS env vars
-func LoadEnv() {
-	err := godotenv.Load("/app/.env") // absolute path for Docker
-	if err != nil {
-		log.Println("No .env file found; falling back to system env vars")
+	Name string `yaml:"name"`
+	Env  string `yaml:"env"` // development, test, staging or production
+	Port string `yaml:"port"`
+
+	// FrontendURL is the base of links sent in emails, e.g. confirmation links
+	FrontendURL string `yaml:"frontend_url"`
+}
+
+// IsProduction reports whether the stricter production checks apply
+func (a AppConfig) IsProduction() bool {
+	return a.Env == EnvProduction
+}
+
+// ServerConfig holds the HTTP server limits and optional TLS certificate
+type ServerConfig struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	TLSCertFile       string        `yaml:"tls_cert_file"`
+	TLSKeyFile        string        `yaml:"tls_key_file"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// DatabaseConfig holds the PostgreSQL connection and pool settings.
+// URL takes precedence over the individual connection fields.
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// DSN returns the connection string for lib/pq
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
 	}
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode,
+	)
 }
 
-// getEnv gets env var or fallback
-func getEnv(key, fallback string) string {
-	if val, ok := os.LookupEnv(key); ok && val != "" {
-		return val
+// AuthConfig holds the token signing secrets and sign-in policy
+type AuthConfig struct {
+	JWTSecret        string `yaml:"jwt_secret"`
+	JWTRefreshSecret string `yaml:"jwt_refresh_secret"`
+
+	// EmailVerification decides what unverified users may do: off, limited
+	// (sign in and read, but not enroll or create content) or required (no sign-in)
+	EmailVerification string        `yaml:"email_verification"`
+	VerificationKey   string        `yaml:"verification_key"` // signs verification and unlock links
+	VerificationTTL   time.Duration `yaml:"verification_ttl"`
+
+	// EncryptionKey encrypts secrets stored in the database, such as TOTP
+	// seeds and identity provider client secrets
+	EncryptionKey string `yaml:"encryption_key"`
+
+	Lockout       LockoutConfig       `yaml:"lockout"`
+	MFA           MFAConfig           `yaml:"mfa"`
+	SSO           SSOConfig           `yaml:"sso"`
+	Password      PasswordConfig      `yaml:"password"`
+	PasswordReset PasswordResetConfig `yaml:"password_reset"`
+}
+
+// LockoutConfig throttles failed sign-ins per account and per client IP.
+// Counters live in Redis when redis.url is set, otherwise in memory.
+type LockoutConfig struct {
+	Window       time.Duration `yaml:"window"`        // failures older than this are forgotten
+	AccountLimit int           `yaml:"account_limit"` // failures before the account is locked
+	IPLimit      int           `yaml:"ip_limit"`      // failures before the client IP is blocked
+	Duration     time.Duration `yaml:"duration"`      // how long a lockout lasts
+	DelayAfter   int           `yaml:"delay_after"`   // failures before responses slow down
+	BaseDelay    time.Duration `yaml:"base_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+}
+
+// MFAConfig configures two-factor authentication with authenticator apps
+type MFAConfig struct {
+	Issuer       string        `yaml:"issuer"`        // account label shown in authenticator apps
+	ChallengeTTL time.Duration `yaml:"challenge_ttl"` // time allowed for the second step of a login
+}
+
+// PasswordConfig is the policy new passwords must satisfy. Expiry is set per
+// organization in its member policy.
+type PasswordConfig struct {
+	MinLength     int  `yaml:"min_length"`
+	MaxLength     int  `yaml:"max_length"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	RejectCommon  bool `yaml:"reject_common"` // refuse passwords on the built-in denylist
+	History       int  `yaml:"history"`       // previous passwords that may not be reused
+}
+
+// MaxPasswordHistory is the most previous passwords that are kept
+const MaxPasswordHistory = 24
+
+// PasswordResetConfig limits password reset links. Requests over a limit
+// are refused per client IP; per email address they are dropped silently so
+// responses do not reveal which addresses have accounts.
+type PasswordResetConfig struct {
+	TTL        time.Duration `yaml:"ttl"`         // lifetime of a reset link
+	Window     time.Duration `yaml:"window"`      // period the limits apply to
+	EmailLimit int           `yaml:"email_limit"` // links sent per email address
+	IPLimit    int           `yaml:"ip_limit"`    // requests accepted per client IP
+}
+
+// SSOConfig configures single sign-on through the identity providers of organizations
+type SSOConfig struct {
+	// RedirectURL is the frontend page identity providers send users back
+	// to; it must be registered with every provider. Defaults to
+	// app.frontend_url + /sso/callback.
+	RedirectURL string        `yaml:"redirect_url"`
+	StateTTL    time.Duration `yaml:"state_ttl"` // time allowed to sign in at the provider
+
+	// PublicURL is the base URL browsers and SAML identity providers reach
+	// this API at; SAML metadata and assertion consumer URLs are derived from it
+	PublicURL string `yaml:"public_url"`
+	// SAMLCallbackURL is the frontend page the assertion consumer sends users
+	// on to with a one-time login code. Defaults to
+	// app.frontend_url + /sso/saml/callback.
+	SAMLCallbackURL string `yaml:"saml_callback_url"`
+}
+
+// RedisConfig points at the Redis instance
+type RedisConfig struct {
+	URL string `yaml:"url"`
+}
+
+// StorageConfig selects and configures the object storage driver
+type StorageConfig struct {
+	Driver      string `yaml:"driver"` // local or s3
+	Dir         string `yaml:"dir"`
+	PublicURL   string `yaml:"public_url"`
+	SigningKey  string `yaml:"signing_key"`
+	S3Endpoint  string `yaml:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region"`
+	S3Bucket    string `yaml:"s3_bucket"`
+	S3AccessKey string `yaml:"s3_access_key"`
+	S3SecretKey string `yaml:"s3_secret_key"`
+	S3PathStyle bool   `yaml:"s3_path_style"`
+}
+
+// RetentionConfig sets how long deleted records are kept before they are purged
+type RetentionConfig struct {
+	SoftDeletePeriod time.Duration `yaml:"soft_delete_period"` // soft-deleted records can be restored until then
+}
+
+// PrivacyConfig holds the settings of user data exports
+type PrivacyConfig struct {
+	ExportTTL time.Duration `yaml:"export_ttl"` // how long a finished export can be downloaded
+}
+
+// ImportConfig holds the limits of bulk user imports
+type ImportConfig struct {
+	MaxRows       int           `yaml:"max_rows"`       // users per uploaded file
+	InvitationTTL time.Duration `yaml:"invitation_ttl"` // lifetime of the link invited users set their password with
+}
+
+// VideoConfig holds lesson video upload settings
+type VideoConfig struct {
+	UploadDir string `yaml:"upload_dir"`
+}
+
+// LogConfig selects the log level and output format
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // json or text
+}
+
+// TracingConfig selects where spans are exported
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter"`      // none, stdout or otlp
+	OTLPEndpoint string  `yaml:"otlp_endpoint"` // e.g. localhost:4318
+	OTLPInsecure bool    `yaml:"otlp_insecure"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+// MetricsConfig protects the /metrics endpoint
+type MetricsConfig struct {
+	Token string `yaml:"token"` // bearer token; empty leaves /metrics open
+}
+
+// BillingConfig holds the payment provider settings
+type BillingConfig struct {
+	WaafiMerchantUID string `yaml:"waafi_merchant_uid"`
+}
+
+// MailConfig selects how transactional email is delivered
+type MailConfig struct {
+	Driver       string `yaml:"driver"` // log or smtp
+	From         string `yaml:"from"`
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+}
+
+// Email verification modes recognised in Auth.EmailVerification
+const (
+	VerificationOff      = "off"
+	VerificationLimited  = "limited"
+	VerificationRequired = "required"
+)
+
+// Environments recognised in App.Env
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// DefaultConfigFile is read when neither -config nor CONFIG_FILE is given.
+// It may be missing, in which case defaults and env vars are used.
+const DefaultConfigFile = "config/config.yaml"
+
+// minSecretLength is the shortest secret accepted in production
+const minSecretLength = 32
+
+// knownDefaultSecrets are placeholder values from old defaults and sample files
+var knownDefaultSecrets = []string{
+	"default_jwt_secret",
+	"default_jwt_refresh_secret",
+	"your_super_secret_key",
+	"your_super_refresh_secret_key",
+	"secret",
+	"changeme",
+}
+
+// Defaults returns the configuration used before any source is applied.
+// Secrets have no defaults.
+func Defaults() *Config {
+	cfg := &Config{}
+
+	cfg.App = AppConfig{
+		Name:        "e-learning-system",
+		Env:         EnvDevelopment,
+		Port:        "8080",
+		FrontendURL: "http://localhost:3000",
 	}
-	return fallback
+
+	cfg.Server = ServerConfig{
+		ReadTimeout:       60 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+	}
+
+	cfg.CORS = CORSConfig{
+		AllowedOrigins:   []string{"http://localhost:3000"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+
+	cfg.Database = DatabaseConfig{
+		Host:            "localhost",
+		Port:            "5432",
+		User:            "elearning_user",
+		Password:        "elearning_password",
+		Name:            "elearning",
+		SSLMode:         "disable",
+		MaxOpenConns:    25,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+
+	cfg.Auth = AuthConfig{
+		EmailVerification: VerificationLimited,
+		VerificationTTL:   48 * time.Hour,
+		Lockout: LockoutConfig{
+			Window:       15 * time.Minute,
+			AccountLimit: 5,
+			IPLimit:      100,
+			Duration:     15 * time.Minute,
+			DelayAfter:   2,
+			BaseDelay:    500 * time.Millisecond,
+			MaxDelay:     8 * time.Second,
+		},
+		MFA: MFAConfig{
+			Issuer:       "E-Learning",
+			ChallengeTTL: 5 * time.Minute,
+		},
+		SSO: SSOConfig{
+			StateTTL:  10 * time.Minute,
+			PublicURL: "http://localhost:8080",
+		},
+		Password: PasswordConfig{
+			MinLength:    10,
+			MaxLength:    128,
+			RejectCommon: true,
+			History:      5,
+		},
+		PasswordReset: PasswordResetConfig{
+			TTL:        30 * time.Minute,
+			Window:     time.Hour,
+			EmailLimit: 3,
+			IPLimit:    20,
+		},
+	}
+
+	cfg.Redis = RedisConfig{URL: "redis://localhost:6379"}
+
+	cfg.Storage = StorageConfig{
+		Driver:      "local",
+		Dir:         "uploads",
+		PublicURL:   "http://localhost:8080/files",
+		S3Region:    "us-east-1",
+		S3PathStyle: true,
+	}
+
+	cfg.Video = VideoConfig{UploadDir: "uploads/.video-uploads"}
+	cfg.Log = LogConfig{Level: "info", Format: "json"}
+	cfg.Tracing = TracingConfig{Exporter: "none", OTLPInsecure: true, SampleRatio: 1}
+	cfg.Mail = MailConfig{Driver: "log", From: "no-reply@localhost", SMTPPort: "587"}
+	cfg.Retention = RetentionConfig{SoftDeletePeriod: 30 * 24 * time.Hour}
+	cfg.Privacy = PrivacyConfig{ExportTTL: 7 * 24 * time.Hour}
+	cfg.Import = ImportConfig{MaxRows: 5000, InvitationTTL: 7 * 24 * time.Hour}
+	cfg.ExportDir = "exports"
+
+	return cfg
 }
 
-// LoadAppConfig loads YAML + overrides from env
-func LoadAppConfig() (*AppConfig, error) {
-	file, err := os.ReadFile("config/config.yaml")
-	if err != nil {
+// Load builds the configuration from defaults, the YAML file, the .env file,
+// environment variables and the command-line args (without the program name),
+// in increasing order of precedence, and validates the result.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to the YAML config file (env CONFIG_FILE)")
+	envFile := fs.String("env-file", ".env", "path to a .env file; a missing file is ignored")
+	port := fs.String("port", "", "HTTP port (env PORT)")
+	env := fs.String("env", "", "development, test, staging or production (env ENV)")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error (env LOG_LEVEL)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// .env only fills variables that are not already set
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %v", *envFile, err)
+	}
+
+	cfg := Defaults()
+
+	path, required := *configFile, true
+	if path == "" {
+		path = getEnv("CONFIG_FILE", "")
+	}
+	if path == "" {
+		path, required = DefaultConfigFile, false
+	}
+	if err := cfg.loadFile(path, required); err != nil {
 		return nil, err
 	}
 
-	var cfg AppConfig
-	if err := yaml.Unmarshal(file, &cfg); err != nil {
+	if err := cfg.applyEnv(); err != nil {
 		return nil, err
 	}
 
-	// override with env vars
-	cfg.App.Port = getEnv("PORT", cfg.App.Port)
-	cfg.DatabaseURL = getEnv("DATABASE_URL", cfg.DatabaseURL)
-	cfg.JWTSecret = getEnv("JWT_SECRET", cfg.JWTSecret)
+	// Flags win over everything else
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.App.Port = *port
+		case "env":
+			cfg.App.Env = *env
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		}
+	})
 
-	return &cfg, nil
+	if err := cfg.fillSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-// LoadDBConfig returns DBConfig using only env vars
-func LoadDBConfig() *DBConfig {
-	return &DBConfig{
-		Port:             getEnv("PORT", "8080"),
-		DBHost:           getEnv("DB_HOST", "localhost"),
-		DBPort:           getEnv("DB_PORT", "5432"),
-		DBUser:           getEnv("DB_USER", "elearning_user"),
-		DBPassword:       getEnv("DB_PASSWORD", "elearning_password"),
-		DBName:           getEnv("DB_NAME", "elearning"),
-		DBSSLMode:        getEnv("DB_SSLMODE", "disable"),
-		DatabaseURL:      getEnv("DATABASE_URL", ""),
-		JWTSecret:        getEnv("JWT_SECRET", "default_jwt_secret"),
-		JWTRefreshSecret: getEnv("JWT_REFRESH_SECRET", "default_jwt_refresh_secret"),
-		RedisURL:         getEnv("REDIS_URL", "redis://localhost:6379"),
-		Env:              getEnv("ENV", "development"),
+// loadFile overlays the YAML file at path. A missing file is only an
+// error when it was asked for explicitly.
+func (c *Config) loadFile(path string, required bool) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	if err := yaml.Unmarshal(file, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
 	}
+	return nil
 }
 
-// InitDB connects to PostgreSQL using database/sql
-func InitDB(cfg *DBConfig) *sql.DB {
-	var dsn string
+// envBinding ties an environment variable to the field it overrides
+type envBinding struct {
+	key    string
+	target any // *string, *int, *bool, *float64, *time.Duration or *[]string
+}
 
-	if cfg.DatabaseURL != "" {
-		dsn = cfg.DatabaseURL
-	} else {
-		dsn = fmt.Sprintf(
-			"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-			cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode,
-		)
+func (c *Config) envBindings() []envBinding {
+	return []envBinding{
+		{"APP_NAME", &c.App.Name},
+		{"ENV", &c.App.Env},
+		{"PORT", &c.App.Port},
+		{"FRONTEND_URL", &c.App.FrontendURL},
+
+		{"HTTP_READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout},
+		{"HTTP_MAX_HEADER_BYTES", &c.Server.MaxHeaderBytes},
+		{"TLS_CERT_FILE", &c.Server.TLSCertFile},
+		{"TLS_KEY_FILE", &c.Server.TLSKeyFile},
+
+		{"CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins},
+		{"CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials},
+		{"CORS_MAX_AGE", &c.CORS.MaxAge},
+
+		{"DATABASE_URL", &c.Database.URL},
+		{"DB_HOST", &c.Database.Host},
+		{"DB_PORT", &c.Database.Port},
+		{"DB_USER", &c.Database.User},
+		{"DB_PASSWORD", &c.Database.Password},
+		{"DB_NAME", &c.Database.Name},
+		{"DB_SSLMODE", &c.Database.SSLMode},
+		{"DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns},
+		{"DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime},
+
+		{"JWT_SECRET", &c.Auth.JWTSecret},
+		{"JWT_REFRESH_SECRET", &c.Auth.JWTRefreshSecret},
+		{"EMAIL_VERIFICATION", &c.Auth.EmailVerification},
+		{"EMAIL_VERIFICATION_KEY", &c.Auth.VerificationKey},
+		{"EMAIL_VERIFICATION_TTL", &c.Auth.VerificationTTL},
+		{"LOGIN_FAILURE_WINDOW", &c.Auth.Lockout.Window},
+		{"LOGIN_ACCOUNT_LIMIT", &c.Auth.Lockout.AccountLimit},
+		{"LOGIN_IP_LIMIT", &c.Auth.Lockout.IPLimit},
+		{"LOGIN_LOCKOUT_DURATION", &c.Auth.Lockout.Duration},
+		{"LOGIN_DELAY_AFTER", &c.Auth.Lockout.DelayAfter},
+		{"LOGIN_BASE_DELAY", &c.Auth.Lockout.BaseDelay},
+		{"LOGIN_MAX_DELAY", &c.Auth.Lockout.MaxDelay},
+		{"MFA_ISSUER", &c.Auth.MFA.Issuer},
+		{"MFA_CHALLENGE_TTL", &c.Auth.MFA.ChallengeTTL},
+		{"DATA_ENCRYPTION_KEY", &c.Auth.EncryptionKey},
+		{"SSO_REDIRECT_URL", &c.Auth.SSO.RedirectURL},
+		{"SSO_STATE_TTL", &c.Auth.SSO.StateTTL},
+		{"SSO_PUBLIC_URL", &c.Auth.SSO.PublicURL},
+		{"SAML_CALLBACK_URL", &c.Auth.SSO.SAMLCallbackURL},
+		{"PASSWORD_MIN_LENGTH", &c.Auth.Password.MinLength},
+		{"PASSWORD_MAX_LENGTH", &c.Auth.Password.MaxLength},
+		{"PASSWORD_REQUIRE_UPPER", &c.Auth.Password.RequireUpper},
+		{"PASSWORD_REQUIRE_LOWER", &c.Auth.Password.RequireLower},
+		{"PASSWORD_REQUIRE_DIGIT", &c.Auth.Password.RequireDigit},
+		{"PASSWORD_REQUIRE_SYMBOL", &c.Auth.Password.RequireSymbol},
+		{"PASSWORD_REJECT_COMMON", &c.Auth.Password.RejectCommon},
+		{"PASSWORD_HISTORY", &c.Auth.Password.History},
+		{"PASSWORD_RESET_TTL", &c.Auth.PasswordReset.TTL},
+		{"PASSWORD_RESET_WINDOW", &c.Auth.PasswordReset.Window},
+		{"PASSWORD_RESET_EMAIL_LIMIT", &c.Auth.PasswordReset.EmailLimit},
+		{"PASSWORD_RESET_IP_LIMIT", &c.Auth.PasswordReset.IPLimit},
+
+		{"REDIS_URL", &c.Redis.URL},
+
+		{"STORAGE_DRIVER", &c.Storage.Driver},
+		{"STORAGE_DIR", &c.Storage.Dir},
+		{"STORAGE_PUBLIC_URL", &c.Storage.PublicURL},
+		{"STORAGE_SIGNING_KEY", &c.Storage.SigningKey},
+		{"S3_ENDPOINT", &c.Storage.S3Endpoint},
+		{"S3_REGION", &c.Storage.S3Region},
+		{"S3_BUCKET", &c.Storage.S3Bucket},
+		{"S3_ACCESS_KEY", &c.Storage.S3AccessKey},
+		{"S3_SECRET_KEY", &c.Storage.S3SecretKey},
+		{"S3_PATH_STYLE", &c.Storage.S3PathStyle},
+
+		{"VIDEO_UPLOAD_DIR", &c.Video.UploadDir},
+
+		{"LOG_LEVEL", &c.Log.Level},
+		{"LOG_FORMAT", &c.Log.Format},
+
+		{"TRACE_EXPORTER", &c.Tracing.Exporter},
+		{"OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint},
+		{"OTEL_EXPORTER_OTLP_INSECURE", &c.Tracing.OTLPInsecure},
+		{"TRACE_SAMPLE_RATIO", &c.Tracing.SampleRatio},
+
+		{"METRICS_TOKEN", &c.Metrics.Token},
+		{"WAAFI_MERCHANT_UID", &c.Billing.WaafiMerchantUID},
+
+		{"MAIL_DRIVER", &c.Mail.Driver},
+		{"MAIL_FROM", &c.Mail.From},
+		{"SMTP_HOST", &c.Mail.SMTPHost},
+		{"SMTP_PORT", &c.Mail.SMTPPort},
+		{"SMTP_USERNAME", &c.Mail.SMTPUsername},
+		{"SMTP_PASSWORD", &c.Mail.SMTPPassword},
+
+		{"RETENTION_SOFT_DELETE_PERIOD", &c.Retention.SoftDeletePeriod},
+
+		{"PRIVACY_EXPORT_TTL", &c.Privacy.ExportTTL},
+
+		{"IMPORT_MAX_ROWS", &c.Import.MaxRows},
+		{"IMPORT_INVITATION_TTL", &c.Import.InvitationTTL},
+
+		{"EXPORT_DIR", &c.ExportDir},
+	}
+}
+
+// applyEnv overrides fields with the environment variables that are set
+func (c *Config) applyEnv() error {
+	for _, b := range c.envBindings() {
+		val := getEnv(b.key, "")
+		if val == "" {
+			continue
+		}
+
+		var err error
+		switch t := b.target.(type) {
+		case *string:
+			*t = val
+		case *int:
+			*t, err = strconv.Atoi(val)
+		case *bool:
+			*t, err = strconv.ParseBool(val)
+		case *float64:
+			*t, err = strconv.ParseFloat(val, 64)
+		case *time.Duration:
+			*t, err = time.ParseDuration(val)
+		case *[]string:
+			*t = splitList(val)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", b.key, err)
+		}
+	}
+	return nil
+}
+
+// fillSecrets generates throwaway secrets outside production, so local setups
+// work without configuration while nothing insecure is ever baked in.
+func (c *Config) fillSecrets() error {
+	if c.App.IsProduction() {
+		return nil
+	}
+
+	secrets := []struct {
+		name  string
+		value *string
+	}{
+		{"JWT_SECRET", &c.Auth.JWTSecret},
+		{"JWT_REFRESH_SECRET", &c.Auth.JWTRefreshSecret},
+		{"STORAGE_SIGNING_KEY", &c.Storage.SigningKey},
+		{"EMAIL_VERIFICATION_KEY", &c.Auth.VerificationKey},
+		{"DATA_ENCRYPTION_KEY", &c.Auth.EncryptionKey},
+	}
+	for _, s := range secrets {
+		if *s.value != "" {
+			continue
+		}
+		secret, err := randomSecret()
+		if err != nil {
+			return err
+		}
+		*s.value = secret
+		slog.Warn("Secret not configured, using a random one that will not survive a restart", "setting", s.name)
+	}
+	return nil
+}
+
+// Validate checks every section and returns all problems at once.
+// In production it also refuses missing, short or placeholder secrets.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.App.Env {
+	case EnvDevelopment, EnvTest, EnvStaging, EnvProduction:
+	default:
+		fail("app.env must be one of development, test, staging, production, got %q", c.App.Env)
+	}
+	if p, err := strconv.Atoi(c.App.Port); err != nil || p < 1 || p > 65535 {
+		fail("app.port must be a TCP port, got %q", c.App.Port)
+	}
+	if u, err := url.Parse(c.App.FrontendURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("app.frontend_url must be an absolute URL, got %q", c.App.FrontendURL)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			fail("%s must be positive", t.name)
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		fail("server.max_header_bytes must be positive")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		fail("server TLS needs both tls_cert_file and tls_key_file")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				fail("cors.allowed_origins cannot be * while allow_credentials is on")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			fail("cors.allowed_origins entry %q must be scheme://host[:port]", origin)
+		}
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		fail("database pool sizes cannot be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		fail("database.max_idle_conns cannot exceed max_open_conns")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3Bucket == "" || c.Storage.S3AccessKey == "" || c.Storage.S3SecretKey == "" {
+			fail("s3 storage needs s3_bucket, s3_access_key and s3_secret_key")
+		}
+	default:
+		fail("storage.driver must be local or s3, got %q", c.Storage.Driver)
+	}
+
+	switch c.Auth.EmailVerification {
+	case VerificationOff, VerificationLimited, VerificationRequired:
+	default:
+		fail("auth.email_verification must be off, limited or required, got %q", c.Auth.EmailVerification)
+	}
+	if c.Auth.VerificationTTL <= 0 {
+		fail("auth.verification_ttl must be positive")
+	}
+	if l := c.Auth.Lockout; l.Window <= 0 || l.Duration <= 0 || l.AccountLimit < 1 || l.IPLimit < 1 {
+		fail("auth.lockout window, duration, account_limit and ip_limit must be positive")
+	}
+	if l := c.Auth.Lockout; l.DelayAfter < 0 || l.BaseDelay < 0 || l.MaxDelay < l.BaseDelay {
+		fail("auth.lockout delays cannot be negative and max_delay must be at least base_delay")
+	}
+	if c.Auth.MFA.Issuer == "" {
+		fail("auth.mfa.issuer is required")
+	}
+	if c.Auth.MFA.ChallengeTTL <= 0 {
+		fail("auth.mfa.challenge_ttl must be positive")
+	}
+	if c.Auth.SSO.StateTTL <= 0 {
+		fail("auth.sso.state_ttl must be positive")
+	}
+	if c.Auth.SSO.RedirectURL != "" {
+		if u, err := url.Parse(c.Auth.SSO.RedirectURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("auth.sso.redirect_url must be an absolute URL, got %q", c.Auth.SSO.RedirectURL)
+		}
+	}
+	if u, err := url.Parse(c.Auth.SSO.PublicURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("auth.sso.public_url must be an absolute URL, got %q", c.Auth.SSO.PublicURL)
+	}
+	if c.Auth.SSO.SAMLCallbackURL != "" {
+		if u, err := url.Parse(c.Auth.SSO.SAMLCallbackURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("auth.sso.saml_callback_url must be an absolute URL, got %q", c.Auth.SSO.SAMLCallbackURL)
+		}
+	}
+	if p := c.Auth.Password; p.MinLength < 8 || p.MaxLength < p.MinLength || p.MaxLength > 1024 {
+		fail("auth.password.min_length must be at least 8 and max_length between min_length and 1024")
+	}
+	if p := c.Auth.Password; p.History < 0 || p.History > MaxPasswordHistory {
+		fail("auth.password.history must be between 0 and %d", MaxPasswordHistory)
+	}
+	if r := c.Auth.PasswordReset; r.TTL <= 0 || r.Window <= 0 || r.EmailLimit < 1 || r.IPLimit < 1 {
+		fail("auth.password_reset ttl, window, email_limit and ip_limit must be positive")
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			fail("smtp mail needs smtp_host")
+		}
+	default:
+		fail("mail.driver must be log or smtp, got %q", c.Mail.Driver)
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		fail("mail.from must be an email address, got %q", c.Mail.From)
+	}
+
+	if c.Retention.SoftDeletePeriod <= 0 {
+		fail("retention.soft_delete_period must be positive")
+	}
+	if c.Privacy.ExportTTL <= 0 {
+		fail("privacy.export_ttl must be positive")
+	}
+	if c.Import.MaxRows < 1 {
+		fail("import.max_rows must be at least 1")
+	}
+	if c.Import.InvitationTTL <= 0 {
+		fail("import.invitation_ttl must be positive")
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		fail("log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		fail("log.format must be json or text, got %q", c.Log.Format)
+	}
+
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "", "none", "stdout", "otlp":
+	default:
+		fail("tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio must be between 0 and 1")
 	}
 
-	db, err := sql.Open("postgres", dsn)
+	if c.App.IsProduction() {
+		secrets := []struct {
+			name, value string
+		}{
+			{"JWT_SECRET", c.Auth.JWTSecret},
+			{"JWT_REFRESH_SECRET", c.Auth.JWTRefreshSecret},
+			{"STORAGE_SIGNING_KEY", c.Storage.SigningKey},
+			{"EMAIL_VERIFICATION_KEY", c.Auth.VerificationKey},
+			{"DATA_ENCRYPTION_KEY", c.Auth.EncryptionKey},
+		}
+		for _, s := range secrets {
+			if err := checkSecret(s.value); err != nil {
+				fail("%s %v", s.name, err)
+			}
+		}
+		if c.Auth.JWTSecret != "" && c.Auth.JWTSecret == c.Auth.JWTRefreshSecret {
+			fail("JWT_SECRET and JWT_REFRESH_SECRET must differ")
+		}
+		if c.Database.URL == "" && c.Database.Password == Defaults().Database.Password {
+			fail("DB_PASSWORD must be changed from the development default")
+		}
+		if c.Mail.Driver != "smtp" {
+			fail("mail.driver must be smtp in production, otherwise emails are only logged")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkSecret rejects empty, short and placeholder secrets
+func checkSecret(value string) error {
+	if value == "" {
+		return errors.New("is required in production")
+	}
+	for _, known := range knownDefaultSecrets {
+		if strings.EqualFold(value, known) {
+			return errors.New("is set to a well-known placeholder value")
+		}
+	}
+	if len(value) < minSecretLength {
+		return fmt.Errorf("must be at least %d characters in production", minSecretLength)
+	}
+	return nil
+}
+
+// randomSecret returns 32 random bytes, hex encoded
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// splitList parses a comma-separated list, dropping empty entries
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnv gets env var or fallback
+func getEnv(key, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		return val
+	}
+	return fallback
+}
+
+// InitDB connects to PostgreSQL using database/sql and applies the pool settings
+func InitDB(cfg DatabaseConfig) *sql.DB {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		log.Fatalf("Failed to open database connection: %v", err)
 	}
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
 	if err := db.Ping(); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
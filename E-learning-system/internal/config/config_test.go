@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the defaults with secrets filled in, which must pass validation
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := Defaults()
+	if err := cfg.fillSecrets(); err != nil {
+		t.Fatalf("fillSecrets: %v", err)
+	}
+	return cfg
+}
+
+// productionConfig returns a configuration that passes the production checks
+func productionConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg := Defaults()
+	cfg.App.Env = EnvProduction
+	cfg.Auth.JWTSecret = strings.Repeat("a", minSecretLength)
+	cfg.Auth.JWTRefreshSecret = strings.Repeat("b", minSecretLength)
+	cfg.Storage.SigningKey = strings.Repeat("c", minSecretLength)
+	cfg.Auth.VerificationKey = strings.Repeat("d", minSecretLength)
+	cfg.Auth.EncryptionKey = strings.Repeat("e", minSecretLength)
+	cfg.Database.Password = "a production password"
+	cfg.Mail.Driver = "smtp"
+	cfg.Mail.SMTPHost = "smtp.example.com"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(t *testing.T) *Config
+		edit    func(c *Config)
+		wantErr string // empty when valid
+	}{
+		{"defaults", validConfig, func(c *Config) {}, ""},
+		{"production", productionConfig, func(c *Config) {}, ""},
+		{"unknown env", validConfig, func(c *Config) { c.App.Env = "prod" }, "app.env must be one of"},
+		{"port out of range", validConfig, func(c *Config) { c.App.Port = "70000" }, "app.port must be a TCP port"},
+		{"relative frontend URL", validConfig, func(c *Config) { c.App.FrontendURL = "/app" }, "app.frontend_url"},
+		{"zero timeout", validConfig, func(c *Config) { c.Server.WriteTimeout = 0 }, "server.write_timeout must be positive"},
+		{"TLS cert without key", validConfig, func(c *Config) { c.Server.TLSCertFile = "cert.pem" }, "tls_cert_file and tls_key_file"},
+		{"wildcard origin with credentials", validConfig, func(c *Config) { c.CORS.AllowedOrigins = []string{"*"} }, "cannot be * while allow_credentials"},
+		{"wildcard origin without credentials", validConfig, func(c *Config) {
+			c.CORS.AllowedOrigins, c.CORS.AllowCredentials = []string{"*"}, false
+		}, ""},
+		{"origin with a path", validConfig, func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/app"} }, "scheme://host[:port]"},
+		{"idle above open connections", validConfig, func(c *Config) { c.Database.MaxIdleConns = 50 }, "max_idle_conns cannot exceed"},
+		{"s3 without bucket", validConfig, func(c *Config) { c.Storage.Driver = "s3" }, "s3 storage needs"},
+		{"unknown storage", validConfig, func(c *Config) { c.Storage.Driver = "ftp" }, "storage.driver must be local or s3"},
+		{"unknown verification mode", validConfig, func(c *Config) { c.Auth.EmailVerification = "strict" }, "auth.email_verification"},
+		{"lockout max below base delay", validConfig, func(c *Config) { c.Auth.Lockout.MaxDelay = time.Millisecond }, "max_delay must be at least base_delay"},
+		{"short passwords", validConfig, func(c *Config) { c.Auth.Password.MinLength = 6 }, "auth.password.min_length"},
+		{"long password history", validConfig, func(c *Config) { c.Auth.Password.History = MaxPasswordHistory + 1 }, "auth.password.history"},
+		{"smtp without host", validConfig, func(c *Config) { c.Mail.Driver = "smtp" }, "smtp mail needs smtp_host"},
+		{"bad sender", validConfig, func(c *Config) { c.Mail.From = "nobody" }, "mail.from must be an email address"},
+		{"no import rows", validConfig, func(c *Config) { c.Import.MaxRows = 0 }, "import.max_rows"},
+		{"unknown log level", validConfig, func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		{"sample ratio above one", validConfig, func(c *Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+		{"production without secret", productionConfig, func(c *Config) { c.Auth.EncryptionKey = "" }, "DATA_ENCRYPTION_KEY is required in production"},
+		{"production placeholder secret", productionConfig, func(c *Config) { c.Auth.JWTSecret = "ChangeMe" }, "JWT_SECRET is set to a well-known placeholder"},
+		{"production short secret", productionConfig, func(c *Config) { c.Storage.SigningKey = "short" }, "STORAGE_SIGNING_KEY must be at least"},
+		{"production shared JWT secrets", productionConfig, func(c *Config) { c.Auth.JWTRefreshSecret = c.Auth.JWTSecret }, "must differ"},
+		{"production default database password", productionConfig, func(c *Config) {
+			c.Database.Password = Defaults().Database.Password
+		}, "DB_PASSWORD must be changed"},
+		{"production database URL", productionConfig, func(c *Config) {
+			c.Database.Password, c.Database.URL = Defaults().Database.Password, "postgres://db.example.com/elearning"
+		}, ""},
+		{"production logged mail", productionConfig, func(c *Config) { c.Mail.Driver = "log" }, "mail.driver must be smtp in production"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config(t)
+			tt.edit(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.App.Port = "http"
+	cfg.Log.Format = "xml"
+	cfg.Import.MaxRows = -1
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted an invalid configuration")
+	}
+	for _, want := range []string{"app.port", "log.format", "import.max_rows"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestFillSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.Auth.JWTSecret = "configured"
+	if err := cfg.fillSecrets(); err != nil {
+		t.Fatalf("fillSecrets: %v", err)
+	}
+	if cfg.Auth.JWTSecret != "configured" {
+		t.Errorf("configured secret replaced with %q", cfg.Auth.JWTSecret)
+	}
+	if len(cfg.Auth.JWTRefreshSecret) != 64 || cfg.Auth.JWTRefreshSecret == cfg.Storage.SigningKey {
+		t.Errorf("generated secrets %q and %q", cfg.Auth.JWTRefreshSecret, cfg.Storage.SigningKey)
+	}
+
+	production := Defaults()
+	production.App.Env = EnvProduction
+	if err := production.fillSecrets(); err != nil {
+		t.Fatalf("fillSecrets: %v", err)
+	}
+	if production.Auth.JWTSecret != "" {
+		t.Error("secret generated in production")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	yaml := "app:\n  port: \"9000\"\n  name: from-file\nlog:\n  level: debug\ncors:\n  allowed_origins: [\"https://file.example.com\"]\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	noEnvFile := filepath.Join(dir, "missing.env")
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantPort    string
+		wantLevel   string
+		wantOrigins string
+	}{
+		{"file", []string{"-config", file}, nil, "9000", "debug", "https://file.example.com"},
+		{"env over file", []string{"-config", file}, map[string]string{
+			"PORT": "9100", "CORS_ALLOWED_ORIGINS": "https://a.example.com, ,https://b.example.com",
+		}, "9100", "debug", "https://a.example.com,https://b.example.com"},
+		{"flags over env", []string{"-config", file, "-port", "9200", "-log-level", "warn"}, map[string]string{
+			"PORT": "9100", "LOG_LEVEL": "error",
+		}, "9200", "warn", "https://file.example.com"},
+		{"file from env", nil, map[string]string{"CONFIG_FILE": file}, "9000", "debug", "https://file.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"CONFIG_FILE", "PORT", "LOG_LEVEL", "CORS_ALLOWED_ORIGINS", "ENV"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := Load(append(tt.args, "-env-file", noEnvFile))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.App.Port != tt.wantPort || cfg.Log.Level != tt.wantLevel {
+				t.Errorf("port %s, log level %s, want %s, %s", cfg.App.Port, cfg.Log.Level, tt.wantPort, tt.wantLevel)
+			}
+			if origins := strings.Join(cfg.CORS.AllowedOrigins, ","); origins != tt.wantOrigins {
+				t.Errorf("origins = %s, want %s", origins, tt.wantOrigins)
+			}
+			if cfg.App.Name != "from-file" || cfg.Database.Host != "localhost" {
+				t.Errorf("name %s, database host %s: file and defaults not layered", cfg.App.Name, cfg.Database.Host)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	noEnvFile := filepath.Join(dir, "missing.env")
+	for _, key := range []string{"CONFIG_FILE", "PORT", "ENV", "HTTP_WRITE_TIMEOUT"} {
+		t.Setenv(key, "")
+	}
+
+	if _, err := Load([]string{"-config", filepath.Join(dir, "missing.yaml"), "-env-file", noEnvFile}); err == nil {
+		t.Error("missing -config file accepted")
+	}
+
+	t.Setenv("HTTP_WRITE_TIMEOUT", "a minute")
+	if _, err := Load([]string{"-env-file", noEnvFile}); err == nil || !strings.Contains(err.Error(), "HTTP_WRITE_TIMEOUT") {
+		t.Errorf("unparsable env var error = %v", err)
+	}
+	t.Setenv("HTTP_WRITE_TIMEOUT", "")
+
+	if _, err := Load([]string{"-env", "production", "-env-file", noEnvFile}); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Errorf("production without secrets error = %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+// Package apperr defines the typed errors returned by the domain layer.
+// Each error has a Kind, which decides the HTTP status, and a stable Code
+// that clients can rely on.
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// Kind classifies an error independently of the transport
+type Kind string
+
+const (
+	KindValidation       Kind = "validation"
+	KindUnauthenticated  Kind = "unauthenticated"
+	KindForbidden        Kind = "forbidden"
+	KindNotFound         Kind = "not_found"
+	KindConflict         Kind = "conflict"
+	KindGone             Kind = "gone"
+	KindTooLarge         Kind = "too_large"
+	KindUnsupportedMedia Kind = "unsupported_media"
+	KindRateLimited      Kind = "rate_limited"
+	KindInternal         Kind = "internal"
+)
+
+// FieldError describes why a single input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a kind, a stable code and a client-safe message
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error // underlying cause, never shown to clients
+
+	// RetryAfter tells clients when to try again; zero when unknown
+	RetryAfter time.Duration
+}
+
+// Error returns the client-safe message; the cause is left out on purpose
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap exposes the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any error of the same kind and code, so copies made by Wrap
+// and WithFields still match the sentinel they came from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e that records cause
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.Err = cause
+	return &c
+}
+
+// WithFields returns a copy of e carrying field-level details
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &c
+}
+
+// WithRetryAfter returns a copy of e telling clients to retry after d
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+	return &c
+}
+
+// New creates an error of the given kind
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Validation is returned for malformed or rejected input
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// Unauthenticated is returned when the caller's identity is missing or wrong
+func Unauthenticated(code, message string) *Error {
+	return New(KindUnauthenticated, code, message)
+}
+
+// Forbidden is returned when the caller may not perform the action
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+// NotFound is returned when the addressed resource does not exist
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// Conflict is returned when the action clashes with the current state
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// RateLimited is returned when the caller has to slow down or wait
+func RateLimited(code, message string) *Error {
+	return New(KindRateLimited, code, message)
+}
+
+// As returns the first *Error in err's chain
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of err, or KindInternal for untyped errors
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return KindInternal
+}
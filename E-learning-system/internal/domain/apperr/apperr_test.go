@@ -0,0 +1,77 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var errSentinel = Conflict("thing_exists", "thing already exists")
+
+func TestIs(t *testing.T) {
+	cause := errors.New("duplicate key value violates unique constraint")
+
+	tests := []struct {
+		name  string
+		err   error
+		match bool
+	}{
+		{"itself", errSentinel, true},
+		{"wrapped copy", errSentinel.Wrap(cause), true},
+		{"copy with fields", errSentinel.WithFields(FieldError{Field: "name", Message: "is taken"}), true},
+		{"copy with retry", errSentinel.WithRetryAfter(time.Second), true},
+		{"wrapped with fmt", fmt.Errorf("failed to create thing: %w", errSentinel), true},
+		{"same code, other kind", Validation("thing_exists", "thing already exists"), false},
+		{"same kind, other code", Conflict("other_exists", "thing already exists"), false},
+		{"untyped", errors.New("thing already exists"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, errSentinel); got != tt.match {
+				t.Errorf("errors.Is = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCopiesLeaveTheSentinelAlone(t *testing.T) {
+	cause := errors.New("connection reset")
+	wrapped := errSentinel.Wrap(cause).WithFields(FieldError{Field: "name", Message: "is taken"})
+
+	if errSentinel.Err != nil || len(errSentinel.Fields) != 0 {
+		t.Fatalf("sentinel changed: %+v", errSentinel)
+	}
+	if !errors.Is(wrapped, cause) {
+		t.Error("cause not reachable through Unwrap")
+	}
+	if wrapped.Error() != "thing already exists" {
+		t.Errorf("message = %q, want the client-safe message only", wrapped.Error())
+	}
+
+	more := wrapped.WithFields(FieldError{Field: "slug", Message: "is taken"})
+	if len(wrapped.Fields) != 1 || len(more.Fields) != 2 {
+		t.Errorf("fields = %d and %d, want 1 and 2", len(wrapped.Fields), len(more.Fields))
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"typed", NotFound("thing_not_found", "thing not found"), KindNotFound},
+		{"wrapped", fmt.Errorf("lookup: %w", Forbidden("thing_forbidden", "not yours")), KindForbidden},
+		{"typed cause of a typed error", Validation("bad", "bad").Wrap(RateLimited("slow", "slow down")), KindValidation},
+		{"untyped", errors.New("boom"), KindInternal},
+		{"nil", nil, KindInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
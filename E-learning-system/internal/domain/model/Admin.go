@@ -26,6 +26,26 @@ const (
 	ManageSettings      AdminPermission = "manage_settings"
 )
 
+// AdminPermissions lists every known permission
+var AdminPermissions = []AdminPermission{
+	ManageUser,
+	ManageOrganizations,
+	ManageCourses,
+	ManagePayments,
+	ViewAnalytics,
+	ManageSettings,
+}
+
+// IsValid reports whether p is a known permission
+func (p AdminPermission) IsValid() bool {
+	for _, known := range AdminPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
 // Admin represents platform or organization administrators
 type Admin struct {
 	ID             uuid.UUID         `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
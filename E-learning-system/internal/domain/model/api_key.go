@@ -0,0 +1,47 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// APIKeyPrefix starts every API key, so keys are easy to recognise in
+// headers and to find with secret scanners
+const APIKeyPrefix = "elk_"
+
+// APIKey lets an integration call the API on behalf of an organization.
+// Requests made with it act as the admin who created it, limited to its
+// scopes.
+type APIKey struct {
+	ID                 uuid.UUID          `json:"id"`
+	OrganizationID     uuid.UUID          `json:"organization_id"`
+	Name               string             `json:"name"`
+	Prefix             string             `json:"prefix"` // public part of the key, shown in listings
+	KeyHash            string             `json:"-"`
+	Scopes             []AdminPermission  `json:"scopes"`
+	CreatedBy          uuid.UUID          `json:"created_by"`
+	CreatedAt          time.Time          `json:"created_at"`
+	ExpiresAt          *time.Time         `json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time         `json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time         `json:"revoked_at,omitempty"`
+	OrganizationStatus OrganizationStatus `json:"-"` // only loaded for authentication
+}
+
+// HasScope reports whether the key was granted permission p
+func (k *APIKey) HasScope(p AdminPermission) bool {
+	return slices.Contains(k.Scopes, p)
+}
+
+// IsExpired reports whether the key had expired at now
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// NewAPIKey is a freshly created key. Key is the full secret and is only
+// available in this response.
+type NewAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
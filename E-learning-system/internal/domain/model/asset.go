@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// AssetPurpose tells what an uploaded file is used for, which drives its limits
+type AssetPurpose string
+
+const (
+	AssetPurposeLogo           AssetPurpose = "logo"
+	AssetPurposeCourseMaterial AssetPurpose = "course_material"
+)
+
+// Asset is an uploaded file kept in object storage
+type Asset struct {
+	ID             uuid.UUID         `json:"id"`
+	OrganizationID *uuid.UUID        `json:"organization_id,omitempty"`
+	OwnerID        uuid.UUID         `json:"owner_id"`
+	Purpose        AssetPurpose      `json:"purpose"`
+	FileName       string            `json:"file_name"`
+	ContentType    string            `json:"content_type"`
+	Size           int64             `json:"size"`
+	StorageKey     string            `json:"-"`
+	Variants       map[string]string `json:"-"` // variant name (e.g. "128") -> storage key
+	CreatedAt      time.Time         `json:"created_at"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Targets of audit events
+const (
+	AuditTargetOrganization         = "organization"
+	AuditTargetOrganizationAdmin    = "organization_admin"
+	AuditTargetOrganizationTutor    = "organization_tutor"
+	AuditTargetOrganizationBilling  = "organization_billing"
+	AuditTargetOrganizationBranding = "organization_branding"
+	AuditTargetAPIKey               = "api_key"
+	AuditTargetUser                 = "user"
+	AuditTargetMFAPolicy            = "mfa_policy"
+)
+
+// AuditEvent records one administrative change. Events are only ever
+// appended; they outlive the organizations and users they mention.
+type AuditEvent struct {
+	ID             uuid.UUID              `json:"id"`
+	OrganizationID *uuid.UUID             `json:"organization_id,omitempty"`
+	ActorID        *uuid.UUID             `json:"actor_id,omitempty"`   // nil for changes made by the system
+	APIKeyID       *uuid.UUID             `json:"api_key_id,omitempty"` // set when the actor used an API key
+	Action         string                 `json:"action"`               // target type and verb, e.g. organization.update
+	TargetType     string                 `json:"target_type"`
+	TargetID       *uuid.UUID             `json:"target_id,omitempty"`
+	Changes        map[string]AuditChange `json:"changes"` // by field; empty when nothing visible changed
+	IP             string                 `json:"ip_address"`
+	UserAgent      string                 `json:"user_agent"`
+	RequestID      string                 `json:"request_id"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+// AuditChange is the value of a field before and after a change. From is
+// absent for created fields and To for removed ones.
+type AuditChange struct {
+	From any `json:"from,omitempty"`
+	To   any `json:"to,omitempty"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Course groups lessons offered by an organization
+type Course struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	Title          string     `json:"title" binding:"required"`
+	Description    string     `json:"description"`
+	CreatedBy      uuid.UUID  `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"` // only listed with include_deleted
+}
+
+// VideoStatus is the processing state of a lesson video
+type VideoStatus string
+
+const (
+	VideoNone       VideoStatus = "none"       // no video uploaded yet
+	VideoUploading  VideoStatus = "uploading"  // resumable upload in progress
+	VideoUploaded   VideoStatus = "uploaded"   // waiting for the processing job
+	VideoProcessing VideoStatus = "processing" // being transcoded
+	VideoReady      VideoStatus = "ready"      // playable
+	VideoFailed     VideoStatus = "failed"     // processing failed, see VideoError
+)
+
+// Playback formats of a ready video
+const (
+	PlaybackHLS         = "hls"         // adaptive HLS renditions
+	PlaybackProgressive = "progressive" // original file, used when ffmpeg is not available
+)
+
+// Lesson is a single unit of a course, usually backed by a video
+type Lesson struct {
+	ID                uuid.UUID   `json:"id"`
+	CourseID          uuid.UUID   `json:"course_id"`
+	Title             string      `json:"title" binding:"required"`
+	Position          int         `json:"position"`
+	VideoStatus       VideoStatus `json:"video_status"`
+	VideoError        string      `json:"video_error,omitempty"`
+	VideoSourceKey    string      `json:"-"`
+	VideoPlaybackType string      `json:"video_playback_type,omitempty"`
+	VideoPlaylistKey  string      `json:"-"` // HLS master playlist or progressive file
+	VideoThumbnailKey string      `json:"-"`
+	VideoDuration     float64     `json:"video_duration,omitempty"` // seconds
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// Enrollment statuses
+const (
+	EnrollmentActive  = "active"  // the student may follow the course
+	EnrollmentPending = "pending" // waiting for the course creator to approve
+)
+
+// Enrollment links a student to a course
+type Enrollment struct {
+	ID        uuid.UUID `json:"id"`
+	CourseID  uuid.UUID `json:"course_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VideoUpload is a resumable (tus-style) upload session for a lesson video
+type VideoUpload struct {
+	ID          uuid.UUID  `json:"id"`
+	LessonID    uuid.UUID  `json:"lesson_id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	FileName    string     `json:"file_name"`
+	Length      int64      `json:"length"`
+	Offset      int64      `json:"offset"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
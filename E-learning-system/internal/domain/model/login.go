@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// ClientInfo describes where a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginOutcome is the result of a sign-in attempt
+type LoginOutcome string
+
+const (
+	LoginSuccess LoginOutcome = "success"
+	LoginFailure LoginOutcome = "failure" // wrong email or password
+	LoginLocked  LoginOutcome = "locked"  // account or client IP temporarily locked out
+	LoginBlocked LoginOutcome = "blocked" // right password, but sign-in is not allowed
+)
+
+// LoginEvent records one sign-in attempt
+type LoginEvent struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    *uuid.UUID   `json:"user_id,omitempty"` // nil when the email is unknown
+	Email     string       `json:"email"`
+	Outcome   LoginOutcome `json:"outcome"`
+	Reason    string       `json:"reason,omitempty"` // error code for unsuccessful attempts
+	IP        string       `json:"ip_address"`
+	UserAgent string       `json:"user_agent"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// MFAEnrollment is a user's authenticator app registration
+type MFAEnrollment struct {
+	UserID       uuid.UUID
+	Secret       string // encrypted TOTP secret
+	Enabled      bool   // false until the first code has been confirmed
+	LastUsedStep int64  // time step of the last accepted code
+	EnabledAt    *time.Time
+}
+
+// MFAStatus tells a user whether MFA is on and whether they must turn it on
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // by the user's role or one of their organizations
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+}
+
+// MFASetup is what an authenticator app needs to enroll
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI, shown as a QR code
+}
+
+// MFAChallenge is handed out after a correct password when a second factor
+// is needed. Its token is exchanged, together with a code, for a session.
+type MFAChallenge struct {
+	Token     string    `json:"challenge_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginResult is the outcome of a correct password: either a session, or a
+// challenge when the user has MFA enabled
+type LoginResult struct {
+	User      *User
+	Token     *Token
+	Challenge *MFAChallenge
+}
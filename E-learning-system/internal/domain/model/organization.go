@@ -6,50 +6,140 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// OrganizationStatus is the lifecycle state of an organization
+type OrganizationStatus string
+
+const (
+	OrganizationPending         OrganizationStatus = "pending"
+	OrganizationActive          OrganizationStatus = "active"
+	OrganizationSuspended       OrganizationStatus = "suspended"
+	OrganizationPendingDeletion OrganizationStatus = "pending_deletion"
+	OrganizationDeleted         OrganizationStatus = "deleted"
+)
+
+// organizationTransitions lists the states each status may move to.
+// "deleted" is only reached by the scheduled purge job. A restore moves an
+// organization pending deletion back to the status it was deleted from.
+var organizationTransitions = map[OrganizationStatus][]OrganizationStatus{
+	OrganizationPending:         {OrganizationActive, OrganizationPendingDeletion},
+	OrganizationActive:          {OrganizationSuspended, OrganizationPendingDeletion},
+	OrganizationSuspended:       {OrganizationActive, OrganizationPendingDeletion},
+	OrganizationPendingDeletion: {OrganizationPending, OrganizationActive, OrganizationSuspended, OrganizationDeleted},
+	OrganizationDeleted:         {},
+}
+
+// IsValid reports whether s is a known organization status
+func (s OrganizationStatus) IsValid() bool {
+	_, ok := organizationTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether moving from s to next is allowed
+func (s OrganizationStatus) CanTransitionTo(next OrganizationStatus) bool {
+	for _, allowed := range organizationTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// BlocksMemberLogin reports whether members of an organization in this state may not sign in
+func (s OrganizationStatus) BlocksMemberLogin() bool {
+	return s == OrganizationSuspended || s == OrganizationPendingDeletion || s == OrganizationDeleted
+}
+
 // Stores core info about each organization
 type Organization struct {
-	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Name           string    `gorm:"size:255;not null"`
-	Description    string    `gorm:"type:text"`
-	LogoURL        string    `gorm:"size:512"`        // branding
-	PrimaryColor   string    `gorm:"size:20"`         // branding
-	SecondaryColor string    `gorm:"size:20"`         // branding
-	Domain         string    `gorm:"size:255;unique"` // custom subdomain/domain
-	Status         string    `gorm:"type:organization_status;default:'pending'"`
-	Plan           string    `gorm:"size:50;default:'free'"` // free, pro, enterprise
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	ID                  uuid.UUID          `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name                string             `gorm:"size:255;not null"`
+	Description         string             `gorm:"type:text"`
+	LogoURL             string             `gorm:"size:512"`        // branding
+	PrimaryColor        string             `gorm:"size:20"`         // branding
+	SecondaryColor      string             `gorm:"size:20"`         // branding
+	Domain              string             `gorm:"size:255;unique"` // custom subdomain/domain
+	Status              OrganizationStatus `gorm:"type:organization_status;default:'pending'"`
+	Plan                string             `gorm:"size:50;default:'free'"` // free, pro, enterprise
+	StatusReason        string             `gorm:"type:text"`
+	StatusChangedBy     *uuid.UUID         `gorm:"type:uuid"`
+	StatusChangedAt     *time.Time
+	DeletionScheduledAt *time.Time `gorm:"index"` // hard delete date while pending_deletion
+	CreatedAt           time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time  `gorm:"autoUpdateTime"`
+}
+
+// OrganizationPolicy holds the rules an organization sets for its members
+type OrganizationPolicy struct {
+	OrganizationID       uuid.UUID `json:"organization_id"`
+	RequireVerifiedEmail bool      `json:"require_verified_email"` // checked before enrollment
+	RequireMFA           bool      `json:"require_mfa"`            // admins and tutors must use two-factor authentication
+	PasswordMaxAgeDays   int       `json:"password_max_age_days"`  // members must choose a new password after this many days; 0 never
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Records every lifecycle transition of an organization.
+type OrganizationStatusEvent struct {
+	ID             uuid.UUID          `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	OrganizationID uuid.UUID          `gorm:"type:uuid;not null;index"`
+	FromStatus     OrganizationStatus `gorm:"type:organization_status"`
+	ToStatus       OrganizationStatus `gorm:"type:organization_status;not null"`
+	Reason         string             `gorm:"type:text"`
+	ActorID        *uuid.UUID         `gorm:"type:uuid"` // nil when changed by the system
+	CreatedAt      time.Time          `gorm:"autoCreateTime"`
 }
 
 // Links users with organization admin role.
 type OrganizationAdmin struct {
-	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
-	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index"`
-	Role           string    `gorm:"size:50;default:'admin'"` // admin, manager
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Role           string     `gorm:"size:50;default:'admin'"` // admin, manager
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	DeletedAt      *time.Time `gorm:"index"` // set while soft-deleted
 }
 
 // Mapping of tutors to organizations.
 type OrganizationTutor struct {
-	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
-	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index"`
-	Approved       bool      `gorm:"default:false"` // org admin must approve tutor
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Approved       bool       `gorm:"default:false"` // org admin must approve tutor
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	DeletedAt      *time.Time `gorm:"index"` // set while soft-deleted
 }
 
 // Advanced branding config, separated for flexibility.
 type OrganizationBranding struct {
-	ID             uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
-	LogoURL        string    `gorm:"size:512"`
-	PrimaryColor   string    `gorm:"size:20"`
-	SecondaryColor string    `gorm:"size:20"`
-	Theme          string    `gorm:"size:50;default:'light'"` // light, dark, custom
-	EmailTemplate  string    `gorm:"type:text"`               // HTML email template
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	ID             uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	LogoURL        string     `gorm:"size:512"`
+	PrimaryColor   string     `gorm:"size:20"`
+	SecondaryColor string     `gorm:"size:20"`
+	Theme          string     `gorm:"size:50;default:'light'"` // light, dark, custom
+	EmailTemplate  string     `gorm:"type:text"`               // HTML email template
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+	DeletedAt      *time.Time `gorm:"index"` // set while soft-deleted
+}
+
+// Branding themes an organization can choose from
+const (
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+	ThemeCustom = "custom"
+)
+
+// Public branding manifest served before login, resolved by organization domain.
+type BrandingManifest struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Domain         string    `json:"domain"`
+	LogoURL        string    `json:"logo_url"`
+	PrimaryColor   string    `json:"primary_color"`
+	SecondaryColor string    `json:"secondary_color"`
+	Theme          string    `json:"theme"`
+	StylesheetURL  string    `json:"stylesheet_url"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // Handles subscriptions & payments for organizations
@@ -60,6 +150,7 @@ type OrganizationBilling struct {
 	PaymentMethod  string    `gorm:"size:100"`               // Stripe, PayPal, Local
 	SubscriptionID string    `gorm:"size:255"`               // external payment ID
 	NextBillingAt  time.Time
-	CreatedAt      time.Time `gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+	DeletedAt      *time.Time `gorm:"index"` // set while soft-deleted
 }
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Page size limits of list endpoints
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by us or
+// belong to a different sort order
+var ErrInvalidCursor = apperr.Validation("invalid_cursor", "invalid pagination cursor")
+
+// PageRequest selects one page of a list: at most Limit items ordered by
+// Sort (then ID), starting after the After cursor
+type PageRequest struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	After *Cursor
+}
+
+// Cursor points at the last item of the previous page. Value is that item's
+// sort key, so the next page continues from it even when rows are inserted.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Desc  bool      `json:"d,omitempty"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Encode returns the opaque form of c handed to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+	return &c, nil
+}
+
+// Page is one page of a list. Total counts every item matching the filters;
+// NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"` // URL of the next page
+}
+
+// Filters of the list endpoints. Empty fields do not filter.
+type (
+	UserFilter struct {
+		Role           string
+		EmailPrefix    string
+		IncludeDeleted bool
+	}
+
+	OrganizationFilter struct {
+		Status         OrganizationStatus // deleted organizations are only listed when asked for
+		Plan           string
+		IncludeDeleted bool // organizations pending deletion are only listed when asked for
+	}
+
+	CourseFilter struct {
+		OrganizationID *uuid.UUID
+		IncludeDeleted bool
+	}
+
+	OrganizationTutorFilter struct {
+		OrganizationID *uuid.UUID
+		Approved       *bool
+		IncludeDeleted bool // soft-deleted records are only listed when asked for
+	}
+
+	OrganizationAdminFilter struct {
+		OrganizationID *uuid.UUID
+		Role           string
+		IncludeDeleted bool
+	}
+
+	OrganizationBillingFilter struct {
+		OrganizationID *uuid.UUID
+		Plan           string
+		IncludeDeleted bool
+	}
+
+	OrganizationBrandingFilter struct {
+		OrganizationID *uuid.UUID
+		Theme          string
+		IncludeDeleted bool
+	}
+
+	AuditEventFilter struct {
+		OrganizationID *uuid.UUID
+		ActorID        *uuid.UUID
+		Action         string
+		From           *time.Time // inclusive
+		To             *time.Time // exclusive
+	}
+)
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Sort: "created_at", Desc: true, Value: "2024-01-31T10:00:00.123456Z", ID: uuid.Must(uuid.NewV4())}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if *decoded != cursor {
+		t.Errorf("decoded %+v, want %+v", *decoded, cursor)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("created_at"))},
+		{"no ID", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"email","v":"a@example.com"}`))},
+		{"bad ID", base64.RawURLEncoding.EncodeToString([]byte(`{"s":"email","v":"a","id":"42"}`))},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
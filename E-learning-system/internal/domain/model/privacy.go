@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserDataExportStatus is the progress of a user data export
+type UserDataExportStatus string
+
+const (
+	UserDataExportPending    UserDataExportStatus = "pending"
+	UserDataExportProcessing UserDataExportStatus = "processing"
+	UserDataExportReady      UserDataExportStatus = "ready"
+	UserDataExportFailed     UserDataExportStatus = "failed"
+)
+
+// UserDataExport is a ZIP archive of everything stored about a user,
+// prepared in the background and downloadable until it expires
+type UserDataExport struct {
+	ID          uuid.UUID            `json:"id"`
+	UserID      uuid.UUID            `json:"user_id"`
+	RequestedBy *uuid.UUID           `json:"requested_by,omitempty"` // admin who asked on the user's behalf
+	Status      UserDataExportStatus `json:"status"`
+	StorageKey  string               `json:"-"`
+	Error       string               `json:"-"` // internal; users only see the status
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time           `json:"expires_at,omitempty"`
+	DownloadURL string               `json:"download_url,omitempty"` // short-lived signed link while ready
+}
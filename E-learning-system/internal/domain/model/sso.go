@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// OrganizationSSO is an organization's OpenID Connect identity provider
+type OrganizationSSO struct {
+	OrganizationID  uuid.UUID `json:"organization_id"`
+	Issuer          string    `json:"issuer"`
+	ClientID        string    `json:"client_id"`
+	ClientSecret    string    `json:"-"`                 // encrypted at rest, never returned
+	HasClientSecret bool      `json:"has_client_secret"` // false for public clients
+	AllowedDomains  []string  `json:"allowed_domains"`   // email domains accepted from the provider
+	DefaultRole     string    `json:"default_role"`      // role of users created on first sign-in
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SSOIdentity links a user at an organization's identity provider to a local user
+type SSOIdentity struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Issuer         string    `json:"issuer"`
+	Subject        string    `json:"subject"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastLoginAt    time.Time `json:"last_login_at"`
+}
+
+// SSO protocols an organization can offer
+const (
+	SSOProtocolOIDC = "oidc"
+	SSOProtocolSAML = "saml"
+)
+
+// SSOOrganization is an organization offering single sign-on
+type SSOOrganization struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Name           string    `json:"name"`
+	Protocol       string    `json:"protocol"` // oidc or saml; decides which start endpoint to use
+}
+
+// OrganizationSAML is an organization's SAML 2.0 identity provider and the
+// service provider the organization is registered as
+type OrganizationSAML struct {
+	OrganizationID   uuid.UUID            `json:"organization_id"`
+	IdPEntityID      string               `json:"idp_entity_id"`
+	IdPSSOURL        string               `json:"idp_sso_url"`
+	IdPMetadata      string               `json:"-"`
+	IdPMetadataURL   string               `json:"idp_metadata_url,omitempty"`
+	SPEntityID       string               `json:"sp_entity_id"`   // also the SP metadata URL
+	SPACSURL         string               `json:"sp_acs_url"`     // assertion consumer service
+	SPCertificate    string               `json:"sp_certificate"` // PEM, for the identity provider
+	SPPrivateKey     string               `json:"-"`              // encrypted PEM
+	AttributeMapping SAMLAttributeMapping `json:"attribute_mapping"`
+	AllowedDomains   []string             `json:"allowed_domains"`
+	DefaultRole      string               `json:"default_role"`
+	Enabled          bool                 `json:"enabled"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// SAMLAttributeMapping names the assertion attributes holding user fields.
+// RoleValues maps values of the Role attribute to student or instructor;
+// users without a mapped value get the default role.
+type SAMLAttributeMapping struct {
+	Email      string            `json:"email"`
+	FirstName  string            `json:"first_name"`
+	LastName   string            `json:"last_name"`
+	Role       string            `json:"role"`
+	RoleValues map[string]string `json:"role_values"`
+}
+
+// SAMLLogin is a validated assertion waiting to be claimed with a one-time
+// code by the browser that started the sign-in
+type SAMLLogin struct {
+	OrganizationID uuid.UUID
+	RequestID      string
+	CodeHash       string
+	Subject        string
+	Email          string
+	FirstName      string
+	LastName       string
+	Role           string
+	ExpiresAt      time.Time
+}
+
+// SSOStart sends the user to the identity provider. The state token is kept
+// by the client and handed back with the provider's response.
+type SSOStart struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	StateToken       string    `json:"state_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
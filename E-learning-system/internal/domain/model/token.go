@@ -16,5 +16,5 @@ type Token struct {
 	ExpiresAt  time.Time  `json:"expired_at"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
-	DeletedAt   time.Time  `json:"deleted_at,omitempty"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"` // set when the session is revoked
 }
\ No newline at end of file
@@ -9,14 +9,66 @@ import (
 
 type User struct {
 	ID               uuid.UUID  `json:"id"`
-	Email            string     `json:"email" binding:"required,email"`
-	Password         string     `json:"password" binding:"required,min=6"`
+	Email            string     `json:"email"`
+	Password         string     `json:"-"` // Argon2id, or bcrypt until the next sign-in; never serialized
 	FirstName        string     `json:"first_name"`
 	LastName         string     `json:"last_name"`
 	Role             string     `json:"role"`
-	ResetToken       *uuid.UUID `json:"reset_token,omitempty"`        // omit if not used
-	ResetTokenExpiry *time.Time `json:"reset_token_expiry,omitempty"` // omit if not used
 	CreatedAt        time.Time  `json:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at"`
-	DeletedAt       *time.Time  `json:"deleted_at,omitempty"` // omit if not used
+	DeletedAt       *time.Time  `json:"deleted_at,omitempty"` // set while soft-deleted
+	EmailVerified    bool       `json:"email_verified"`
+
+	// Profile
+	AvatarURL string `json:"avatar_url"`
+	Bio       string `json:"bio"`
+	Locale    string `json:"locale"`   // BCP 47 tag, e.g. "en-GB"
+	Timezone  string `json:"timezone"` // IANA name, e.g. "Africa/Mogadishu"
+}
+
+// UserChanges lists the user fields that may be changed through an update.
+// Nil fields are left as they are.
+type UserChanges struct {
+	Email     *string
+	FirstName *string
+	LastName  *string
+}
+
+// Apply copies the set fields onto u
+func (c UserChanges) Apply(u *User) {
+	if c.Email != nil {
+		u.Email = *c.Email
+	}
+	if c.FirstName != nil {
+		u.FirstName = *c.FirstName
+	}
+	if c.LastName != nil {
+		u.LastName = *c.LastName
+	}
+}
+
+// ProfileChanges lists the fields users may change on their own profile.
+// Nil fields are left as they are.
+type ProfileChanges struct {
+	FirstName *string
+	LastName  *string
+	AvatarURL *string
+	Bio       *string
+	Locale    *string
+	Timezone  *string
+}
+
+// Apply copies the set fields onto u
+func (c ProfileChanges) Apply(u *User) {
+	set := func(dst, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&u.FirstName, c.FirstName)
+	set(&u.LastName, c.LastName)
+	set(&u.AvatarURL, c.AvatarURL)
+	set(&u.Bio, c.Bio)
+	set(&u.Locale, c.Locale)
+	set(&u.Timezone, c.Timezone)
 }
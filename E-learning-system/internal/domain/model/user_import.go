@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserImportStatus is the progress of a bulk user import
+type UserImportStatus string
+
+const (
+	UserImportPending    UserImportStatus = "pending"
+	UserImportProcessing UserImportStatus = "processing"
+	UserImportCompleted  UserImportStatus = "completed"
+	UserImportFailed     UserImportStatus = "failed"
+)
+
+// Roles a user can be given in an organization by an import. They are also
+// the roles listed by a member export.
+const (
+	MemberRoleStudent = "student"
+	MemberRoleTutor   = "tutor"
+	MemberRoleManager = "manager"
+	MemberRoleAdmin   = "admin"
+)
+
+// MemberRoles lists the roles of MemberRoleStudent and the like
+var MemberRoles = []string{MemberRoleStudent, MemberRoleTutor, MemberRoleManager, MemberRoleAdmin}
+
+// Outcomes of importing one row
+const (
+	ImportRowCreated   = "created"
+	ImportRowUpdated   = "updated"
+	ImportRowUnchanged = "unchanged"
+)
+
+// UserImport is a CSV file of users uploaded for an organization and
+// applied row by row in the background
+type UserImport struct {
+	ID              uuid.UUID        `json:"id"`
+	OrganizationID  uuid.UUID        `json:"organization_id"`
+	RequestedBy     *uuid.UUID       `json:"requested_by,omitempty"`
+	Status          UserImportStatus `json:"status"`
+	FileName        string           `json:"file_name"`
+	StorageKey      string           `json:"-"` // the uploaded file until it is processed
+	SendInvitations bool             `json:"send_invitations"`
+	TotalRows       int              `json:"total_rows"`
+	CreatedRows     int              `json:"created_rows"`
+	UpdatedRows     int              `json:"updated_rows"`
+	UnchangedRows   int              `json:"unchanged_rows"`
+	FailedRows      int              `json:"failed_rows"`
+	Error           string           `json:"error,omitempty"` // why the whole file was rejected
+	CreatedAt       time.Time        `json:"created_at"`
+	StartedAt       *time.Time       `json:"started_at,omitempty"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+}
+
+// UserImportRow is one user read from an import file
+type UserImportRow struct {
+	Line      int // line of the file, the header being line 1
+	Email     string
+	FirstName string
+	LastName  string
+	Role      string
+}
+
+// UserImportError reports a row of an import that was not applied
+type UserImportError struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email"`
+	Message string `json:"message"`
+}
+
+// OrganizationMember is one membership of a user in an organization
+type OrganizationMember struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Role      string    `json:"role"`     // one of MemberRoles
+	Approved  bool      `json:"approved"` // false for tutors awaiting approval
+	JoinedAt  time.Time `json:"joined_at"`
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +9,9 @@ import (
 
 // OrganizationRepository interface with required methods
 type AdminRepository interface {
-	Create(Admin *model.Admin) error
-	Update(Admin *model.Admin) error
-	Delete(AdminID uuid.UUID) error
-	GetByID(AdminID uuid.UUID) (*model.Admin, error)
-	GetAll() ([]*model.Admin, error)
+	Create(ctx context.Context, Admin *model.Admin) error
+	Update(ctx context.Context, Admin *model.Admin) error
+	Delete(ctx context.Context, AdminID uuid.UUID) error
+	GetByID(ctx context.Context, AdminID uuid.UUID) (*model.Admin, error)
+	GetAll(ctx context.Context) ([]*model.Admin, error)
 }
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// APIKeyRepository stores the API keys of organizations
+type APIKeyRepository interface {
+	// Create stores a new key and fills in its ID and creation time
+	Create(ctx context.Context, key *model.APIKey) error
+	List(ctx context.Context, organizationID uuid.UUID) ([]*model.APIKey, error)
+	// FindByPrefix returns the key with the given prefix, including its hash and organization status
+	FindByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	// Revoke disables a key of the organization; false when there is no such active key
+	Revoke(ctx context.Context, organizationID, keyID uuid.UUID) (bool, error)
+	// Touch records that a key was used
+	Touch(ctx context.Context, keyID uuid.UUID) error
+}
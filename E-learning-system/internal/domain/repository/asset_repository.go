@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// AssetRepository stores metadata about uploaded files
+type AssetRepository interface {
+	Create(ctx context.Context, asset *model.Asset) error
+	GetByID(ctx context.Context, assetID uuid.UUID) (*model.Asset, error)
+	Delete(ctx context.Context, assetID uuid.UUID) error
+	// Restore undeletes a soft-deleted asset; NotFound when there is none with the ID
+	Restore(ctx context.Context, assetID uuid.UUID) error
+}
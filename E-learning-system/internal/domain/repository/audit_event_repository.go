@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+)
+
+// AuditEventRepository appends and lists audit events; events cannot be changed
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *model.AuditEvent) error
+	List(ctx context.Context, filter model.AuditEventFilter, page model.PageRequest) (*model.Page[*model.AuditEvent], error)
+}
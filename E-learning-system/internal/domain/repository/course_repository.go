@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// CourseRepository interface with required methods
+type CourseRepository interface {
+	Create(ctx context.Context, course *model.Course) error
+	GetByID(ctx context.Context, courseID uuid.UUID) (*model.Course, error)
+	List(ctx context.Context, filter model.CourseFilter, page model.PageRequest) (*model.Page[*model.Course], error)
+	// Delete soft-deletes a course together with its lessons; NotFound when there is no live course with the ID
+	Delete(ctx context.Context, courseID uuid.UUID) error
+	// Restore undeletes a soft-deleted course and the lessons deleted with it; NotFound when there is none with the ID
+	Restore(ctx context.Context, courseID uuid.UUID) error
+}
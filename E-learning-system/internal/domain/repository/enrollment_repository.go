@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// EnrollmentRepository interface with required methods
+type EnrollmentRepository interface {
+	Create(ctx context.Context, enrollment *model.Enrollment) error
+	// IsEnrolled reports whether the user has an active enrollment in the course
+	IsEnrolled(ctx context.Context, userID, courseID uuid.UUID) (bool, error)
+	GetByUser(ctx context.Context, userID uuid.UUID) ([]*model.Enrollment, error)
+	GetByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Enrollment, error)
+	// Approve activates a pending enrollment; NotFound when the course has none with the ID
+	Approve(ctx context.Context, enrollmentID, courseID uuid.UUID) error
+}
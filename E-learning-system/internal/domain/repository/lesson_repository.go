@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// LessonRepository interface with required methods
+type LessonRepository interface {
+	Create(ctx context.Context, lesson *model.Lesson) error
+	GetByID(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error)
+	GetByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Lesson, error)
+	// Delete soft-deletes a lesson; NotFound when there is no live lesson with the ID
+	Delete(ctx context.Context, lessonID uuid.UUID) error
+	// Restore undeletes a soft-deleted lesson of a live course; NotFound when there is none with the ID
+	Restore(ctx context.Context, lessonID uuid.UUID) error
+	UpdateVideo(ctx context.Context, lesson *model.Lesson) error
+	GetByVideoStatus(ctx context.Context, status model.VideoStatus) ([]*model.Lesson, error)
+	ClaimVideoProcessing(ctx context.Context, lessonID uuid.UUID) (bool, error)
+	// ReleaseStaleVideoClaims puts videos claimed before the given time back to uploaded
+	ReleaseStaleVideoClaims(ctx context.Context, claimedBefore time.Time) (int, error)
+}
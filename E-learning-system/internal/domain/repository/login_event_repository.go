@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+)
+
+// LoginEventRepository records sign-in attempts
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *model.LoginEvent) error
+}
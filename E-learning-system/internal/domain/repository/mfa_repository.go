@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// MFARepository stores authenticator enrollments, recovery codes and who has to use MFA
+type MFARepository interface {
+	// StartEnrollment stores a pending secret; false when MFA is already enabled
+	StartEnrollment(ctx context.Context, userID uuid.UUID, secret string) (bool, error)
+	Get(ctx context.Context, userID uuid.UUID) (*model.MFAEnrollment, error)
+	// Enable confirms a pending enrollment and replaces the recovery codes; false when none is pending
+	Enable(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) (bool, error)
+	// Disable removes the enrollment; false when there was none
+	Disable(ctx context.Context, userID uuid.UUID) (bool, error)
+
+	// UseStep accepts a code's time step once; false for a replay
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// UseRecoveryCode spends a recovery code; false when it is unknown or used
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	// ReplaceRecoveryCodes issues new codes; false when MFA is not enabled
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) (bool, error)
+
+	GetStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error)
+	GetRequiredRoles(ctx context.Context) ([]string, error)
+	SetRequiredRoles(ctx context.Context, roles []string) error
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +9,11 @@ import (
 
 // OrganizationBillingRepository interface with required methods
 type OrganizationBillingRepository interface {
-	Create(OrganizationBilling *model.OrganizationBilling) error
-	Update(OrganizationBilling *model.OrganizationBilling) error
-	Delete(OrganizationBrandingID uuid.UUID) error
-	GetByID(OrganizationBrandingID uuid.UUID) (*model.OrganizationBilling, error)
-	GetAll() ([]*model.OrganizationBilling, error)
+	Create(ctx context.Context, OrganizationBilling *model.OrganizationBilling) error
+	Update(ctx context.Context, OrganizationBilling *model.OrganizationBilling) error
+	Delete(ctx context.Context, OrganizationBillingID uuid.UUID) error
+	// Restore undeletes a soft-deleted billing record; NotFound when there is none with the ID
+	Restore(ctx context.Context, OrganizationBillingID uuid.UUID) error
+	GetByID(ctx context.Context, OrganizationBillingID uuid.UUID) (*model.OrganizationBilling, error)
+	List(ctx context.Context, filter model.OrganizationBillingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBilling], error)
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +9,12 @@ import (
 
 // OrganizationBrandingRepository interface with required methods
 type OrganizationBrandingRepository interface {
-	Create(OrganizationBranding *model.OrganizationBranding) error
-	Update(OrganizationBranding *model.OrganizationBranding) error
-	Delete(OrganizationBrandingID uuid.UUID) error
-	GetByID(OrganizationBrandingID uuid.UUID) (*model.OrganizationBranding, error)
-	GetAll() ([]*model.OrganizationBranding, error)
+	Create(ctx context.Context, OrganizationBranding *model.OrganizationBranding) error
+	Update(ctx context.Context, OrganizationBranding *model.OrganizationBranding) error
+	Delete(ctx context.Context, OrganizationBrandingID uuid.UUID) error
+	// Restore undeletes a soft-deleted branding; NotFound when there is none with the ID
+	Restore(ctx context.Context, OrganizationBrandingID uuid.UUID) error
+	GetByID(ctx context.Context, OrganizationBrandingID uuid.UUID) (*model.OrganizationBranding, error)
+	List(ctx context.Context, filter model.OrganizationBrandingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBranding], error)
+	GetManifestByDomain(ctx context.Context, domain string) (*model.BrandingManifest, error)
 }
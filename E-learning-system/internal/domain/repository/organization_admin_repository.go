@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +9,13 @@ import (
 
 // OrganizationAdminRepository interface with required methods
 type OrganizationAdminRepository interface {
-	Create(organization *model.OrganizationAdmin) error
-	Update(organization *model.OrganizationAdmin) error
-	Delete(OrganizationAdminID uuid.UUID) error
-	GetByID(OrganizationAdminID uuid.UUID) (*model.OrganizationAdmin, error)
-	GetAll() ([]*model.OrganizationAdmin, error)
+	Create(ctx context.Context, organization *model.OrganizationAdmin) error
+	Update(ctx context.Context, organization *model.OrganizationAdmin) error
+	Delete(ctx context.Context, OrganizationAdminID uuid.UUID) error
+	// Restore undeletes a soft-deleted admin; NotFound when there is none with the ID
+	Restore(ctx context.Context, OrganizationAdminID uuid.UUID) error
+	GetByID(ctx context.Context, OrganizationAdminID uuid.UUID) (*model.OrganizationAdmin, error)
+	List(ctx context.Context, filter model.OrganizationAdminFilter, page model.PageRequest) (*model.Page[*model.OrganizationAdmin], error)
+	// IsAdmin reports whether the user is a live admin or manager of the organization
+	IsAdmin(ctx context.Context, orgID, userID uuid.UUID) (bool, error)
 }
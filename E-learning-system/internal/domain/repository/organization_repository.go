@@ -1,17 +1,34 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
 
 // OrganizationRepository interface with required methods
 type OrganizationRepository interface {
-	Create(organization *model.Organization) error
-	Update(organization *model.Organization) error
-	Delete(organizationID uuid.UUID) error
-	GetByID(organizationID uuid.UUID) (*model.Organization, error)
-	GetAll() ([]*model.Organization, error)
-}
+	Create(ctx context.Context, organization *model.Organization) error
+	Update(ctx context.Context, organization *model.Organization) error
+	GetByID(ctx context.Context, organizationID uuid.UUID) (*model.Organization, error)
+	List(ctx context.Context, filter model.OrganizationFilter, page model.PageRequest) (*model.Page[*model.Organization], error)
+
+	// lifecycle
+	ChangeStatus(ctx context.Context, organizationID uuid.UUID, from, to model.OrganizationStatus, reason string, actorID *uuid.UUID, deletionScheduledAt *time.Time) error
+	GetStatusEvents(ctx context.Context, organizationID uuid.UUID) ([]*model.OrganizationStatusEvent, error)
+	GetDueForDeletion(ctx context.Context, before time.Time) ([]uuid.UUID, error)
+	Export(ctx context.Context, organizationID uuid.UUID) ([]byte, error)
+	HardDelete(ctx context.Context, organizationID uuid.UUID, reason string) error
+	// GetStorageKeys lists the stored files of an organization's assets, lesson videos and imports
+	GetStorageKeys(ctx context.Context, organizationID uuid.UUID) ([]string, error)
+	GetMemberStatuses(ctx context.Context, userID uuid.UUID) ([]model.OrganizationStatus, error)
+	// GetMemberRole returns the user's strongest membership of the organization:
+	// its admin role, "tutor" or "student"; empty when the user is not a member
+	GetMemberRole(ctx context.Context, organizationID, userID uuid.UUID) (string, error)
 
+	// policy
+	GetPolicy(ctx context.Context, organizationID uuid.UUID) (*model.OrganizationPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *model.OrganizationPolicy) error
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
 
 	"github.com/gofrs/uuid"
@@ -8,9 +9,11 @@ import (
 
 // OrganizationTutorRepository interface with required methods
 type OrganizationTutorRepository interface {
-	Create(OrganizationTutor *model.OrganizationTutor) error
-	Update(OrganizationTutor *model.OrganizationTutor) error
-	Delete(OrganizationTutorID uuid.UUID) error
-	GetByID(OrganizationTutorID uuid.UUID) (*model.OrganizationTutor, error)
-	GetAll() ([]*model.OrganizationTutor, error)
+	Create(ctx context.Context, OrganizationTutor *model.OrganizationTutor) error
+	Update(ctx context.Context, OrganizationTutor *model.OrganizationTutor) error
+	Delete(ctx context.Context, OrganizationTutorID uuid.UUID) error
+	// Restore undeletes a soft-deleted tutor; NotFound when there is none with the ID
+	Restore(ctx context.Context, OrganizationTutorID uuid.UUID) error
+	GetByID(ctx context.Context, OrganizationTutorID uuid.UUID) (*model.OrganizationTutor, error)
+	List(ctx context.Context, filter model.OrganizationTutorFilter, page model.PageRequest) (*model.Page[*model.OrganizationTutor], error)
 }
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// PrivacyRepository stores user data exports and erases users
+type PrivacyRepository interface {
+	// CreateExport queues an export; Conflict when one is already in progress
+	CreateExport(ctx context.Context, userID uuid.UUID, requestedBy *uuid.UUID) (uuid.UUID, error)
+	GetExport(ctx context.Context, exportID uuid.UUID) (*model.UserDataExport, error)
+	ListExportsByUser(ctx context.Context, userID uuid.UUID) ([]*model.UserDataExport, error)
+	// ClaimExports marks up to limit pending exports as processing and returns them
+	ClaimExports(ctx context.Context, limit int) ([]*model.UserDataExport, error)
+	CompleteExport(ctx context.Context, exportID uuid.UUID, storageKey string, expiresAt time.Time) error
+	FailExport(ctx context.Context, exportID uuid.UUID, reason string) error
+	// ListExpiredExports returns exports whose archive should be deleted by now
+	ListExpiredExports(ctx context.Context, now time.Time) ([]*model.UserDataExport, error)
+	DeleteExport(ctx context.Context, exportID uuid.UUID) error
+
+	// ExportUserData returns everything stored about a user, by archive file name
+	ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error)
+	// EraseUser anonymizes a user; false when the user is unknown or already erased
+	EraseUser(ctx context.Context, userID uuid.UUID) (bool, error)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionRepository hard deletes records once they have been soft-deleted long enough
+type RetentionRepository interface {
+	// PurgeSoftDeleted removes records soft-deleted before the given time and
+	// returns how many went, by record type
+	PurgeSoftDeleted(ctx context.Context, before time.Time) (map[string]int64, error)
+	// GetExpiredStorageKeys returns the stored files of the assets and lessons
+	// PurgeSoftDeleted would remove for the same time
+	GetExpiredStorageKeys(ctx context.Context, before time.Time) ([]string, error)
+}
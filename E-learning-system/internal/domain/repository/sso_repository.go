@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// SSORepository stores organization identity providers and the identities linked through them
+type SSORepository interface {
+	GetConfig(ctx context.Context, organizationID uuid.UUID) (*model.OrganizationSSO, error)
+	// SaveConfig creates or replaces the configuration; a nil clientSecret keeps the stored one
+	SaveConfig(ctx context.Context, config *model.OrganizationSSO, clientSecret *string) error
+	DeleteConfig(ctx context.Context, organizationID uuid.UUID) error
+	// FindByDomain lists the OpenID Connect and SAML providers accepting an email domain
+	FindByDomain(ctx context.Context, domain string) ([]model.SSOOrganization, error)
+
+	GetSAMLConfig(ctx context.Context, organizationID uuid.UUID) (*model.OrganizationSAML, error)
+	SaveSAMLConfig(ctx context.Context, config *model.OrganizationSAML) error
+	DeleteSAMLConfig(ctx context.Context, organizationID uuid.UUID) error
+	// CreateSAMLLogin stores a validated assertion; a Conflict means its request was already answered
+	CreateSAMLLogin(ctx context.Context, login *model.SAMLLogin) error
+	// ConsumeSAMLLogin claims an unexpired, unused assertion by its code hash
+	ConsumeSAMLLogin(ctx context.Context, codeHash string) (*model.SAMLLogin, error)
+
+	FindIdentity(ctx context.Context, organizationID uuid.UUID, issuer, subject string) (*model.SSOIdentity, error)
+	// LinkIdentity stores the link and makes the user a member of the organization
+	LinkIdentity(ctx context.Context, identity *model.SSOIdentity) error
+	TouchIdentity(ctx context.Context, identityID uuid.UUID, email string) error
+}
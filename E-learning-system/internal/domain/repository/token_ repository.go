@@ -1,10 +1,19 @@
 package repository
 
-import "e-learning-system/internal/domain/model"
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
 
 // interface token
 type TokenRepository interface {
-	FindByToken(token string) (*model.Token, error )
-	Create(token *model.Token) error
+	FindByToken(ctx context.Context, token string) (*model.Token, error )
+	Create(ctx context.Context, token *model.Token) error
+
+	// RevokeOthers revokes every session of the user except keepID and
+	// returns how many were revoked
+	RevokeOthers(ctx context.Context, userID, keepID uuid.UUID) (int, error)
 
 }
\ No newline at end of file
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserImportRepository stores bulk user imports and applies their rows
+type UserImportRepository interface {
+	Create(ctx context.Context, userImport *model.UserImport) error
+	Get(ctx context.Context, importID uuid.UUID) (*model.UserImport, error)
+	ListByOrganization(ctx context.Context, orgID uuid.UUID) ([]*model.UserImport, error)
+	// Claim marks up to limit pending imports as processing and returns them
+	Claim(ctx context.Context, limit int) ([]*model.UserImport, error)
+	// Complete records the row counts of a processed import
+	Complete(ctx context.Context, userImport *model.UserImport) error
+	Fail(ctx context.Context, importID uuid.UUID, reason string) error
+
+	AddError(ctx context.Context, importID uuid.UUID, rowErr model.UserImportError) error
+	ListErrors(ctx context.Context, importID uuid.UUID) ([]model.UserImportError, error)
+
+	// ImportUser creates or updates the user of row and gives them the
+	// membership of row.Role; the outcome is one of ImportRowCreated and the like
+	ImportUser(ctx context.Context, orgID uuid.UUID, row model.UserImportRow) (userID uuid.UUID, outcome string, err error)
+	// ListMembers returns every live membership of an organization, by email
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]*model.OrganizationMember, error)
+}
@@ -1,22 +1,52 @@
 package repository
 
 import (
+	"context"
 	"e-learning-system/internal/domain/model"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
 
 type UserRepository interface {
-	Create(user *model.User) error
-	Get(user uuid.UUID) (*model.User, error)
-	Update(user *model.User) error
-	FindByEmail(email string) (*model.User, error)
-	Delete(user uuid.UUID) error
-	List() ([]*model.User, error)
-
-	// password reset 
-	SetResetToken(email string, token uuid.UUID, expiry string) error
-	FindByResetToken(token uuid.UUID) (*model.User, error)
-	UpdatePassword(userID uuid.UUID, hashedPassword string) error
-	ClearResetToken(userID uuid.UUID) error
+	Create(ctx context.Context, user *model.User) error
+	Get(ctx context.Context, user uuid.UUID) (*model.User, error)
+	Update(ctx context.Context, user *model.User) error
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	Delete(ctx context.Context, user uuid.UUID) error
+	// Restore undeletes a soft-deleted user; NotFound when there is none with
+	// the ID or the retention job has erased it
+	Restore(ctx context.Context, user uuid.UUID) error
+	List(ctx context.Context, filter model.UserFilter, page model.PageRequest) (*model.Page[*model.User], error)
+
+	// password reset: only the token hash is stored. FindPasswordReset and
+	// CompletePasswordReset return uuid.Nil when the token is unknown, used or
+	// expired; ClearResetToken invalidates every pending token of the user.
+	CreatePasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, ttl time.Duration, ip string) error
+	FindPasswordReset(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	CompletePasswordReset(ctx context.Context, tokenHash, hashedPassword string) (uuid.UUID, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
+	ClearResetToken(ctx context.Context, userID uuid.UUID) error
+
+	// self-service profile
+	UpdateProfile(ctx context.Context, user *model.User) error
+
+	// email change: only the token hash is stored. ConfirmEmailChange applies
+	// the change and returns uuid.Nil when the token is unknown, used or expired.
+	CreateEmailChange(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, ttl time.Duration) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (uuid.UUID, error)
+
+	// email verification. MarkVerificationSent returns false when the user is
+	// already verified or the last link is younger than minInterval; VerifyEmail
+	// returns false when email is no longer the user's address.
+	MarkVerificationSent(ctx context.Context, userID uuid.UUID, minInterval time.Duration) (bool, error)
+	VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (bool, error)
+
+	// password policy. UpdatePassword keeps the replaced hash in the history;
+	// RehashPassword upgrades the hash of an unchanged password and returns
+	// false when the password changed meanwhile. GetPasswordExpiry returns nil
+	// when none of the user's organizations makes passwords expire.
+	GetPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
+	RehashPassword(ctx context.Context, userID uuid.UUID, oldHash, newHash string) (bool, error)
+	GetPasswordExpiry(ctx context.Context, userID uuid.UUID) (*time.Time, error)
 }
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+
+	"github.com/gofrs/uuid"
+)
+
+// VideoUploadRepository stores resumable upload sessions
+type VideoUploadRepository interface {
+	Create(ctx context.Context, upload *model.VideoUpload) error
+	GetByID(ctx context.Context, uploadID uuid.UUID) (*model.VideoUpload, error)
+	UpdateOffset(ctx context.Context, uploadID uuid.UUID, offset int64) error
+	Complete(ctx context.Context, uploadID uuid.UUID) error
+	Delete(ctx context.Context, uploadID uuid.UUID) error
+}
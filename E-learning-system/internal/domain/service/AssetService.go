@@ -0,0 +1,381 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// LogoSizes are the square bounding boxes logo variants are rendered to
+var LogoSizes = []int{64, 128, 256, 512}
+
+// SignedURLExpiry is how long signed asset URLs stay valid
+const SignedURLExpiry = 15 * time.Minute
+
+// assetPolicy limits what may be uploaded for a purpose
+type assetPolicy struct {
+	MaxSize      int64
+	ContentTypes []string
+}
+
+// MaxLogoSize is the largest logo upload accepted
+const MaxLogoSize = 2 << 20 // 2 MiB
+
+var assetPolicies = map[model.AssetPurpose]assetPolicy{
+	model.AssetPurposeLogo: {
+		MaxSize:      MaxLogoSize,
+		ContentTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+	},
+	model.AssetPurposeCourseMaterial: {
+		MaxSize: 50 << 20, // 50 MiB
+		ContentTypes: []string{
+			"application/pdf",
+			"application/zip", // also docx, pptx, xlsx
+			"image/png", "image/jpeg", "image/gif", "image/webp",
+			"text/plain",
+		},
+	},
+}
+
+// assetExtensions gives stored originals an extension so files serve with the right type
+var assetExtensions = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+var (
+	ErrInvalidAsset     = apperr.Validation("invalid_asset", "invalid asset")
+	ErrAssetTooLarge    = apperr.New(apperr.KindTooLarge, "file_too_large", "file is too large")
+	ErrAssetUnsupported = apperr.New(apperr.KindUnsupportedMedia, "file_type_not_allowed", "file type is not allowed")
+	ErrAssetNotFound    = apperr.NotFound("asset_not_found", "asset not found")
+	ErrAssetForbidden   = apperr.Forbidden("asset_forbidden", "you do not have access to this asset")
+)
+
+// AssetService handles file uploads and access to stored files
+type AssetService interface {
+	Upload(ctx context.Context, ownerID uuid.UUID, orgID *uuid.UUID, purpose model.AssetPurpose, fileName string, r io.Reader) (*model.Asset, error)
+	// GetAsset returns an asset to its owner, admins of its organization and platform admins
+	GetAsset(ctx context.Context, callerID, assetID uuid.UUID) (*model.Asset, error)
+	// GetLogo returns a logo asset to anyone; other assets are not found
+	GetLogo(ctx context.Context, assetID uuid.UUID) (*model.Asset, error)
+	SignedURLs(asset *model.Asset) (map[string]string, error)
+	SignedURL(asset *model.Asset, variant string) (string, error)
+	DeleteAsset(ctx context.Context, callerID, assetID uuid.UUID) error
+	// RestoreAsset brings back a soft-deleted asset until the retention job has purged it
+	RestoreAsset(ctx context.Context, assetID uuid.UUID) (*model.Asset, error)
+	MaxUploadSize(purpose model.AssetPurpose) int64
+}
+
+type assetServiceImpl struct {
+	repo      repository.AssetRepository
+	store     storage.Storage
+	userRepo  repository.UserRepository
+	adminRepo repository.OrganizationAdminRepository
+}
+
+// Constructor
+func NewAssetService(assetRepo repository.AssetRepository, store storage.Storage, userRepo repository.UserRepository,
+	adminRepo repository.OrganizationAdminRepository) AssetService {
+	return &assetServiceImpl{repo: assetRepo, store: store, userRepo: userRepo, adminRepo: adminRepo}
+}
+
+// MaxUploadSize returns the size limit for a purpose, or 0 when the purpose is unknown
+func (s *assetServiceImpl) MaxUploadSize(purpose model.AssetPurpose) int64 {
+	return assetPolicies[purpose].MaxSize
+}
+
+// Upload validates, stores and records a file. Logos are also resized to LogoSizes.
+func (s *assetServiceImpl) Upload(ctx context.Context, ownerID uuid.UUID, orgID *uuid.UUID, purpose model.AssetPurpose, fileName string, r io.Reader) (*model.Asset, error) {
+	ctx, span := tracing.Start(ctx, "AssetService.Upload")
+	defer span.End()
+
+	policy, ok := assetPolicies[purpose]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown purpose %q", ErrInvalidAsset, purpose)
+	}
+
+	// Read one byte past the limit to detect oversize uploads
+	data, err := io.ReadAll(io.LimitReader(r, policy.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > policy.MaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrAssetTooLarge, policy.MaxSize)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidAsset)
+	}
+
+	// Trust the bytes, not the client supplied name or header
+	contentType := sniffContentType(data)
+	if !contentTypeAllowed(contentType, policy.ContentTypes) {
+		return nil, fmt.Errorf("%w: %s", ErrAssetUnsupported, contentType)
+	}
+
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	asset := &model.Asset{
+		ID:             newID,
+		OrganizationID: orgID,
+		OwnerID:        ownerID,
+		Purpose:        purpose,
+		FileName:       sanitizeFileName(fileName),
+		ContentType:    contentType,
+		Size:           int64(len(data)),
+		StorageKey:     assetKey(newID, "original"+assetExtensions[contentType]),
+		Variants:       map[string]string{},
+		CreatedAt:      time.Now(),
+	}
+
+	if err := s.store.Put(asset.StorageKey, bytes.NewReader(data), asset.Size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	if purpose == model.AssetPurposeLogo {
+		if err := s.storeLogoVariants(asset, data); err != nil {
+			s.removeObjects(asset)
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(ctx, asset); err != nil {
+		s.removeObjects(asset)
+		return nil, fmt.Errorf("failed to save asset: %w", err)
+	}
+
+	return asset, nil
+}
+
+// storeLogoVariants renders the logo at every standard size as PNG
+func (s *assetServiceImpl) storeLogoVariants(asset *model.Asset, data []byte) error {
+	img, _, err := utils.DecodeImage(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAsset, err)
+	}
+
+	for _, size := range LogoSizes {
+		encoded, err := utils.EncodePNG(utils.ResizeToFit(img, size))
+		if err != nil {
+			return fmt.Errorf("failed to encode %dpx logo: %w", size, err)
+		}
+
+		name := strconv.Itoa(size)
+		key := assetKey(asset.ID, name+".png")
+		if err := s.store.Put(key, bytes.NewReader(encoded), int64(len(encoded)), "image/png"); err != nil {
+			return fmt.Errorf("failed to store %dpx logo: %w", size, err)
+		}
+		asset.Variants[name] = key
+	}
+
+	return nil
+}
+
+// GetAsset retrieves asset metadata for a caller allowed to see it
+func (s *assetServiceImpl) GetAsset(ctx context.Context, callerID, assetID uuid.UUID) (*model.Asset, error) {
+	ctx, span := tracing.Start(ctx, "AssetService.GetAsset")
+	defer span.End()
+
+	asset, err := s.repo.GetByID(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset %s: %w", assetID, err)
+	}
+	if err := s.checkAccess(ctx, callerID, asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// GetLogo retrieves a logo, which is public because branding shows it before sign-in
+func (s *assetServiceImpl) GetLogo(ctx context.Context, assetID uuid.UUID) (*model.Asset, error) {
+	ctx, span := tracing.Start(ctx, "AssetService.GetLogo")
+	defer span.End()
+
+	asset, err := s.repo.GetByID(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset %s: %w", assetID, err)
+	}
+	if asset.Purpose != model.AssetPurposeLogo {
+		return nil, ErrAssetNotFound
+	}
+	return asset, nil
+}
+
+// checkAccess lets the owner of an asset, admins of its organization and
+// platform admins through
+func (s *assetServiceImpl) checkAccess(ctx context.Context, callerID uuid.UUID, asset *model.Asset) error {
+	if asset.OwnerID == callerID {
+		return nil
+	}
+
+	if asset.OrganizationID != nil {
+		isAdmin, err := s.adminRepo.IsAdmin(ctx, *asset.OrganizationID, callerID)
+		if err != nil {
+			return fmt.Errorf("failed to check organization admin: %w", err)
+		}
+		if isAdmin {
+			return nil
+		}
+	}
+
+	caller, err := s.userRepo.Get(ctx, callerID)
+	if err != nil {
+		return err
+	}
+	if caller.Role != "admin" {
+		return ErrAssetForbidden
+	}
+	return nil
+}
+
+// SignedURL returns a short-lived URL for the original file or one of its variants
+func (s *assetServiceImpl) SignedURL(asset *model.Asset, variant string) (string, error) {
+	key := asset.StorageKey
+	if variant != "" && variant != "original" {
+		v, ok := asset.Variants[variant]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown variant %q", ErrInvalidAsset, variant)
+		}
+		key = v
+	}
+
+	return s.store.SignedURL(key, SignedURLExpiry)
+}
+
+// SignedURLs returns short-lived URLs for the original file and every variant
+func (s *assetServiceImpl) SignedURLs(asset *model.Asset) (map[string]string, error) {
+	urls := map[string]string{}
+
+	original, err := s.store.SignedURL(asset.StorageKey, SignedURLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	urls["original"] = original
+
+	for name, key := range asset.Variants {
+		u, err := s.store.SignedURL(key, SignedURLExpiry)
+		if err != nil {
+			return nil, err
+		}
+		urls[name] = u
+	}
+
+	return urls, nil
+}
+
+// DeleteAsset soft deletes the record. The stored objects stay until the
+// retention job purges the record, so the asset can be restored meanwhile.
+func (s *assetServiceImpl) DeleteAsset(ctx context.Context, callerID, assetID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AssetService.DeleteAsset")
+	defer span.End()
+
+	asset, err := s.repo.GetByID(ctx, assetID)
+	if err != nil {
+		return fmt.Errorf("asset not found with ID %s: %w", assetID, err)
+	}
+	if err := s.checkAccess(ctx, callerID, asset); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, assetID); err != nil {
+		return fmt.Errorf("failed to delete asset %s: %w", assetID, err)
+	}
+
+	log.Printf("Asset deleted: %v", assetID)
+	return nil
+}
+
+// RestoreAsset brings back a soft-deleted asset
+func (s *assetServiceImpl) RestoreAsset(ctx context.Context, assetID uuid.UUID) (*model.Asset, error) {
+	ctx, span := tracing.Start(ctx, "AssetService.RestoreAsset")
+	defer span.End()
+
+	if err := s.repo.Restore(ctx, assetID); err != nil {
+		return nil, fmt.Errorf("failed to restore asset with ID %s: %w", assetID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored asset with ID %s: %w", assetID, err)
+	}
+
+	log.Printf("Asset restored: %v", assetID)
+	return restored, nil
+}
+
+// removeObjects deletes every stored object of an asset, logging failures
+func (s *assetServiceImpl) removeObjects(asset *model.Asset) {
+	keys := []string{asset.StorageKey}
+	for _, key := range asset.Variants {
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("Failed to remove stored object %s: %v", key, err)
+		}
+	}
+}
+
+// assetKey builds the storage key of an asset object
+func assetKey(assetID uuid.UUID, name string) string {
+	return path.Join("assets", assetID.String(), name)
+}
+
+// sniffContentType detects the media type of data, without parameters
+func sniffContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFileName keeps only the base name of an uploaded file
+func sanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "upload"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
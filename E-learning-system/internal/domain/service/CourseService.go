@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tenant"
+	"e-learning-system/internal/tracing"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrCourseForbidden      = apperr.Forbidden("course_forbidden", "you may not manage this course")
+	ErrEnrollmentNotAllowed = apperr.Forbidden("enrollment_not_allowed", "only members of the course's organization may enroll")
+)
+
+// CourseService handles courses, their lessons and enrollments
+type CourseService interface {
+	CreateCourse(ctx context.Context, course *model.Course) (*model.Course, error)
+	GetCourseByID(ctx context.Context, courseID uuid.UUID) (*model.Course, error)
+	ListCourses(ctx context.Context, filter model.CourseFilter, page model.PageRequest) (*model.Page[*model.Course], error)
+	// DeleteCourse soft-deletes a course the caller manages together with its lessons
+	DeleteCourse(ctx context.Context, callerID, courseID uuid.UUID) error
+	// RestoreCourse brings back a soft-deleted course until the retention job has purged it
+	RestoreCourse(ctx context.Context, courseID uuid.UUID) (*model.Course, error)
+
+	CreateLesson(ctx context.Context, callerID uuid.UUID, lesson *model.Lesson) (*model.Lesson, error)
+	GetLessonByID(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error)
+	GetLessonsByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Lesson, error)
+	DeleteLesson(ctx context.Context, callerID, lessonID uuid.UUID) error
+	// RestoreLesson brings back a soft-deleted lesson of a live course until the retention job has purged it
+	RestoreLesson(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error)
+
+	// Enroll admits members of the course's organization at once; requests
+	// to join a course without an organization wait for approval
+	Enroll(ctx context.Context, userID, courseID uuid.UUID) (*model.Enrollment, error)
+	GetEnrollmentsByUser(ctx context.Context, userID uuid.UUID) ([]*model.Enrollment, error)
+	GetEnrollmentsByCourse(ctx context.Context, callerID, courseID uuid.UUID) ([]*model.Enrollment, error)
+	ApproveEnrollment(ctx context.Context, callerID, courseID, enrollmentID uuid.UUID) error
+}
+
+type courseServiceImpl struct {
+	courseRepo     repository.CourseRepository
+	lessonRepo     repository.LessonRepository
+	enrollmentRepo repository.EnrollmentRepository
+	orgRepo        repository.OrganizationRepository
+	userRepo       repository.UserRepository
+}
+
+// Constructor
+func NewCourseService(courseRepo repository.CourseRepository, lessonRepo repository.LessonRepository, enrollmentRepo repository.EnrollmentRepository,
+	orgRepo repository.OrganizationRepository, userRepo repository.UserRepository) CourseService {
+	return &courseServiceImpl{
+		courseRepo:     courseRepo,
+		lessonRepo:     lessonRepo,
+		enrollmentRepo: enrollmentRepo,
+		orgRepo:        orgRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// CreateCourse creates a new course
+func (s *courseServiceImpl) CreateCourse(ctx context.Context, course *model.Course) (*model.Course, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.CreateCourse")
+	defer span.End()
+
+	if err := tenant.Check(ctx, course.OrganizationID); err != nil {
+		return nil, err
+	}
+	if course.OrganizationID != nil {
+		if err := s.checkOrganizationRole(ctx, course.CreatedBy, *course.OrganizationID, "admin", "manager", "tutor"); err != nil {
+			return nil, err
+		}
+	}
+
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	course.ID = newID
+	course.CreatedAt = time.Now()
+	course.UpdatedAt = time.Now()
+
+	if err := s.courseRepo.Create(ctx, course); err != nil {
+		return nil, fmt.Errorf("failed to create course: %w", err)
+	}
+
+	return course, nil
+}
+
+// GetCourseByID retrieves a single course
+func (s *courseServiceImpl) GetCourseByID(ctx context.Context, courseID uuid.UUID) (*model.Course, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.GetCourseByID")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get course by ID %s: %w", courseID, err)
+	}
+	if err := tenant.Check(ctx, course.OrganizationID); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+// ListCourses retrieves one page of courses matching the filter
+func (s *courseServiceImpl) ListCourses(ctx context.Context, filter model.CourseFilter, page model.PageRequest) (*model.Page[*model.Course], error) {
+	ctx, span := tracing.Start(ctx, "CourseService.ListCourses")
+	defer span.End()
+
+	orgID, err := tenant.Filter(ctx, filter.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	filter.OrganizationID = orgID
+
+	courses, err := s.courseRepo.List(ctx, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list courses: %w", err)
+	}
+	return courses, nil
+}
+
+// DeleteCourse soft-deletes a course the caller manages; its lessons go with it
+func (s *courseServiceImpl) DeleteCourse(ctx context.Context, callerID, courseID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "CourseService.DeleteCourse")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return fmt.Errorf("course not found with ID %s: %w", courseID, err)
+	}
+	if err := tenant.Check(ctx, course.OrganizationID); err != nil {
+		return err
+	}
+	if err := s.checkManageCourse(ctx, callerID, course); err != nil {
+		return err
+	}
+
+	if err := s.courseRepo.Delete(ctx, courseID); err != nil {
+		return fmt.Errorf("failed to delete course %s: %w", courseID, err)
+	}
+
+	log.Printf("Course %s deleted by %s", courseID, callerID)
+	return nil
+}
+
+// RestoreCourse brings back a soft-deleted course and the lessons deleted with it
+func (s *courseServiceImpl) RestoreCourse(ctx context.Context, courseID uuid.UUID) (*model.Course, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.RestoreCourse")
+	defer span.End()
+
+	if err := s.courseRepo.Restore(ctx, courseID); err != nil {
+		return nil, fmt.Errorf("failed to restore course with ID %s: %w", courseID, err)
+	}
+
+	restored, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored course with ID %s: %w", courseID, err)
+	}
+
+	log.Printf("Course restored: %v", courseID)
+	return restored, nil
+}
+
+// CreateLesson adds a lesson to an existing course the caller manages
+func (s *courseServiceImpl) CreateLesson(ctx context.Context, callerID uuid.UUID, lesson *model.Lesson) (*model.Lesson, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.CreateLesson")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, lesson.CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("course not found with ID %s: %w", lesson.CourseID, err)
+	}
+	if err := tenant.Check(ctx, course.OrganizationID); err != nil {
+		return nil, err
+	}
+	if err := s.checkManageCourse(ctx, callerID, course); err != nil {
+		return nil, err
+	}
+
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	lesson.ID = newID
+	lesson.VideoStatus = model.VideoNone
+	lesson.CreatedAt = time.Now()
+	lesson.UpdatedAt = time.Now()
+
+	if err := s.lessonRepo.Create(ctx, lesson); err != nil {
+		return nil, fmt.Errorf("failed to create lesson: %w", err)
+	}
+
+	return lesson, nil
+}
+
+// GetLessonByID retrieves a single lesson
+func (s *courseServiceImpl) GetLessonByID(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.GetLessonByID")
+	defer span.End()
+
+	lesson, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lesson by ID %s: %w", lessonID, err)
+	}
+	if err := s.checkCourseTenant(ctx, lesson.CourseID); err != nil {
+		return nil, err
+	}
+	return lesson, nil
+}
+
+// GetLessonsByCourse retrieves the lessons of a course in order
+func (s *courseServiceImpl) GetLessonsByCourse(ctx context.Context, courseID uuid.UUID) ([]*model.Lesson, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.GetLessonsByCourse")
+	defer span.End()
+
+	if err := s.checkCourseTenant(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	lessons, err := s.lessonRepo.GetByCourse(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lessons of course %s: %w", courseID, err)
+	}
+	return lessons, nil
+}
+
+// DeleteLesson soft-deletes a lesson of a course the caller manages
+func (s *courseServiceImpl) DeleteLesson(ctx context.Context, callerID, lessonID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "CourseService.DeleteLesson")
+	defer span.End()
+
+	lesson, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return fmt.Errorf("lesson not found with ID %s: %w", lessonID, err)
+	}
+	course, err := s.courseRepo.GetByID(ctx, lesson.CourseID)
+	if err != nil {
+		return fmt.Errorf("course not found with ID %s: %w", lesson.CourseID, err)
+	}
+	if err := tenant.Check(ctx, course.OrganizationID); err != nil {
+		return err
+	}
+	if err := s.checkManageCourse(ctx, callerID, course); err != nil {
+		return err
+	}
+
+	if err := s.lessonRepo.Delete(ctx, lessonID); err != nil {
+		return fmt.Errorf("failed to delete lesson %s: %w", lessonID, err)
+	}
+
+	log.Printf("Lesson %s deleted by %s", lessonID, callerID)
+	return nil
+}
+
+// RestoreLesson brings back a soft-deleted lesson
+func (s *courseServiceImpl) RestoreLesson(ctx context.Context, lessonID uuid.UUID) (*model.Lesson, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.RestoreLesson")
+	defer span.End()
+
+	if err := s.lessonRepo.Restore(ctx, lessonID); err != nil {
+		return nil, fmt.Errorf("failed to restore lesson with ID %s: %w", lessonID, err)
+	}
+
+	restored, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored lesson with ID %s: %w", lessonID, err)
+	}
+
+	log.Printf("Lesson restored: %v", lessonID)
+	return restored, nil
+}
+
+// checkCourseTenant refuses courses of other organizations to requests
+// confined to one. Other requests skip the lookup.
+func (s *courseServiceImpl) checkCourseTenant(ctx context.Context, courseID uuid.UUID) error {
+	if _, ok := tenant.OrganizationFromContext(ctx); !ok {
+		return nil
+	}
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return fmt.Errorf("course not found with ID %s: %w", courseID, err)
+	}
+	return tenant.Check(ctx, course.OrganizationID)
+}
+
+// Enroll registers a user as a student of a course; enrolling twice is a no-op
+func (s *courseServiceImpl) Enroll(ctx context.Context, userID, courseID uuid.UUID) (*model.Enrollment, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.Enroll")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("course not found with ID %s: %w", courseID, err)
+	}
+	if err := s.checkEnrollmentPolicy(ctx, userID, course); err != nil {
+		return nil, err
+	}
+	status, err := s.enrollmentStatus(ctx, userID, course)
+	if err != nil {
+		return nil, err
+	}
+
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	enrollment := &model.Enrollment{
+		ID:        newID,
+		CourseID:  courseID,
+		UserID:    userID,
+		Status:    status,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.enrollmentRepo.Create(ctx, enrollment); err != nil {
+		return nil, fmt.Errorf("failed to enroll user: %w", err)
+	}
+
+	log.Printf("User %s enrolled in course %s (%s)", userID, courseID, status)
+	return enrollment, nil
+}
+
+// enrollmentStatus decides whether a new enrollment is active at once.
+// Organization courses admit members only; courses without an organization
+// admit anyone once the creator approves.
+func (s *courseServiceImpl) enrollmentStatus(ctx context.Context, userID uuid.UUID, course *model.Course) (string, error) {
+	if course.CreatedBy == userID {
+		return model.EnrollmentActive, nil
+	}
+
+	if course.OrganizationID != nil {
+		role, err := s.orgRepo.GetMemberRole(ctx, *course.OrganizationID, userID)
+		if err != nil {
+			return "", fmt.Errorf("failed to check organization membership: %w", err)
+		}
+		if role == "" {
+			return "", ErrEnrollmentNotAllowed
+		}
+		return model.EnrollmentActive, nil
+	}
+
+	// Enrolling again must not turn an approved enrollment back into a request
+	enrolled, err := s.enrollmentRepo.IsEnrolled(ctx, userID, course.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check enrollment: %w", err)
+	}
+	if enrolled {
+		return model.EnrollmentActive, nil
+	}
+	return model.EnrollmentPending, nil
+}
+
+// checkEnrollmentPolicy refuses users the course's organization does not admit
+func (s *courseServiceImpl) checkEnrollmentPolicy(ctx context.Context, userID uuid.UUID, course *model.Course) error {
+	if course.OrganizationID == nil {
+		return nil
+	}
+
+	policy, err := s.orgRepo.GetPolicy(ctx, *course.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to get organization policy: %w", err)
+	}
+	if !policy.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := s.userRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// GetEnrollmentsByCourse lists the enrollments of a course the caller manages
+func (s *courseServiceImpl) GetEnrollmentsByCourse(ctx context.Context, callerID, courseID uuid.UUID) ([]*model.Enrollment, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.GetEnrollmentsByCourse")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("course not found with ID %s: %w", courseID, err)
+	}
+	if err := s.checkManageCourse(ctx, callerID, course); err != nil {
+		return nil, err
+	}
+
+	enrollments, err := s.enrollmentRepo.GetByCourse(ctx, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrollments of course %s: %w", courseID, err)
+	}
+	return enrollments, nil
+}
+
+// ApproveEnrollment activates a pending enrollment of a course the caller manages
+func (s *courseServiceImpl) ApproveEnrollment(ctx context.Context, callerID, courseID, enrollmentID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "CourseService.ApproveEnrollment")
+	defer span.End()
+
+	course, err := s.courseRepo.GetByID(ctx, courseID)
+	if err != nil {
+		return fmt.Errorf("course not found with ID %s: %w", courseID, err)
+	}
+	if err := s.checkManageCourse(ctx, callerID, course); err != nil {
+		return err
+	}
+
+	if err := s.enrollmentRepo.Approve(ctx, enrollmentID, courseID); err != nil {
+		return fmt.Errorf("failed to approve enrollment %s: %w", enrollmentID, err)
+	}
+
+	log.Printf("Enrollment %s in course %s approved by %s", enrollmentID, courseID, callerID)
+	return nil
+}
+
+// checkManageCourse lets the course creator, admins of its organization and
+// platform admins through
+func (s *courseServiceImpl) checkManageCourse(ctx context.Context, callerID uuid.UUID, course *model.Course) error {
+	if course.CreatedBy == callerID {
+		return nil
+	}
+	if course.OrganizationID == nil {
+		return s.checkPlatformAdmin(ctx, callerID)
+	}
+	return s.checkOrganizationRole(ctx, callerID, *course.OrganizationID, "admin", "manager")
+}
+
+// checkOrganizationRole lets members holding one of roles and platform admins through
+func (s *courseServiceImpl) checkOrganizationRole(ctx context.Context, userID, orgID uuid.UUID, roles ...string) error {
+	role, err := s.orgRepo.GetMemberRole(ctx, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check organization membership: %w", err)
+	}
+	if slices.Contains(roles, role) {
+		return nil
+	}
+	return s.checkPlatformAdmin(ctx, userID)
+}
+
+func (s *courseServiceImpl) checkPlatformAdmin(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role != "admin" {
+		return ErrCourseForbidden
+	}
+	return nil
+}
+
+// GetEnrollmentsByUser lists the courses a user is enrolled in
+func (s *courseServiceImpl) GetEnrollmentsByUser(ctx context.Context, userID uuid.UUID) ([]*model.Enrollment, error) {
+	ctx, span := tracing.Start(ctx, "CourseService.GetEnrollmentsByUser")
+	defer span.End()
+
+	enrollments, err := s.enrollmentRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrollments: %w", err)
+	}
+	return enrollments, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeCourseRepo knows a fixed set of courses
+type fakeCourseRepo struct {
+	repository.CourseRepository
+	courses map[uuid.UUID]*model.Course
+}
+
+func (r *fakeCourseRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Course, error) {
+	course, ok := r.courses[id]
+	if !ok {
+		return nil, apperr.NotFound("course_not_found", "course not found")
+	}
+	return course, nil
+}
+
+// fakeEnrollmentRepo keeps enrollments in memory
+type fakeEnrollmentRepo struct {
+	repository.EnrollmentRepository
+	enrollments []*model.Enrollment
+}
+
+func (r *fakeEnrollmentRepo) Create(_ context.Context, enrollment *model.Enrollment) error {
+	r.enrollments = append(r.enrollments, enrollment)
+	return nil
+}
+
+func (r *fakeEnrollmentRepo) IsEnrolled(_ context.Context, userID, courseID uuid.UUID) (bool, error) {
+	for _, e := range r.enrollments {
+		if e.UserID == userID && e.CourseID == courseID && e.Status == model.EnrollmentActive {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeEnrollmentRepo) GetByCourse(_ context.Context, courseID uuid.UUID) ([]*model.Enrollment, error) {
+	var found []*model.Enrollment
+	for _, e := range r.enrollments {
+		if e.CourseID == courseID {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+// fakeMemberRepo knows the members of one organization and its policy
+type fakeMemberRepo struct {
+	repository.OrganizationRepository
+	orgID  uuid.UUID
+	roles  map[uuid.UUID]string
+	policy model.OrganizationPolicy
+}
+
+func (r *fakeMemberRepo) GetMemberRole(_ context.Context, orgID, userID uuid.UUID) (string, error) {
+	if orgID != r.orgID {
+		return "", nil
+	}
+	return r.roles[userID], nil
+}
+
+func (r *fakeMemberRepo) GetPolicy(_ context.Context, orgID uuid.UUID) (*model.OrganizationPolicy, error) {
+	policy := r.policy
+	policy.OrganizationID = orgID
+	return &policy, nil
+}
+
+// fakeUserRepo knows a fixed set of users
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[uuid.UUID]*model.User
+}
+
+func (r *fakeUserRepo) Get(_ context.Context, id uuid.UUID) (*model.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, apperr.NotFound("user_not_found", "user not found")
+	}
+	return user, nil
+}
+
+// courseFixture is an organization course and a course without one, both
+// created by "author", and users in every relevant position, all by name
+type courseFixture struct {
+	service     *courseServiceImpl
+	enrollments *fakeEnrollmentRepo
+	members     *fakeMemberRepo
+	ids         map[string]uuid.UUID
+}
+
+func newCourseFixture() *courseFixture {
+	f := &courseFixture{ids: map[string]uuid.UUID{}}
+	for _, name := range []string{"org course", "open course", "author", "org admin", "tutor",
+		"student", "unverified", "stranger", "platform admin"} {
+		f.ids[name] = uuid.Must(uuid.NewV4())
+	}
+	orgID := uuid.Must(uuid.NewV4())
+
+	f.enrollments = &fakeEnrollmentRepo{}
+	f.members = &fakeMemberRepo{orgID: orgID, roles: map[uuid.UUID]string{
+		f.ids["author"]: "tutor", f.ids["org admin"]: "admin", f.ids["tutor"]: "tutor",
+		f.ids["student"]: "student", f.ids["unverified"]: "student",
+	}}
+	users := map[uuid.UUID]*model.User{}
+	for _, name := range []string{"author", "org admin", "tutor", "student", "stranger"} {
+		users[f.ids[name]] = &model.User{ID: f.ids[name], Role: "student", EmailVerified: true}
+	}
+	users[f.ids["unverified"]] = &model.User{ID: f.ids["unverified"], Role: "student"}
+	users[f.ids["platform admin"]] = &model.User{ID: f.ids["platform admin"], Role: "admin", EmailVerified: true}
+
+	f.service = &courseServiceImpl{
+		courseRepo: &fakeCourseRepo{courses: map[uuid.UUID]*model.Course{
+			f.ids["org course"]:  {ID: f.ids["org course"], OrganizationID: &orgID, CreatedBy: f.ids["author"]},
+			f.ids["open course"]: {ID: f.ids["open course"], CreatedBy: f.ids["author"]},
+		}},
+		enrollmentRepo: f.enrollments,
+		orgRepo:        f.members,
+		userRepo:       &fakeUserRepo{users: users},
+	}
+	return f
+}
+
+func TestEnroll(t *testing.T) {
+	tests := []struct {
+		name          string
+		course        string
+		user          string
+		requireVerify bool
+		activeBefore  bool // the user already has an active enrollment
+		wantStatus    string
+		wantErr       error
+	}{
+		{"member of the organization", "org course", "student", false, false, model.EnrollmentActive, nil},
+		{"creator", "org course", "author", false, false, model.EnrollmentActive, nil},
+		{"not a member", "org course", "stranger", false, false, "", ErrEnrollmentNotAllowed},
+		{"unverified member when required", "org course", "unverified", true, false, "", ErrEmailNotVerified},
+		{"unverified member when not required", "org course", "unverified", false, false, model.EnrollmentActive, nil},
+		{"verified member when required", "org course", "student", true, false, model.EnrollmentActive, nil},
+		{"anyone asks to join an open course", "open course", "stranger", false, false, model.EnrollmentPending, nil},
+		{"creator of an open course", "open course", "author", false, false, model.EnrollmentActive, nil},
+		{"enrolling again stays approved", "open course", "stranger", false, true, model.EnrollmentActive, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCourseFixture()
+			f.members.policy.RequireVerifiedEmail = tt.requireVerify
+			courseID, userID := f.ids[tt.course], f.ids[tt.user]
+			if tt.activeBefore {
+				f.enrollments.enrollments = append(f.enrollments.enrollments,
+					&model.Enrollment{CourseID: courseID, UserID: userID, Status: model.EnrollmentActive})
+			}
+			before := len(f.enrollments.enrollments)
+
+			enrollment, err := f.service.Enroll(context.Background(), userID, courseID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if len(f.enrollments.enrollments) != before {
+					t.Error("enrollment created although refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Enroll: %v", err)
+			}
+			if enrollment.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", enrollment.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestManageCourse(t *testing.T) {
+	tests := []struct {
+		name   string
+		course string
+		caller string
+		ok     bool
+	}{
+		{"creator", "org course", "author", true},
+		{"organization admin", "org course", "org admin", true},
+		{"platform admin", "org course", "platform admin", true},
+		{"other tutor", "org course", "tutor", false},
+		{"student", "org course", "student", false},
+		{"creator of an open course", "open course", "author", true},
+		{"platform admin on an open course", "open course", "platform admin", true},
+		{"organization admin on an open course", "open course", "org admin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCourseFixture()
+			_, err := f.service.GetEnrollmentsByCourse(context.Background(), f.ids[tt.caller], f.ids[tt.course])
+			if tt.ok && err != nil {
+				t.Errorf("GetEnrollmentsByCourse: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrCourseForbidden) {
+				t.Errorf("error = %v, want ErrCourseForbidden", err)
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tenant"
+	"e-learning-system/internal/tracing"
 	"fmt"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -12,93 +17,186 @@ import (
 
 // OrganizationBillingService interface with CRUD methods
 type OrganizationBillingService interface {
-	CreateBilling(billing *model.OrganizationBilling) (*model.OrganizationBilling, error)
-	UpdateBilling(billing *model.OrganizationBilling) error
-	DeleteBilling(billingID uuid.UUID) error
-	GetBillingByID(billingID uuid.UUID) (*model.OrganizationBilling, error)
-	GetAllBillings() ([]*model.OrganizationBilling, error)
+	CreateBilling(ctx context.Context, billing *model.OrganizationBilling) (*model.OrganizationBilling, error)
+	UpdateBilling(ctx context.Context, billing *model.OrganizationBilling) error
+	DeleteBilling(ctx context.Context, billingID uuid.UUID) error
+	// RestoreBilling brings back a soft-deleted billing until the retention job has purged it
+	RestoreBilling(ctx context.Context, billingID uuid.UUID) (*model.OrganizationBilling, error)
+	GetBillingByID(ctx context.Context, billingID uuid.UUID) (*model.OrganizationBilling, error)
+	ListBillings(ctx context.Context, filter model.OrganizationBillingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBilling], error)
 }
 
 // organizationBillingServiceImpl struct implementing OrganizationBillingService
 type organizationBillingServiceImpl struct {
-	repo repository.OrganizationBillingRepository
+	repo  repository.OrganizationBillingRepository
+	audit AuditService
 }
 
 // Constructor
-func NewOrganizationBillingService(billingRepo repository.OrganizationBillingRepository) OrganizationBillingService {
+func NewOrganizationBillingService(billingRepo repository.OrganizationBillingRepository, auditService AuditService) OrganizationBillingService {
 	return &organizationBillingServiceImpl{
-		repo: billingRepo,
+		repo:  billingRepo,
+		audit: auditService,
 	}
 }
 
 // CreateBilling creates a new billing record
-func (s *organizationBillingServiceImpl) CreateBilling(billing *model.OrganizationBilling) (*model.OrganizationBilling, error) {
+func (s *organizationBillingServiceImpl) CreateBilling(ctx context.Context, billing *model.OrganizationBilling) (*model.OrganizationBilling, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.CreateBilling")
+	defer span.End()
+
+	if err := tenant.Check(ctx, &billing.OrganizationID); err != nil {
+		return nil, err
+	}
+
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
 	billing.ID = newID
 	billing.CreatedAt = time.Now()
 	billing.UpdatedAt = time.Now()
 
-	log.Printf("Creating organization billing: %+v", billing)
+	slog.Info("Creating organization billing", "billing_id", billing.ID, "organization_id", billing.OrganizationID)
 
-	if err := s.repo.Create(billing); err != nil {
-		return nil, fmt.Errorf("failed to create billing: %v", err)
+	if err := s.repo.Create(ctx, billing); err != nil {
+		return nil, fmt.Errorf("failed to create billing: %w", err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &billing.OrganizationID,
+		Action:         "organization_billing.create",
+		TargetType:     model.AuditTargetOrganizationBilling,
+		TargetID:       &billing.ID,
+		Changes:        audit.Diff(nil, billing),
+	})
+
 	return billing, nil
 }
 
 // UpdateBilling updates an existing billing record
-func (s *organizationBillingServiceImpl) UpdateBilling(billing *model.OrganizationBilling) error {
+func (s *organizationBillingServiceImpl) UpdateBilling(ctx context.Context, billing *model.OrganizationBilling) error {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.UpdateBilling")
+	defer span.End()
+
 	billing.UpdatedAt = time.Now()
 
 	// Check if billing exists
-	_, err := s.repo.GetByID(billing.ID)
+	existing, err := s.repo.GetByID(ctx, billing.ID)
 	if err != nil {
-		return fmt.Errorf("billing not found with ID %s: %v", billing.ID, err)
+		return fmt.Errorf("billing not found with ID %s: %w", billing.ID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Update(billing); err != nil {
-		return fmt.Errorf("failed to update billing with ID %s: %v", billing.ID, err)
+	if err := s.repo.Update(ctx, billing); err != nil {
+		return fmt.Errorf("failed to update billing with ID %s: %w", billing.ID, err)
 	}
 
-	log.Printf("Organization billing updated: %+v", billing)
+	// Diff against the stored record: the request may leave fields out
+	updated, err := s.repo.GetByID(ctx, billing.ID)
+	if err != nil {
+		updated = billing
+	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_billing.update",
+		TargetType:     model.AuditTargetOrganizationBilling,
+		TargetID:       &billing.ID,
+		Changes:        audit.Diff(existing, updated),
+	})
+
+	slog.Info("Organization billing updated", "billing_id", billing.ID, "organization_id", billing.OrganizationID)
 	return nil
 }
 
 // DeleteBilling performs a soft delete
-func (s *organizationBillingServiceImpl) DeleteBilling(billingID uuid.UUID) error {
+func (s *organizationBillingServiceImpl) DeleteBilling(ctx context.Context, billingID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.DeleteBilling")
+	defer span.End()
+
 	// Check if billing exists
-	_, err := s.repo.GetByID(billingID)
+	existing, err := s.repo.GetByID(ctx, billingID)
 	if err != nil {
-		return fmt.Errorf("billing not found with ID %s: %v", billingID, err)
+		return fmt.Errorf("billing not found with ID %s: %w", billingID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Delete(billingID); err != nil {
-		return fmt.Errorf("failed to delete billing with ID %s: %v", billingID, err)
+	if err := s.repo.Delete(ctx, billingID); err != nil {
+		return fmt.Errorf("failed to delete billing with ID %s: %w", billingID, err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_billing.delete",
+		TargetType:     model.AuditTargetOrganizationBilling,
+		TargetID:       &billingID,
+		Changes:        audit.Diff(existing, nil),
+	})
+
 	log.Printf("Organization billing deleted: %v", billingID)
 	return nil
 }
 
+// RestoreBilling undoes a soft delete
+func (s *organizationBillingServiceImpl) RestoreBilling(ctx context.Context, billingID uuid.UUID) (*model.OrganizationBilling, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.RestoreBilling")
+	defer span.End()
+
+	if err := s.repo.Restore(ctx, billingID); err != nil {
+		return nil, fmt.Errorf("failed to restore billing with ID %s: %w", billingID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, billingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored billing with ID %s: %w", billingID, err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &restored.OrganizationID,
+		Action:         "organization_billing.restore",
+		TargetType:     model.AuditTargetOrganizationBilling,
+		TargetID:       &billingID,
+		Changes:        audit.Diff(nil, restored),
+	})
+
+	log.Printf("Organization billing restored: %v", billingID)
+	return restored, nil
+}
+
 // GetBillingByID retrieves a single billing record by ID
-func (s *organizationBillingServiceImpl) GetBillingByID(billingID uuid.UUID) (*model.OrganizationBilling, error) {
-	billing, err := s.repo.GetByID(billingID)
+func (s *organizationBillingServiceImpl) GetBillingByID(ctx context.Context, billingID uuid.UUID) (*model.OrganizationBilling, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.GetBillingByID")
+	defer span.End()
+
+	billing, err := s.repo.GetByID(ctx, billingID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get billing by ID %s: %v", billingID, err)
+		return nil, fmt.Errorf("failed to get billing by ID %s: %w", billingID, err)
+	}
+	if err := tenant.Check(ctx, &billing.OrganizationID); err != nil {
+		return nil, err
 	}
 	return billing, nil
 }
 
-// GetAllBillings retrieves all billing records
-func (s *organizationBillingServiceImpl) GetAllBillings() ([]*model.OrganizationBilling, error) {
-	billings, err := s.repo.GetAll()
+// ListBillings retrieves one page of billing records matching the filter
+func (s *organizationBillingServiceImpl) ListBillings(ctx context.Context, filter model.OrganizationBillingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBilling], error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBillingService.ListBillings")
+	defer span.End()
+
+	orgID, err := tenant.Filter(ctx, filter.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	filter.OrganizationID = orgID
+
+	billings, err := s.repo.List(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all billings: %v", err)
+		return nil, fmt.Errorf("failed to list billings: %w", err)
 	}
 	return billings, nil
 }
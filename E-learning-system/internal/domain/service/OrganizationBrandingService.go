@@ -1,10 +1,16 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tenant"
+	"e-learning-system/internal/tracing"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -12,93 +18,248 @@ import (
 
 // OrganizationBrandingService interface with CRUD methods
 type OrganizationBrandingService interface {
-	CreateBranding(branding *model.OrganizationBranding) (*model.OrganizationBranding, error)
-	UpdateBranding(branding *model.OrganizationBranding) error
-	DeleteBranding(brandingID uuid.UUID) error
-	GetBrandingByID(brandingID uuid.UUID) (*model.OrganizationBranding, error)
-	GetAllBrandings() ([]*model.OrganizationBranding, error)
+	CreateBranding(ctx context.Context, branding *model.OrganizationBranding) (*model.OrganizationBranding, error)
+	UpdateBranding(ctx context.Context, branding *model.OrganizationBranding) error
+	DeleteBranding(ctx context.Context, brandingID uuid.UUID) error
+	// RestoreBranding brings back a soft-deleted branding until the retention job has purged it
+	RestoreBranding(ctx context.Context, brandingID uuid.UUID) (*model.OrganizationBranding, error)
+	GetBrandingByID(ctx context.Context, brandingID uuid.UUID) (*model.OrganizationBranding, error)
+	ListBrandings(ctx context.Context, filter model.OrganizationBrandingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBranding], error)
+
+	// Public theming
+	GetBrandingManifest(ctx context.Context, domain string) (*model.BrandingManifest, error)
+	RenderThemeCSS(manifest *model.BrandingManifest) string
+
+	// Logo upload
+	UploadLogo(ctx context.Context, brandingID, ownerID uuid.UUID, fileName string, r io.Reader) (*model.OrganizationBranding, error)
 }
 
 // organizationBrandingServiceImpl struct implementing OrganizationBrandingService
 type organizationBrandingServiceImpl struct {
-	repo repository.OrganizationBrandingRepository
+	repo   repository.OrganizationBrandingRepository
+	assets AssetService
+	audit  AuditService
 }
 
 // Constructor
-func NewOrganizationBrandingService(brandingRepo repository.OrganizationBrandingRepository) OrganizationBrandingService {
+func NewOrganizationBrandingService(brandingRepo repository.OrganizationBrandingRepository, assetService AssetService, auditService AuditService) OrganizationBrandingService {
 	return &organizationBrandingServiceImpl{
-		repo: brandingRepo,
+		repo:   brandingRepo,
+		assets: assetService,
+		audit:  auditService,
 	}
 }
 
 // CreateBranding creates a new branding record
-func (s *organizationBrandingServiceImpl) CreateBranding(branding *model.OrganizationBranding) (*model.OrganizationBranding, error) {
+func (s *organizationBrandingServiceImpl) CreateBranding(ctx context.Context, branding *model.OrganizationBranding) (*model.OrganizationBranding, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.CreateBranding")
+	defer span.End()
+
+	if err := tenant.Check(ctx, &branding.OrganizationID); err != nil {
+		return nil, err
+	}
+
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	if err := ValidateBranding(branding); err != nil {
+		return nil, err
 	}
 
 	branding.ID = newID
 	branding.CreatedAt = time.Now()
 	branding.UpdatedAt = time.Now()
 
-	log.Printf("Creating organization branding: %+v", branding)
+	slog.Info("Creating organization branding", "branding_id", branding.ID, "organization_id", branding.OrganizationID)
 
-	if err := s.repo.Create(branding); err != nil {
-		return nil, fmt.Errorf("failed to create branding: %v", err)
+	if err := s.repo.Create(ctx, branding); err != nil {
+		return nil, fmt.Errorf("failed to create branding: %w", err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &branding.OrganizationID,
+		Action:         "organization_branding.create",
+		TargetType:     model.AuditTargetOrganizationBranding,
+		TargetID:       &branding.ID,
+		Changes:        audit.Diff(nil, branding),
+	})
+
 	return branding, nil
 }
 
 // UpdateBranding updates an existing branding record
-func (s *organizationBrandingServiceImpl) UpdateBranding(branding *model.OrganizationBranding) error {
+func (s *organizationBrandingServiceImpl) UpdateBranding(ctx context.Context, branding *model.OrganizationBranding) error {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.UpdateBranding")
+	defer span.End()
+
+	if err := ValidateBranding(branding); err != nil {
+		return err
+	}
+
 	branding.UpdatedAt = time.Now()
 
 	// Check if branding exists
-	_, err := s.repo.GetByID(branding.ID)
+	existing, err := s.repo.GetByID(ctx, branding.ID)
 	if err != nil {
-		return fmt.Errorf("branding not found with ID %s: %v", branding.ID, err)
+		return fmt.Errorf("branding not found with ID %s: %w", branding.ID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, branding); err != nil {
+		return fmt.Errorf("failed to update branding with ID %s: %w", branding.ID, err)
 	}
 
-	if err := s.repo.Update(branding); err != nil {
-		return fmt.Errorf("failed to update branding with ID %s: %v", branding.ID, err)
+	// Diff against the stored record: the request may leave fields out
+	updated, err := s.repo.GetByID(ctx, branding.ID)
+	if err != nil {
+		updated = branding
 	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_branding.update",
+		TargetType:     model.AuditTargetOrganizationBranding,
+		TargetID:       &branding.ID,
+		Changes:        audit.Diff(existing, updated),
+	})
 
-	log.Printf("Organization branding updated: %+v", branding)
+	slog.Info("Organization branding updated", "branding_id", branding.ID, "organization_id", branding.OrganizationID)
 	return nil
 }
 
 // DeleteBranding performs a soft delete
-func (s *organizationBrandingServiceImpl) DeleteBranding(brandingID uuid.UUID) error {
+func (s *organizationBrandingServiceImpl) DeleteBranding(ctx context.Context, brandingID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.DeleteBranding")
+	defer span.End()
+
 	// Check if branding exists
-	_, err := s.repo.GetByID(brandingID)
+	existing, err := s.repo.GetByID(ctx, brandingID)
 	if err != nil {
-		return fmt.Errorf("branding not found with ID %s: %v", brandingID, err)
+		return fmt.Errorf("branding not found with ID %s: %w", brandingID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Delete(brandingID); err != nil {
-		return fmt.Errorf("failed to delete branding with ID %s: %v", brandingID, err)
+	if err := s.repo.Delete(ctx, brandingID); err != nil {
+		return fmt.Errorf("failed to delete branding with ID %s: %w", brandingID, err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_branding.delete",
+		TargetType:     model.AuditTargetOrganizationBranding,
+		TargetID:       &brandingID,
+		Changes:        audit.Diff(existing, nil),
+	})
+
 	log.Printf("Organization branding deleted: %v", brandingID)
 	return nil
 }
 
+// RestoreBranding undoes a soft delete
+func (s *organizationBrandingServiceImpl) RestoreBranding(ctx context.Context, brandingID uuid.UUID) (*model.OrganizationBranding, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.RestoreBranding")
+	defer span.End()
+
+	if err := s.repo.Restore(ctx, brandingID); err != nil {
+		return nil, fmt.Errorf("failed to restore branding with ID %s: %w", brandingID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, brandingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored branding with ID %s: %w", brandingID, err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &restored.OrganizationID,
+		Action:         "organization_branding.restore",
+		TargetType:     model.AuditTargetOrganizationBranding,
+		TargetID:       &brandingID,
+		Changes:        audit.Diff(nil, restored),
+	})
+
+	log.Printf("Organization branding restored: %v", brandingID)
+	return restored, nil
+}
+
 // GetBrandingByID retrieves a single branding record by ID
-func (s *organizationBrandingServiceImpl) GetBrandingByID(brandingID uuid.UUID) (*model.OrganizationBranding, error) {
-	branding, err := s.repo.GetByID(brandingID)
+func (s *organizationBrandingServiceImpl) GetBrandingByID(ctx context.Context, brandingID uuid.UUID) (*model.OrganizationBranding, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.GetBrandingByID")
+	defer span.End()
+
+	branding, err := s.repo.GetByID(ctx, brandingID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get branding by ID %s: %v", brandingID, err)
+		return nil, fmt.Errorf("failed to get branding by ID %s: %w", brandingID, err)
+	}
+	if err := tenant.Check(ctx, &branding.OrganizationID); err != nil {
+		return nil, err
 	}
 	return branding, nil
 }
 
-// GetAllBrandings retrieves all branding records
-func (s *organizationBrandingServiceImpl) GetAllBrandings() ([]*model.OrganizationBranding, error) {
-	brandings, err := s.repo.GetAll()
+// ListBrandings retrieves one page of branding records matching the filter
+func (s *organizationBrandingServiceImpl) ListBrandings(ctx context.Context, filter model.OrganizationBrandingFilter, page model.PageRequest) (*model.Page[*model.OrganizationBranding], error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.ListBrandings")
+	defer span.End()
+
+	orgID, err := tenant.Filter(ctx, filter.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	filter.OrganizationID = orgID
+
+	brandings, err := s.repo.List(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all brandings: %v", err)
+		return nil, fmt.Errorf("failed to list brandings: %w", err)
 	}
 	return brandings, nil
 }
+
+// UploadLogo stores a new logo for a branding and points LogoURL at it
+func (s *organizationBrandingServiceImpl) UploadLogo(ctx context.Context, brandingID, ownerID uuid.UUID, fileName string, r io.Reader) (*model.OrganizationBranding, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.UploadLogo")
+	defer span.End()
+
+	branding, err := s.repo.GetByID(ctx, brandingID)
+	if err != nil {
+		return nil, fmt.Errorf("branding not found with ID %s: %w", brandingID, err)
+	}
+	if err := tenant.Check(ctx, &branding.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	orgID := branding.OrganizationID
+	asset, err := s.assets.Upload(ctx, ownerID, &orgID, model.AssetPurposeLogo, fileName, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logos are public, so the URL is the stable redirect endpoint rather than an expiring link
+	oldLogoURL := branding.LogoURL
+	branding.LogoURL = LogoURL(asset.ID)
+	branding.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, branding); err != nil {
+		return nil, fmt.Errorf("failed to update branding logo: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &orgID,
+		Action:         "organization_branding.upload_logo",
+		TargetType:     model.AuditTargetOrganizationBranding,
+		TargetID:       &branding.ID,
+		Changes:        map[string]model.AuditChange{"LogoURL": {From: oldLogoURL, To: branding.LogoURL}},
+	})
+
+	log.Printf("Logo %v uploaded for branding %v", asset.ID, brandingID)
+	return branding, nil
+}
+
+// LogoURL returns the public URL that serves a logo asset
+func LogoURL(assetID uuid.UUID) string {
+	return fmt.Sprintf("/assets/%s/content", assetID)
+}
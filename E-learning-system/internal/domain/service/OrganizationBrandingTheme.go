@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"strings"
+)
+
+// MinBrandingContrast is the WCAG AA ratio for large text and UI components,
+// required between each brand color and the theme background.
+const MinBrandingContrast = 3.0
+
+// ErrInvalidBranding is wrapped by every branding validation failure
+var ErrInvalidBranding = apperr.Validation("invalid_branding", "invalid branding")
+
+// themePalette holds the base colors of a theme
+type themePalette struct {
+	Background string
+	Surface    string
+	Text       string
+}
+
+var themePalettes = map[string]themePalette{
+	model.ThemeLight: {Background: "#ffffff", Surface: "#f5f5f5", Text: "#1a1a1a"},
+	model.ThemeDark:  {Background: "#121212", Surface: "#1e1e1e", Text: "#f5f5f5"},
+}
+
+// Default brand colors used when an organization has not configured any
+const (
+	defaultPrimaryColor   = "#1f6feb"
+	defaultSecondaryColor = "#6e40c9"
+)
+
+// ValidateBranding checks the theme, the color format and the contrast of
+// PrimaryColor and SecondaryColor against the theme background.
+func ValidateBranding(branding *model.OrganizationBranding) error {
+	if branding.Theme == "" {
+		branding.Theme = model.ThemeLight
+	}
+	if branding.Theme != model.ThemeLight && branding.Theme != model.ThemeDark && branding.Theme != model.ThemeCustom {
+		return fmt.Errorf("%w: theme must be one of light, dark, custom", ErrInvalidBranding.WithFields(
+			apperr.FieldError{Field: "theme", Message: "must be one of light, dark, custom"}))
+	}
+
+	// Custom themes follow the user's color scheme, so colors must work on both backgrounds
+	backgrounds := []string{model.ThemeLight, model.ThemeDark}
+	if branding.Theme != model.ThemeCustom {
+		backgrounds = []string{branding.Theme}
+	}
+
+	colors := []struct {
+		field string
+		value *string
+	}{
+		{"primary_color", &branding.PrimaryColor},
+		{"secondary_color", &branding.SecondaryColor},
+	}
+	for _, c := range colors {
+		if *c.value == "" {
+			continue
+		}
+		rgb, err := utils.ParseHexColor(*c.value)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBranding.WithFields(
+				apperr.FieldError{Field: c.field, Message: err.Error()}), c.field, err)
+		}
+		*c.value = rgb.Hex()
+
+		for _, theme := range backgrounds {
+			bg, _ := utils.ParseHexColor(themePalettes[theme].Background)
+			if ratio := utils.ContrastRatio(rgb, bg); ratio < MinBrandingContrast {
+				msg := fmt.Sprintf("has contrast %.2f:1 against the %s background, minimum is %.1f:1", ratio, theme, MinBrandingContrast)
+				return fmt.Errorf("%w: %s %s %s", ErrInvalidBranding.WithFields(
+					apperr.FieldError{Field: c.field, Message: msg}), c.field, rgb.Hex(), msg)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetBrandingManifest returns the public branding of the organization serving the given domain
+func (s *organizationBrandingServiceImpl) GetBrandingManifest(ctx context.Context, domain string) (*model.BrandingManifest, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationBrandingService.GetBrandingManifest")
+	defer span.End()
+
+	manifest, err := s.repo.GetManifestByDomain(ctx, strings.ToLower(strings.TrimSpace(domain)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get branding for domain %s: %w", domain, err)
+	}
+
+	manifest.PrimaryColor = normalizeColor(manifest.PrimaryColor, defaultPrimaryColor)
+	manifest.SecondaryColor = normalizeColor(manifest.SecondaryColor, defaultSecondaryColor)
+	if _, ok := themePalettes[manifest.Theme]; !ok && manifest.Theme != model.ThemeCustom {
+		manifest.Theme = model.ThemeLight
+	}
+	manifest.StylesheetURL = fmt.Sprintf("/branding/%s/theme.css", manifest.Domain)
+
+	return manifest, nil
+}
+
+// RenderThemeCSS renders the CSS custom properties for a branding manifest
+func (s *organizationBrandingServiceImpl) RenderThemeCSS(manifest *model.BrandingManifest) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "/* Theme for %s */\n", cssComment(manifest.Name))
+
+	switch manifest.Theme {
+	case model.ThemeDark:
+		writeThemeBlock(&b, ":root", manifest, themePalettes[model.ThemeDark])
+	case model.ThemeCustom:
+		writeThemeBlock(&b, ":root", manifest, themePalettes[model.ThemeLight])
+		b.WriteString("@media (prefers-color-scheme: dark) {\n")
+		writeThemeBlock(&b, ":root", manifest, themePalettes[model.ThemeDark])
+		b.WriteString("}\n")
+	default:
+		writeThemeBlock(&b, ":root", manifest, themePalettes[model.ThemeLight])
+	}
+
+	return b.String()
+}
+
+func writeThemeBlock(b *strings.Builder, selector string, m *model.BrandingManifest, p themePalette) {
+	primary, _ := utils.ParseHexColor(m.PrimaryColor)
+	secondary, _ := utils.ParseHexColor(m.SecondaryColor)
+
+	fmt.Fprintf(b, "%s {\n", selector)
+	fmt.Fprintf(b, "  --brand-primary: %s;\n", primary.Hex())
+	fmt.Fprintf(b, "  --brand-primary-contrast: %s;\n", utils.ReadableTextColor(primary).Hex())
+	fmt.Fprintf(b, "  --brand-secondary: %s;\n", secondary.Hex())
+	fmt.Fprintf(b, "  --brand-secondary-contrast: %s;\n", utils.ReadableTextColor(secondary).Hex())
+	fmt.Fprintf(b, "  --brand-background: %s;\n", p.Background)
+	fmt.Fprintf(b, "  --brand-surface: %s;\n", p.Surface)
+	fmt.Fprintf(b, "  --brand-text: %s;\n", p.Text)
+	if m.LogoURL != "" {
+		fmt.Fprintf(b, "  --brand-logo-url: url(\"%s\");\n", cssString(m.LogoURL))
+	}
+	b.WriteString("}\n")
+}
+
+// normalizeColor returns the color as #rrggbb, or the fallback when it is not a valid color
+func normalizeColor(value, fallback string) string {
+	rgb, err := utils.ParseHexColor(value)
+	if err != nil {
+		return fallback
+	}
+	return rgb.Hex()
+}
+
+// cssString escapes a value for use inside a double-quoted CSS string
+func cssString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\%x ", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// cssComment strips anything that could terminate a CSS comment
+func cssComment(s string) string {
+	return strings.ReplaceAll(s, "*/", "")
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateBranding(t *testing.T) {
+	tests := []struct {
+		name      string
+		branding  model.OrganizationBranding
+		wantField string // empty when valid
+		want      model.OrganizationBranding
+	}{
+		{"defaults to light", model.OrganizationBranding{PrimaryColor: "#1F6FEB"}, "",
+			model.OrganizationBranding{Theme: model.ThemeLight, PrimaryColor: "#1f6feb"}},
+		{"no colors", model.OrganizationBranding{Theme: model.ThemeDark}, "",
+			model.OrganizationBranding{Theme: model.ThemeDark}},
+		{"short colors expanded", model.OrganizationBranding{Theme: model.ThemeLight, PrimaryColor: "#00f", SecondaryColor: "#000"}, "",
+			model.OrganizationBranding{Theme: model.ThemeLight, PrimaryColor: "#0000ff", SecondaryColor: "#000000"}},
+		{"readable on both backgrounds", model.OrganizationBranding{Theme: model.ThemeCustom, PrimaryColor: "#1f6feb", SecondaryColor: "#e8590c"}, "",
+			model.OrganizationBranding{Theme: model.ThemeCustom, PrimaryColor: "#1f6feb", SecondaryColor: "#e8590c"}},
+		{"unknown theme", model.OrganizationBranding{Theme: "neon"}, "theme", model.OrganizationBranding{}},
+		{"not a color", model.OrganizationBranding{PrimaryColor: "blue"}, "primary_color", model.OrganizationBranding{}},
+		{"bad secondary", model.OrganizationBranding{PrimaryColor: "#1f6feb", SecondaryColor: "#12345"}, "secondary_color", model.OrganizationBranding{}},
+		{"yellow on light", model.OrganizationBranding{Theme: model.ThemeLight, PrimaryColor: "#ffff00"}, "primary_color", model.OrganizationBranding{}},
+		{"yellow on dark", model.OrganizationBranding{Theme: model.ThemeDark, PrimaryColor: "#ffff00"}, "",
+			model.OrganizationBranding{Theme: model.ThemeDark, PrimaryColor: "#ffff00"}},
+		{"navy on dark", model.OrganizationBranding{Theme: model.ThemeDark, SecondaryColor: "#000080"}, "secondary_color", model.OrganizationBranding{}},
+		{"custom needs both backgrounds", model.OrganizationBranding{Theme: model.ThemeCustom, PrimaryColor: "#0000ff"}, "primary_color", model.OrganizationBranding{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branding := tt.branding
+			err := ValidateBranding(&branding)
+			if tt.wantField != "" {
+				appErr, ok := apperr.As(err)
+				if !errors.Is(err, ErrInvalidBranding) || !ok {
+					t.Fatalf("error = %v, want ErrInvalidBranding", err)
+				}
+				if len(appErr.Fields) != 1 || appErr.Fields[0].Field != tt.wantField {
+					t.Errorf("fields = %+v, want %s", appErr.Fields, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateBranding: %v", err)
+			}
+			if branding.Theme != tt.want.Theme || branding.PrimaryColor != tt.want.PrimaryColor || branding.SecondaryColor != tt.want.SecondaryColor {
+				t.Errorf("branding = %s %s %s, want %s %s %s", branding.Theme, branding.PrimaryColor, branding.SecondaryColor,
+					tt.want.Theme, tt.want.PrimaryColor, tt.want.SecondaryColor)
+			}
+		})
+	}
+}
+
+// fakeManifestRepo serves one manifest by domain
+type fakeManifestRepo struct {
+	repository.OrganizationBrandingRepository
+	manifest model.BrandingManifest
+}
+
+func (r *fakeManifestRepo) GetManifestByDomain(_ context.Context, domain string) (*model.BrandingManifest, error) {
+	if domain != r.manifest.Domain {
+		return nil, apperr.NotFound("branding_not_found", "branding not found")
+	}
+	copied := r.manifest
+	return &copied, nil
+}
+
+func TestGetBrandingManifest(t *testing.T) {
+	tests := []struct {
+		name          string
+		stored        model.BrandingManifest
+		wantPrimary   string
+		wantSecondary string
+		wantTheme     string
+	}{
+		{"configured", model.BrandingManifest{PrimaryColor: "#ABC", SecondaryColor: "#e8590c", Theme: model.ThemeDark},
+			"#aabbcc", "#e8590c", model.ThemeDark},
+		{"nothing configured", model.BrandingManifest{}, defaultPrimaryColor, defaultSecondaryColor, model.ThemeLight},
+		{"invalid stored values", model.BrandingManifest{PrimaryColor: "blue", SecondaryColor: "#12", Theme: "neon"},
+			defaultPrimaryColor, defaultSecondaryColor, model.ThemeLight},
+		{"custom", model.BrandingManifest{Theme: model.ThemeCustom}, defaultPrimaryColor, defaultSecondaryColor, model.ThemeCustom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.stored
+			stored.Domain = "learn.example.com"
+			s := &organizationBrandingServiceImpl{repo: &fakeManifestRepo{manifest: stored}}
+
+			m, err := s.GetBrandingManifest(context.Background(), " Learn.Example.COM ")
+			if err != nil {
+				t.Fatalf("GetBrandingManifest: %v", err)
+			}
+			if m.PrimaryColor != tt.wantPrimary || m.SecondaryColor != tt.wantSecondary || m.Theme != tt.wantTheme {
+				t.Errorf("manifest = %s %s %s, want %s %s %s", m.PrimaryColor, m.SecondaryColor, m.Theme,
+					tt.wantPrimary, tt.wantSecondary, tt.wantTheme)
+			}
+			if m.StylesheetURL != "/branding/learn.example.com/theme.css" {
+				t.Errorf("stylesheet URL = %q", m.StylesheetURL)
+			}
+		})
+	}
+
+	s := &organizationBrandingServiceImpl{repo: &fakeManifestRepo{manifest: model.BrandingManifest{Domain: "learn.example.com"}}}
+	if _, err := s.GetBrandingManifest(context.Background(), "other.example.com"); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Errorf("unknown domain error = %v, want not found", err)
+	}
+}
+
+func TestRenderThemeCSS(t *testing.T) {
+	s := &organizationBrandingServiceImpl{}
+	base := model.BrandingManifest{Name: "Acme", PrimaryColor: "#ffff00", SecondaryColor: "#1a237e"}
+
+	tests := []struct {
+		name     string
+		edit     func(m *model.BrandingManifest)
+		contains []string
+		excludes []string
+	}{
+		{"light", func(m *model.BrandingManifest) { m.Theme = model.ThemeLight }, []string{
+			"/* Theme for Acme */",
+			"--brand-primary: #ffff00;",
+			"--brand-primary-contrast: #000000;",
+			"--brand-secondary-contrast: #ffffff;",
+			"--brand-background: #ffffff;",
+		}, []string{"prefers-color-scheme", "--brand-logo-url"}},
+		{"dark", func(m *model.BrandingManifest) { m.Theme = model.ThemeDark }, []string{
+			"--brand-background: #121212;",
+		}, []string{"--brand-background: #ffffff;", "prefers-color-scheme"}},
+		{"custom", func(m *model.BrandingManifest) { m.Theme = model.ThemeCustom }, []string{
+			"--brand-background: #ffffff;",
+			"@media (prefers-color-scheme: dark) {\n:root {\n",
+			"--brand-background: #121212;",
+		}, nil},
+		{"logo", func(m *model.BrandingManifest) { m.LogoURL = `https://cdn.example.com/logo.png?a="b"\` }, []string{
+			`--brand-logo-url: url("https://cdn.example.com/logo.png?a=\"b\"\\");`,
+		}, nil},
+		{"logo with a newline", func(m *model.BrandingManifest) { m.LogoURL = "https://cdn.example.com/a\n}body{" }, []string{
+			`url("https://cdn.example.com/a\a }body{")`,
+		}, []string{"a\n}"}},
+		{"name closing the comment", func(m *model.BrandingManifest) { m.Name = "Acme */ :root { --x: 1; } /*" }, []string{
+			"/* Theme for Acme  :root { --x: 1; } /* */",
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base
+			tt.edit(&m)
+			css := s.RenderThemeCSS(&m)
+			for _, want := range tt.contains {
+				if !strings.Contains(css, want) {
+					t.Errorf("CSS lacks %q:\n%s", want, css)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(css, unwanted) {
+					t.Errorf("CSS contains %q:\n%s", unwanted, css)
+				}
+			}
+		})
+	}
+}
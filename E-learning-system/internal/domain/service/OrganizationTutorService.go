@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tenant"
+	"e-learning-system/internal/tracing"
 	"fmt"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -12,90 +17,183 @@ import (
 
 // OrganizationTutorService interface with CRUD methods
 type OrganizationTutorService interface {
-	CreateTutor(tutor *model.OrganizationTutor) (*model.OrganizationTutor, error)
-	UpdateTutor(tutor *model.OrganizationTutor) error
-	DeleteTutor(tutorID uuid.UUID) error
-	GetTutorByID(tutorID uuid.UUID) (*model.OrganizationTutor, error)
-	GetAllTutors() ([]*model.OrganizationTutor, error)
+	CreateTutor(ctx context.Context, tutor *model.OrganizationTutor) (*model.OrganizationTutor, error)
+	UpdateTutor(ctx context.Context, tutor *model.OrganizationTutor) error
+	DeleteTutor(ctx context.Context, tutorID uuid.UUID) error
+	// RestoreTutor brings back a soft-deleted tutor until the retention job has purged it
+	RestoreTutor(ctx context.Context, tutorID uuid.UUID) (*model.OrganizationTutor, error)
+	GetTutorByID(ctx context.Context, tutorID uuid.UUID) (*model.OrganizationTutor, error)
+	ListTutors(ctx context.Context, filter model.OrganizationTutorFilter, page model.PageRequest) (*model.Page[*model.OrganizationTutor], error)
 }
 
 // organizationTutorServiceImpl struct implementing OrganizationTutorService
 type organizationTutorServiceImpl struct {
-	repo repository.OrganizationTutorRepository
+	repo  repository.OrganizationTutorRepository
+	audit AuditService
 }
 
 // Constructor
-func NewOrganizationTutorService(tutorRepo repository.OrganizationTutorRepository) OrganizationTutorService {
+func NewOrganizationTutorService(tutorRepo repository.OrganizationTutorRepository, auditService AuditService) OrganizationTutorService {
 	return &organizationTutorServiceImpl{
-		repo: tutorRepo,
+		repo:  tutorRepo,
+		audit: auditService,
 	}
 }
 
 // CreateTutor creates a new tutor
-func (s *organizationTutorServiceImpl) CreateTutor(tutor *model.OrganizationTutor) (*model.OrganizationTutor, error) {
+func (s *organizationTutorServiceImpl) CreateTutor(ctx context.Context, tutor *model.OrganizationTutor) (*model.OrganizationTutor, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.CreateTutor")
+	defer span.End()
+
+	if err := tenant.Check(ctx, &tutor.OrganizationID); err != nil {
+		return nil, err
+	}
+
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
 	tutor.ID = newID
 	tutor.CreatedAt = time.Now()
 
-	log.Printf("Creating organization tutor: %+v", tutor)
+	slog.Info("Creating organization tutor", "tutor_id", tutor.ID, "organization_id", tutor.OrganizationID)
 
-	if err := s.repo.Create(tutor); err != nil {
-		return nil, fmt.Errorf("failed to create tutor: %v", err)
+	if err := s.repo.Create(ctx, tutor); err != nil {
+		return nil, fmt.Errorf("failed to create tutor: %w", err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &tutor.OrganizationID,
+		Action:         "organization_tutor.create",
+		TargetType:     model.AuditTargetOrganizationTutor,
+		TargetID:       &tutor.ID,
+		Changes:        audit.Diff(nil, tutor),
+	})
+
 	return tutor, nil
 }
 
 // UpdateTutor updates an existing tutor
-func (s *organizationTutorServiceImpl) UpdateTutor(tutor *model.OrganizationTutor) error {
+func (s *organizationTutorServiceImpl) UpdateTutor(ctx context.Context, tutor *model.OrganizationTutor) error {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.UpdateTutor")
+	defer span.End()
+
 	// Check if tutor exists
-	_, err := s.repo.GetByID(tutor.ID)
+	existing, err := s.repo.GetByID(ctx, tutor.ID)
 	if err != nil {
-		return fmt.Errorf("tutor not found with ID %s: %v", tutor.ID, err)
+		return fmt.Errorf("tutor not found with ID %s: %w", tutor.ID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Update(tutor); err != nil {
-		return fmt.Errorf("failed to update tutor with ID %s: %v", tutor.ID, err)
+	if err := s.repo.Update(ctx, tutor); err != nil {
+		return fmt.Errorf("failed to update tutor with ID %s: %w", tutor.ID, err)
 	}
 
-	log.Printf("Organization tutor updated: %+v", tutor)
+	// Diff against the stored record: the request may leave fields out
+	updated, err := s.repo.GetByID(ctx, tutor.ID)
+	if err != nil {
+		updated = tutor
+	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_tutor.update",
+		TargetType:     model.AuditTargetOrganizationTutor,
+		TargetID:       &tutor.ID,
+		Changes:        audit.Diff(existing, updated),
+	})
+
+	slog.Info("Organization tutor updated", "tutor_id", tutor.ID, "organization_id", tutor.OrganizationID)
 	return nil
 }
 
 // DeleteTutor performs a soft delete
-func (s *organizationTutorServiceImpl) DeleteTutor(tutorID uuid.UUID) error {
+func (s *organizationTutorServiceImpl) DeleteTutor(ctx context.Context, tutorID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.DeleteTutor")
+	defer span.End()
+
 	// Check if tutor exists
-	_, err := s.repo.GetByID(tutorID)
+	existing, err := s.repo.GetByID(ctx, tutorID)
 	if err != nil {
-		return fmt.Errorf("tutor not found with ID %s: %v", tutorID, err)
+		return fmt.Errorf("tutor not found with ID %s: %w", tutorID, err)
+	}
+	if err := tenant.Check(ctx, &existing.OrganizationID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Delete(tutorID); err != nil {
-		return fmt.Errorf("failed to delete tutor with ID %s: %v", tutorID, err)
+	if err := s.repo.Delete(ctx, tutorID); err != nil {
+		return fmt.Errorf("failed to delete tutor with ID %s: %w", tutorID, err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_tutor.delete",
+		TargetType:     model.AuditTargetOrganizationTutor,
+		TargetID:       &tutorID,
+		Changes:        audit.Diff(existing, nil),
+	})
+
 	log.Printf("Organization tutor deleted: %v", tutorID)
 	return nil
 }
 
+// RestoreTutor undoes a soft delete
+func (s *organizationTutorServiceImpl) RestoreTutor(ctx context.Context, tutorID uuid.UUID) (*model.OrganizationTutor, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.RestoreTutor")
+	defer span.End()
+
+	if err := s.repo.Restore(ctx, tutorID); err != nil {
+		return nil, fmt.Errorf("failed to restore tutor with ID %s: %w", tutorID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, tutorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored tutor with ID %s: %w", tutorID, err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &restored.OrganizationID,
+		Action:         "organization_tutor.restore",
+		TargetType:     model.AuditTargetOrganizationTutor,
+		TargetID:       &tutorID,
+		Changes:        audit.Diff(nil, restored),
+	})
+
+	log.Printf("Organization tutor restored: %v", tutorID)
+	return restored, nil
+}
+
 // GetTutorByID retrieves a single tutor by ID
-func (s *organizationTutorServiceImpl) GetTutorByID(tutorID uuid.UUID) (*model.OrganizationTutor, error) {
-	tutor, err := s.repo.GetByID(tutorID)
+func (s *organizationTutorServiceImpl) GetTutorByID(ctx context.Context, tutorID uuid.UUID) (*model.OrganizationTutor, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.GetTutorByID")
+	defer span.End()
+
+	tutor, err := s.repo.GetByID(ctx, tutorID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tutor by ID %s: %v", tutorID, err)
+		return nil, fmt.Errorf("failed to get tutor by ID %s: %w", tutorID, err)
+	}
+	if err := tenant.Check(ctx, &tutor.OrganizationID); err != nil {
+		return nil, err
 	}
 	return tutor, nil
 }
 
-// GetAllTutors retrieves all organization tutors
-func (s *organizationTutorServiceImpl) GetAllTutors() ([]*model.OrganizationTutor, error) {
-	tutors, err := s.repo.GetAll()
+// ListTutors retrieves one page of organization tutors matching the filter
+func (s *organizationTutorServiceImpl) ListTutors(ctx context.Context, filter model.OrganizationTutorFilter, page model.PageRequest) (*model.Page[*model.OrganizationTutor], error) {
+	ctx, span := tracing.Start(ctx, "OrganizationTutorService.ListTutors")
+	defer span.End()
+
+	orgID, err := tenant.Filter(ctx, filter.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	filter.OrganizationID = orgID
+
+	tutors, err := s.repo.List(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all tutors: %v", err)
+		return nil, fmt.Errorf("failed to list tutors: %w", err)
 	}
 	return tutors, nil
 }
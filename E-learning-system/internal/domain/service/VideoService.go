@@ -0,0 +1,813 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/media"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxVideoSize is the largest lesson video accepted
+const MaxVideoSize = 5 << 30 // 5 GiB
+
+// VideoUploadExpiry is how long an unfinished upload can be resumed
+const VideoUploadExpiry = 24 * time.Hour
+
+// VideoClaimTimeout is how long a video may stay in processing before
+// another run takes it over from a worker presumed dead
+const VideoClaimTimeout = 6 * time.Hour
+
+// PlaybackTokenExpiry is how long a playback URL stays valid
+const PlaybackTokenExpiry = 2 * time.Hour
+
+// videoExtensions gives stored sources an extension so files serve with the right type
+var videoExtensions = map[string]string{
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/avi":       ".avi",
+	"video/quicktime": ".mov",
+}
+
+var (
+	ErrInvalidVideoUpload  = apperr.Validation("invalid_video_upload", "invalid video upload")
+	ErrVideoUploadNotFound = apperr.NotFound("video_upload_not_found", "video upload not found")
+	ErrVideoUploadExpired  = apperr.New(apperr.KindGone, "video_upload_expired", "video upload has expired")
+	ErrVideoUploadOffset   = apperr.Conflict("upload_offset_mismatch", "upload offset does not match")
+	ErrVideoUnsupported    = apperr.New(apperr.KindUnsupportedMedia, "video_unsupported", "file is not a supported video")
+	ErrVideoNotReady       = apperr.Conflict("video_not_ready", "video is not ready for playback")
+	ErrVideoAccessDenied   = apperr.Forbidden("video_access_denied", "access to this video is not allowed")
+)
+
+// VideoPlayback is what a player needs to stream a lesson video
+type VideoPlayback struct {
+	LessonID     uuid.UUID `json:"lesson_id"`
+	Type         string    `json:"type"` // hls or progressive
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	Duration     float64   `json:"duration,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// StreamResponse is either an inline playlist body or a redirect to a signed file URL
+type StreamResponse struct {
+	Body        []byte
+	ContentType string
+	Redirect    string
+}
+
+// VideoService handles lesson video uploads, processing and playback
+type VideoService interface {
+	CreateUpload(ctx context.Context, lessonID, ownerID uuid.UUID, length int64, fileName string) (*model.VideoUpload, error)
+	GetUpload(ctx context.Context, uploadID, ownerID uuid.UUID) (*model.VideoUpload, error)
+	AppendChunk(ctx context.Context, uploadID, ownerID uuid.UUID, offset int64, r io.Reader) (*model.VideoUpload, error)
+	DeleteUpload(ctx context.Context, uploadID, ownerID uuid.UUID) error
+
+	PendingVideoCount(ctx context.Context) (int, error)
+	ReleaseStaleVideoClaims(ctx context.Context) (int, error)
+	ProcessPendingVideos(ctx context.Context) error
+
+	Playback(ctx context.Context, lessonID, userID uuid.UUID, streamBaseURL string) (*VideoPlayback, error)
+	StreamFile(ctx context.Context, lessonID uuid.UUID, file, token string) (*StreamResponse, error)
+}
+
+type videoServiceImpl struct {
+	courseRepo     repository.CourseRepository
+	lessonRepo     repository.LessonRepository
+	enrollmentRepo repository.EnrollmentRepository
+	uploadRepo     repository.VideoUploadRepository
+	store          storage.Storage
+	uploadDir      string
+	signingKey     []byte
+
+	// Serializes chunks of the same upload within this process
+	uploadLocks keyedMutex
+}
+
+// Constructor
+func NewVideoService(
+	courseRepo repository.CourseRepository,
+	lessonRepo repository.LessonRepository,
+	enrollmentRepo repository.EnrollmentRepository,
+	uploadRepo repository.VideoUploadRepository,
+	store storage.Storage,
+	uploadDir string,
+	signingKey string,
+) VideoService {
+	return &videoServiceImpl{
+		courseRepo:     courseRepo,
+		lessonRepo:     lessonRepo,
+		enrollmentRepo: enrollmentRepo,
+		uploadRepo:     uploadRepo,
+		store:          store,
+		uploadDir:      uploadDir,
+		signingKey:     []byte(signingKey),
+	}
+}
+
+// CreateUpload opens a resumable upload session for a lesson video.
+// Only the course creator may upload.
+func (s *videoServiceImpl) CreateUpload(ctx context.Context, lessonID, ownerID uuid.UUID, length int64, fileName string) (*model.VideoUpload, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.CreateUpload")
+	defer span.End()
+
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: upload length is required", ErrInvalidVideoUpload)
+	}
+	if length > MaxVideoSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrAssetTooLarge, int64(MaxVideoSize))
+	}
+
+	lesson, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("lesson not found with ID %s: %w", lessonID, err)
+	}
+	course, err := s.courseRepo.GetByID(ctx, lesson.CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("course not found with ID %s: %w", lesson.CourseID, err)
+	}
+	if course.CreatedBy != ownerID {
+		return nil, ErrVideoAccessDenied
+	}
+	if lesson.VideoStatus == model.VideoProcessing {
+		return nil, fmt.Errorf("%w: the current video is still processing", ErrInvalidVideoUpload)
+	}
+
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	upload := &model.VideoUpload{
+		ID:        newID,
+		LessonID:  lessonID,
+		OwnerID:   ownerID,
+		FileName:  sanitizeFileName(fileName),
+		Length:    length,
+		ExpiresAt: time.Now().Add(VideoUploadExpiry),
+		CreatedAt: time.Now(),
+	}
+
+	if err := os.MkdirAll(s.uploadDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	f, err := os.OpenFile(s.partPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	f.Close()
+
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		os.Remove(s.partPath(upload.ID))
+		return nil, fmt.Errorf("failed to create video upload: %w", err)
+	}
+
+	// A ready video stays playable until its replacement is uploaded
+	if lesson.VideoStatus != model.VideoReady {
+		lesson.VideoStatus = model.VideoUploading
+		lesson.VideoError = ""
+		if err := s.lessonRepo.UpdateVideo(ctx, lesson); err != nil {
+			log.Printf("Failed to mark lesson %s as uploading: %v", lessonID, err)
+		}
+	}
+
+	log.Printf("Video upload %s created for lesson %s (%d bytes)", upload.ID, lessonID, length)
+	return upload, nil
+}
+
+// GetUpload returns an upload session owned by ownerID
+func (s *videoServiceImpl) GetUpload(ctx context.Context, uploadID, ownerID uuid.UUID) (*model.VideoUpload, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.GetUpload")
+	defer span.End()
+
+	upload, err := s.uploadRepo.GetByID(ctx, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVideoUploadNotFound, err)
+	}
+	if upload.OwnerID != ownerID {
+		// Do not reveal uploads of other users
+		return nil, ErrVideoUploadNotFound
+	}
+	return upload, nil
+}
+
+// AppendChunk writes the bytes of r at offset, which must equal the current
+// upload offset. The last chunk hands the video over to processing.
+func (s *videoServiceImpl) AppendChunk(ctx context.Context, uploadID, ownerID uuid.UUID, offset int64, r io.Reader) (*model.VideoUpload, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.AppendChunk")
+	defer span.End()
+
+	s.uploadLocks.Lock(uploadID)
+	defer s.uploadLocks.Unlock(uploadID)
+
+	upload, err := s.GetUpload(ctx, uploadID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.CompletedAt != nil {
+		return nil, fmt.Errorf("%w: upload is already complete", ErrVideoUploadOffset)
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrVideoUploadExpired
+	}
+	if offset != upload.Offset {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrVideoUploadOffset, upload.Offset, offset)
+	}
+
+	f, err := os.OpenFile(s.partPath(uploadID), os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer f.Close()
+
+	// Drop bytes of a chunk that was written but never recorded
+	if err := f.Truncate(upload.Offset); err != nil {
+		return nil, fmt.Errorf("failed to prepare upload file: %w", err)
+	}
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to prepare upload file: %w", err)
+	}
+
+	remaining := upload.Length - upload.Offset
+	n, copyErr := io.Copy(f, io.LimitReader(r, remaining+1))
+	if n > remaining {
+		f.Truncate(upload.Offset)
+		return nil, fmt.Errorf("%w: chunk exceeds the declared upload length", ErrInvalidVideoUpload)
+	}
+
+	// Keep whatever arrived, even if the client disconnected mid-chunk
+	if n > 0 {
+		if err := f.Sync(); err != nil {
+			return nil, fmt.Errorf("failed to write upload file: %w", err)
+		}
+		upload.Offset += n
+		if err := s.uploadRepo.UpdateOffset(ctx, uploadID, upload.Offset); err != nil {
+			return nil, fmt.Errorf("failed to record upload offset: %w", err)
+		}
+	}
+	if copyErr != nil {
+		return upload, fmt.Errorf("failed to read chunk: %v", copyErr)
+	}
+
+	if upload.Offset == upload.Length {
+		if err := s.finishUpload(ctx, upload); err != nil {
+			return nil, err
+		}
+	}
+
+	return upload, nil
+}
+
+// finishUpload moves a complete upload to storage and queues it for processing
+func (s *videoServiceImpl) finishUpload(ctx context.Context, upload *model.VideoUpload) error {
+	partPath := s.partPath(upload.ID)
+
+	contentType, err := detectVideoType(partPath)
+	if err != nil {
+		if errors.Is(err, ErrVideoUnsupported) {
+			s.rejectUpload(ctx, upload, err)
+		}
+		return err
+	}
+
+	lesson, err := s.lessonRepo.GetByID(ctx, upload.LessonID)
+	if err != nil {
+		return fmt.Errorf("lesson not found with ID %s: %w", upload.LessonID, err)
+	}
+
+	f, err := os.Open(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer f.Close()
+
+	key := path.Join("videos", lesson.ID.String(), "source"+videoExtensions[contentType])
+	if err := s.store.Put(key, f, upload.Length, contentType); err != nil {
+		return fmt.Errorf("failed to store video: %w", err)
+	}
+
+	if lesson.VideoSourceKey != "" && lesson.VideoSourceKey != key {
+		s.removeObject(lesson.VideoSourceKey)
+	}
+
+	lesson.VideoSourceKey = key
+	lesson.VideoStatus = model.VideoUploaded
+	lesson.VideoError = ""
+	if err := s.lessonRepo.UpdateVideo(ctx, lesson); err != nil {
+		return fmt.Errorf("failed to update lesson video: %w", err)
+	}
+
+	now := time.Now()
+	upload.CompletedAt = &now
+	if err := s.uploadRepo.Complete(ctx, upload.ID); err != nil {
+		log.Printf("Failed to mark video upload %s complete: %v", upload.ID, err)
+	}
+
+	os.Remove(partPath)
+	log.Printf("Video upload %s complete, lesson %s queued for processing", upload.ID, lesson.ID)
+	return nil
+}
+
+// rejectUpload drops a complete upload that is not a video, so the client
+// cannot retry it, and records why on a lesson still waiting for it
+func (s *videoServiceImpl) rejectUpload(ctx context.Context, upload *model.VideoUpload, reason error) {
+	if err := s.uploadRepo.Delete(ctx, upload.ID); err != nil {
+		log.Printf("Failed to delete rejected video upload %s: %v", upload.ID, err)
+	}
+	os.Remove(s.partPath(upload.ID))
+
+	lesson, err := s.lessonRepo.GetByID(ctx, upload.LessonID)
+	if err == nil && lesson.VideoStatus == model.VideoUploading {
+		lesson.VideoStatus = model.VideoFailed
+		lesson.VideoError = reason.Error()
+		if err := s.lessonRepo.UpdateVideo(ctx, lesson); err != nil {
+			log.Printf("Failed to mark lesson %s video as failed: %v", lesson.ID, err)
+		}
+	}
+
+	log.Printf("Video upload %s rejected: %v", upload.ID, reason)
+}
+
+// DeleteUpload cancels an unfinished upload
+func (s *videoServiceImpl) DeleteUpload(ctx context.Context, uploadID, ownerID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "VideoService.DeleteUpload")
+	defer span.End()
+
+	upload, err := s.GetUpload(ctx, uploadID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.uploadRepo.Delete(ctx, uploadID); err != nil {
+		return fmt.Errorf("failed to delete video upload %s: %w", uploadID, err)
+	}
+	os.Remove(s.partPath(uploadID))
+
+	if upload.CompletedAt == nil {
+		lesson, err := s.lessonRepo.GetByID(ctx, upload.LessonID)
+		if err == nil && lesson.VideoStatus == model.VideoUploading {
+			lesson.VideoStatus = model.VideoNone
+			if err := s.lessonRepo.UpdateVideo(ctx, lesson); err != nil {
+				log.Printf("Failed to reset lesson %s video status: %v", lesson.ID, err)
+			}
+		}
+	}
+
+	log.Printf("Video upload deleted: %v", uploadID)
+	return nil
+}
+
+// PendingVideoCount returns how many uploaded videos wait for processing
+func (s *videoServiceImpl) PendingVideoCount(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.PendingVideoCount")
+	defer span.End()
+
+	lessons, err := s.lessonRepo.GetByVideoStatus(ctx, model.VideoUploaded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list uploaded videos: %w", err)
+	}
+	return len(lessons), nil
+}
+
+// ReleaseStaleVideoClaims queues again the videos whose processing was
+// claimed longer than VideoClaimTimeout ago
+func (s *videoServiceImpl) ReleaseStaleVideoClaims(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.ReleaseStaleVideoClaims")
+	defer span.End()
+
+	released, err := s.lessonRepo.ReleaseStaleVideoClaims(ctx, time.Now().Add(-VideoClaimTimeout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to release stale video claims: %w", err)
+	}
+	if released > 0 {
+		log.Printf("Queued %d videos again after their processing stalled", released)
+	}
+	return released, nil
+}
+
+// ProcessPendingVideos transcodes every uploaded video. Lessons are claimed
+// first so several instances can run the job side by side.
+func (s *videoServiceImpl) ProcessPendingVideos(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "VideoService.ProcessPendingVideos")
+	defer span.End()
+
+	lessons, err := s.lessonRepo.GetByVideoStatus(ctx, model.VideoUploaded)
+	if err != nil {
+		return fmt.Errorf("failed to list uploaded videos: %w", err)
+	}
+
+	for _, lesson := range lessons {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		claimed, err := s.lessonRepo.ClaimVideoProcessing(ctx, lesson.ID)
+		if err != nil {
+			log.Printf("Failed to claim video of lesson %s: %v", lesson.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		lesson.VideoStatus = model.VideoProcessing
+		if err := s.processVideo(ctx, lesson); err != nil {
+			if ctx.Err() != nil {
+				// Shutting down; leave the video to the next run
+				lesson.VideoStatus = model.VideoUploaded
+			} else {
+				log.Printf("Processing video of lesson %s failed: %v", lesson.ID, err)
+				lesson.VideoStatus = model.VideoFailed
+				lesson.VideoError = err.Error()
+			}
+		} else {
+			lesson.VideoStatus = model.VideoReady
+			lesson.VideoError = ""
+		}
+
+		// Record the outcome even when the job is being stopped
+		if err := s.lessonRepo.UpdateVideo(context.WithoutCancel(ctx), lesson); err != nil {
+			log.Printf("Failed to update video of lesson %s: %v", lesson.ID, err)
+			continue
+		}
+		log.Printf("Video of lesson %s is %s", lesson.ID, lesson.VideoStatus)
+	}
+
+	return nil
+}
+
+// processVideo packages the source as HLS with a thumbnail, or serves the
+// source as is when ffmpeg is not installed.
+func (s *videoServiceImpl) processVideo(ctx context.Context, lesson *model.Lesson) (err error) {
+	ctx, span := tracing.Start(ctx, "VideoService.processVideo", attribute.String("lesson_id", lesson.ID.String()))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	if !media.Available() {
+		lesson.VideoPlaybackType = model.PlaybackProgressive
+		lesson.VideoPlaylistKey = lesson.VideoSourceKey
+		lesson.VideoThumbnailKey = ""
+		return nil
+	}
+
+	workDir, err := os.MkdirTemp("", "lesson-video-")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	src := filepath.Join(workDir, "source"+path.Ext(lesson.VideoSourceKey))
+	if err := s.download(lesson.VideoSourceKey, src); err != nil {
+		return err
+	}
+
+	probe, err := media.Probe(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	hlsDir := filepath.Join(workDir, "hls")
+	if err := media.TranscodeHLS(ctx, src, hlsDir, probe, media.DefaultRenditions); err != nil {
+		return err
+	}
+
+	// A frame a little into the video is more telling than the first one
+	thumbnail := filepath.Join(hlsDir, "thumbnail.jpg")
+	if err := media.Thumbnail(ctx, src, thumbnail, min(probe.Duration*0.1, 5)); err != nil {
+		log.Printf("No thumbnail for lesson %s: %v", lesson.ID, err)
+		thumbnail = ""
+	}
+
+	prefix := path.Join("videos", lesson.ID.String(), "hls")
+	if err := s.uploadDirectory(hlsDir, prefix); err != nil {
+		return err
+	}
+
+	lesson.VideoPlaybackType = model.PlaybackHLS
+	lesson.VideoPlaylistKey = path.Join(prefix, "master.m3u8")
+	lesson.VideoThumbnailKey = ""
+	if thumbnail != "" {
+		lesson.VideoThumbnailKey = path.Join(prefix, "thumbnail.jpg")
+	}
+	lesson.VideoDuration = probe.Duration
+	return nil
+}
+
+// download copies a stored object to a local file
+func (s *videoServiceImpl) download(key, dst string) error {
+	r, err := s.store.Get(key)
+	if err != nil {
+		return fmt.Errorf("failed to read video source: %w", err)
+	}
+	defer r.Close()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to download video source: %w", err)
+	}
+	return nil
+}
+
+// uploadDirectory stores every file under dir at prefix/<relative path>
+func (s *videoServiceImpl) uploadDirectory(dir, prefix string) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		if err := s.store.Put(key, f, info.Size(), streamContentType(key)); err != nil {
+			return fmt.Errorf("failed to store %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// Playback returns a signed, expiring URL for enrolled students and the course creator
+func (s *videoServiceImpl) Playback(ctx context.Context, lessonID, userID uuid.UUID, streamBaseURL string) (*VideoPlayback, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.Playback")
+	defer span.End()
+
+	lesson, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("lesson not found with ID %s: %w", lessonID, err)
+	}
+	if err := s.checkAccess(ctx, lesson, userID); err != nil {
+		return nil, err
+	}
+	if lesson.VideoStatus != model.VideoReady || lesson.VideoPlaylistKey == "" {
+		return nil, ErrVideoNotReady
+	}
+
+	expiresAt := time.Now().Add(PlaybackTokenExpiry)
+	playback := &VideoPlayback{
+		LessonID:  lesson.ID,
+		Type:      lesson.VideoPlaybackType,
+		Duration:  lesson.VideoDuration,
+		ExpiresAt: expiresAt,
+	}
+
+	if lesson.VideoPlaybackType == model.PlaybackHLS {
+		token := utils.SignToken(s.signingKey, playbackTokenPayload(lesson.ID, userID), expiresAt)
+		playback.URL = strings.TrimRight(streamBaseURL, "/") + "/master.m3u8?token=" + token
+	} else {
+		playback.URL, err = s.store.SignedURL(lesson.VideoPlaylistKey, PlaybackTokenExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign playback URL: %w", err)
+		}
+	}
+
+	if lesson.VideoThumbnailKey != "" {
+		playback.ThumbnailURL, err = s.store.SignedURL(lesson.VideoThumbnailKey, PlaybackTokenExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign thumbnail URL: %w", err)
+		}
+	}
+
+	return playback, nil
+}
+
+// checkAccess allows the course creator and enrolled students
+func (s *videoServiceImpl) checkAccess(ctx context.Context, lesson *model.Lesson, userID uuid.UUID) error {
+	course, err := s.courseRepo.GetByID(ctx, lesson.CourseID)
+	if err != nil {
+		return fmt.Errorf("course not found with ID %s: %w", lesson.CourseID, err)
+	}
+	if course.CreatedBy == userID {
+		return nil
+	}
+
+	enrolled, err := s.enrollmentRepo.IsEnrolled(ctx, userID, course.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check enrollment: %w", err)
+	}
+	if !enrolled {
+		return ErrVideoAccessDenied
+	}
+	return nil
+}
+
+// playbackTokenPayload binds a playback token to a lesson and the viewer it was issued to
+func playbackTokenPayload(lessonID, userID uuid.UUID) string {
+	return lessonID.String() + ":" + userID.String()
+}
+
+// StreamFile serves a file of an HLS package. Playlists are rewritten so that
+// nested playlists carry the token and segments point at signed storage URLs
+// expiring with the token; other files redirect to a signed URL. The viewer
+// named in the token must still have access, so a withdrawn enrollment ends
+// playback at the next playlist or file request.
+func (s *videoServiceImpl) StreamFile(ctx context.Context, lessonID uuid.UUID, file, token string) (*StreamResponse, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.StreamFile")
+	defer span.End()
+
+	payload, expiresAt, err := utils.VerifyToken(s.signingKey, token)
+	if err != nil {
+		return nil, ErrVideoAccessDenied
+	}
+	tokenLesson, rawUserID, ok := strings.Cut(payload, ":")
+	if !ok || tokenLesson != lessonID.String() {
+		return nil, ErrVideoAccessDenied
+	}
+	userID, err := uuid.FromString(rawUserID)
+	if err != nil {
+		return nil, ErrVideoAccessDenied
+	}
+
+	lesson, err := s.lessonRepo.GetByID(ctx, lessonID)
+	if err != nil {
+		return nil, fmt.Errorf("lesson not found with ID %s: %w", lessonID, err)
+	}
+	if err := s.checkAccess(ctx, lesson, userID); err != nil {
+		return nil, err
+	}
+
+	prefix := path.Join("videos", lessonID.String(), "hls")
+	key, err := storage.CleanKey(path.Join(prefix, file))
+	if err != nil || !strings.HasPrefix(key, prefix+"/") {
+		return nil, fmt.Errorf("%w: invalid file", ErrInvalidVideoUpload)
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil, ErrVideoAccessDenied
+	}
+
+	if path.Ext(key) != ".m3u8" {
+		url, err := s.store.SignedURL(key, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign stream URL: %w", err)
+		}
+		return &StreamResponse{Redirect: url}, nil
+	}
+
+	r, err := s.store.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", storage.ErrNotFound, file)
+		}
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+	defer r.Close()
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			out.WriteString(line)
+		case strings.HasSuffix(line, ".m3u8"):
+			out.WriteString(line + "?token=" + token)
+		default:
+			url, err := s.store.SignedURL(path.Join(path.Dir(key), line), ttl)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign segment URL: %w", err)
+			}
+			out.WriteString(url)
+		}
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	return &StreamResponse{Body: out.Bytes(), ContentType: streamContentType(key)}, nil
+}
+
+// hlsPackageKeys lists the objects of the HLS package whose playlist is
+// stored under key: the playlist, the playlists it nests and every segment
+// they name. A missing playlist names nothing further.
+func hlsPackageKeys(store storage.Storage, key string) ([]string, error) {
+	keys := []string{key}
+
+	r, err := store.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return keys, nil
+		}
+		return nil, fmt.Errorf("failed to read playlist %s: %w", key, err)
+	}
+	defer r.Close()
+
+	var nested []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry := path.Join(path.Dir(key), line)
+		if path.Ext(entry) == ".m3u8" {
+			nested = append(nested, entry)
+			continue
+		}
+		keys = append(keys, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist %s: %w", key, err)
+	}
+
+	for _, playlist := range nested {
+		nestedKeys, err := hlsPackageKeys(store, playlist)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, nestedKeys...)
+	}
+	return keys, nil
+}
+
+func (s *videoServiceImpl) partPath(uploadID uuid.UUID) string {
+	return filepath.Join(s.uploadDir, uploadID.String()+".part")
+}
+
+func (s *videoServiceImpl) removeObject(key string) {
+	if err := s.store.Delete(key); err != nil {
+		log.Printf("Failed to remove stored object %s: %v", key, err)
+	}
+}
+
+// detectVideoType sniffs the uploaded file, falling back to ffprobe for
+// containers the standard library does not recognize (e.g. QuickTime)
+func detectVideoType(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload file: %w", err)
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	f.Close()
+
+	contentType := sniffContentType(head[:n])
+	if _, ok := videoExtensions[contentType]; ok {
+		return contentType, nil
+	}
+
+	if media.Available() && isQuickTime(head[:n]) {
+		if _, err := media.Probe(context.Background(), p); err == nil {
+			return "video/quicktime", nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrVideoUnsupported, contentType)
+}
+
+// isQuickTime reports whether data starts with an ISO base media "ftyp" box
+func isQuickTime(data []byte) bool {
+	return len(data) >= 12 && string(data[4:8]) == "ftyp"
+}
+
+// streamContentType returns the media type of an HLS package file
+func streamContentType(key string) string {
+	switch path.Ext(key) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/storage"
+	utils "e-learning-system/pkg/config"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeLessonRepo knows a fixed set of lessons
+type fakeLessonRepo struct {
+	repository.LessonRepository
+	lessons map[uuid.UUID]*model.Lesson
+}
+
+func (r *fakeLessonRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Lesson, error) {
+	lesson, ok := r.lessons[id]
+	if !ok {
+		return nil, apperr.NotFound("lesson_not_found", "lesson not found")
+	}
+	copied := *lesson
+	return &copied, nil
+}
+
+func (r *fakeLessonRepo) UpdateVideo(_ context.Context, lesson *model.Lesson) error {
+	copied := *lesson
+	r.lessons[lesson.ID] = &copied
+	return nil
+}
+
+// fakeUploadRepo keeps video uploads in memory
+type fakeUploadRepo struct {
+	repository.VideoUploadRepository
+	uploads map[uuid.UUID]*model.VideoUpload
+}
+
+func (r *fakeUploadRepo) GetByID(_ context.Context, id uuid.UUID) (*model.VideoUpload, error) {
+	upload, ok := r.uploads[id]
+	if !ok {
+		return nil, apperr.NotFound("video_upload_not_found", "video upload not found")
+	}
+	copied := *upload
+	return &copied, nil
+}
+
+func (r *fakeUploadRepo) UpdateOffset(_ context.Context, id uuid.UUID, offset int64) error {
+	r.uploads[id].Offset = offset
+	return nil
+}
+
+func (r *fakeUploadRepo) Delete(_ context.Context, id uuid.UUID) error {
+	delete(r.uploads, id)
+	return nil
+}
+
+// memoryStorage keeps objects in memory and signs URLs as "signed:<key>"
+type memoryStorage struct {
+	objects map[string][]byte
+}
+
+func (s *memoryStorage) Put(key string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryStorage) Get(key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Delete(key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStorage) SignedURL(key string, _ time.Duration) (string, error) {
+	return "signed:" + key, nil
+}
+
+// videoFixture is an HLS lesson and a progressive one of a course created by
+// "author", with "student" enrolled and "stranger" not
+type videoFixture struct {
+	service     *videoServiceImpl
+	lessons     *fakeLessonRepo
+	enrollments *fakeEnrollmentRepo
+	ids         map[string]uuid.UUID
+}
+
+func newVideoFixture(t *testing.T) *videoFixture {
+	f := &videoFixture{ids: map[string]uuid.UUID{}}
+	for _, name := range []string{"course", "hls", "progressive", "author", "student", "stranger"} {
+		f.ids[name] = uuid.Must(uuid.NewV4())
+	}
+
+	hlsPrefix := "videos/" + f.ids["hls"].String() + "/hls/"
+	f.lessons = &fakeLessonRepo{lessons: map[uuid.UUID]*model.Lesson{
+		f.ids["hls"]: {ID: f.ids["hls"], CourseID: f.ids["course"], VideoStatus: model.VideoReady,
+			VideoPlaybackType: model.PlaybackHLS, VideoPlaylistKey: hlsPrefix + "master.m3u8"},
+		f.ids["progressive"]: {ID: f.ids["progressive"], CourseID: f.ids["course"], VideoStatus: model.VideoReady,
+			VideoPlaybackType: model.PlaybackProgressive, VideoPlaylistKey: "videos/source.mp4"},
+	}}
+	f.enrollments = &fakeEnrollmentRepo{enrollments: []*model.Enrollment{
+		{CourseID: f.ids["course"], UserID: f.ids["student"], Status: model.EnrollmentActive},
+	}}
+	f.service = &videoServiceImpl{
+		courseRepo: &fakeCourseRepo{courses: map[uuid.UUID]*model.Course{
+			f.ids["course"]: {ID: f.ids["course"], CreatedBy: f.ids["author"]},
+		}},
+		lessonRepo:     f.lessons,
+		enrollmentRepo: f.enrollments,
+		uploadRepo:     &fakeUploadRepo{uploads: map[uuid.UUID]*model.VideoUpload{}},
+		store: &memoryStorage{objects: map[string][]byte{
+			hlsPrefix + "master.m3u8":     []byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=800000\n720p/index.m3u8\n"),
+			hlsPrefix + "720p/index.m3u8": []byte("#EXTM3U\n#EXTINF:4.0,\nsegment0.ts\n#EXT-X-ENDLIST\n"),
+		}},
+		uploadDir:  t.TempDir(),
+		signingKey: []byte("test signing key"),
+	}
+	return f
+}
+
+// streamToken issues the playback token of lesson to user
+func (f *videoFixture) streamToken(t *testing.T, lesson, user string) string {
+	t.Helper()
+	playback, err := f.service.Playback(context.Background(), f.ids[lesson], f.ids[user], "https://api.example.com/stream/")
+	if err != nil {
+		t.Fatalf("Playback: %v", err)
+	}
+	u, err := url.Parse(playback.URL)
+	if err != nil {
+		t.Fatalf("playback URL %q: %v", playback.URL, err)
+	}
+	return u.Query().Get("token")
+}
+
+func TestPlayback(t *testing.T) {
+	tests := []struct {
+		name    string
+		lesson  string
+		user    string
+		status  model.VideoStatus
+		wantURL string // prefix
+		wantErr error
+	}{
+		{"enrolled student", "hls", "student", model.VideoReady, "https://api.example.com/stream/master.m3u8?token=", nil},
+		{"course creator", "hls", "author", model.VideoReady, "https://api.example.com/stream/master.m3u8?token=", nil},
+		{"progressive", "progressive", "student", model.VideoReady, "signed:videos/source.mp4", nil},
+		{"not enrolled", "hls", "stranger", model.VideoReady, "", ErrVideoAccessDenied},
+		{"still processing", "hls", "student", model.VideoProcessing, "", ErrVideoNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newVideoFixture(t)
+			f.lessons.lessons[f.ids[tt.lesson]].VideoStatus = tt.status
+
+			playback, err := f.service.Playback(context.Background(), f.ids[tt.lesson], f.ids[tt.user], "https://api.example.com/stream/")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Playback: %v", err)
+			}
+			if !strings.HasPrefix(playback.URL, tt.wantURL) {
+				t.Errorf("URL = %q, want %q...", playback.URL, tt.wantURL)
+			}
+			if until := time.Until(playback.ExpiresAt); until <= 0 || until > PlaybackTokenExpiry {
+				t.Errorf("expires in %v", until)
+			}
+		})
+	}
+}
+
+func TestPlaybackTokenBinding(t *testing.T) {
+	f := newVideoFixture(t)
+	token := f.streamToken(t, "hls", "student")
+
+	payload, _, err := utils.VerifyToken(f.service.signingKey, token)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if want := f.ids["hls"].String() + ":" + f.ids["student"].String(); payload != want {
+		t.Errorf("payload = %q, want %q", payload, want)
+	}
+
+	tests := []struct {
+		name    string
+		lesson  string
+		file    string
+		token   func(token string) string
+		unenrol bool
+		wantErr error
+	}{
+		{"another lesson", "progressive", "master.m3u8", nil, false, ErrVideoAccessDenied},
+		{"tampered", "hls", "master.m3u8", func(token string) string { return token[:len(token)-2] + "xx" }, false, ErrVideoAccessDenied},
+		{"no token", "hls", "master.m3u8", func(string) string { return "" }, false, ErrVideoAccessDenied},
+		{"expired", "hls", "master.m3u8", func(string) string {
+			return utils.SignToken(f.service.signingKey, payload, time.Now().Add(-time.Minute))
+		}, false, ErrVideoAccessDenied},
+		{"enrollment withdrawn", "hls", "master.m3u8", nil, true, ErrVideoAccessDenied},
+		{"escapes the package", "hls", "../source.mp4", nil, false, ErrInvalidVideoUpload},
+		{"escapes the lesson", "hls", "../../other/hls/master.m3u8", nil, false, ErrInvalidVideoUpload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamed := token
+			if tt.token != nil {
+				streamed = tt.token(token)
+			}
+			if tt.unenrol {
+				defer func(enrollments []*model.Enrollment) { f.enrollments.enrollments = enrollments }(f.enrollments.enrollments)
+				f.enrollments.enrollments = nil
+			}
+
+			_, err := f.service.StreamFile(context.Background(), f.ids[tt.lesson], tt.file, streamed)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamFile(t *testing.T) {
+	f := newVideoFixture(t)
+	token := f.streamToken(t, "hls", "student")
+	ctx := context.Background()
+	prefix := "videos/" + f.ids["hls"].String() + "/hls/"
+
+	master, err := f.service.StreamFile(ctx, f.ids["hls"], "master.m3u8", token)
+	if err != nil {
+		t.Fatalf("StreamFile master: %v", err)
+	}
+	if want := "720p/index.m3u8?token=" + token + "\n"; !strings.Contains(string(master.Body), want) {
+		t.Errorf("master playlist lacks %q:\n%s", want, master.Body)
+	}
+	if master.ContentType != "application/vnd.apple.mpegurl" {
+		t.Errorf("content type = %q", master.ContentType)
+	}
+
+	variant, err := f.service.StreamFile(ctx, f.ids["hls"], "720p/index.m3u8", token)
+	if err != nil {
+		t.Fatalf("StreamFile variant: %v", err)
+	}
+	if want := "signed:" + prefix + "720p/segment0.ts\n"; !strings.Contains(string(variant.Body), want) {
+		t.Errorf("variant playlist lacks %q:\n%s", want, variant.Body)
+	}
+
+	segment, err := f.service.StreamFile(ctx, f.ids["hls"], "720p/segment0.ts", token)
+	if err != nil {
+		t.Fatalf("StreamFile segment: %v", err)
+	}
+	if segment.Redirect != "signed:"+prefix+"720p/segment0.ts" {
+		t.Errorf("redirect = %q", segment.Redirect)
+	}
+
+	if _, err := f.service.StreamFile(ctx, f.ids["hls"], "missing.m3u8", token); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("missing playlist error = %v, want not found", err)
+	}
+}
+
+func TestAppendChunkRejectsNonVideo(t *testing.T) {
+	f := newVideoFixture(t)
+	uploads := f.service.uploadRepo.(*fakeUploadRepo)
+	ownerID := f.ids["author"]
+	lessonID := f.ids["hls"]
+	f.lessons.lessons[lessonID].VideoStatus = model.VideoUploading
+
+	content := []byte("just some notes, not a video at all")
+	upload := &model.VideoUpload{ID: uuid.Must(uuid.NewV4()), LessonID: lessonID, OwnerID: ownerID,
+		Length: int64(len(content)), ExpiresAt: time.Now().Add(time.Hour)}
+	uploads.uploads[upload.ID] = upload
+	if err := os.WriteFile(f.service.partPath(upload.ID), nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := f.service.AppendChunk(context.Background(), upload.ID, ownerID, 0, bytes.NewReader(content))
+	if !errors.Is(err, ErrVideoUnsupported) {
+		t.Fatalf("error = %v, want ErrVideoUnsupported", err)
+	}
+	if apperr.KindOf(err) != apperr.KindUnsupportedMedia {
+		t.Errorf("kind = %v, want unsupported media", apperr.KindOf(err))
+	}
+	if _, ok := uploads.uploads[upload.ID]; ok {
+		t.Error("rejected upload was kept")
+	}
+	if _, err := os.Stat(f.service.partPath(upload.ID)); !os.IsNotExist(err) {
+		t.Errorf("part file still there: %v", err)
+	}
+	if lesson := f.lessons.lessons[lessonID]; lesson.VideoStatus != model.VideoFailed || lesson.VideoError == "" {
+		t.Errorf("lesson video = %s %q, want failed with the reason", lesson.VideoStatus, lesson.VideoError)
+	}
+}
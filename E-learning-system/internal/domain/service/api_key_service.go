@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// apiKeyPrefixBytes is the entropy of the public part of an API key. It only
+// has to be unique; the secret part carries the security.
+const apiKeyPrefixBytes = 6
+
+var (
+	ErrAPIKeyNameRequired  = apperr.Validation("api_key_name_required", "name is required")
+	ErrAPIKeyScopesEmpty   = apperr.Validation("api_key_scopes_required", "at least one scope is required")
+	ErrInvalidAPIKeyScope  = apperr.Validation("invalid_api_key_scope", "unknown scope")
+	ErrAPIKeyExpiryInPast  = apperr.Validation("api_key_expiry_in_past", "expires_at must be in the future")
+	ErrAPIKeyNotFound      = apperr.NotFound("api_key_not_found", "API key not found")
+	ErrInvalidAPIKey       = apperr.Unauthenticated("api_key_invalid", "invalid API key")
+	ErrAPIKeyExpired       = apperr.Unauthenticated("api_key_expired", "API key expired")
+	ErrAPIKeyRevoked       = apperr.Unauthenticated("api_key_revoked", "API key revoked")
+	ErrAPIKeyOrgNotAllowed = apperr.Forbidden("api_key_organization_suspended", "the organization of this API key is not active")
+)
+
+// APIKeyService issues and checks the API keys organizations use for integrations
+type APIKeyService interface {
+	// CreateAPIKey issues a key; the full key is only returned here
+	CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.NewAPIKey, error)
+	ListAPIKeys(ctx context.Context, organizationID uuid.UUID) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, organizationID, keyID uuid.UUID) error
+	// Authenticate returns the active key matching a full key
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error)
+}
+
+type apiKeyServiceImpl struct {
+	repo    repository.APIKeyRepository
+	orgRepo repository.OrganizationRepository
+	audit   AuditService
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(repo repository.APIKeyRepository, orgRepo repository.OrganizationRepository, auditService AuditService) APIKeyService {
+	return &apiKeyServiceImpl{repo: repo, orgRepo: orgRepo, audit: auditService}
+}
+
+// CreateAPIKey validates the name, scopes and expiry of key, generates the
+// secret and stores its hash. OrganizationID and CreatedBy must be set.
+func (s *apiKeyServiceImpl) CreateAPIKey(ctx context.Context, key *model.APIKey) (*model.NewAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" {
+		return nil, ErrAPIKeyNameRequired
+	}
+	if len(key.Scopes) == 0 {
+		return nil, ErrAPIKeyScopesEmpty
+	}
+	for _, scope := range key.Scopes {
+		if !scope.IsValid() {
+			return nil, ErrInvalidAPIKeyScope
+		}
+	}
+	slices.Sort(key.Scopes)
+	key.Scopes = slices.Compact(key.Scopes)
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+
+	// The organization must exist; its status is checked on every use
+	if _, err := s.orgRepo.GetByID(ctx, key.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret, _, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key.Prefix = model.APIKeyPrefix + hex.EncodeToString(b)
+	rawKey := key.Prefix + "_" + secret
+	key.KeyHash = hashSecretToken(rawKey)
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &key.OrganizationID,
+		Action:         "api_key.create",
+		TargetType:     model.AuditTargetAPIKey,
+		TargetID:       &key.ID,
+		Changes:        audit.Diff(nil, key),
+	})
+	return &model.NewAPIKey{APIKey: key, Key: rawKey}, nil
+}
+
+// ListAPIKeys returns the keys of an organization, revoked ones included
+func (s *apiKeyServiceImpl) ListAPIKeys(ctx context.Context, orgID uuid.UUID) ([]*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListAPIKeys")
+	defer span.End()
+
+	if _, err := s.orgRepo.GetByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, orgID)
+}
+
+// RevokeAPIKey disables a key for good
+func (s *apiKeyServiceImpl) RevokeAPIKey(ctx context.Context, orgID, keyID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	revoked, err := s.repo.Revoke(ctx, orgID, keyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &orgID,
+		Action:         "api_key.revoke",
+		TargetType:     model.AuditTargetAPIKey,
+		TargetID:       &keyID,
+	})
+	return nil
+}
+
+// Authenticate looks the key up by its prefix and compares the hash of the
+// whole key. Keys of organizations that may not sign in are refused like
+// their members are.
+func (s *apiKeyServiceImpl) Authenticate(ctx context.Context, rawKey string) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	// elk_<prefix>_<secret>; the prefix is hex, the secret may contain '_'
+	rest, ok := strings.CutPrefix(rawKey, model.APIKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByPrefix(ctx, model.APIKeyPrefix+prefix)
+	if err != nil {
+		if apperr.KindOf(err) == apperr.KindNotFound {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecretToken(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	switch {
+	case key.RevokedAt != nil:
+		return nil, ErrAPIKeyRevoked
+	case key.IsExpired(time.Now()):
+		return nil, ErrAPIKeyExpired
+	case key.OrganizationStatus.BlocksMemberLogin():
+		return nil, ErrAPIKeyOrgNotAllowed
+	}
+
+	// Last-used tracking is informational; a failed write does not fail the request
+	if err := s.repo.Touch(ctx, key.ID); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+	}
+	return key, nil
+}
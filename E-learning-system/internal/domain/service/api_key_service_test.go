@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeAPIKeyRepo keeps keys in memory by prefix
+type fakeAPIKeyRepo struct {
+	keys    map[string]*model.APIKey
+	touched []uuid.UUID
+}
+
+func (r *fakeAPIKeyRepo) Create(_ context.Context, key *model.APIKey) error {
+	key.ID = uuid.Must(uuid.NewV4())
+	key.CreatedAt = time.Now()
+	key.OrganizationStatus = model.OrganizationActive
+	r.keys[key.Prefix] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) List(context.Context, uuid.UUID) ([]*model.APIKey, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeAPIKeyRepo) FindByPrefix(_ context.Context, prefix string) (*model.APIKey, error) {
+	key, ok := r.keys[prefix]
+	if !ok {
+		return nil, apperr.NotFound("api_key_not_found", "API key not found")
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (r *fakeAPIKeyRepo) Revoke(context.Context, uuid.UUID, uuid.UUID) (bool, error) {
+	return false, errors.New("not implemented")
+}
+
+func (r *fakeAPIKeyRepo) Touch(_ context.Context, keyID uuid.UUID) error {
+	r.touched = append(r.touched, keyID)
+	return nil
+}
+
+// fakeOrgRepo knows a single organization
+type fakeOrgRepo struct {
+	repository.OrganizationRepository
+	org *model.Organization
+}
+
+func (r *fakeOrgRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Organization, error) {
+	if r.org == nil || r.org.ID != id {
+		return nil, apperr.NotFound("organization_not_found", "organization not found")
+	}
+	return r.org, nil
+}
+
+// discardAudit drops audit events
+type discardAudit struct{ AuditService }
+
+func (discardAudit) Record(context.Context, *model.AuditEvent) {}
+
+func newTestAPIKeyService() (*apiKeyServiceImpl, *fakeAPIKeyRepo, uuid.UUID) {
+	orgID := uuid.Must(uuid.NewV4())
+	repo := &fakeAPIKeyRepo{keys: make(map[string]*model.APIKey)}
+	s := &apiKeyServiceImpl{
+		repo:    repo,
+		orgRepo: &fakeOrgRepo{org: &model.Organization{ID: orgID, Status: model.OrganizationActive}},
+		audit:   discardAudit{},
+	}
+	return s, repo, orgID
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		key     model.APIKey
+		wantErr error
+	}{
+		{"valid", model.APIKey{Name: " ci ", Scopes: []model.AdminPermission{model.ManageCourses}}, nil},
+		{"with expiry", model.APIKey{Name: "ci", Scopes: []model.AdminPermission{model.ManageCourses}, ExpiresAt: &future}, nil},
+		{"no name", model.APIKey{Name: "  ", Scopes: []model.AdminPermission{model.ManageCourses}}, ErrAPIKeyNameRequired},
+		{"no scopes", model.APIKey{Name: "ci"}, ErrAPIKeyScopesEmpty},
+		{"unknown scope", model.APIKey{Name: "ci", Scopes: []model.AdminPermission{"everything"}}, ErrInvalidAPIKeyScope},
+		{"expired", model.APIKey{Name: "ci", Scopes: []model.AdminPermission{model.ManageCourses}, ExpiresAt: &past}, ErrAPIKeyExpiryInPast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, orgID := newTestAPIKeyService()
+			key := tt.key
+			key.OrganizationID = orgID
+
+			created, err := s.CreateAPIKey(context.Background(), &key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateAPIKey error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if key.Name != "ci" {
+				t.Errorf("name = %q, want it trimmed", key.Name)
+			}
+			if !strings.HasPrefix(created.Key, key.Prefix+"_") || created.KeyHash == created.Key {
+				t.Errorf("key %q does not start with prefix %q or is stored in clear", created.Key, key.Prefix)
+			}
+
+			got, err := s.Authenticate(context.Background(), created.Key)
+			if err != nil {
+				t.Fatalf("Authenticate with the new key: %v", err)
+			}
+			if got.ID != key.ID {
+				t.Errorf("Authenticate returned key %s, want %s", got.ID, key.ID)
+			}
+		})
+	}
+}
+
+func TestCreateAPIKeyUnknownOrganization(t *testing.T) {
+	s, _, _ := newTestAPIKeyService()
+	key := &model.APIKey{OrganizationID: uuid.Must(uuid.NewV4()), Name: "ci", Scopes: []model.AdminPermission{model.ManageCourses}}
+	if _, err := s.CreateAPIKey(context.Background(), key); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Errorf("CreateAPIKey error = %v, want not found", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	s, repo, orgID := newTestAPIKeyService()
+	created, err := s.CreateAPIKey(context.Background(), &model.APIKey{
+		OrganizationID: orgID,
+		Name:           "ci",
+		Scopes:         []model.AdminPermission{model.ManageCourses},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := created.Key
+	prefix := created.Prefix
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		rawKey  string
+		modify  func(k *model.APIKey)
+		wantErr error
+	}{
+		{"valid", raw, nil, nil},
+		{"expires later", raw, func(k *model.APIKey) { later := time.Now().Add(time.Hour); k.ExpiresAt = &later }, nil},
+		{"no key prefix", strings.TrimPrefix(raw, model.APIKeyPrefix), nil, ErrInvalidAPIKey},
+		{"other key prefix", "sk_" + strings.TrimPrefix(raw, model.APIKeyPrefix), nil, ErrInvalidAPIKey},
+		{"no secret", prefix, nil, ErrInvalidAPIKey},
+		{"empty prefix", model.APIKeyPrefix + "_secret", nil, ErrInvalidAPIKey},
+		{"unknown prefix", model.APIKeyPrefix + "000000000000_secret", nil, ErrInvalidAPIKey},
+		{"wrong secret", prefix + "_wrong", nil, ErrInvalidAPIKey},
+		{"secret with extra text", raw + "x", nil, ErrInvalidAPIKey},
+		{"empty", "", nil, ErrInvalidAPIKey},
+		{"revoked", raw, func(k *model.APIKey) { k.RevokedAt = &past }, ErrAPIKeyRevoked},
+		{"expired", raw, func(k *model.APIKey) { k.ExpiresAt = &past }, ErrAPIKeyExpired},
+		{"organization suspended", raw, func(k *model.APIKey) { k.OrganizationStatus = model.OrganizationSuspended }, ErrAPIKeyOrgNotAllowed},
+		{"organization pending deletion", raw, func(k *model.APIKey) { k.OrganizationStatus = model.OrganizationPendingDeletion }, ErrAPIKeyOrgNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := *repo.keys[prefix]
+			defer func() { repo.keys[prefix] = &saved }()
+			if tt.modify != nil {
+				modified := saved
+				tt.modify(&modified)
+				repo.keys[prefix] = &modified
+			}
+			repo.touched = nil
+
+			key, err := s.Authenticate(context.Background(), tt.rawKey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(repo.touched) != 0 {
+					t.Error("a refused key was recorded as used")
+				}
+				return
+			}
+			if key.ID != saved.ID || len(repo.touched) != 1 || repo.touched[0] != saved.ID {
+				t.Errorf("Authenticate = %s, touched %v; want %s touched once", key.ID, repo.touched, saved.ID)
+			}
+		})
+	}
+}
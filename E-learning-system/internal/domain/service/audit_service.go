@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/tracing"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// auditExportBatch is how many events an export reads at a time
+const auditExportBatch = 500
+
+// auditCSVHeader names the columns of an audit export
+var auditCSVHeader = []string{
+	"id", "created_at", "organization_id", "actor_id", "api_key_id", "action",
+	"target_type", "target_id", "changes", "ip_address", "user_agent", "request_id",
+}
+
+// AuditService records administrative changes and lets platform admins review them
+type AuditService interface {
+	// Record appends event, taking actor and request details from ctx.
+	// Failures are logged rather than returned: the change has been made.
+	Record(ctx context.Context, event *model.AuditEvent)
+	ListAuditEvents(ctx context.Context, filter model.AuditEventFilter, page model.PageRequest) (*model.Page[*model.AuditEvent], error)
+	// ExportAuditEvents writes every event matching the filter to w as CSV, oldest first
+	ExportAuditEvents(ctx context.Context, filter model.AuditEventFilter, w io.Writer) error
+}
+
+type auditServiceImpl struct {
+	repo repository.AuditEventRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo repository.AuditEventRepository) AuditService {
+	return &auditServiceImpl{repo: repo}
+}
+
+// Record appends an audit event
+func (s *auditServiceImpl) Record(ctx context.Context, event *model.AuditEvent) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	src := audit.SourceFromContext(ctx)
+	event.ActorID = src.ActorID
+	event.APIKeyID = src.APIKeyID
+	event.IP = src.IP
+	event.UserAgent = src.UserAgent
+	event.RequestID = src.RequestID
+	if event.Changes == nil {
+		event.Changes = map[string]model.AuditChange{}
+	}
+
+	if err := s.repo.Create(ctx, event); err != nil {
+		logger.FromContext(ctx).Error("Failed to record audit event", "action", event.Action, "target_id", event.TargetID, "error", err)
+	}
+}
+
+// ListAuditEvents retrieves one page of audit events matching the filter
+func (s *auditServiceImpl) ListAuditEvents(ctx context.Context, filter model.AuditEventFilter, page model.PageRequest) (*model.Page[*model.AuditEvent], error) {
+	ctx, span := tracing.Start(ctx, "AuditService.ListAuditEvents")
+	defer span.End()
+
+	events, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, nil
+}
+
+// ExportAuditEvents streams the matching events batch by batch, so exports
+// of any size use little memory
+func (s *auditServiceImpl) ExportAuditEvents(ctx context.Context, filter model.AuditEventFilter, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "AuditService.ExportAuditEvents")
+	defer span.End()
+
+	out := csv.NewWriter(w)
+	if err := out.Write(auditCSVHeader); err != nil {
+		return err
+	}
+
+	page := model.PageRequest{Limit: auditExportBatch, Sort: "created_at"}
+	for {
+		events, err := s.repo.List(ctx, filter, page)
+		if err != nil {
+			return fmt.Errorf("failed to list audit events: %w", err)
+		}
+		for _, event := range events.Items {
+			if err := out.Write(auditCSVRecord(event)); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return err
+		}
+
+		if events.NextCursor == "" {
+			return nil
+		}
+		if page.After, err = model.DecodeCursor(events.NextCursor); err != nil {
+			return err
+		}
+	}
+}
+
+// auditCSVRecord formats an event as a row of auditCSVHeader
+func auditCSVRecord(e *model.AuditEvent) []string {
+	changes, _ := json.Marshal(e.Changes)
+	return []string{
+		e.ID.String(),
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		uuidOrEmpty(e.OrganizationID),
+		uuidOrEmpty(e.ActorID),
+		uuidOrEmpty(e.APIKeyID),
+		e.Action,
+		e.TargetType,
+		uuidOrEmpty(e.TargetID),
+		csvSafe(string(changes)),
+		e.IP,
+		csvSafe(e.UserAgent),
+		e.RequestID,
+	}
+}
+
+func uuidOrEmpty(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// csvSafe keeps spreadsheet applications from running a cell that comes
+// from user input as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
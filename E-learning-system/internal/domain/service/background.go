@@ -0,0 +1,37 @@
+package service
+
+import "sync"
+
+// background runs work that outlives the request starting it and lets
+// shutdown wait for it while the database is still open.
+type background struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	stopped bool
+}
+
+// Go runs fn in its own goroutine. Once Wait has been called nothing new
+// is started and Go reports false.
+func (b *background) Go(fn func()) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return false
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// Wait stops new work from starting and blocks until the running work is done
+func (b *background) Wait() {
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
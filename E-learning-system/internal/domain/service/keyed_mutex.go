@@ -0,0 +1,49 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// keyedMutex hands out one mutex per key. Entries only live while someone
+// holds or waits for them, so finished and abandoned keys take no memory.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[uuid.UUID]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks the mutex of key, waiting while another caller holds it
+func (m *keyedMutex) Lock(key uuid.UUID) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[uuid.UUID]*keyedLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+}
+
+// Unlock unlocks the mutex of key and drops it when nobody else waits for it
+func (m *keyedMutex) Unlock(key uuid.UUID) {
+	m.mu.Lock()
+	l := m.locks[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(m.locks, key)
+	}
+	m.mu.Unlock()
+
+	l.Unlock()
+}
@@ -1,10 +1,18 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/sso"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/tracing"
 	"fmt"
 	"log"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -12,96 +20,389 @@ import (
 
 // OrganizationService interface with CRUD methods
 type OrganizationService interface {
-	CreateOrganization(org *model.Organization) (*model.Organization, error)
-	UpdateOrganization(org *model.Organization) error
-	DeleteOrganization(orgID uuid.UUID) error
-	GetOrganizationByID(orgID uuid.UUID) (*model.Organization, error)
-	GetAllOrganizations() ([]*model.Organization, error)
+	CreateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error)
+	UpdateOrganization(ctx context.Context, org *model.Organization) error
+	DeleteOrganization(ctx context.Context, orgID, actorID uuid.UUID) error
+	// RestoreOrganization cancels a deletion until the purge job has run
+	RestoreOrganization(ctx context.Context, orgID, actorID uuid.UUID) (*model.Organization, error)
+	GetOrganizationByID(ctx context.Context, orgID uuid.UUID) (*model.Organization, error)
+	ListOrganizations(ctx context.Context, filter model.OrganizationFilter, page model.PageRequest) (*model.Page[*model.Organization], error)
+
+	// Lifecycle
+	ChangeOrganizationStatus(ctx context.Context, orgID uuid.UUID, status model.OrganizationStatus, reason string, actorID uuid.UUID) (*model.Organization, error)
+	GetOrganizationStatusHistory(ctx context.Context, orgID uuid.UUID) ([]*model.OrganizationStatusEvent, error)
+	GetOrganizationsDueForDeletion(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	ExportOrganization(ctx context.Context, orgID uuid.UUID) ([]byte, error)
+	PurgeOrganization(ctx context.Context, orgID uuid.UUID) error
+
+	// Member policy
+	GetOrganizationPolicy(ctx context.Context, orgID uuid.UUID) (*model.OrganizationPolicy, error)
+	UpdateOrganizationPolicy(ctx context.Context, policy *model.OrganizationPolicy) (*model.OrganizationPolicy, error)
+
+	// Single sign-on
+	GetSSOConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSSO, error)
+	UpdateSSOConfig(ctx context.Context, config *model.OrganizationSSO, clientSecret *string) (*model.OrganizationSSO, error)
+	DeleteSSOConfig(ctx context.Context, orgID uuid.UUID) error
+	GetSAMLConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSAML, error)
+	UpdateSAMLConfig(ctx context.Context, config *model.OrganizationSAML, idpMetadata, idpMetadataURL string) (*model.OrganizationSAML, error)
+	DeleteSAMLConfig(ctx context.Context, orgID uuid.UUID) error
 }
 
+// OrganizationServiceOptions configures single sign-on
+type OrganizationServiceOptions struct {
+	EncryptionKey        []byte // encrypts identity provider client secrets and SAML keys
+	AllowInsecureIssuers bool   // accept http:// issuers and metadata URLs, e.g. a local mock IdP
+	PublicURL            string // base of the SAML service provider URLs
+}
+
+// OrganizationDeletionGracePeriod is how long an organization stays in
+// pending_deletion before the purge job hard deletes it.
+const OrganizationDeletionGracePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidOrganizationStatus    = apperr.Validation("invalid_organization_status", "invalid organization status")
+	ErrOrganizationStatusTransition = apperr.Conflict("organization_status_transition", "organization status transition not allowed")
+	ErrOrganizationStatusReason     = apperr.Validation("status_reason_required", "a reason is required for this status change")
+	ErrOrganizationNotDeleted       = apperr.NotFound("deleted_organization_not_found", "no deleted organization with this ID")
+)
+
 // organizationServiceImpl struct implementing OrganizationService
 type organizationServiceImpl struct {
-	repo repository.OrganizationRepository
+	repo      repository.OrganizationRepository
+	ssoRepo   repository.SSORepository
+	ssoClient *sso.Client
+	store     storage.Storage
+	audit     AuditService
+	opts      OrganizationServiceOptions
 }
 
 // Constructor
-func NewOrganizationService(orgRepo repository.OrganizationRepository) OrganizationService {
+func NewOrganizationService(orgRepo repository.OrganizationRepository, ssoRepo repository.SSORepository, ssoClient *sso.Client,
+	store storage.Storage, auditService AuditService, opts OrganizationServiceOptions) OrganizationService {
 	return &organizationServiceImpl{
-		repo: orgRepo,
+		repo:      orgRepo,
+		ssoRepo:   ssoRepo,
+		ssoClient: ssoClient,
+		store:     store,
+		audit:     auditService,
+		opts:      opts,
 	}
 }
 
+// recordChange audits a change to an organization or one of its settings.
+// before is nil for creations and after for deletions.
+func (s *organizationServiceImpl) recordChange(ctx context.Context, orgID uuid.UUID, action string, before, after any) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &orgID,
+		Action:         action,
+		TargetType:     model.AuditTargetOrganization,
+		TargetID:       &orgID,
+		Changes:        audit.Diff(before, after),
+	})
+}
+
 // CreateOrganization creates a new organization
-func (s *organizationServiceImpl) CreateOrganization(org *model.Organization) (*model.Organization, error) {
+func (s *organizationServiceImpl) CreateOrganization(ctx context.Context, org *model.Organization) (*model.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.CreateOrganization")
+	defer span.End()
+
 	// Generate a new UUID for the organization
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
 	org.ID = newID
+	org.Status = model.OrganizationPending
 	org.CreatedAt = time.Now()
 	org.UpdatedAt = time.Now()
 
 	// Log the creation attempt
-	log.Printf("Creating organization: %+v", org)
+	slog.Info("Creating organization", "organization_id", org.ID)
 
 	// Save to repository
-	if err := s.repo.Create(org); err != nil {
-		return nil, fmt.Errorf("failed to create organization: %v", err)
+	if err := s.repo.Create(ctx, org); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
 	}
 
+	s.recordChange(ctx, org.ID, "organization.create", nil, org)
+
 	return org, nil
 }
 
 // UpdateOrganization updates an existing organization
-func (s *organizationServiceImpl) UpdateOrganization(org *model.Organization) error {
+func (s *organizationServiceImpl) UpdateOrganization(ctx context.Context, org *model.Organization) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateOrganization")
+	defer span.End()
+
 	org.UpdatedAt = time.Now()
 
 	// Check if organization exists
-	_, err := s.repo.GetByID(org.ID)
+	existing, err := s.repo.GetByID(ctx, org.ID)
 	if err != nil {
-		return fmt.Errorf("organization not found with ID %s: %v", org.ID, err)
+		return fmt.Errorf("organization not found with ID %s: %w", org.ID, err)
 	}
 
-	if err := s.repo.Update(org); err != nil {
-		return fmt.Errorf("failed to update organization with ID %s: %v", org.ID, err)
+	// Status only moves through ChangeOrganizationStatus
+	org.Status = existing.Status
+
+	if err := s.repo.Update(ctx, org); err != nil {
+		return fmt.Errorf("failed to update organization with ID %s: %w", org.ID, err)
+	}
+
+	// Diff against the stored record: lifecycle fields are not part of the request
+	updated, err := s.repo.GetByID(ctx, org.ID)
+	if err != nil {
+		updated = org
 	}
+	s.recordChange(ctx, org.ID, "organization.update", existing, updated)
 
-	log.Printf("Organization updated: %+v", org)
+	slog.Info("Organization updated", "organization_id", org.ID)
 	return nil
 }
 
-// DeleteOrganization performs a soft delete
-func (s *organizationServiceImpl) DeleteOrganization(orgID uuid.UUID) error {
+// DeleteOrganization moves an organization to pending_deletion. Like other
+// soft-deleted records it can be restored until the grace period ends and
+// the purge job hard deletes it.
+func (s *organizationServiceImpl) DeleteOrganization(ctx context.Context, orgID, actorID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.DeleteOrganization")
+	defer span.End()
+
 	// Check if organization exists
-	_, err := s.repo.GetByID(orgID)
+	existing, err := s.repo.GetByID(ctx, orgID)
 	if err != nil {
-		return fmt.Errorf("organization not found with ID %s: %v", orgID, err)
+		return fmt.Errorf("organization not found with ID %s: %w", orgID, err)
+	}
+
+	if !existing.Status.CanTransitionTo(model.OrganizationPendingDeletion) {
+		return fmt.Errorf("%w: %s -> %s", ErrOrganizationStatusTransition, existing.Status, model.OrganizationPendingDeletion)
 	}
 
-	if err := s.repo.Delete(orgID); err != nil {
-		return fmt.Errorf("failed to delete organization with ID %s: %v", orgID, err)
+	at := time.Now().Add(OrganizationDeletionGracePeriod)
+	if err := s.repo.ChangeStatus(ctx, orgID, existing.Status, model.OrganizationPendingDeletion, "deleted", &actorID, &at); err != nil {
+		return fmt.Errorf("failed to delete organization with ID %s: %w", orgID, err)
 	}
 
+	deleted, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	s.recordChange(ctx, orgID, "organization.delete", existing, deleted)
+
 	log.Printf("Organization soft-deleted: %v", orgID)
 	return nil
 }
 
+// RestoreOrganization puts an organization pending deletion back in the
+// status it had before, or suspended when the history does not tell
+func (s *organizationServiceImpl) RestoreOrganization(ctx context.Context, orgID, actorID uuid.UUID) (*model.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RestoreOrganization")
+	defer span.End()
+
+	org, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("organization not found with ID %s: %w", orgID, err)
+	}
+	if org.Status != model.OrganizationPendingDeletion {
+		return nil, ErrOrganizationNotDeleted
+	}
+
+	events, err := s.repo.GetStatusEvents(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history for organization %s: %w", orgID, err)
+	}
+	previous := model.OrganizationSuspended
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ToStatus != model.OrganizationPendingDeletion {
+			continue
+		}
+		if from := events[i].FromStatus; from != model.OrganizationDeleted && org.Status.CanTransitionTo(from) {
+			previous = from
+		}
+		break
+	}
+	if !org.Status.CanTransitionTo(previous) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrOrganizationStatusTransition, org.Status, previous)
+	}
+
+	if err := s.repo.ChangeStatus(ctx, orgID, org.Status, previous, "restored", &actorID, nil); err != nil {
+		return nil, fmt.Errorf("failed to restore organization %s: %w", orgID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(ctx, orgID, "organization.restore", org, restored)
+
+	log.Printf("Organization %s restored to %s by %s", orgID, previous, actorID)
+	return restored, nil
+}
+
 // GetOrganizationByID retrieves a single organization
-func (s *organizationServiceImpl) GetOrganizationByID(orgID uuid.UUID) (*model.Organization, error) {
-	org, err := s.repo.GetByID(orgID)
+func (s *organizationServiceImpl) GetOrganizationByID(ctx context.Context, orgID uuid.UUID) (*model.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganizationByID")
+	defer span.End()
+
+	org, err := s.repo.GetByID(ctx, orgID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get organization by ID %s: %v", orgID, err)
+		return nil, fmt.Errorf("failed to get organization by ID %s: %w", orgID, err)
 	}
 	return org, nil
 }
 
-// GetAllOrganizations retrieves all organizations
-func (s *organizationServiceImpl) GetAllOrganizations() ([]*model.Organization, error) {
-	orgs, err := s.repo.GetAll()
+// ListOrganizations retrieves one page of organizations matching the filter
+func (s *organizationServiceImpl) ListOrganizations(ctx context.Context, filter model.OrganizationFilter, page model.PageRequest) (*model.Page[*model.Organization], error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ListOrganizations")
+	defer span.End()
+
+	orgs, err := s.repo.List(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all organizations: %v", err)
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
 	}
 	return orgs, nil
 }
+
+// ChangeOrganizationStatus moves an organization to a new lifecycle state,
+// enforcing the allowed transitions and recording reason and actor.
+func (s *organizationServiceImpl) ChangeOrganizationStatus(ctx context.Context, orgID uuid.UUID, status model.OrganizationStatus, reason string, actorID uuid.UUID) (*model.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ChangeOrganizationStatus")
+	defer span.End()
+
+	if !status.IsValid() {
+		return nil, ErrInvalidOrganizationStatus
+	}
+	if status == model.OrganizationDeleted {
+		// Hard deletes only happen through the scheduled purge
+		return nil, ErrOrganizationStatusTransition
+	}
+	if (status == model.OrganizationSuspended || status == model.OrganizationPendingDeletion) && strings.TrimSpace(reason) == "" {
+		return nil, ErrOrganizationStatusReason
+	}
+
+	org, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("organization not found with ID %s: %w", orgID, err)
+	}
+
+	if !org.Status.CanTransitionTo(status) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrOrganizationStatusTransition, org.Status, status)
+	}
+
+	var deletionScheduledAt *time.Time
+	if status == model.OrganizationPendingDeletion {
+		at := time.Now().Add(OrganizationDeletionGracePeriod)
+		deletionScheduledAt = &at
+	}
+
+	if err := s.repo.ChangeStatus(ctx, orgID, org.Status, status, reason, &actorID, deletionScheduledAt); err != nil {
+		return nil, fmt.Errorf("failed to change status of organization %s: %w", orgID, err)
+	}
+
+	log.Printf("Organization %s moved from %s to %s by %s", orgID, org.Status, status, actorID)
+	updated, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordChange(ctx, orgID, "organization.change_status", org, updated)
+	return updated, nil
+}
+
+// GetOrganizationStatusHistory returns every lifecycle transition of an organization
+func (s *organizationServiceImpl) GetOrganizationStatusHistory(ctx context.Context, orgID uuid.UUID) ([]*model.OrganizationStatusEvent, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganizationStatusHistory")
+	defer span.End()
+
+	events, err := s.repo.GetStatusEvents(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history for organization %s: %w", orgID, err)
+	}
+	return events, nil
+}
+
+// GetOrganizationPolicy returns the rules an organization sets for its members
+func (s *organizationServiceImpl) GetOrganizationPolicy(ctx context.Context, orgID uuid.UUID) (*model.OrganizationPolicy, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganizationPolicy")
+	defer span.End()
+
+	return s.repo.GetPolicy(ctx, orgID)
+}
+
+// UpdateOrganizationPolicy replaces the rules an organization sets for its members
+func (s *organizationServiceImpl) UpdateOrganizationPolicy(ctx context.Context, policy *model.OrganizationPolicy) (*model.OrganizationPolicy, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateOrganizationPolicy")
+	defer span.End()
+
+	existing, err := s.repo.GetPolicy(ctx, policy.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	s.recordChange(ctx, policy.OrganizationID, "organization.update_policy", existing, policy)
+	return policy, nil
+}
+
+// GetOrganizationsDueForDeletion lists organizations whose grace period has ended
+func (s *organizationServiceImpl) GetOrganizationsDueForDeletion(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetOrganizationsDueForDeletion")
+	defer span.End()
+
+	ids, err := s.repo.GetDueForDeletion(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations due for deletion: %w", err)
+	}
+	return ids, nil
+}
+
+// ExportOrganization returns a JSON export of all data held for an organization
+func (s *organizationServiceImpl) ExportOrganization(ctx context.Context, orgID uuid.UUID) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ExportOrganization")
+	defer span.End()
+
+	data, err := s.repo.Export(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export organization %s: %w", orgID, err)
+	}
+	return data, nil
+}
+
+// PurgeOrganization hard deletes an organization once its deletion is due.
+// Callers are expected to export the organization first.
+func (s *organizationServiceImpl) PurgeOrganization(ctx context.Context, orgID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.PurgeOrganization")
+	defer span.End()
+
+	org, err := s.repo.GetByID(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("organization not found with ID %s: %w", orgID, err)
+	}
+
+	if !org.Status.CanTransitionTo(model.OrganizationDeleted) {
+		return fmt.Errorf("%w: %s -> %s", ErrOrganizationStatusTransition, org.Status, model.OrganizationDeleted)
+	}
+	if org.DeletionScheduledAt == nil || org.DeletionScheduledAt.After(time.Now()) {
+		return fmt.Errorf("organization %s is not due for deletion", orgID)
+	}
+
+	// Files first: once the records are gone nothing points at them
+	keys, err := s.repo.GetStorageKeys(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to list stored files of organization %s: %w", orgID, err)
+	}
+	if failed := removeStoredFiles(ctx, s.store, keys); failed > 0 {
+		return fmt.Errorf("failed to remove %d stored files of organization %s, keeping it until the next run", failed, orgID)
+	}
+
+	if err := s.repo.HardDelete(ctx, orgID, "scheduled deletion"); err != nil {
+		return fmt.Errorf("failed to purge organization %s: %w", orgID, err)
+	}
+
+	s.recordChange(ctx, orgID, "organization.purge", org, nil)
+
+	log.Printf("Organization purged: %v", orgID)
+	return nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeLifecycleRepo holds one organization and the trail of its status changes
+type fakeLifecycleRepo struct {
+	repository.OrganizationRepository
+	org    model.Organization
+	events []*model.OrganizationStatusEvent
+}
+
+func (r *fakeLifecycleRepo) GetByID(_ context.Context, id uuid.UUID) (*model.Organization, error) {
+	if r.org.ID != id {
+		return nil, apperr.NotFound("organization_not_found", "organization not found")
+	}
+	copied := r.org
+	return &copied, nil
+}
+
+func (r *fakeLifecycleRepo) ChangeStatus(_ context.Context, id uuid.UUID, from, to model.OrganizationStatus, reason string, actorID *uuid.UUID, deletionScheduledAt *time.Time) error {
+	if r.org.ID != id || r.org.Status != from {
+		return errors.New("organization is not in the expected status")
+	}
+	r.org.Status = to
+	r.org.StatusReason = reason
+	r.org.DeletionScheduledAt = deletionScheduledAt
+	r.events = append(r.events, &model.OrganizationStatusEvent{OrganizationID: id, FromStatus: from, ToStatus: to, Reason: reason, ActorID: actorID})
+	return nil
+}
+
+func (r *fakeLifecycleRepo) GetStatusEvents(context.Context, uuid.UUID) ([]*model.OrganizationStatusEvent, error) {
+	return r.events, nil
+}
+
+func newTestLifecycle(status model.OrganizationStatus) (*organizationServiceImpl, *fakeLifecycleRepo) {
+	repo := &fakeLifecycleRepo{org: model.Organization{ID: uuid.Must(uuid.NewV4()), Status: status}}
+	return &organizationServiceImpl{repo: repo, audit: discardAudit{}}, repo
+}
+
+func TestChangeOrganizationStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    model.OrganizationStatus
+		to      model.OrganizationStatus
+		reason  string
+		wantErr error
+	}{
+		{"approve", model.OrganizationPending, model.OrganizationActive, "", nil},
+		{"suspend", model.OrganizationActive, model.OrganizationSuspended, "unpaid invoices", nil},
+		{"reactivate", model.OrganizationSuspended, model.OrganizationActive, "", nil},
+		{"schedule deletion", model.OrganizationActive, model.OrganizationPendingDeletion, "requested by the owner", nil},
+		{"suspend without reason", model.OrganizationActive, model.OrganizationSuspended, " ", ErrOrganizationStatusReason},
+		{"delete without reason", model.OrganizationActive, model.OrganizationPendingDeletion, "", ErrOrganizationStatusReason},
+		{"back to pending", model.OrganizationActive, model.OrganizationPending, "", ErrOrganizationStatusTransition},
+		{"suspend a pending organization", model.OrganizationPending, model.OrganizationSuspended, "spam", ErrOrganizationStatusTransition},
+		{"hard delete", model.OrganizationPendingDeletion, model.OrganizationDeleted, "done", ErrOrganizationStatusTransition},
+		{"unknown status", model.OrganizationActive, "archived", "", ErrInvalidOrganizationStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newTestLifecycle(tt.from)
+			actorID := uuid.Must(uuid.NewV4())
+
+			org, err := s.ChangeOrganizationStatus(context.Background(), repo.org.ID, tt.to, tt.reason, actorID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if repo.org.Status != tt.from || len(repo.events) != 0 {
+					t.Errorf("status changed to %s on a refused transition", repo.org.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeOrganizationStatus: %v", err)
+			}
+			if org.Status != tt.to {
+				t.Errorf("status = %s, want %s", org.Status, tt.to)
+			}
+			if len(repo.events) != 1 || *repo.events[0].ActorID != actorID {
+				t.Errorf("events = %v, want one by the actor", repo.events)
+			}
+			if scheduled := org.DeletionScheduledAt != nil; scheduled != (tt.to == model.OrganizationPendingDeletion) {
+				t.Errorf("deletion scheduled = %v for %s", scheduled, tt.to)
+			}
+		})
+	}
+}
+
+func TestDeleteAndRestoreOrganization(t *testing.T) {
+	tests := []struct {
+		name string
+		from model.OrganizationStatus
+	}{
+		{"pending", model.OrganizationPending},
+		{"active", model.OrganizationActive},
+		{"suspended", model.OrganizationSuspended},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newTestLifecycle(tt.from)
+			ctx := context.Background()
+			actorID := uuid.Must(uuid.NewV4())
+
+			if err := s.DeleteOrganization(ctx, repo.org.ID, actorID); err != nil {
+				t.Fatalf("DeleteOrganization: %v", err)
+			}
+			if repo.org.Status != model.OrganizationPendingDeletion || repo.org.DeletionScheduledAt == nil {
+				t.Fatalf("after delete: status %s, deletion scheduled at %v", repo.org.Status, repo.org.DeletionScheduledAt)
+			}
+			if grace := time.Until(*repo.org.DeletionScheduledAt); grace < OrganizationDeletionGracePeriod-time.Minute {
+				t.Errorf("deletion scheduled in %v, want the grace period", grace)
+			}
+
+			restored, err := s.RestoreOrganization(ctx, repo.org.ID, actorID)
+			if err != nil {
+				t.Fatalf("RestoreOrganization: %v", err)
+			}
+			if restored.Status != tt.from {
+				t.Errorf("restored to %s, want %s", restored.Status, tt.from)
+			}
+			if restored.DeletionScheduledAt != nil {
+				t.Errorf("deletion still scheduled at %v", restored.DeletionScheduledAt)
+			}
+
+			if _, err := s.RestoreOrganization(ctx, repo.org.ID, actorID); !errors.Is(err, ErrOrganizationNotDeleted) {
+				t.Errorf("second restore error = %v, want ErrOrganizationNotDeleted", err)
+			}
+		})
+	}
+}
+
+func TestRestoreOrganizationWithoutHistory(t *testing.T) {
+	s, repo := newTestLifecycle(model.OrganizationPendingDeletion)
+
+	restored, err := s.RestoreOrganization(context.Background(), repo.org.ID, uuid.Must(uuid.NewV4()))
+	if err != nil {
+		t.Fatalf("RestoreOrganization: %v", err)
+	}
+	if restored.Status != model.OrganizationSuspended {
+		t.Errorf("restored to %s, want suspended", restored.Status)
+	}
+}
@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/tracing"
 	"fmt"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -12,90 +16,164 @@ import (
 
 // OrganizationAdminService interface with CRUD methods
 type OrganizationAdminService interface {
-	CreateAdmin(admin *model.OrganizationAdmin) (*model.OrganizationAdmin, error)
-	UpdateAdmin(admin *model.OrganizationAdmin) error
-	DeleteAdmin(adminID uuid.UUID) error
-	GetAdminByID(adminID uuid.UUID) (*model.OrganizationAdmin, error)
-	GetAllAdmins() ([]*model.OrganizationAdmin, error)
+	CreateAdmin(ctx context.Context, admin *model.OrganizationAdmin) (*model.OrganizationAdmin, error)
+	UpdateAdmin(ctx context.Context, admin *model.OrganizationAdmin) error
+	DeleteAdmin(ctx context.Context, adminID uuid.UUID) error
+	// RestoreAdmin brings back a soft-deleted admin until the retention job has purged it
+	RestoreAdmin(ctx context.Context, adminID uuid.UUID) (*model.OrganizationAdmin, error)
+	GetAdminByID(ctx context.Context, adminID uuid.UUID) (*model.OrganizationAdmin, error)
+	ListAdmins(ctx context.Context, filter model.OrganizationAdminFilter, page model.PageRequest) (*model.Page[*model.OrganizationAdmin], error)
 }
 
 // organizationAdminServiceImpl struct implementing OrganizationAdminService
 type organizationAdminServiceImpl struct {
-	repo repository.OrganizationAdminRepository
+	repo  repository.OrganizationAdminRepository
+	audit AuditService
 }
 
 // Constructor
-func NewOrganizationAdminService(adminRepo repository.OrganizationAdminRepository) OrganizationAdminService {
+func NewOrganizationAdminService(adminRepo repository.OrganizationAdminRepository, auditService AuditService) OrganizationAdminService {
 	return &organizationAdminServiceImpl{
-		repo: adminRepo,
+		repo:  adminRepo,
+		audit: auditService,
 	}
 }
 
 // CreateAdmin creates a new organization admin
-func (s *organizationAdminServiceImpl) CreateAdmin(admin *model.OrganizationAdmin) (*model.OrganizationAdmin, error) {
+func (s *organizationAdminServiceImpl) CreateAdmin(ctx context.Context, admin *model.OrganizationAdmin) (*model.OrganizationAdmin, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.CreateAdmin")
+	defer span.End()
+
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
 	admin.ID = newID
 	admin.CreatedAt = time.Now()
 
-	log.Printf("Creating organization admin: %+v", admin)
+	slog.Info("Creating organization admin", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 
-	if err := s.repo.Create(admin); err != nil {
-		return nil, fmt.Errorf("failed to create admin: %v", err)
+	if err := s.repo.Create(ctx, admin); err != nil {
+		return nil, fmt.Errorf("failed to create admin: %w", err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &admin.OrganizationID,
+		Action:         "organization_admin.create",
+		TargetType:     model.AuditTargetOrganizationAdmin,
+		TargetID:       &admin.ID,
+		Changes:        audit.Diff(nil, admin),
+	})
+
 	return admin, nil
 }
 
 // UpdateAdmin updates an existing admin
-func (s *organizationAdminServiceImpl) UpdateAdmin(admin *model.OrganizationAdmin) error {
+func (s *organizationAdminServiceImpl) UpdateAdmin(ctx context.Context, admin *model.OrganizationAdmin) error {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.UpdateAdmin")
+	defer span.End()
+
 	// Check if admin exists
-	_, err := s.repo.GetByID(admin.ID)
+	existing, err := s.repo.GetByID(ctx, admin.ID)
 	if err != nil {
-		return fmt.Errorf("admin not found with ID %s: %v", admin.ID, err)
+		return fmt.Errorf("admin not found with ID %s: %w", admin.ID, err)
 	}
 
-	if err := s.repo.Update(admin); err != nil {
-		return fmt.Errorf("failed to update admin with ID %s: %v", admin.ID, err)
+	if err := s.repo.Update(ctx, admin); err != nil {
+		return fmt.Errorf("failed to update admin with ID %s: %w", admin.ID, err)
 	}
 
-	log.Printf("Organization admin updated: %+v", admin)
+	// Diff against the stored record: the request may leave fields out
+	updated, err := s.repo.GetByID(ctx, admin.ID)
+	if err != nil {
+		updated = admin
+	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_admin.update",
+		TargetType:     model.AuditTargetOrganizationAdmin,
+		TargetID:       &admin.ID,
+		Changes:        audit.Diff(existing, updated),
+	})
+
+	slog.Info("Organization admin updated", "admin_id", admin.ID, "organization_id", admin.OrganizationID)
 	return nil
 }
 
 // DeleteAdmin performs a soft delete
-func (s *organizationAdminServiceImpl) DeleteAdmin(adminID uuid.UUID) error {
+func (s *organizationAdminServiceImpl) DeleteAdmin(ctx context.Context, adminID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.DeleteAdmin")
+	defer span.End()
+
 	// Check if admin exists
-	_, err := s.repo.GetByID(adminID)
+	existing, err := s.repo.GetByID(ctx, adminID)
 	if err != nil {
-		return fmt.Errorf("admin not found with ID %s: %v", adminID, err)
+		return fmt.Errorf("admin not found with ID %s: %w", adminID, err)
 	}
 
-	if err := s.repo.Delete(adminID); err != nil {
-		return fmt.Errorf("failed to delete admin with ID %s: %v", adminID, err)
+	if err := s.repo.Delete(ctx, adminID); err != nil {
+		return fmt.Errorf("failed to delete admin with ID %s: %w", adminID, err)
 	}
 
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &existing.OrganizationID,
+		Action:         "organization_admin.delete",
+		TargetType:     model.AuditTargetOrganizationAdmin,
+		TargetID:       &adminID,
+		Changes:        audit.Diff(existing, nil),
+	})
+
 	log.Printf("Organization admin deleted: %v", adminID)
 	return nil
 }
 
+// RestoreAdmin undoes a soft delete
+func (s *organizationAdminServiceImpl) RestoreAdmin(ctx context.Context, adminID uuid.UUID) (*model.OrganizationAdmin, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.RestoreAdmin")
+	defer span.End()
+
+	if err := s.repo.Restore(ctx, adminID); err != nil {
+		return nil, fmt.Errorf("failed to restore admin with ID %s: %w", adminID, err)
+	}
+
+	restored, err := s.repo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored admin with ID %s: %w", adminID, err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &restored.OrganizationID,
+		Action:         "organization_admin.restore",
+		TargetType:     model.AuditTargetOrganizationAdmin,
+		TargetID:       &adminID,
+		Changes:        audit.Diff(nil, restored),
+	})
+
+	log.Printf("Organization admin restored: %v", adminID)
+	return restored, nil
+}
+
 // GetAdminByID retrieves a single admin by ID
-func (s *organizationAdminServiceImpl) GetAdminByID(adminID uuid.UUID) (*model.OrganizationAdmin, error) {
-	admin, err := s.repo.GetByID(adminID)
+func (s *organizationAdminServiceImpl) GetAdminByID(ctx context.Context, adminID uuid.UUID) (*model.OrganizationAdmin, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.GetAdminByID")
+	defer span.End()
+
+	admin, err := s.repo.GetByID(ctx, adminID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get admin by ID %s: %v", adminID, err)
+		return nil, fmt.Errorf("failed to get admin by ID %s: %w", adminID, err)
 	}
 	return admin, nil
 }
 
-// GetAllAdmins retrieves all organization admins
-func (s *organizationAdminServiceImpl) GetAllAdmins() ([]*model.OrganizationAdmin, error) {
-	admins, err := s.repo.GetAll()
+// ListAdmins retrieves one page of organization admins matching the filter
+func (s *organizationAdminServiceImpl) ListAdmins(ctx context.Context, filter model.OrganizationAdminFilter, page model.PageRequest) (*model.Page[*model.OrganizationAdmin], error) {
+	ctx, span := tracing.Start(ctx, "OrganizationAdminService.ListAdmins")
+	defer span.End()
+
+	admins, err := s.repo.List(ctx, filter, page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all admins: %v", err)
+		return nil, fmt.Errorf("failed to list admins: %w", err)
 	}
 	return admins, nil
 }
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/sso"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrInvalidIssuer   = apperr.Validation("invalid_issuer", "issuer must be a valid OpenID Connect provider URL")
+	ErrInvalidSSORole  = apperr.Validation("invalid_sso_role", "default role must be student or instructor")
+	ErrSSODomainsEmpty = apperr.Validation("sso_domains_required", "at least one allowed email domain is required")
+
+	ErrInvalidSAMLMetadata    = apperr.Validation("invalid_saml_metadata", "identity provider metadata needs an entity ID, a redirect sign-on endpoint and a signing certificate")
+	ErrInvalidSAMLMetadataURL = apperr.Validation("invalid_saml_metadata_url", "metadata URL must be an https URL")
+	ErrSAMLMetadataRequired   = apperr.Validation("saml_metadata_required", "identity provider metadata or its URL is required")
+)
+
+// ssoRoles are the roles users provisioned through SSO may get; admins are never created this way
+var ssoRoles = []string{"student", "instructor"}
+
+// GetSSOConfig returns the identity provider of an organization
+func (s *organizationServiceImpl) GetSSOConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSSO, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetSSOConfig")
+	defer span.End()
+
+	return s.ssoRepo.GetConfig(ctx, orgID)
+}
+
+// UpdateSSOConfig creates or replaces the identity provider of an
+// organization. The issuer is discovered before saving so that a typo does
+// not lock the organization's users out. A nil clientSecret keeps the stored one.
+func (s *organizationServiceImpl) UpdateSSOConfig(ctx context.Context, config *model.OrganizationSSO, clientSecret *string) (*model.OrganizationSSO, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateSSOConfig")
+	defer span.End()
+
+	if _, err := s.repo.GetByID(ctx, config.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	config.Issuer = strings.TrimRight(strings.TrimSpace(config.Issuer), "/")
+	issuer, err := url.Parse(config.Issuer)
+	if err != nil || issuer.Host == "" || (issuer.Scheme != "https" && !(s.opts.AllowInsecureIssuers && issuer.Scheme == "http")) {
+		return nil, ErrInvalidIssuer
+	}
+	if !slices.Contains(ssoRoles, config.DefaultRole) {
+		return nil, ErrInvalidSSORole
+	}
+
+	domains, err := normalizeSSODomains(config.AllowedDomains)
+	if err != nil {
+		return nil, err
+	}
+	config.AllowedDomains = domains
+
+	if err := s.ssoClient.Discover(ctx, config.Issuer); err != nil {
+		slog.Warn("SSO issuer discovery failed", "organization_id", config.OrganizationID, "issuer", config.Issuer, "error", err)
+		return nil, ErrInvalidIssuer.Wrap(err)
+	}
+
+	var sealedSecret *string
+	if clientSecret != nil {
+		sealed := ""
+		if *clientSecret != "" {
+			sealed, err = utils.Seal(s.opts.EncryptionKey, *clientSecret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt client secret: %w", err)
+			}
+		}
+		sealedSecret = &sealed
+	}
+
+	existing, err := s.ssoRepo.GetConfig(ctx, config.OrganizationID)
+	if err != nil && apperr.KindOf(err) != apperr.KindNotFound {
+		return nil, err
+	}
+
+	if err := s.ssoRepo.SaveConfig(ctx, config, sealedSecret); err != nil {
+		return nil, err
+	}
+	saved, err := s.ssoRepo.GetConfig(ctx, config.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The secret itself is never serialized; record that it was replaced
+	changes := audit.Diff(existing, saved)
+	if clientSecret != nil {
+		changes["client_secret"] = model.AuditChange{To: logger.Redacted}
+	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		OrganizationID: &config.OrganizationID,
+		Action:         "organization.update_sso",
+		TargetType:     model.AuditTargetOrganization,
+		TargetID:       &config.OrganizationID,
+		Changes:        changes,
+	})
+	return saved, nil
+}
+
+// DeleteSSOConfig removes the identity provider of an organization. Linked
+// accounts keep their membership; users created through SSO can set a
+// password with the reset flow.
+func (s *organizationServiceImpl) DeleteSSOConfig(ctx context.Context, orgID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.DeleteSSOConfig")
+	defer span.End()
+
+	existing, err := s.ssoRepo.GetConfig(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if err := s.ssoRepo.DeleteConfig(ctx, orgID); err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, orgID, "organization.delete_sso", existing, nil)
+	return nil
+}
+
+// GetSAMLConfig returns the SAML identity provider of an organization with
+// the service provider details to register with it
+func (s *organizationServiceImpl) GetSAMLConfig(ctx context.Context, orgID uuid.UUID) (*model.OrganizationSAML, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.GetSAMLConfig")
+	defer span.End()
+
+	config, err := s.ssoRepo.GetSAMLConfig(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	setSAMLEndpoints(config, s.opts.PublicURL)
+	return config, nil
+}
+
+// UpdateSAMLConfig creates or replaces the SAML identity provider of an
+// organization. Metadata is imported from idpMetadata or fetched from
+// idpMetadataURL; with neither, the stored metadata is kept. The service
+// provider key pair is generated once and kept across updates, so the
+// identity provider does not have to be reconfigured.
+func (s *organizationServiceImpl) UpdateSAMLConfig(ctx context.Context, config *model.OrganizationSAML, idpMetadata, idpMetadataURL string) (*model.OrganizationSAML, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateSAMLConfig")
+	defer span.End()
+
+	if _, err := s.repo.GetByID(ctx, config.OrganizationID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.ssoRepo.GetSAMLConfig(ctx, config.OrganizationID)
+	if err != nil && apperr.KindOf(err) != apperr.KindNotFound {
+		return nil, err
+	}
+
+	if err := s.validateSAMLConfig(config); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case idpMetadata != "":
+		config.IdPMetadata = idpMetadata
+	case idpMetadataURL != "":
+		metadataURL, err := url.Parse(idpMetadataURL)
+		if err != nil || metadataURL.Host == "" || (metadataURL.Scheme != "https" && !(s.opts.AllowInsecureIssuers && metadataURL.Scheme == "http")) {
+			return nil, ErrInvalidSAMLMetadataURL
+		}
+		data, err := s.ssoClient.FetchSAMLMetadata(ctx, idpMetadataURL)
+		if err != nil {
+			slog.Warn("SAML metadata import failed", "organization_id", config.OrganizationID, "url", idpMetadataURL, "error", err)
+			return nil, ErrInvalidSAMLMetadata.Wrap(err)
+		}
+		config.IdPMetadata = string(data)
+		config.IdPMetadataURL = idpMetadataURL
+	case existing != nil:
+		config.IdPMetadata = existing.IdPMetadata
+		config.IdPMetadataURL = existing.IdPMetadataURL
+	default:
+		return nil, ErrSAMLMetadataRequired
+	}
+
+	idp, err := sso.ParseSAMLMetadata([]byte(config.IdPMetadata))
+	if err != nil {
+		return nil, ErrInvalidSAMLMetadata.Wrap(err)
+	}
+	config.IdPEntityID = idp.EntityID
+	config.IdPSSOURL = idp.SSOURL
+
+	if existing != nil {
+		config.SPCertificate = existing.SPCertificate
+		config.SPPrivateKey = existing.SPPrivateKey
+	} else {
+		setSAMLEndpoints(config, s.opts.PublicURL)
+		certPEM, keyPEM, err := sso.GenerateSAMLKeyPair(config.SPEntityID)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := utils.Seal(s.opts.EncryptionKey, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt SAML key: %w", err)
+		}
+		config.SPCertificate = certPEM
+		config.SPPrivateKey = sealed
+	}
+
+	if err := s.ssoRepo.SaveSAMLConfig(ctx, config); err != nil {
+		return nil, err
+	}
+	setSAMLEndpoints(config, s.opts.PublicURL)
+
+	if existing != nil {
+		setSAMLEndpoints(existing, s.opts.PublicURL)
+	}
+	s.recordChange(ctx, config.OrganizationID, "organization.update_saml", existing, config)
+	return config, nil
+}
+
+// DeleteSAMLConfig removes the SAML identity provider of an organization
+func (s *organizationServiceImpl) DeleteSAMLConfig(ctx context.Context, orgID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.DeleteSAMLConfig")
+	defer span.End()
+
+	existing, err := s.ssoRepo.GetSAMLConfig(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if err := s.ssoRepo.DeleteSAMLConfig(ctx, orgID); err != nil {
+		return err
+	}
+
+	s.recordChange(ctx, orgID, "organization.delete_saml", existing, nil)
+	return nil
+}
+
+// validateSAMLConfig normalizes the domains and checks the role mapping,
+// filling in the standard attributes where none are given
+func (s *organizationServiceImpl) validateSAMLConfig(config *model.OrganizationSAML) error {
+	if !slices.Contains(ssoRoles, config.DefaultRole) {
+		return ErrInvalidSSORole
+	}
+
+	domains, err := normalizeSSODomains(config.AllowedDomains)
+	if err != nil {
+		return err
+	}
+	config.AllowedDomains = domains
+
+	defaults := defaultSAMLAttributeMapping()
+	mapping := &config.AttributeMapping
+	if mapping.Email == "" {
+		mapping.Email = defaults.Email
+	}
+	if mapping.FirstName == "" {
+		mapping.FirstName = defaults.FirstName
+	}
+	if mapping.LastName == "" {
+		mapping.LastName = defaults.LastName
+	}
+	if mapping.Role == "" {
+		mapping.Role = defaults.Role
+	}
+	if mapping.RoleValues == nil {
+		mapping.RoleValues = defaults.RoleValues
+	}
+	// Values are matched case-insensitively
+	roleValues := make(map[string]string, len(mapping.RoleValues))
+	for value, role := range mapping.RoleValues {
+		if !slices.Contains(ssoRoles, role) {
+			return ErrInvalidSSORole
+		}
+		roleValues[strings.ToLower(strings.TrimSpace(value))] = role
+	}
+	mapping.RoleValues = roleValues
+	return nil
+}
+
+// normalizeSSODomains lowercases and deduplicates allowed email domains
+func normalizeSSODomains(allowed []string) ([]string, error) {
+	domains := make([]string, 0, len(allowed))
+	for _, d := range allowed {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" && !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, ErrSSODomainsEmpty
+	}
+	return domains, nil
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// exportBatchSize is how many data exports one job run prepares
+const exportBatchSize = 5
+
+var ErrUserAlreadyErased = apperr.Conflict("user_already_erased", "user has already been erased")
+
+// PrivacyService fulfils data subject requests: exporting everything stored
+// about a user and erasing a user
+type PrivacyService interface {
+	// RequestExport queues an export of userID's data; requestedBy is the
+	// signed-in user, who is either userID or a platform admin
+	RequestExport(ctx context.Context, userID, requestedBy uuid.UUID) (*model.UserDataExport, error)
+	// GetExport returns an export of userID with a download link while it is ready
+	GetExport(ctx context.Context, userID, exportID uuid.UUID) (*model.UserDataExport, error)
+	ListExports(ctx context.Context, userID uuid.UUID) ([]*model.UserDataExport, error)
+	// ProcessPendingExports prepares queued exports; run by a background job
+	ProcessPendingExports(ctx context.Context) error
+	// PurgeExpiredExports deletes archives past their expiry; run by a background job
+	PurgeExpiredExports(ctx context.Context) error
+
+	// EraseOwnAccount erases the signed-in user after checking their password
+	EraseOwnAccount(ctx context.Context, userID uuid.UUID, currentPassword string) error
+	// EraseUser erases any user; reserved for platform admins
+	EraseUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// PrivacyServiceOptions holds the settings of a PrivacyService
+type PrivacyServiceOptions struct {
+	ExportTTL   time.Duration // how long a finished archive can be downloaded
+	FrontendURL string        // base of the page users are sent to when their export is ready
+}
+
+type privacyService struct {
+	repo     repository.PrivacyRepository
+	userRepo repository.UserRepository
+	store    storage.Storage
+	mailer   mail.Sender
+	audit    AuditService
+	opts     PrivacyServiceOptions
+}
+
+// NewPrivacyService creates a new PrivacyService
+func NewPrivacyService(repo repository.PrivacyRepository, userRepo repository.UserRepository, store storage.Storage,
+	mailer mail.Sender, auditService AuditService, opts PrivacyServiceOptions) PrivacyService {
+	return &privacyService{
+		repo:     repo,
+		userRepo: userRepo,
+		store:    store,
+		mailer:   mailer,
+		audit:    auditService,
+		opts:     opts,
+	}
+}
+
+// RequestExport queues an export; the background job prepares it
+func (s *privacyService) RequestExport(ctx context.Context, userID, requestedBy uuid.UUID) (*model.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.RequestExport")
+	defer span.End()
+
+	if _, err := s.userRepo.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var onBehalfOf *uuid.UUID
+	if requestedBy != userID {
+		onBehalfOf = &requestedBy
+	}
+	exportID, err := s.repo.CreateExport(ctx, userID, onBehalfOf)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     "user.export",
+		TargetType: model.AuditTargetUser,
+		TargetID:   &userID,
+	})
+
+	return s.repo.GetExport(ctx, exportID)
+}
+
+// GetExport returns one of a user's exports
+func (s *privacyService) GetExport(ctx context.Context, userID, exportID uuid.UUID) (*model.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.GetExport")
+	defer span.End()
+
+	export, err := s.repo.GetExport(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	// Exports of other users do not exist as far as the caller is concerned
+	if export.UserID != userID {
+		return nil, apperr.NotFound("user_data_export_not_found", "data export not found")
+	}
+
+	if err := s.signDownload(export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ListExports returns a user's exports, newest first
+func (s *privacyService) ListExports(ctx context.Context, userID uuid.UUID) ([]*model.UserDataExport, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ListExports")
+	defer span.End()
+
+	exports, err := s.repo.ListExportsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+	for _, export := range exports {
+		if err := s.signDownload(export); err != nil {
+			return nil, err
+		}
+	}
+	return exports, nil
+}
+
+// signDownload sets the download link of a ready, unexpired export
+func (s *privacyService) signDownload(export *model.UserDataExport) error {
+	if export.Status != model.UserDataExportReady || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil
+	}
+
+	url, err := s.store.SignedURL(export.StorageKey, SignedURLExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to sign data export link: %w", err)
+	}
+	export.DownloadURL = url
+	return nil
+}
+
+// ProcessPendingExports builds the archives of a batch of queued exports.
+// A failed export is marked as such and does not stop the others.
+func (s *privacyService) ProcessPendingExports(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ProcessPendingExports")
+	defer span.End()
+
+	exports, err := s.repo.ClaimExports(ctx, exportBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to claim data exports: %w", err)
+	}
+
+	for _, export := range exports {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.prepareExport(ctx, export); err != nil {
+			logger.FromContext(ctx).Error("Data export failed", "export_id", export.ID, "user_id", export.UserID, "error", err)
+			if err := s.repo.FailExport(ctx, export.ID, err.Error()); err != nil {
+				return fmt.Errorf("failed to mark data export %s as failed: %w", export.ID, err)
+			}
+			continue
+		}
+		s.notifyExportReady(ctx, export)
+	}
+	return nil
+}
+
+// prepareExport writes the archive of an export to storage
+func (s *privacyService) prepareExport(ctx context.Context, export *model.UserDataExport) error {
+	files, err := s.repo.ExportUserData(ctx, export.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to collect user data: %w", err)
+	}
+
+	archive, err := zipJSONFiles(files)
+	if err != nil {
+		return fmt.Errorf("failed to build archive: %w", err)
+	}
+
+	key := fmt.Sprintf("exports/users/%s/%s.zip", export.UserID, export.ID)
+	if err := s.store.Put(key, bytes.NewReader(archive), int64(len(archive)), "application/zip"); err != nil {
+		return fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	if err := s.repo.CompleteExport(ctx, export.ID, key, time.Now().Add(s.opts.ExportTTL)); err != nil {
+		// Do not leave an archive nobody can find
+		if delErr := s.store.Delete(key); delErr != nil {
+			logger.FromContext(ctx).Error("Failed to delete orphaned data export", "key", key, "error", delErr)
+		}
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+	return nil
+}
+
+// notifyExportReady mails the user that their archive can be downloaded.
+// The link leads to the signed-in account page, not to the archive itself.
+func (s *privacyService) notifyExportReady(ctx context.Context, export *model.UserDataExport) {
+	user, err := s.userRepo.Get(ctx, export.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to load user for data export notice", "user_id", export.UserID, "error", err)
+		return
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"The export of your personal data is ready. Sign in and download it within %d days:\n\n%s\n\n"+
+			"If you did not ask for this export, contact support.\n",
+			user.FirstName, int(s.opts.ExportTTL.Hours()/24), s.opts.FrontendURL+"/account/data-exports/"+export.ID.String()),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logger.FromContext(ctx).Error("Failed to send data export notice", "user_id", export.UserID, "error", err)
+	}
+}
+
+// zipJSONFiles packs each entry as an indented <name>.json file
+func zipJSONFiles(files map[string]json.RawMessage) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, files[name], "", "  "); err != nil {
+			return nil, err
+		}
+		w, err := zw.Create(name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(pretty.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PurgeExpiredExports deletes expired archives and their records
+func (s *privacyService) PurgeExpiredExports(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.PurgeExpiredExports")
+	defer span.End()
+
+	exports, err := s.repo.ListExpiredExports(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+
+	for _, export := range exports {
+		if export.StorageKey != "" {
+			if err := s.store.Delete(export.StorageKey); err != nil {
+				// Keep the record so the next run tries again
+				logger.FromContext(ctx).Error("Failed to delete expired data export", "export_id", export.ID, "error", err)
+				continue
+			}
+		}
+		if err := s.repo.DeleteExport(ctx, export.ID); err != nil {
+			return fmt.Errorf("failed to delete data export %s: %w", export.ID, err)
+		}
+	}
+	return nil
+}
+
+// EraseOwnAccount checks the password before erasing the signed-in user
+func (s *privacyService) EraseOwnAccount(ctx context.Context, userID uuid.UUID, currentPassword string) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.EraseOwnAccount")
+	defer span.End()
+
+	user, err := s.userRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return ErrCurrentPasswordIncorrect
+	}
+	return s.erase(ctx, userID)
+}
+
+// EraseUser erases a user on an admin's request
+func (s *privacyService) EraseUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.EraseUser")
+	defer span.End()
+
+	if _, err := s.userRepo.Get(ctx, userID); err != nil {
+		return err
+	}
+	return s.erase(ctx, userID)
+}
+
+// erase deletes the user's export archives, then anonymizes the user. The
+// audit event names the user by ID only.
+func (s *privacyService) erase(ctx context.Context, userID uuid.UUID) error {
+	exports, err := s.repo.ListExportsByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list data exports: %w", err)
+	}
+	for _, export := range exports {
+		if export.StorageKey == "" {
+			continue
+		}
+		if err := s.store.Delete(export.StorageKey); err != nil {
+			return fmt.Errorf("failed to delete data export %s: %w", export.ID, err)
+		}
+	}
+
+	erased, err := s.repo.EraseUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to erase user: %w", err)
+	}
+	if !erased {
+		return ErrUserAlreadyErased
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     "user.erase",
+		TargetType: model.AuditTargetUser,
+		TargetID:   &userID,
+	})
+	logger.FromContext(ctx).Info("User erased", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/storage"
+	"e-learning-system/internal/tracing"
+	"fmt"
+	"path"
+	"time"
+)
+
+// RetentionService enforces how long deleted records are kept
+type RetentionService interface {
+	// PurgeSoftDeleted hard deletes records soft-deleted before the given
+	// time, together with their stored files, and returns how many went.
+	// Users are erased instead.
+	PurgeSoftDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+type retentionServiceImpl struct {
+	repo  repository.RetentionRepository
+	store storage.Storage
+	audit AuditService
+}
+
+// NewRetentionService creates a new RetentionService
+func NewRetentionService(repo repository.RetentionRepository, store storage.Storage, auditService AuditService) RetentionService {
+	return &retentionServiceImpl{repo: repo, store: store, audit: auditService}
+}
+
+// PurgeSoftDeleted purges expired records, noting each kind purged in the audit log
+func (s *retentionServiceImpl) PurgeSoftDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "RetentionService.PurgeSoftDeleted")
+	defer span.End()
+
+	// Files first: once the records are gone nothing points at them
+	if err := s.removeExpiredObjects(ctx, before); err != nil {
+		return 0, err
+	}
+
+	purged, err := s.repo.PurgeSoftDeleted(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge soft-deleted records: %w", err)
+	}
+
+	var total int64
+	for recordType, n := range purged {
+		if n == 0 {
+			continue
+		}
+		total += n
+		s.audit.Record(ctx, &model.AuditEvent{
+			Action:     recordType + ".purge",
+			TargetType: recordType,
+			Changes: map[string]model.AuditChange{
+				"purged":         {To: n},
+				"deleted_before": {To: before.UTC().Format(time.RFC3339)},
+			},
+		})
+		logger.FromContext(ctx).Info("Soft-deleted records purged", "record_type", recordType, "count", n)
+	}
+	return total, nil
+}
+
+// removeExpiredObjects deletes the stored files of the assets and lessons
+// about to be purged. Any failure keeps every record for the next run.
+func (s *retentionServiceImpl) removeExpiredObjects(ctx context.Context, before time.Time) error {
+	keys, err := s.repo.GetExpiredStorageKeys(ctx, before)
+	if err != nil {
+		return fmt.Errorf("failed to list stored files of expired records: %w", err)
+	}
+
+	if failed := removeStoredFiles(ctx, s.store, keys); failed > 0 {
+		return fmt.Errorf("failed to remove %d stored files, keeping expired records until the next run", failed)
+	}
+	return nil
+}
+
+// removeStoredFiles deletes the files under keys, whole HLS packages for
+// playlists, and returns how many could not be removed
+func removeStoredFiles(ctx context.Context, store storage.Storage, keys []string) int {
+	var failed int
+	for _, key := range keys {
+		objects := []string{key}
+		if path.Ext(key) == ".m3u8" {
+			var err error
+			if objects, err = hlsPackageKeys(store, key); err != nil {
+				logger.FromContext(ctx).Error("Failed to list HLS package", "key", key, "error", err)
+				failed++
+				continue
+			}
+		}
+
+		for _, object := range objects {
+			if err := store.Delete(object); err != nil {
+				logger.FromContext(ctx).Error("Failed to remove stored object", "key", object, "error", err)
+				failed++
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/sso"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"strings"
+)
+
+// defaultSAMLAttributeMapping reads the standard eduPerson and X.500
+// attributes most university identity providers release
+func defaultSAMLAttributeMapping() model.SAMLAttributeMapping {
+	return model.SAMLAttributeMapping{
+		Email:     "urn:oid:0.9.2342.19200300.100.1.3", // mail
+		FirstName: "urn:oid:2.5.4.42",                  // givenName
+		LastName:  "urn:oid:2.5.4.4",                   // sn
+		Role:      "urn:oid:1.3.6.1.4.1.5923.1.1.1.1",  // eduPersonAffiliation
+		RoleValues: map[string]string{
+			"faculty": "instructor",
+			"staff":   "instructor",
+			"student": "student",
+		},
+	}
+}
+
+// setSAMLEndpoints fills in the service provider URLs of an organization.
+// The entity ID is the metadata URL, as identity providers expect.
+func setSAMLEndpoints(config *model.OrganizationSAML, publicURL string) {
+	base := strings.TrimRight(publicURL, "/") + "/saml/" + config.OrganizationID.String()
+	config.SPEntityID = base + "/metadata"
+	config.SPACSURL = base + "/acs"
+}
+
+// samlServiceProvider builds the service provider of an organization from
+// its stored configuration
+func samlServiceProvider(config *model.OrganizationSAML, publicURL string, encryptionKey []byte) (*sso.SAMLServiceProvider, error) {
+	setSAMLEndpoints(config, publicURL)
+
+	key, err := utils.Open(encryptionKey, config.SPPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt SAML key: %w", err)
+	}
+	return sso.NewSAMLServiceProvider(sso.SAMLConfig{
+		EntityID:       config.SPEntityID,
+		MetadataURL:    config.SPEntityID,
+		ACSURL:         config.SPACSURL,
+		CertificatePEM: config.SPCertificate,
+		KeyPEM:         key,
+		IdPMetadata:    []byte(config.IdPMetadata),
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"e-learning-system/internal/domain/model"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestMapSAMLRole(t *testing.T) {
+	mapping := defaultSAMLAttributeMapping()
+
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"student", []string{"student"}, "student"},
+		{"faculty", []string{"faculty"}, "instructor"},
+		{"staff", []string{"staff"}, "instructor"},
+		{"case and spaces", []string{" Faculty "}, "instructor"},
+		{"most privileged wins", []string{"student", "staff"}, "instructor"},
+		{"most privileged first", []string{"faculty", "student"}, "instructor"},
+		{"unmapped values skipped", []string{"member", "student"}, "student"},
+		{"only unmapped values", []string{"member", "alum"}, ""},
+		{"no values", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapSAMLRole(mapping, tt.values); got != tt.want {
+				t.Errorf("mapSAMLRole(%q) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSAMLEndpoints(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	base := "https://api.example.com/saml/" + orgID.String()
+
+	for _, publicURL := range []string{"https://api.example.com", "https://api.example.com/"} {
+		config := &model.OrganizationSAML{OrganizationID: orgID}
+		setSAMLEndpoints(config, publicURL)
+		if config.SPEntityID != base+"/metadata" || config.SPACSURL != base+"/acs" {
+			t.Errorf("endpoints for %q = %q, %q", publicURL, config.SPEntityID, config.SPACSURL)
+		}
+	}
+}
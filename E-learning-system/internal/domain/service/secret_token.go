@@ -0,0 +1,30 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// secretTokenBytes is the entropy of tokens sent to users by email
+const secretTokenBytes = 32
+
+// newSecretToken returns a random URL-safe token and the hash to store in
+// its place, so a leaked database row cannot be replayed.
+func newSecretToken() (token, hash string, err error) {
+	b := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashSecretToken(token), nil
+}
+
+// hashSecretToken returns the hex SHA-256 of a token. The tokens are random,
+// so a fast unsalted hash is enough.
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
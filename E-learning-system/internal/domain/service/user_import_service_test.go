@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/mail"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestReadUserImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		wantRows []model.UserImportRow
+		wantErrs []model.UserImportError // Message is matched as a substring
+		wantCode string                  // code of a file-level error
+	}{
+		{"template", userImportTemplate, []model.UserImportRow{
+			{Line: 2, Email: "amina.hassan@example.com", FirstName: "Amina", LastName: "Hassan", Role: "student"},
+			{Line: 3, Email: "omar.ali@example.com", FirstName: "Omar", LastName: "Ali", Role: "tutor"},
+		}, nil, ""},
+		{"byte order mark, other column order and extra columns",
+			"\xef\xbb\xbfRole, Email ,user_id,last_name,first_name\nADMIN, Ada@Example.com ,x,Lovelace,Ada\n",
+			[]model.UserImportRow{{Line: 2, Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Role: "admin"}}, nil, ""},
+		{"blank lines skipped", "email,first_name,last_name,role\n\n,,,\na@example.com,A,B,manager\n",
+			[]model.UserImportRow{{Line: 4, Email: "a@example.com", FirstName: "A", LastName: "B", Role: "manager"}}, nil, ""},
+		{"quoted fields", "email,first_name,last_name,role\n\"a@example.com\",\"Mary, Jr.\",\"O\"\"Brien\",student\n",
+			[]model.UserImportRow{{Line: 2, Email: "a@example.com", FirstName: "Mary, Jr.", LastName: "O\"Brien", Role: "student"}}, nil, ""},
+		{"invalid rows reported", "email,first_name,last_name,role\n" +
+			"not-an-email,A,B,student\n" +
+			"b@example.com,,B,student\n" +
+			"c@example.com,C,C,owner\n" +
+			"d@example.com,D\n" +
+			"Ada <e@example.com>,E,E,student\n" +
+			"f@example.com,F,F,student\n" +
+			"F@example.com,F,F,tutor\n",
+			[]model.UserImportRow{{Line: 7, Email: "f@example.com", FirstName: "F", LastName: "F", Role: "student"}},
+			[]model.UserImportError{
+				{Row: 2, Email: "not-an-email", Message: "email is not a valid email address"},
+				{Row: 3, Email: "b@example.com", Message: "first_name is required"},
+				{Row: 4, Email: "c@example.com", Message: "role must be one of student, tutor, manager, admin"},
+				{Row: 5, Message: "has 2 columns, the header has 4"},
+				{Row: 6, Email: "ada <e@example.com>", Message: "email is not a valid email address"},
+				{Row: 8, Email: "f@example.com", Message: "email is already listed on row 7"},
+			}, ""},
+		{"every problem of a row", "email,first_name,last_name,role\n,," + strings.Repeat("x", 101) + ",\n", nil,
+			[]model.UserImportError{{Row: 2, Message: "email is required; first_name is required; last_name must be at most 100 characters; role must be one of"}}, ""},
+		{"empty", "", nil, nil, "empty_import"},
+		{"header only", "email,first_name,last_name,role\n", nil, nil, ""},
+		{"missing columns", "email,name\na@example.com,A\n", nil, nil, "missing_columns"},
+		{"duplicate column", "email,first_name,last_name,role,Email\n", nil, nil, "malformed_import"},
+		{"malformed CSV", "email,first_name,last_name,role\n\"a@example.com,A,B,student\n", nil, nil, "malformed_import"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrs, err := readUserImport([]byte(tt.file))
+			if tt.wantCode != "" {
+				if e, ok := apperr.As(err); !ok || e.Kind != apperr.KindValidation || e.Code != tt.wantCode {
+					t.Fatalf("error = %v, want validation %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readUserImport: %v", err)
+			}
+
+			if len(rows) != len(tt.wantRows) {
+				t.Fatalf("rows = %+v, want %+v", rows, tt.wantRows)
+			}
+			for i := range rows {
+				if rows[i] != tt.wantRows[i] {
+					t.Errorf("row %d = %+v, want %+v", i, rows[i], tt.wantRows[i])
+				}
+			}
+			if len(rowErrs) != len(tt.wantErrs) {
+				t.Fatalf("errors = %+v, want %+v", rowErrs, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				got := rowErrs[i]
+				if got.Row != want.Row || got.Email != want.Email || !strings.Contains(got.Message, want.Message) {
+					t.Errorf("error %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// fakeImportRepo keeps imports in memory and imports users by email
+type fakeImportRepo struct {
+	repository.UserImportRepository
+	imports  map[uuid.UUID]*model.UserImport
+	pending  []*model.UserImport
+	errors   []model.UserImportError
+	existing map[string]string // email to the role they already have
+	failed   string            // reason given to Fail
+}
+
+func (r *fakeImportRepo) Create(_ context.Context, userImport *model.UserImport) error {
+	userImport.ID = uuid.Must(uuid.NewV4())
+	userImport.Status = model.UserImportPending
+	copied := *userImport
+	r.imports[userImport.ID] = &copied
+	r.pending = append(r.pending, &copied)
+	return nil
+}
+
+func (r *fakeImportRepo) Get(_ context.Context, id uuid.UUID) (*model.UserImport, error) {
+	userImport, ok := r.imports[id]
+	if !ok {
+		return nil, errUserImportNotFound
+	}
+	copied := *userImport
+	return &copied, nil
+}
+
+func (r *fakeImportRepo) Claim(context.Context, int) ([]*model.UserImport, error) {
+	claimed := r.pending
+	r.pending = nil
+	return claimed, nil
+}
+
+func (r *fakeImportRepo) Complete(_ context.Context, userImport *model.UserImport) error {
+	userImport.Status = model.UserImportCompleted
+	copied := *userImport
+	r.imports[userImport.ID] = &copied
+	return nil
+}
+
+func (r *fakeImportRepo) Fail(_ context.Context, id uuid.UUID, reason string) error {
+	r.imports[id].Status = model.UserImportFailed
+	r.failed = reason
+	return nil
+}
+
+func (r *fakeImportRepo) AddError(_ context.Context, _ uuid.UUID, rowErr model.UserImportError) error {
+	r.errors = append(r.errors, rowErr)
+	return nil
+}
+
+func (r *fakeImportRepo) ImportUser(_ context.Context, _ uuid.UUID, row model.UserImportRow) (uuid.UUID, string, error) {
+	if strings.HasSuffix(row.Email, "@blocked.example.com") {
+		return uuid.Nil, "", apperr.Conflict("user_deleted", "user is deleted")
+	}
+	if strings.HasSuffix(row.Email, "@broken.example.com") {
+		return uuid.Nil, "", errors.New("connection reset")
+	}
+	role, ok := r.existing[row.Email]
+	r.existing[row.Email] = row.Role
+	switch {
+	case !ok:
+		return uuid.Must(uuid.NewV4()), model.ImportRowCreated, nil
+	case role != row.Role:
+		return uuid.Must(uuid.NewV4()), model.ImportRowUpdated, nil
+	}
+	return uuid.Must(uuid.NewV4()), model.ImportRowUnchanged, nil
+}
+
+// fakeResetRepo accepts invitation tokens
+type fakeResetRepo struct {
+	repository.UserRepository
+}
+
+func (fakeResetRepo) CreatePasswordReset(context.Context, uuid.UUID, string, time.Duration, string) error {
+	return nil
+}
+
+// recordingMailer keeps what it was asked to send
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestImportService(status model.OrganizationStatus) (*userImportService, *fakeImportRepo, *memoryStorage, *recordingMailer, uuid.UUID) {
+	_, orgs := newTestLifecycle(status)
+	orgs.org.Name = "Acme"
+	repo := &fakeImportRepo{imports: map[uuid.UUID]*model.UserImport{}, existing: map[string]string{}}
+	store := &memoryStorage{objects: map[string][]byte{}}
+	mailer := &recordingMailer{}
+	s := &userImportService{
+		repo:     repo,
+		orgRepo:  orgs,
+		userRepo: fakeResetRepo{},
+		store:    store,
+		mailer:   mailer,
+		audit:    discardAudit{},
+		opts:     UserImportServiceOptions{MaxRows: 3, InvitationTTL: 7 * 24 * time.Hour, FrontendURL: "https://learn.example.com"},
+	}
+	return s, repo, store, mailer, orgs.org.ID
+}
+
+func TestStartImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   model.OrganizationStatus
+		file     string
+		wantErr  error
+		wantCode string
+	}{
+		{"accepted", model.OrganizationActive, userImportTemplate + "bad row\n", nil, ""},
+		{"organization closing", model.OrganizationPendingDeletion, userImportTemplate, ErrOrganizationClosing, ""},
+		{"no rows", model.OrganizationActive, "email,first_name,last_name,role\n", nil, "empty_import"},
+		{"too many rows", model.OrganizationActive, userImportTemplate + "a@example.com,A,A,student\nb@example.com,B,B,student\n", nil, "too_many_rows"},
+		{"too large", model.OrganizationActive, userImportTemplate + strings.Repeat(" ", MaxImportFileSize), ErrImportTooLarge, ""},
+		{"not CSV", model.OrganizationActive, "email,first_name,last_name,role\n\"unterminated\n", nil, "malformed_import"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, store, _, orgID := newTestImportService(tt.status)
+
+			userImport, err := s.StartImport(context.Background(), orgID, uuid.Must(uuid.NewV4()), "users.csv", strings.NewReader(tt.file), true)
+			if tt.wantErr != nil || tt.wantCode != "" {
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if e, ok := apperr.As(err); tt.wantCode != "" && (!ok || e.Code != tt.wantCode) {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				if len(repo.imports) != 0 || len(store.objects) != 0 {
+					t.Error("rejected file was queued or stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("StartImport: %v", err)
+			}
+			if userImport.Status != model.UserImportPending || userImport.TotalRows != 3 || !userImport.SendInvitations {
+				t.Errorf("import = %+v, want pending with 3 rows", userImport)
+			}
+			if _, ok := store.objects[userImport.StorageKey]; !ok || !strings.HasPrefix(userImport.StorageKey, "imports/organizations/"+orgID.String()+"/") {
+				t.Errorf("file stored under %q", userImport.StorageKey)
+			}
+		})
+	}
+}
+
+func TestProcessPendingImports(t *testing.T) {
+	s, repo, store, mailer, orgID := newTestImportService(model.OrganizationActive)
+	s.opts.MaxRows = 10
+	repo.existing["same@example.com"] = "student"
+	repo.existing["promoted@example.com"] = "student"
+	ctx := context.Background()
+
+	file := "email,first_name,last_name,role\n" +
+		"new@example.com,New,User,student\n" +
+		"same@example.com,Same,User,student\n" +
+		"promoted@example.com,Promoted,User,tutor\n" +
+		"gone@blocked.example.com,Gone,User,student\n" +
+		"invalid,Bad,Row,student\n"
+	started, err := s.StartImport(ctx, orgID, uuid.Must(uuid.NewV4()), "users.csv", strings.NewReader(file), true)
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+
+	if err := s.ProcessPendingImports(ctx); err != nil {
+		t.Fatalf("ProcessPendingImports: %v", err)
+	}
+	done, _ := repo.Get(ctx, started.ID)
+	if done.Status != model.UserImportCompleted || done.TotalRows != 5 || done.CreatedRows != 1 ||
+		done.UnchangedRows != 1 || done.UpdatedRows != 1 || done.FailedRows != 2 {
+		t.Errorf("import = %+v, want 1 created, 1 unchanged, 1 updated and 2 failed of 5", done)
+	}
+	if len(repo.errors) != 2 || repo.errors[0].Row != 6 || repo.errors[1].Email != "gone@blocked.example.com" {
+		t.Errorf("errors = %+v, want the invalid row and the refused one", repo.errors)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "new@example.com" ||
+		!strings.Contains(mailer.sent[0].Body, "https://learn.example.com/reset-password?token=") {
+		t.Errorf("sent = %+v, want one invitation to the new user", mailer.sent)
+	}
+	if len(store.objects) != 0 {
+		t.Error("import file kept after processing")
+	}
+}
+
+func TestProcessPendingImportsFailure(t *testing.T) {
+	s, repo, store, mailer, orgID := newTestImportService(model.OrganizationActive)
+	ctx := context.Background()
+
+	file := "email,first_name,last_name,role\nnew@example.com,New,User,student\ndown@broken.example.com,Down,User,student\n"
+	started, err := s.StartImport(ctx, orgID, uuid.Must(uuid.NewV4()), "users.csv", strings.NewReader(file), false)
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+
+	if err := s.ProcessPendingImports(ctx); err != nil {
+		t.Fatalf("ProcessPendingImports: %v", err)
+	}
+	failed, _ := repo.Get(ctx, started.ID)
+	if failed.Status != model.UserImportFailed {
+		t.Errorf("status = %s, want failed", failed.Status)
+	}
+	if strings.Contains(repo.failed, "connection reset") || repo.failed == "" {
+		t.Errorf("failure reason = %q, want a generic one", repo.failed)
+	}
+	if len(mailer.sent) != 0 {
+		t.Errorf("sent %d invitations although not asked to", len(mailer.sent))
+	}
+	if len(store.objects) != 0 {
+		t.Error("import file kept after a failure")
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// unlockTokenTTL is how long an unlock link stays valid. Expired links do no
+// harm: the lockout ends on its own.
+const unlockTokenTTL = 24 * time.Hour
+
+// unlockPayloadPrefix keeps unlock links apart from verification links,
+// which are signed with the same key
+const unlockPayloadPrefix = "unlock:"
+
+var (
+	ErrAccountLocked        = apperr.RateLimited("account_locked", "account is temporarily locked after too many failed sign-ins")
+	ErrTooManyLoginAttempts = apperr.RateLimited("too_many_login_attempts", "too many failed sign-ins, try again later")
+	ErrInvalidUnlockToken   = apperr.Validation("invalid_unlock_token", "unlock link is invalid or expired")
+)
+
+// dummyPasswordHash is compared against when the email is unknown, so that
+// response times do not reveal which addresses have accounts
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := utils.HashePassword("not-a-real-password")
+	if err != nil {
+		panic(fmt.Sprintf("failed to hash dummy password: %v", err))
+	}
+	return hash
+})
+
+// UnlockAccount lifts the lockout named by an unlock link
+func (s *userService) UnlockAccount(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "UserService.UnlockAccount")
+	defer span.End()
+
+	payload, _, err := utils.VerifyToken(s.opts.SigningKey, token)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+	id, ok := strings.CutPrefix(payload, unlockPayloadPrefix)
+	if !ok {
+		return ErrInvalidUnlockToken
+	}
+	userID, err := uuid.FromString(id)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	user, err := s.repo.Get(ctx, userID)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return ErrInvalidUnlockToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.guard.Unlock(ctx, user.Email); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+// loginFailed counts a wrong password or MFA code and, when that locks the
+// account, mails its owner an unlock link. user is nil for unknown emails.
+func (s *userService) loginFailed(ctx context.Context, user *model.User, email string, reason error, client model.ClientInfo) {
+	var userID *uuid.UUID
+	if user != nil {
+		userID = &user.ID
+	}
+	s.recordLogin(ctx, userID, email, model.LoginFailure, reason, client)
+
+	locked, err := s.guard.Fail(ctx, email, client.IP)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to count failed login", "error", err)
+		return
+	}
+	if locked && user != nil {
+		if err := s.sendUnlock(ctx, user); err != nil {
+			logger.FromContext(ctx).Error("Failed to send unlock email", "user_id", user.ID, "error", err)
+		}
+	}
+}
+
+// recordLogin stores a login event. Failures are logged and otherwise
+// ignored: a sign-in must not fail because its audit record could not be written.
+func (s *userService) recordLogin(ctx context.Context, userID *uuid.UUID, email string, outcome model.LoginOutcome, reason error, client model.ClientInfo) {
+	event := &model.LoginEvent{
+		UserID:    userID,
+		Email:     email,
+		Outcome:   outcome,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if e, ok := apperr.As(reason); ok {
+		event.Reason = e.Code
+	}
+
+	if err := s.loginEvents.Create(ctx, event); err != nil {
+		logger.FromContext(ctx).Error("Failed to record login event", "outcome", outcome, "error", err)
+	}
+}
+
+// sendUnlock mails a signed link that lifts the user's lockout
+func (s *userService) sendUnlock(ctx context.Context, user *model.User) error {
+	token := utils.SignToken(s.opts.SigningKey, unlockPayloadPrefix+user.ID.String(), time.Now().Add(unlockTokenTTL))
+	link := s.opts.FrontendURL + "/unlock-account?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Your account was locked after several failed sign-in attempts. It will unlock on its own shortly, "+
+			"or you can unlock it now by following this link:\n\n%s\n\n"+
+			"If these attempts were not yours, consider changing your password.\n",
+			user.FirstName, link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send unlock email: %w", err)
+	}
+	return nil
+}
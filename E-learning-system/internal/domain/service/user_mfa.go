@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/totp"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"encoding/base32"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+	// recoveryCodeBytes is drawn per code; the first 50 bits are shown as
+	// two groups of five base32 characters
+	recoveryCodeBytes = 7
+
+	// mfaPayloadPrefix keeps challenge tokens apart from the other links
+	// signed with the same key
+	mfaPayloadPrefix = "mfa:"
+)
+
+var (
+	ErrInvalidMFACode      = apperr.Unauthenticated("invalid_mfa_code", "invalid authentication code")
+	ErrInvalidMFAChallenge = apperr.Unauthenticated("invalid_mfa_challenge", "sign-in has expired, enter your password again")
+	ErrMFAAlreadyEnabled   = apperr.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	ErrMFANotEnabled       = apperr.Conflict("mfa_not_enabled", "two-factor authentication is not enabled")
+	ErrMFANotPending       = apperr.Conflict("mfa_not_pending", "start the two-factor setup first")
+	ErrMFARequired         = apperr.Forbidden("mfa_required", "two-factor authentication is required for your account")
+	ErrInvalidMFASetupCode = apperr.Validation("invalid_mfa_code", "invalid authentication code",
+		apperr.FieldError{Field: "code", Message: "is incorrect"})
+)
+
+// mfaPolicy is how the MFA required roles appear in the audit log
+type mfaPolicy struct {
+	RequiredRoles []string `json:"required_roles"`
+}
+
+var (
+	recoveryCodeEncoding  = base32.StdEncoding.WithPadding(base32.NoPadding)
+	recoveryCodeSeparator = strings.NewReplacer("-", "", " ", "")
+)
+
+// VerifyMFALogin completes a login started by AuthenticateUser. The code is
+// either from the authenticator app or one of the recovery codes. Wrong
+// codes count towards the same lockout as wrong passwords.
+func (s *userService) VerifyMFALogin(ctx context.Context, challengeToken, code string, client model.ClientInfo) (*model.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyMFALogin")
+	defer span.End()
+
+	payload, _, err := utils.VerifyToken(s.opts.SigningKey, challengeToken)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	id, ok := strings.CutPrefix(payload, mfaPayloadPrefix)
+	if !ok {
+		return nil, ErrInvalidMFAChallenge
+	}
+	userID, err := uuid.FromString(id)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.repo.Get(ctx, userID)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLoginThrottle(ctx, user.Email, client); err != nil {
+		return nil, err
+	}
+
+	ok, err = s.checkMFACode(ctx, user.ID, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.loginFailed(ctx, user, user.Email, ErrInvalidMFACode, client)
+		return nil, ErrInvalidMFACode
+	}
+
+	token, err := s.openSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{User: user, Token: token}, nil
+}
+
+// GetMFAStatus tells the user whether MFA is enabled and whether it is required
+func (s *userService) GetMFAStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetMFAStatus")
+	defer span.End()
+
+	return s.mfaRepo.GetStatus(ctx, userID)
+}
+
+// StartMFAEnrollment generates a new secret for an authenticator app. MFA
+// stays off until ConfirmMFAEnrollment receives a code made from it.
+func (s *userService) StartMFAEnrollment(ctx context.Context, userID uuid.UUID, currentPassword string) (*model.MFASetup, error) {
+	ctx, span := tracing.Start(ctx, "UserService.StartMFAEnrollment")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return nil, ErrCurrentPasswordIncorrect
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := utils.Seal(s.opts.EncryptionKey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt MFA secret: %w", err)
+	}
+
+	started, err := s.mfaRepo.StartEnrollment(ctx, userID, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start MFA enrollment: %w", err)
+	}
+	if !started {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &model.MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.opts.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment enables MFA once the authenticator app produces a
+// valid code, and returns the recovery codes. They are shown only this once.
+func (s *userService) ConfirmMFAEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ConfirmMFAEnrollment")
+	defer span.End()
+
+	enrollment, err := s.mfaRepo.Get(ctx, userID)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return nil, ErrMFANotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.Open(s.opts.EncryptionKey, enrollment.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MFA secret: %w", err)
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFASetupCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.mfaRepo.Enable(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	if !enabled {
+		return nil, ErrMFANotPending
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code with new ones
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, currentPassword string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return nil, ErrCurrentPasswordIncorrect
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	if !replaced {
+		return nil, ErrMFANotEnabled
+	}
+	return codes, nil
+}
+
+// DisableMFA turns MFA off after checking the password and a current code.
+// Users whose role or organization requires MFA cannot turn it off.
+func (s *userService) DisableMFA(ctx context.Context, userID uuid.UUID, currentPassword, code string) error {
+	ctx, span := tracing.Start(ctx, "UserService.DisableMFA")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return ErrCurrentPasswordIncorrect
+	}
+
+	status, err := s.mfaRepo.GetStatus(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		return ErrMFANotEnabled
+	}
+	if status.Required {
+		return ErrMFARequired
+	}
+
+	ok, err := s.checkMFACode(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFASetupCode
+	}
+
+	if _, err := s.mfaRepo.Disable(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	return nil
+}
+
+// ResetMFA removes a user's enrollment, for instance after a lost phone,
+// and signs them out everywhere. They can then enroll again after signing
+// in with their password.
+func (s *userService) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetMFA")
+	defer span.End()
+
+	if _, err := s.repo.Get(ctx, userID); err != nil {
+		return err
+	}
+
+	disabled, err := s.mfaRepo.Disable(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset MFA: %w", err)
+	}
+	if !disabled {
+		return ErrMFANotEnabled
+	}
+
+	// uuid.Nil matches no session, so every one of them is revoked
+	if _, err := s.tokenRepo.RevokeOthers(ctx, userID, uuid.Nil); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.recordChange(ctx, userID, "user.reset_mfa", &model.MFAStatus{Enabled: true}, &model.MFAStatus{})
+	logger.FromContext(ctx).Info("MFA reset by an admin", "user_id", userID)
+	return nil
+}
+
+// GetMFARequiredRoles lists the roles whose users must use MFA
+func (s *userService) GetMFARequiredRoles(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetMFARequiredRoles")
+	defer span.End()
+
+	return s.mfaRepo.GetRequiredRoles(ctx)
+}
+
+// SetMFARequiredRoles replaces the roles whose users must use MFA
+func (s *userService) SetMFARequiredRoles(ctx context.Context, roles []string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetMFARequiredRoles")
+	defer span.End()
+
+	previous, err := s.mfaRepo.GetRequiredRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MFA required roles: %w", err)
+	}
+
+	roles = slices.Compact(slices.Sorted(slices.Values(roles)))
+	if err := s.mfaRepo.SetRequiredRoles(ctx, roles); err != nil {
+		return nil, fmt.Errorf("failed to set MFA required roles: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     "mfa_policy.update",
+		TargetType: model.AuditTargetMFAPolicy,
+		Changes:    audit.Diff(mfaPolicy{RequiredRoles: previous}, mfaPolicy{RequiredRoles: roles}),
+	})
+	return roles, nil
+}
+
+// mfaChallenge returns a challenge when the user has MFA enabled, and nil
+// when the password alone is enough
+func (s *userService) mfaChallenge(ctx context.Context, user *model.User) (*model.MFAChallenge, error) {
+	enrollment, err := s.mfaRepo.Get(ctx, user.ID)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check MFA: %w", err)
+	}
+	if !enrollment.Enabled {
+		return nil, nil
+	}
+
+	expires := time.Now().Add(s.opts.MFAChallengeTTL)
+	return &model.MFAChallenge{
+		Token:     utils.SignToken(s.opts.SigningKey, mfaPayloadPrefix+user.ID.String(), expires),
+		ExpiresAt: expires,
+	}, nil
+}
+
+// checkMFACode accepts a current authenticator code that has not been used
+// yet, or an unused recovery code, which is then spent
+func (s *userService) checkMFACode(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	enrollment, err := s.mfaRepo.Get(ctx, userID)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !enrollment.Enabled {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := utils.Open(s.opts.EncryptionKey, enrollment.Secret)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt MFA secret: %w", err)
+		}
+		step, ok := totp.Validate(secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return s.mfaRepo.UseStep(ctx, userID, step)
+	}
+
+	return s.mfaRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	b := make([]byte, recoveryCodeBytes)
+	for range recoveryCodeCount {
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, dashes and spaces so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	return hashSecretToken(strings.ToLower(recoveryCodeSeparator.Replace(code)))
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/totp"
+	utils "e-learning-system/pkg/config"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeMFARepo holds one enrollment and mirrors the SQL of use_mfa_step
+// and use_recovery_code
+type fakeMFARepo struct {
+	repository.MFARepository
+	enrollment *model.MFAEnrollment
+	recovery   map[string]bool // hash -> used
+}
+
+func (r *fakeMFARepo) Get(_ context.Context, userID uuid.UUID) (*model.MFAEnrollment, error) {
+	if r.enrollment == nil || r.enrollment.UserID != userID {
+		return nil, apperr.NotFound("mfa_not_found", "MFA not set up")
+	}
+	copied := *r.enrollment
+	return &copied, nil
+}
+
+func (r *fakeMFARepo) UseStep(_ context.Context, userID uuid.UUID, step int64) (bool, error) {
+	e := r.enrollment
+	if e == nil || e.UserID != userID || !e.Enabled || e.LastUsedStep >= step {
+		return false, nil
+	}
+	e.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseRecoveryCode(_ context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	used, ok := r.recovery[codeHash]
+	if r.enrollment == nil || r.enrollment.UserID != userID || !ok || used {
+		return false, nil
+	}
+	r.recovery[codeHash] = true
+	return true, nil
+}
+
+func TestCheckMFACode(t *testing.T) {
+	key := []byte("encryption key")
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := utils.Seal(key, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// checkMFACode reads the clock itself; keep the test within one step
+	if left := totp.Period - time.Duration(time.Now().UnixNano()%int64(totp.Period)); left < 2*time.Second {
+		time.Sleep(left)
+	}
+	now := totp.Step(time.Now())
+	code := func(step int64) string {
+		c, err := totp.Code(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	userID := uuid.Must(uuid.NewV4())
+
+	// Each case runs its codes in order against a fresh enrollment whose
+	// last used step is lastUsed
+	tests := []struct {
+		name     string
+		enabled  bool
+		lastUsed int64
+		codes    []string
+		want     []bool
+	}{
+		{"current code", true, 0, []string{code(now)}, []bool{true}},
+		{"code of the previous step", true, 0, []string{code(now - 1)}, []bool{true}},
+		{"replayed code", true, 0, []string{code(now), code(now)}, []bool{true, false}},
+		{"replay with spaces", true, 0, []string{code(now), " " + code(now) + " "}, []bool{true, false}},
+		{"older code after a newer one", true, 0, []string{code(now), code(now - 1)}, []bool{true, false}},
+		{"newer code after an older one", true, 0, []string{code(now - 1), code(now)}, []bool{true, true}},
+		{"step already used at enrollment", true, now, []string{code(now)}, []bool{false}},
+		{"code outside the window", true, 0, []string{code(now - 2)}, []bool{false}},
+		{"not a code", true, 0, []string{"abcdef"}, []bool{false}},
+		{"not enabled", false, 0, []string{code(now)}, []bool{false}},
+		{"recovery code", true, 0, []string{codes[0]}, []bool{true}},
+		{"recovery code typed loosely", true, 0, []string{" " + codes[1][:5] + codes[1][6:] + " "}, []bool{true}},
+		{"recovery code spent", true, 0, []string{codes[2], codes[2]}, []bool{true, false}},
+		{"unknown recovery code", true, 0, []string{"aaaaa-bbbbb"}, []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeMFARepo{
+				enrollment: &model.MFAEnrollment{UserID: userID, Secret: sealed, Enabled: tt.enabled, LastUsedStep: tt.lastUsed},
+				recovery:   make(map[string]bool),
+			}
+			for _, h := range hashes {
+				repo.recovery[h] = false
+			}
+			s := &userService{mfaRepo: repo, opts: UserServiceOptions{EncryptionKey: key}}
+
+			for i, c := range tt.codes {
+				ok, err := s.checkMFACode(context.Background(), userID, c)
+				if err != nil {
+					t.Fatalf("code %d: %v", i, err)
+				}
+				if ok != tt.want[i] {
+					t.Errorf("code %d accepted = %v, want %v", i, ok, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMFACodeWithoutEnrollment(t *testing.T) {
+	s := &userService{mfaRepo: &fakeMFARepo{}, opts: UserServiceOptions{EncryptionKey: []byte("key")}}
+	ok, err := s.checkMFACode(context.Background(), uuid.Must(uuid.NewV4()), "123456")
+	if err != nil || ok {
+		t.Errorf("checkMFACode = (%v, %v), want (false, nil)", ok, err)
+	}
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrPasswordExpired = apperr.Forbidden("password_expired", "your password has expired; choose a new one")
+	ErrPasswordReused  = apperr.Validation("password_reused", "choose a password you have not used recently")
+
+	ErrTooManyResetRequests = apperr.RateLimited("too_many_reset_requests", "too many password reset requests, try again later")
+)
+
+// checkNewPassword applies the password policy to a password about to be
+// set. field names the request field in errors. user is nil for accounts
+// that do not exist yet; otherwise the current and recent passwords are
+// refused, the current one counting towards the history size.
+func (s *userService) checkNewPassword(ctx context.Context, field, password string, user *model.User, email, firstName, lastName string) error {
+	problems := s.opts.PasswordPolicy.Check(password, email, firstName, lastName)
+	if len(problems) > 0 {
+		fields := make([]apperr.FieldError, len(problems))
+		for i, problem := range problems {
+			fields[i] = apperr.FieldError{Field: field, Message: problem}
+		}
+		return apperr.Validation("weak_password", "password does not meet the password policy", fields...)
+	}
+
+	history := s.opts.PasswordPolicy.History
+	if user == nil || history == 0 {
+		return nil
+	}
+	if utils.CheckPasswordHash(password, user.Password) {
+		return ErrPasswordReused
+	}
+	if history == 1 {
+		return nil
+	}
+
+	hashes, err := s.repo.GetPasswordHistory(ctx, user.ID, history-1)
+	if err != nil {
+		return fmt.Errorf("failed to load password history: %w", err)
+	}
+	for _, hash := range hashes {
+		if utils.CheckPasswordHash(password, hash) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// upgradePasswordHash rehashes a correct password whose stored hash uses
+// bcrypt or outdated Argon2id parameters. Failures are logged: the old hash
+// keeps working and is upgraded at the next sign-in.
+func (s *userService) upgradePasswordHash(ctx context.Context, user *model.User, password string) {
+	if !utils.PasswordNeedsRehash(user.Password) {
+		return
+	}
+
+	hash, err := utils.HashePassword(password)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to rehash password", "user_id", user.ID, "error", err)
+		return
+	}
+	rehashed, err := s.repo.RehashPassword(ctx, user.ID, user.Password, hash)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to store rehashed password", "user_id", user.ID, "error", err)
+		return
+	}
+	if rehashed {
+		user.Password = hash
+		logger.FromContext(ctx).Info("Password hash upgraded", "user_id", user.ID)
+	}
+}
+
+// passwordExpired reports whether the password of user has expired under
+// the policy of one of their organizations
+func (s *userService) passwordExpired(ctx context.Context, user *model.User) (bool, error) {
+	expiresAt, err := s.repo.GetPasswordExpiry(ctx, user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check password expiry: %w", err)
+	}
+	return expiresAt != nil && !time.Now().Before(*expiresAt), nil
+}
+
+// RenewExpiredPassword signs in a user whose password has expired by
+// setting a new one. It is throttled and recorded like a sign-in, and ends
+// every existing session.
+func (s *userService) RenewExpiredPassword(ctx context.Context, email, currentPassword, newPassword string, client model.ClientInfo) (*model.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RenewExpiredPassword")
+	defer span.End()
+
+	if err := s.checkLoginThrottle(ctx, email, client); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		utils.CheckPasswordHash(currentPassword, dummyPasswordHash())
+		s.loginFailed(ctx, nil, email, ErrInvalidCredentials, client)
+		return nil, ErrInvalidCredentials
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		s.loginFailed(ctx, user, email, ErrInvalidCredentials, client)
+		return nil, ErrInvalidCredentials
+	}
+	if s.opts.RequireVerifiedLogin && !user.EmailVerified {
+		s.recordLogin(ctx, &user.ID, email, model.LoginBlocked, ErrEmailNotVerified, client)
+		return nil, ErrEmailNotVerified
+	}
+
+	if err := s.checkNewPassword(ctx, "new_password", newPassword, user, user.Email, user.FirstName, user.LastName); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashePassword(newPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash new password: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+	user.Password = hashedPassword
+
+	if err := s.repo.ClearResetToken(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear reset token: %w", err)
+	}
+	if _, err := s.tokenRepo.RevokeOthers(ctx, user.ID, uuid.Nil); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return s.finishLogin(ctx, user, email, client)
+}
+
+// ForgotPassword emails a password reset link to the account of email, if
+// there is one. The answer does not depend on it: the lookup and the email
+// happen after the request returns, and a request over the per-address
+// limit is dropped without telling the caller. Only the per-IP limit is
+// reported, as it says nothing about the address.
+func (s *userService) ForgotPassword(ctx context.Context, email string, client model.ClientInfo) error {
+	ctx, span := tracing.Start(ctx, "UserService.ForgotPassword")
+	defer span.End()
+
+	if client.IP != "" {
+		ok, retryAfter, err := s.opts.ResetIPLimiter.Allow(ctx, client.IP)
+		if err != nil {
+			return fmt.Errorf("failed to check reset rate limit: %w", err)
+		}
+		if !ok {
+			return ErrTooManyResetRequests.WithRetryAfter(retryAfter)
+		}
+	}
+
+	resetCtx := context.WithoutCancel(ctx)
+	if !s.resets.Go(func() { s.sendPasswordReset(resetCtx, email, client) }) {
+		logger.FromContext(ctx).Warn("Password reset request dropped during shutdown")
+	}
+	return nil
+}
+
+// Wait implements UserService
+func (s *userService) Wait() {
+	s.resets.Wait()
+}
+
+// sendPasswordReset issues and mails a reset link unless the address has
+// no account or has had too many links already. Failures are only logged,
+// the caller has been answered.
+func (s *userService) sendPasswordReset(ctx context.Context, email string, client model.ClientInfo) {
+	ctx, span := tracing.Start(ctx, "UserService.sendPasswordReset")
+	defer span.End()
+
+	ok, _, err := s.opts.ResetEmailLimiter.Allow(ctx, email)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to check reset rate limit", "error", err)
+		return
+	}
+	if !ok {
+		logger.FromContext(ctx).Warn("Password reset request over the per-address limit dropped")
+		return
+	}
+
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to generate reset token", "error", err)
+		return
+	}
+	if err := s.repo.CreatePasswordReset(ctx, user.ID, hash, s.opts.PasswordResetTTL, client.IP); err != nil {
+		logger.FromContext(ctx).Error("Failed to store reset token", "user_id", user.ID, "error", err)
+		return
+	}
+
+	link := s.opts.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your account.\n"+
+			"Follow this link within %d minutes to choose a new one:\n\n%s\n\n"+
+			"If this was not you, ignore this email and your password stays the same.\n",
+			user.FirstName, int(s.opts.PasswordResetTTL.Minutes()), link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logger.FromContext(ctx).Error("Failed to send password reset email", "user_id", user.ID, "error", err)
+		return
+	}
+	logger.FromContext(ctx).Info("Password reset link sent", "user_id", user.ID)
+}
+
+// ResetPassword sets a new password with a reset token. The token works
+// once; afterwards every session of the user is ended and a lockout of
+// the account is lifted.
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	hash := hashSecretToken(token)
+	userID, err := s.repo.FindPasswordReset(ctx, hash)
+	if err != nil {
+		return fmt.Errorf("failed to find reset token: %w", err)
+	}
+	if userID == uuid.Nil {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkNewPassword(ctx, "new_password", newPassword, user, user.Email, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashePassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	// Another request may have spent the token since it was looked up
+	userID, err = s.repo.CompletePasswordReset(ctx, hash, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if userID == uuid.Nil {
+		return ErrInvalidResetToken
+	}
+
+	if _, err := s.tokenRepo.RevokeOthers(ctx, user.ID, uuid.Nil); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.guard.Unlock(ctx, user.Email); err != nil {
+		logger.FromContext(ctx).Error("Failed to lift lockout after password reset", "user_id", user.ID, "error", err)
+	}
+
+	logger.FromContext(ctx).Info("Password reset", "user_id", user.ID)
+	return nil
+}
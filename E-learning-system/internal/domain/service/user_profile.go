@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"golang.org/x/text/language"
+)
+
+// emailChangeTTL is how long the confirmation link for a new address is valid
+const emailChangeTTL = 24 * time.Hour
+
+var (
+	ErrCurrentPasswordIncorrect = apperr.Validation("current_password_incorrect", "current password is incorrect",
+		apperr.FieldError{Field: "current_password", Message: "is incorrect"})
+	ErrEmailTaken     = apperr.Conflict("email_taken", "email address is already in use")
+	ErrEmailUnchanged = apperr.Validation("email_unchanged", "new email is the current email",
+		apperr.FieldError{Field: "new_email", Message: "must differ from the current email"})
+	ErrInvalidEmailChangeToken = apperr.Validation("invalid_email_change_token", "email change link is invalid or expired")
+)
+
+// GetProfile returns the signed-in user's own record
+func (s *userService) GetProfile(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetProfile")
+	defer span.End()
+
+	return s.repo.Get(ctx, userID)
+}
+
+// UpdateProfile applies profile changes after normalizing the locale and
+// checking the time zone
+func (s *userService) UpdateProfile(ctx context.Context, userID uuid.UUID, changes model.ProfileChanges) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
+	var fields []apperr.FieldError
+	if changes.Locale != nil && *changes.Locale != "" {
+		tag, err := language.Parse(*changes.Locale)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: "locale", Message: "must be a BCP 47 language tag such as en-GB"})
+		} else {
+			locale := tag.String()
+			changes.Locale = &locale
+		}
+	}
+	if changes.Timezone != nil && *changes.Timezone != "" {
+		// "Local" would mean the server's zone, which is meaningless to the user
+		if _, err := time.LoadLocation(*changes.Timezone); err != nil || *changes.Timezone == "Local" {
+			fields = append(fields, apperr.FieldError{Field: "timezone", Message: "must be an IANA time zone such as Europe/London"})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, apperr.Validation("invalid_profile", "profile has invalid values", fields...)
+	}
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes.Apply(user)
+	if err := s.repo.UpdateProfile(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password after checking the current one and
+// signs the user out of every session except keepSessionID
+func (s *userService) ChangePassword(ctx context.Context, userID, keepSessionID uuid.UUID, currentPassword, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return ErrCurrentPasswordIncorrect
+	}
+	if err := s.checkNewPassword(ctx, "new_password", newPassword, user, user.Email, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashePassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// A pending reset link would otherwise still work with the old password gone
+	if err := s.repo.ClearResetToken(ctx, userID); err != nil {
+		return fmt.Errorf("failed to clear reset token: %w", err)
+	}
+	if _, err := s.tokenRepo.RevokeOthers(ctx, userID, keepSessionID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RequestEmailChange checks the password and mails a confirmation link to
+// the new address. The email only changes once the link is followed.
+func (s *userService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, currentPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.RequestEmailChange")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return ErrCurrentPasswordIncorrect
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrEmailUnchanged
+	}
+	if _, err := s.repo.FindByEmail(ctx, newEmail); err == nil {
+		return ErrEmailTaken
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateEmailChange(ctx, userID, newEmail, hash, emailChangeTTL); err != nil {
+		return fmt.Errorf("failed to store email change: %w", err)
+	}
+
+	link := s.opts.FrontendURL + "/confirm-email?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to change the email address of your account to this one.\n"+
+			"Follow this link within %d hours to confirm:\n\n%s\n\n"+
+			"If this was not you, ignore this email and your address stays the same.\n",
+			user.FirstName, int(emailChangeTTL.Hours()), link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send email change confirmation: %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange applies the email change the token was issued for
+func (s *userService) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ConfirmEmailChange")
+	defer span.End()
+
+	userID, err := s.repo.ConfirmEmailChange(ctx, hashSecretToken(token))
+	if err != nil {
+		// The address was taken between the request and the confirmation
+		if apperr.KindOf(err) == apperr.KindConflict {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("failed to confirm email change: %w", err)
+	}
+	if userID == uuid.Nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	return s.repo.Get(ctx, userID)
+}
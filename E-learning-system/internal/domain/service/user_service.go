@@ -1,12 +1,21 @@
 package service
 
 import (
+	"context"
+	"e-learning-system/internal/audit"
+	"e-learning-system/internal/domain/apperr"
 	"e-learning-system/internal/domain/model"
 	"e-learning-system/internal/domain/repository"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/password"
+	"e-learning-system/internal/sso"
+	"e-learning-system/internal/throttle"
+	"e-learning-system/internal/tracing"
 	utils "e-learning-system/pkg/config"
-	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -14,43 +23,133 @@ import (
 
 type UserService interface {
 	// Create a new user
-	RegisterUser(email, password, firstName, lastName, role string) (*model.User, error)
-
-	// Authenticate user
-	AuthenticateUser(email, password string) (*model.User, error)
+	RegisterUser(ctx context.Context, email, password, firstName, lastName, role string) (*model.User, error)
+
+	// Authenticate user and open a session, or hand out an MFA challenge
+	AuthenticateUser(ctx context.Context, email, password string, client model.ClientInfo) (*model.LoginResult, error)
+	// Sign in with an expired password by replacing it
+	RenewExpiredPassword(ctx context.Context, email, currentPassword, newPassword string, client model.ClientInfo) (*model.LoginResult, error)
+	// Second login step: exchange an MFA challenge and a code for a session
+	VerifyMFALogin(ctx context.Context, challengeToken, code string, client model.ClientInfo) (*model.LoginResult, error)
+
+	// Single sign-on through an organization's identity provider
+	FindSSOOrganizations(ctx context.Context, email string) ([]model.SSOOrganization, error)
+	StartSSOLogin(ctx context.Context, orgID uuid.UUID) (*model.SSOStart, error)
+	CompleteSSOLogin(ctx context.Context, stateToken, state, code string, client model.ClientInfo) (*model.LoginResult, error)
+	GetSAMLMetadata(ctx context.Context, orgID uuid.UUID) ([]byte, error)
+	StartSAMLLogin(ctx context.Context, orgID uuid.UUID) (*model.SSOStart, error)
+	AcceptSAMLResponse(ctx context.Context, orgID uuid.UUID, samlResponse string) string
+	CompleteSAMLLogin(ctx context.Context, stateToken, code string, client model.ClientInfo) (*model.LoginResult, error)
 
 	// Get user by ID
-	GetUserByID(userID uuid.UUID) (*model.User, error)
+	GetUserByID(ctx context.Context, userID uuid.UUID) (*model.User, error)
 
-	// Update user
-	UpdateUser(user *model.User) error
+	// Update the allowlisted fields of a user
+	UpdateUser(ctx context.Context, userID uuid.UUID, changes model.UserChanges) (*model.User, error)
+	// Give a user another role, such as instructor
+	ChangeUserRole(ctx context.Context, userID uuid.UUID, role string) (*model.User, error)
 
 	// Delete user
-	DeleteUser(userID uuid.UUID) error
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
+	// Restore a deleted user until the retention job has erased it
+	RestoreUser(ctx context.Context, userID uuid.UUID) (*model.User, error)
 
 	// List all users
-	ListUsers() ([]*model.User, error)
+	ListUsers(ctx context.Context, filter model.UserFilter, page model.PageRequest) (*model.Page[*model.User], error)
 
 	// Forgot/reset password
-	ForgotPassword(email string) error
-	ResetPassword(token uuid.UUID, newPassword string) error
+	ForgotPassword(ctx context.Context, email string, client model.ClientInfo) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+
+	// Self-service profile of the signed-in user
+	GetProfile(ctx context.Context, userID uuid.UUID) (*model.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, changes model.ProfileChanges) (*model.User, error)
+	ChangePassword(ctx context.Context, userID, keepSessionID uuid.UUID, currentPassword, newPassword string) error
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, currentPassword string) error
+	ConfirmEmailChange(ctx context.Context, token string) (*model.User, error)
+
+	// Email verification
+	ResendVerification(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) (*model.User, error)
+
+	// Lift a lockout using the link mailed when it started
+	UnlockAccount(ctx context.Context, token string) error
+
+	// Two-factor authentication of the signed-in user
+	GetMFAStatus(ctx context.Context, userID uuid.UUID) (*model.MFAStatus, error)
+	StartMFAEnrollment(ctx context.Context, userID uuid.UUID, currentPassword string) (*model.MFASetup, error)
+	ConfirmMFAEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, currentPassword string) ([]string, error)
+	DisableMFA(ctx context.Context, userID uuid.UUID, currentPassword, code string) error
+
+	// Two-factor administration by platform admins
+	ResetMFA(ctx context.Context, userID uuid.UUID) error
+	GetMFARequiredRoles(ctx context.Context) ([]string, error)
+	SetMFARequiredRoles(ctx context.Context, roles []string) ([]string, error)
+
+	// Wait for the password reset emails still being sent; call on shutdown
+	// once the server has stopped taking requests
+	Wait()
 }
 
+var (
+	// ErrOrganizationSuspended is returned when a member of a suspended organization tries to log in
+	ErrOrganizationSuspended = apperr.Forbidden("organization_suspended", "organization is suspended")
+	ErrUserExists            = apperr.Conflict("user_exists", "user already exists")
+	ErrInvalidCredentials    = apperr.Unauthenticated("invalid_credentials", "invalid email or password")
+	ErrInvalidResetToken     = apperr.Validation("invalid_reset_token", "invalid or expired reset token")
+)
+
 type userService struct {
-	repo      repository.UserRepository
-	tokenRepo repository.TokenRepository
+	repo        repository.UserRepository
+	tokenRepo   repository.TokenRepository
+	orgRepo     repository.OrganizationRepository
+	loginEvents repository.LoginEventRepository
+	mfaRepo     repository.MFARepository
+	ssoRepo     repository.SSORepository
+	ssoClient   *sso.Client
+	mailer      mail.Sender
+	guard       *throttle.LoginGuard
+	audit       AuditService
+	opts        UserServiceOptions
+	resets      background
+}
+
+// UserServiceOptions configures the links and sign-in policy of the user service
+type UserServiceOptions struct {
+	FrontendURL          string        // base of links sent by email
+	SigningKey           []byte        // signs verification and unlock links
+	VerificationTTL      time.Duration // lifetime of a verification link
+	RequireVerifiedLogin bool          // refuse sign-in until the email is verified
+	EncryptionKey        []byte        // encrypts TOTP secrets and identity provider client secrets
+	MFAIssuer            string        // account label shown in authenticator apps
+	MFAChallengeTTL      time.Duration // time allowed for the second login step
+	SSOStateTTL          time.Duration // time allowed for a round trip to an identity provider
+	SSOPublicURL         string        // base of the SAML service provider URLs
+	SAMLCallbackURL      string        // frontend page the SAML assertion consumer redirects to
+	PasswordPolicy       password.Policy
+	PasswordResetTTL     time.Duration     // lifetime of a password reset link
+	ResetEmailLimiter    *throttle.Limiter // password reset links per email address
+	ResetIPLimiter       *throttle.Limiter // password reset requests per client IP
 }
 
 // Register a new user
-func (s *userService) RegisterUser(email, password, firstName, lastName, role string) (*model.User, error) {
+func (s *userService) RegisterUser(ctx context.Context, email, password, firstName, lastName, role string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RegisterUser")
+	defer span.End()
+
 	// Check if user already exists
-	if _, err := s.repo.FindByEmail(email); err == nil {
-		return nil, errors.New("user already exists")
+	if _, err := s.repo.FindByEmail(ctx, email); err == nil {
+		return nil, ErrUserExists
 	}
 
 	newID, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UUID: %v", err)
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	if err := s.checkNewPassword(ctx, "password", password, nil, email, firstName, lastName); err != nil {
+		return nil, err
 	}
 
 	// Hash password
@@ -71,156 +170,293 @@ func (s *userService) RegisterUser(email, password, firstName, lastName, role st
 	}
 
 	// Save user
-	if err := s.repo.Create(user); err != nil {
+	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
+	// The account exists either way; the user can ask for another link
+	if err := s.sendVerification(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
 	return user, nil
 }
 
-// Authenticate a user
-func (s *userService) AuthenticateUser(email, password string) (*model.User, error) {
-    user, err := s.repo.FindByEmail(email)
-    if err != nil {
-        return nil, errors.New("invalid email or password")
-    }
+// Authenticate a user. Failed attempts slow down and eventually lock out
+// further attempts; every attempt is recorded as a login event. Users with
+// MFA enabled get a challenge instead of a session.
+func (s *userService) AuthenticateUser(ctx context.Context, email, password string, client model.ClientInfo) (*model.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.AuthenticateUser")
+	defer span.End()
 
-    // Check the password hash
-    if !utils.CheckPasswordHash(password, user.Password) {
-        return nil, errors.New("invalid email or password")
-    }
+	if err := s.checkLoginThrottle(ctx, email, client); err != nil {
+		return nil, err
+	}
 
-    // Validate role from DB
-    validRoles := map[string]bool{
-        "admin":       true,
-        "structor":      true,
-        "student": true,
-    }
-    if !validRoles[user.Role] {
-        user.Role = "user"
-    }
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		// Compare anyway so unknown addresses take as long as wrong passwords
+		utils.CheckPasswordHash(password, dummyPasswordHash())
+		s.loginFailed(ctx, nil, email, ErrInvalidCredentials, client)
+		return nil, ErrInvalidCredentials
+	}
 
-    // Create token (optional session feature)
-    newToken, err := uuid.NewV4()
-    if err != nil {
-        return nil, errors.New("failed to generate token")
-    }
+	// Check the password hash
+	if !utils.CheckPasswordHash(password, user.Password) {
+		s.loginFailed(ctx, user, email, ErrInvalidCredentials, client)
+		return nil, ErrInvalidCredentials
+	}
 
-    token := &model.Token{
-        ID:        newToken,
-        UserID:    user.ID,
-        Token:     newToken.String(),
-        ExpiresAt: time.Now().Add(24 * time.Hour),
-    }
+	// Checked after the password so it does not reveal which addresses exist
+	if s.opts.RequireVerifiedLogin && !user.EmailVerified {
+		s.recordLogin(ctx, &user.ID, email, model.LoginBlocked, ErrEmailNotVerified, client)
+		return nil, ErrEmailNotVerified
+	}
 
-    if err := s.tokenRepo.Create(token); err != nil {
-        return nil, errors.New("failed to save token")
-    }
+	s.upgradePasswordHash(ctx, user, password)
 
-    return user, nil
-}
+	// The password was right, so it can be replaced through RenewExpiredPassword
+	expired, err := s.passwordExpired(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		s.recordLogin(ctx, &user.ID, email, model.LoginBlocked, ErrPasswordExpired, client)
+		return nil, ErrPasswordExpired
+	}
 
+	return s.finishLogin(ctx, user, email, client)
+}
 
-// Get user by ID
-func (s *userService) GetUserByID(userID uuid.UUID) (*model.User, error) {
-	user, err := s.repo.Get(userID)
+// finishLogin runs the checks shared by every way of signing in once the
+// user is identified, then opens a session or hands out an MFA challenge
+func (s *userService) finishLogin(ctx context.Context, user *model.User, email string, client model.ClientInfo) (*model.LoginResult, error) {
+	// Members of suspended organizations may not sign in
+	statuses, err := s.orgRepo.GetMemberStatuses(ctx, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %v", err)
+		return nil, fmt.Errorf("failed to check organization status: %w", err)
+	}
+	for _, status := range statuses {
+		if status.BlocksMemberLogin() {
+			s.recordLogin(ctx, &user.ID, email, model.LoginBlocked, ErrOrganizationSuspended, client)
+			return nil, ErrOrganizationSuspended
+		}
 	}
-	return user, nil
-}
 
-// Update user
-func (s *userService) UpdateUser(user *model.User) error {
-	_, err := s.repo.Get(user.ID)
+	// Failures are only cleared once the second factor is checked as well
+	challenge, err := s.mfaChallenge(ctx, user)
 	if err != nil {
-		return errors.New("user not found")
+		return nil, err
 	}
-	if err := s.repo.Update(user); err != nil {
-		return fmt.Errorf("failed to update user: %v", err)
+	if challenge != nil {
+		return &model.LoginResult{User: user, Challenge: challenge}, nil
 	}
-	return nil
+
+	token, err := s.openSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{User: user, Token: token}, nil
 }
 
-// Delete user
-func (s *userService) DeleteUser(userID uuid.UUID) error {
-	_, err := s.repo.Get(userID)
+// checkLoginThrottle refuses attempts while the account or client IP is
+// locked out, and slows down accounts with recent failures
+func (s *userService) checkLoginThrottle(ctx context.Context, email string, client model.ClientInfo) error {
+	decision, err := s.guard.Check(ctx, email, client.IP)
 	if err != nil {
-		log.Printf("User not found: %v", err)
-		return fmt.Errorf("user not found")
+		// Fail open: an outage of the counter store must not lock everybody out
+		logger.FromContext(ctx).Error("Login throttle unavailable", "error", err)
 	}
-	if err := s.repo.Delete(userID); err != nil {
-		log.Printf("Error deleting user: %v", err)
-		return err
+	if decision.AccountLocked {
+		s.recordLogin(ctx, nil, email, model.LoginLocked, ErrAccountLocked, client)
+		return ErrAccountLocked.WithRetryAfter(decision.RetryAfter)
+	}
+	if decision.IPBlocked {
+		s.recordLogin(ctx, nil, email, model.LoginLocked, ErrTooManyLoginAttempts, client)
+		return ErrTooManyLoginAttempts.WithRetryAfter(decision.RetryAfter)
+	}
+	if decision.Delay > 0 {
+		select {
+		case <-time.After(decision.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	log.Printf("User deleted successfully")
 	return nil
 }
 
-// List users
-func (s *userService) ListUsers() ([]*model.User, error) {
-	users, err := s.repo.List()
+// openSession creates the session token of a fully authenticated user
+func (s *userService) openSession(ctx context.Context, user *model.User, client model.ClientInfo) (*model.Token, error) {
+	// Validate role from DB
+	validRoles := map[string]bool{
+		"admin":      true,
+		"instructor": true,
+		"student":    true,
+	}
+	if !validRoles[user.Role] {
+		user.Role = "user"
+	}
+
+	// Create the session token
+	newToken, err := uuid.NewV4()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %v", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
-	return users, nil
-}
 
-// ForgotPassword sets a reset token and expiry for the user
-func (s *userService) ForgotPassword(email string) error {
-	// Just check if the user exists
-	if _, err := s.repo.FindByEmail(email); err != nil {
-		return errors.New("email not found")
+	token := &model.Token{
+		ID:        newToken,
+		UserID:    user.ID,
+		Token:     newToken.String(),
+		ExpiresAt: time.Now().Add(24 * time.Hour),
 	}
 
-	// Generate reset token
-	resetToken, err := uuid.NewV4()
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	if err := s.guard.Succeed(ctx, user.Email); err != nil {
+		logger.FromContext(ctx).Error("Failed to reset login failures", "error", err)
+	}
+	s.recordLogin(ctx, &user.ID, user.Email, model.LoginSuccess, nil, client)
+
+	return token, nil
+}
+
+// Get user by ID
+func (s *userService) GetUserByID(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	user, err := s.repo.Get(ctx, userID)
 	if err != nil {
-		return errors.New("failed to generate reset token")
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	return user, nil
+}
 
-	expiry := time.Now().Add(15 * time.Minute).Format(time.RFC3339)
+// UpdateUser applies changes to a user; the password hash and role are kept
+func (s *userService) UpdateUser(ctx context.Context, userID uuid.UUID, changes model.UserChanges) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
 
-	// Store reset token
-	if err := s.repo.SetResetToken(email, resetToken, expiry); err != nil {
-		return fmt.Errorf("failed to store reset token: %v", err)
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 🚀 Simulate email sending (for now)
-	log.Printf("Reset token sent to %s: %s", email, resetToken.String())
+	before := *user
+	changes.Apply(user)
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
 
-	return nil
+	s.recordChange(ctx, userID, "user.update", &before, user)
+	return user, nil
 }
 
+// ChangeUserRole sets the role of a user. Only admins call it; users
+// register as students.
+func (s *userService) ChangeUserRole(ctx context.Context, userID uuid.UUID, role string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangeUserRole")
+	defer span.End()
 
-// ResetPassword updates the user's password using a valid reset token
-func (s *userService) ResetPassword(token uuid.UUID, newPassword string) error {
-	user, err := s.repo.FindByResetToken(token)
+	user, err := s.repo.Get(ctx, userID)
 	if err != nil {
-		return errors.New("invalid or expired reset token")
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
 	}
 
-	hashedPassword, err := utils.HashePassword(newPassword)
+	before := *user
+	user.Role = role
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to change role of user %s: %w", userID, err)
+	}
+
+	s.recordChange(ctx, userID, "user.change_role", &before, user)
+	logger.FromContext(ctx).Info("User role changed", "user_id", userID, "from", before.Role, "to", role)
+	return user, nil
+}
+
+// recordChange audits an admin's change to a user. before is nil for
+// restores and after for deletions.
+func (s *userService) recordChange(ctx context.Context, userID uuid.UUID, action string, before, after any) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		TargetID:   &userID,
+		Changes:    audit.Diff(before, after),
+	})
+}
+
+// Delete user
+func (s *userService) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	existing, err := s.repo.Get(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to hash new password: %v", err)
+		log.Printf("User not found: %v", err)
+		return err
 	}
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		log.Printf("Error deleting user: %v", err)
+		return err
+	}
+
+	s.recordChange(ctx, userID, "user.delete", existing, nil)
+	log.Printf("User deleted successfully")
+	return nil
+}
+
+// RestoreUser brings back a soft-deleted user
+func (s *userService) RestoreUser(ctx context.Context, userID uuid.UUID) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RestoreUser")
+	defer span.End()
 
-	if err := s.repo.UpdatePassword(user.ID, hashedPassword); err != nil {
-		return fmt.Errorf("failed to update password: %v", err)
+	if err := s.repo.Restore(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to restore user with ID %s: %w", userID, err)
 	}
 
-	if err := s.repo.ClearResetToken(user.ID); err != nil {
-		return fmt.Errorf("failed to clear reset token: %v", err)
+	user, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restored user with ID %s: %w", userID, err)
 	}
 
-	log.Printf("Password reset successful for user: %s", user.Email)
-	return nil
+	s.recordChange(ctx, userID, "user.restore", nil, user)
+	log.Printf("User restored: %v", userID)
+	return user, nil
+}
+
+// ListUsers retrieves one page of users matching the filter
+func (s *userService) ListUsers(ctx context.Context, filter model.UserFilter, page model.PageRequest) (*model.Page[*model.User], error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
+	users, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
 }
 
 // Factory
-func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, orgRepo repository.OrganizationRepository,
+	loginEventRepo repository.LoginEventRepository, mfaRepo repository.MFARepository, ssoRepo repository.SSORepository, ssoClient *sso.Client,
+	mailer mail.Sender, guard *throttle.LoginGuard, auditService AuditService, opts UserServiceOptions) UserService {
+	opts.FrontendURL = strings.TrimRight(opts.FrontendURL, "/")
 	return &userService{
-		repo:      userRepo,
-		tokenRepo: tokenRepo,
+		repo:        userRepo,
+		tokenRepo:   tokenRepo,
+		orgRepo:     orgRepo,
+		loginEvents: loginEventRepo,
+		mfaRepo:     mfaRepo,
+		ssoRepo:     ssoRepo,
+		ssoClient:   ssoClient,
+		mailer:      mailer,
+		guard:       guard,
+		audit:       auditService,
+		opts:        opts,
 	}
 }
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/logger"
+	"e-learning-system/internal/sso"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrSSONotAvailable     = apperr.NotFound("sso_not_available", "single sign-on is not available for this organization")
+	ErrInvalidSSOState     = apperr.Unauthenticated("invalid_sso_state", "sign-in has expired, please start again")
+	ErrSSOFailed           = apperr.Unauthenticated("sso_failed", "the identity provider did not confirm your sign-in")
+	ErrSSOEmailMissing     = apperr.Forbidden("sso_email_missing", "the identity provider did not share your email address")
+	ErrSSODomainNotAllowed = apperr.Forbidden("sso_domain_not_allowed", "your email domain may not sign in to this organization")
+	ErrSSOEmailNotVerified = apperr.Forbidden("sso_email_not_verified", "the identity provider has not verified your email address")
+	ErrSSOLinkNotAllowed   = apperr.Forbidden("sso_link_not_allowed", "this account cannot sign in through single sign-on")
+)
+
+// ssoProvider is what linking and provisioning need from an OpenID Connect
+// or SAML configuration
+type ssoProvider struct {
+	OrganizationID uuid.UUID
+	Issuer         string // OpenID Connect issuer or SAML entity ID
+	AllowedDomains []string
+	DefaultRole    string
+}
+
+// ssoState is what the login needs back from the start of the flow. It is
+// sealed into the state token the client holds while the user is at the
+// identity provider.
+type ssoState struct {
+	Protocol       string    `json:"protocol"`
+	OrganizationID uuid.UUID `json:"org"`
+	State          string    `json:"state"`
+	Nonce          string    `json:"nonce"`
+	Verifier       string    `json:"verifier"`
+	ExpiresAt      time.Time `json:"exp"`
+}
+
+// FindSSOOrganizations lists the organizations an email address can sign in to with SSO
+func (s *userService) FindSSOOrganizations(ctx context.Context, email string) ([]model.SSOOrganization, error) {
+	ctx, span := tracing.Start(ctx, "UserService.FindSSOOrganizations")
+	defer span.End()
+
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok || domain == "" {
+		return []model.SSOOrganization{}, nil
+	}
+	return s.ssoRepo.FindByDomain(ctx, domain)
+}
+
+// StartSSOLogin returns the identity provider URL of an organization and
+// the state token to hand back to CompleteSSOLogin
+func (s *userService) StartSSOLogin(ctx context.Context, orgID uuid.UUID) (*model.SSOStart, error) {
+	ctx, span := tracing.Start(ctx, "UserService.StartSSOLogin")
+	defer span.End()
+
+	config, err := s.ssoRepo.GetConfig(ctx, orgID)
+	if apperr.KindOf(err) == apperr.KindNotFound || (err == nil && !config.Enabled) {
+		return nil, ErrSSONotAvailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req := sso.NewRequest()
+	authURL, err := s.ssoClient.AuthCodeURL(ctx, sso.Provider{Issuer: config.Issuer, ClientID: config.ClientID}, req)
+	if err != nil {
+		logger.FromContext(ctx).Error("SSO provider unavailable", "organization_id", orgID, "error", err)
+		return nil, ErrSSONotAvailable.Wrap(err)
+	}
+
+	state := ssoState{
+		Protocol:       model.SSOProtocolOIDC,
+		OrganizationID: orgID,
+		State:          req.State,
+		Nonce:          req.Nonce,
+		Verifier:       req.Verifier,
+		ExpiresAt:      time.Now().Add(s.opts.SSOStateTTL),
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SSO state: %w", err)
+	}
+	stateToken, err := utils.Seal(s.opts.EncryptionKey, string(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal SSO state: %w", err)
+	}
+
+	return &model.SSOStart{
+		AuthorizationURL: authURL,
+		StateToken:       stateToken,
+		ExpiresAt:        state.ExpiresAt,
+	}, nil
+}
+
+// CompleteSSOLogin signs in the user the identity provider sent back. A
+// known identity signs in its linked user; otherwise a user with the same
+// verified email is linked, or a new user is provisioned for the
+// organization. The result is the same as for a password login.
+func (s *userService) CompleteSSOLogin(ctx context.Context, stateToken, state, code string, client model.ClientInfo) (*model.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CompleteSSOLogin")
+	defer span.End()
+
+	payload, err := utils.Open(s.opts.EncryptionKey, stateToken)
+	if err != nil {
+		return nil, ErrInvalidSSOState
+	}
+	var saved ssoState
+	if err := json.Unmarshal([]byte(payload), &saved); err != nil {
+		return nil, ErrInvalidSSOState
+	}
+	if saved.Protocol != model.SSOProtocolOIDC || time.Now().After(saved.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, ErrInvalidSSOState
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx, saved.OrganizationID)
+	if apperr.KindOf(err) == apperr.KindNotFound || (err == nil && !config.Enabled) {
+		return nil, ErrSSONotAvailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sso.Provider{Issuer: config.Issuer, ClientID: config.ClientID}
+	if config.ClientSecret != "" {
+		provider.ClientSecret, err = utils.Open(s.opts.EncryptionKey, config.ClientSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt SSO client secret: %w", err)
+		}
+	}
+
+	identity, err := s.ssoClient.Exchange(ctx, provider, sso.Request{
+		State:    saved.State,
+		Nonce:    saved.Nonce,
+		Verifier: saved.Verifier,
+	}, code)
+	if err != nil {
+		logger.FromContext(ctx).Warn("SSO code exchange failed", "organization_id", config.OrganizationID, "error", err)
+		s.recordLogin(ctx, nil, "", model.LoginFailure, ErrSSOFailed, client)
+		return nil, ErrSSOFailed.Wrap(err)
+	}
+
+	user, err := s.ssoUser(ctx, ssoProvider{
+		OrganizationID: config.OrganizationID,
+		Issuer:         config.Issuer,
+		AllowedDomains: config.AllowedDomains,
+		DefaultRole:    config.DefaultRole,
+	}, identity)
+	if err != nil {
+		if apperr.KindOf(err) == apperr.KindForbidden {
+			s.recordLogin(ctx, nil, identity.Email, model.LoginBlocked, err, client)
+		}
+		return nil, err
+	}
+
+	return s.finishLogin(ctx, user, user.Email, client)
+}
+
+// ssoUser resolves the local user of an identity, linking or provisioning
+// one on first sign-in
+func (s *userService) ssoUser(ctx context.Context, provider ssoProvider, identity *sso.Identity) (*model.User, error) {
+	_, domain, _ := strings.Cut(identity.Email, "@")
+	if identity.Email == "" || domain == "" {
+		return nil, ErrSSOEmailMissing
+	}
+	if !slices.Contains(provider.AllowedDomains, domain) {
+		return nil, ErrSSODomainNotAllowed
+	}
+
+	linked, err := s.ssoRepo.FindIdentity(ctx, provider.OrganizationID, provider.Issuer, identity.Subject)
+	if err == nil {
+		if err := s.ssoRepo.TouchIdentity(ctx, linked.ID, identity.Email); err != nil {
+			logger.FromContext(ctx).Error("Failed to update SSO identity", "identity_id", linked.ID, "error", err)
+		}
+		return s.repo.Get(ctx, linked.UserID)
+	}
+	if apperr.KindOf(err) != apperr.KindNotFound {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		// Only a provider that verified the address may take over the account,
+		// and platform admins never sign in through an organization's provider
+		if !identity.EmailVerified {
+			return nil, ErrSSOEmailNotVerified
+		}
+		if user.Role == "admin" {
+			return nil, ErrSSOLinkNotAllowed
+		}
+	case apperr.KindOf(err) == apperr.KindNotFound:
+		user, err = s.provisionSSOUser(ctx, provider, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.ssoRepo.LinkIdentity(ctx, &model.SSOIdentity{
+		OrganizationID: provider.OrganizationID,
+		UserID:         user.ID,
+		Issuer:         provider.Issuer,
+		Subject:        identity.Subject,
+		Email:          identity.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("SSO identity linked", "organization_id", provider.OrganizationID, "user_id", user.ID)
+	return user, nil
+}
+
+// provisionSSOUser creates the user of an identity seen for the first time.
+// The password is random and unknown, so the account can only be used
+// through SSO until its owner resets it.
+func (s *userService) provisionSSOUser(ctx context.Context, provider ssoProvider, identity *sso.Identity) (*model.User, error) {
+	newID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	password, _, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := utils.HashePassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	role := provider.DefaultRole
+	if identity.Role != "" {
+		role = identity.Role
+	}
+
+	user := &model.User{
+		ID:        newID,
+		Email:     identity.Email,
+		Password:  hashedPassword,
+		FirstName: identity.GivenName,
+		LastName:  identity.FamilyName,
+		Role:      role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if identity.EmailVerified {
+		if _, err := s.repo.VerifyEmail(ctx, user.ID, user.Email); err != nil {
+			return nil, fmt.Errorf("failed to verify email: %w", err)
+		}
+		user.EmailVerified = true
+	} else if err := s.sendVerification(ctx, user); err != nil {
+		logger.FromContext(ctx).Error("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
+	logger.FromContext(ctx).Info("User provisioned through SSO", "organization_id", provider.OrganizationID, "user_id", user.ID)
+	return user, nil
+}
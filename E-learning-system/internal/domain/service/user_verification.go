@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+	"e-learning-system/internal/domain/model"
+	"e-learning-system/internal/mail"
+	"e-learning-system/internal/tracing"
+	utils "e-learning-system/pkg/config"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// verificationResendInterval is the shortest time between two verification links to one user
+const verificationResendInterval = time.Minute
+
+var (
+	ErrEmailNotVerified         = apperr.Forbidden("email_not_verified", "email address is not verified")
+	ErrInvalidVerificationToken = apperr.Validation("invalid_verification_token", "verification link is invalid or expired")
+)
+
+// ResendVerification mails a new verification link. Unknown and already
+// verified addresses succeed silently so the endpoint cannot be used to
+// probe for accounts.
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResendVerification")
+	defer span.End()
+
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil || user.EmailVerified {
+		return nil
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail marks the address in a verification link as verified. The
+// link only works while the address is still the user's.
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	payload, _, err := utils.VerifyToken(s.opts.SigningKey, token)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	id, email, ok := strings.Cut(payload, ":")
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+	userID, err := uuid.FromString(id)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	verified, err := s.repo.VerifyEmail(ctx, userID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	if !verified {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	return s.repo.Get(ctx, userID)
+}
+
+// sendVerification mails a signed verification link for the user's current
+// address, unless one was sent within verificationResendInterval
+func (s *userService) sendVerification(ctx context.Context, user *model.User) error {
+	marked, err := s.repo.MarkVerificationSent(ctx, user.ID, verificationResendInterval)
+	if err != nil {
+		return fmt.Errorf("failed to record verification email: %w", err)
+	}
+	if !marked {
+		return nil
+	}
+
+	token := utils.SignToken(s.opts.SigningKey, user.ID.String()+":"+user.Email, time.Now().Add(s.opts.VerificationTTL))
+	link := s.opts.FrontendURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Please confirm that this is your email address by following this link within %d hours:\n\n%s\n\n"+
+			"If you did not create an account, ignore this email.\n",
+			user.FirstName, int(s.opts.VerificationTTL.Hours()), link),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
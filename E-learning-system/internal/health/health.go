@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Checker verifies that a dependency is reachable
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string `json:"name"`
+	Healthy    bool   `json:"healthy"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Run executes every checker concurrently, each bounded by timeout.
+// It reports whether all of them passed.
+func Run(ctx context.Context, timeout time.Duration, checkers ...Checker) ([]Result, bool) {
+	results := make([]Result, len(checkers))
+
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checker.Check(checkCtx)
+			results[i] = Result{
+				Name:       checker.Name(),
+				Healthy:    err == nil,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, checker)
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, r := range results {
+		healthy = healthy && r.Healthy
+	}
+	return results, healthy
+}
+
+// PostgresChecker pings the database
+type PostgresChecker struct {
+	db *sql.DB
+}
+
+// NewPostgresChecker creates a checker for db
+func NewPostgresChecker(db *sql.DB) *PostgresChecker {
+	return &PostgresChecker{db: db}
+}
+
+// Name implements Checker
+func (c *PostgresChecker) Name() string {
+	return "postgres"
+}
+
+// Check implements Checker
+func (c *PostgresChecker) Check(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// RedisChecker sends a PING over a short-lived connection
+type RedisChecker struct {
+	addr     string
+	username string
+	password string
+}
+
+// NewRedisChecker creates a checker from a redis://[user:password@]host:port URL
+func NewRedisChecker(redisURL string) (*RedisChecker, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid redis URL")
+	}
+
+	c := &RedisChecker{addr: u.Host}
+	if !strings.Contains(c.addr, ":") {
+		c.addr += ":6379"
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			c.username, c.password = u.User.Username(), password
+		} else {
+			c.password = u.User.Username()
+		}
+	}
+	return c, nil
+}
+
+// Name implements Checker
+func (c *RedisChecker) Name() string {
+	return "redis"
+}
+
+// Check implements Checker
+func (c *RedisChecker) Check(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(conn)
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if err := redisCommand(conn, r, "+OK", args...); err != nil {
+			return err
+		}
+	}
+
+	return redisCommand(conn, r, "+PONG", "PING")
+}
+
+// redisCommand writes a RESP command and checks the single line reply
+func redisCommand(conn net.Conn, r *bufio.Reader, want string, args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return err
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line != want {
+		if strings.HasPrefix(line, "-") {
+			return fmt.Errorf("redis %s failed: %s", args[0], strings.TrimPrefix(line, "-"))
+		}
+		return fmt.Errorf("unexpected redis reply to %s", args[0])
+	}
+	return nil
+}
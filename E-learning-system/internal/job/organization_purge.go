@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/metrics"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OrganizationPurgeJob hard deletes organizations whose deletion grace period
+// has ended, writing a JSON export of each one to disk first.
+type OrganizationPurgeJob struct {
+	orgService service.OrganizationService
+	exportDir  string
+}
+
+// NewOrganizationPurgeJob creates the scheduled organization deletion job
+func NewOrganizationPurgeJob(orgService service.OrganizationService, exportDir string) *OrganizationPurgeJob {
+	return &OrganizationPurgeJob{orgService: orgService, exportDir: exportDir}
+}
+
+// Name implements Job
+func (j *OrganizationPurgeJob) Name() string {
+	return "organization-purge"
+}
+
+// Run implements Job
+func (j *OrganizationPurgeJob) Run(ctx context.Context) error {
+	ids, err := j.orgService.GetOrganizationsDueForDeletion(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	metrics.JobQueueDepth.WithLabelValues(j.Name()).Set(float64(len(ids)))
+
+	if err := os.MkdirAll(j.exportDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create export directory: %v", err)
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		data, err := j.orgService.ExportOrganization(ctx, id)
+		if err != nil {
+			// Never delete what we could not export
+			log.Printf("Skipping purge of organization %s: %v", id, err)
+			continue
+		}
+
+		path := filepath.Join(j.exportDir, fmt.Sprintf("organization-%s-%s.json", id, time.Now().UTC().Format("20060102T150405Z")))
+		if err := os.WriteFile(path, data, 0o640); err != nil {
+			log.Printf("Skipping purge of organization %s: failed to write export: %v", id, err)
+			continue
+		}
+
+		if err := j.orgService.PurgeOrganization(ctx, id); err != nil {
+			log.Printf("Failed to purge organization %s: %v", id, err)
+			continue
+		}
+
+		log.Printf("Organization %s purged, export written to %s", id, path)
+	}
+
+	return nil
+}
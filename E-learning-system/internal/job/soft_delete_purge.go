@@ -0,0 +1,30 @@
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/domain/service"
+	"time"
+)
+
+// SoftDeletePurgeJob hard deletes records that have stayed soft-deleted for
+// longer than the retention period, after which they can no longer be restored
+type SoftDeletePurgeJob struct {
+	retentionService service.RetentionService
+	period           time.Duration
+}
+
+// NewSoftDeletePurgeJob creates the scheduled retention purge job
+func NewSoftDeletePurgeJob(retentionService service.RetentionService, period time.Duration) *SoftDeletePurgeJob {
+	return &SoftDeletePurgeJob{retentionService: retentionService, period: period}
+}
+
+// Name implements Job
+func (j *SoftDeletePurgeJob) Name() string {
+	return "soft-delete-purge"
+}
+
+// Run implements Job
+func (j *SoftDeletePurgeJob) Run(ctx context.Context) error {
+	_, err := j.retentionService.PurgeSoftDeleted(ctx, time.Now().Add(-j.period))
+	return err
+}
@@ -0,0 +1,30 @@
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/domain/service"
+	"errors"
+)
+
+// UserDataExportJob prepares queued user data exports and deletes the
+// archives of expired ones
+type UserDataExportJob struct {
+	privacyService service.PrivacyService
+}
+
+// NewUserDataExportJob creates the user data export job
+func NewUserDataExportJob(privacyService service.PrivacyService) *UserDataExportJob {
+	return &UserDataExportJob{privacyService: privacyService}
+}
+
+// Name implements Job
+func (j *UserDataExportJob) Name() string {
+	return "user-data-export"
+}
+
+// Run implements Job
+func (j *UserDataExportJob) Run(ctx context.Context) error {
+	processErr := j.privacyService.ProcessPendingExports(ctx)
+	purgeErr := j.privacyService.PurgeExpiredExports(ctx)
+	return errors.Join(processErr, purgeErr)
+}
@@ -0,0 +1,26 @@
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/domain/service"
+)
+
+// UserImportJob applies queued bulk user imports
+type UserImportJob struct {
+	userImportService service.UserImportService
+}
+
+// NewUserImportJob creates the user import job
+func NewUserImportJob(userImportService service.UserImportService) *UserImportJob {
+	return &UserImportJob{userImportService: userImportService}
+}
+
+// Name implements Job
+func (j *UserImportJob) Name() string {
+	return "user-import"
+}
+
+// Run implements Job
+func (j *UserImportJob) Run(ctx context.Context) error {
+	return j.userImportService.ProcessPendingImports(ctx)
+}
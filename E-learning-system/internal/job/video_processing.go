@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/domain/service"
+	"e-learning-system/internal/metrics"
+)
+
+// VideoProcessingJob transcodes uploaded lesson videos to HLS
+type VideoProcessingJob struct {
+	videoService service.VideoService
+}
+
+// NewVideoProcessingJob creates the lesson video processing job
+func NewVideoProcessingJob(videoService service.VideoService) *VideoProcessingJob {
+	return &VideoProcessingJob{videoService: videoService}
+}
+
+// Name implements Job
+func (j *VideoProcessingJob) Name() string {
+	return "video-processing"
+}
+
+// Run implements Job
+func (j *VideoProcessingJob) Run(ctx context.Context) error {
+	if _, err := j.videoService.ReleaseStaleVideoClaims(ctx); err != nil {
+		return err
+	}
+
+	pending, err := j.videoService.PendingVideoCount(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.JobQueueDepth.WithLabelValues(j.Name()).Set(float64(pending))
+	if pending == 0 {
+		return nil
+	}
+
+	err = j.videoService.ProcessPendingVideos(ctx)
+
+	// Report what is left, including uploads that arrived meanwhile
+	if pending, countErr := j.videoService.PendingVideoCount(ctx); countErr == nil {
+		metrics.JobQueueDepth.WithLabelValues(j.Name()).Set(float64(pending))
+	}
+	return err
+}
@@ -1 +1,87 @@
-package job
\ No newline at end of file
+package job
+
+import (
+	"context"
+	"e-learning-system/internal/metrics"
+	"e-learning-system/internal/tracing"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work run periodically by the Worker
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+// Worker runs registered jobs on a fixed interval until stopped
+type Worker struct {
+	jobs     []Job
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewWorker creates a worker that runs its jobs every interval
+func NewWorker(interval time.Duration, jobs ...Job) *Worker {
+	return &Worker{jobs: jobs, interval: interval}
+}
+
+// Start launches the worker loop in the background
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		w.runAll(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.runAll(ctx)
+			}
+		}
+	}()
+
+	log.Printf("Background worker started with %d job(s), interval %s", len(w.jobs), w.interval)
+}
+
+// Stop cancels the running jobs and waits for the loop to exit
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+	log.Println("Background worker stopped")
+}
+
+func (w *Worker) runAll(ctx context.Context) {
+	for _, j := range w.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		// Each run is the root of its own trace
+		jobCtx, span := tracing.Start(ctx, "job "+j.Name())
+		start := time.Now()
+		err := j.Run(jobCtx)
+		metrics.JobDuration.WithLabelValues(j.Name()).Observe(time.Since(start).Seconds())
+		tracing.RecordError(span, err)
+		span.End()
+
+		if err != nil {
+			metrics.JobRuns.WithLabelValues(j.Name(), "failure").Inc()
+			log.Printf("Job %s failed: %v", j.Name(), err)
+			continue
+		}
+		metrics.JobRuns.WithLabelValues(j.Name(), "success").Inc()
+	}
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively against attribute keys
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"cookie",
+	"api_key",
+	"apikey",
+}
+
+// Config selects the level and output format of the logger
+type Config struct {
+	Level  string // debug, info, warn or error
+	Format string // json or text
+	Output io.Writer
+}
+
+// New creates a structured logger that redacts sensitive attributes
+func New(cfg Config) *slog.Logger {
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       ParseLevel(cfg.Level),
+		ReplaceAttr: redactAttr,
+	}
+
+	if strings.EqualFold(cfg.Format, "text") {
+		return slog.New(slog.NewTextHandler(out, opts))
+	}
+	return slog.New(slog.NewJSONHandler(out, opts))
+}
+
+// ParseLevel maps a level name to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// IsSensitive reports whether values under key must never be logged
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request logger stored in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
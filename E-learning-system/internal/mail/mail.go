@@ -0,0 +1,43 @@
+// Package mail sends transactional email such as confirmation links.
+package mail
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a Sender implementation
+type Config struct {
+	Driver string // log or smtp
+	From   string
+
+	// smtp
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// New builds the Sender selected by cfg.Driver
+func New(cfg Config) (Sender, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLogSender(), nil
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPSender sends messages through an SMTP relay. smtp.SendMail upgrades
+// to TLS when the server offers STARTTLS.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates an SMTPSender; username may be empty for relays without auth
+func NewSMTPSender(host, port, username, password, from string) (*SMTPSender, error) {
+	if host == "" || port == "" {
+		return nil, errors.New("smtp host and port are required")
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %v", from, err)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{addr: net.JoinHostPort(host, port), auth: auth, from: from}, nil
+}
+
+// Send implements Sender. net/smtp takes no context, so a cancelled
+// request only stops the wait, not the delivery.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %v", msg.To, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.fromAddress(), []string{to.Address}, s.render(to, msg))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SMTPSender) fromAddress() string {
+	addr, _ := mail.ParseAddress(s.from)
+	return addr.Address
+}
+
+// render builds an RFC 5322 message with a UTF-8 plain-text body
+func (s *SMTPSender) render(to *mail.Address, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + s.from + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,151 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Rendition is one quality level of an HLS stream
+type Rendition struct {
+	Name         string // directory name, e.g. "720p"
+	Height       int
+	VideoBitrate int // bits per second
+	AudioBitrate int // bits per second
+}
+
+// DefaultRenditions is the HLS ladder offered to students
+var DefaultRenditions = []Rendition{
+	{Name: "360p", Height: 360, VideoBitrate: 800_000, AudioBitrate: 96_000},
+	{Name: "720p", Height: 720, VideoBitrate: 2_800_000, AudioBitrate: 128_000},
+	{Name: "1080p", Height: 1080, VideoBitrate: 5_000_000, AudioBitrate: 160_000},
+}
+
+// ProbeResult describes a source video
+type ProbeResult struct {
+	Width    int
+	Height   int
+	Duration float64 // seconds
+}
+
+// Available reports whether ffmpeg and ffprobe are installed
+func Available() bool {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("ffprobe")
+	return err == nil
+}
+
+// Probe reads the dimensions and duration of a video with ffprobe
+func Probe(ctx context.Context, src string) (*ProbeResult, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		src,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	var parsed struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+	if len(parsed.Streams) == 0 {
+		return nil, fmt.Errorf("no video stream found")
+	}
+
+	duration, _ := strconv.ParseFloat(parsed.Format.Duration, 64)
+	return &ProbeResult{
+		Width:    parsed.Streams[0].Width,
+		Height:   parsed.Streams[0].Height,
+		Duration: duration,
+	}, nil
+}
+
+// TranscodeHLS renders src into one HLS playlist per rendition under outDir and
+// writes outDir/master.m3u8 referencing them. Renditions taller than the source
+// are skipped, but the smallest one is always produced.
+func TranscodeHLS(ctx context.Context, src, outDir string, probe *ProbeResult, renditions []Rendition) error {
+	var selected []Rendition
+	for _, r := range renditions {
+		if r.Height <= probe.Height || len(selected) == 0 {
+			selected = append(selected, r)
+		}
+	}
+
+	var master strings.Builder
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	for _, r := range selected {
+		dir := filepath.Join(outDir, r.Name)
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return err
+		}
+
+		cmd := exec.CommandContext(ctx, "ffmpeg",
+			"-y", "-v", "error",
+			"-i", src,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", fmt.Sprintf("scale=-2:%d", r.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+			"-b:v", strconv.Itoa(r.VideoBitrate),
+			"-maxrate", strconv.Itoa(r.VideoBitrate*107/100),
+			"-bufsize", strconv.Itoa(r.VideoBitrate*3/2),
+			"-c:a", "aac", "-b:a", strconv.Itoa(r.AudioBitrate), "-ac", "2",
+			"-hls_time", "6",
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(dir, "segment_%04d.ts"),
+			filepath.Join(dir, "index.m3u8"),
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("ffmpeg %s failed: %v: %s", r.Name, err, lastLine(out))
+		}
+
+		width := r.Height
+		if probe.Height > 0 {
+			width = (probe.Width*r.Height/probe.Height + 1) &^ 1
+		}
+		fmt.Fprintf(&master, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/index.m3u8\n",
+			r.VideoBitrate+r.AudioBitrate, width, r.Height, r.Name)
+	}
+
+	return os.WriteFile(filepath.Join(outDir, "master.m3u8"), []byte(master.String()), 0o640)
+}
+
+// Thumbnail extracts a single JPEG frame at the given second
+func Thumbnail(ctx context.Context, src, dst string, at float64) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-y", "-v", "error",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-i", src,
+		"-frames:v", "1",
+		"-vf", "scale=640:-2",
+		dst,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("thumbnail extraction failed: %v: %s", err, lastLine(out))
+	}
+	return nil
+}
+
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "elearning"
+
+var (
+	// HTTPRequestDuration observes request latency per route template
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight counts requests currently being served
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// JobQueueDepth is the number of items a background job still has to process
+	JobQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_depth",
+		Help:      "Items waiting to be processed by a background job.",
+	}, []string{"job"})
+
+	// JobRuns counts background job runs by outcome
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Background job runs by job and result.",
+	}, []string{"job", "result"})
+
+	// JobDuration observes how long background job runs take
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Background job run duration.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, []string{"job"})
+
+	// Registrations counts successful user registrations
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_registrations_total",
+		Help:      "Successful user registrations.",
+	})
+
+	// Logins counts login attempts by result (success, failure, blocked, locked or mfa_required)
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+
+	// Enrollments counts course enrollments
+	Enrollments = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "course_enrollments_total",
+		Help:      "Course enrollments.",
+	})
+)
+
+// RegisterDB exposes the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
@@ -0,0 +1,104 @@
+// Package password checks new passwords against the configured password
+// policy: length, character classes and a denylist of common passwords
+// shipped with the binary.
+package password
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commonList holds one common password per line, lower case
+//
+//go:embed common.txt
+var commonList string
+
+var common = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(commonList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			set[line] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// Policy is what a new password must satisfy
+type Policy struct {
+	MinLength     int  // in characters
+	MaxLength     int  // in characters; bounds the work of hashing
+	RequireUpper  bool // at least one upper case letter
+	RequireLower  bool // at least one lower case letter
+	RequireDigit  bool
+	RequireSymbol bool // at least one character that is not a letter or digit
+	RejectCommon  bool // refuse passwords on the shipped denylist
+	// History is how many previous passwords may not be reused. It is
+	// enforced by the caller, which has the stored hashes.
+	History int
+}
+
+// Check returns why password does not satisfy the policy, or nil when it
+// does. personal lists values such as the email address that the password
+// must not contain.
+func (p Policy) Check(password string, personal ...string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "must contain an upper case letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "must contain a lower case letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if p.RejectCommon && IsCommon(password) {
+		problems = append(problems, "is too common")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		// The local part of an email address is what people reuse
+		value, _, _ = strings.Cut(strings.ToLower(value), "@")
+		if utf8.RuneCountInString(value) >= 4 && strings.Contains(lowered, value) {
+			problems = append(problems, "must not contain your email address or name")
+			break
+		}
+	}
+
+	return problems
+}
+
+// IsCommon reports whether password is on the denylist, ignoring case
+func IsCommon(password string) bool {
+	_, ok := common[strings.ToLower(password)]
+	return ok
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	strict := Policy{
+		MinLength:     10,
+		MaxLength:     64,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		RejectCommon:  true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		personal []string
+		want     []string
+	}{
+		{"satisfies everything", strict, "Tr0ub4dor&3x", nil, nil},
+		{"too short", strict, "Tr0ub&3x", nil, []string{"must be at least 10 characters long"}},
+		{"length counts characters, not bytes", Policy{MinLength: 4}, "éééé", nil, nil},
+		{"too long", Policy{MaxLength: 8}, "abcdefghi", nil, []string{"must be at most 8 characters long"}},
+		{"no maximum", Policy{}, string(make([]byte, 500)), nil, nil},
+		{"no upper case", strict, "tr0ub4dor&3x", nil, []string{"must contain an upper case letter"}},
+		{"no lower case", strict, "TR0UB4DOR&3X", nil, []string{"must contain a lower case letter"}},
+		{"no digit", strict, "Troubador&xx", nil, []string{"must contain a digit"}},
+		{"no symbol", strict, "Tr0ub4dor3xx", nil, []string{"must contain a symbol"}},
+		{"space is a symbol", Policy{RequireSymbol: true}, "two words", nil, nil},
+		{"unicode letters", Policy{RequireUpper: true, RequireLower: true}, "Ünïcode", nil, nil},
+		{"common", Policy{RejectCommon: true}, "password", nil, []string{"is too common"}},
+		{"common in other case", Policy{RejectCommon: true}, "PassWord", nil, []string{"is too common"}},
+		{"common allowed", Policy{}, "password", nil, nil},
+		{
+			"several problems", strict, "abc", nil,
+			[]string{"must be at least 10 characters long", "must contain an upper case letter", "must contain a digit", "must contain a symbol"},
+		},
+		{"contains email local part", Policy{}, "MyAdaLovelace!1", []string{"adalovelace@example.com"}, []string{"must not contain your email address or name"}},
+		{"contains name", Policy{}, "xx-lovelace-xx", []string{"ada@example.com", "Lovelace"}, []string{"must not contain your email address or name"}},
+		{"short personal values ignored", Policy{}, "ada-is-here", []string{"ada@example.com", "Ada"}, nil},
+		{"unrelated personal values", Policy{}, "Tr0ub4dor&3x", []string{"grace@example.com", "Hopper"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.Check(tt.password, tt.personal...)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q) = %q, want %q", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCommon(t *testing.T) {
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{"123456", true},
+		{"PASSWORD", true},
+		{"Tr0ub4dor&3x", false},
+		{"", false},
+		{"# Common passwords refused when auth.password.reject_common is on: frequent", false},
+	}
+	for _, tt := range tests {
+		if got := IsCommon(tt.password); got != tt.want {
+			t.Errorf("IsCommon(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
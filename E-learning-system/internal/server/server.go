@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Config holds the HTTP server limits and optional TLS certificate
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration // whole request, including the body
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration // keep-alive connections
+	ShutdownTimeout   time.Duration // how long in-flight requests get to finish
+	MaxHeaderBytes    int
+	TLSCertFile       string
+	TLSKeyFile        string
+}
+
+// TLSEnabled reports whether both certificate paths are configured
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// New builds an http.Server for the handler with the configured limits
+func New(cfg Config, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if cfg.TLSEnabled() {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return srv
+}
+
+// Run serves until ctx is cancelled, then stops accepting connections and
+// waits up to ShutdownTimeout for in-flight requests to finish.
+func Run(ctx context.Context, srv *http.Server, cfg Config) error {
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if cfg.TLSEnabled() {
+			slog.Info("HTTPS server listening", "addr", srv.Addr)
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			slog.Info("HTTP server listening", "addr", srv.Addr)
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("server failed: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down HTTP server", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Drain deadline passed; drop whatever is left
+		srv.Close()
+		return fmt.Errorf("failed to drain connections: %v", err)
+	}
+	return nil
+}
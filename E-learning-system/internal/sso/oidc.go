@@ -0,0 +1,175 @@
+// Package sso signs users in through the identity providers of
+// organizations: OpenID Connect with the authorization code flow and PKCE,
+// and SAML 2.0 with signed requests and the POST binding.
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// discoveryTTL is how long a provider's discovery document and keys are reused
+const discoveryTTL = time.Hour
+
+// ErrInvalidIDToken is returned when the provider's ID token does not check out
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Provider is one organization's identity provider registration
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+}
+
+// Request is the per-login secret state: state guards against CSRF, nonce
+// binds the ID token to this login and verifier is the PKCE secret
+type Request struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewRequest generates the random values for a login
+func NewRequest() Request {
+	return Request{
+		State:    oauth2.GenerateVerifier(),
+		Nonce:    oauth2.GenerateVerifier(),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+}
+
+// Identity is what the provider asserts about the user
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Role          string // mapped from SAML attributes; empty for the organization's default
+}
+
+// Client talks to identity providers. Discovery documents are cached per issuer.
+type Client struct {
+	httpClient  *http.Client
+	redirectURL string
+
+	mu        sync.Mutex
+	providers map[string]cachedProvider
+}
+
+type cachedProvider struct {
+	provider  *oidc.Provider
+	fetchedAt time.Time
+}
+
+// NewClient creates a Client sending users back to redirectURL
+func NewClient(httpClient *http.Client, redirectURL string) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		redirectURL: redirectURL,
+		providers:   make(map[string]cachedProvider),
+	}
+}
+
+// Discover fetches the provider's discovery document, checking that the
+// issuer is reachable and well configured
+func (c *Client) Discover(ctx context.Context, issuer string) error {
+	_, err := c.provider(ctx, issuer)
+	return err
+}
+
+// AuthCodeURL returns the provider URL the user is sent to
+func (c *Client) AuthCodeURL(ctx context.Context, p Provider, req Request) (string, error) {
+	provider, err := c.provider(ctx, p.Issuer)
+	if err != nil {
+		return "", err
+	}
+	return c.config(provider, p).AuthCodeURL(req.State,
+		oidc.Nonce(req.Nonce),
+		oauth2.S256ChallengeOption(req.Verifier),
+	), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the
+// verified identity from the ID token
+func (c *Client) Exchange(ctx context.Context, p Provider, req Request, code string) (*Identity, error) {
+	provider, err := c.provider(ctx, p.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, c.httpClient)
+	token, err := c.config(provider, p).Exchange(ctx, code, oauth2.VerifierOption(req.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing from token response", ErrInvalidIDToken)
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if idToken.Nonce != req.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"` // some providers send "true"
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	return &Identity{
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+	}, nil
+}
+
+func (c *Client) config(provider *oidc.Provider, p Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  c.redirectURL,
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+}
+
+// provider returns the cached provider for issuer, discovering it when
+// missing or stale
+func (c *Client) provider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	c.mu.Lock()
+	cached, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < discoveryTTL {
+		return cached.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, c.httpClient), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider %s: %w", issuer, err)
+	}
+
+	c.mu.Lock()
+	c.providers[issuer] = cachedProvider{provider: provider, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return provider, nil
+}
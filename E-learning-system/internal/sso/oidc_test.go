@@ -0,0 +1,358 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "learning-platform"
+	testClientSecret = "client secret"
+	testRedirectURL  = "https://sp.example.com/sso/callback"
+	testKeyID        = "signing-key"
+)
+
+// authorization is what the mock provider remembers about an issued code
+type authorization struct {
+	nonce     string
+	challenge string
+}
+
+// mockOIDCProvider is an OpenID provider with discovery, authorization,
+// token and JWKS endpoints. It signs ID tokens with key under testKeyID.
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu          sync.Mutex
+	codes       map[string]authorization
+	discoveries int
+
+	// idToken builds the ID token of a token response from its claims;
+	// signIDToken by default
+	idToken func(claims map[string]any) string
+	// editClaims changes the claims before the ID token is built
+	editClaims func(claims map[string]any)
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDCProvider{key: key, codes: map[string]authorization{}}
+	m.idToken = func(claims map[string]any) string { return signIDToken(t, m.key, "RS256", claims) }
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCProvider) issuer() string {
+	return m.server.URL
+}
+
+func (m *mockOIDCProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	m.discoveries++
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                m.issuer(),
+		"authorization_endpoint":                m.issuer() + "/authorize",
+		"token_endpoint":                        m.issuer() + "/token",
+		"jwks_uri":                              m.issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// authorize signs the user in at once and sends them back with a code
+func (m *mockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != testClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	m.mu.Lock()
+	m.codes[code] = authorization{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	m.mu.Unlock()
+
+	back, _ := url.Parse(q.Get("redirect_uri"))
+	back.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token redeems a code once, for the client that asked for it and the PKCE
+// verifier of its challenge
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	// Credentials are form-encoded inside the Basic header (RFC 6749, 2.3.1)
+	id, secret, _ := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != testClientID || secret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":            m.issuer(),
+		"sub":            "user-42",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          " Ada.Lovelace@Example.edu ",
+		"email_verified": "true",
+		"given_name":     "Ada",
+		"family_name":    "Lovelace",
+	}
+	if m.editClaims != nil {
+		m.editClaims(claims)
+	}
+
+	response := map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 300}
+	if idToken := m.idToken(claims); idToken != "" {
+		response["id_token"] = idToken
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (m *mockOIDCProvider) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": testKeyID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+	}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// signIDToken encodes claims as a JWT signed with key, or unsigned for alg "none"
+func signIDToken(t *testing.T, key *rsa.PrivateKey, alg string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": testKeyID})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if alg == "none" {
+		return signingInput + "."
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// login starts a sign-in for req, follows the provider's redirect and returns
+// the code it sent back after checking the state came back unchanged
+func login(t *testing.T, client *Client, p Provider, req Request) string {
+	t.Helper()
+	authURL, err := client.AuthCodeURL(context.Background(), p, req)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if q.Get("state") != req.State || q.Get("nonce") != req.Nonce || q.Get("redirect_uri") != testRedirectURL {
+		t.Fatalf("authorization URL %s does not carry the login's state, nonce and redirect", authURL)
+	}
+	if q.Get("code_challenge") == "" || strings.Contains(authURL, req.Verifier) {
+		t.Fatalf("authorization URL %s must carry the PKCE challenge, not the verifier", authURL)
+	}
+	if scopes := q.Get("scope"); !strings.Contains(scopes, "openid") {
+		t.Fatalf("scope = %q, want openid", scopes)
+	}
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := browser.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize answered %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if back.Query().Get("state") != req.State {
+		t.Fatalf("state came back as %q, want %q", back.Query().Get("state"), req.State)
+	}
+	return back.Query().Get("code")
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	client := NewClient(idp.server.Client(), testRedirectURL)
+	p := Provider{Issuer: idp.issuer(), ClientID: testClientID, ClientSecret: testClientSecret}
+	req := NewRequest()
+
+	code := login(t, client, p, req)
+	identity, err := client.Exchange(context.Background(), p, req, code)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{Subject: "user-42", Email: "ada.lovelace@example.edu", EmailVerified: true, GivenName: "Ada", FamilyName: "Lovelace"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	if _, err := client.Exchange(context.Background(), p, req, code); err == nil || errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("redeeming the code twice: error = %v, want the provider's refusal", err)
+	}
+	if idp.discoveries != 1 {
+		t.Errorf("discovery fetched %d times, want once", idp.discoveries)
+	}
+
+	other := NewRequest()
+	if other.State == req.State || other.Nonce == req.Nonce || other.Verifier == req.Verifier {
+		t.Error("logins share random values")
+	}
+}
+
+func TestOIDCRejects(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// setup changes the provider, or the request the code is exchanged
+		// for, which starts out as the one that got the code
+		setup          func(t *testing.T, idp *mockOIDCProvider, exchange *Request)
+		wantIDTokenErr bool // rejected by the ID token checks rather than the provider
+	}{
+		{"nonce of another login", func(_ *testing.T, _ *mockOIDCProvider, exchange *Request) {
+			exchange.Nonce = NewRequest().Nonce
+		}, true},
+		{"no nonce", func(_ *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.editClaims = func(claims map[string]any) { delete(claims, "nonce") }
+		}, true},
+		{"signed with another key", func(t *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.idToken = func(claims map[string]any) string { return signIDToken(t, otherKey, "RS256", claims) }
+		}, true},
+		{"payload changed after signing", func(t *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.idToken = func(claims map[string]any) string {
+				signed := strings.Split(signIDToken(t, idp.key, "RS256", claims), ".")
+				claims["email"] = "admin@example.edu"
+				forged, _ := json.Marshal(claims)
+				return signed[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + signed[2]
+			}
+		}, true},
+		{"unsigned", func(t *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.idToken = func(claims map[string]any) string { return signIDToken(t, nil, "none", claims) }
+		}, true},
+		{"for another client", func(_ *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.editClaims = func(claims map[string]any) { claims["aud"] = "another-app" }
+		}, true},
+		{"from another issuer", func(_ *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.editClaims = func(claims map[string]any) { claims["iss"] = "https://evil.example.com" }
+		}, true},
+		{"expired", func(_ *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.editClaims = func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }
+		}, true},
+		{"no ID token", func(_ *testing.T, idp *mockOIDCProvider, _ *Request) {
+			idp.idToken = func(map[string]any) string { return "" }
+		}, true},
+		{"PKCE verifier of another login", func(_ *testing.T, _ *mockOIDCProvider, exchange *Request) {
+			exchange.Verifier = NewRequest().Verifier
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockOIDCProvider(t)
+			client := NewClient(idp.server.Client(), testRedirectURL)
+			p := Provider{Issuer: idp.issuer(), ClientID: testClientID, ClientSecret: testClientSecret}
+			req := NewRequest()
+			code := login(t, client, p, req)
+
+			exchange := req
+			tt.setup(t, idp, &exchange)
+			identity, err := client.Exchange(context.Background(), p, exchange, code)
+			if err == nil {
+				t.Fatalf("accepted as %+v", identity)
+			}
+			if errors.Is(err, ErrInvalidIDToken) != tt.wantIDTokenErr {
+				t.Errorf("error = %v, want ErrInvalidIDToken: %v", err, tt.wantIDTokenErr)
+			}
+		})
+	}
+}
+
+func TestOIDCClientAuthentication(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	client := NewClient(idp.server.Client(), testRedirectURL)
+	p := Provider{Issuer: idp.issuer(), ClientID: testClientID, ClientSecret: "wrong secret"}
+	req := NewRequest()
+
+	code := login(t, client, p, req)
+	if _, err := client.Exchange(context.Background(), p, req, code); err == nil || errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("error = %v, want the provider's refusal", err)
+	}
+}
+
+func TestOIDCDiscover(t *testing.T) {
+	idp := newMockOIDCProvider(t)
+	client := NewClient(idp.server.Client(), testRedirectURL)
+
+	if err := client.Discover(context.Background(), idp.issuer()); err != nil {
+		t.Errorf("Discover: %v", err)
+	}
+	// The discovery document names a different issuer
+	if err := client.Discover(context.Background(), idp.issuer()+"/"); err == nil {
+		t.Error("issuer mismatch accepted")
+	}
+	if err := client.Discover(context.Background(), idp.issuer()+"/tenant"); err == nil {
+		t.Error("missing discovery document accepted")
+	}
+}
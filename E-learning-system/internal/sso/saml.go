@@ -0,0 +1,296 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	// samlCertificateLifetime is the validity of generated SP certificates.
+	// Identity providers pin the certificate from the metadata, so it is long.
+	samlCertificateLifetime = 10 * 365 * 24 * time.Hour
+
+	// maxSAMLMetadataSize bounds identity provider metadata fetched by URL
+	maxSAMLMetadataSize = 1 << 20
+)
+
+var (
+	// ErrInvalidSAMLMetadata is returned for identity provider metadata that cannot be used
+	ErrInvalidSAMLMetadata = errors.New("invalid SAML identity provider metadata")
+	// ErrInvalidSAMLResponse is returned when an identity provider's response does not check out
+	ErrInvalidSAMLResponse = errors.New("invalid SAML response")
+)
+
+// SAMLIdP is what the service provider needs from identity provider metadata
+type SAMLIdP struct {
+	EntityID string
+	SSOURL   string // HTTP-Redirect single sign-on endpoint
+}
+
+// SAMLAssertion is a validated assertion. RequestID is the AuthnRequest it
+// answers; the caller checks that it is one it started.
+type SAMLAssertion struct {
+	RequestID  string
+	Issuer     string
+	NameID     string
+	Attributes map[string][]string // by Name and, when set, FriendlyName
+}
+
+// SAMLServiceProvider is one organization's SAML 2.0 service provider
+type SAMLServiceProvider struct {
+	sp saml.ServiceProvider
+}
+
+// SAMLConfig configures a SAMLServiceProvider. The certificate and key are PEM encoded.
+type SAMLConfig struct {
+	EntityID       string
+	MetadataURL    string
+	ACSURL         string
+	CertificatePEM string
+	KeyPEM         string
+	IdPMetadata    []byte
+}
+
+// GenerateSAMLKeyPair creates the self-signed certificate and RSA key a
+// service provider signs its requests with
+func GenerateSAMLKeyPair(commonName string) (certPEM, keyPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(samlCertificateLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	return certPEM, keyPEM, nil
+}
+
+// FetchSAMLMetadata downloads identity provider metadata
+func (c *Client) FetchSAMLMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLMetadata, err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SAML metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch SAML metadata: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSAMLMetadataSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SAML metadata: %w", err)
+	}
+	if len(data) > maxSAMLMetadataSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidSAMLMetadata, maxSAMLMetadataSize)
+	}
+	return data, nil
+}
+
+// ParseSAMLMetadata checks that identity provider metadata has a redirect
+// sign-on endpoint and a signing certificate
+func ParseSAMLMetadata(data []byte) (*SAMLIdP, error) {
+	entity, err := samlsp.ParseMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLMetadata, err)
+	}
+	if entity.EntityID == "" || len(entity.IDPSSODescriptors) == 0 {
+		return nil, fmt.Errorf("%w: no identity provider descriptor", ErrInvalidSAMLMetadata)
+	}
+
+	idp := &SAMLIdP{EntityID: entity.EntityID}
+	hasCertificate := false
+	for _, descriptor := range entity.IDPSSODescriptors {
+		for _, service := range descriptor.SingleSignOnServices {
+			if service.Binding == saml.HTTPRedirectBinding && idp.SSOURL == "" {
+				idp.SSOURL = service.Location
+			}
+		}
+		for _, key := range descriptor.KeyDescriptors {
+			if key.Use != "encryption" && len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				hasCertificate = true
+			}
+		}
+	}
+	if idp.SSOURL == "" {
+		return nil, fmt.Errorf("%w: no HTTP-Redirect single sign-on endpoint", ErrInvalidSAMLMetadata)
+	}
+	if !hasCertificate {
+		return nil, fmt.Errorf("%w: no signing certificate", ErrInvalidSAMLMetadata)
+	}
+	return idp, nil
+}
+
+// NewSAMLServiceProvider builds a service provider from its stored configuration
+func NewSAMLServiceProvider(cfg SAMLConfig) (*SAMLServiceProvider, error) {
+	metadataURL, err := url.Parse(cfg.MetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata URL: %w", err)
+	}
+	acsURL, err := url.Parse(cfg.ACSURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertion consumer URL: %w", err)
+	}
+
+	certBlock, _ := pem.Decode([]byte(cfg.CertificatePEM))
+	if certBlock == nil {
+		return nil, errors.New("invalid SAML certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode([]byte(cfg.KeyPEM))
+	if keyBlock == nil {
+		return nil, errors.New("invalid SAML key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML key: %w", err)
+	}
+
+	idpMetadata, err := samlsp.ParseMetadata(cfg.IdPMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLMetadata, err)
+	}
+
+	return &SAMLServiceProvider{sp: saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		Key:               key,
+		Certificate:       cert,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.PersistentNameIDFormat,
+		SignatureMethod:   dsig.RSASHA256SignatureMethod,
+		// Every response must answer a request started here; the request
+		// is matched to the browser that started it by the caller
+		ValidateRequestID: func(response saml.Response, _ []string) error {
+			if response.InResponseTo == "" {
+				return errors.New("unsolicited response")
+			}
+			return nil
+		},
+	}}, nil
+}
+
+// Metadata returns the service provider metadata to register with the identity provider
+func (p *SAMLServiceProvider) Metadata() ([]byte, error) {
+	metadata := p.sp.Metadata()
+	// Responses are only accepted through the POST binding
+	for i := range metadata.SPSSODescriptors {
+		services := metadata.SPSSODescriptors[i].AssertionConsumerServices
+		metadata.SPSSODescriptors[i].AssertionConsumerServices = services[:1]
+	}
+	data, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SAML metadata: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// AuthnRequestURL returns the signed HTTP-Redirect URL that sends the user
+// to the identity provider, and the ID of the request
+func (p *SAMLServiceProvider) AuthnRequestURL() (string, string, error) {
+	req, err := p.sp.MakeAuthenticationRequest(p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create SAML request: %w", err)
+	}
+	redirect, err := req.Redirect("", &p.sp)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign SAML request: %w", err)
+	}
+	return redirect.String(), req.ID, nil
+}
+
+// ParseResponse validates the base64 SAMLResponse posted to the assertion
+// consumer: signature, issuer, destination, audience, recipient and time
+// conditions
+func (p *SAMLServiceProvider) ParseResponse(encoded string) (*SAMLAssertion, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	// The request ID is read first so the assertion can be checked against
+	// it; ValidateRequestID refuses responses without one
+	var response saml.Response
+	if err := xml.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+
+	assertion, err := p.sp.ParseXMLResponse(raw, []string{response.InResponseTo}, p.sp.AcsURL)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLResponse, err)
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidSAMLResponse)
+	}
+
+	result := &SAMLAssertion{
+		RequestID:  response.InResponseTo,
+		Issuer:     p.sp.IDPMetadata.EntityID,
+		NameID:     assertion.Subject.NameID.Value,
+		Attributes: make(map[string][]string),
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, value := range attr.Values {
+				result.Attributes[attr.Name] = append(result.Attributes[attr.Name], value.Value)
+				if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+					result.Attributes[attr.FriendlyName] = append(result.Attributes[attr.FriendlyName], value.Value)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// First returns the first value of an attribute, or ""
+func (a *SAMLAssertion) First(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
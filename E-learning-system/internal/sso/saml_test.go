@@ -0,0 +1,393 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	testIdPEntityID = "https://idp.example.edu/metadata"
+	testSPEntityID  = "https://sp.example.com/saml/org/metadata"
+	testSPACSURL    = "https://sp.example.com/saml/org/acs"
+)
+
+// mockIdP is an identity provider that signs responses for a test service
+// provider without any browser round trip
+type mockIdP struct {
+	idp saml.IdentityProvider
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	certPEM, keyPEM, err := GenerateSAMLKeyPair("idp.example.edu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key := parseTestKeyPair(t, certPEM, keyPEM)
+
+	metadataURL, _ := url.Parse(testIdPEntityID)
+	ssoURL, _ := url.Parse("https://idp.example.edu/sso")
+	return &mockIdP{idp: saml.IdentityProvider{
+		Key:             key,
+		Certificate:     cert,
+		MetadataURL:     *metadataURL,
+		SSOURL:          *ssoURL,
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}}
+}
+
+// metadata returns the XML metadata of the identity provider
+func (m *mockIdP) metadata(t *testing.T) []byte {
+	t.Helper()
+	data, err := xml.Marshal(m.idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// respond returns a base64 SAMLResponse answering requestID for sp. edit
+// may change the request or assertion before it is signed.
+func (m *mockIdP) respond(t *testing.T, sp *SAMLServiceProvider, requestID string, session *saml.Session, edit func(req *saml.IdpAuthnRequest)) string {
+	t.Helper()
+	spMetadata := sp.sp.Metadata()
+	descriptor := &spMetadata.SPSSODescriptors[0]
+	req := &saml.IdpAuthnRequest{
+		IDP:                     &m.idp,
+		HTTPRequest:             httptest.NewRequest("POST", m.idp.SSOURL.String(), nil),
+		Request:                 saml.AuthnRequest{ID: requestID, IssueInstant: time.Now()},
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         descriptor,
+		ACSEndpoint:             &descriptor.AssertionConsumerServices[0],
+		Now:                     time.Now(),
+	}
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		edit(req)
+	}
+	form, err := req.PostBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.SAMLResponse
+}
+
+func parseTestKeyPair(t *testing.T, certPEM, keyPEM string) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	certBlock, _ := pem.Decode([]byte(certPEM))
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBlock, _ := pem.Decode([]byte(keyPEM))
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestSP(t *testing.T, idpMetadata []byte) *SAMLServiceProvider {
+	t.Helper()
+	certPEM, keyPEM, err := GenerateSAMLKeyPair("sp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, err := NewSAMLServiceProvider(SAMLConfig{
+		EntityID:       testSPEntityID,
+		MetadataURL:    testSPEntityID,
+		ACSURL:         testSPACSURL,
+		CertificatePEM: certPEM,
+		KeyPEM:         keyPEM,
+		IdPMetadata:    idpMetadata,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sp
+}
+
+func testSession() *saml.Session {
+	return &saml.Session{
+		ID:            "session",
+		CreateTime:    time.Now(),
+		ExpireTime:    time.Now().Add(time.Hour),
+		Index:         "1",
+		NameID:        "student-42",
+		NameIDFormat:  string(saml.PersistentNameIDFormat),
+		UserEmail:     "ada@example.edu",
+		UserGivenName: "Ada",
+		UserSurname:   "Lovelace",
+		Groups:        []string{"member", "student"},
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := newMockIdP(t)
+	sp := newTestSP(t, idp.metadata(t))
+	impostor := newMockIdP(t) // same entity ID, different key
+
+	tests := []struct {
+		name    string
+		idp     *mockIdP
+		request string
+		session func(s *saml.Session)
+		edit    func(req *saml.IdpAuthnRequest)
+		wantErr bool
+	}{
+		{name: "valid", idp: idp, request: "id-1"},
+		{name: "unsolicited", idp: idp, request: "", wantErr: true},
+		{name: "signed by another key", idp: impostor, request: "id-1", wantErr: true},
+		{name: "no subject", idp: idp, request: "id-1", session: func(s *saml.Session) { s.NameID = "" }, wantErr: true},
+		{
+			name: "other audience", idp: idp, request: "id-1", wantErr: true,
+			edit: func(req *saml.IdpAuthnRequest) {
+				req.Assertion.Conditions.AudienceRestrictions[0].Audience.Value = "https://other.example.com/metadata"
+			},
+		},
+		{
+			name: "other recipient", idp: idp, request: "id-1", wantErr: true,
+			edit: func(req *saml.IdpAuthnRequest) {
+				endpoint := *req.ACSEndpoint
+				endpoint.Location = "https://other.example.com/acs"
+				req.ACSEndpoint = &endpoint
+				req.Assertion.Subject.SubjectConfirmations[0].SubjectConfirmationData.Recipient = endpoint.Location
+			},
+		},
+		{
+			name: "expired", idp: idp, request: "id-1", wantErr: true,
+			edit: func(req *saml.IdpAuthnRequest) {
+				past := time.Now().Add(-time.Hour)
+				req.Now = past
+				req.Assertion.IssueInstant = past
+				req.Assertion.Conditions.NotBefore = past.Add(-time.Minute)
+				req.Assertion.Conditions.NotOnOrAfter = past.Add(time.Minute)
+				req.Assertion.Subject.SubjectConfirmations[0].SubjectConfirmationData.NotOnOrAfter = past.Add(time.Minute)
+			},
+		},
+		{
+			name: "not yet valid", idp: idp, request: "id-1", wantErr: true,
+			edit: func(req *saml.IdpAuthnRequest) {
+				req.Assertion.Conditions.NotBefore = time.Now().Add(time.Hour)
+				req.Assertion.Conditions.NotOnOrAfter = time.Now().Add(2 * time.Hour)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := testSession()
+			if tt.session != nil {
+				tt.session(session)
+			}
+			encoded := tt.idp.respond(t, sp, tt.request, session, tt.edit)
+
+			assertion, err := sp.ParseResponse(encoded)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSAMLResponse) {
+					t.Fatalf("ParseResponse error = %v, want ErrInvalidSAMLResponse", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResponse: %v", err)
+			}
+
+			if assertion.RequestID != tt.request || assertion.Issuer != testIdPEntityID || assertion.NameID != "student-42" {
+				t.Errorf("assertion = %+v", assertion)
+			}
+			// Attributes are found by name and by friendly name
+			checks := map[string]string{
+				"urn:oid:0.9.2342.19200300.100.1.3": "ada@example.edu",
+				"mail":                              "ada@example.edu",
+				"urn:oid:2.5.4.42":                  "Ada",
+				"givenName":                         "Ada",
+				"sn":                                "Lovelace",
+				"missing":                           "",
+			}
+			for name, want := range checks {
+				if got := assertion.First(name); got != want {
+					t.Errorf("First(%q) = %q, want %q", name, got, want)
+				}
+			}
+			if got := assertion.Attributes["eduPersonAffiliation"]; len(got) != 2 || got[1] != "student" {
+				t.Errorf("eduPersonAffiliation = %q, want both groups", got)
+			}
+		})
+	}
+}
+
+func TestParseResponseMalformed(t *testing.T) {
+	idp := newMockIdP(t)
+	sp := newTestSP(t, idp.metadata(t))
+	valid := idp.respond(t, sp, "id-1", testSession(), nil)
+	raw, err := base64.StdEncoding.DecodeString(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"not base64", "not base64!"},
+		{"not XML", base64.StdEncoding.EncodeToString([]byte("plain text"))},
+		{"tampered destination", base64.StdEncoding.EncodeToString(bytes.Replace(raw, []byte(testSPACSURL), []byte("https://sp.example.com/saml/org/acs2"), 1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sp.ParseResponse(tt.encoded); !errors.Is(err, ErrInvalidSAMLResponse) {
+				t.Errorf("ParseResponse error = %v, want ErrInvalidSAMLResponse", err)
+			}
+		})
+	}
+}
+
+func TestParseSAMLMetadata(t *testing.T) {
+	idp := newMockIdP(t)
+	valid := string(idp.metadata(t))
+	spMetadata, err := newTestSP(t, idp.metadata(t)).Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"no redirect binding", strings.Replace(valid, saml.HTTPRedirectBinding, "urn:example:binding", 1), true},
+		{"encryption certificate only", strings.Replace(valid, `use="signing"`, `use="encryption"`, 1), true},
+		{"service provider metadata", string(spMetadata), true},
+		{"not XML", "metadata", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSAMLMetadata([]byte(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSAMLMetadata) {
+					t.Errorf("ParseSAMLMetadata error = %v, want ErrInvalidSAMLMetadata", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.EntityID != testIdPEntityID || got.SSOURL != "https://idp.example.edu/sso" {
+				t.Errorf("ParseSAMLMetadata = %+v", got)
+			}
+		})
+	}
+}
+
+func TestSAMLServiceProviderMetadata(t *testing.T) {
+	sp := newTestSP(t, newMockIdP(t).metadata(t))
+	data, err := sp.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var metadata saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.EntityID != testSPEntityID {
+		t.Errorf("entity ID = %q, want %q", metadata.EntityID, testSPEntityID)
+	}
+	services := metadata.SPSSODescriptors[0].AssertionConsumerServices
+	if len(services) != 1 || services[0].Binding != saml.HTTPPostBinding || services[0].Location != testSPACSURL {
+		t.Errorf("assertion consumers = %+v, want only the POST binding", services)
+	}
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	sp := newTestSP(t, newMockIdP(t).metadata(t))
+	redirect, requestID, err := sp.AuthnRequestURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestID == "" {
+		t.Fatal("no request ID")
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "idp.example.edu" || u.Path != "/sso" {
+		t.Errorf("request goes to %s", redirect)
+	}
+	q := u.Query()
+	if q.Get("SigAlg") != dsig.RSASHA256SignatureMethod || q.Get("Signature") == "" {
+		t.Errorf("request is not signed with RSA-SHA256: %v", q)
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(q.Get("SAMLRequest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req saml.AuthnRequest
+	if err := xml.Unmarshal(raw, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ID != requestID || req.AssertionConsumerServiceURL != testSPACSURL || req.Issuer.Value != testSPEntityID {
+		t.Errorf("AuthnRequest = ID %q, ACS %q, issuer %q", req.ID, req.AssertionConsumerServiceURL, req.Issuer.Value)
+	}
+}
+
+func TestNewSAMLServiceProviderRejects(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSAMLKeyPair("sp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idpMetadata := newMockIdP(t).metadata(t)
+
+	tests := []struct {
+		name string
+		edit func(cfg *SAMLConfig)
+	}{
+		{"invalid certificate", func(cfg *SAMLConfig) { cfg.CertificatePEM = "certificate" }},
+		{"invalid key", func(cfg *SAMLConfig) { cfg.KeyPEM = "key" }},
+		{"key in certificate place", func(cfg *SAMLConfig) { cfg.CertificatePEM = keyPEM }},
+		{"invalid identity provider metadata", func(cfg *SAMLConfig) { cfg.IdPMetadata = []byte("metadata") }},
+		{"invalid ACS URL", func(cfg *SAMLConfig) { cfg.ACSURL = "://acs" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := SAMLConfig{
+				EntityID:       testSPEntityID,
+				MetadataURL:    testSPEntityID,
+				ACSURL:         testSPACSURL,
+				CertificatePEM: certPEM,
+				KeyPEM:         keyPEM,
+				IdPMetadata:    idpMetadata,
+			}
+			tt.edit(&cfg)
+			if _, err := NewSAMLServiceProvider(cfg); err == nil {
+				t.Error("NewSAMLServiceProvider accepted the configuration")
+			}
+		})
+	}
+}
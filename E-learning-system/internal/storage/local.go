@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage keeps objects on the local filesystem and signs URLs with HMAC
+type LocalStorage struct {
+	root       string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewLocalStorage creates a filesystem-backed store rooted at dir
+func NewLocalStorage(dir, publicURL, signingKey string) (*LocalStorage, error) {
+	if dir == "" {
+		return nil, errors.New("local storage directory is required")
+	}
+	if signingKey == "" {
+		return nil, errors.New("local storage signing key is required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &LocalStorage{
+		root:       dir,
+		publicURL:  strings.TrimRight(publicURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put implements Storage
+func (s *LocalStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get implements Storage
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Storage
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL implements Storage
+func (s *LocalStorage) SignedURL(key string, expiry time.Duration) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiry).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", s.sign(cleaned, expires))
+
+	return fmt.Sprintf("%s/%s?%s", s.publicURL, escapePath(cleaned), q.Encode()), nil
+}
+
+// Verify checks a signature produced by SignedURL
+func (s *LocalStorage) Verify(key, expires, signature string) bool {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(s.sign(cleaned, exp)))
+}
+
+func (s *LocalStorage) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// escapePath escapes each segment of a slash separated key
+func escapePath(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
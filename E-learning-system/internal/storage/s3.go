@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+)
+
+// S3Storage talks to any S3-compatible object store (AWS S3, MinIO, ...)
+// using Signature Version 4.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// NewS3Storage creates an S3-compatible store. pathStyle addresses the bucket
+// as endpoint/bucket/key, which local stand-ins such as MinIO expect.
+func NewS3Storage(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) (*S3Storage, error) {
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("s3 storage requires endpoint, bucket, access key and secret key")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// objectURL returns the URL of key without query parameters
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+// Put implements Storage
+func (s *S3Storage) Put(key string, r io.Reader, size int64, contentType string) error {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(cleaned).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// Get implements Storage
+func (s *S3Storage) Get(key string) (io.ReadCloser, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(cleaned).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+}
+
+// Delete implements Storage
+func (s *S3Storage) Delete(key string) error {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(cleaned).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// SignedURL implements Storage with a presigned GET URL
+func (s *S3Storage) SignedURL(key string, expiry time.Duration) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expiry <= 0 || expiry > 7*24*time.Hour {
+		return "", errors.New("s3 presigned URLs must expire within 7 days")
+	}
+
+	now := time.Now().UTC()
+	u := s.objectURL(cleaned)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", s3Algorithm)
+	q.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	q.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		s3CanonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	q.Set("X-Amz-Signature", s.signature(now, canonical))
+	u.RawQuery = s3CanonicalQuery(q)
+
+	return u.String(), nil
+}
+
+// do signs req with an Authorization header and sends it
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	now := time.Now().UTC()
+
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+		"x-amz-date:" + now.Format(s3TimeFormat) + "\n"
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		headers,
+		strings.Join(signed, ";"),
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, s.scope(now), strings.Join(signed, ";"), s.signature(now, canonical)))
+
+	return s.client.Do(req)
+}
+
+func (s *S3Storage) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s *S3Storage) signature(t time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		s.scope(t),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery encodes query parameters sorted by key as SigV4 requires
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3EscapePath escapes every path segment, keeping the slashes
+func s3EscapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = s3Escape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// s3Escape percent-encodes everything except RFC 3986 unreserved characters
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
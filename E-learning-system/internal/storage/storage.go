@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"e-learning-system/internal/domain/apperr"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist in the store
+var ErrNotFound = apperr.NotFound("object_not_found", "object not found")
+
+// Storage is a minimal object store used for uploaded files
+type Storage interface {
+	// Put stores the content of r under key
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(key string) error
+	// SignedURL returns a URL that grants read access to key until expiry
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// Config selects and configures a Storage implementation
+type Config struct {
+	Driver string // local or s3
+
+	// local
+	LocalDir   string
+	PublicURL  string // base URL the local files handler is mounted on, e.g. http://localhost:8080/files
+	SigningKey string
+
+	// s3
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+}
+
+// New builds the Storage selected by cfg.Driver
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStorage(cfg.LocalDir, cfg.PublicURL, cfg.SigningKey)
+	case "s3":
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// CleanKey normalizes an object key and rejects keys escaping the store root
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + strings.TrimSpace(key))
+	cleaned = strings.TrimPrefix(cleaned, "/")
+	if cleaned == "" || cleaned == "." || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import "testing"
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"avatars/a.png", "avatars/a.png", false},
+		{"/avatars/a.png", "avatars/a.png", false},
+		{" avatars/a.png ", "avatars/a.png", false},
+		{"avatars//a.png", "avatars/a.png", false},
+		{"avatars/./a.png", "avatars/a.png", false},
+		{"videos/1/hls/", "videos/1/hls", false},
+		{"a b/é.png", "a b/é.png", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"/", "", true},
+		{".", "", true},
+		{"..", "", true},
+		{"../etc/passwd", "", true},
+		{"avatars/../../etc/passwd", "", true},
+		{"avatars/../a.png", "", true},
+		{"a..b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := CleanKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CleanKey(%q) error = %v, want error %v", tt.key, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CleanKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package tenant carries the organization a request is confined to down to
+// the services. Requests made with an API key may only see and change the
+// records of the key's organization; requests made by signed-in users carry
+// no organization and are left to the role checks of their routes.
+package tenant
+
+import (
+	"context"
+	"e-learning-system/internal/domain/apperr"
+
+	"github.com/gofrs/uuid"
+)
+
+// ErrOtherOrganization is returned for records outside the request's organization
+var ErrOtherOrganization = apperr.Forbidden("api_key_wrong_organization", "this API key belongs to another organization")
+
+type contextKey struct{}
+
+// WithOrganization returns a copy of ctx confined to orgID
+func WithOrganization(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// OrganizationFromContext returns the organization ctx is confined to, if any
+func OrganizationFromContext(ctx context.Context) (uuid.UUID, bool) {
+	orgID, ok := ctx.Value(contextKey{}).(uuid.UUID)
+	return orgID, ok
+}
+
+// Check refuses a record of organization orgID when ctx is confined to
+// another one. Records without an organization, passed as nil, belong to
+// the platform and are refused to every confined request.
+func Check(ctx context.Context, orgID *uuid.UUID) error {
+	own, ok := OrganizationFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if orgID == nil || *orgID != own {
+		return ErrOtherOrganization
+	}
+	return nil
+}
+
+// Filter narrows a list filter's organization to the one ctx is confined
+// to. An empty filter gets that organization; a filter naming another one
+// is refused.
+func Filter(ctx context.Context, orgID *uuid.UUID) (*uuid.UUID, error) {
+	own, ok := OrganizationFromContext(ctx)
+	if !ok {
+		return orgID, nil
+	}
+	if orgID != nil && *orgID != own {
+		return nil, ErrOtherOrganization
+	}
+	return &own, nil
+}
@@ -0,0 +1,39 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter allows a fixed number of events per key within a window, such as
+// password reset emails per address
+type Limiter struct {
+	store  Store
+	name   string
+	limit  int
+	window time.Duration
+}
+
+// NewLimiter creates a Limiter keeping its counters in store. name keeps
+// the counters of different limiters apart.
+func NewLimiter(store Store, name string, limit int, window time.Duration) *Limiter {
+	return &Limiter{store: store, name: name, limit: limit, window: window}
+}
+
+// Allow counts an event for key and reports whether it is within the limit.
+// When it is not, retryAfter says when the window ends. Keys are compared
+// case-insensitively so spellings of an email address share a counter.
+func (l *Limiter) Allow(ctx context.Context, key string) (ok bool, retryAfter time.Duration, err error) {
+	k := "limit:" + l.name + ":" + normalizeAccount(key)
+
+	count, err := l.store.Incr(ctx, k, l.window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count <= int64(l.limit) {
+		return true, 0, nil
+	}
+
+	retryAfter, err = l.store.TTL(ctx, k)
+	return false, retryAfter, err
+}
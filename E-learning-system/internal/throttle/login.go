@@ -0,0 +1,137 @@
+package throttle
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// LoginPolicy sets how failed sign-ins are slowed down and locked out
+type LoginPolicy struct {
+	Window          time.Duration // failures older than this are forgotten
+	AccountLimit    int           // failures per account before it is locked
+	IPLimit         int           // failures per client IP before it is blocked
+	LockoutDuration time.Duration // how long a locked account stays locked
+	DelayAfter      int           // failures per account before responses slow down
+	BaseDelay       time.Duration // first delay, doubled with every further failure
+	MaxDelay        time.Duration
+}
+
+// LoginDecision says whether a sign-in attempt may go ahead
+type LoginDecision struct {
+	AccountLocked bool
+	IPBlocked     bool
+	RetryAfter    time.Duration // set when locked or blocked
+	Delay         time.Duration // wait this long before checking the password
+}
+
+// Allowed reports whether the attempt may go ahead
+func (d LoginDecision) Allowed() bool {
+	return !d.AccountLocked && !d.IPBlocked
+}
+
+// LoginGuard tracks failed sign-ins per account and per client IP
+type LoginGuard struct {
+	store  Store
+	policy LoginPolicy
+}
+
+// NewLoginGuard creates a LoginGuard keeping its counters in store
+func NewLoginGuard(store Store, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{store: store, policy: policy}
+}
+
+// Check decides whether a sign-in to account from ip may go ahead
+func (g *LoginGuard) Check(ctx context.Context, account, ip string) (LoginDecision, error) {
+	account = normalizeAccount(account)
+
+	var d LoginDecision
+	lockTTL, err := g.store.TTL(ctx, lockKey(account))
+	if err != nil {
+		return d, err
+	}
+	if lockTTL > 0 {
+		d.AccountLocked, d.RetryAfter = true, lockTTL
+		return d, nil
+	}
+
+	if ip != "" {
+		ipFailures, err := g.store.Get(ctx, ipKey(ip))
+		if err != nil {
+			return d, err
+		}
+		if ipFailures >= int64(g.policy.IPLimit) {
+			d.IPBlocked = true
+			d.RetryAfter, err = g.store.TTL(ctx, ipKey(ip))
+			return d, err
+		}
+	}
+
+	failures, err := g.store.Get(ctx, accountKey(account))
+	if err != nil {
+		return d, err
+	}
+	d.Delay = g.delay(failures)
+	return d, nil
+}
+
+// Fail records a failed sign-in. It reports locked=true for the failure
+// that locks the account, so the caller can notify the owner once.
+func (g *LoginGuard) Fail(ctx context.Context, account, ip string) (locked bool, err error) {
+	account = normalizeAccount(account)
+
+	if ip != "" {
+		if _, err := g.store.Incr(ctx, ipKey(ip), g.policy.Window); err != nil {
+			return false, err
+		}
+	}
+
+	failures, err := g.store.Incr(ctx, accountKey(account), g.policy.Window)
+	if err != nil {
+		return false, err
+	}
+	if failures < int64(g.policy.AccountLimit) {
+		return false, nil
+	}
+
+	if err := g.store.Set(ctx, lockKey(account), 1, g.policy.LockoutDuration); err != nil {
+		return false, err
+	}
+	// Start afresh once the lock expires
+	return true, g.store.Delete(ctx, accountKey(account))
+}
+
+// Succeed clears the account's failures. The IP counter is kept, so one
+// valid account cannot be used to reset a credential-stuffing run.
+func (g *LoginGuard) Succeed(ctx context.Context, account string) error {
+	return g.store.Delete(ctx, accountKey(normalizeAccount(account)))
+}
+
+// Unlock lifts a lockout and clears the account's failures
+func (g *LoginGuard) Unlock(ctx context.Context, account string) error {
+	account = normalizeAccount(account)
+	return g.store.Delete(ctx, lockKey(account), accountKey(account))
+}
+
+// delay grows exponentially from BaseDelay once DelayAfter failures are reached
+func (g *LoginGuard) delay(failures int64) time.Duration {
+	over := failures - int64(g.policy.DelayAfter)
+	if g.policy.BaseDelay <= 0 || over < 0 {
+		return 0
+	}
+
+	d := g.policy.BaseDelay
+	for i := int64(0); i < over && d < g.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, g.policy.MaxDelay)
+}
+
+// normalizeAccount makes differently cased spellings of an email share a counter
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+func accountKey(account string) string { return "login:fail:account:" + account }
+func ipKey(ip string) string           { return "login:fail:ip:" + ip }
+func lockKey(account string) string    { return "login:lock:" + account }
@@ -0,0 +1,111 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store local to the process. Counters are lost on
+// restart and not shared between instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+	writes  int
+}
+
+type memoryEntry struct {
+	value   int64
+	expires time.Time
+}
+
+// pruneEvery is how many writes pass between sweeps of expired keys
+const pruneEvery = 1024
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Incr implements Store
+func (s *MemoryStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	e, ok := s.live(key, now)
+	if !ok {
+		e = memoryEntry{expires: now.Add(ttl)}
+	}
+	e.value++
+	s.put(key, e, now)
+	return e.value, nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, _ := s.live(key, s.now())
+	return e.value, nil
+}
+
+// Set implements Store
+func (s *MemoryStore) Set(_ context.Context, key string, value int64, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.put(key, memoryEntry{value: value, expires: now.Add(ttl)}, now)
+	return nil
+}
+
+// TTL implements Store
+func (s *MemoryStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	e, ok := s.live(key, now)
+	if !ok {
+		return 0, nil
+	}
+	return e.expires.Sub(now), nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// live returns the entry for key unless it has expired
+func (s *MemoryStore) live(key string, now time.Time) (memoryEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return memoryEntry{}, false
+	}
+	return e, true
+}
+
+// put stores e and, every pruneEvery writes, drops expired entries so the map stays bounded
+func (s *MemoryStore) put(key string, e memoryEntry, now time.Time) {
+	s.entries[key] = e
+
+	s.writes++
+	if s.writes%pruneEvery != 0 {
+		return
+	}
+	for k, v := range s.entries {
+		if !now.Before(v.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
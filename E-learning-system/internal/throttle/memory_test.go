@@ -0,0 +1,151 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for MemoryStore
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	s := NewMemoryStore()
+	s.now = clock.now
+	return s, clock
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	// Each step runs one operation after advancing the clock and checks
+	// the value and TTL of the key afterwards
+	type step struct {
+		advance time.Duration
+		op      func(s *MemoryStore) error
+		value   int64
+		ttl     time.Duration
+	}
+	incr := func(ttl time.Duration) func(s *MemoryStore) error {
+		return func(s *MemoryStore) error { _, err := s.Incr(ctx, "k", ttl); return err }
+	}
+	set := func(value int64, ttl time.Duration) func(s *MemoryStore) error {
+		return func(s *MemoryStore) error { return s.Set(ctx, "k", value, ttl) }
+	}
+	del := func(s *MemoryStore) error { return s.Delete(ctx, "k", "missing") }
+	none := func(s *MemoryStore) error { return nil }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"missing key", []step{{0, none, 0, 0}}},
+		{"first increment starts the window", []step{{0, incr(time.Minute), 1, time.Minute}}},
+		{"increment keeps the window", []step{
+			{0, incr(time.Minute), 1, time.Minute},
+			{20 * time.Second, incr(time.Hour), 2, 40 * time.Second},
+		}},
+		{"expired key starts over", []step{
+			{0, incr(time.Minute), 1, time.Minute},
+			{0, incr(time.Minute), 2, time.Minute},
+			{time.Minute, none, 0, 0},
+			{0, incr(time.Minute), 1, time.Minute},
+		}},
+		{"set replaces value and window", []step{
+			{0, incr(time.Minute), 1, time.Minute},
+			{0, set(7, time.Hour), 7, time.Hour},
+			{0, incr(time.Minute), 8, time.Hour},
+		}},
+		{"delete", []step{
+			{0, incr(time.Minute), 1, time.Minute},
+			{0, del, 0, 0},
+			{0, incr(time.Minute), 1, time.Minute},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clock := newTestStore()
+			for i, st := range tt.steps {
+				clock.t = clock.t.Add(st.advance)
+				if err := st.op(s); err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				value, err := s.Get(ctx, "k")
+				if err != nil {
+					t.Fatal(err)
+				}
+				ttl, err := s.TTL(ctx, "k")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != st.value || ttl != st.ttl {
+					t.Errorf("step %d: value %d, ttl %s; want %d, %s", i, value, ttl, st.value, st.ttl)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStorePrunesExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newTestStore()
+
+	for i := range pruneEvery - 1 {
+		if _, err := s.Incr(ctx, fmt.Sprint("old", i), time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.t = clock.t.Add(time.Second)
+	if _, err := s.Incr(ctx, "new", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.entries) != 1 {
+		t.Errorf("%d entries after pruning, want 1", len(s.entries))
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		limit     int
+		keys      []string
+		advance   time.Duration // before the last key
+		wantOK    bool
+		wantRetry time.Duration
+	}{
+		{"within the limit", 3, []string{"a", "a", "a"}, 0, true, 0},
+		{"over the limit", 3, []string{"a", "a", "a", "a"}, 10 * time.Minute, false, 50 * time.Minute},
+		{"keys ignore case", 1, []string{"Ada@Example.com", " ada@example.com"}, 0, false, time.Hour},
+		{"keys are separate", 1, []string{"a", "b"}, 0, true, 0},
+		{"window ends", 1, []string{"a", "a"}, time.Hour, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clock := newTestStore()
+			l := NewLimiter(s, "test", tt.limit, time.Hour)
+
+			var ok bool
+			var retry time.Duration
+			for i, key := range tt.keys {
+				if i == len(tt.keys)-1 {
+					clock.t = clock.t.Add(tt.advance)
+				}
+				var err error
+				ok, retry, err = l.Allow(ctx, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if ok != tt.wantOK || retry != tt.wantRetry {
+				t.Errorf("Allow = (%v, %s), want (%v, %s)", ok, retry, tt.wantOK, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrScript increments a key and sets its expiry only when the key is new,
+// atomically, so a crash between the two steps cannot leave a counter that
+// never expires. EXPIRE NX would do the same but needs Redis 7.
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisStore is a Store shared by every instance using the same Redis
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to a redis:// or rediss:// URL. Keys are prefixed
+// with prefix so several applications can share one Redis.
+func NewRedisStore(redisURL, prefix string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %v", err)
+	}
+	return &RedisStore{client: redis.NewClient(opts), prefix: prefix}, nil
+}
+
+// Close releases the connection pool
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// Incr implements Store
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.client, []string{s.prefix + key}, ttl.Milliseconds()).Int64()
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	n, err := s.client.Get(ctx, s.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// TTL implements Store
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, s.prefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// -2 means no key, -1 no expiry; neither happens for keys written here
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
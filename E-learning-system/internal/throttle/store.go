@@ -0,0 +1,28 @@
+// Package throttle counts events per key in expiring windows, backed by
+// Redis so that limits hold across instances, or by memory for tests and
+// single-instance setups.
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps expiring integer counters
+type Store interface {
+	// Incr adds one to key and returns the new value. A new key expires
+	// after ttl; incrementing does not extend an existing key.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Get returns the value of key, or 0 if it does not exist
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value int64, ttl time.Duration) error
+
+	// TTL returns how long key lives on, or 0 if it does not exist
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Delete removes the keys
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The last six digits of the RFC 6238 test vectors
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(s int64) string {
+		c, err := Code(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, code(step), step, true},
+		{"previous step", rfcSecret, code(step - 1), step - 1, true},
+		{"next step", rfcSecret, code(step + 1), step + 1, true},
+		{"two steps back", rfcSecret, code(step - 2), 0, false},
+		{"two steps ahead", rfcSecret, code(step + 2), 0, false},
+		{"surrounding spaces", rfcSecret, " " + code(step) + "\n", step, true},
+		{"lower case secret", strings.ToLower(rfcSecret), code(step), step, true},
+		{"too short", rfcSecret, code(step)[:5], 0, false},
+		{"too long", rfcSecret, code(step) + "0", 0, false},
+		{"empty", rfcSecret, "", 0, false},
+		{"invalid secret", "not base32!", code(step), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 0); err != nil {
+		t.Errorf("generated secret %q does not decode: %v", secret, err)
+	}
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == other {
+		t.Error("two generated secrets are equal")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("E-Learning", "ada@example.com", rfcSecret)
+	want := "otpauth://totp/E-Learning:ada@example.com?algorithm=SHA1&digits=6&issuer=E-Learning&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Errorf("ProvisioningURI = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProcedureKey is the span attribute holding the called procedure or function
+const ProcedureKey = attribute.Key("db.procedure")
+
+// procedurePattern finds the procedure of "CALL proc(...)" and the function of
+// "SELECT ... FROM fn(...)" or "SELECT fn(...)"
+var procedurePattern = regexp.MustCompile(`(?is)^\s*(?:CALL\s+(\w+)\s*\(|SELECT\s+(?:\*\s+FROM\s+)?(\w+)\s*\()`)
+
+// DB wraps *sql.DB so that every query is recorded as a span named after the
+// stored procedure it calls. Only the context-aware methods are traced.
+type DB struct {
+	*sql.DB
+}
+
+// WrapDB returns a tracing wrapper around db
+func WrapDB(db *sql.DB) *DB {
+	return &DB{DB: db}
+}
+
+// ExecContext implements the traced variant of sql.DB.ExecContext
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	RecordError(span, err)
+	return result, err
+}
+
+// QueryContext implements the traced variant of sql.DB.QueryContext
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	RecordError(span, err)
+	return rows, err
+}
+
+// QueryRowContext implements the traced variant of sql.DB.QueryRowContext
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); !errors.Is(err, sql.ErrNoRows) {
+		RecordError(span, err)
+	}
+	return row
+}
+
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	procedure := Procedure(query)
+	name := "db.query"
+	if procedure != "" {
+		name = "db " + procedure
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemPostgreSQL,
+		semconv.DBQueryText(strings.TrimSpace(query)),
+	}
+	if procedure != "" {
+		attrs = append(attrs, ProcedureKey.String(procedure))
+	}
+	if op := operation(query); op != "" {
+		attrs = append(attrs, semconv.DBOperationName(op))
+	}
+
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// Procedure returns the stored procedure or function a query calls, if any
+func Procedure(query string) string {
+	m := procedurePattern.FindStringSubmatch(query)
+	if m == nil {
+		return ""
+	}
+	if m[1] != "" {
+		return m[1]
+	}
+	return m[2]
+}
+
+func operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
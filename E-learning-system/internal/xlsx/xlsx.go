@@ -0,0 +1,143 @@
+// Package xlsx writes simple single-sheet Excel workbooks. Every cell is
+// written as text, which is enough for exports meant to be opened in a
+// spreadsheet application, and needs no third-party library.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the workbooks written by Write
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+// Write writes a workbook with one sheet holding rows, the first of which
+// is usually a header
+func Write(w io.Writer, sheetName string, rows [][]string) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"[Content_Types].xml", writeString(contentTypes)},
+		{"_rels/.rels", writeString(rootRels)},
+		{"xl/workbook.xml", func(w io.Writer) error { return writeWorkbook(w, sheetName) }},
+		{"xl/_rels/workbook.xml.rels", writeString(workbookRels)},
+		{"xl/worksheets/sheet1.xml", func(w io.Writer) error { return writeSheet(w, rows) }},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := f.write(fw); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func writeWorkbook(w io.Writer, sheetName string) error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	b.WriteString(`<sheet name="`)
+	escape(&b, sheetTitle(sheetName))
+	b.WriteString(`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSheet(w io.Writer, rows [][]string) error {
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		b.Reset()
+		r := strconv.Itoa(i + 1)
+		b.WriteString(`<row r="` + r + `">`)
+		for j, value := range row {
+			b.WriteString(`<c r="` + column(j) + r + `" t="inlineStr"><is><t xml:space="preserve">`)
+			escape(&b, value)
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString(`</row>`)
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, `</sheetData></worksheet>`)
+	return err
+}
+
+// column returns the letters of the zero-based column i: A, B, ..., Z, AA, ...
+func column(i int) string {
+	var letters []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		letters = append([]byte{byte('A' + (i-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// escape writes s as XML text, dropping characters XML cannot hold
+func escape(b *strings.Builder, s string) {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, s)
+	_ = xml.EscapeText(b, []byte(s))
+}
+
+// sheetTitle makes name a valid sheet name: at most 31 characters and none
+// of []:*?/\
+func sheetTitle(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
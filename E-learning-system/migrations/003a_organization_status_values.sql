@@ -0,0 +1,20 @@
+-- =====================================================
+-- ORGANIZATION STATUS VALUES
+-- Values added to an enum cannot be used in the transaction that adds
+-- them, so they are committed here before 004 builds on them.
+-- =====================================================
+
+-- Make sure every lifecycle state exists on the enum
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'organization_status') THEN
+        CREATE TYPE organization_status AS ENUM ('pending', 'active', 'suspended', 'pending_deletion', 'deleted');
+    END IF;
+END;
+$$;
+
+ALTER TYPE organization_status ADD VALUE IF NOT EXISTS 'pending';
+ALTER TYPE organization_status ADD VALUE IF NOT EXISTS 'active';
+ALTER TYPE organization_status ADD VALUE IF NOT EXISTS 'suspended';
+ALTER TYPE organization_status ADD VALUE IF NOT EXISTS 'pending_deletion';
+ALTER TYPE organization_status ADD VALUE IF NOT EXISTS 'deleted';
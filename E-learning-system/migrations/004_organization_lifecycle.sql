@@ -0,0 +1,230 @@
+-- =====================================================
+-- ORGANIZATION LIFECYCLE
+-- pending -> active -> suspended -> pending_deletion -> deleted
+-- =====================================================
+
+-- The status values themselves are added by 003a
+
+-- Who changed the status, why and when the hard delete is due
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS status_changed_by UUID;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_organizations_deletion_scheduled_at
+    ON organizations (deletion_scheduled_at)
+    WHERE status = 'pending_deletion';
+
+-- History of every transition. No foreign key so the trail survives the hard delete.
+CREATE TABLE IF NOT EXISTS organization_status_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL,
+    from_status organization_status,
+    to_status organization_status NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor_id UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_status_events_org
+    ON organization_status_events (organization_id, created_at);
+
+-- Get Organization by ID (now with lifecycle columns)
+DROP FUNCTION IF EXISTS get_organization_by_id(UUID);
+CREATE OR REPLACE FUNCTION get_organization_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    name VARCHAR,
+    description TEXT,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    domain VARCHAR,
+    status organization_status,
+    plan VARCHAR,
+    status_reason TEXT,
+    status_changed_by UUID,
+    status_changed_at TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT o.id, o.name, o.description, o.logo_url, o.primary_color, o.secondary_color,
+           o.domain, o.status, o.plan::VARCHAR, o.status_reason, o.status_changed_by,
+           o.status_changed_at, o.deletion_scheduled_at, o.created_at, o.updated_at
+    FROM organizations o
+    WHERE o.id = p_id;
+END;
+$$;
+
+-- Get All Organizations (now with lifecycle columns)
+DROP FUNCTION IF EXISTS get_all_organizations();
+CREATE OR REPLACE FUNCTION get_all_organizations()
+RETURNS TABLE (
+    id UUID,
+    name VARCHAR,
+    description TEXT,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    domain VARCHAR,
+    status organization_status,
+    plan VARCHAR,
+    status_reason TEXT,
+    status_changed_by UUID,
+    status_changed_at TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT o.id, o.name, o.description, o.logo_url, o.primary_color, o.secondary_color,
+           o.domain, o.status, o.plan::VARCHAR, o.status_reason, o.status_changed_by,
+           o.status_changed_at, o.deletion_scheduled_at, o.created_at, o.updated_at
+    FROM organizations o
+    WHERE o.status <> 'deleted'
+    ORDER BY o.created_at DESC;
+END;
+$$;
+
+-- Change status and record the transition atomically.
+-- Fails when the organization is no longer in p_from_status (concurrent change).
+CREATE OR REPLACE PROCEDURE change_organization_status(
+    IN p_id UUID,
+    IN p_from_status organization_status,
+    IN p_to_status organization_status,
+    IN p_reason TEXT,
+    IN p_actor_id UUID,
+    IN p_deletion_scheduled_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE organizations
+    SET status = p_to_status,
+        status_reason = COALESCE(p_reason, ''),
+        status_changed_by = p_actor_id,
+        status_changed_at = CURRENT_TIMESTAMP,
+        deletion_scheduled_at = p_deletion_scheduled_at,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND status = p_from_status;
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    IF row_count = 0 THEN
+        RAISE EXCEPTION 'organization % is not in status %', p_id, p_from_status;
+    END IF;
+
+    INSERT INTO organization_status_events (organization_id, from_status, to_status, reason, actor_id)
+    VALUES (p_id, p_from_status, p_to_status, COALESCE(p_reason, ''), p_actor_id);
+END;
+$$;
+
+-- Status history for an organization, oldest first
+CREATE OR REPLACE FUNCTION get_organization_status_events(p_org_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    from_status organization_status,
+    to_status organization_status,
+    reason TEXT,
+    actor_id UUID,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT e.id, e.organization_id, e.from_status, e.to_status, e.reason, e.actor_id, e.created_at
+    FROM organization_status_events e
+    WHERE e.organization_id = p_org_id
+    ORDER BY e.created_at ASC;
+END;
+$$;
+
+-- Organizations whose scheduled hard delete is due
+CREATE OR REPLACE FUNCTION get_organizations_due_for_deletion(p_before TIMESTAMP)
+RETURNS TABLE (id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT o.id
+    FROM organizations o
+    WHERE o.status = 'pending_deletion'
+      AND o.deletion_scheduled_at IS NOT NULL
+      AND o.deletion_scheduled_at <= p_before;
+END;
+$$;
+
+-- Everything we hold about an organization, as one JSON document
+CREATE OR REPLACE FUNCTION export_organization(p_org_id UUID)
+RETURNS JSONB
+LANGUAGE plpgsql AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    SELECT jsonb_build_object(
+        'organization', to_jsonb(o),
+        'admins', COALESCE((SELECT jsonb_agg(to_jsonb(a)) FROM organization_admins a WHERE a.organization_id = o.id), '[]'::jsonb),
+        'tutors', COALESCE((SELECT jsonb_agg(to_jsonb(t)) FROM organization_tutors t WHERE t.organization_id = o.id), '[]'::jsonb),
+        'branding', COALESCE((SELECT jsonb_agg(to_jsonb(b)) FROM organization_brandings b WHERE b.organization_id = o.id), '[]'::jsonb),
+        'billing', COALESCE((SELECT jsonb_agg(to_jsonb(bl)) FROM organization_billings bl WHERE bl.organization_id = o.id), '[]'::jsonb),
+        'status_events', COALESCE((SELECT jsonb_agg(to_jsonb(e) ORDER BY e.created_at) FROM organization_status_events e WHERE e.organization_id = o.id), '[]'::jsonb),
+        'exported_at', CURRENT_TIMESTAMP
+    )
+    INTO result
+    FROM organizations o
+    WHERE o.id = p_org_id;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'organization % not found', p_org_id;
+    END IF;
+
+    RETURN result;
+END;
+$$;
+
+-- Remove an organization and all its dependent rows, keeping only the status trail
+CREATE OR REPLACE PROCEDURE hard_delete_organization(IN p_id UUID, IN p_reason TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    current_status organization_status;
+BEGIN
+    SELECT status INTO current_status FROM organizations WHERE id = p_id FOR UPDATE;
+    IF current_status IS NULL THEN
+        RAISE EXCEPTION 'organization % not found', p_id;
+    END IF;
+    IF current_status <> 'pending_deletion' THEN
+        RAISE EXCEPTION 'organization % is not pending deletion', p_id;
+    END IF;
+
+    DELETE FROM organization_admins WHERE organization_id = p_id;
+    DELETE FROM organization_tutors WHERE organization_id = p_id;
+    DELETE FROM organization_brandings WHERE organization_id = p_id;
+    DELETE FROM organization_billings WHERE organization_id = p_id;
+    DELETE FROM organizations WHERE id = p_id;
+
+    INSERT INTO organization_status_events (organization_id, from_status, to_status, reason, actor_id)
+    VALUES (p_id, current_status, 'deleted', COALESCE(p_reason, ''), NULL);
+END;
+$$;
+
+-- Statuses of every organization a user belongs to (as admin or tutor)
+CREATE OR REPLACE FUNCTION get_member_organization_statuses(p_user_id UUID)
+RETURNS TABLE (organization_id UUID, status organization_status)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT DISTINCT o.id, o.status
+    FROM organizations o
+    WHERE o.id IN (
+        SELECT a.organization_id FROM organization_admins a WHERE a.user_id = p_user_id AND a.deleted_at IS NULL
+        UNION
+        SELECT t.organization_id FROM organization_tutors t WHERE t.user_id = p_user_id AND t.deleted_at IS NULL
+    );
+END;
+$$;
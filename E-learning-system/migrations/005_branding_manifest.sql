@@ -0,0 +1,38 @@
+-- =====================================================
+-- PUBLIC BRANDING MANIFEST
+-- =====================================================
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_domain_lower
+    ON organizations (LOWER(domain));
+
+-- Branding for an organization resolved by its domain.
+-- Falls back to the colors stored on the organization when no branding row exists.
+CREATE OR REPLACE FUNCTION get_branding_manifest_by_domain(p_domain VARCHAR)
+RETURNS TABLE (
+    organization_id UUID,
+    name VARCHAR,
+    domain VARCHAR,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    theme VARCHAR,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT o.id,
+           o.name,
+           o.domain,
+           COALESCE(NULLIF(b.logo_url, ''), o.logo_url, '')::VARCHAR,
+           COALESCE(NULLIF(b.primary_color, ''), o.primary_color, '')::VARCHAR,
+           COALESCE(NULLIF(b.secondary_color, ''), o.secondary_color, '')::VARCHAR,
+           COALESCE(NULLIF(b.theme, ''), 'light')::VARCHAR,
+           GREATEST(o.updated_at, COALESCE(b.updated_at, o.updated_at))
+    FROM organizations o
+    LEFT JOIN organization_brandings b
+        ON b.organization_id = o.id AND b.deleted_at IS NULL
+    WHERE LOWER(o.domain) = LOWER(p_domain)
+      AND o.status NOT IN ('pending_deletion', 'deleted');
+END;
+$$;
@@ -0,0 +1,73 @@
+-- =====================================================
+-- UPLOADED ASSETS (logos, course materials)
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS assets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID,
+    owner_id UUID NOT NULL REFERENCES users(id),
+    purpose VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    variants JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assets_organization_id ON assets (organization_id);
+
+-- Create Asset
+CREATE OR REPLACE PROCEDURE create_asset(
+    IN p_id UUID,
+    IN p_organization_id UUID,
+    IN p_owner_id UUID,
+    IN p_purpose VARCHAR,
+    IN p_file_name VARCHAR,
+    IN p_content_type VARCHAR,
+    IN p_size BIGINT,
+    IN p_storage_key TEXT,
+    IN p_variants JSONB
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO assets (id, organization_id, owner_id, purpose, file_name, content_type, size, storage_key, variants)
+    VALUES (p_id, p_organization_id, p_owner_id, p_purpose, p_file_name, p_content_type, p_size, p_storage_key, p_variants);
+END;
+$$;
+
+-- Get Asset by ID
+CREATE OR REPLACE FUNCTION get_asset_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    owner_id UUID,
+    purpose VARCHAR,
+    file_name VARCHAR,
+    content_type VARCHAR,
+    size BIGINT,
+    storage_key TEXT,
+    variants JSONB,
+    created_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT a.id, a.organization_id, a.owner_id, a.purpose, a.file_name, a.content_type,
+           a.size, a.storage_key, a.variants, a.created_at, a.deleted_at
+    FROM assets a
+    WHERE a.id = p_id AND a.deleted_at IS NULL;
+END;
+$$;
+
+-- Soft Delete Asset
+CREATE OR REPLACE PROCEDURE delete_asset(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE assets
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id;
+END;
+$$;
@@ -0,0 +1,349 @@
+-- =====================================================
+-- COURSES, LESSONS, ENROLLMENTS AND LESSON VIDEOS
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS courses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS lessons (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    video_status VARCHAR(20) NOT NULL DEFAULT 'none',
+    video_error TEXT NOT NULL DEFAULT '',
+    video_source_key TEXT NOT NULL DEFAULT '',
+    video_playback_type VARCHAR(20) NOT NULL DEFAULT '',
+    video_playlist_key TEXT NOT NULL DEFAULT '',
+    video_thumbnail_key TEXT NOT NULL DEFAULT '',
+    video_duration DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lessons_course_id ON lessons (course_id, position);
+CREATE INDEX IF NOT EXISTS idx_lessons_video_status ON lessons (video_status);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (course_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS video_uploads (
+    id UUID PRIMARY KEY,
+    lesson_id UUID NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES users(id),
+    file_name VARCHAR(255) NOT NULL DEFAULT '',
+    length BIGINT NOT NULL,
+    "offset" BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ---------- Courses ----------
+
+CREATE OR REPLACE PROCEDURE create_course(
+    IN p_id UUID,
+    IN p_organization_id UUID,
+    IN p_title VARCHAR,
+    IN p_description TEXT,
+    IN p_created_by UUID
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO courses (id, organization_id, title, description, created_by)
+    VALUES (p_id, p_organization_id, p_title, COALESCE(p_description, ''), p_created_by);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_course_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    title VARCHAR,
+    description TEXT,
+    created_by UUID,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT c.id, c.organization_id, c.title, c.description, c.created_by, c.created_at, c.updated_at
+    FROM courses c
+    WHERE c.id = p_id AND c.deleted_at IS NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_all_courses()
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    title VARCHAR,
+    description TEXT,
+    created_by UUID,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT c.id, c.organization_id, c.title, c.description, c.created_by, c.created_at, c.updated_at
+    FROM courses c
+    WHERE c.deleted_at IS NULL
+    ORDER BY c.created_at DESC;
+END;
+$$;
+
+-- ---------- Lessons ----------
+
+CREATE OR REPLACE PROCEDURE create_lesson(
+    IN p_id UUID,
+    IN p_course_id UUID,
+    IN p_title VARCHAR,
+    IN p_position INT
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO lessons (id, course_id, title, position)
+    VALUES (p_id, p_course_id, p_title, p_position);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_lesson_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    title VARCHAR,
+    position INT,
+    video_status VARCHAR,
+    video_error TEXT,
+    video_source_key TEXT,
+    video_playback_type VARCHAR,
+    video_playlist_key TEXT,
+    video_thumbnail_key TEXT,
+    video_duration DOUBLE PRECISION,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT l.id, l.course_id, l.title, l.position, l.video_status, l.video_error, l.video_source_key,
+           l.video_playback_type, l.video_playlist_key, l.video_thumbnail_key, l.video_duration,
+           l.created_at, l.updated_at
+    FROM lessons l
+    WHERE l.id = p_id AND l.deleted_at IS NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_lessons_by_course(p_course_id UUID)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    title VARCHAR,
+    position INT,
+    video_status VARCHAR,
+    video_error TEXT,
+    video_source_key TEXT,
+    video_playback_type VARCHAR,
+    video_playlist_key TEXT,
+    video_thumbnail_key TEXT,
+    video_duration DOUBLE PRECISION,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT l.id, l.course_id, l.title, l.position, l.video_status, l.video_error, l.video_source_key,
+           l.video_playback_type, l.video_playlist_key, l.video_thumbnail_key, l.video_duration,
+           l.created_at, l.updated_at
+    FROM lessons l
+    WHERE l.course_id = p_course_id AND l.deleted_at IS NULL
+    ORDER BY l.position, l.created_at;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_lessons_by_video_status(p_status VARCHAR)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    title VARCHAR,
+    position INT,
+    video_status VARCHAR,
+    video_error TEXT,
+    video_source_key TEXT,
+    video_playback_type VARCHAR,
+    video_playlist_key TEXT,
+    video_thumbnail_key TEXT,
+    video_duration DOUBLE PRECISION,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT l.id, l.course_id, l.title, l.position, l.video_status, l.video_error, l.video_source_key,
+           l.video_playback_type, l.video_playlist_key, l.video_thumbnail_key, l.video_duration,
+           l.created_at, l.updated_at
+    FROM lessons l
+    WHERE l.video_status = p_status AND l.deleted_at IS NULL
+    ORDER BY l.updated_at;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE update_lesson_video(
+    IN p_id UUID,
+    IN p_video_status VARCHAR,
+    IN p_video_error TEXT,
+    IN p_video_source_key TEXT,
+    IN p_video_playback_type VARCHAR,
+    IN p_video_playlist_key TEXT,
+    IN p_video_thumbnail_key TEXT,
+    IN p_video_duration DOUBLE PRECISION
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE lessons
+    SET video_status = p_video_status,
+        video_error = COALESCE(p_video_error, ''),
+        video_source_key = COALESCE(p_video_source_key, ''),
+        video_playback_type = COALESCE(p_video_playback_type, ''),
+        video_playlist_key = COALESCE(p_video_playlist_key, ''),
+        video_thumbnail_key = COALESCE(p_video_thumbnail_key, ''),
+        video_duration = COALESCE(p_video_duration, 0),
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+-- Atomically move an uploaded video to processing; false when another worker got it first
+CREATE OR REPLACE FUNCTION claim_lesson_video_processing(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE lessons
+    SET video_status = 'processing',
+        video_error = '',
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND video_status = 'uploaded';
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    RETURN row_count > 0;
+END;
+$$;
+
+-- ---------- Enrollments ----------
+
+CREATE OR REPLACE PROCEDURE create_enrollment(
+    IN p_id UUID,
+    IN p_course_id UUID,
+    IN p_user_id UUID
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO enrollments (id, course_id, user_id)
+    VALUES (p_id, p_course_id, p_user_id)
+    ON CONFLICT (course_id, user_id) DO NOTHING;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION is_user_enrolled(p_user_id UUID, p_course_id UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM enrollments WHERE user_id = p_user_id AND course_id = p_course_id
+    );
+$$;
+
+CREATE OR REPLACE FUNCTION get_enrollments_by_user(p_user_id UUID)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    user_id UUID,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT e.id, e.course_id, e.user_id, e.created_at
+    FROM enrollments e
+    WHERE e.user_id = p_user_id
+    ORDER BY e.created_at DESC;
+END;
+$$;
+
+-- ---------- Resumable video uploads ----------
+
+CREATE OR REPLACE PROCEDURE create_video_upload(
+    IN p_id UUID,
+    IN p_lesson_id UUID,
+    IN p_owner_id UUID,
+    IN p_file_name VARCHAR,
+    IN p_length BIGINT,
+    IN p_expires_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO video_uploads (id, lesson_id, owner_id, file_name, length, expires_at)
+    VALUES (p_id, p_lesson_id, p_owner_id, p_file_name, p_length, p_expires_at);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_video_upload_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    lesson_id UUID,
+    owner_id UUID,
+    file_name VARCHAR,
+    length BIGINT,
+    upload_offset BIGINT,
+    expires_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT u.id, u.lesson_id, u.owner_id, u.file_name, u.length, u."offset", u.expires_at, u.completed_at, u.created_at
+    FROM video_uploads u
+    WHERE u.id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE update_video_upload_offset(IN p_id UUID, IN p_offset BIGINT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE video_uploads SET "offset" = p_offset WHERE id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE complete_video_upload(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE video_uploads SET completed_at = CURRENT_TIMESTAMP WHERE id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE delete_video_upload(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM video_uploads WHERE id = p_id;
+END;
+$$;
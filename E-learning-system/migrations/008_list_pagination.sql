@@ -0,0 +1,362 @@
+-- =====================================================
+-- LIST PAGINATION
+-- Keyset (cursor) pagination with filters and sorting for every list
+-- endpoint. Each list_* function returns at most p_limit rows after the
+-- cursor (p_after_value, p_after_id) ordered by p_sort, then id; the
+-- matching count_* function returns the total for the same filters.
+-- Unknown sort keys fall back to created_at.
+-- =====================================================
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_last_name ON users (last_name, id);
+CREATE INDEX IF NOT EXISTS idx_organizations_created_at ON organizations (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_organizations_name ON organizations (name, id);
+CREATE INDEX IF NOT EXISTS idx_courses_created_at ON courses (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_organization_tutors_created_at ON organization_tutors (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_organization_admins_created_at ON organization_admins (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_organization_billings_created_at ON organization_billings (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_organization_brandings_created_at ON organization_brandings (created_at, id);
+
+-- ---------- Users ----------
+
+CREATE OR REPLACE FUNCTION count_users(p_role user_role, p_email_prefix TEXT)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM users u
+    WHERE (p_role IS NULL OR u.role = p_role)
+      AND (p_email_prefix IS NULL OR starts_with(lower(u.email), lower(p_email_prefix)));
+$$;
+
+CREATE OR REPLACE FUNCTION list_users(
+    p_role user_role,
+    p_email_prefix TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR,
+    password TEXT,
+    first_name VARCHAR,
+    last_name VARCHAR,
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'email' THEN 'u.email' WHEN 'last_name' THEN 'u.last_name' ELSE 'u.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'email' THEN 'varchar' WHEN 'last_name' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT u.id, u.email::varchar, u.password, u.first_name::varchar, u.last_name::varchar,
+                u.role, u.created_at, u.updated_at
+         FROM users u
+         WHERE ($1 IS NULL OR u.role = $1)
+           AND ($2 IS NULL OR starts_with(lower(u.email), lower($2)))
+           AND ($4 IS NULL OR (%1$s, u.id) %2$s ($3::%3$s, $4))
+         ORDER BY %1$s %4$s, u.id %4$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_role, p_email_prefix, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organizations ----------
+
+-- Deleted organizations are only listed when asked for by status
+CREATE OR REPLACE FUNCTION count_organizations(p_status organization_status, p_plan TEXT)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organizations o
+    WHERE (CASE WHEN p_status IS NULL THEN o.status <> 'deleted' ELSE o.status = p_status END)
+      AND (p_plan IS NULL OR o.plan::text = p_plan);
+$$;
+
+CREATE OR REPLACE FUNCTION list_organizations(
+    p_status organization_status,
+    p_plan TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    name VARCHAR,
+    description TEXT,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    domain VARCHAR,
+    status organization_status,
+    plan VARCHAR,
+    status_reason TEXT,
+    status_changed_by UUID,
+    status_changed_at TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'name' THEN 'o.name' ELSE 'o.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'name' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT o.id, o.name::varchar, o.description, o.logo_url::varchar, o.primary_color::varchar,
+                o.secondary_color::varchar, o.domain::varchar, o.status, o.plan::varchar, o.status_reason,
+                o.status_changed_by, o.status_changed_at, o.deletion_scheduled_at, o.created_at, o.updated_at
+         FROM organizations o
+         WHERE (CASE WHEN $1 IS NULL THEN o.status <> ''deleted'' ELSE o.status = $1 END)
+           AND ($2 IS NULL OR o.plan::text = $2)
+           AND ($4 IS NULL OR (%1$s, o.id) %2$s ($3::%3$s, $4))
+         ORDER BY %1$s %4$s, o.id %4$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_status, p_plan, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Courses ----------
+
+CREATE OR REPLACE FUNCTION count_courses(p_organization_id UUID)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM courses c
+    WHERE c.deleted_at IS NULL
+      AND (p_organization_id IS NULL OR c.organization_id = p_organization_id);
+$$;
+
+CREATE OR REPLACE FUNCTION list_courses(
+    p_organization_id UUID,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    title VARCHAR,
+    description TEXT,
+    created_by UUID,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'title' THEN 'c.title' ELSE 'c.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'title' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT c.id, c.organization_id, c.title::varchar, c.description, c.created_by, c.created_at, c.updated_at
+         FROM courses c
+         WHERE c.deleted_at IS NULL
+           AND ($1 IS NULL OR c.organization_id = $1)
+           AND ($3 IS NULL OR (%1$s, c.id) %2$s ($2::%3$s, $3))
+         ORDER BY %1$s %4$s, c.id %4$s
+         LIMIT $4',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organization tutors ----------
+
+CREATE OR REPLACE FUNCTION count_organization_tutors(p_organization_id UUID, p_approved BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_tutors t
+    WHERE t.deleted_at IS NULL
+      AND (p_organization_id IS NULL OR t.organization_id = p_organization_id)
+      AND (p_approved IS NULL OR t.approved = p_approved);
+$$;
+
+CREATE OR REPLACE FUNCTION list_organization_tutors(
+    p_organization_id UUID,
+    p_approved BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    approved BOOLEAN,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Tutors only sort by created_at
+    RETURN QUERY EXECUTE format(
+        'SELECT t.id, t.user_id, t.organization_id, t.approved, t.created_at
+         FROM organization_tutors t
+         WHERE t.deleted_at IS NULL
+           AND ($1 IS NULL OR t.organization_id = $1)
+           AND ($2 IS NULL OR t.approved = $2)
+           AND ($4 IS NULL OR (t.created_at, t.id) %1$s ($3::timestamp, $4))
+         ORDER BY t.created_at %2$s, t.id %2$s
+         LIMIT $5',
+        CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_approved, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organization admins ----------
+
+CREATE OR REPLACE FUNCTION count_organization_admins(p_organization_id UUID, p_role TEXT)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_admins a
+    WHERE a.deleted_at IS NULL
+      AND (p_organization_id IS NULL OR a.organization_id = p_organization_id)
+      AND (p_role IS NULL OR a.role::text = p_role);
+$$;
+
+CREATE OR REPLACE FUNCTION list_organization_admins(
+    p_organization_id UUID,
+    p_role TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    role VARCHAR,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Admins only sort by created_at
+    RETURN QUERY EXECUTE format(
+        'SELECT a.id, a.user_id, a.organization_id, a.role::varchar, a.created_at
+         FROM organization_admins a
+         WHERE a.deleted_at IS NULL
+           AND ($1 IS NULL OR a.organization_id = $1)
+           AND ($2 IS NULL OR a.role::text = $2)
+           AND ($4 IS NULL OR (a.created_at, a.id) %1$s ($3::timestamp, $4))
+         ORDER BY a.created_at %2$s, a.id %2$s
+         LIMIT $5',
+        CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_role, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organization billings ----------
+
+CREATE OR REPLACE FUNCTION count_organization_billings(p_organization_id UUID, p_plan TEXT)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_billings b
+    WHERE b.deleted_at IS NULL
+      AND (p_organization_id IS NULL OR b.organization_id = p_organization_id)
+      AND (p_plan IS NULL OR b.plan::text = p_plan);
+$$;
+
+CREATE OR REPLACE FUNCTION list_organization_billings(
+    p_organization_id UUID,
+    p_plan TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    plan VARCHAR,
+    payment_method VARCHAR,
+    subscription_id VARCHAR,
+    next_billing_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'next_billing_at' THEN 'b.next_billing_at' ELSE 'b.created_at' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT b.id, b.organization_id, b.plan::varchar, b.payment_method::varchar, b.subscription_id::varchar,
+                b.next_billing_at, b.created_at, b.updated_at
+         FROM organization_billings b
+         WHERE b.deleted_at IS NULL
+           AND ($1 IS NULL OR b.organization_id = $1)
+           AND ($2 IS NULL OR b.plan::text = $2)
+           AND ($4 IS NULL OR (%1$s, b.id) %2$s ($3::timestamp, $4))
+         ORDER BY %1$s %3$s, b.id %3$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_plan, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organization brandings ----------
+
+CREATE OR REPLACE FUNCTION count_organization_brandings(p_organization_id UUID, p_theme TEXT)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_brandings b
+    WHERE b.deleted_at IS NULL
+      AND (p_organization_id IS NULL OR b.organization_id = p_organization_id)
+      AND (p_theme IS NULL OR b.theme = p_theme);
+$$;
+
+CREATE OR REPLACE FUNCTION list_organization_brandings(
+    p_organization_id UUID,
+    p_theme TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    theme VARCHAR,
+    email_template TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'updated_at' THEN 'b.updated_at' ELSE 'b.created_at' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT b.id, b.organization_id, b.logo_url::varchar, b.primary_color::varchar, b.secondary_color::varchar,
+                b.theme::varchar, b.email_template, b.created_at, b.updated_at
+         FROM organization_brandings b
+         WHERE b.deleted_at IS NULL
+           AND ($1 IS NULL OR b.organization_id = $1)
+           AND ($2 IS NULL OR b.theme = $2)
+           AND ($4 IS NULL OR (%1$s, b.id) %2$s ($3::timestamp, $4))
+         ORDER BY %1$s %3$s, b.id %3$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_theme, p_after_value, p_after_id, p_limit;
+END;
+$$;
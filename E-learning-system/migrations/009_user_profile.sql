@@ -0,0 +1,162 @@
+-- =====================================================
+-- USER PROFILE
+-- Self-service profile fields, email changes confirmed through the new
+-- address, and session revocation after a password change.
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT '';
+
+-- The result columns change, so the function has to be dropped first
+DROP FUNCTION IF EXISTS get_user_by_id(UUID);
+
+CREATE OR REPLACE FUNCTION get_user_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR(255),
+    password TEXT,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    avatar_url TEXT,
+    bio TEXT,
+    locale VARCHAR(35),
+    timezone VARCHAR(64)
+)
+LANGUAGE SQL AS $$
+    SELECT u.id, u.email, u.password, u.first_name, u.last_name, u.role,
+           u.created_at, u.updated_at, u.avatar_url, u.bio, u.locale, u.timezone
+    FROM users u
+    WHERE u.id = p_id;
+$$;
+
+-- Updates the fields a user may change on their own profile
+CREATE OR REPLACE PROCEDURE update_user_profile(
+    IN p_id UUID,
+    IN p_first_name VARCHAR,
+    IN p_last_name VARCHAR,
+    IN p_avatar_url TEXT,
+    IN p_bio TEXT,
+    IN p_locale VARCHAR,
+    IN p_timezone VARCHAR,
+    INOUT p_updated_at TIMESTAMP
+)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE users SET
+        first_name = p_first_name,
+        last_name = p_last_name,
+        avatar_url = p_avatar_url,
+        bio = p_bio,
+        locale = p_locale,
+        timezone = p_timezone,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id
+    RETURNING updated_at INTO p_updated_at;
+END;
+$$;
+
+-- ---------- Email changes ----------
+
+-- Pending email changes. Only the SHA-256 of the emailed token is stored.
+CREATE TABLE IF NOT EXISTS email_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_changes_user_id ON email_changes (user_id);
+
+-- Starts an email change. Earlier pending requests of the user are
+-- dropped, so only the latest link works.
+CREATE OR REPLACE FUNCTION create_email_change(
+    p_user_id UUID,
+    p_new_email VARCHAR,
+    p_token_hash TEXT,
+    p_ttl_seconds INT
+)
+RETURNS UUID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    new_id UUID;
+BEGIN
+    DELETE FROM email_changes
+    WHERE user_id = p_user_id AND confirmed_at IS NULL;
+
+    INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+    VALUES (p_user_id, p_new_email, p_token_hash,
+            CURRENT_TIMESTAMP + make_interval(secs => p_ttl_seconds))
+    RETURNING id INTO new_id;
+
+    RETURN new_id;
+END;
+$$;
+
+-- Applies the email change matching the token and consumes the token.
+-- Returns the user ID, or NULL when the token is unknown, used or expired.
+-- Raises a unique violation if the address was taken in the meantime.
+CREATE OR REPLACE FUNCTION confirm_email_change(p_token_hash TEXT)
+RETURNS UUID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    pending email_changes%ROWTYPE;
+BEGIN
+    SELECT * INTO pending
+    FROM email_changes
+    WHERE token_hash = p_token_hash
+      AND confirmed_at IS NULL
+      AND expires_at > CURRENT_TIMESTAMP
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE users
+    SET email = pending.new_email,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = pending.user_id;
+
+    UPDATE email_changes
+    SET confirmed_at = CURRENT_TIMESTAMP
+    WHERE id = pending.id;
+
+    RETURN pending.user_id;
+END;
+$$;
+
+-- ---------- Sessions ----------
+
+CREATE INDEX IF NOT EXISTS idx_tokens_user_id ON tokens (user_id) WHERE deleted_at IS NULL;
+
+-- Signs the user out everywhere except the session p_keep_id (may be NULL).
+-- Returns the number of revoked sessions.
+CREATE OR REPLACE FUNCTION revoke_user_tokens(p_user_id UUID, p_keep_id UUID)
+RETURNS INTEGER
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    revoked INTEGER;
+BEGIN
+    UPDATE tokens
+    SET deleted_at = NOW(),
+        updated_at = NOW()
+    WHERE user_id = p_user_id
+      AND deleted_at IS NULL
+      AND id IS DISTINCT FROM p_keep_id;
+
+    GET DIAGNOSTICS revoked = ROW_COUNT;
+    RETURN revoked;
+END;
+$$;
@@ -0,0 +1,211 @@
+-- =====================================================
+-- EMAIL VERIFICATION
+-- Users prove they own their address by following a signed link. The link
+-- itself is stateless (HMAC over user ID and email); only the verified flag
+-- and the time of the last link are stored, the latter to throttle resends.
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP;
+
+-- Accounts created before verification existed keep working
+UPDATE users
+SET email_verified = TRUE,
+    email_verified_at = CURRENT_TIMESTAMP
+WHERE NOT email_verified AND verification_sent_at IS NULL AND email_verified_at IS NULL;
+
+-- The result columns change, so the functions have to be dropped first
+DROP FUNCTION IF EXISTS get_user_by_email(VARCHAR);
+DROP FUNCTION IF EXISTS get_user_by_id(UUID);
+DROP FUNCTION IF EXISTS list_users(user_role, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+
+CREATE OR REPLACE FUNCTION get_user_by_email(p_email VARCHAR)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR(255),
+    password TEXT,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    email_verified BOOLEAN
+)
+LANGUAGE SQL AS $$
+    SELECT u.id, u.email, u.password, u.first_name, u.last_name, u.role,
+           u.created_at, u.updated_at, u.email_verified
+    FROM users u
+    WHERE u.email = p_email;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR(255),
+    password TEXT,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    avatar_url TEXT,
+    bio TEXT,
+    locale VARCHAR(35),
+    timezone VARCHAR(64),
+    email_verified BOOLEAN
+)
+LANGUAGE SQL AS $$
+    SELECT u.id, u.email, u.password, u.first_name, u.last_name, u.role,
+           u.created_at, u.updated_at, u.avatar_url, u.bio, u.locale, u.timezone,
+           u.email_verified
+    FROM users u
+    WHERE u.id = p_id;
+$$;
+
+CREATE OR REPLACE FUNCTION list_users(
+    p_role user_role,
+    p_email_prefix TEXT,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR,
+    password TEXT,
+    first_name VARCHAR,
+    last_name VARCHAR,
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    email_verified BOOLEAN
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'email' THEN 'u.email' WHEN 'last_name' THEN 'u.last_name' ELSE 'u.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'email' THEN 'varchar' WHEN 'last_name' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT u.id, u.email::varchar, u.password, u.first_name::varchar, u.last_name::varchar,
+                u.role, u.created_at, u.updated_at, u.email_verified
+         FROM users u
+         WHERE ($1 IS NULL OR u.role = $1)
+           AND ($2 IS NULL OR starts_with(lower(u.email), lower($2)))
+           AND ($4 IS NULL OR (%1$s, u.id) %2$s ($3::%3$s, $4))
+         ORDER BY %1$s %4$s, u.id %4$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_role, p_email_prefix, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- Records that a verification link is about to be sent. Returns FALSE,
+-- and records nothing, when the previous link is younger than p_min_interval_seconds.
+CREATE OR REPLACE FUNCTION mark_verification_sent(p_user_id UUID, p_min_interval_seconds INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE users
+    SET verification_sent_at = CURRENT_TIMESTAMP
+    WHERE id = p_user_id
+      AND NOT email_verified
+      AND (verification_sent_at IS NULL
+           OR verification_sent_at <= CURRENT_TIMESTAMP - make_interval(secs => p_min_interval_seconds));
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    RETURN row_count > 0;
+END;
+$$;
+
+-- Marks the address as verified if it is still the user's address, so a
+-- link for an old address cannot verify a new one. Returns FALSE otherwise.
+CREATE OR REPLACE FUNCTION verify_user_email(p_user_id UUID, p_email VARCHAR)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE users
+    SET email_verified = TRUE,
+        email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP),
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_user_id AND email = p_email;
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    RETURN row_count > 0;
+END;
+$$;
+
+-- A confirmed email change proves ownership of the new address
+CREATE OR REPLACE FUNCTION confirm_email_change(p_token_hash TEXT)
+RETURNS UUID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    pending email_changes%ROWTYPE;
+BEGIN
+    SELECT * INTO pending
+    FROM email_changes
+    WHERE token_hash = p_token_hash
+      AND confirmed_at IS NULL
+      AND expires_at > CURRENT_TIMESTAMP
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE users
+    SET email = pending.new_email,
+        email_verified = TRUE,
+        email_verified_at = CURRENT_TIMESTAMP,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = pending.user_id;
+
+    UPDATE email_changes
+    SET confirmed_at = CURRENT_TIMESTAMP
+    WHERE id = pending.id;
+
+    RETURN pending.user_id;
+END;
+$$;
+
+-- ---------- Organization policy ----------
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS require_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE FUNCTION get_organization_policy(p_org_id UUID)
+RETURNS TABLE (
+    organization_id UUID,
+    require_verified_email BOOLEAN,
+    updated_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT o.id, o.require_verified_email, o.updated_at
+    FROM organizations o
+    WHERE o.id = p_org_id;
+$$;
+
+CREATE OR REPLACE FUNCTION update_organization_policy(p_org_id UUID, p_require_verified_email BOOLEAN)
+RETURNS TIMESTAMP
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    new_updated_at TIMESTAMP;
+BEGIN
+    UPDATE organizations
+    SET require_verified_email = p_require_verified_email,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_org_id
+    RETURNING updated_at INTO new_updated_at;
+
+    RETURN new_updated_at;
+END;
+$$;
@@ -0,0 +1,35 @@
+-- =====================================================
+-- LOGIN EVENTS
+-- One row per sign-in attempt, successful or not, for security review.
+-- Failure counters and lockouts live in Redis; this table is the record.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL when the email is unknown
+    email VARCHAR(255) NOT NULL,
+    outcome VARCHAR(20) NOT NULL,  -- success, failure, locked, blocked
+    reason VARCHAR(50) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id ON login_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_ip_address ON login_events (ip_address, created_at);
+
+CREATE OR REPLACE PROCEDURE record_login_event(
+    IN p_user_id UUID,
+    IN p_email VARCHAR,
+    IN p_outcome VARCHAR,
+    IN p_reason VARCHAR,
+    IN p_ip_address VARCHAR,
+    IN p_user_agent TEXT
+)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO login_events (user_id, email, outcome, reason, ip_address, user_agent)
+    VALUES (p_user_id, p_email, p_outcome, COALESCE(p_reason, ''), COALESCE(p_ip_address, ''), COALESCE(p_user_agent, ''));
+END;
+$$;
@@ -0,0 +1,242 @@
+-- =====================================================
+-- TWO-FACTOR AUTHENTICATION (TOTP)
+-- A user enrolls an authenticator app; the shared secret is stored
+-- encrypted by the application. Recovery codes are single use and only
+-- their SHA-256 is stored. MFA can be required for every member of an
+-- organization, or for every user with a given role.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,                          -- encrypted TOTP secret
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,        -- false until the first code is confirmed
+    last_used_step BIGINT NOT NULL DEFAULT 0,      -- time step of the last accepted code, to refuse replays
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    enabled_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Roles whose users must use MFA, managed by platform admins
+CREATE TABLE IF NOT EXISTS mfa_required_roles (
+    role VARCHAR(50) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Platform admins manage payments and every organization
+INSERT INTO mfa_required_roles (role) VALUES ('admin') ON CONFLICT DO NOTHING;
+
+-- ---------- Enrollment ----------
+
+-- Starts or restarts an enrollment with a new secret. Returns FALSE when
+-- MFA is already enabled; it has to be disabled first.
+CREATE OR REPLACE FUNCTION start_mfa_enrollment(p_user_id UUID, p_secret TEXT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO user_mfa (user_id, secret)
+    VALUES (p_user_id, p_secret)
+    ON CONFLICT (user_id) DO UPDATE
+        SET secret = EXCLUDED.secret,
+            last_used_step = 0,
+            created_at = CURRENT_TIMESTAMP
+        WHERE NOT user_mfa.enabled;
+
+    RETURN FOUND;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_mfa(p_user_id UUID)
+RETURNS TABLE (
+    user_id UUID,
+    secret TEXT,
+    enabled BOOLEAN,
+    last_used_step BIGINT,
+    enabled_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT m.user_id, m.secret, m.enabled, m.last_used_step, m.enabled_at
+    FROM user_mfa m
+    WHERE m.user_id = p_user_id;
+$$;
+
+-- Enables a pending enrollment with its first code and issues the recovery
+-- codes. Returns FALSE when there is no pending enrollment.
+CREATE OR REPLACE FUNCTION enable_mfa(p_user_id UUID, p_step BIGINT, p_code_hashes TEXT[])
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE user_mfa
+    SET enabled = TRUE,
+        enabled_at = CURRENT_TIMESTAMP,
+        last_used_step = p_step
+    WHERE user_id = p_user_id AND NOT enabled;
+
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    DELETE FROM mfa_recovery_codes WHERE user_id = p_user_id;
+    INSERT INTO mfa_recovery_codes (user_id, code_hash)
+    SELECT p_user_id, h FROM unnest(p_code_hashes) AS h;
+
+    RETURN TRUE;
+END;
+$$;
+
+-- Removes the enrollment and recovery codes. Returns FALSE when there was none.
+CREATE OR REPLACE FUNCTION disable_mfa(p_user_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM mfa_recovery_codes WHERE user_id = p_user_id;
+    DELETE FROM user_mfa WHERE user_id = p_user_id;
+    RETURN FOUND;
+END;
+$$;
+
+-- ---------- Verification ----------
+
+-- Accepts a code's time step once: a step at or before the last accepted
+-- one is a replay and returns FALSE.
+CREATE OR REPLACE FUNCTION use_mfa_step(p_user_id UUID, p_step BIGINT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE user_mfa
+    SET last_used_step = p_step
+    WHERE user_id = p_user_id AND enabled AND last_used_step < p_step;
+
+    RETURN FOUND;
+END;
+$$;
+
+-- Marks an unused recovery code as used. Returns FALSE when there is none.
+CREATE OR REPLACE FUNCTION use_recovery_code(p_user_id UUID, p_code_hash VARCHAR)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE mfa_recovery_codes
+    SET used_at = CURRENT_TIMESTAMP
+    WHERE user_id = p_user_id AND code_hash = p_code_hash AND used_at IS NULL;
+
+    RETURN FOUND;
+END;
+$$;
+
+-- Replaces all recovery codes of an enabled enrollment. Returns FALSE when
+-- MFA is not enabled.
+CREATE OR REPLACE FUNCTION replace_recovery_codes(p_user_id UUID, p_code_hashes TEXT[])
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM user_mfa WHERE user_id = p_user_id AND enabled) THEN
+        RETURN FALSE;
+    END IF;
+
+    DELETE FROM mfa_recovery_codes WHERE user_id = p_user_id;
+    INSERT INTO mfa_recovery_codes (user_id, code_hash)
+    SELECT p_user_id, h FROM unnest(p_code_hashes) AS h;
+
+    RETURN TRUE;
+END;
+$$;
+
+-- ---------- Enforcement ----------
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS require_mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether the user has MFA enabled and whether it is required, either by
+-- their role or by an organization they belong to
+CREATE OR REPLACE FUNCTION get_mfa_status(p_user_id UUID)
+RETURNS TABLE (
+    enabled BOOLEAN,
+    required BOOLEAN,
+    recovery_codes_remaining INT,
+    enabled_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT
+        COALESCE(m.enabled, FALSE),
+        EXISTS (SELECT 1 FROM mfa_required_roles r WHERE r.role = u.role::TEXT)
+            OR EXISTS (
+                SELECT 1 FROM organizations o
+                WHERE o.require_mfa AND o.id IN (
+                    SELECT a.organization_id FROM organization_admins a WHERE a.user_id = u.id AND a.deleted_at IS NULL
+                    UNION
+                    SELECT t.organization_id FROM organization_tutors t WHERE t.user_id = u.id AND t.deleted_at IS NULL
+                )
+            ),
+        (SELECT COUNT(*)::INT FROM mfa_recovery_codes c WHERE c.user_id = u.id AND c.used_at IS NULL),
+        m.enabled_at
+    FROM users u
+    LEFT JOIN user_mfa m ON m.user_id = u.id AND m.enabled
+    WHERE u.id = p_user_id;
+$$;
+
+CREATE OR REPLACE FUNCTION get_mfa_required_roles()
+RETURNS TABLE (role VARCHAR(50))
+LANGUAGE SQL AS $$
+    SELECT r.role FROM mfa_required_roles r ORDER BY r.role;
+$$;
+
+CREATE OR REPLACE PROCEDURE set_mfa_required_roles(IN p_roles TEXT[])
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM mfa_required_roles WHERE role <> ALL(p_roles);
+    INSERT INTO mfa_required_roles (role)
+    SELECT DISTINCT r FROM unnest(p_roles) AS r
+    ON CONFLICT DO NOTHING;
+END;
+$$;
+
+-- ---------- Organization policy ----------
+
+-- The result columns change, so the functions have to be dropped first
+DROP FUNCTION IF EXISTS get_organization_policy(UUID);
+DROP FUNCTION IF EXISTS update_organization_policy(UUID, BOOLEAN);
+
+CREATE OR REPLACE FUNCTION get_organization_policy(p_org_id UUID)
+RETURNS TABLE (
+    organization_id UUID,
+    require_verified_email BOOLEAN,
+    require_mfa BOOLEAN,
+    updated_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT o.id, o.require_verified_email, o.require_mfa, o.updated_at
+    FROM organizations o
+    WHERE o.id = p_org_id;
+$$;
+
+CREATE OR REPLACE FUNCTION update_organization_policy(p_org_id UUID, p_require_verified_email BOOLEAN, p_require_mfa BOOLEAN)
+RETURNS TIMESTAMP
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    new_updated_at TIMESTAMP;
+BEGIN
+    UPDATE organizations
+    SET require_verified_email = p_require_verified_email,
+        require_mfa = p_require_mfa,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_org_id
+    RETURNING updated_at INTO new_updated_at;
+
+    RETURN new_updated_at;
+END;
+$$;
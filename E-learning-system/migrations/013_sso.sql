@@ -0,0 +1,183 @@
+-- =====================================================
+-- SINGLE SIGN-ON (OpenID Connect)
+-- An organization can register its own identity provider. Users signing in
+-- through it are linked to a local account by the provider's subject;
+-- unknown users are created on first sign-in and become members of the
+-- organization. The link row is the membership of SSO students; SSO
+-- instructors are added as organization tutors as well.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS organization_sso (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    client_secret TEXT NOT NULL DEFAULT '',              -- encrypted; empty for public clients
+    allowed_domains TEXT[] NOT NULL DEFAULT '{}',         -- email domains accepted from the provider
+    default_role user_role NOT NULL DEFAULT 'student',   -- role of users created on first sign-in
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_sso_allowed_domains ON organization_sso USING GIN (allowed_domains);
+
+CREATE TABLE IF NOT EXISTS sso_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email VARCHAR(255) NOT NULL,        -- as asserted at the last sign-in
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, issuer, subject),
+    UNIQUE (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sso_identities_user_id ON sso_identities (user_id);
+
+-- ---------- Configuration ----------
+
+CREATE OR REPLACE FUNCTION get_organization_sso(p_org_id UUID)
+RETURNS TABLE (
+    organization_id UUID,
+    issuer TEXT,
+    client_id TEXT,
+    client_secret TEXT,
+    allowed_domains TEXT[],
+    default_role user_role,
+    enabled BOOLEAN,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT s.organization_id, s.issuer, s.client_id, s.client_secret, s.allowed_domains,
+           s.default_role, s.enabled, s.created_at, s.updated_at
+    FROM organization_sso s
+    WHERE s.organization_id = p_org_id;
+$$;
+
+-- Creates or replaces the configuration. A NULL secret keeps the stored one.
+CREATE OR REPLACE FUNCTION save_organization_sso(
+    p_org_id UUID,
+    p_issuer TEXT,
+    p_client_id TEXT,
+    p_client_secret TEXT,
+    p_allowed_domains TEXT[],
+    p_default_role user_role,
+    p_enabled BOOLEAN
+)
+RETURNS TABLE (created_at TIMESTAMP, updated_at TIMESTAMP)
+LANGUAGE SQL AS $$
+    INSERT INTO organization_sso (organization_id, issuer, client_id, client_secret, allowed_domains, default_role, enabled)
+    VALUES (p_org_id, p_issuer, p_client_id, COALESCE(p_client_secret, ''), p_allowed_domains, p_default_role, p_enabled)
+    ON CONFLICT (organization_id) DO UPDATE
+        SET issuer = EXCLUDED.issuer,
+            client_id = EXCLUDED.client_id,
+            client_secret = COALESCE(p_client_secret, organization_sso.client_secret),
+            allowed_domains = EXCLUDED.allowed_domains,
+            default_role = EXCLUDED.default_role,
+            enabled = EXCLUDED.enabled,
+            updated_at = CURRENT_TIMESTAMP
+    RETURNING organization_sso.created_at, organization_sso.updated_at;
+$$;
+
+-- Removes the configuration; existing links are kept so that re-enabling
+-- SSO finds the same accounts. Returns FALSE when there was none.
+CREATE OR REPLACE FUNCTION delete_organization_sso(p_org_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM organization_sso WHERE organization_id = p_org_id;
+    RETURN FOUND;
+END;
+$$;
+
+-- Organizations with SSO enabled for an email domain, for "sign in with your work email"
+CREATE OR REPLACE FUNCTION find_sso_organizations_by_domain(p_domain TEXT)
+RETURNS TABLE (organization_id UUID, name VARCHAR)
+LANGUAGE SQL AS $$
+    SELECT o.id, o.name
+    FROM organization_sso s
+    JOIN organizations o ON o.id = s.organization_id
+    WHERE s.enabled AND s.allowed_domains @> ARRAY[lower(p_domain)]
+      AND o.status = 'active'
+    ORDER BY o.name;
+$$;
+
+-- ---------- Identities ----------
+
+CREATE OR REPLACE FUNCTION find_sso_identity(p_org_id UUID, p_issuer TEXT, p_subject TEXT)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    user_id UUID,
+    issuer TEXT,
+    subject TEXT,
+    email VARCHAR(255),
+    created_at TIMESTAMP,
+    last_login_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT i.id, i.organization_id, i.user_id, i.issuer, i.subject, i.email, i.created_at, i.last_login_at
+    FROM sso_identities i
+    WHERE i.organization_id = p_org_id AND i.issuer = p_issuer AND i.subject = p_subject;
+$$;
+
+-- Links a provider identity to a user and makes the user a member of the
+-- organization; instructors are added as approved tutors
+CREATE OR REPLACE PROCEDURE link_sso_identity(
+    IN p_org_id UUID,
+    IN p_user_id UUID,
+    IN p_issuer TEXT,
+    IN p_subject TEXT,
+    IN p_email VARCHAR
+)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO sso_identities (organization_id, user_id, issuer, subject, email)
+    VALUES (p_org_id, p_user_id, p_issuer, p_subject, p_email);
+
+    IF EXISTS (SELECT 1 FROM users WHERE id = p_user_id AND role = 'instructor')
+       AND NOT EXISTS (
+           SELECT 1 FROM organization_tutors
+           WHERE organization_id = p_org_id AND user_id = p_user_id AND deleted_at IS NULL
+       ) THEN
+        INSERT INTO organization_tutors (user_id, organization_id, approved)
+        VALUES (p_user_id, p_org_id, TRUE);
+    END IF;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE touch_sso_identity(IN p_id UUID, IN p_email VARCHAR)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE sso_identities
+    SET email = p_email,
+        last_login_at = CURRENT_TIMESTAMP
+    WHERE id = p_id;
+END;
+$$;
+
+-- ---------- Membership ----------
+
+-- SSO members count as members, so suspending an organization blocks them too
+CREATE OR REPLACE FUNCTION get_member_organization_statuses(p_user_id UUID)
+RETURNS TABLE (organization_id UUID, status organization_status)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT DISTINCT o.id, o.status
+    FROM organizations o
+    WHERE o.id IN (
+        SELECT a.organization_id FROM organization_admins a WHERE a.user_id = p_user_id AND a.deleted_at IS NULL
+        UNION
+        SELECT t.organization_id FROM organization_tutors t WHERE t.user_id = p_user_id AND t.deleted_at IS NULL
+        UNION
+        SELECT i.organization_id FROM sso_identities i WHERE i.user_id = p_user_id
+    );
+END;
+$$;
@@ -0,0 +1,186 @@
+-- =====================================================
+-- SINGLE SIGN-ON (SAML 2.0)
+-- An organization can register a SAML identity provider by importing its
+-- metadata. Each organization is its own service provider with a generated
+-- signing key. Identities are linked in sso_identities like OpenID Connect
+-- ones, with the identity provider's entity ID as issuer.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS organization_saml (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    idp_entity_id TEXT NOT NULL,
+    idp_sso_url TEXT NOT NULL,
+    idp_metadata TEXT NOT NULL,                         -- as imported
+    idp_metadata_url TEXT NOT NULL DEFAULT '',          -- where it was imported from, if anywhere
+    sp_certificate TEXT NOT NULL,                       -- PEM, shared with the identity provider
+    sp_private_key TEXT NOT NULL,                       -- encrypted PEM
+    attribute_mapping JSONB NOT NULL DEFAULT '{}',      -- assertion attributes to user fields and roles
+    allowed_domains TEXT[] NOT NULL DEFAULT '{}',
+    default_role user_role NOT NULL DEFAULT 'student',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_saml_allowed_domains ON organization_saml USING GIN (allowed_domains);
+
+-- Validated assertions waiting for the browser that started the sign-in to
+-- claim them with a one-time code. One row per request, so a response
+-- cannot be replayed.
+CREATE TABLE IF NOT EXISTS saml_logins (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    request_id VARCHAR(255) NOT NULL UNIQUE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    subject TEXT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    first_name VARCHAR(255) NOT NULL DEFAULT '',
+    last_name VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(50) NOT NULL DEFAULT '',               -- mapped role, empty for the default
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ---------- Configuration ----------
+
+CREATE OR REPLACE FUNCTION get_organization_saml(p_org_id UUID)
+RETURNS TABLE (
+    organization_id UUID,
+    idp_entity_id TEXT,
+    idp_sso_url TEXT,
+    idp_metadata TEXT,
+    idp_metadata_url TEXT,
+    sp_certificate TEXT,
+    sp_private_key TEXT,
+    attribute_mapping JSONB,
+    allowed_domains TEXT[],
+    default_role user_role,
+    enabled BOOLEAN,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT s.organization_id, s.idp_entity_id, s.idp_sso_url, s.idp_metadata, s.idp_metadata_url,
+           s.sp_certificate, s.sp_private_key, s.attribute_mapping, s.allowed_domains,
+           s.default_role, s.enabled, s.created_at, s.updated_at
+    FROM organization_saml s
+    WHERE s.organization_id = p_org_id;
+$$;
+
+-- Creates or replaces the configuration
+CREATE OR REPLACE FUNCTION save_organization_saml(
+    p_org_id UUID,
+    p_idp_entity_id TEXT,
+    p_idp_sso_url TEXT,
+    p_idp_metadata TEXT,
+    p_idp_metadata_url TEXT,
+    p_sp_certificate TEXT,
+    p_sp_private_key TEXT,
+    p_attribute_mapping JSONB,
+    p_allowed_domains TEXT[],
+    p_default_role user_role,
+    p_enabled BOOLEAN
+)
+RETURNS TABLE (created_at TIMESTAMP, updated_at TIMESTAMP)
+LANGUAGE SQL AS $$
+    INSERT INTO organization_saml (organization_id, idp_entity_id, idp_sso_url, idp_metadata, idp_metadata_url,
+                                   sp_certificate, sp_private_key, attribute_mapping, allowed_domains, default_role, enabled)
+    VALUES (p_org_id, p_idp_entity_id, p_idp_sso_url, p_idp_metadata, p_idp_metadata_url,
+            p_sp_certificate, p_sp_private_key, p_attribute_mapping, p_allowed_domains, p_default_role, p_enabled)
+    ON CONFLICT (organization_id) DO UPDATE
+        SET idp_entity_id = EXCLUDED.idp_entity_id,
+            idp_sso_url = EXCLUDED.idp_sso_url,
+            idp_metadata = EXCLUDED.idp_metadata,
+            idp_metadata_url = EXCLUDED.idp_metadata_url,
+            sp_certificate = EXCLUDED.sp_certificate,
+            sp_private_key = EXCLUDED.sp_private_key,
+            attribute_mapping = EXCLUDED.attribute_mapping,
+            allowed_domains = EXCLUDED.allowed_domains,
+            default_role = EXCLUDED.default_role,
+            enabled = EXCLUDED.enabled,
+            updated_at = CURRENT_TIMESTAMP
+    RETURNING organization_saml.created_at, organization_saml.updated_at;
+$$;
+
+-- Removes the configuration; linked identities are kept. Returns FALSE when there was none.
+CREATE OR REPLACE FUNCTION delete_organization_saml(p_org_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM organization_saml WHERE organization_id = p_org_id;
+    RETURN FOUND;
+END;
+$$;
+
+-- ---------- Sign-in handoff ----------
+
+-- Stores a validated assertion. Returns FALSE when the request was already
+-- answered, i.e. the response is a replay.
+CREATE OR REPLACE FUNCTION create_saml_login(
+    p_org_id UUID,
+    p_request_id VARCHAR,
+    p_code_hash VARCHAR,
+    p_subject TEXT,
+    p_email VARCHAR,
+    p_first_name VARCHAR,
+    p_last_name VARCHAR,
+    p_role VARCHAR,
+    p_expires_at TIMESTAMP
+)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    -- Request IDs only need to be remembered while their responses are valid
+    DELETE FROM saml_logins WHERE expires_at < CURRENT_TIMESTAMP - INTERVAL '1 day';
+
+    INSERT INTO saml_logins (organization_id, request_id, code_hash, subject, email, first_name, last_name, role, expires_at)
+    VALUES (p_org_id, p_request_id, p_code_hash, p_subject, p_email, p_first_name, p_last_name, p_role, p_expires_at)
+    ON CONFLICT (request_id) DO NOTHING;
+
+    RETURN FOUND;
+END;
+$$;
+
+-- Claims an unexpired, unused sign-in by its code
+CREATE OR REPLACE FUNCTION consume_saml_login(p_code_hash VARCHAR)
+RETURNS TABLE (
+    organization_id UUID,
+    request_id VARCHAR(255),
+    subject TEXT,
+    email VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    role VARCHAR(50),
+    expires_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    UPDATE saml_logins l
+    SET used_at = CURRENT_TIMESTAMP
+    WHERE l.code_hash = p_code_hash AND l.used_at IS NULL AND l.expires_at > CURRENT_TIMESTAMP
+    RETURNING l.organization_id, l.request_id, l.subject, l.email, l.first_name, l.last_name, l.role, l.expires_at;
+$$;
+
+-- ---------- Discovery ----------
+
+-- The result columns change, so the function has to be dropped first
+DROP FUNCTION IF EXISTS find_sso_organizations_by_domain(TEXT);
+
+-- Organizations with SSO enabled for an email domain, with the protocol to start it with
+CREATE OR REPLACE FUNCTION find_sso_organizations_by_domain(p_domain TEXT)
+RETURNS TABLE (organization_id UUID, name VARCHAR, protocol TEXT)
+LANGUAGE SQL AS $$
+    SELECT o.id, o.name, p.protocol
+    FROM (
+        SELECT s.organization_id, 'oidc' AS protocol FROM organization_sso s
+        WHERE s.enabled AND s.allowed_domains @> ARRAY[lower(p_domain)]
+        UNION ALL
+        SELECT s.organization_id, 'saml' AS protocol FROM organization_saml s
+        WHERE s.enabled AND s.allowed_domains @> ARRAY[lower(p_domain)]
+    ) p
+    JOIN organizations o ON o.id = p.organization_id
+    WHERE o.status = 'active'
+    ORDER BY o.name, p.protocol;
+$$;
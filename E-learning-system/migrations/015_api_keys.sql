@@ -0,0 +1,117 @@
+-- =====================================================
+-- API KEYS
+-- Integrations such as student information system syncs authenticate with
+-- an organization's API key instead of a user's session. A key is shown
+-- once; only its public prefix and the SHA-256 of the whole key are stored.
+-- Scopes are admin permission names. Requests made with a key act on
+-- behalf of the admin who created it, so removing or demoting that admin
+-- disables the key as well.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,         -- public part, used to look the key up
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,                       -- NULL keys do not expire
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_organization_id ON api_keys (organization_id);
+
+CREATE OR REPLACE FUNCTION create_api_key(
+    p_org_id UUID,
+    p_name VARCHAR,
+    p_prefix VARCHAR,
+    p_key_hash VARCHAR,
+    p_scopes TEXT[],
+    p_created_by UUID,
+    p_expires_at TIMESTAMP
+)
+RETURNS TABLE (
+    id UUID,
+    created_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    INSERT INTO api_keys (organization_id, name, prefix, key_hash, scopes, created_by, expires_at)
+    VALUES (p_org_id, p_name, p_prefix, p_key_hash, p_scopes, p_created_by, p_expires_at)
+    RETURNING api_keys.id, api_keys.created_at;
+$$;
+
+-- Keys of an organization, newest first, revoked ones included
+CREATE OR REPLACE FUNCTION list_api_keys(p_org_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    name VARCHAR(100),
+    prefix VARCHAR(32),
+    scopes TEXT[],
+    created_by UUID,
+    created_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT k.id, k.organization_id, k.name, k.prefix, k.scopes, k.created_by,
+           k.created_at, k.expires_at, k.last_used_at, k.revoked_at
+    FROM api_keys k
+    WHERE k.organization_id = p_org_id
+    ORDER BY k.created_at DESC;
+$$;
+
+-- Looks a key up by its prefix for authentication, together with the status
+-- of its organization
+CREATE OR REPLACE FUNCTION find_api_key_by_prefix(p_prefix VARCHAR)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    name VARCHAR(100),
+    prefix VARCHAR(32),
+    key_hash VARCHAR(64),
+    scopes TEXT[],
+    created_by UUID,
+    created_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    organization_status organization_status
+)
+LANGUAGE SQL AS $$
+    SELECT k.id, k.organization_id, k.name, k.prefix, k.key_hash, k.scopes, k.created_by,
+           k.created_at, k.expires_at, k.last_used_at, k.revoked_at, o.status
+    FROM api_keys k
+    JOIN organizations o ON o.id = k.organization_id
+    WHERE k.prefix = p_prefix;
+$$;
+
+-- Revokes a key of an organization. Returns FALSE when there is no such
+-- active key.
+CREATE OR REPLACE FUNCTION revoke_api_key(p_org_id UUID, p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE api_keys
+    SET revoked_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND organization_id = p_org_id AND revoked_at IS NULL;
+
+    RETURN FOUND;
+END;
+$$;
+
+-- Records that a key was used. Writes at most once a minute per key so busy
+-- integrations do not update the row on every request.
+CREATE OR REPLACE PROCEDURE touch_api_key(p_id UUID)
+LANGUAGE SQL
+AS $$
+    UPDATE api_keys
+    SET last_used_at = CURRENT_TIMESTAMP
+    WHERE id = p_id
+      AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');
+$$;
@@ -0,0 +1,157 @@
+-- =====================================================
+-- PASSWORD POLICY
+-- Previous password hashes are kept so they cannot be reused, and the time
+-- of the last change is recorded so organizations can make their members'
+-- passwords expire. Upgrading the hash of an unchanged password (bcrypt to
+-- Argon2id on sign-in) is not a change and touches neither.
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS password_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id, created_at DESC);
+
+-- Days after which members must choose a new password; 0 never expires
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS password_max_age_days INT NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'organizations_password_max_age_days_check') THEN
+        ALTER TABLE organizations ADD CONSTRAINT organizations_password_max_age_days_check CHECK (password_max_age_days >= 0);
+    END IF;
+END;
+$$;
+
+-- ---------- Changing passwords ----------
+
+-- Replaces the password and moves the old hash into the history, which
+-- keeps the 24 most recent entries
+CREATE OR REPLACE FUNCTION update_user_password(
+    p_user_id UUID,
+    p_password TEXT
+)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    old_password TEXT;
+BEGIN
+    SELECT password INTO old_password FROM users WHERE id = p_user_id FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'No user found with ID: %', p_user_id;
+    END IF;
+
+    INSERT INTO password_history (user_id, password_hash) VALUES (p_user_id, old_password);
+    DELETE FROM password_history
+    WHERE user_id = p_user_id
+      AND id NOT IN (
+          SELECT h.id FROM password_history h
+          WHERE h.user_id = p_user_id
+          ORDER BY h.created_at DESC
+          LIMIT 24
+      );
+
+    UPDATE users
+    SET password = p_password,
+        password_changed_at = CURRENT_TIMESTAMP,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_user_id;
+END;
+$$;
+
+-- Swaps the hash of an unchanged password for a stronger one. Returns FALSE
+-- when the password was changed in the meantime.
+CREATE OR REPLACE FUNCTION rehash_user_password(p_user_id UUID, p_old_hash TEXT, p_new_hash TEXT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE users
+    SET password = p_new_hash
+    WHERE id = p_user_id AND password = p_old_hash;
+
+    RETURN FOUND;
+END;
+$$;
+
+-- Hashes of the previous passwords of a user, newest first
+CREATE OR REPLACE FUNCTION get_password_history(p_user_id UUID, p_limit INT)
+RETURNS TABLE (password_hash TEXT)
+LANGUAGE SQL AS $$
+    SELECT h.password_hash
+    FROM password_history h
+    WHERE h.user_id = p_user_id
+    ORDER BY h.created_at DESC
+    LIMIT p_limit;
+$$;
+
+-- ---------- Expiry ----------
+
+-- When the user's password expires under the strictest policy of the
+-- organizations they belong to; NULL when none of them sets an expiry
+CREATE OR REPLACE FUNCTION get_password_expiry(p_user_id UUID)
+RETURNS TIMESTAMP
+LANGUAGE SQL AS $$
+    SELECT u.password_changed_at + make_interval(days => MIN(o.password_max_age_days))
+    FROM users u
+    JOIN organizations o ON o.id IN (
+        SELECT a.organization_id FROM organization_admins a WHERE a.user_id = p_user_id AND a.deleted_at IS NULL
+        UNION
+        SELECT t.organization_id FROM organization_tutors t WHERE t.user_id = p_user_id AND t.deleted_at IS NULL
+        UNION
+        SELECT i.organization_id FROM sso_identities i WHERE i.user_id = p_user_id
+    )
+    WHERE u.id = p_user_id AND o.password_max_age_days > 0
+    GROUP BY u.password_changed_at;
+$$;
+
+-- ---------- Organization policy ----------
+
+-- The result columns change, so the functions have to be dropped first
+DROP FUNCTION IF EXISTS get_organization_policy(UUID);
+DROP FUNCTION IF EXISTS update_organization_policy(UUID, BOOLEAN, BOOLEAN);
+
+CREATE OR REPLACE FUNCTION get_organization_policy(p_org_id UUID)
+RETURNS TABLE (
+    organization_id UUID,
+    require_verified_email BOOLEAN,
+    require_mfa BOOLEAN,
+    password_max_age_days INT,
+    updated_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT o.id, o.require_verified_email, o.require_mfa, o.password_max_age_days, o.updated_at
+    FROM organizations o
+    WHERE o.id = p_org_id;
+$$;
+
+CREATE OR REPLACE FUNCTION update_organization_policy(
+    p_org_id UUID,
+    p_require_verified_email BOOLEAN,
+    p_require_mfa BOOLEAN,
+    p_password_max_age_days INT
+)
+RETURNS TIMESTAMP
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    new_updated_at TIMESTAMP;
+BEGIN
+    UPDATE organizations
+    SET require_verified_email = p_require_verified_email,
+        require_mfa = p_require_mfa,
+        password_max_age_days = p_password_max_age_days,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_org_id
+    RETURNING updated_at INTO new_updated_at;
+
+    RETURN new_updated_at;
+END;
+$$;
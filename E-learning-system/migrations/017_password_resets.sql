@@ -0,0 +1,94 @@
+-- =====================================================
+-- PASSWORD RESETS
+-- Reset links carry a random token of which only the SHA-256 is stored, in
+-- a table of its own instead of users.reset_token. A token works once; a
+-- new request replaces the user's earlier tokens, and changing the
+-- password by any means invalidates them.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    requested_ip VARCHAR(45),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+
+-- Raw tokens must not outlive this migration
+DROP FUNCTION IF EXISTS set_reset_token(VARCHAR, UUID, TIMESTAMP);
+DROP FUNCTION IF EXISTS get_user_by_reset_token(UUID);
+ALTER TABLE users DROP COLUMN IF EXISTS reset_token;
+ALTER TABLE users DROP COLUMN IF EXISTS reset_token_expiry;
+
+-- Issues a token, replacing the user's unused ones. Rows that expired more
+-- than a day ago are purged on the way.
+CREATE OR REPLACE FUNCTION create_password_reset(
+    p_user_id UUID,
+    p_token_hash VARCHAR,
+    p_ttl_seconds INT,
+    p_ip VARCHAR
+)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM password_resets
+    WHERE (user_id = p_user_id AND used_at IS NULL)
+       OR expires_at < CURRENT_TIMESTAMP - INTERVAL '1 day';
+
+    INSERT INTO password_resets (user_id, token_hash, requested_ip, expires_at)
+    VALUES (p_user_id, p_token_hash, NULLIF(p_ip, ''),
+            CURRENT_TIMESTAMP + make_interval(secs => p_ttl_seconds));
+END;
+$$;
+
+-- The user a valid, unused token belongs to; NULL otherwise
+CREATE OR REPLACE FUNCTION find_password_reset(p_token_hash VARCHAR)
+RETURNS UUID
+LANGUAGE SQL AS $$
+    SELECT r.user_id
+    FROM password_resets r
+    WHERE r.token_hash = p_token_hash
+      AND r.used_at IS NULL
+      AND r.expires_at > CURRENT_TIMESTAMP;
+$$;
+
+-- Spends a token and sets the new password in one step. Returns the user
+-- ID, or NULL when the token is unknown, used or expired; a token raced by
+-- two requests therefore only changes the password once.
+CREATE OR REPLACE FUNCTION complete_password_reset(p_token_hash VARCHAR, p_password TEXT)
+RETURNS UUID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    reset_user_id UUID;
+BEGIN
+    UPDATE password_resets
+    SET used_at = CURRENT_TIMESTAMP
+    WHERE token_hash = p_token_hash
+      AND used_at IS NULL
+      AND expires_at > CURRENT_TIMESTAMP
+    RETURNING user_id INTO reset_user_id;
+
+    IF reset_user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM update_user_password(reset_user_id, p_password);
+    PERFORM clear_reset_token(reset_user_id);
+    RETURN reset_user_id;
+END;
+$$;
+
+-- Invalidates every unused token of a user, e.g. after a password change
+CREATE OR REPLACE FUNCTION clear_reset_token(p_user_id UUID)
+RETURNS VOID
+LANGUAGE SQL AS $$
+    UPDATE password_resets
+    SET used_at = CURRENT_TIMESTAMP
+    WHERE user_id = p_user_id AND used_at IS NULL;
+$$;
@@ -0,0 +1,127 @@
+-- =====================================================
+-- AUDIT EVENTS
+-- One row per administrative change: who made it, through which request,
+-- on which organization and record, and the fields it changed. Rows are
+-- never updated or deleted, and carry no foreign keys so that they outlive
+-- the organizations, users and keys they mention.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID,
+    actor_id UUID,       -- NULL for changes made by the system
+    api_key_id UUID,     -- set when the actor used an API key
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id UUID,
+    changes JSONB NOT NULL DEFAULT '{}',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_organization_id ON audit_events (organization_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE OR REPLACE PROCEDURE record_audit_event(
+    IN p_organization_id UUID,
+    IN p_actor_id UUID,
+    IN p_api_key_id UUID,
+    IN p_action VARCHAR,
+    IN p_target_type VARCHAR,
+    IN p_target_id UUID,
+    IN p_changes JSONB,
+    IN p_ip_address VARCHAR,
+    IN p_user_agent TEXT,
+    IN p_request_id VARCHAR
+)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO audit_events (organization_id, actor_id, api_key_id, action, target_type, target_id,
+                              changes, ip_address, user_agent, request_id)
+    VALUES (p_organization_id, p_actor_id, p_api_key_id, p_action, p_target_type, p_target_id,
+            COALESCE(p_changes, '{}'), COALESCE(p_ip_address, ''), COALESCE(p_user_agent, ''), COALESCE(p_request_id, ''));
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION count_audit_events(
+    p_organization_id UUID,
+    p_actor_id UUID,
+    p_action TEXT,
+    p_from TIMESTAMP,
+    p_to TIMESTAMP
+)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM audit_events e
+    WHERE (p_organization_id IS NULL OR e.organization_id = p_organization_id)
+      AND (p_actor_id IS NULL OR e.actor_id = p_actor_id)
+      AND (p_action IS NULL OR e.action = p_action)
+      AND (p_from IS NULL OR e.created_at >= p_from)
+      AND (p_to IS NULL OR e.created_at < p_to);
+$$;
+
+CREATE OR REPLACE FUNCTION list_audit_events(
+    p_organization_id UUID,
+    p_actor_id UUID,
+    p_action TEXT,
+    p_from TIMESTAMP,
+    p_to TIMESTAMP,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    actor_id UUID,
+    api_key_id UUID,
+    action VARCHAR,
+    target_type VARCHAR,
+    target_id UUID,
+    changes JSONB,
+    ip_address VARCHAR,
+    user_agent TEXT,
+    request_id VARCHAR,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Audit events only sort by created_at
+    RETURN QUERY EXECUTE format(
+        'SELECT e.id, e.organization_id, e.actor_id, e.api_key_id, e.action, e.target_type, e.target_id,
+                e.changes, e.ip_address, e.user_agent, e.request_id, e.created_at
+         FROM audit_events e
+         WHERE ($1 IS NULL OR e.organization_id = $1)
+           AND ($2 IS NULL OR e.actor_id = $2)
+           AND ($3 IS NULL OR e.action = $3)
+           AND ($4 IS NULL OR e.created_at >= $4)
+           AND ($5 IS NULL OR e.created_at < $5)
+           AND ($7 IS NULL OR (e.created_at, e.id) %1$s ($6::timestamp, $7))
+         ORDER BY e.created_at %2$s, e.id %2$s
+         LIMIT $8',
+        CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_actor_id, p_action, p_from, p_to, p_after_value, p_after_id, p_limit;
+END;
+$$;
@@ -0,0 +1,469 @@
+-- =====================================================
+-- SOFT DELETE, RESTORE AND RETENTION
+-- Organization admins, tutors, brandings and billings are deleted the same
+-- way: by record ID, setting deleted_at. Deleted records can be restored
+-- until the retention job hard deletes them, and list endpoints can include
+-- them on request.
+-- =====================================================
+
+CREATE INDEX IF NOT EXISTS idx_organization_admins_deleted_at ON organization_admins (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_organization_tutors_deleted_at ON organization_tutors (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_organization_brandings_deleted_at ON organization_brandings (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_organization_billings_deleted_at ON organization_billings (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- ---------- Brandings and billings are now keyed by their own ID ----------
+
+DROP PROCEDURE IF EXISTS update_organization_branding(UUID, VARCHAR, VARCHAR, VARCHAR, VARCHAR, TEXT);
+CREATE OR REPLACE PROCEDURE update_organization_branding(
+    IN p_id UUID,
+    IN p_logo_url VARCHAR,
+    IN p_primary_color VARCHAR,
+    IN p_secondary_color VARCHAR,
+    IN p_theme VARCHAR,
+    IN p_email_template TEXT
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_brandings
+    SET logo_url = p_logo_url,
+        primary_color = p_primary_color,
+        secondary_color = p_secondary_color,
+        theme = p_theme,
+        email_template = p_email_template,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+DROP PROCEDURE IF EXISTS delete_organization_branding(UUID);
+CREATE OR REPLACE PROCEDURE delete_organization_branding(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_brandings
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+DROP PROCEDURE IF EXISTS update_organization_billing(UUID, plan_type, VARCHAR, VARCHAR, TIMESTAMP);
+CREATE OR REPLACE PROCEDURE update_organization_billing(
+    IN p_id UUID,
+    IN p_plan plan_type,
+    IN p_payment_method VARCHAR,
+    IN p_subscription_id VARCHAR,
+    IN p_next_billing_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_billings
+    SET plan = p_plan,
+        payment_method = p_payment_method,
+        subscription_id = p_subscription_id,
+        next_billing_at = p_next_billing_at,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+DROP PROCEDURE IF EXISTS delete_organization_billing(UUID);
+CREATE OR REPLACE PROCEDURE delete_organization_billing(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_billings
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+-- Deleting twice must not move the retention deadline
+CREATE OR REPLACE PROCEDURE delete_organization_admin(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_admins
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE delete_organization_tutor(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_tutors
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+-- ---------- Lookups by ID, live records only ----------
+
+DROP FUNCTION IF EXISTS get_organization_admin_by_id(UUID);
+CREATE OR REPLACE FUNCTION get_organization_admin_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    role VARCHAR,
+    created_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT a.id, a.user_id, a.organization_id, a.role::varchar, a.created_at, a.deleted_at
+    FROM organization_admins a
+    WHERE a.id = p_id AND a.deleted_at IS NULL;
+$$;
+
+DROP FUNCTION IF EXISTS get_organization_tutor_by_id(UUID);
+CREATE OR REPLACE FUNCTION get_organization_tutor_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    approved BOOLEAN,
+    created_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT t.id, t.user_id, t.organization_id, t.approved, t.created_at, t.deleted_at
+    FROM organization_tutors t
+    WHERE t.id = p_id AND t.deleted_at IS NULL;
+$$;
+
+DROP FUNCTION IF EXISTS get_organization_branding_by_id(UUID);
+CREATE OR REPLACE FUNCTION get_organization_branding_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    theme VARCHAR,
+    email_template TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT b.id, b.organization_id, b.logo_url::varchar, b.primary_color::varchar, b.secondary_color::varchar,
+           b.theme::varchar, b.email_template, b.created_at, b.updated_at, b.deleted_at
+    FROM organization_brandings b
+    WHERE b.id = p_id AND b.deleted_at IS NULL;
+$$;
+
+DROP FUNCTION IF EXISTS get_organization_billing_by_id(UUID);
+CREATE OR REPLACE FUNCTION get_organization_billing_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    plan VARCHAR,
+    payment_method VARCHAR,
+    subscription_id VARCHAR,
+    next_billing_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT b.id, b.organization_id, b.plan::varchar, b.payment_method::varchar, b.subscription_id::varchar,
+           b.next_billing_at, b.created_at, b.updated_at, b.deleted_at
+    FROM organization_billings b
+    WHERE b.id = p_id AND b.deleted_at IS NULL;
+$$;
+
+-- ---------- Restore ----------
+-- Each returns FALSE when there is no deleted record with the ID. Restoring
+-- a membership the user has since been given again is a unique violation.
+
+CREATE OR REPLACE FUNCTION restore_organization_admin(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    restored organization_admins%ROWTYPE;
+BEGIN
+    SELECT * INTO restored FROM organization_admins WHERE id = p_id AND deleted_at IS NOT NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM organization_admins
+               WHERE user_id = restored.user_id AND organization_id = restored.organization_id AND deleted_at IS NULL) THEN
+        RAISE EXCEPTION 'user is already an admin of organization %', restored.organization_id
+            USING ERRCODE = 'unique_violation';
+    END IF;
+
+    UPDATE organization_admins SET deleted_at = NULL WHERE id = p_id;
+    RETURN TRUE;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION restore_organization_tutor(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    restored organization_tutors%ROWTYPE;
+BEGIN
+    SELECT * INTO restored FROM organization_tutors WHERE id = p_id AND deleted_at IS NOT NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM organization_tutors
+               WHERE user_id = restored.user_id AND organization_id = restored.organization_id AND deleted_at IS NULL) THEN
+        RAISE EXCEPTION 'user is already a tutor of organization %', restored.organization_id
+            USING ERRCODE = 'unique_violation';
+    END IF;
+
+    UPDATE organization_tutors SET deleted_at = NULL WHERE id = p_id;
+    RETURN TRUE;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION restore_organization_branding(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_brandings
+    SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NOT NULL;
+    RETURN FOUND;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION restore_organization_billing(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE organization_billings
+    SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NOT NULL;
+    RETURN FOUND;
+END;
+$$;
+
+-- ---------- Lists that can include deleted records ----------
+
+DROP FUNCTION IF EXISTS count_organization_tutors(UUID, BOOLEAN);
+CREATE OR REPLACE FUNCTION count_organization_tutors(p_organization_id UUID, p_approved BOOLEAN, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_tutors t
+    WHERE (p_include_deleted OR t.deleted_at IS NULL)
+      AND (p_organization_id IS NULL OR t.organization_id = p_organization_id)
+      AND (p_approved IS NULL OR t.approved = p_approved);
+$$;
+
+DROP FUNCTION IF EXISTS list_organization_tutors(UUID, BOOLEAN, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_organization_tutors(
+    p_organization_id UUID,
+    p_approved BOOLEAN,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    approved BOOLEAN,
+    created_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Tutors only sort by created_at
+    RETURN QUERY EXECUTE format(
+        'SELECT t.id, t.user_id, t.organization_id, t.approved, t.created_at, t.deleted_at
+         FROM organization_tutors t
+         WHERE ($3 OR t.deleted_at IS NULL)
+           AND ($1 IS NULL OR t.organization_id = $1)
+           AND ($2 IS NULL OR t.approved = $2)
+           AND ($5 IS NULL OR (t.created_at, t.id) %1$s ($4::timestamp, $5))
+         ORDER BY t.created_at %2$s, t.id %2$s
+         LIMIT $6',
+        CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_approved, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS count_organization_admins(UUID, TEXT);
+CREATE OR REPLACE FUNCTION count_organization_admins(p_organization_id UUID, p_role TEXT, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_admins a
+    WHERE (p_include_deleted OR a.deleted_at IS NULL)
+      AND (p_organization_id IS NULL OR a.organization_id = p_organization_id)
+      AND (p_role IS NULL OR a.role::text = p_role);
+$$;
+
+DROP FUNCTION IF EXISTS list_organization_admins(UUID, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_organization_admins(
+    p_organization_id UUID,
+    p_role TEXT,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    organization_id UUID,
+    role VARCHAR,
+    created_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- Admins only sort by created_at
+    RETURN QUERY EXECUTE format(
+        'SELECT a.id, a.user_id, a.organization_id, a.role::varchar, a.created_at, a.deleted_at
+         FROM organization_admins a
+         WHERE ($3 OR a.deleted_at IS NULL)
+           AND ($1 IS NULL OR a.organization_id = $1)
+           AND ($2 IS NULL OR a.role::text = $2)
+           AND ($5 IS NULL OR (a.created_at, a.id) %1$s ($4::timestamp, $5))
+         ORDER BY a.created_at %2$s, a.id %2$s
+         LIMIT $6',
+        CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_role, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS count_organization_billings(UUID, TEXT);
+CREATE OR REPLACE FUNCTION count_organization_billings(p_organization_id UUID, p_plan TEXT, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_billings b
+    WHERE (p_include_deleted OR b.deleted_at IS NULL)
+      AND (p_organization_id IS NULL OR b.organization_id = p_organization_id)
+      AND (p_plan IS NULL OR b.plan::text = p_plan);
+$$;
+
+DROP FUNCTION IF EXISTS list_organization_billings(UUID, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_organization_billings(
+    p_organization_id UUID,
+    p_plan TEXT,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    plan VARCHAR,
+    payment_method VARCHAR,
+    subscription_id VARCHAR,
+    next_billing_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'next_billing_at' THEN 'b.next_billing_at' ELSE 'b.created_at' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT b.id, b.organization_id, b.plan::varchar, b.payment_method::varchar, b.subscription_id::varchar,
+                b.next_billing_at, b.created_at, b.updated_at, b.deleted_at
+         FROM organization_billings b
+         WHERE ($3 OR b.deleted_at IS NULL)
+           AND ($1 IS NULL OR b.organization_id = $1)
+           AND ($2 IS NULL OR b.plan::text = $2)
+           AND ($5 IS NULL OR (%1$s, b.id) %2$s ($4::timestamp, $5))
+         ORDER BY %1$s %3$s, b.id %3$s
+         LIMIT $6',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_plan, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS count_organization_brandings(UUID, TEXT);
+CREATE OR REPLACE FUNCTION count_organization_brandings(p_organization_id UUID, p_theme TEXT, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organization_brandings b
+    WHERE (p_include_deleted OR b.deleted_at IS NULL)
+      AND (p_organization_id IS NULL OR b.organization_id = p_organization_id)
+      AND (p_theme IS NULL OR b.theme = p_theme);
+$$;
+
+DROP FUNCTION IF EXISTS list_organization_brandings(UUID, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_organization_brandings(
+    p_organization_id UUID,
+    p_theme TEXT,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    theme VARCHAR,
+    email_template TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'updated_at' THEN 'b.updated_at' ELSE 'b.created_at' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT b.id, b.organization_id, b.logo_url::varchar, b.primary_color::varchar, b.secondary_color::varchar,
+                b.theme::varchar, b.email_template, b.created_at, b.updated_at, b.deleted_at
+         FROM organization_brandings b
+         WHERE ($3 OR b.deleted_at IS NULL)
+           AND ($1 IS NULL OR b.organization_id = $1)
+           AND ($2 IS NULL OR b.theme = $2)
+           AND ($5 IS NULL OR (%1$s, b.id) %2$s ($4::timestamp, $5))
+         ORDER BY %1$s %3$s, b.id %3$s
+         LIMIT $6',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_theme, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Retention ----------
+
+-- Hard deletes records soft-deleted before p_before and returns how many
+-- of each kind went
+CREATE OR REPLACE FUNCTION purge_soft_deleted(p_before TIMESTAMP)
+RETURNS TABLE (record_type TEXT, purged BIGINT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    n BIGINT;
+BEGIN
+    DELETE FROM organization_admins WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_admin'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_tutors WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_tutor'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_brandings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_branding'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_billings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_billing'; purged := n; RETURN NEXT;
+END;
+$$;
@@ -0,0 +1,282 @@
+-- =====================================================
+-- USER DATA EXPORT AND ERASURE
+-- Data subject requests: a user, or a platform admin on their behalf, can
+-- have everything stored about the user exported as a ZIP archive, and can
+-- have the account erased. Erasure anonymizes the user in place, so that
+-- financial and audit records that must be kept still resolve to a row.
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS user_data_exports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by UUID,                    -- NULL when the user asked themselves
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processing, ready, failed
+    storage_key TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP                  -- the archive is deleted after this
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_data_exports_user_id ON user_data_exports (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_user_data_exports_status ON user_data_exports (status, created_at);
+-- One export in the works per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_data_exports_in_progress ON user_data_exports (user_id)
+    WHERE status IN ('pending', 'processing');
+
+CREATE OR REPLACE FUNCTION create_user_data_export(p_user_id UUID, p_requested_by UUID)
+RETURNS UUID
+LANGUAGE plpgsql AS $$
+DECLARE
+    export_id UUID;
+BEGIN
+    INSERT INTO user_data_exports (user_id, requested_by)
+    VALUES (p_user_id, p_requested_by)
+    RETURNING id INTO export_id;
+    RETURN export_id;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_data_export(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    storage_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT e.id, e.user_id, e.requested_by, e.status, e.storage_key, e.error, e.created_at, e.completed_at, e.expires_at
+    FROM user_data_exports e
+    WHERE e.id = p_id;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_data_exports_by_user(p_user_id UUID)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    storage_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT e.id, e.user_id, e.requested_by, e.status, e.storage_key, e.error, e.created_at, e.completed_at, e.expires_at
+    FROM user_data_exports e
+    WHERE e.user_id = p_user_id
+    ORDER BY e.created_at DESC;
+$$;
+
+-- Moves up to p_limit pending exports to processing and returns them.
+-- Exports stuck in processing for an hour, e.g. after a crash, are retried.
+CREATE OR REPLACE FUNCTION claim_user_data_exports(p_limit INT)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    storage_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    UPDATE user_data_exports e
+    SET status = 'processing', started_at = CURRENT_TIMESTAMP
+    WHERE e.id IN (
+        SELECT p.id FROM user_data_exports p
+        WHERE p.status = 'pending'
+           OR (p.status = 'processing' AND p.started_at < CURRENT_TIMESTAMP - INTERVAL '1 hour')
+        ORDER BY p.created_at
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING e.id, e.user_id, e.requested_by, e.status, e.storage_key, e.error, e.created_at, e.completed_at, e.expires_at;
+$$;
+
+CREATE OR REPLACE PROCEDURE complete_user_data_export(IN p_id UUID, IN p_storage_key TEXT, IN p_expires_at TIMESTAMP)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE user_data_exports
+    SET status = 'ready',
+        storage_key = p_storage_key,
+        completed_at = CURRENT_TIMESTAMP,
+        expires_at = p_expires_at
+    WHERE id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE fail_user_data_export(IN p_id UUID, IN p_error TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE user_data_exports
+    SET status = 'failed',
+        error = p_error,
+        completed_at = CURRENT_TIMESTAMP
+    WHERE id = p_id;
+END;
+$$;
+
+-- Ready exports whose archive has expired, and failed ones older than p_before
+CREATE OR REPLACE FUNCTION get_expired_user_data_exports(p_before TIMESTAMP)
+RETURNS TABLE (
+    id UUID,
+    user_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    storage_key TEXT,
+    error TEXT,
+    created_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT e.id, e.user_id, e.requested_by, e.status, e.storage_key, e.error, e.created_at, e.completed_at, e.expires_at
+    FROM user_data_exports e
+    WHERE (e.status = 'ready' AND e.expires_at < p_before)
+       OR (e.status = 'failed' AND e.completed_at < p_before - INTERVAL '7 days');
+$$;
+
+CREATE OR REPLACE PROCEDURE delete_user_data_export(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM user_data_exports WHERE id = p_id;
+END;
+$$;
+
+-- Everything stored about a user, one key per file of the archive. Secrets
+-- (password hashes, MFA secrets, session tokens) are left out. The platform
+-- keeps no grades or payments per user yet; those files are empty lists.
+CREATE OR REPLACE FUNCTION export_user_data(p_user_id UUID)
+RETURNS JSONB
+LANGUAGE plpgsql AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    SELECT jsonb_build_object(
+        'profile', jsonb_build_object(
+            'id', u.id,
+            'email', u.email,
+            'first_name', u.first_name,
+            'last_name', u.last_name,
+            'role', u.role,
+            'avatar_url', u.avatar_url,
+            'bio', u.bio,
+            'locale', u.locale,
+            'timezone', u.timezone,
+            'email_verified', u.email_verified,
+            'email_verified_at', u.email_verified_at,
+            'password_changed_at', u.password_changed_at,
+            'mfa_enabled', COALESCE((SELECT m.enabled FROM user_mfa m WHERE m.user_id = u.id), FALSE),
+            'sso_identities', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'organization_id', i.organization_id, 'issuer', i.issuer, 'subject', i.subject,
+                    'email', i.email, 'created_at', i.created_at, 'last_login_at', i.last_login_at) ORDER BY i.created_at)
+                FROM sso_identities i WHERE i.user_id = u.id), '[]'::jsonb),
+            'created_at', u.created_at,
+            'updated_at', u.updated_at
+        ),
+        'sessions', jsonb_build_object(
+            'sessions', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'id', t.id, 'created_at', t.created_at, 'expires_at', t.expires_at, 'revoked_at', t.deleted_at)
+                    ORDER BY t.created_at)
+                FROM tokens t WHERE t.user_id = u.id), '[]'::jsonb),
+            'sign_ins', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'outcome', l.outcome, 'reason', l.reason, 'ip_address', l.ip_address,
+                    'user_agent', l.user_agent, 'created_at', l.created_at) ORDER BY l.created_at)
+                FROM login_events l WHERE l.user_id = u.id), '[]'::jsonb)
+        ),
+        'memberships', COALESCE((SELECT jsonb_agg(m ORDER BY m->>'created_at') FROM (
+                SELECT jsonb_build_object('organization_id', a.organization_id, 'organization_name', o.name,
+                                          'membership', 'admin', 'role', a.role, 'created_at', a.created_at,
+                                          'deleted_at', a.deleted_at) AS m
+                FROM organization_admins a JOIN organizations o ON o.id = a.organization_id
+                WHERE a.user_id = u.id
+                UNION ALL
+                SELECT jsonb_build_object('organization_id', t.organization_id, 'organization_name', o.name,
+                                          'membership', 'tutor', 'approved', t.approved, 'created_at', t.created_at,
+                                          'deleted_at', t.deleted_at)
+                FROM organization_tutors t JOIN organizations o ON o.id = t.organization_id
+                WHERE t.user_id = u.id
+            ) memberships), '[]'::jsonb),
+        'enrollments', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                'course_id', c.id, 'course_title', c.title, 'organization_id', c.organization_id,
+                'enrolled_at', e.created_at) ORDER BY e.created_at)
+            FROM enrollments e JOIN courses c ON c.id = e.course_id
+            WHERE e.user_id = u.id), '[]'::jsonb),
+        'grades', '[]'::jsonb,
+        'payments', '[]'::jsonb
+    )
+    INTO result
+    FROM users u
+    WHERE u.id = p_user_id;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'user % not found', p_user_id;
+    END IF;
+
+    RETURN result;
+END;
+$$;
+
+-- Anonymizes a user: personal data is overwritten or deleted, credentials
+-- and sessions go, and memberships end. Enrollments, audit events and
+-- organization billing stay and point to the anonymized row. Returns FALSE
+-- when the user does not exist or is already erased.
+CREATE OR REPLACE FUNCTION erase_user(p_user_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    old_email VARCHAR;
+BEGIN
+    SELECT email INTO old_email FROM users WHERE id = p_user_id AND erased_at IS NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE users
+    SET email = 'erased-' || id || '@erased.invalid',
+        password = '!',   -- matches no password
+        first_name = '',
+        last_name = '',
+        avatar_url = '',
+        bio = '',
+        locale = '',
+        timezone = '',
+        email_verified = FALSE,
+        email_verified_at = NULL,
+        verification_sent_at = NULL,
+        erased_at = CURRENT_TIMESTAMP,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_user_id;
+
+    DELETE FROM tokens WHERE user_id = p_user_id;
+    DELETE FROM user_mfa WHERE user_id = p_user_id;
+    DELETE FROM mfa_recovery_codes WHERE user_id = p_user_id;
+    DELETE FROM sso_identities WHERE user_id = p_user_id;
+    DELETE FROM password_history WHERE user_id = p_user_id;
+    DELETE FROM password_resets WHERE user_id = p_user_id;
+    DELETE FROM email_changes WHERE user_id = p_user_id;
+    DELETE FROM user_data_exports WHERE user_id = p_user_id;
+
+    -- Keep the outcome of sign-in attempts, not who made them from where
+    UPDATE login_events
+    SET email = '', ip_address = '', user_agent = ''
+    WHERE user_id = p_user_id OR lower(email) = lower(old_email);
+
+    UPDATE organization_admins SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = p_user_id AND deleted_at IS NULL;
+    UPDATE organization_tutors SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = p_user_id AND deleted_at IS NULL;
+
+    RETURN TRUE;
+END;
+$$;
@@ -0,0 +1,535 @@
+-- =====================================================
+-- BULK USER IMPORT AND MEMBER EXPORT
+-- Platform admins onboard an organization by uploading a CSV of users. A
+-- background job creates or updates each user and gives them a membership:
+-- student, tutor, or admin/manager. Students become members of an
+-- organization through organization_students.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS organization_students (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_students_user_id ON organization_students (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_students_live ON organization_students (organization_id, user_id)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_imports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    requested_by UUID,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processing, completed, failed
+    file_name TEXT NOT NULL DEFAULT '',
+    storage_key TEXT NOT NULL DEFAULT '',  -- the uploaded file, deleted once processed
+    send_invitations BOOLEAN NOT NULL DEFAULT FALSE,
+    total_rows INT NOT NULL DEFAULT 0,
+    created_rows INT NOT NULL DEFAULT 0,
+    updated_rows INT NOT NULL DEFAULT 0,
+    unchanged_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_imports_organization_id ON user_imports (organization_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_user_imports_status ON user_imports (status, created_at);
+
+-- Rows of an import that could not be applied
+CREATE TABLE IF NOT EXISTS user_import_errors (
+    id BIGSERIAL PRIMARY KEY,
+    import_id UUID NOT NULL REFERENCES user_imports(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,               -- line of the file, the header being line 1
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_import_errors_import_id ON user_import_errors (import_id, row_number);
+
+-- ---------- Imports ----------
+
+CREATE OR REPLACE FUNCTION create_user_import(
+    p_organization_id UUID,
+    p_requested_by UUID,
+    p_file_name TEXT,
+    p_storage_key TEXT,
+    p_send_invitations BOOLEAN,
+    p_total_rows INT
+)
+RETURNS UUID
+LANGUAGE plpgsql AS $$
+DECLARE
+    import_id UUID;
+BEGIN
+    INSERT INTO user_imports (organization_id, requested_by, file_name, storage_key, send_invitations, total_rows)
+    VALUES (p_organization_id, p_requested_by, p_file_name, p_storage_key, p_send_invitations, p_total_rows)
+    RETURNING id INTO import_id;
+    RETURN import_id;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_import(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    file_name TEXT,
+    storage_key TEXT,
+    send_invitations BOOLEAN,
+    total_rows INT,
+    created_rows INT,
+    updated_rows INT,
+    unchanged_rows INT,
+    failed_rows INT,
+    error TEXT,
+    created_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT i.id, i.organization_id, i.requested_by, i.status, i.file_name, i.storage_key, i.send_invitations,
+           i.total_rows, i.created_rows, i.updated_rows, i.unchanged_rows, i.failed_rows, i.error,
+           i.created_at, i.started_at, i.completed_at
+    FROM user_imports i
+    WHERE i.id = p_id;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_imports_by_organization(p_organization_id UUID)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    file_name TEXT,
+    storage_key TEXT,
+    send_invitations BOOLEAN,
+    total_rows INT,
+    created_rows INT,
+    updated_rows INT,
+    unchanged_rows INT,
+    failed_rows INT,
+    error TEXT,
+    created_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT i.id, i.organization_id, i.requested_by, i.status, i.file_name, i.storage_key, i.send_invitations,
+           i.total_rows, i.created_rows, i.updated_rows, i.unchanged_rows, i.failed_rows, i.error,
+           i.created_at, i.started_at, i.completed_at
+    FROM user_imports i
+    WHERE i.organization_id = p_organization_id
+    ORDER BY i.created_at DESC;
+$$;
+
+-- Moves up to p_limit pending imports to processing and returns them.
+-- Imports stuck in processing for an hour, e.g. after a crash, are retried;
+-- rows already applied are applied again, which leaves them unchanged.
+CREATE OR REPLACE FUNCTION claim_user_imports(p_limit INT)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    requested_by UUID,
+    status VARCHAR,
+    file_name TEXT,
+    storage_key TEXT,
+    send_invitations BOOLEAN,
+    total_rows INT,
+    created_rows INT,
+    updated_rows INT,
+    unchanged_rows INT,
+    failed_rows INT,
+    error TEXT,
+    created_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    -- A retried import reports its rows afresh
+    DELETE FROM user_import_errors ue
+    WHERE ue.import_id IN (
+        SELECT p.id FROM user_imports p
+        WHERE p.status = 'processing' AND p.started_at < CURRENT_TIMESTAMP - INTERVAL '1 hour'
+    );
+
+    RETURN QUERY
+    UPDATE user_imports i
+    SET status = 'processing', started_at = CURRENT_TIMESTAMP
+    WHERE i.id IN (
+        SELECT p.id FROM user_imports p
+        WHERE p.status = 'pending'
+           OR (p.status = 'processing' AND p.started_at < CURRENT_TIMESTAMP - INTERVAL '1 hour')
+        ORDER BY p.created_at
+        LIMIT p_limit
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING i.id, i.organization_id, i.requested_by, i.status, i.file_name, i.storage_key, i.send_invitations,
+              i.total_rows, i.created_rows, i.updated_rows, i.unchanged_rows, i.failed_rows, i.error,
+              i.created_at, i.started_at, i.completed_at;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE complete_user_import(
+    IN p_id UUID,
+    IN p_total_rows INT,
+    IN p_created_rows INT,
+    IN p_updated_rows INT,
+    IN p_unchanged_rows INT,
+    IN p_failed_rows INT
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE user_imports
+    SET status = 'completed',
+        total_rows = p_total_rows,
+        created_rows = p_created_rows,
+        updated_rows = p_updated_rows,
+        unchanged_rows = p_unchanged_rows,
+        failed_rows = p_failed_rows,
+        storage_key = '',
+        completed_at = CURRENT_TIMESTAMP
+    WHERE id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE fail_user_import(IN p_id UUID, IN p_error TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE user_imports
+    SET status = 'failed',
+        error = p_error,
+        storage_key = '',
+        completed_at = CURRENT_TIMESTAMP
+    WHERE id = p_id;
+END;
+$$;
+
+CREATE OR REPLACE PROCEDURE add_user_import_error(IN p_import_id UUID, IN p_row_number INT, IN p_email VARCHAR, IN p_message TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO user_import_errors (import_id, row_number, email, message)
+    VALUES (p_import_id, p_row_number, p_email, p_message);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_import_errors(p_import_id UUID)
+RETURNS TABLE (row_number INT, email VARCHAR, message TEXT)
+LANGUAGE SQL AS $$
+    SELECT e.row_number, e.email, e.message
+    FROM user_import_errors e
+    WHERE e.import_id = p_import_id
+    ORDER BY e.row_number, e.id;
+$$;
+
+-- ---------- Rows ----------
+
+-- Creates or updates one user of an import and gives them the membership
+-- named by p_role: student, tutor, manager or admin. Names of existing users
+-- are only updated when they already belong to the organization, so an
+-- import cannot rename accounts of other organizations. New users get an
+-- unusable password and set one through an invitation. Students are created
+-- as students and everyone else as instructors; existing students are
+-- promoted when they become tutors or admins, other roles stay as they are.
+-- Returns created, updated or unchanged.
+CREATE OR REPLACE FUNCTION import_organization_user(
+    p_organization_id UUID,
+    p_email VARCHAR,
+    p_first_name VARCHAR,
+    p_last_name VARCHAR,
+    p_role VARCHAR
+)
+RETURNS TABLE (user_id UUID, outcome TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    u users%ROWTYPE;
+    is_member BOOLEAN;
+    changed BOOLEAN := FALSE;
+    rows_changed INT;
+BEGIN
+    IF p_role NOT IN ('student', 'tutor', 'manager', 'admin') THEN
+        RAISE EXCEPTION 'unknown import role %', p_role USING ERRCODE = 'check_violation';
+    END IF;
+
+    SELECT * INTO u FROM users WHERE lower(email) = lower(p_email) FOR UPDATE;
+
+    IF NOT FOUND THEN
+        INSERT INTO users (email, password, first_name, last_name, role)
+        VALUES (lower(p_email), '!', p_first_name, p_last_name,
+                (CASE WHEN p_role = 'student' THEN 'student' ELSE 'instructor' END)::user_role)
+        RETURNING * INTO u;
+        outcome := 'created';
+    ELSE
+        is_member := EXISTS (SELECT 1 FROM organization_admins a WHERE a.organization_id = p_organization_id AND a.user_id = u.id AND a.deleted_at IS NULL)
+                  OR EXISTS (SELECT 1 FROM organization_tutors t WHERE t.organization_id = p_organization_id AND t.user_id = u.id AND t.deleted_at IS NULL)
+                  OR EXISTS (SELECT 1 FROM organization_students s WHERE s.organization_id = p_organization_id AND s.user_id = u.id AND s.deleted_at IS NULL)
+                  OR EXISTS (SELECT 1 FROM sso_identities i WHERE i.organization_id = p_organization_id AND i.user_id = u.id);
+
+        IF is_member AND (u.first_name <> p_first_name OR u.last_name <> p_last_name) THEN
+            UPDATE users SET first_name = p_first_name, last_name = p_last_name, updated_at = CURRENT_TIMESTAMP
+            WHERE id = u.id;
+            changed := TRUE;
+        END IF;
+
+        IF p_role <> 'student' AND u.role = 'student' THEN
+            UPDATE users SET role = 'instructor', updated_at = CURRENT_TIMESTAMP WHERE id = u.id;
+            changed := TRUE;
+        END IF;
+    END IF;
+
+    IF p_role = 'student' THEN
+        INSERT INTO organization_students (user_id, organization_id)
+        SELECT u.id, p_organization_id
+        WHERE NOT EXISTS (SELECT 1 FROM organization_students s
+                          WHERE s.organization_id = p_organization_id AND s.user_id = u.id AND s.deleted_at IS NULL);
+    ELSIF p_role = 'tutor' THEN
+        UPDATE organization_tutors t SET approved = TRUE
+        WHERE t.organization_id = p_organization_id AND t.user_id = u.id AND t.deleted_at IS NULL AND NOT t.approved;
+        GET DIAGNOSTICS rows_changed = ROW_COUNT;
+        changed := changed OR rows_changed > 0;
+
+        INSERT INTO organization_tutors (user_id, organization_id, approved)
+        SELECT u.id, p_organization_id, TRUE
+        WHERE NOT EXISTS (SELECT 1 FROM organization_tutors t
+                          WHERE t.organization_id = p_organization_id AND t.user_id = u.id AND t.deleted_at IS NULL);
+    ELSE
+        UPDATE organization_admins a SET role = p_role
+        WHERE a.organization_id = p_organization_id AND a.user_id = u.id AND a.deleted_at IS NULL AND a.role <> p_role;
+        GET DIAGNOSTICS rows_changed = ROW_COUNT;
+        changed := changed OR rows_changed > 0;
+
+        INSERT INTO organization_admins (user_id, organization_id, role)
+        SELECT u.id, p_organization_id, p_role
+        WHERE NOT EXISTS (SELECT 1 FROM organization_admins a
+                          WHERE a.organization_id = p_organization_id AND a.user_id = u.id AND a.deleted_at IS NULL);
+    END IF;
+    GET DIAGNOSTICS rows_changed = ROW_COUNT;
+    changed := changed OR rows_changed > 0;
+
+    user_id := u.id;
+    IF outcome IS NULL THEN
+        outcome := CASE WHEN changed THEN 'updated' ELSE 'unchanged' END;
+    END IF;
+    RETURN NEXT;
+END;
+$$;
+
+-- ---------- Members ----------
+
+-- One row per live membership, in the role vocabulary of the import file
+CREATE OR REPLACE FUNCTION get_organization_members(p_organization_id UUID)
+RETURNS TABLE (
+    user_id UUID,
+    email VARCHAR,
+    first_name VARCHAR,
+    last_name VARCHAR,
+    role VARCHAR,
+    approved BOOLEAN,
+    joined_at TIMESTAMP
+)
+LANGUAGE SQL AS $$
+    SELECT m.user_id, u.email, u.first_name, u.last_name, m.role, m.approved, m.joined_at
+    FROM (
+        SELECT a.user_id, a.role::VARCHAR AS role, TRUE AS approved, a.created_at AS joined_at
+        FROM organization_admins a
+        WHERE a.organization_id = p_organization_id AND a.deleted_at IS NULL
+        UNION ALL
+        SELECT t.user_id, 'tutor', t.approved, t.created_at
+        FROM organization_tutors t
+        WHERE t.organization_id = p_organization_id AND t.deleted_at IS NULL
+        UNION ALL
+        SELECT s.user_id, 'student', TRUE, s.created_at
+        FROM organization_students s
+        WHERE s.organization_id = p_organization_id AND s.deleted_at IS NULL
+    ) m
+    JOIN users u ON u.id = m.user_id
+    WHERE u.erased_at IS NULL
+    ORDER BY lower(u.email), m.role;
+$$;
+
+-- Students count as members, so suspending an organization blocks them too
+CREATE OR REPLACE FUNCTION get_member_organization_statuses(p_user_id UUID)
+RETURNS TABLE (organization_id UUID, status organization_status)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT DISTINCT o.id, o.status
+    FROM organizations o
+    WHERE o.id IN (
+        SELECT a.organization_id FROM organization_admins a WHERE a.user_id = p_user_id AND a.deleted_at IS NULL
+        UNION
+        SELECT t.organization_id FROM organization_tutors t WHERE t.user_id = p_user_id AND t.deleted_at IS NULL
+        UNION
+        SELECT s.organization_id FROM organization_students s WHERE s.user_id = p_user_id AND s.deleted_at IS NULL
+        UNION
+        SELECT i.organization_id FROM sso_identities i WHERE i.user_id = p_user_id
+    );
+END;
+$$;
+
+-- ---------- Retention and erasure ----------
+
+CREATE OR REPLACE FUNCTION purge_soft_deleted(p_before TIMESTAMP)
+RETURNS TABLE (record_type TEXT, purged BIGINT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    n BIGINT;
+BEGIN
+    DELETE FROM organization_admins WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_admin'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_tutors WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_tutor'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_students WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_student'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_brandings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_branding'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_billings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_billing'; purged := n; RETURN NEXT;
+END;
+$$;
+
+-- As in 020, with student memberships
+CREATE OR REPLACE FUNCTION export_user_data(p_user_id UUID)
+RETURNS JSONB
+LANGUAGE plpgsql AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    SELECT jsonb_build_object(
+        'profile', jsonb_build_object(
+            'id', u.id,
+            'email', u.email,
+            'first_name', u.first_name,
+            'last_name', u.last_name,
+            'role', u.role,
+            'avatar_url', u.avatar_url,
+            'bio', u.bio,
+            'locale', u.locale,
+            'timezone', u.timezone,
+            'email_verified', u.email_verified,
+            'email_verified_at', u.email_verified_at,
+            'password_changed_at', u.password_changed_at,
+            'mfa_enabled', COALESCE((SELECT m.enabled FROM user_mfa m WHERE m.user_id = u.id), FALSE),
+            'sso_identities', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'organization_id', i.organization_id, 'issuer', i.issuer, 'subject', i.subject,
+                    'email', i.email, 'created_at', i.created_at, 'last_login_at', i.last_login_at) ORDER BY i.created_at)
+                FROM sso_identities i WHERE i.user_id = u.id), '[]'::jsonb),
+            'created_at', u.created_at,
+            'updated_at', u.updated_at
+        ),
+        'sessions', jsonb_build_object(
+            'sessions', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'id', t.id, 'created_at', t.created_at, 'expires_at', t.expires_at, 'revoked_at', t.deleted_at)
+                    ORDER BY t.created_at)
+                FROM tokens t WHERE t.user_id = u.id), '[]'::jsonb),
+            'sign_ins', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'outcome', l.outcome, 'reason', l.reason, 'ip_address', l.ip_address,
+                    'user_agent', l.user_agent, 'created_at', l.created_at) ORDER BY l.created_at)
+                FROM login_events l WHERE l.user_id = u.id), '[]'::jsonb)
+        ),
+        'memberships', COALESCE((SELECT jsonb_agg(m ORDER BY m->>'created_at') FROM (
+                SELECT jsonb_build_object('organization_id', a.organization_id, 'organization_name', o.name,
+                                          'membership', 'admin', 'role', a.role, 'created_at', a.created_at,
+                                          'deleted_at', a.deleted_at) AS m
+                FROM organization_admins a JOIN organizations o ON o.id = a.organization_id
+                WHERE a.user_id = u.id
+                UNION ALL
+                SELECT jsonb_build_object('organization_id', t.organization_id, 'organization_name', o.name,
+                                          'membership', 'tutor', 'approved', t.approved, 'created_at', t.created_at,
+                                          'deleted_at', t.deleted_at)
+                FROM organization_tutors t JOIN organizations o ON o.id = t.organization_id
+                WHERE t.user_id = u.id
+                UNION ALL
+                SELECT jsonb_build_object('organization_id', s.organization_id, 'organization_name', o.name,
+                                          'membership', 'student', 'created_at', s.created_at,
+                                          'deleted_at', s.deleted_at)
+                FROM organization_students s JOIN organizations o ON o.id = s.organization_id
+                WHERE s.user_id = u.id
+            ) memberships), '[]'::jsonb),
+        'enrollments', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                'course_id', c.id, 'course_title', c.title, 'organization_id', c.organization_id,
+                'enrolled_at', e.created_at) ORDER BY e.created_at)
+            FROM enrollments e JOIN courses c ON c.id = e.course_id
+            WHERE e.user_id = u.id), '[]'::jsonb),
+        'grades', '[]'::jsonb,
+        'payments', '[]'::jsonb
+    )
+    INTO result
+    FROM users u
+    WHERE u.id = p_user_id;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'user % not found', p_user_id;
+    END IF;
+
+    RETURN result;
+END;
+$$;
+
+-- As in 020, also ending student memberships
+CREATE OR REPLACE FUNCTION erase_user(p_user_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    old_email VARCHAR;
+BEGIN
+    SELECT email INTO old_email FROM users WHERE id = p_user_id AND erased_at IS NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE users
+    SET email = 'erased-' || id || '@erased.invalid',
+        password = '!',   -- matches no password
+        first_name = '',
+        last_name = '',
+        avatar_url = '',
+        bio = '',
+        locale = '',
+        timezone = '',
+        email_verified = FALSE,
+        email_verified_at = NULL,
+        verification_sent_at = NULL,
+        erased_at = CURRENT_TIMESTAMP,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_user_id;
+
+    DELETE FROM tokens WHERE user_id = p_user_id;
+    DELETE FROM user_mfa WHERE user_id = p_user_id;
+    DELETE FROM mfa_recovery_codes WHERE user_id = p_user_id;
+    DELETE FROM sso_identities WHERE user_id = p_user_id;
+    DELETE FROM password_history WHERE user_id = p_user_id;
+    DELETE FROM password_resets WHERE user_id = p_user_id;
+    DELETE FROM email_changes WHERE user_id = p_user_id;
+    DELETE FROM user_data_exports WHERE user_id = p_user_id;
+
+    -- Keep the outcome of sign-in attempts, not who made them from where
+    UPDATE login_events
+    SET email = '', ip_address = '', user_agent = ''
+    WHERE user_id = p_user_id OR lower(email) = lower(old_email);
+
+    -- Import reports name users by email
+    UPDATE user_import_errors SET email = '' WHERE lower(email) = lower(old_email);
+
+    UPDATE organization_admins SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = p_user_id AND deleted_at IS NULL;
+    UPDATE organization_tutors SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = p_user_id AND deleted_at IS NULL;
+    UPDATE organization_students SET deleted_at = CURRENT_TIMESTAMP WHERE user_id = p_user_id AND deleted_at IS NULL;
+
+    RETURN TRUE;
+END;
+$$;
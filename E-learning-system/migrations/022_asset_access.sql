@@ -0,0 +1,17 @@
+-- =====================================================
+-- ASSET ACCESS
+-- Asset metadata and signed download URLs are only handed to the user who
+-- uploaded the file, admins of the organization it belongs to, and
+-- platform admins.
+-- =====================================================
+
+CREATE OR REPLACE FUNCTION is_organization_admin(p_organization_id UUID, p_user_id UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM organization_admins
+        WHERE organization_id = p_organization_id
+          AND user_id = p_user_id
+          AND deleted_at IS NULL
+    );
+$$;
@@ -0,0 +1,114 @@
+-- =====================================================
+-- ENROLLMENT ACCESS
+-- Courses of an organization only admit its members: admins, approved
+-- tutors, students and users who signed in through its identity provider.
+-- Requests to join a course without an organization wait for the course
+-- creator or a platform admin to approve them.
+-- =====================================================
+
+ALTER TABLE enrollments
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'; -- active, pending
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_course_id ON enrollments (course_id, status);
+
+-- The strongest live membership of a user: the admin role ('admin' or
+-- 'manager'), 'tutor' or 'student'. NULL when the user is not a member.
+CREATE OR REPLACE FUNCTION get_organization_member_role(p_organization_id UUID, p_user_id UUID)
+RETURNS VARCHAR
+LANGUAGE SQL AS $$
+    SELECT m.role FROM (
+        SELECT a.role::VARCHAR AS role, 1 AS rank
+        FROM organization_admins a
+        WHERE a.organization_id = p_organization_id AND a.user_id = p_user_id AND a.deleted_at IS NULL
+        UNION ALL
+        SELECT 'tutor', 2
+        FROM organization_tutors t
+        WHERE t.organization_id = p_organization_id AND t.user_id = p_user_id
+          AND t.approved AND t.deleted_at IS NULL
+        UNION ALL
+        SELECT 'student', 3
+        FROM organization_students s
+        WHERE s.organization_id = p_organization_id AND s.user_id = p_user_id AND s.deleted_at IS NULL
+        UNION ALL
+        SELECT 'student', 3
+        FROM sso_identities i
+        WHERE i.organization_id = p_organization_id AND i.user_id = p_user_id
+    ) m
+    ORDER BY m.rank
+    LIMIT 1;
+$$;
+
+-- A pending request is activated when the user enrolls again as a member
+DROP PROCEDURE IF EXISTS create_enrollment(UUID, UUID, UUID);
+CREATE OR REPLACE PROCEDURE create_enrollment(
+    IN p_id UUID,
+    IN p_course_id UUID,
+    IN p_user_id UUID,
+    IN p_status VARCHAR
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO enrollments (id, course_id, user_id, status)
+    VALUES (p_id, p_course_id, p_user_id, p_status)
+    ON CONFLICT (course_id, user_id) DO UPDATE
+        SET status = 'active'
+        WHERE enrollments.status = 'pending' AND EXCLUDED.status = 'active';
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION is_user_enrolled(p_user_id UUID, p_course_id UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM enrollments
+        WHERE user_id = p_user_id AND course_id = p_course_id AND status = 'active'
+    );
+$$;
+
+DROP FUNCTION IF EXISTS get_enrollments_by_user(UUID);
+CREATE OR REPLACE FUNCTION get_enrollments_by_user(p_user_id UUID)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    user_id UUID,
+    status VARCHAR,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT e.id, e.course_id, e.user_id, e.status, e.created_at
+    FROM enrollments e
+    WHERE e.user_id = p_user_id
+    ORDER BY e.created_at DESC;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION get_enrollments_by_course(p_course_id UUID)
+RETURNS TABLE (
+    id UUID,
+    course_id UUID,
+    user_id UUID,
+    status VARCHAR,
+    created_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    RETURN QUERY
+    SELECT e.id, e.course_id, e.user_id, e.status, e.created_at
+    FROM enrollments e
+    WHERE e.course_id = p_course_id
+    ORDER BY e.created_at DESC;
+END;
+$$;
+
+-- Returns FALSE when the course has no pending enrollment with the ID
+CREATE OR REPLACE FUNCTION approve_enrollment(p_id UUID, p_course_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE enrollments SET status = 'active'
+    WHERE id = p_id AND course_id = p_course_id AND status = 'pending';
+    RETURN FOUND;
+END;
+$$;
@@ -0,0 +1,248 @@
+-- =====================================================
+-- SOFT DELETE OF USERS AND ORGANIZATIONS
+-- Users are deleted like the organization records of 019: deleted_at is
+-- set, they can be restored until the retention job runs, and lists include
+-- them on request. Their rows are referenced from too many places to go
+-- away, so the retention job erases them instead of deleting them.
+-- Organizations already have a deletion lifecycle (pending_deletion, then
+-- the organization purge job); lists now leave out organizations pending
+-- deletion unless asked for.
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- ---------- Users ----------
+
+-- Returns 0 when there is no live user with the ID. Sessions end at once.
+CREATE OR REPLACE FUNCTION delete_user(p_id UUID)
+RETURNS INTEGER
+LANGUAGE plpgsql AS $$
+DECLARE
+    rows_deleted INTEGER;
+BEGIN
+    UPDATE users
+    SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+    GET DIAGNOSTICS rows_deleted = ROW_COUNT;
+
+    IF rows_deleted > 0 THEN
+        DELETE FROM tokens WHERE user_id = p_id;
+    END IF;
+    RETURN rows_deleted;
+END;
+$$;
+
+-- Erased users stay deleted
+CREATE OR REPLACE FUNCTION restore_user(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE users
+    SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NOT NULL AND erased_at IS NULL;
+    RETURN FOUND;
+END;
+$$;
+
+-- As in 010, live users only
+CREATE OR REPLACE FUNCTION get_user_by_email(p_email VARCHAR)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR(255),
+    password TEXT,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    email_verified BOOLEAN
+)
+LANGUAGE SQL AS $$
+    SELECT u.id, u.email, u.password, u.first_name, u.last_name, u.role,
+           u.created_at, u.updated_at, u.email_verified
+    FROM users u
+    WHERE u.email = p_email AND u.deleted_at IS NULL;
+$$;
+
+CREATE OR REPLACE FUNCTION get_user_by_id(p_id UUID)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR(255),
+    password TEXT,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    avatar_url TEXT,
+    bio TEXT,
+    locale VARCHAR(35),
+    timezone VARCHAR(64),
+    email_verified BOOLEAN
+)
+LANGUAGE SQL AS $$
+    SELECT u.id, u.email, u.password, u.first_name, u.last_name, u.role,
+           u.created_at, u.updated_at, u.avatar_url, u.bio, u.locale, u.timezone,
+           u.email_verified
+    FROM users u
+    WHERE u.id = p_id AND u.deleted_at IS NULL;
+$$;
+
+DROP FUNCTION IF EXISTS count_users(user_role, TEXT);
+CREATE OR REPLACE FUNCTION count_users(p_role user_role, p_email_prefix TEXT, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM users u
+    WHERE (p_include_deleted OR u.deleted_at IS NULL)
+      AND (p_role IS NULL OR u.role = p_role)
+      AND (p_email_prefix IS NULL OR starts_with(lower(u.email), lower(p_email_prefix)));
+$$;
+
+DROP FUNCTION IF EXISTS list_users(user_role, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_users(
+    p_role user_role,
+    p_email_prefix TEXT,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    email VARCHAR,
+    password TEXT,
+    first_name VARCHAR,
+    last_name VARCHAR,
+    role user_role,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    email_verified BOOLEAN,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'email' THEN 'u.email' WHEN 'last_name' THEN 'u.last_name' ELSE 'u.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'email' THEN 'varchar' WHEN 'last_name' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT u.id, u.email::varchar, u.password, u.first_name::varchar, u.last_name::varchar,
+                u.role, u.created_at, u.updated_at, u.email_verified, u.deleted_at
+         FROM users u
+         WHERE ($3 OR u.deleted_at IS NULL)
+           AND ($1 IS NULL OR u.role = $1)
+           AND ($2 IS NULL OR starts_with(lower(u.email), lower($2)))
+           AND ($5 IS NULL OR (%1$s, u.id) %2$s ($4::%3$s, $5))
+         ORDER BY %1$s %4$s, u.id %4$s
+         LIMIT $6',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_role, p_email_prefix, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Organizations ----------
+
+-- Organizations pending deletion are listed on request or when asked for by status
+DROP FUNCTION IF EXISTS count_organizations(organization_status, TEXT);
+CREATE OR REPLACE FUNCTION count_organizations(p_status organization_status, p_plan TEXT, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM organizations o
+    WHERE (CASE WHEN p_status IS NULL THEN o.status <> 'deleted' AND (p_include_deleted OR o.status <> 'pending_deletion')
+                ELSE o.status = p_status END)
+      AND (p_plan IS NULL OR o.plan::text = p_plan);
+$$;
+
+DROP FUNCTION IF EXISTS list_organizations(organization_status, TEXT, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_organizations(
+    p_status organization_status,
+    p_plan TEXT,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    name VARCHAR,
+    description TEXT,
+    logo_url VARCHAR,
+    primary_color VARCHAR,
+    secondary_color VARCHAR,
+    domain VARCHAR,
+    status organization_status,
+    plan VARCHAR,
+    status_reason TEXT,
+    status_changed_by UUID,
+    status_changed_at TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'name' THEN 'o.name' ELSE 'o.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'name' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT o.id, o.name::varchar, o.description, o.logo_url::varchar, o.primary_color::varchar,
+                o.secondary_color::varchar, o.domain::varchar, o.status, o.plan::varchar, o.status_reason,
+                o.status_changed_by, o.status_changed_at, o.deletion_scheduled_at, o.created_at, o.updated_at
+         FROM organizations o
+         WHERE (CASE WHEN $1 IS NULL THEN o.status <> ''deleted'' AND ($3 OR o.status <> ''pending_deletion'')
+                     ELSE o.status = $1 END)
+           AND ($2 IS NULL OR o.plan::text = $2)
+           AND ($5 IS NULL OR (%1$s, o.id) %2$s ($4::%3$s, $5))
+         ORDER BY %1$s %4$s, o.id %4$s
+         LIMIT $6',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_status, p_plan, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Retention ----------
+
+-- As in 021, also erasing users deleted before p_before
+CREATE OR REPLACE FUNCTION purge_soft_deleted(p_before TIMESTAMP)
+RETURNS TABLE (record_type TEXT, purged BIGINT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    n BIGINT;
+    expired_user UUID;
+BEGIN
+    DELETE FROM organization_admins WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_admin'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_tutors WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_tutor'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_students WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_student'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_brandings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_branding'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_billings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_billing'; purged := n; RETURN NEXT;
+
+    n := 0;
+    FOR expired_user IN SELECT id FROM users WHERE deleted_at < p_before AND erased_at IS NULL LOOP
+        IF erase_user(expired_user) THEN
+            n := n + 1;
+        END IF;
+    END LOOP;
+    record_type := 'user'; purged := n; RETURN NEXT;
+END;
+$$;
@@ -0,0 +1,219 @@
+-- =====================================================
+-- SOFT DELETE OF COURSES, LESSONS AND ASSETS
+-- Deleted the same way as the records of 019 and 024. A course takes its
+-- lessons with it and brings back the ones deleted together with it. The
+-- stored files of deleted assets and lesson videos are kept until the
+-- retention job, which removes them before purging the rows.
+-- =====================================================
+
+CREATE INDEX IF NOT EXISTS idx_courses_deleted_at ON courses (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_lessons_deleted_at ON lessons (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_assets_deleted_at ON assets (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- ---------- Courses and lessons ----------
+
+-- Each returns FALSE when there is no live record with the ID
+CREATE OR REPLACE FUNCTION delete_course(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    deleted_time TIMESTAMP := CURRENT_TIMESTAMP;
+BEGIN
+    UPDATE courses SET deleted_at = deleted_time WHERE id = p_id AND deleted_at IS NULL;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE lessons SET deleted_at = deleted_time WHERE course_id = p_id AND deleted_at IS NULL;
+    RETURN TRUE;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION delete_lesson(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE lessons SET deleted_at = CURRENT_TIMESTAMP WHERE id = p_id AND deleted_at IS NULL;
+    RETURN FOUND;
+END;
+$$;
+
+-- Lessons deleted on their own before the course stay deleted
+CREATE OR REPLACE FUNCTION restore_course(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    deleted_time TIMESTAMP;
+BEGIN
+    SELECT deleted_at INTO deleted_time FROM courses WHERE id = p_id AND deleted_at IS NOT NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE lessons SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+    WHERE course_id = p_id AND deleted_at = deleted_time;
+    UPDATE courses SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = p_id;
+    RETURN TRUE;
+END;
+$$;
+
+-- The lesson's course has to be restored first
+CREATE OR REPLACE FUNCTION restore_lesson(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    restored lessons%ROWTYPE;
+BEGIN
+    SELECT * INTO restored FROM lessons WHERE id = p_id AND deleted_at IS NOT NULL FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN FALSE;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM courses WHERE id = restored.course_id AND deleted_at IS NULL) THEN
+        RAISE EXCEPTION 'course % of lesson % is deleted', restored.course_id, p_id
+            USING ERRCODE = 'foreign_key_violation';
+    END IF;
+
+    UPDATE lessons SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = p_id;
+    RETURN TRUE;
+END;
+$$;
+
+DROP FUNCTION IF EXISTS count_courses(UUID);
+CREATE OR REPLACE FUNCTION count_courses(p_organization_id UUID, p_include_deleted BOOLEAN)
+RETURNS BIGINT
+LANGUAGE SQL AS $$
+    SELECT COUNT(*)
+    FROM courses c
+    WHERE (p_include_deleted OR c.deleted_at IS NULL)
+      AND (p_organization_id IS NULL OR c.organization_id = p_organization_id);
+$$;
+
+DROP FUNCTION IF EXISTS list_courses(UUID, TEXT, BOOLEAN, TEXT, UUID, INT);
+CREATE OR REPLACE FUNCTION list_courses(
+    p_organization_id UUID,
+    p_include_deleted BOOLEAN,
+    p_sort TEXT,
+    p_desc BOOLEAN,
+    p_after_value TEXT,
+    p_after_id UUID,
+    p_limit INT
+)
+RETURNS TABLE (
+    id UUID,
+    organization_id UUID,
+    title VARCHAR,
+    description TEXT,
+    created_by UUID,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP
+)
+LANGUAGE plpgsql AS $$
+DECLARE
+    sort_col TEXT := CASE p_sort WHEN 'title' THEN 'c.title' ELSE 'c.created_at' END;
+    sort_type TEXT := CASE p_sort WHEN 'title' THEN 'varchar' ELSE 'timestamp' END;
+BEGIN
+    RETURN QUERY EXECUTE format(
+        'SELECT c.id, c.organization_id, c.title::varchar, c.description, c.created_by, c.created_at, c.updated_at,
+                c.deleted_at
+         FROM courses c
+         WHERE ($2 OR c.deleted_at IS NULL)
+           AND ($1 IS NULL OR c.organization_id = $1)
+           AND ($4 IS NULL OR (%1$s, c.id) %2$s ($3::%3$s, $4))
+         ORDER BY %1$s %4$s, c.id %4$s
+         LIMIT $5',
+        sort_col, CASE WHEN p_desc THEN '<' ELSE '>' END, sort_type, CASE WHEN p_desc THEN 'DESC' ELSE 'ASC' END)
+    USING p_organization_id, p_include_deleted, p_after_value, p_after_id, p_limit;
+END;
+$$;
+
+-- ---------- Assets ----------
+
+-- Deleting twice must not move the retention deadline
+CREATE OR REPLACE PROCEDURE delete_asset(IN p_id UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE assets
+    SET deleted_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND deleted_at IS NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION restore_asset(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE assets SET deleted_at = NULL WHERE id = p_id AND deleted_at IS NOT NULL;
+    RETURN FOUND;
+END;
+$$;
+
+-- ---------- Retention ----------
+
+-- Storage keys of the files purge_soft_deleted is about to orphan: every
+-- asset object and variant, and the video files of lessons. Lessons of a
+-- deleted course are deleted with it. HLS playlists name the rest of their
+-- package.
+CREATE OR REPLACE FUNCTION get_expired_storage_keys(p_before TIMESTAMP)
+RETURNS TABLE (storage_key TEXT)
+LANGUAGE SQL AS $$
+    SELECT a.storage_key FROM assets a WHERE a.deleted_at < p_before
+    UNION
+    SELECT v.value FROM assets a, jsonb_each_text(a.variants) v WHERE a.deleted_at < p_before
+    UNION
+    SELECT k.key
+    FROM lessons l, unnest(ARRAY[l.video_source_key, l.video_playlist_key, l.video_thumbnail_key]) AS k(key)
+    WHERE l.deleted_at < p_before AND k.key <> '';
+$$;
+
+-- As in 024, also purging courses, lessons and assets. Enrollments and
+-- upload sessions of purged courses and lessons go with them.
+CREATE OR REPLACE FUNCTION purge_soft_deleted(p_before TIMESTAMP)
+RETURNS TABLE (record_type TEXT, purged BIGINT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    n BIGINT;
+    expired_user UUID;
+BEGIN
+    DELETE FROM organization_admins WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_admin'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_tutors WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_tutor'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_students WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_student'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_brandings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_branding'; purged := n; RETURN NEXT;
+
+    DELETE FROM organization_billings WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'organization_billing'; purged := n; RETURN NEXT;
+
+    DELETE FROM lessons WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'lesson'; purged := n; RETURN NEXT;
+
+    DELETE FROM courses WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'course'; purged := n; RETURN NEXT;
+
+    DELETE FROM assets WHERE deleted_at < p_before;
+    GET DIAGNOSTICS n = ROW_COUNT;
+    record_type := 'asset'; purged := n; RETURN NEXT;
+
+    n := 0;
+    FOR expired_user IN SELECT id FROM users WHERE deleted_at < p_before AND erased_at IS NULL LOOP
+        IF erase_user(expired_user) THEN
+            n := n + 1;
+        END IF;
+    END LOOP;
+    record_type := 'user'; purged := n; RETURN NEXT;
+END;
+$$;
@@ -0,0 +1,83 @@
+-- =====================================================
+-- SESSIONS IN USER DATA EXPORTS
+-- The ID of a token is the bearer token itself, so the export lists
+-- sessions by their times alone.
+-- =====================================================
+
+-- As in 021, without the token IDs
+CREATE OR REPLACE FUNCTION export_user_data(p_user_id UUID)
+RETURNS JSONB
+LANGUAGE plpgsql AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    SELECT jsonb_build_object(
+        'profile', jsonb_build_object(
+            'id', u.id,
+            'email', u.email,
+            'first_name', u.first_name,
+            'last_name', u.last_name,
+            'role', u.role,
+            'avatar_url', u.avatar_url,
+            'bio', u.bio,
+            'locale', u.locale,
+            'timezone', u.timezone,
+            'email_verified', u.email_verified,
+            'email_verified_at', u.email_verified_at,
+            'password_changed_at', u.password_changed_at,
+            'mfa_enabled', COALESCE((SELECT m.enabled FROM user_mfa m WHERE m.user_id = u.id), FALSE),
+            'sso_identities', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'organization_id', i.organization_id, 'issuer', i.issuer, 'subject', i.subject,
+                    'email', i.email, 'created_at', i.created_at, 'last_login_at', i.last_login_at) ORDER BY i.created_at)
+                FROM sso_identities i WHERE i.user_id = u.id), '[]'::jsonb),
+            'created_at', u.created_at,
+            'updated_at', u.updated_at
+        ),
+        'sessions', jsonb_build_object(
+            'sessions', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'created_at', t.created_at, 'expires_at', t.expires_at, 'revoked_at', t.deleted_at)
+                    ORDER BY t.created_at)
+                FROM tokens t WHERE t.user_id = u.id), '[]'::jsonb),
+            'sign_ins', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                    'outcome', l.outcome, 'reason', l.reason, 'ip_address', l.ip_address,
+                    'user_agent', l.user_agent, 'created_at', l.created_at) ORDER BY l.created_at)
+                FROM login_events l WHERE l.user_id = u.id), '[]'::jsonb)
+        ),
+        'memberships', COALESCE((SELECT jsonb_agg(m ORDER BY m->>'created_at') FROM (
+                SELECT jsonb_build_object('organization_id', a.organization_id, 'organization_name', o.name,
+                                          'membership', 'admin', 'role', a.role, 'created_at', a.created_at,
+                                          'deleted_at', a.deleted_at) AS m
+                FROM organization_admins a JOIN organizations o ON o.id = a.organization_id
+                WHERE a.user_id = u.id
+                UNION ALL
+                SELECT jsonb_build_object('organization_id', t.organization_id, 'organization_name', o.name,
+                                          'membership', 'tutor', 'approved', t.approved, 'created_at', t.created_at,
+                                          'deleted_at', t.deleted_at)
+                FROM organization_tutors t JOIN organizations o ON o.id = t.organization_id
+                WHERE t.user_id = u.id
+                UNION ALL
+                SELECT jsonb_build_object('organization_id', s.organization_id, 'organization_name', o.name,
+                                          'membership', 'student', 'created_at', s.created_at,
+                                          'deleted_at', s.deleted_at)
+                FROM organization_students s JOIN organizations o ON o.id = s.organization_id
+                WHERE s.user_id = u.id
+            ) memberships), '[]'::jsonb),
+        'enrollments', COALESCE((SELECT jsonb_agg(jsonb_build_object(
+                'course_id', c.id, 'course_title', c.title, 'organization_id', c.organization_id,
+                'enrolled_at', e.created_at) ORDER BY e.created_at)
+            FROM enrollments e JOIN courses c ON c.id = e.course_id
+            WHERE e.user_id = u.id), '[]'::jsonb),
+        'grades', '[]'::jsonb,
+        'payments', '[]'::jsonb
+    )
+    INTO result
+    FROM users u
+    WHERE u.id = p_user_id;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'user % not found', p_user_id;
+    END IF;
+
+    RETURN result;
+END;
+$$;
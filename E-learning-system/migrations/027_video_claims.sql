@@ -0,0 +1,48 @@
+-- =====================================================
+-- STALE VIDEO PROCESSING CLAIMS
+-- A worker that dies mid-transcode leaves its lesson in processing, where
+-- new uploads are refused. Claims carry their time so the job can hand
+-- stale ones back to the queue.
+-- =====================================================
+
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS video_claimed_at TIMESTAMP;
+
+-- As in 007, stamping the claim
+CREATE OR REPLACE FUNCTION claim_lesson_video_processing(p_id UUID)
+RETURNS BOOLEAN
+LANGUAGE plpgsql AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE lessons
+    SET video_status = 'processing',
+        video_error = '',
+        video_claimed_at = CURRENT_TIMESTAMP,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = p_id AND video_status = 'uploaded';
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    RETURN row_count > 0;
+END;
+$$;
+
+-- Puts videos claimed before the given time back to uploaded. Claims made
+-- before this migration have no time and go by the last update instead.
+CREATE OR REPLACE FUNCTION release_stale_lesson_video_claims(p_claimed_before TIMESTAMP)
+RETURNS INT
+LANGUAGE plpgsql AS $$
+DECLARE
+    row_count INT;
+BEGIN
+    UPDATE lessons
+    SET video_status = 'uploaded',
+        video_claimed_at = NULL,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE video_status = 'processing'
+      AND deleted_at IS NULL
+      AND COALESCE(video_claimed_at, updated_at) < p_claimed_before;
+
+    GET DIAGNOSTICS row_count = ROW_COUNT;
+    RETURN row_count;
+END;
+$$;
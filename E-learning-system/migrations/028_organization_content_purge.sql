@@ -0,0 +1,88 @@
+-- =====================================================
+-- ORGANIZATION CONTENT IN EXPORTS AND PURGES
+-- The courses of an organization, with their lessons and enrollments, and
+-- its assets are exported and purged along with the organization. The
+-- purge job deletes their stored files before calling the purge.
+-- =====================================================
+
+-- Stored files of an organization's assets, lesson videos and user imports,
+-- deleted or not
+CREATE OR REPLACE FUNCTION get_organization_storage_keys(p_org_id UUID)
+RETURNS TABLE (storage_key TEXT)
+LANGUAGE SQL AS $$
+    SELECT a.storage_key FROM assets a WHERE a.organization_id = p_org_id
+    UNION
+    SELECT v.value FROM assets a, jsonb_each_text(a.variants) v WHERE a.organization_id = p_org_id
+    UNION
+    SELECT k.key
+    FROM lessons l
+    JOIN courses c ON c.id = l.course_id,
+    unnest(ARRAY[l.video_source_key, l.video_playlist_key, l.video_thumbnail_key]) AS k(key)
+    WHERE c.organization_id = p_org_id AND k.key <> ''
+    UNION
+    SELECT i.storage_key FROM user_imports i WHERE i.organization_id = p_org_id AND i.storage_key <> '';
+$$;
+
+-- As in 004, with students, courses, lessons, enrollments and assets
+CREATE OR REPLACE FUNCTION export_organization(p_org_id UUID)
+RETURNS JSONB
+LANGUAGE plpgsql AS $$
+DECLARE
+    result JSONB;
+BEGIN
+    SELECT jsonb_build_object(
+        'organization', to_jsonb(o),
+        'admins', COALESCE((SELECT jsonb_agg(to_jsonb(a)) FROM organization_admins a WHERE a.organization_id = o.id), '[]'::jsonb),
+        'tutors', COALESCE((SELECT jsonb_agg(to_jsonb(t)) FROM organization_tutors t WHERE t.organization_id = o.id), '[]'::jsonb),
+        'students', COALESCE((SELECT jsonb_agg(to_jsonb(s)) FROM organization_students s WHERE s.organization_id = o.id), '[]'::jsonb),
+        'branding', COALESCE((SELECT jsonb_agg(to_jsonb(b)) FROM organization_brandings b WHERE b.organization_id = o.id), '[]'::jsonb),
+        'billing', COALESCE((SELECT jsonb_agg(to_jsonb(bl)) FROM organization_billings bl WHERE bl.organization_id = o.id), '[]'::jsonb),
+        'courses', COALESCE((SELECT jsonb_agg(to_jsonb(c) ORDER BY c.created_at) FROM courses c WHERE c.organization_id = o.id), '[]'::jsonb),
+        'lessons', COALESCE((SELECT jsonb_agg(to_jsonb(l) ORDER BY l.course_id, l.position)
+            FROM lessons l JOIN courses c ON c.id = l.course_id WHERE c.organization_id = o.id), '[]'::jsonb),
+        'enrollments', COALESCE((SELECT jsonb_agg(to_jsonb(e) ORDER BY e.created_at)
+            FROM enrollments e JOIN courses c ON c.id = e.course_id WHERE c.organization_id = o.id), '[]'::jsonb),
+        'assets', COALESCE((SELECT jsonb_agg(to_jsonb(a) ORDER BY a.created_at) FROM assets a WHERE a.organization_id = o.id), '[]'::jsonb),
+        'status_events', COALESCE((SELECT jsonb_agg(to_jsonb(e) ORDER BY e.created_at) FROM organization_status_events e WHERE e.organization_id = o.id), '[]'::jsonb),
+        'exported_at', CURRENT_TIMESTAMP
+    )
+    INTO result
+    FROM organizations o
+    WHERE o.id = p_org_id;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'organization % not found', p_org_id;
+    END IF;
+
+    RETURN result;
+END;
+$$;
+
+-- As in 004, also removing the organization's content. Lessons, their
+-- upload sessions and enrollments go with the courses.
+CREATE OR REPLACE PROCEDURE hard_delete_organization(IN p_id UUID, IN p_reason TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE
+    current_status organization_status;
+BEGIN
+    SELECT status INTO current_status FROM organizations WHERE id = p_id FOR UPDATE;
+    IF current_status IS NULL THEN
+        RAISE EXCEPTION 'organization % not found', p_id;
+    END IF;
+    IF current_status <> 'pending_deletion' THEN
+        RAISE EXCEPTION 'organization % is not pending deletion', p_id;
+    END IF;
+
+    DELETE FROM courses WHERE organization_id = p_id;
+    DELETE FROM assets WHERE organization_id = p_id;
+    DELETE FROM organization_admins WHERE organization_id = p_id;
+    DELETE FROM organization_tutors WHERE organization_id = p_id;
+    DELETE FROM organization_students WHERE organization_id = p_id;
+    DELETE FROM organization_brandings WHERE organization_id = p_id;
+    DELETE FROM organization_billings WHERE organization_id = p_id;
+    DELETE FROM organizations WHERE id = p_id;
+
+    INSERT INTO organization_status_events (organization_id, from_status, to_status, reason, actor_id)
+    VALUES (p_id, current_status, 'deleted', COALESCE(p_reason, ''), NULL);
+END;
+$$;
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RGB is a color with 8-bit channels
+type RGB struct {
+	R, G, B uint8
+}
+
+// ParseHexColor parses "#RGB" or "#RRGGBB" colors
+func ParseHexColor(s string) (RGB, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if !strings.HasPrefix(strings.TrimSpace(s), "#") {
+		return RGB{}, fmt.Errorf("color %q must start with #", s)
+	}
+
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return RGB{}, fmt.Errorf("color %q must be #RGB or #RRGGBB", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("color %q is not valid hex", s)
+	}
+
+	return RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// Hex returns the color as lowercase "#rrggbb"
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// RelativeLuminance returns the WCAG 2.x relative luminance of the color
+func (c RGB) RelativeLuminance() float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// ContrastRatio returns the WCAG contrast ratio between two colors (1 to 21)
+func ContrastRatio(a, b RGB) float64 {
+	la, lb := a.RelativeLuminance(), b.RelativeLuminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// ReadableTextColor picks black or white text, whichever contrasts more with the background
+func ReadableTextColor(background RGB) RGB {
+	black, white := RGB{0, 0, 0}, RGB{255, 255, 255}
+	if ContrastRatio(background, black) >= ContrastRatio(background, white) {
+		return black
+	}
+	return white
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"#1f6feb", "#1f6feb", true},
+		{"#1F6FEB", "#1f6feb", true},
+		{"#abc", "#aabbcc", true},
+		{" #000 ", "#000000", true},
+		{"1f6feb", "", false},
+		{"#12345", "", false},
+		{"#1234567", "", false},
+		{"#gggggg", "", false},
+		{"red", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			rgb, err := ParseHexColor(tt.in)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("ParseHexColor(%q) = %s, want an error", tt.in, rgb.Hex())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseHexColor(%q): %v", tt.in, err)
+			}
+			if got := rgb.Hex(); got != tt.want {
+				t.Errorf("ParseHexColor(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	black, white := RGB{0, 0, 0}, RGB{255, 255, 255}
+
+	tests := []struct {
+		name string
+		a, b RGB
+		want float64
+	}{
+		{"black on white", black, white, 21},
+		{"either order", white, black, 21},
+		{"same color", RGB{0x1f, 0x6f, 0xeb}, RGB{0x1f, 0x6f, 0xeb}, 1},
+		{"grey on white", RGB{0x77, 0x77, 0x77}, white, 4.48},
+		{"blue on white", RGB{0, 0, 255}, white, 8.59},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContrastRatio(tt.a, tt.b); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("ContrastRatio = %.3f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadableTextColor(t *testing.T) {
+	tests := []struct {
+		background string
+		want       string
+	}{
+		{"#ffffff", "#000000"},
+		{"#ffff00", "#000000"},
+		{"#000000", "#ffffff"},
+		{"#1a237e", "#ffffff"},
+	}
+	for _, tt := range tests {
+		bg, err := ParseHexColor(tt.background)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ReadableTextColor(bg).Hex(); got != tt.want {
+			t.Errorf("ReadableTextColor(%s) = %s, want %s", tt.background, got, tt.want)
+		}
+	}
+}
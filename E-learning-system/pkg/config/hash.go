@@ -1,19 +1,105 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
 
+// Argon2id parameters for new hashes, following the OWASP recommendation of
+// 19 MiB memory, 2 iterations and 1 degree of parallelism. Hashes made with
+// other parameters still verify and are upgraded by PasswordNeedsRehash.
+const (
+	argon2Memory  = 19 * 1024 // KiB
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// argon2Prefix starts hashes in the PHC string format written by HashePassword
+const argon2Prefix = "$argon2id$"
+
+// HashePassword hashes a password with Argon2id. The result is a PHC string,
+// $argon2id$v=19$m=...,t=...,p=...$salt$key, so it carries its own parameters.
 func HashePassword(password string) (string, error) {
- HashePassword, err := bcrypt.GenerateFromPassword([]byte (password) , bcrypt.DefaultCost)
- if err != nil {
-	return "", err
- }
- return string(HashePassword), nil 
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPasswordHash compares a plain text password with an Argon2id hash or
+// with a bcrypt hash made before Argon2id was introduced
+func CheckPasswordHash(password, hash string) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	params, salt, key, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+// PasswordNeedsRehash reports whether hash should be replaced by a fresh
+// HashePassword of the same password: bcrypt hashes and Argon2id hashes made
+// with other parameters. Call it after a successful CheckPasswordHash.
+func PasswordNeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		return true
+	}
+	params, _, key, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	return params != argon2Params{memory: argon2Memory, time: argon2Time, threads: argon2Threads} || len(key) != argon2KeyLen
+}
 
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
 }
 
-// ComparePassword compares the hashed password with the plain text password
-func CheckPasswordHash( password, hash string) bool {
- err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
- return  err  == nil
-}
\ No newline at end of file
+// parseArgon2Hash splits a PHC string written by HashePassword
+func parseArgon2Hash(hash string) (params argon2Params, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	if params.memory == 0 || params.time == 0 || params.threads == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters %q", parts[3])
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id key")
+	}
+	return params, salt, key, nil
+}
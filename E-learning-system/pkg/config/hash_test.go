@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// phcHash builds an Argon2id PHC string with cheap parameters
+func phcHash(password string, memory, time uint32, threads uint8, keyLen uint32) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, memory, time, threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestHashePassword(t *testing.T) {
+	hash, err := HashePassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPasswordHash("correct horse", hash) {
+		t.Error("hash does not verify its own password")
+	}
+	if CheckPasswordHash("wrong horse", hash) {
+		t.Error("hash verifies another password")
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Errorf("fresh hash %q needs a rehash", hash)
+	}
+
+	again, err := HashePassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Error("two hashes of the same password share a salt")
+	}
+}
+
+func TestCheckPasswordHash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{"argon2id", "secret", phcHash("secret", 64, 1, 1, 32), true},
+		{"argon2id wrong password", "Secret", phcHash("secret", 64, 1, 1, 32), false},
+		{"argon2id other parameters", "secret", phcHash("secret", 128, 3, 2, 16), true},
+		{"bcrypt", "secret", string(bcryptHash), true},
+		{"bcrypt wrong password", "Secret", string(bcryptHash), false},
+		{"empty hash", "secret", "", false},
+		{"missing key", "secret", "$argon2id$v=19$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg", false},
+		{"empty key", "secret", "$argon2id$v=19$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$", false},
+		{"unsupported version", "secret", "$argon2id$v=16$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAA", false},
+		{"zero memory", "secret", "$argon2id$v=19$m=0,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAA", false},
+		{"zero threads", "secret", "$argon2id$v=19$m=64,t=1,p=0$MDEyMzQ1Njc4OWFiY2RlZg$AAAA", false},
+		{"malformed parameters", "secret", "$argon2id$v=19$m=x,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$AAAA", false},
+		{"malformed salt", "secret", "$argon2id$v=19$m=64,t=1,p=1$!!!$AAAA", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPasswordHash(tt.password, tt.hash); got != tt.want {
+				t.Errorf("CheckPasswordHash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current parameters", phcHash("secret", argon2Memory, argon2Time, argon2Threads, argon2KeyLen), false},
+		{"less memory", phcHash("secret", argon2Memory/2, argon2Time, argon2Threads, argon2KeyLen), true},
+		{"fewer iterations", phcHash("secret", argon2Memory, 1, argon2Threads, argon2KeyLen), true},
+		{"more threads", phcHash("secret", argon2Memory, argon2Time, 2, argon2KeyLen), true},
+		{"shorter key", phcHash("secret", argon2Memory, argon2Time, argon2Threads, 16), true},
+		{"bcrypt", string(bcryptHash), true},
+		{"malformed argon2id", "$argon2id$v=19$garbage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PasswordNeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("PasswordNeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
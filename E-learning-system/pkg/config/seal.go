@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSealedValue is returned for values that were not sealed with the key or were tampered with
+var ErrInvalidSealedValue = errors.New("invalid sealed value")
+
+// Seal encrypts and authenticates plaintext with AES-256-GCM under a key
+// derived from key, for secrets that have to be read back, such as TOTP seeds
+func Seal(key []byte, plaintext string) (string, error) {
+	aead, err := sealCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func Open(key []byte, sealed string) (string, error) {
+	aead, err := sealCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", ErrInvalidSealedValue
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealedValue
+	}
+	return string(plaintext), nil
+}
+
+// sealCipher hashes key so secrets of any length give a 256-bit AES key
+func sealCipher(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
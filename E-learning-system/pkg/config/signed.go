@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignedToken is returned for malformed, tampered or expired signed tokens
+var ErrInvalidSignedToken = errors.New("invalid or expired token")
+
+// SignToken returns an opaque, URL-safe token binding payload to an expiry time
+func SignToken(key []byte, payload string, expires time.Time) string {
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return body + "." + signTokenBody(key, body)
+}
+
+// VerifyToken checks a token produced by SignToken and returns its payload and expiry
+func VerifyToken(key []byte, token string) (string, time.Time, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	body, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(signTokenBody(key, body))) {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	parts := strings.SplitN(body, ".", 2)
+	if len(parts) != 2 {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	expires := time.Unix(exp, 0)
+	if time.Now().After(expires) {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	return string(payload), expires, nil
+}
+
+func signTokenBody(key []byte, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
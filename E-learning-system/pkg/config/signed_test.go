@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	key := []byte("signing key")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	valid := SignToken(key, "verify:42", expires)
+	// Change the last character of the signature to another one
+	last := "A"
+	if strings.HasSuffix(valid, last) {
+		last = "B"
+	}
+	tampered := valid[:len(valid)-1] + last
+
+	tests := []struct {
+		name    string
+		key     []byte
+		token   string
+		payload string
+		ok      bool
+	}{
+		{"valid", key, valid, "verify:42", true},
+		{"payload with dots", key, SignToken(key, "a.b.c", expires), "a.b.c", true},
+		{"empty payload", key, SignToken(key, "", expires), "", true},
+		{"other key", []byte("other key"), valid, "", false},
+		{"expired", key, SignToken(key, "verify:42", time.Now().Add(-time.Second)), "", false},
+		{"tampered payload", key, "eA" + valid[strings.Index(valid, "."):], "", false},
+		{"tampered expiry", key, strings.Replace(valid, ".", ".9", 1), "", false},
+		{"tampered signature", key, tampered, "", false},
+		{"no signature", key, valid[:strings.LastIndex(valid, ".")], "", false},
+		{"no dots", key, "token", "", false},
+		{"empty", key, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, gotExpires, err := VerifyToken(tt.key, tt.token)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidSignedToken) {
+					t.Errorf("VerifyToken error = %v, want ErrInvalidSignedToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if payload != tt.payload {
+				t.Errorf("payload = %q, want %q", payload, tt.payload)
+			}
+			if !gotExpires.Equal(expires) {
+				t.Errorf("expires = %v, want %v", gotExpires, expires)
+			}
+		})
+	}
+}
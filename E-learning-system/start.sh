@@ -6,6 +6,6 @@
 # Wait for Redis
 /wait-for-it.sh redis:6379 --timeout=30 --strict -- echo "Redis is up"
 
-# Start the Go app
-/elearning
+# Start the Go app; exec so it receives SIGTERM and can shut down gracefully
+exec /elearning
 